@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// LatestName is the remote file holding the name of the most recent backup
+const LatestName = "LATEST"
+
+// Name returns the versioned file name of a backup taken at t
+func Name(t time.Time) string {
+	return "ameria-" + t.UTC().Format("20060102T150405Z") + ".db.enc"
+}
+
+// Source is a database that can write a consistent copy of itself to a
+// file. *db.DB implements it.
+type Source interface {
+	BackupTo(path string) error
+}
+
+var _ Source = (*db.DB)(nil)
+
+// Upload takes a consistent copy of the database, encrypts it and uploads it
+// under a new versioned name, then points LATEST at it. Returns the name.
+func Upload(ctx context.Context, database Source, remote Remote, passphrase string) (string, error) {
+	dir, err := os.MkdirTemp("", "ameriagrab-backup-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	plainPath := filepath.Join(dir, "ameria.db")
+	if err := database.BackupTo(plainPath); err != nil {
+		return "", err
+	}
+	plain, err := os.Open(plainPath)
+	if err != nil {
+		return "", err
+	}
+	defer plain.Close()
+
+	encrypted, err := os.Create(filepath.Join(dir, "ameria.db.enc"))
+	if err != nil {
+		return "", err
+	}
+	defer encrypted.Close()
+	if err := Encrypt(encrypted, plain, passphrase); err != nil {
+		return "", fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+	size, err := encrypted.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+
+	// A section reader isn't closed by the HTTP client, so remotes can rewind it to retry
+	name := Name(time.Now())
+	if err := remote.Put(ctx, name, io.NewSectionReader(encrypted, 0, size), size); err != nil {
+		return "", err
+	}
+	latest := []byte(name + "\n")
+	if err := remote.Put(ctx, LatestName, bytes.NewReader(latest), int64(len(latest))); err != nil {
+		return "", fmt.Errorf("updating %s: %w", LatestName, err)
+	}
+	return name, nil
+}
+
+// Download fetches a backup (the latest one if name is empty), decrypts it
+// and writes the database to path, which must not exist.
+// Returns the name of the restored backup.
+func Download(ctx context.Context, remote Remote, name, path, passphrase string) (string, error) {
+	if name == "" {
+		body, err := remote.Get(ctx, LatestName)
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", LatestName, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(body, 1024))
+		body.Close()
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", LatestName, err)
+		}
+		name = strings.TrimSpace(string(data))
+	}
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create output file: %w", err)
+	}
+	body, err := remote.Get(ctx, name)
+	if err == nil {
+		err = Decrypt(out, body, passphrase)
+		body.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return name, nil
+}
@@ -0,0 +1,133 @@
+// Package backup encrypts database backups and stores them on remote storage (S3, WebDAV)
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted files start with magic, PBKDF2 iteration count and salt, followed by
+// AES-256-GCM encrypted chunks. Each chunk's nonce is its sequence number and
+// the last chunk is authenticated as such, so reordering or truncation is detected.
+const (
+	magic     = "AGBACKUP"
+	saltSize  = 16
+	chunkSize = 64 * 1024
+)
+
+// Iterations is the PBKDF2-SHA256 iteration count used for new backups
+var Iterations = 600000
+
+// ErrDecrypt is returned for a wrong passphrase or corrupted data
+var ErrDecrypt = errors.New("decryption failed (wrong passphrase or corrupted backup)")
+
+func newGCM(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(aead cipher.AEAD, seq uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+// chunkAD marks the last chunk
+func chunkAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// Encrypt encrypts r to w with a key derived from the passphrase
+func Encrypt(w io.Writer, r io.Reader, passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("empty passphrase")
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := newGCM(passphrase, salt, Iterations)
+	if err != nil {
+		return err
+	}
+
+	header := append([]byte(magic), binary.BigEndian.AppendUint32(nil, uint32(Iterations))...)
+	header = append(header, salt...)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(r, chunkSize+1)
+	buf := make([]byte, chunkSize)
+	for seq := uint64(0); ; seq++ {
+		n, err := io.ReadFull(br, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+		_, peekErr := br.Peek(1)
+		last := peekErr != nil
+		sealed := aead.Seal(nil, chunkNonce(aead, seq), buf[:n], chunkAD(last))
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Decrypt decrypts data produced by Encrypt
+func Decrypt(w io.Writer, r io.Reader, passphrase string) error {
+	header := make([]byte, len(magic)+4+saltSize)
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.HasPrefix(header, []byte(magic)) {
+		return fmt.Errorf("not an encrypted ameriagrab backup")
+	}
+	iterations := binary.BigEndian.Uint32(header[len(magic):])
+	aead, err := newGCM(passphrase, header[len(magic)+4:], int(iterations))
+	if err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(r, chunkSize+aead.Overhead()+1)
+	buf := make([]byte, chunkSize+aead.Overhead())
+	for seq := uint64(0); ; seq++ {
+		n, err := io.ReadFull(br, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			if err == io.EOF {
+				return ErrDecrypt // truncated before the last chunk
+			}
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+		_, peekErr := br.Peek(1)
+		last := peekErr != nil
+		plain, err := aead.Open(buf[:0], chunkNonce(aead, seq), buf[:n], chunkAD(last))
+		if err != nil {
+			return ErrDecrypt
+		}
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
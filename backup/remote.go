@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Remote stores backup files
+type Remote interface {
+	// Put uploads a file of the given size under name, relative to the remote's base path
+	Put(ctx context.Context, name string, body io.ReadSeeker, size int64) error
+	// Get downloads a file; the caller closes the returned reader
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// String describes the remote location
+	String() string
+}
+
+// ParseRemote creates a remote from a URL:
+//
+//	s3://bucket/path           - S3 or compatible storage; credentials from AWS_ACCESS_KEY_ID,
+//	                             AWS_SECRET_ACCESS_KEY (and AWS_SESSION_TOKEN), region from AWS_REGION,
+//	                             custom endpoint (e.g. MinIO) from AMERIA_S3_ENDPOINT
+//	webdav://host/path         - WebDAV over HTTPS (webdav+http:// for plain HTTP); credentials from
+//	                             the URL or AMERIA_WEBDAV_USER and AMERIA_WEBDAV_PASSWORD
+func ParseRemote(rawURL string) (Remote, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid remote %q (expected s3://bucket/path or webdav://host/path)", rawURL)
+	}
+	httpClient := &http.Client{Timeout: 30 * time.Minute}
+	prefix := strings.Trim(u.Path, "/")
+
+	switch u.Scheme {
+	case "s3":
+		accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if accessKey == "" || secretKey == "" {
+			return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set for S3 backups")
+		}
+		region := os.Getenv("AWS_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		endpoint := os.Getenv("AMERIA_S3_ENDPOINT")
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+		}
+		return &S3Remote{
+			Endpoint:     strings.TrimRight(endpoint, "/"),
+			Region:       region,
+			Bucket:       u.Host,
+			Prefix:       prefix,
+			AccessKey:    accessKey,
+			SecretKey:    secretKey,
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+			HTTPClient:   httpClient,
+		}, nil
+	case "webdav", "webdav+https", "webdav+http":
+		scheme := "https"
+		if u.Scheme == "webdav+http" {
+			scheme = "http"
+		}
+		r := &WebDAVRemote{
+			BaseURL:    fmt.Sprintf("%s://%s/%s", scheme, u.Host, prefix),
+			Username:   os.Getenv("AMERIA_WEBDAV_USER"),
+			Password:   os.Getenv("AMERIA_WEBDAV_PASSWORD"),
+			HTTPClient: httpClient,
+		}
+		if u.User != nil {
+			r.Username = u.User.Username()
+			if password, ok := u.User.Password(); ok {
+				r.Password = password
+			}
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("unsupported remote scheme %q (expected s3 or webdav)", u.Scheme)
+}
+
+// checkResponse fails on non-2xx responses
+func checkResponse(resp *http.Response, action string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 500))
+	return fmt.Errorf("%s failed with status %d: %s", action, resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WebDAVRemote stores backups on a WebDAV server (Nextcloud, ownCloud, Apache mod_dav, ...)
+type WebDAVRemote struct {
+	BaseURL    string // collection URL, e.g. https://cloud.example.com/remote.php/dav/files/me/backups
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+}
+
+func (r *WebDAVRemote) String() string {
+	return r.BaseURL
+}
+
+// request sends a request with basic auth credentials, if configured
+func (r *WebDAVRemote) request(ctx context.Context, method, url string, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+	return r.HTTPClient.Do(req)
+}
+
+// mkcol creates a collection, creating missing parents first
+func (r *WebDAVRemote) mkcol(ctx context.Context, url string) error {
+	resp, err := r.request(ctx, "MKCOL", url+"/", nil, 0)
+	if err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusMethodNotAllowed: // already exists
+		return nil
+	case http.StatusConflict: // parent missing
+		i := strings.LastIndex(url, "/")
+		if i <= strings.Index(url, "://")+2 {
+			return fmt.Errorf("creating collection %s failed with status %d", url, resp.StatusCode)
+		}
+		if err := r.mkcol(ctx, url[:i]); err != nil {
+			return err
+		}
+		return r.mkcol(ctx, url)
+	}
+	return checkResponse(resp, "creating collection "+url)
+}
+
+// Put uploads the file, creating the base collection if the server reports it missing
+func (r *WebDAVRemote) Put(ctx context.Context, name string, body io.ReadSeeker, size int64) error {
+	resp, err := r.request(ctx, "PUT", r.BaseURL+"/"+name, body, size)
+	if err == nil && (resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusNotFound) {
+		resp.Body.Close()
+		if err := r.mkcol(ctx, r.BaseURL); err != nil {
+			return err
+		}
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		resp, err = r.request(ctx, "PUT", r.BaseURL+"/"+name, body, size)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload backup: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, "upload")
+}
+
+func (r *WebDAVRemote) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := r.request(ctx, "GET", r.BaseURL+"/"+name, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download backup: %w", err)
+	}
+	if err := checkResponse(resp, "download"); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,79 @@
+package calendar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/report"
+)
+
+// FeedStore is what Feed reads: loans and the ledger. *db.DB implements it.
+type FeedStore interface {
+	GetLoans() ([]client.LoanInfo, error)
+	GetLedgerEntries(opts db.LedgerOptions) ([]db.LedgerEntry, error)
+}
+
+// Feed builds a calendar with the next payment of each loan and the predicted
+// charges of recurring payments detected in the ledger, up to a year ahead
+func Feed(database FeedStore, now time.Time) (*Calendar, error) {
+	cal := &Calendar{Name: "Ameriabank payments"}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	loans, err := database.GetLoans()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range loans {
+		if l.NextPaymentDate == "" {
+			continue
+		}
+		due, err := time.ParseInLocation("2006-01-02", l.NextPaymentDate, time.Local)
+		if err != nil || due.Before(today) {
+			continue
+		}
+		cal.Events = append(cal.Events, Event{
+			UID:     fmt.Sprintf("loan-%s-%s@ameriagrab", l.ID, due.Format("20060102")),
+			Date:    due,
+			Summary: fmt.Sprintf("Loan payment: %s %.2f %s", l.Name, l.NextPaymentAmount, l.Currency),
+			Description: fmt.Sprintf("Outstanding principal %.2f %s, interest rate %.2f%%",
+				l.OutstandingAmount, l.Currency, l.InterestRate),
+		})
+	}
+
+	entries, err := database.GetLedgerEntries(db.LedgerOptions{})
+	if err != nil {
+		return nil, err
+	}
+	until := today.AddDate(1, 0, 0)
+	for _, p := range report.FindRecurring(entries, now) {
+		if p.NextDate.After(until) {
+			continue
+		}
+		cal.Events = append(cal.Events, Event{
+			UID:     recurringUID(p),
+			Date:    p.NextDate,
+			Summary: fmt.Sprintf("%s ~%.2f %s", p.Counterparty, p.Amount, p.Currency),
+			Description: fmt.Sprintf("Predicted %s payment, last charged %s (%d charges seen)",
+				p.Period, p.LastDate.Format("2006-01-02"), p.Count),
+			RRule: fmt.Sprintf("FREQ=%s;UNTIL=%s", frequencies[p.Period], until.Format("20060102")),
+		})
+	}
+	return cal, nil
+}
+
+var frequencies = map[string]string{
+	report.PeriodWeekly:  "WEEKLY",
+	report.PeriodMonthly: "MONTHLY",
+	report.PeriodYearly:  "YEARLY",
+}
+
+// recurringUID identifies a recurring payment by counterparty and currency
+func recurringUID(p report.RecurringPayment) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(p.Counterparty) + "|" + p.Currency))
+	return "recurring-" + hex.EncodeToString(sum[:8]) + "@ameriagrab"
+}
@@ -0,0 +1,89 @@
+// Package calendar renders upcoming payments as an iCalendar (RFC 5545) feed
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the MIME type of iCalendar feeds
+const ContentType = "text/calendar; charset=utf-8"
+
+// maxLineOctets is the line length limit after which content lines are folded
+const maxLineOctets = 75
+
+// Calendar is a named collection of all-day events
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Event is an all-day event
+type Event struct {
+	UID         string // stable across feed refreshes, so calendar apps update instead of duplicating
+	Date        time.Time
+	Summary     string
+	Description string
+	RRule       string // optional recurrence rule, e.g. "FREQ=MONTHLY;UNTIL=20250101"
+}
+
+// Write renders the calendar in iCalendar format. now is used as the DTSTAMP of events.
+func (c *Calendar) Write(w io.Writer, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//ameriagrab//ameriagrab//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escapeText(c.Name))
+	}
+	for _, e := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", stamp)
+		line("DTSTART;VALUE=DATE", e.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE", e.Date.AddDate(0, 0, 1).Format("20060102"))
+		if e.RRule != "" {
+			line("RRULE", e.RRule)
+		}
+		line("SUMMARY", escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escapeText(e.Description))
+		}
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// escapeText escapes a TEXT property value
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeFolded writes a content line terminated by CRLF, folding it into
+// continuation lines of at most maxLineOctets without splitting UTF-8 sequences
+func writeFolded(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		fmt.Fprintf(w, "%s\r\n ", s[:cut])
+		s = s[cut:]
+		limit = maxLineOctets - 1 // the leading space counts towards the limit
+	}
+	fmt.Fprintf(w, "%s\r\n", s)
+}
@@ -0,0 +1,103 @@
+package calendar
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestWrite(t *testing.T) {
+	cal := &Calendar{
+		Name: "Payments",
+		Events: []Event{{
+			UID:         "x@ameriagrab",
+			Date:        time.Date(2024, 5, 31, 0, 0, 0, 0, time.Local),
+			Summary:     "Rent; flat, 1\\2",
+			Description: strings.Repeat("Երևան ", 20),
+			RRule:       "FREQ=MONTHLY;UNTIL=20250101",
+		}},
+	}
+	var buf bytes.Buffer
+	if err := cal.Write(&buf, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Payments\r\n",
+		"DTSTAMP:20240501T100000Z\r\n",
+		"DTSTART;VALUE=DATE:20240531\r\n",
+		"DTEND;VALUE=DATE:20240601\r\n",
+		"RRULE:FREQ=MONTHLY;UNTIL=20250101\r\n",
+		`SUMMARY:Rent\; flat\, 1\\2` + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	var unfolded strings.Builder
+	for i, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line %d exceeds %d octets: %q", i, maxLineOctets, line)
+		}
+		if strings.HasPrefix(line, " ") {
+			unfolded.WriteString(line[1:])
+		} else {
+			unfolded.WriteString("\n" + line)
+		}
+	}
+	if !strings.Contains(unfolded.String(), "DESCRIPTION:"+cal.Events[0].Description+"\n") {
+		t.Errorf("folded description doesn't unfold to the original:\n%s", unfolded.String())
+	}
+}
+
+func TestFeed(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.ReplaceLoans([]client.LoanDetails{
+		{LoanInfo: client.LoanInfo{ID: "loan1", Name: "Mortgage", Currency: "AMD", NextPaymentDate: "2024-04-20", NextPaymentAmount: 150000}},
+		{LoanInfo: client.LoanInfo{ID: "loan2", Name: "Old loan", Currency: "AMD", NextPaymentDate: "2024-01-20"}},
+	}); err != nil {
+		t.Fatalf("failed to store loans: %v", err)
+	}
+	var txns []client.Transaction
+	for i, month := range []string{"01", "02", "03"} {
+		txns = append(txns, client.Transaction{
+			ID:             fmt.Sprintf("t%d", i),
+			AccountingType: "DEBIT",
+			Amount:         client.Amount{Currency: "AMD", Amount: 5000},
+			Details:        "Netflix",
+			OperationDate:  "2024-" + month + "-05T10:00:00Z",
+		})
+	}
+	if _, err := database.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+
+	cal, err := Feed(database, time.Date(2024, 4, 1, 12, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("Feed failed: %v", err)
+	}
+	if len(cal.Events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", cal.Events)
+	}
+	loan, recurring := cal.Events[0], cal.Events[1]
+	if loan.UID != "loan-loan1-20240420@ameriagrab" || loan.Summary != "Loan payment: Mortgage 150000.00 AMD" {
+		t.Errorf("unexpected loan event %+v", loan)
+	}
+	if recurring.Date.Format("2006-01-02") != "2024-04-05" || recurring.RRule != "FREQ=MONTHLY;UNTIL=20250401" {
+		t.Errorf("unexpected recurring event %+v", recurring)
+	}
+}
@@ -0,0 +1,88 @@
+// Package category assigns spending categories to transactions
+package category
+
+import (
+	"strings"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// Other is the category of transactions no rule matches
+const Other = "other"
+
+// Rule assigns Category to transactions whose counterparty or details
+// contain any of the keywords (case-insensitive)
+type Rule struct {
+	Category string
+	Keywords []string
+}
+
+// DefaultRules are built-in rules for common merchants and transaction descriptions
+var DefaultRules = []Rule{
+	{"groceries", []string{"yerevan city", "sas supermarket", "carrefour", "nor zovq", "parma", "supermarket", "grocery", "market"}},
+	{"restaurants", []string{"restaurant", "cafe", "coffee", "pizza", "burger", "kfc", "mcdonald", "tashir pizza", "wolt", "glovo", "menu.am"}},
+	{"transport", []string{"yandex go", "gg taxi", "taxi", "bolt", "uber", "metro", "parking"}},
+	{"fuel", []string{"fuel", "petrol", "gazprom", "max oil"}},
+	{"utilities", []string{"electric", "veolia", "gas supply", "water", "ucom", "viva", "team telecom", "beeline", "internet"}},
+	{"health", []string{"pharmacy", "apteka", "natali pharm", "alfa pharm", "clinic", "medical", "dental"}},
+	{"shopping", []string{"amazon", "aliexpress", "ebay", "wildberries", "ozon", "zara", "h&m", "store", "shop"}},
+	{"travel", []string{"airline", "airways", "booking.com", "airbnb", "hotel", "flyone", "aeroflot"}},
+	{"entertainment", []string{"netflix", "spotify", "youtube", "apple.com", "steam", "cinema", "kino"}},
+	{"cash", []string{"atm", "cash withdrawal"}},
+	{"fees", []string{"commission", "fee", "service charge"}},
+}
+
+// Icons are the emoji shown next to transactions of each category. They are all
+// double-width, so tables stay aligned.
+var Icons = map[string]string{
+	"groceries":     "🛒",
+	"restaurants":   "🍴",
+	"transport":     "🚕",
+	"fuel":          "⛽",
+	"utilities":     "🏠",
+	"health":        "💊",
+	"shopping":      "🛍",
+	"travel":        "🛫",
+	"entertainment": "🎬",
+	"cash":          "💵",
+	"fees":          "🧾",
+	Other:           "❔",
+}
+
+// Icon returns the icon of a category, or that of Other for unknown ones
+func Icon(category string) string {
+	if icon, ok := Icons[category]; ok {
+		return icon
+	}
+	return Icons[Other]
+}
+
+// Categorizer applies rules in order; the first matching rule wins. Entries
+// no rule matches fall back to the merchant category code mentioned in their
+// details or workflow code (see OfMCC), so that merchants without a rule are
+// categorized too.
+type Categorizer struct {
+	Rules []Rule
+}
+
+// Default returns a categorizer using DefaultRules
+func Default() *Categorizer {
+	return &Categorizer{Rules: DefaultRules}
+}
+
+// Categorize returns the category of an entry, or Other if neither a rule nor
+// its merchant category code match
+func (c *Categorizer) Categorize(e db.LedgerEntry) string {
+	text := strings.ToLower(e.Counterparty + " " + e.Details)
+	for _, r := range c.Rules {
+		for _, kw := range r.Keywords {
+			if strings.Contains(text, kw) {
+				return r.Category
+			}
+		}
+	}
+	if code := MCC(e.Details + " " + e.Workflow); code != 0 {
+		return OfMCC(code)
+	}
+	return Other
+}
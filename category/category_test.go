@@ -0,0 +1,95 @@
+package category
+
+import (
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/textwidth"
+)
+
+func TestCategorize(t *testing.T) {
+	c := Default()
+	tests := []struct {
+		entry db.LedgerEntry
+		want  string
+	}{
+		{db.LedgerEntry{Counterparty: "YEREVAN CITY 12"}, "groceries"},
+		{db.LedgerEntry{Counterparty: "Coffee House"}, "restaurants"}, // not "fees"
+		{db.LedgerEntry{Counterparty: "Card", Details: "ATM withdrawal"}, "cash"},
+		{db.LedgerEntry{Counterparty: "John Smith"}, Other},
+		{db.LedgerEntry{Counterparty: "ARARAT LLC", Details: "PURCHASE MCC 5411"}, "groceries"},
+		{db.LedgerEntry{Counterparty: "ARARAT LLC", Workflow: "CARD_PURCHASE_MCC_5542"}, "fuel"},
+		{db.LedgerEntry{Counterparty: "Tashir Pizza", Details: "MCC:5411"}, "restaurants"}, // rules win
+		{db.LedgerEntry{Counterparty: "ARARAT LLC", Details: "MCC 1234"}, Other},
+	}
+	for _, tt := range tests {
+		if got := c.Categorize(tt.entry); got != tt.want {
+			t.Errorf("%+v: expected %q, got %q", tt.entry, tt.want, got)
+		}
+	}
+}
+
+func TestIcons(t *testing.T) {
+	for _, r := range append(DefaultRules, Rule{Category: Other}) {
+		icon, ok := Icons[r.Category]
+		if !ok {
+			t.Errorf("category %q has no icon", r.Category)
+			continue
+		}
+		// Icons must all be as wide as each other to keep tables aligned
+		if w := textwidth.Width(icon); w != 2 {
+			t.Errorf("icon of %q is %d columns wide, expected 2", r.Category, w)
+		}
+	}
+	if Icon("no such category") != Icons[Other] {
+		t.Error("unknown categories should get the icon of Other")
+	}
+}
+
+func TestOfMCC(t *testing.T) {
+	for code, want := range map[int]string{
+		3005: "travel",
+		5411: "groceries",
+		5542: "fuel",
+		5812: "restaurants",
+		5912: "health", // within 5900-5999 shopping, listed later
+		5999: "shopping",
+		6011: "cash",
+		1520: Other,
+	} {
+		if got := OfMCC(code); got != want {
+			t.Errorf("OfMCC(%d) = %q, want %q", code, got, want)
+		}
+	}
+
+	for _, r := range mccRanges {
+		if _, ok := Icons[r.category]; !ok || r.category == Other {
+			t.Errorf("MCC range %d-%d has unknown category %q", r.from, r.to, r.category)
+		}
+	}
+
+	for text, want := range map[string]int{
+		"POS PURCHASE MCC 5812 YEREVAN": 5812,
+		"mcc=4121":                      4121,
+		"MCC5411":                       5411,
+		"EMCC 5411":                     0,
+		"MCC 54112":                     0,
+		"no code":                       0,
+	} {
+		if got := MCC(text); got != want {
+			t.Errorf("MCC(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestParseMCCTable(t *testing.T) {
+	ranges, err := parseMCCTable("# comment\n\n5411 groceries # supermarkets\n5811-5814 restaurants\n")
+	if err != nil || len(ranges) != 2 || ranges[1] != (mccRange{5811, 5814, "restaurants"}) {
+		t.Errorf("unexpected ranges %+v (err %v)", ranges, err)
+	}
+	for _, table := range []string{"5411", "54x1 groceries", "5814-5811 restaurants"} {
+		if _, err := parseMCCTable(table); err == nil {
+			t.Errorf("expected an error for %q", table)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package category
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//go:embed mcc.txt
+var mccTable string
+
+// mccRange assigns category to merchant category codes from..to
+type mccRange struct {
+	from, to int
+	category string
+}
+
+// mccRanges are the ranges of mcc.txt, in file order
+var mccRanges = mustParseMCCTable(mccTable)
+
+// mccRe finds a merchant category code in transaction details or workflow
+// codes, e.g. "MCC 5411", "mcc:5411" or "PURCHASE_MCC_5411"
+var mccRe = regexp.MustCompile(`(?i)(?:^|[^a-z])mcc[\s:=_#-]*(\d{4})\b`)
+
+// MCC returns the merchant category code mentioned in text, or 0
+func MCC(text string) int {
+	m := mccRe.FindStringSubmatch(text)
+	if m == nil {
+		return 0
+	}
+	code, _ := strconv.Atoi(m[1])
+	return code
+}
+
+// OfMCC returns the category of a merchant category code, or Other for
+// unknown codes
+func OfMCC(code int) string {
+	category := Other
+	for _, r := range mccRanges {
+		if code >= r.from && code <= r.to {
+			category = r.category
+		}
+	}
+	return category
+}
+
+// parseMCCTable parses lines of "code[-code] category [# comment]"
+func parseMCCTable(table string) ([]mccRange, error) {
+	var ranges []mccRange
+	scanner := bufio.NewScanner(strings.NewReader(table))
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("mcc.txt:%d: expected a code or range and a category", line)
+		}
+		from, to, isRange := strings.Cut(fields[0], "-")
+		if !isRange {
+			to = from
+		}
+		r := mccRange{category: fields[1]}
+		var err error
+		if r.from, err = strconv.Atoi(from); err != nil {
+			return nil, fmt.Errorf("mcc.txt:%d: %w", line, err)
+		}
+		if r.to, err = strconv.Atoi(to); err != nil {
+			return nil, fmt.Errorf("mcc.txt:%d: %w", line, err)
+		}
+		if r.from > r.to {
+			return nil, fmt.Errorf("mcc.txt:%d: empty range %s", line, fields[0])
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, scanner.Err()
+}
+
+func mustParseMCCTable(table string) []mccRange {
+	ranges, err := parseMCCTable(table)
+	if err != nil {
+		panic(err)
+	}
+	return ranges
+}
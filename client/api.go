@@ -5,13 +5,46 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// sinceParam returns the query parameter restricting history to dates from since on,
+// or an empty string for the zero time
+func sinceParam(since time.Time) string {
+	if since.IsZero() {
+		return ""
+	}
+	return "&fromDate=" + since.Format("2006-01-02")
+}
+
+// envelopeError returns an error for a response envelope whose status isn't
+// SUCCESS, even though the HTTP request itself succeeded
+func envelopeError(status string, errorMessages interface{}) error {
+	if status == "" || strings.EqualFold(status, "success") {
+		return nil
+	}
+	if errorMessages != nil {
+		return fmt.Errorf("status %s: %v", status, errorMessages)
+	}
+	return fmt.Errorf("status %s", status)
+}
+
+// untilParam returns the query parameter restricting history to dates up to and
+// including until, or an empty string for the zero time
+func untilParam(until time.Time) string {
+	if until.IsZero() {
+		return ""
+	}
+	return "&toDate=" + until.Format("2006-01-02")
+}
+
 // GetTransactions fetches settled transactions for a card
 func (c *Client) GetTransactions(accessToken, cardID string) (*TransactionsResponse, error) {
 	txnURL := fmt.Sprintf("%s/api/events/settled/%s", c.APIBaseURL, cardID)
 
-	req, err := http.NewRequest("GET", txnURL, nil)
+	req, err := c.newRequest("GET", txnURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transactions request: %w", err)
 	}
@@ -40,11 +73,44 @@ func (c *Client) GetTransactions(accessToken, cardID string) (*TransactionsRespo
 	return &txnResp, nil
 }
 
-// GetAccountsAndCards fetches all accounts and cards
-func (c *Client) GetAccountsAndCards(accessToken string) (*AccountsAndCardsResponse, error) {
+// GetEventsUpcoming fetches pending authorizations (holds) of a card that are not settled yet
+func (c *Client) GetEventsUpcoming(accessToken, cardID string) (*TransactionsResponse, error) {
+	url := fmt.Sprintf("%s/api/events/upcoming/%s", c.APIBaseURL, cardID)
+
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upcoming events request: %w", err)
+	}
+	c.AddAPIHeaders(req, accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch upcoming events: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upcoming events response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upcoming events request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result TransactionsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse upcoming events response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// fetchAccountsAndCards fetches all accounts and cards from the API
+func (c *Client) fetchAccountsAndCards(accessToken string) (*AccountsAndCardsResponse, error) {
 	url := fmt.Sprintf("%s/api/accounts-and-cards?page=0&size=100&skipApplications=false&specifications=SIMPLE&isFullList=true", c.APIBaseURL)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create accounts-and-cards request: %w", err)
 	}
@@ -73,11 +139,19 @@ func (c *Client) GetAccountsAndCards(accessToken string) (*AccountsAndCardsRespo
 	return &result, nil
 }
 
-// GetAccountHistory fetches transaction history for an account
-func (c *Client) GetAccountHistory(accessToken, accountID string, size, page int) (*HistoryResponse, error) {
-	url := fmt.Sprintf("%s/api/history?accountIds=%s&size=%d&page=%d", c.APIBaseURL, accountID, size, page)
+// GetAccountHistory fetches transaction history for an account, newest first.
+// A non-zero since limits the history to transactions from that date on.
+func (c *Client) GetAccountHistory(accessToken, accountID string, size, page int, since time.Time) (*HistoryResponse, error) {
+	return c.GetAccountHistoryRange(accessToken, accountID, size, page, since, time.Time{})
+}
+
+// GetAccountHistoryRange fetches the transaction history of an account between the
+// dates from and to (inclusive), newest first. A zero from or to leaves that end open.
+func (c *Client) GetAccountHistoryRange(accessToken, accountID string, size, page int, from, to time.Time) (*HistoryResponse, error) {
+	url := fmt.Sprintf("%s/api/history?accountIds=%s&size=%d&page=%d", c.APIBaseURL, accountID, size, page) +
+		sinceParam(from) + untilParam(to)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create history request: %w", err)
 	}
@@ -106,12 +180,21 @@ func (c *Client) GetAccountHistory(accessToken, accountID string, size, page int
 	return &result, nil
 }
 
-// GetEventsPast fetches past events/transactions for an account (works with card-linked accounts)
-func (c *Client) GetEventsPast(accessToken, accountID string, size, page int) (*TransactionsResponse, error) {
-	url := fmt.Sprintf("%s/api/events/past?locale=ru&fromAmount=0.1&accountIds=%s&sort=date&size=%d&page=%d",
-		c.APIBaseURL, accountID, size, page)
+// GetEventsPast fetches past events/transactions for an account (works with card-linked accounts),
+// newest first. A non-zero since limits the events to those from that date on.
+// Events below c.EventsFromAmount are left out.
+func (c *Client) GetEventsPast(accessToken, accountID string, size, page int, since time.Time) (*TransactionsResponse, error) {
+	return c.GetEventsPastRange(accessToken, accountID, size, page, since, time.Time{})
+}
+
+// GetEventsPastRange fetches past events of an account between the dates from and
+// to (inclusive), newest first. A zero from or to leaves that end open.
+func (c *Client) GetEventsPastRange(accessToken, accountID string, size, page int, from, to time.Time) (*TransactionsResponse, error) {
+	url := fmt.Sprintf("%s/api/events/past?locale=ru&fromAmount=%s&accountIds=%s&sort=date&size=%d&page=%d",
+		c.APIBaseURL, strconv.FormatFloat(c.EventsFromAmount, 'f', -1, 64), accountID, size, page) +
+		sinceParam(from) + untilParam(to)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create events/past request: %w", err)
 	}
@@ -144,7 +227,7 @@ func (c *Client) GetEventsPast(accessToken, accountID string, size, page int) (*
 func (c *Client) GetTransactionDetails(accessToken, transactionID string) (*TransactionDetailsResponse, error) {
 	url := fmt.Sprintf("%s/api/transactions/%s", c.APIBaseURL, transactionID)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transaction details request: %w", err)
 	}
@@ -178,7 +261,7 @@ func (c *Client) GetAvailableBalance(accessToken, productType, productID string)
 	url := fmt.Sprintf("%s/api/accounts-and-cards/available-balance?productType=%s&productId=%s",
 		c.APIBaseURL, productType, productID)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create available-balance request: %w", err)
 	}
@@ -207,11 +290,43 @@ func (c *Client) GetAvailableBalance(accessToken, productType, productID string)
 	return &result, nil
 }
 
-// GetTemplates fetches transfer templates
+// GetTemplates fetches transfer templates, both ungrouped ones and those in template
+// groups. Grouped templates are included in Data.Templates with their group set.
 func (c *Client) GetTemplates(accessToken string) (*TemplatesResponse, error) {
-	url := fmt.Sprintf("%s/api/templates?page=1&size=1000&hasGroup=false", c.APIBaseURL)
+	result, err := c.getTemplates(accessToken, false)
+	if err != nil {
+		return nil, err
+	}
+	grouped, err := c.getTemplates(accessToken, true)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(result.Data.Templates))
+	for i, t := range result.Data.Templates {
+		index[t.ID] = i
+	}
+	for _, g := range grouped.Data.Groups {
+		for _, t := range g.Templates {
+			t.GroupID, t.GroupName = g.ID, g.Name
+			if i, ok := index[t.ID]; ok {
+				result.Data.Templates[i] = t
+				continue
+			}
+			index[t.ID] = len(result.Data.Templates)
+			result.Data.Templates = append(result.Data.Templates, t)
+		}
+	}
+	result.Data.Groups = grouped.Data.Groups
 
-	req, err := http.NewRequest("GET", url, nil)
+	return result, nil
+}
+
+// getTemplates fetches either ungrouped templates or template groups
+func (c *Client) getTemplates(accessToken string, hasGroup bool) (*TemplatesResponse, error) {
+	url := fmt.Sprintf("%s/api/templates?page=1&size=1000&hasGroup=%t", c.APIBaseURL, hasGroup)
+
+	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create templates request: %w", err)
 	}
@@ -239,3 +354,362 @@ func (c *Client) GetTemplates(accessToken string) (*TemplatesResponse, error) {
 
 	return &templatesResult, nil
 }
+
+// GetUtilityPayments fetches a page of utility payment history (electricity, gas,
+// phone, ...), newest first, optionally restricted to payments from since on
+func (c *Client) GetUtilityPayments(accessToken string, size, page int, since time.Time) (*UtilityPaymentsResponse, error) {
+	url := fmt.Sprintf("%s/api/utility-payments/history?page=%d&size=%d", c.APIBaseURL, page, size) +
+		sinceParam(since)
+
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create utility payments request: %w", err)
+	}
+	c.AddAPIHeaders(req, accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch utility payments: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read utility payments response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("utility payments request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result UtilityPaymentsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse utility payments response: %w", err)
+	}
+	if err := envelopeError(result.Status, result.ErrorMessages); err != nil {
+		return nil, fmt.Errorf("utility payments request failed: %w", err)
+	}
+
+	return &result, nil
+}
+
+// GetRequisites fetches the requisites (IBAN, SWIFT, bank details) of an account
+func (c *Client) GetRequisites(accessToken, accountID string) (*RequisitesResponse, error) {
+	url := fmt.Sprintf("%s/api/accounts/%s/requisites", c.APIBaseURL, accountID)
+
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create requisites request: %w", err)
+	}
+	c.AddAPIHeaders(req, accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch requisites: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read requisites response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requisites request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result RequisitesResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse requisites response: %w", err)
+	}
+	if err := envelopeError(result.Status, result.ErrorMessages); err != nil {
+		return nil, fmt.Errorf("requisites request failed: %w", err)
+	}
+
+	return &result, nil
+}
+
+// DownloadStatement fetches the official statement the bank generates for a
+// product between from and to (inclusive) as a PDF or XLS file
+func (c *Client) DownloadStatement(accessToken, productType, productID string, from, to time.Time, format string) ([]byte, error) {
+	url := fmt.Sprintf("%s/api/statements/download?productType=%s&productId=%s&format=%s",
+		c.APIBaseURL, productType, productID, format) + sinceParam(from) + untilParam(to)
+
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create statement request: %w", err)
+	}
+	c.AddAPIHeaders(req, accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch statement: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read statement response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("statement request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	// Errors come back as a JSON envelope rather than a file
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var result StatementErrorResponse
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse statement response: %w", err)
+		}
+		if err := envelopeError(result.Status, result.ErrorMessages); err != nil {
+			return nil, fmt.Errorf("statement request failed: %w", err)
+		}
+		return nil, fmt.Errorf("statement request returned no file")
+	}
+
+	return body, nil
+}
+
+// GetExchangeRates fetches the bank's current cash, non-cash and card exchange rates
+func (c *Client) GetExchangeRates(accessToken string) (*ExchangeRatesResponse, error) {
+	url := fmt.Sprintf("%s/api/exchange-rates", c.APIBaseURL)
+
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exchange rates request: %w", err)
+	}
+	c.AddAPIHeaders(req, accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exchange rates request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result ExchangeRatesResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates response: %w", err)
+	}
+	if err := envelopeError(result.Status, result.ErrorMessages); err != nil {
+		return nil, fmt.Errorf("exchange rates request failed: %w", err)
+	}
+
+	return &result, nil
+}
+
+// GetLoans fetches all loans
+func (c *Client) GetLoans(accessToken string) (*LoansResponse, error) {
+	url := fmt.Sprintf("%s/api/loans", c.APIBaseURL)
+
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create loans request: %w", err)
+	}
+	c.AddAPIHeaders(req, accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch loans: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read loans response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("loans request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result LoansResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse loans response: %w", err)
+	}
+	if err := envelopeError(result.Status, result.ErrorMessages); err != nil {
+		return nil, fmt.Errorf("loans request failed: %w", err)
+	}
+
+	return &result, nil
+}
+
+// GetLoanDetails fetches a loan with its payment schedule
+func (c *Client) GetLoanDetails(accessToken, loanID string) (*LoanDetailsResponse, error) {
+	url := fmt.Sprintf("%s/api/loans/%s", c.APIBaseURL, loanID)
+
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create loan details request: %w", err)
+	}
+	c.AddAPIHeaders(req, accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch loan details: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read loan details response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("loan details request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result LoanDetailsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse loan details response: %w", err)
+	}
+	if err := envelopeError(result.Status, result.ErrorMessages); err != nil {
+		return nil, fmt.Errorf("loan details request failed: %w", err)
+	}
+
+	return &result, nil
+}
+
+// GetDeposits fetches all deposits
+func (c *Client) GetDeposits(accessToken string) (*DepositsResponse, error) {
+	url := fmt.Sprintf("%s/api/deposits", c.APIBaseURL)
+
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deposits request: %w", err)
+	}
+	c.AddAPIHeaders(req, accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deposits: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deposits response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("deposits request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result DepositsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse deposits response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// GetGoals fetches savings goals and card stashes
+func (c *Client) GetGoals(accessToken string) (*GoalsResponse, error) {
+	url := fmt.Sprintf("%s/api/savings-goals", c.APIBaseURL)
+
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goals request: %w", err)
+	}
+	c.AddAPIHeaders(req, accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch goals: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read goals response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("goals request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result GoalsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse goals response: %w", err)
+	}
+	if err := envelopeError(result.Status, result.ErrorMessages); err != nil {
+		return nil, fmt.Errorf("goals request failed: %w", err)
+	}
+
+	return &result, nil
+}
+
+// GetDepositDetails fetches the terms of a deposit
+func (c *Client) GetDepositDetails(accessToken, depositID string) (*DepositDetailsResponse, error) {
+	url := fmt.Sprintf("%s/api/deposits/%s", c.APIBaseURL, depositID)
+
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deposit details request: %w", err)
+	}
+	c.AddAPIHeaders(req, accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deposit details: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deposit details response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("deposit details request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result DepositDetailsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse deposit details response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// GetDepositSchedule fetches the past and scheduled interest payments of a deposit
+func (c *Client) GetDepositSchedule(accessToken, depositID string) (*DepositScheduleResponse, error) {
+	url := fmt.Sprintf("%s/api/deposits/%s/schedule", c.APIBaseURL, depositID)
+
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deposit schedule request: %w", err)
+	}
+	c.AddAPIHeaders(req, accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deposit schedule: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deposit schedule response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("deposit schedule request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result DepositScheduleResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse deposit schedule response: %w", err)
+	}
+
+	return &result, nil
+}
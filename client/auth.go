@@ -3,18 +3,29 @@ package client
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
-	"regexp"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrPushTimeout is returned when the push notification 2FA isn't confirmed in time
+var ErrPushTimeout = errors.New("push confirmation timed out")
+
+// ErrLoginRejected is returned when the bank rejects the credentials or the
+// push confirmation is declined; trying again won't help until the user acts
+var ErrLoginRejected = errors.New("login rejected")
+
+// ErrUnsupportedApprovalFlow is returned when the bank asks to confirm the
+// login other than by a push notification, e.g. with a link or QR code
+var ErrUnsupportedApprovalFlow = errors.New("unsupported approval flow")
+
 // Login performs the full OAuth login flow with push notification 2FA
 func (c *Client) Login() (string, error) {
 	// Step 1: Get the login page to extract the action URL
@@ -30,7 +41,7 @@ func (c *Client) Login() (string, error) {
 		nonce,
 	)
 
-	req, err := http.NewRequest("GET", authURL, nil)
+	req, err := c.newRequest("GET", authURL, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create auth request: %w", err)
 	}
@@ -57,14 +68,12 @@ func (c *Client) Login() (string, error) {
 		return "", fmt.Errorf("failed to read login page: %w", err)
 	}
 
-	// Extract actionUrl from the login page
-	actionURLRegex := regexp.MustCompile(`actionUrl:\s*"([^"]+)"`)
-	matches := actionURLRegex.FindSubmatch(body)
-	if len(matches) < 2 {
+	// Extract the form action URL from the login page
+	actionURL := parseLoginPage(body).ActionURL
+	if actionURL == "" {
 		c.SaveDebugFile("login_page.html", body)
 		return "", fmt.Errorf("failed to find actionUrl in login page")
 	}
-	actionURL := string(matches[1])
 	fmt.Fprintf(os.Stderr, "Debug: Action URL: %s\n", actionURL)
 
 	// Step 2: Submit login credentials
@@ -77,7 +86,7 @@ func (c *Client) Login() (string, error) {
 	encodedForm := formData.Encode()
 	fmt.Fprintf(os.Stderr, "Debug: Form data (length %d)\n", len(encodedForm))
 
-	req, err = http.NewRequest("POST", actionURL, strings.NewReader(encodedForm))
+	req, err = c.newRequest("POST", actionURL, strings.NewReader(encodedForm))
 	if err != nil {
 		return "", fmt.Errorf("failed to create login request: %w", err)
 	}
@@ -108,47 +117,40 @@ func (c *Client) Login() (string, error) {
 		return "", fmt.Errorf("failed to read login response: %w", err)
 	}
 
-	// Check for error messages in the response
-	errorRegex := regexp.MustCompile(`(?i)error|invalid|incorrect|failed`)
-	if errorRegex.Match(body) {
-		// Look for specific error message
-		msgRegex := regexp.MustCompile(`"message"\s*:\s*"([^"]+)"`)
-		if msgMatch := msgRegex.FindSubmatch(body); len(msgMatch) >= 2 {
-			fmt.Fprintf(os.Stderr, "Debug: Error message found: %s\n", string(msgMatch[1]))
-		}
+	page := parseLoginPage(body)
+	if page.Message != "" {
+		fmt.Fprintf(os.Stderr, "Debug: Message found: %s\n", page.Message)
 	}
-
-	// Debug: print response status and check template type
-	templateRegex := regexp.MustCompile(`template:\s*"([^"]+)"`)
-	if tmplMatch := templateRegex.FindSubmatch(body); len(tmplMatch) >= 2 {
-		fmt.Fprintf(os.Stderr, "Debug: Template type: %s\n", string(tmplMatch[1]))
+	if page.Template != "" {
+		fmt.Fprintf(os.Stderr, "Debug: Template type: %s\n", page.Template)
 	}
 
 	// Extract push session ID and new action URL
-	sessionIDRegex := regexp.MustCompile(`external_system_request_id.*?=\s*"([^"]+)"`)
-	matches = sessionIDRegex.FindSubmatch(body)
-	if len(matches) < 2 {
+	pushSessionID := page.PushSessionID
+	if pushSessionID == "" {
 		c.SaveDebugFile("debug_response.html", body)
+		if err := page.unsupportedFlow(); err != nil {
+			return "", err
+		}
+		if err := page.rejected(); err != nil {
+			return "", err
+		}
 		return "", fmt.Errorf("failed to find push session ID in response. Login may have failed. Response preview: %s", string(body[:Min(1000, len(body))]))
 	}
-	pushSessionID := string(matches[1])
 
 	// Extract evaluatedRequestId for the second POST
-	evalReqIDRegex := regexp.MustCompile(`evaluatedRequestId:\s*\\?"([^"\\]+)`)
-	evalMatches := evalReqIDRegex.FindSubmatch(body)
-	if len(evalMatches) < 2 {
+	evaluatedRequestID := page.EvaluatedRequestID
+	if evaluatedRequestID == "" {
 		c.SaveDebugFile("debug_response.html", body)
 		return "", fmt.Errorf("failed to find evaluatedRequestId in response")
 	}
-	evaluatedRequestID := string(evalMatches[1])
 	fmt.Fprintf(os.Stderr, "Debug: evaluatedRequestId: %s\n", evaluatedRequestID)
 
 	// Extract new actionUrl for the second POST
-	matches = actionURLRegex.FindSubmatch(body)
-	if len(matches) < 2 {
+	pushActionURL := page.ActionURL
+	if pushActionURL == "" {
 		return "", fmt.Errorf("failed to find actionUrl for push confirmation")
 	}
-	pushActionURL := string(matches[1])
 
 	// Step 3: Wait for push notification confirmation
 	fmt.Println("Waiting for push notification confirmation on your phone...")
@@ -170,7 +172,7 @@ func (c *Client) Login() (string, error) {
 
 	fmt.Fprintf(os.Stderr, "Debug: Push form - evaluated_request_id=%s, totp=%s\n", evaluatedRequestID, pushSessionID)
 
-	req, err = http.NewRequest("POST", pushActionURL, strings.NewReader(pushFormData.Encode()))
+	req, err = c.newRequest("POST", pushActionURL, strings.NewReader(pushFormData.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("failed to create push confirmation request: %w", err)
 	}
@@ -203,12 +205,10 @@ func (c *Client) Login() (string, error) {
 	fmt.Fprintf(os.Stderr, "Debug: Redirect location: %s\n", location)
 
 	// Extract authorization code from redirect URL fragment
-	codeRegex := regexp.MustCompile(`code=([^&]+)`)
-	codeMatches := codeRegex.FindStringSubmatch(location)
-	if len(codeMatches) < 2 {
-		return "", fmt.Errorf("failed to extract authorization code from redirect: %s", location)
+	authCode, err := authorizationCode(location)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract authorization code: %w", err)
 	}
-	authCode := codeMatches[1]
 
 	// Step 5: Exchange authorization code for access token
 	return c.exchangeCodeForToken(authCode)
@@ -220,10 +220,10 @@ func (c *Client) waitForPushConfirmation(sessionID string) error {
 	startTime := time.Now()
 	for {
 		if time.Since(startTime) > PollTimeout {
-			return fmt.Errorf("push confirmation timed out after %v", PollTimeout)
+			return fmt.Errorf("%w after %v", ErrPushTimeout, PollTimeout)
 		}
 
-		req, err := http.NewRequest("GET", pushStatusURL, nil)
+		req, err := c.newRequest("GET", pushStatusURL, nil)
 		if err != nil {
 			return fmt.Errorf("failed to create push status request: %w", err)
 		}
@@ -255,12 +255,12 @@ func (c *Client) waitForPushConfirmation(sessionID string) error {
 		case "accepted":
 			return nil
 		case "pending":
-			fmt.Print(".")
+			fmt.Fprint(os.Stderr, ".")
 			time.Sleep(PollInterval)
 		case "rejected":
-			return fmt.Errorf("push notification was rejected")
+			return fmt.Errorf("%w: the push notification was declined", ErrLoginRejected)
 		case "expired":
-			return fmt.Errorf("push notification expired")
+			return fmt.Errorf("push notification expired: %w", ErrPushTimeout)
 		default:
 			return fmt.Errorf("unexpected push status: %s", status.Data.SessionStatus)
 		}
@@ -275,7 +275,7 @@ func (c *Client) exchangeCodeForToken(code string) (string, error) {
 	formData.Set("grant_type", "authorization_code")
 	formData.Set("redirect_uri", RedirectURI)
 
-	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(formData.Encode()))
+	req, err := c.newRequest("POST", tokenURL, strings.NewReader(formData.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("failed to create token request: %w", err)
 	}
@@ -346,69 +346,79 @@ func (c *Client) exchangeCodeForToken(code string) (string, error) {
 func (c *Client) InitializeSession(accessToken string) error {
 	fmt.Fprintf(os.Stderr, "Debug: Initializing session...\n")
 
+	clients, err := c.GetClients(accessToken)
+	if err != nil {
+		return err
+	}
+
+	// Find the default client or use the first one
+	for _, client := range clients {
+		if client.Default {
+			c.ClientID = client.ID
+			break
+		}
+	}
+	if c.ClientID == "" && len(clients) > 0 {
+		c.ClientID = clients[0].ID
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("no client found in clients response")
+	}
+
+	fmt.Fprintf(os.Stderr, "Debug: Client ID set to: %s\n", c.ClientID)
+	fmt.Fprintf(os.Stderr, "Debug: Session initialized successfully\n")
+	return nil
+}
+
+// GetClients fetches the clients (personal and company) the user can act for
+func (c *Client) GetClients(accessToken string) ([]ClientInfo, error) {
 	// Step 1: Get user info to get user ID
-	req, err := http.NewRequest("GET", c.APIBaseURL+"/api/users/info", nil)
+	req, err := c.newRequest("GET", c.APIBaseURL+"/api/users/info", nil)
 	if err != nil {
-		return fmt.Errorf("failed to create user info request: %w", err)
+		return nil, fmt.Errorf("failed to create user info request: %w", err)
 	}
 	c.AddAPIHeaders(req, accessToken)
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to fetch user info: %w", err)
+		return nil, fmt.Errorf("failed to fetch user info: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("user info request failed with status %d: %s", resp.StatusCode, string(body[:Min(200, len(body))]))
+		return nil, fmt.Errorf("user info request failed with status %d: %s", resp.StatusCode, string(body[:Min(200, len(body))]))
 	}
 
 	var userInfo UserInfoResponse
 	if err := json.Unmarshal(body, &userInfo); err != nil {
-		return fmt.Errorf("failed to parse user info: %w", err)
+		return nil, fmt.Errorf("failed to parse user info: %w", err)
 	}
 	userID := userInfo.Data.UserInfo.ID
 	fmt.Fprintf(os.Stderr, "Debug: User ID: %s\n", userID)
 
 	// Step 2: Get clients to find the real Client-Id
-	req, err = http.NewRequest("GET", fmt.Sprintf("%s/api/users/%s/clients", c.APIBaseURL, userID), nil)
+	req, err = c.newRequest("GET", fmt.Sprintf("%s/api/users/%s/clients", c.APIBaseURL, userID), nil)
 	if err != nil {
-		return fmt.Errorf("failed to create clients request: %w", err)
+		return nil, fmt.Errorf("failed to create clients request: %w", err)
 	}
 	c.AddAPIHeaders(req, accessToken)
 
 	resp, err = c.HTTPClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to fetch clients: %w", err)
+		return nil, fmt.Errorf("failed to fetch clients: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, _ = io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("clients request failed with status %d: %s", resp.StatusCode, string(body[:Min(200, len(body))]))
+		return nil, fmt.Errorf("clients request failed with status %d: %s", resp.StatusCode, string(body[:Min(200, len(body))]))
 	}
 
 	var clientsResp ClientsResponse
 	if err := json.Unmarshal(body, &clientsResp); err != nil {
-		return fmt.Errorf("failed to parse clients: %w", err)
-	}
-
-	// Find the default client or use the first one
-	for _, client := range clientsResp.Data.Clients {
-		if client.Default {
-			c.ClientID = client.ID
-			break
-		}
-	}
-	if c.ClientID == "" && len(clientsResp.Data.Clients) > 0 {
-		c.ClientID = clientsResp.Data.Clients[0].ID
-	}
-	if c.ClientID == "" {
-		return fmt.Errorf("no client found in clients response")
+		return nil, fmt.Errorf("failed to parse clients: %w", err)
 	}
 
-	fmt.Fprintf(os.Stderr, "Debug: Client ID set to: %s\n", c.ClientID)
-	fmt.Fprintf(os.Stderr, "Debug: Session initialized successfully\n")
-	return nil
+	return clientsResp.Data.Clients, nil
 }
@@ -1,11 +1,12 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/cookiejar"
 	"os"
-	"path/filepath"
 	"strings"
 )
 
@@ -45,7 +46,7 @@ func NewClient(username, password string, sessionStorage SessionStorage, debugDi
 
 	// Set up debug directory (if provided)
 	if debugDir != "" {
-		if err := os.MkdirAll(debugDir, 0755); err != nil {
+		if err := os.MkdirAll(debugDir, 0700); err != nil {
 			return nil, fmt.Errorf("failed to create debug directory: %w", err)
 		}
 		c.DebugDir = debugDir
@@ -55,17 +56,17 @@ func NewClient(username, password string, sessionStorage SessionStorage, debugDi
 	return c, nil
 }
 
-// SaveDebugFile saves content to a debug file if debug dir is configured
-func (c *Client) SaveDebugFile(filename string, content []byte) {
-	if c.DebugDir == "" {
-		return
-	}
-	path := filepath.Join(c.DebugDir, filename)
-	if err := os.WriteFile(path, content, 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to save debug file %s: %v\n", path, err)
-	} else {
-		fmt.Fprintf(os.Stderr, "Debug: Saved debug file to %s\n", path)
+// context returns the context requests are made with
+func (c *Client) context() context.Context {
+	if c.Context == nil {
+		return context.Background()
 	}
+	return c.Context
+}
+
+// newRequest creates a request bound to c.Context
+func (c *Client) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(c.context(), method, url, body)
 }
 
 // Min returns the minimum of two integers
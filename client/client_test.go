@@ -1,11 +1,15 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -273,6 +277,86 @@ func TestSaveDebugFile(t *testing.T) {
 	}
 }
 
+func TestSaveDebugFile_Redacted(t *testing.T) {
+	tmpDir := t.TempDir()
+	c, _ := NewClient("testuser", "testpass", nil, tmpDir)
+
+	// An existing world-readable file is replaced
+	path := filepath.Join(tmpDir, "page.html")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.SaveDebugFile("page.html", []byte(`<input type="text" name="username" value="testuser"><input type="password" value="testpass">`))
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat debug file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected permissions 0600, got %o", perm)
+	}
+	content, _ := os.ReadFile(path)
+	if strings.Contains(string(content), "testuser") || strings.Contains(string(content), "testpass") {
+		t.Errorf("credentials not redacted: %s", content)
+	}
+}
+
+func TestRedactDebug(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"json token", `{"access_token": "abc.def", "code": "OK"}`, `{"access_token": "[REDACTED]", "code": "OK"}`},
+		{"query", `https://myameria.am/#state=1&session_state=s1&code=c2`, `https://myameria.am/#state=1&session_state=[REDACTED]&code=[REDACTED]`},
+		{"form action", `action="https://login/auth?session_code=xyz&amp;tab_id=t"`, `action="https://login/auth?session_code=[REDACTED]&amp;tab_id=[REDACTED]"`},
+		{"hidden input", `<input type="hidden" value="abc" name="evaluatedRequestId"/>`, `<input type="hidden" value="[REDACTED]" name="evaluatedRequestId"/>`},
+		{"bearer", `Authorization: Bearer abc123`, `Authorization: Bearer [REDACTED]`},
+		{"jwt", `token eyJhbGciOi.eyJzdWIiOi.c2lnbmF0dXJl here`, `token [REDACTED] here`},
+		{"card number", `card 4083 1234 5678 6615 and account 1570000000001234`, `card ****6615 and account ****1234`},
+		{"timestamp", `"transactionDate": 1718000000000`, `"transactionDate": 1718000000000`},
+		{"masked card", `"cardNumber": "4083****6615"`, `"cardNumber": "[REDACTED]"`},
+		{"secrets", `user jdoe logged in`, `user [REDACTED] logged in`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(RedactDebug([]byte(tt.in), "jdoe")); got != tt.want {
+				t.Errorf("RedactDebug(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneDebugDir(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	write := func(name string, size int, age time.Duration) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, size), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("expired.html", 10, 8*24*time.Hour)
+	write("notes.md", 10, 30*24*time.Hour) // not a debug file
+	write("old.json", debugMaxDirSize/2, 3*time.Hour)
+	write("older.json", debugMaxDirSize/2, 4*time.Hour)
+	write("new.html", 100, time.Minute)
+
+	if err := pruneDebugDir(dir, now); err != nil {
+		t.Fatalf("pruneDebugDir failed: %v", err)
+	}
+	var names []string
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if got := strings.Join(names, " "); got != "new.html notes.md old.json" {
+		t.Errorf("expected new.html notes.md old.json to remain, got %s", got)
+	}
+}
+
 func TestSaveDebugFile_NoDebugDir(t *testing.T) {
 	c, _ := NewClient("testuser", "testpass", nil, "")
 
@@ -459,19 +543,60 @@ func mockAPIServer(t *testing.T) *httptest.Server {
 			json.NewEncoder(w).Encode(ClientsResponse{
 				Status: "SUCCESS",
 				Data: struct {
-					Clients []struct {
-						ID      string `json:"id"`
-						Default bool   `json:"default"`
-					} `json:"clients"`
+					Clients []ClientInfo `json:"clients"`
 				}{
-					Clients: []struct {
-						ID      string `json:"id"`
-						Default bool   `json:"default"`
-					}{
-						{ID: "test-client-id", Default: true},
+					Clients: []ClientInfo{
+						{ID: "test-company-id", Name: "Test LLC"},
+						{ID: "test-client-id", Name: "Test User", Default: true},
 					},
 				},
 			})
+		case "/api/loans":
+			w.Write([]byte(`{"status":"SUCCESS","data":{"loans":[{"id":"L1","name":"Consumer Loan","currency":"AMD","amount":1000000,"outstandingAmount":400000,"interestRate":14.5,"nextPaymentDate":"2024-02-15","nextPaymentAmount":52000,"status":"ACTIVE"}]}}`))
+		case "/api/deposits":
+			w.Write([]byte(`{"status":"SUCCESS","data":{"deposits":[{"id":"D1","name":"Term Deposit","currency":"USD","amount":5000,"interestRate":8,"maturityDate":"2025-01-01","status":"ACTIVE"}]}}`))
+		case "/api/accounts/2000000001/requisites":
+			w.Write([]byte(`{"status":"SUCCESS","data":{"requisites":{"holderName":"TEST USER","accountNumber":"1570000000000000","iban":"AM00157000000000000000","currency":"AMD","bankName":"Ameriabank CJSC","swift":"ARMIAM22"}}}`))
+		case "/api/events/upcoming/1000000001":
+			w.Write([]byte(`{"status":"SUCCESS","data":{"totalCount":1,"entries":[{"id":"hold1","accountingType":"DEBIT","amount":{"currency":"AMD","amount":2500},"details":"Coffee Shop","operationDate":"2024-01-16T09:00:00Z"}]}}`))
+		case "/api/templates":
+			if r.URL.Query().Get("hasGroup") == "true" {
+				w.Write([]byte(`{"status":"SUCCESS","data":{"templates":[],"groups":[{"id":"G1","name":"Family","templates":[{"id":"T2","name":"Mom","data":{"creditTarget":{"number":"4454********6615","type":"CARD"}}},{"id":"T1","name":"Rent","data":{"creditTarget":{"number":"1570000000000001","type":"ACCOUNT"}}}]}]}}`))
+				return
+			}
+			w.Write([]byte(`{"status":"SUCCESS","data":{"templates":[{"id":"T1","name":"Rent","data":{"creditTarget":{"number":"1570000000000001","type":"ACCOUNT"}}},{"id":"T3","name":"Gym","data":{"creditTarget":{"number":"5555********1234","type":"CARD"}}}]}}`))
+		case "/api/utility-payments/history":
+			if r.URL.Query().Get("fromDate") != "2024-01-01" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"status":"SUCCESS","data":{"totalCount":2,"entries":[{"id":"U2","serviceType":"GAS","provider":"Gazprom Armenia","subscriberNumber":"123456","amount":{"currency":"AMD","amount":8500},"paymentDate":"2024-02-10","status":"DONE"},{"id":"U1","serviceType":"ELECTRICITY","provider":"ENA","subscriberNumber":"789","amount":{"currency":"AMD","amount":12000},"paymentDate":"2024-02-05","status":"DONE"}]}}`))
+		case "/api/exchange-rates":
+			w.Write([]byte(`{"status":"SUCCESS","data":{"rates":[{"currency":"USD","type":"CASH","buy":385,"sell":390},{"currency":"USD","type":"NON_CASH","buy":386,"sell":389}]}}`))
+		case "/api/statements/download":
+			q := r.URL.Query()
+			if q.Get("productId") != "2000000001" {
+				w.Write([]byte(`{"status":"ERROR","errorMessages":["statement not available"]}`))
+				return
+			}
+			if q.Get("productType") != "ACCOUNT" || q.Get("format") != StatementFormatPDF ||
+				q.Get("fromDate") != "2024-06-01" || q.Get("toDate") != "2024-06-30" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"status":"ERROR","errorMessages":["bad parameters"]}`))
+				return
+			}
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF-1.4 statement"))
+		case "/api/loans/L1":
+			w.Write([]byte(`{"status":"SUCCESS","data":{"loan":{"id":"L1","name":"Consumer Loan","currency":"AMD","amount":1000000,"outstandingAmount":400000,"interestRate":14.5,"nextPaymentDate":"2024-02-15","nextPaymentAmount":52000,"status":"ACTIVE","openDate":"2023-01-15","schedule":[{"date":"2024-01-15","principal":45000,"interest":7000,"total":52000,"paid":true},{"date":"2024-02-15","principal":45500,"interest":6500,"total":52000}]}}}`))
+		case "/api/loans/L2":
+			w.Write([]byte(`{"status":"ERROR","errorMessages":["loan not found"]}`))
+		case "/api/savings-goals":
+			w.Write([]byte(`{"status":"SUCCESS","data":{"goals":[{"id":"G1","name":"Vacation","accountId":"2000000001","currency":"AMD","balance":150000,"targetAmount":500000,"targetDate":"2025-06-01","status":"ACTIVE"}]}}`))
+		case "/api/deposits/D1":
+			w.Write([]byte(`{"status":"SUCCESS","data":{"deposit":{"id":"D1","name":"Term Deposit","currency":"USD","amount":5000,"interestRate":8,"accruedInterest":120.5,"maturityDate":"2025-01-01","status":"ACTIVE","openDate":"2024-01-01","termMonths":12,"capitalization":true}}}`))
+		case "/api/deposits/D1/schedule":
+			w.Write([]byte(`{"status":"SUCCESS","data":{"payments":[{"date":"2024-02-01","amount":33.33,"tax":3.33,"paid":true},{"date":"2024-03-01","amount":33.33}]}}`))
 		default:
 			w.WriteHeader(http.StatusNotFound)
 			w.Write([]byte(`{"status":"ERROR","errorMessages":["not found"]}`))
@@ -479,6 +604,337 @@ func mockAPIServer(t *testing.T) *httptest.Server {
 	}))
 }
 
+func TestGetRequisites_WithMockServer(t *testing.T) {
+	server := mockAPIServer(t)
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	resp, err := c.GetRequisites("test-token", "2000000001")
+	if err != nil {
+		t.Fatalf("GetRequisites failed: %v", err)
+	}
+	r := resp.Data.Requisites
+	if r.HolderName != "TEST USER" || r.IBAN != "AM00157000000000000000" || r.SWIFT != "ARMIAM22" {
+		t.Errorf("unexpected requisites: %+v", r)
+	}
+}
+
+func TestGetEventsUpcoming_WithMockServer(t *testing.T) {
+	server := mockAPIServer(t)
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	resp, err := c.GetEventsUpcoming("test-token", "1000000001")
+	if err != nil {
+		t.Fatalf("GetEventsUpcoming failed: %v", err)
+	}
+	if len(resp.Data.Entries) != 1 || resp.Data.Entries[0].ID != "hold1" || resp.Data.Entries[0].Amount.Amount != 2500 {
+		t.Errorf("unexpected holds: %+v", resp.Data.Entries)
+	}
+}
+
+func TestGetTemplates_WithGroups(t *testing.T) {
+	server := mockAPIServer(t)
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	resp, err := c.GetTemplates("test-token")
+	if err != nil {
+		t.Fatalf("GetTemplates failed: %v", err)
+	}
+	templates := resp.Data.Templates
+	if len(templates) != 3 {
+		t.Fatalf("expected 3 templates, got %+v", templates)
+	}
+	// A template listed both ungrouped and in a group appears once, with its group
+	if templates[0].ID != "T1" || templates[0].GroupName != "Family" {
+		t.Errorf("expected T1 in group Family, got %+v", templates[0])
+	}
+	if templates[1].ID != "T3" || templates[1].GroupName != "" {
+		t.Errorf("expected ungrouped T3, got %+v", templates[1])
+	}
+	if templates[2].ID != "T2" || templates[2].GroupID != "G1" || templates[2].GroupName != "Family" {
+		t.Errorf("expected T2 in group G1, got %+v", templates[2])
+	}
+	if len(resp.Data.Groups) != 1 {
+		t.Errorf("expected 1 group, got %d", len(resp.Data.Groups))
+	}
+}
+
+func TestGetUtilityPayments_WithMockServer(t *testing.T) {
+	server := mockAPIServer(t)
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	resp, err := c.GetUtilityPayments("test-token", 100, 0, time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("GetUtilityPayments failed: %v", err)
+	}
+	entries := resp.Data.Entries
+	if len(entries) != 2 || entries[0].ServiceType != UtilityGas || entries[0].Provider != "Gazprom Armenia" || entries[1].Amount.Amount != 12000 {
+		t.Errorf("unexpected utility payments: %+v", entries)
+	}
+}
+
+func TestGetClients_WithMockServer(t *testing.T) {
+	server := mockAPIServer(t)
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	clients, err := c.GetClients("test-token")
+	if err != nil {
+		t.Fatalf("GetClients failed: %v", err)
+	}
+	if len(clients) != 2 || clients[0].Name != "Test LLC" || !clients[1].Default {
+		t.Errorf("unexpected clients: %+v", clients)
+	}
+}
+
+func TestParseSwiftDetails(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want *SwiftInfo
+	}{
+		{"empty", "", nil},
+		{"null", "null", nil},
+		{"unknown fields only", `{"foo":"bar"}`, nil},
+		{
+			"named fields",
+			`{"SenderBankName":"Example Bank NA","senderReference":"INV-42","fees":12.5,"intermediaryBank":{"name":"Correspondent Bank","bic":"CORRUS33"}}`,
+			&SwiftInfo{SenderBank: "Example Bank NA", Reference: "INV-42", Fees: "12.5", Intermediary: "Correspondent Bank, CORRUS33"},
+		},
+		{
+			"MT103 tags",
+			`{"20":"REF1","52A":"BANKDEFF","71A":"OUR"}`,
+			&SwiftInfo{SenderBank: "BANKDEFF", Reference: "REF1", Fees: "OUR"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSwiftDetails(tt.raw)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("ParseSwiftDetails(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirection(t *testing.T) {
+	card := Transaction{AccountingType: "CREDIT", Amount: Amount{Currency: "AMD", Amount: 500}}
+	if card.Direction() != DirectionCredit || card.SignedAmount() != 500 {
+		t.Errorf("expected a credit of 500, got %s %v", card.Direction(), card.SignedAmount())
+	}
+	card.AccountingType = "DEBIT"
+	if card.Direction() != DirectionDebit || card.SignedAmount() != -500 || card.Direction().Sign() != "-" {
+		t.Errorf("expected a debit of -500, got %s %v", card.Direction(), card.SignedAmount())
+	}
+
+	history := AccountTransaction{FlowDirection: "INCOME", TransactionAmount: TransactionAmt{Currency: "AMD", Value: 200}}
+	if history.Direction() != DirectionCredit || history.SignedAmount() != 200 || history.Direction().Sign() != "+" {
+		t.Errorf("expected a credit of 200, got %s %v", history.Direction(), history.SignedAmount())
+	}
+	history.FlowDirection = "EXPENSE"
+	if history.Direction() != DirectionDebit || history.SignedAmount() != -200 {
+		t.Errorf("expected a debit of -200, got %s %v", history.Direction(), history.SignedAmount())
+	}
+}
+
+func TestParseLoginPage(t *testing.T) {
+	const action = "https://account.myameria.am/auth/realms/ameria/login-actions/authenticate"
+	for _, tt := range []struct {
+		file string
+		want loginPage
+	}{
+		{"login.html", loginPage{
+			ActionURL: action + "?session_code=s1&execution=e1&client_id=banqr-online&tab_id=t1",
+			Template:  "login.ftl",
+		}},
+		{"login_form.html", loginPage{
+			ActionURL: action + "?session_code=s2&execution=e2&tab_id=t2",
+		}},
+		{"push_script.html", loginPage{
+			ActionURL:          action + "?session_code=s3&execution=e3",
+			Template:           "push-confirm.ftl",
+			Message:            "Подтвердите вход в приложении",
+			PushSessionID:      "push-333",
+			EvaluatedRequestID: "eval-333",
+		}},
+		{"push_inputs.html", loginPage{
+			ActionURL:          action + "?session_code=s4&execution=e4",
+			PushSessionID:      "push-444",
+			EvaluatedRequestID: "eval-444",
+		}},
+		{"push_json.html", loginPage{
+			ActionURL:          action + "?session_code=s5&execution=e5",
+			Template:           "push-confirm.ftl",
+			PushSessionID:      "push-555",
+			EvaluatedRequestID: "eval-555",
+		}},
+		{"login_error.html", loginPage{
+			ActionURL: action + "?session_code=s6",
+			Template:  "login.ftl",
+			Message:   "Invalid username or password.",
+		}},
+	} {
+		body, err := os.ReadFile(filepath.Join("testdata", "login", tt.file))
+		if err != nil {
+			t.Fatalf("reading fixture: %v", err)
+		}
+		if got := parseLoginPage(body); got != tt.want {
+			t.Errorf("%s: parsed\n%+v\nwant\n%+v", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestLoginPage_UnsupportedFlow(t *testing.T) {
+	for _, page := range []loginPage{
+		{Template: "push-confirm.ftl", PushSessionID: "push-1"},
+		{Template: "login.ftl", Message: "Invalid username or password."},
+		{},
+	} {
+		if err := page.unsupportedFlow(); err != nil {
+			t.Errorf("%+v: unexpected error %v", page, err)
+		}
+	}
+	err := loginPage{Template: "qr-confirm.ftl"}.unsupportedFlow()
+	if !errors.Is(err, ErrUnsupportedApprovalFlow) || !strings.Contains(err.Error(), "qr-confirm.ftl") {
+		t.Errorf("expected an unsupported approval flow error naming the template, got %v", err)
+	}
+}
+
+func TestLoginPage_Rejected(t *testing.T) {
+	for _, page := range []loginPage{
+		{Template: "push-confirm.ftl", PushSessionID: "push-1"},
+		{Template: "qr-confirm.ftl"},
+		{},
+	} {
+		if err := page.rejected(); err != nil {
+			t.Errorf("%+v: unexpected error %v", page, err)
+		}
+	}
+	err := loginPage{Template: "login.ftl", Message: "Invalid username or password."}.rejected()
+	if !errors.Is(err, ErrLoginRejected) || !strings.Contains(err.Error(), "Invalid username or password.") {
+		t.Errorf("expected a rejected login error with the page's message, got %v", err)
+	}
+}
+
+func TestAuthorizationCode(t *testing.T) {
+	code, err := authorizationCode("https://online.ameriabank.am/#state=abc&session_state=def&code=c0de.123")
+	if err != nil || code != "c0de.123" {
+		t.Errorf("expected the code from the fragment, got %q (err %v)", code, err)
+	}
+	if _, err := authorizationCode("https://online.ameriabank.am/#error=access_denied&state=abc"); err == nil {
+		t.Error("expected an error without a code")
+	}
+}
+
+func TestGetExchangeRates_WithMockServer(t *testing.T) {
+	server := mockAPIServer(t)
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	resp, err := c.GetExchangeRates("test-token")
+	if err != nil {
+		t.Fatalf("GetExchangeRates failed: %v", err)
+	}
+	rates := resp.Data.Rates
+	if len(rates) != 2 || rates[1].Type != RateTypeNonCash || rates[1].Mid() != 387.5 {
+		t.Errorf("unexpected rates: %+v", rates)
+	}
+}
+
+func TestDownloadStatement_WithMockServer(t *testing.T) {
+	server := mockAPIServer(t)
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2024, 6, 30, 0, 0, 0, 0, time.Local)
+	data, err := c.DownloadStatement("test-token", "ACCOUNT", "2000000001", from, to, StatementFormatPDF)
+	if err != nil {
+		t.Fatalf("DownloadStatement failed: %v", err)
+	}
+	if string(data) != "%PDF-1.4 statement" {
+		t.Errorf("unexpected statement data: %q", data)
+	}
+
+	_, err = c.DownloadStatement("test-token", "ACCOUNT", "2000000002", from, to, StatementFormatPDF)
+	if err == nil || !strings.Contains(err.Error(), "statement not available") {
+		t.Errorf("expected envelope error, got %v", err)
+	}
+}
+
+func TestGetLoanDetails_WithMockServer(t *testing.T) {
+	server := mockAPIServer(t)
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	resp, err := c.GetLoanDetails("test-token", "L1")
+	if err != nil {
+		t.Fatalf("GetLoanDetails failed: %v", err)
+	}
+	l := resp.Data.Loan
+	if l.OutstandingAmount != 400000 || l.NextPaymentAmount != 52000 || l.OpenDate != "2023-01-15" {
+		t.Errorf("unexpected loan: %+v", l)
+	}
+	if len(l.Schedule) != 2 || !l.Schedule[0].Paid || l.Schedule[1].Principal != 45500 || l.Schedule[1].Paid {
+		t.Errorf("unexpected schedule: %+v", l.Schedule)
+	}
+
+	// An error envelope with HTTP 200 is reported as an error
+	_, err = c.GetLoanDetails("test-token", "L2")
+	if err == nil || !strings.Contains(err.Error(), "loan not found") {
+		t.Errorf("expected envelope error, got %v", err)
+	}
+}
+
+func TestGetDepositDetailsAndSchedule_WithMockServer(t *testing.T) {
+	server := mockAPIServer(t)
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	details, err := c.GetDepositDetails("test-token", "D1")
+	if err != nil {
+		t.Fatalf("GetDepositDetails failed: %v", err)
+	}
+	d := details.Data.Deposit
+	if d.ID != "D1" || d.AccruedInterest != 120.5 || d.TermMonths != 12 || !d.Capitalization || d.OpenDate != "2024-01-01" {
+		t.Errorf("unexpected deposit details: %+v", d)
+	}
+
+	schedule, err := c.GetDepositSchedule("test-token", "D1")
+	if err != nil {
+		t.Fatalf("GetDepositSchedule failed: %v", err)
+	}
+	payments := schedule.Data.Payments
+	if len(payments) != 2 || !payments[0].Paid || payments[0].Tax != 3.33 || payments[1].Paid {
+		t.Errorf("unexpected schedule: %+v", payments)
+	}
+
+	if _, err := c.GetDepositDetails("test-token", "missing"); err == nil {
+		t.Error("expected error for unknown deposit")
+	}
+}
+
 func TestGetAccountsAndCards_WithMockServer(t *testing.T) {
 	server := mockAPIServer(t)
 	defer server.Close()
@@ -506,6 +962,42 @@ func TestGetAccountsAndCards_WithMockServer(t *testing.T) {
 	}
 }
 
+func TestGetLoansAndDeposits_WithMockServer(t *testing.T) {
+	server := mockAPIServer(t)
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	loans, err := c.GetLoans("test-token")
+	if err != nil {
+		t.Fatalf("GetLoans failed: %v", err)
+	}
+	if len(loans.Data.Loans) != 1 {
+		t.Fatalf("expected 1 loan, got %d", len(loans.Data.Loans))
+	}
+	l := loans.Data.Loans[0]
+	if l.OutstandingAmount != 400000 || l.NextPaymentDate != "2024-02-15" || l.NextPaymentAmount != 52000 {
+		t.Errorf("unexpected loan: %+v", l)
+	}
+
+	deposits, err := c.GetDeposits("test-token")
+	if err != nil {
+		t.Fatalf("GetDeposits failed: %v", err)
+	}
+	if len(deposits.Data.Deposits) != 1 || deposits.Data.Deposits[0].MaturityDate != "2025-01-01" {
+		t.Errorf("unexpected deposits: %+v", deposits.Data.Deposits)
+	}
+
+	goals, err := c.GetGoals("test-token")
+	if err != nil {
+		t.Fatalf("GetGoals failed: %v", err)
+	}
+	if len(goals.Data.Goals) != 1 || goals.Data.Goals[0].AccountID != "2000000001" || goals.Data.Goals[0].Balance != 150000 {
+		t.Errorf("unexpected goals: %+v", goals.Data.Goals)
+	}
+}
+
 func TestGetTransactions_WithMockServer(t *testing.T) {
 	server := mockAPIServer(t)
 	defer server.Close()
@@ -544,7 +1036,7 @@ func TestGetAccountHistory_WithMockServer(t *testing.T) {
 	c.APIBaseURL = server.URL
 	c.ClientID = "test-client-id"
 
-	resp, err := c.GetAccountHistory("test-token", "2000000001", 50, 0)
+	resp, err := c.GetAccountHistory("test-token", "2000000001", 50, 0, time.Time{})
 	if err != nil {
 		t.Fatalf("GetAccountHistory failed: %v", err)
 	}
@@ -571,7 +1063,7 @@ func TestGetEventsPast_WithMockServer(t *testing.T) {
 	c.APIBaseURL = server.URL
 	c.ClientID = "test-client-id"
 
-	resp, err := c.GetEventsPast("test-token", "2000000001", 50, 0)
+	resp, err := c.GetEventsPast("test-token", "2000000001", 50, 0, time.Time{})
 	if err != nil {
 		t.Fatalf("GetEventsPast failed: %v", err)
 	}
@@ -587,6 +1079,87 @@ func TestGetEventsPast_WithMockServer(t *testing.T) {
 	}
 }
 
+func TestGetAccountHistory_Since(t *testing.T) {
+	var gotQuery url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		w.Write([]byte(`{"status":"SUCCESS","data":{"transactions":[]}}`))
+	}))
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	if _, err := c.GetAccountHistory("test-token", "2000000001", 50, 0, time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local)); err != nil {
+		t.Fatalf("GetAccountHistory failed: %v", err)
+	}
+	if gotQuery.Get("fromDate") != "2024-01-15" {
+		t.Errorf("expected fromDate=2024-01-15, got %q", gotQuery.Get("fromDate"))
+	}
+
+	if _, err := c.GetEventsPast("test-token", "2000000001", 50, 0, time.Time{}); err != nil {
+		t.Fatalf("GetEventsPast failed: %v", err)
+	}
+	if gotQuery.Has("fromDate") {
+		t.Errorf("expected no fromDate without since, got %q", gotQuery.Get("fromDate"))
+	}
+}
+
+func TestGetHistoryRange(t *testing.T) {
+	var gotQuery url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		w.Write([]byte(`{"status":"SUCCESS","data":{"transactions":[],"entries":[]}}`))
+	}))
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	from := time.Date(2023, 5, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2023, 5, 31, 0, 0, 0, 0, time.Local)
+	if _, err := c.GetAccountHistoryRange("test-token", "2000000001", 50, 0, from, to); err != nil {
+		t.Fatalf("GetAccountHistoryRange failed: %v", err)
+	}
+	if gotQuery.Get("fromDate") != "2023-05-01" || gotQuery.Get("toDate") != "2023-05-31" {
+		t.Errorf("unexpected date range: %v", gotQuery)
+	}
+
+	if _, err := c.GetEventsPastRange("test-token", "2000000001", 50, 0, time.Time{}, to); err != nil {
+		t.Fatalf("GetEventsPastRange failed: %v", err)
+	}
+	if gotQuery.Has("fromDate") || gotQuery.Get("toDate") != "2023-05-31" {
+		t.Errorf("unexpected date range: %v", gotQuery)
+	}
+}
+
+func TestGetEventsPast_FromAmount(t *testing.T) {
+	var gotQuery url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		w.Write([]byte(`{"status":"SUCCESS","data":{"entries":[]}}`))
+	}))
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+
+	if _, err := c.GetEventsPast("test-token", "2000000001", 50, 0, time.Time{}); err != nil {
+		t.Fatalf("GetEventsPast failed: %v", err)
+	}
+	if gotQuery.Get("fromAmount") != "0" {
+		t.Errorf("expected fromAmount=0 by default, got %q", gotQuery.Get("fromAmount"))
+	}
+
+	c.EventsFromAmount = 0.1
+	if _, err := c.GetEventsPast("test-token", "2000000001", 50, 0, time.Time{}); err != nil {
+		t.Fatalf("GetEventsPast failed: %v", err)
+	}
+	if gotQuery.Get("fromAmount") != "0.1" {
+		t.Errorf("expected fromAmount=0.1, got %q", gotQuery.Get("fromAmount"))
+	}
+}
+
 func TestValidateSession_Valid(t *testing.T) {
 	server := mockAPIServer(t)
 	defer server.Close()
@@ -668,3 +1241,108 @@ func TestGetAccountsAndCards_NotFound(t *testing.T) {
 		t.Error("expected error for not found response")
 	}
 }
+
+func TestContext_CancelsRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	c, _ := NewClient("testuser", "testpass", nil, "")
+	c.APIBaseURL = server.URL
+	c.Context = ctx
+
+	_, err := c.GetAccountsAndCards("test-token")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the request to be cancelled, got %v", err)
+	}
+}
+
+// mockProductsCache is an in-memory products cache for testing
+type mockProductsCache struct {
+	data     map[string][]byte
+	storedAt time.Time
+}
+
+func (m *mockProductsCache) LoadProducts(clientID string, since time.Time) ([]byte, error) {
+	if !m.storedAt.After(since) {
+		return nil, nil
+	}
+	return m.data[clientID], nil
+}
+
+func (m *mockProductsCache) SaveProducts(clientID string, data []byte) error {
+	if m.data == nil {
+		m.data = make(map[string][]byte)
+	}
+	m.data[clientID] = data
+	m.storedAt = time.Now()
+	return nil
+}
+
+func TestGetAccountsAndCards_Cached(t *testing.T) {
+	api := mockAPIServer(t)
+	defer api.Close()
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		api.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	cache := &mockProductsCache{}
+	newClient := func(ttl time.Duration) *Client {
+		c, _ := NewClient("testuser", "testpass", nil, "")
+		c.APIBaseURL = server.URL
+		c.ClientID = "test-client-id"
+		c.ProductsCache = cache
+		c.ProductsTTL = ttl
+		return c
+	}
+
+	c := newClient(0)
+	resp, err := c.GetAccountsAndCards("test-token")
+	if err != nil {
+		t.Fatalf("GetAccountsAndCards failed: %v", err)
+	}
+	// Callers may modify the response without affecting the cached one
+	resp.Data.AccountsAndCards[0].AvailableBalance = 1
+	resp, err = c.GetAccountsAndCards("test-token")
+	if err != nil {
+		t.Fatalf("GetAccountsAndCards failed: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request within a run, got %d", requests)
+	}
+	if len(resp.Data.AccountsAndCards) != 2 || resp.Data.AccountsAndCards[0].AvailableBalance != 0 {
+		t.Errorf("unexpected cached response: %+v", resp.Data.AccountsAndCards)
+	}
+
+	// Another client of the same session fetches them again
+	c.ClientID = "other-client-id"
+	if _, err := c.GetAccountsAndCards("test-token"); err != nil || requests != 2 {
+		t.Errorf("expected another client's products to be fetched, got %d requests, %v", requests, err)
+	}
+	c.ClientID = "test-client-id"
+	if _, err := c.RefreshAccountsAndCards("test-token"); err != nil || requests != 3 {
+		t.Errorf("expected a refresh to fetch the products, got %d requests, %v", requests, err)
+	}
+
+	// A later run reuses the stored response within ProductsTTL only
+	if _, err := newClient(0).GetAccountsAndCards("test-token"); err != nil || requests != 4 {
+		t.Errorf("expected products to be fetched without a TTL, got %d requests, %v", requests, err)
+	}
+	resp, err = newClient(time.Minute).GetAccountsAndCards("test-token")
+	if err != nil || requests != 4 {
+		t.Errorf("expected cached products within the TTL, got %d requests, %v", requests, err)
+	}
+	if resp == nil || len(resp.Data.AccountsAndCards) != 2 || resp.Data.AccountsAndCards[0].ID != "1000000001" {
+		t.Errorf("unexpected response from the cache: %+v", resp)
+	}
+	cache.storedAt = time.Now().Add(-2 * time.Minute)
+	if _, err := newClient(time.Minute).GetAccountsAndCards("test-token"); err != nil || requests != 5 {
+		t.Errorf("expected expired products to be fetched, got %d requests, %v", requests, err)
+	}
+}
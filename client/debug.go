@@ -0,0 +1,155 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Limits on the debug directory. Debug files are pages and API responses
+// saved on errors; they are only useful for a short while.
+const (
+	debugMaxFileSize = 1 << 20            // larger content is truncated
+	debugMaxDirSize  = 16 << 20           // oldest debug files are removed above this
+	debugRetention   = 7 * 24 * time.Hour // older debug files are removed
+)
+
+// debugExtensions are the kinds of files pruned from the debug directory, so
+// that other files in it are left alone
+var debugExtensions = []string{".html", ".json", ".txt"}
+
+const redacted = "[REDACTED]"
+
+// sensitiveNames are JSON keys, form and query parameters and hidden input
+// names whose values are secrets. The OAuth authorization code is only
+// redacted as a parameter, since "code" is also an API status field.
+const sensitiveNames = `access_token|refresh_token|id_token|token|password|secret|client_secret|` +
+	`session_code|session_state|execution|tab_id|evaluatedRequestId|pushSessionId|` +
+	`cardNumber|pan|cvv|cvc`
+
+var (
+	// "name": "value"
+	jsonSecretRe = regexp.MustCompile(`(?i)("(?:` + sensitiveNames + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// name=value in URLs and form bodies
+	paramSecretRe = regexp.MustCompile(`(?i)([?&;#](?:code|` + sensitiveNames + `)=)[^&"'\s<>]+`)
+	// <input name="name" value="value"> in either attribute order
+	inputSecretRe = regexp.MustCompile(`(?i)<input[^>]*\bname=["'](?:` + sensitiveNames + `)["'][^>]*>`)
+	inputValueRe  = regexp.MustCompile(`(?i)(\bvalue=)(?:"[^"]*"|'[^']*')`)
+	bearerRe      = regexp.MustCompile(`(?i)(Bearer\s+)[A-Za-z0-9._~+/=-]+`)
+	jwtRe         = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	// Card and account numbers: 15 to 19 digits, possibly grouped by spaces
+	// or dashes. Shorter runs are mostly millisecond timestamps.
+	cardNumberRe = regexp.MustCompile(`\b\d(?:[ -]?\d){10,14}[ -]?\d{4}\b`)
+)
+
+// RedactDebug replaces tokens, session identifiers, passwords and card and
+// account numbers in a page or API response with placeholders, keeping the
+// last 4 digits of numbers. Any of the given literal secrets (the username
+// and password) are replaced too.
+func RedactDebug(content []byte, secrets ...string) []byte {
+	for _, s := range secrets {
+		if len(s) >= 3 {
+			content = bytes.ReplaceAll(content, []byte(s), []byte(redacted))
+		}
+	}
+	content = jwtRe.ReplaceAll(content, []byte(redacted))
+	content = bearerRe.ReplaceAll(content, []byte("${1}"+redacted))
+	content = jsonSecretRe.ReplaceAll(content, []byte(`${1}"`+redacted+`"`))
+	content = paramSecretRe.ReplaceAll(content, []byte("${1}"+redacted))
+	content = inputSecretRe.ReplaceAllFunc(content, func(tag []byte) []byte {
+		return inputValueRe.ReplaceAll(tag, []byte(`${1}"`+redacted+`"`))
+	})
+	content = cardNumberRe.ReplaceAllFunc(content, func(number []byte) []byte {
+		digits := bytes.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, number)
+		return append([]byte("****"), digits[len(digits)-4:]...)
+	})
+	return content
+}
+
+// SaveDebugFile saves content to a debug file if debug dir is configured.
+// Secrets are redacted, the file is only readable by the user and old debug
+// files are pruned.
+func (c *Client) SaveDebugFile(filename string, content []byte) {
+	if c.DebugDir == "" {
+		return
+	}
+	content = RedactDebug(content, c.Username, c.Password)
+	if len(content) > debugMaxFileSize {
+		content = append(content[:debugMaxFileSize:debugMaxFileSize], "\n[truncated]\n"...)
+	}
+	path := filepath.Join(c.DebugDir, filename)
+	// WriteFile keeps the permissions of an existing file
+	os.Remove(path)
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save debug file %s: %v\n", path, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Debug: Saved debug file to %s\n", path)
+	if err := pruneDebugDir(c.DebugDir, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to prune debug directory %s: %v\n", c.DebugDir, err)
+	}
+}
+
+// pruneDebugDir removes debug files older than debugRetention, then the
+// oldest ones until the directory fits in debugMaxDirSize
+func pruneDebugDir(dir string, now time.Time) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	type debugFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []debugFile
+	var total int64
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !isDebugFile(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if now.Sub(info.ModTime()) > debugRetention {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			continue
+		}
+		files = append(files, debugFile{path, info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	// The newest file is always kept
+	for i := 0; total > debugMaxDirSize && i < len(files)-1; i++ {
+		if err := os.Remove(files[i].path); err != nil {
+			return err
+		}
+		total -= files[i].size
+	}
+	return nil
+}
+
+func isDebugFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range debugExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package client
+
+// Direction is the normalized direction of money in a transaction. Card and
+// linked account transactions carry it as an accounting type, account history
+// as a flow direction; renderers and exporters should only use Direction.
+type Direction string
+
+// Transaction directions
+const (
+	DirectionCredit Direction = "credit" // incoming money
+	DirectionDebit  Direction = "debit"  // outgoing money
+)
+
+// DirectionOfAccountingType returns the direction of an accounting type
+// (CREDIT or DEBIT) of card and linked account transactions
+func DirectionOfAccountingType(accountingType string) Direction {
+	if accountingType == "CREDIT" {
+		return DirectionCredit
+	}
+	return DirectionDebit
+}
+
+// DirectionOfFlow returns the direction of a flow direction (INCOME or EXPENSE)
+// of account history transactions
+func DirectionOfFlow(flowDirection string) Direction {
+	if flowDirection == "INCOME" {
+		return DirectionCredit
+	}
+	return DirectionDebit
+}
+
+// Sign returns "+" for credits and "-" for debits
+func (d Direction) Sign() string {
+	if d == DirectionCredit {
+		return "+"
+	}
+	return "-"
+}
+
+// Signed returns amount with a negative sign for debits
+func (d Direction) Signed(amount float64) float64 {
+	if d == DirectionCredit {
+		return amount
+	}
+	return -amount
+}
+
+// Direction returns the direction of a card or linked account transaction
+func (t Transaction) Direction() Direction {
+	return DirectionOfAccountingType(t.AccountingType)
+}
+
+// SignedAmount returns the amount, negative for debits
+func (t Transaction) SignedAmount() float64 {
+	return t.Direction().Signed(t.Amount.Amount)
+}
+
+// Direction returns the direction of an account history transaction
+func (t AccountTransaction) Direction() Direction {
+	return DirectionOfFlow(t.FlowDirection)
+}
+
+// SignedAmount returns the transaction amount, negative for debits
+func (t AccountTransaction) SignedAmount() float64 {
+	return t.Direction().Signed(t.TransactionAmount.Value)
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// loginPage holds what the login flow needs from a Keycloak page. Values are
+// read from the page's form fields and from its scripts (the kcContext block
+// and inline assignments), wherever they appear, so layout changes that keep
+// the values don't break login.
+type loginPage struct {
+	ActionURL          string // where the page's form is posted
+	Template           string // Keycloak template, e.g. login.ftl
+	Message            string // error or info message shown on the page
+	PushSessionID      string // external_system_request_id of the push 2FA page
+	EvaluatedRequestID string // evaluatedRequestId of the push 2FA page
+}
+
+// loginTemplate is the Keycloak template of the login form, which is shown
+// again when the credentials are rejected
+const loginTemplate = "login.ftl"
+
+// unsupportedFlow returns ErrUnsupportedApprovalFlow if the page after login
+// asks to confirm it in a way other than the push notification
+func (p loginPage) unsupportedFlow() error {
+	if p.PushSessionID != "" || p.Template == "" || p.Template == loginTemplate {
+		return nil
+	}
+	return fmt.Errorf("%w: the login page (%s) has no push notification to confirm; only push confirmation is supported",
+		ErrUnsupportedApprovalFlow, p.Template)
+}
+
+// rejected returns ErrLoginRejected with the page's message if the page after
+// login is the login form again
+func (p loginPage) rejected() error {
+	if p.PushSessionID != "" || p.Template != loginTemplate {
+		return nil
+	}
+	if p.Message == "" {
+		return fmt.Errorf("%w: the login form was shown again", ErrLoginRejected)
+	}
+	return fmt.Errorf("%w: %s", ErrLoginRejected, p.Message)
+}
+
+// parseLoginPage extracts the login values from a Keycloak page
+func parseLoginPage(body []byte) loginPage {
+	var page loginPage
+	var formAction string
+	values := make(map[string]string) // first value seen for each key
+
+	set := func(key, value string) {
+		if _, ok := values[key]; !ok && value != "" {
+			values[key] = value
+		}
+	}
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		tok := z.Token()
+		switch tok.DataAtom {
+		case atom.Form:
+			if formAction == "" {
+				formAction = attr(tok, "action")
+			}
+		case atom.Input:
+			set(attr(tok, "name"), attr(tok, "value"))
+			set(attr(tok, "id"), attr(tok, "value"))
+		case atom.Script:
+			// The tokenizer returns the whole script as raw text
+			if z.Next() == html.TextToken {
+				scanScript(string(z.Text()), set)
+			}
+		}
+	}
+
+	page.ActionURL = firstNonEmpty(values["actionUrl"], values["loginAction"], formAction)
+	page.Template = values["template"]
+	page.Message = firstNonEmpty(values["summary"], values["message"])
+	page.PushSessionID = values["external_system_request_id"]
+	page.EvaluatedRequestID = firstNonEmpty(values["evaluatedRequestId"], values["evaluated_request_id"])
+	return page
+}
+
+// attr returns the value of a tag's attribute, with entities decoded
+func attr(tok html.Token, name string) string {
+	for _, a := range tok.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// A JavaScript string literal, in double or single quotes
+const jsString = `("(?:[^"\\\n]|\\.)*"|'(?:[^'\\\n]|\\.)*')`
+
+var (
+	// An object property (key: "value", "key": "value")
+	scriptPropertyRe = regexp.MustCompile(`["']?([\w$]+)["']?\s*:\s*` + jsString)
+	// An assignment to a variable, a property or the value of an element
+	// named by its ID (x.key = "value", getElementById('key').value = "value")
+	scriptAssignmentRe = regexp.MustCompile(`([\w$]+)["']?\)?(?:\.value)?\s*=\s*` + jsString)
+	// A context embedded as a JSON string (JSON.parse("{...}"))
+	scriptJSONRe = regexp.MustCompile(`JSON\.parse\(\s*` + jsString + `\s*\)`)
+)
+
+// scanScript reports the string values of the object properties and
+// assignments of a script, and of the objects in the JSON it parses
+func scanScript(script string, report func(key, value string)) {
+	for _, m := range scriptJSONRe.FindAllStringSubmatch(script, -1) {
+		var v interface{}
+		if json.Unmarshal([]byte(unquoteJS(m[1])), &v) == nil {
+			reportJSON(v, report)
+		}
+	}
+	for _, re := range []*regexp.Regexp{scriptPropertyRe, scriptAssignmentRe} {
+		for _, m := range re.FindAllStringSubmatch(script, -1) {
+			report(m[1], unquoteJS(m[2]))
+		}
+	}
+}
+
+// reportJSON reports the string values of decoded JSON objects by key
+func reportJSON(v interface{}, report func(key, value string)) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		// Properties are reported before nested objects, in key order
+		keys := slices.Sorted(maps.Keys(v))
+		for _, key := range keys {
+			if s, ok := v[key].(string); ok {
+				report(key, s)
+			}
+		}
+		for _, key := range keys {
+			reportJSON(v[key], report)
+		}
+	case []interface{}:
+		for _, value := range v {
+			reportJSON(value, report)
+		}
+	}
+}
+
+// unquoteJS decodes a JavaScript string literal. The escapes of double-quoted
+// literals are those of JSON; other literals are returned without their quotes.
+func unquoteJS(lit string) string {
+	var s string
+	if lit[0] == '"' && json.Unmarshal([]byte(lit), &s) == nil {
+		return s
+	}
+	return lit[1 : len(lit)-1]
+}
+
+// authorizationCode returns the code parameter of the redirect after login,
+// which Keycloak puts in the URL fragment (response_mode=fragment)
+func authorizationCode(location string) (string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("parsing redirect location: %w", err)
+	}
+	for _, params := range []string{u.Fragment, u.RawQuery} {
+		if values, err := url.ParseQuery(params); err == nil && values.Get("code") != "" {
+			return values.Get("code"), nil
+		}
+	}
+	return "", fmt.Errorf("no authorization code in redirect: %s", location)
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ProductsCache stores accounts-and-cards responses between runs
+type ProductsCache interface {
+	// LoadProducts returns the response stored for a client ID if it was
+	// stored after since, or nil
+	LoadProducts(clientID string, since time.Time) ([]byte, error)
+	// SaveProducts stores the response for a client ID
+	SaveProducts(clientID string, data []byte) error
+}
+
+// GetAccountsAndCards returns all accounts and cards of the current client.
+// The response is fetched once per client and reused for the rest of the run,
+// and for ProductsTTL by later runs sharing ProductsCache.
+func (c *Client) GetAccountsAndCards(accessToken string) (*AccountsAndCardsResponse, error) {
+	c.productsMu.Lock()
+	defer c.productsMu.Unlock()
+	if resp, ok := c.products[c.ClientID]; ok {
+		return resp.clone(), nil
+	}
+	if resp := c.loadProducts(); resp != nil {
+		c.rememberProducts(resp)
+		return resp.clone(), nil
+	}
+	return c.refreshAccountsAndCards(accessToken)
+}
+
+// RefreshAccountsAndCards fetches all accounts and cards of the current client
+// from the API, replacing the ones GetAccountsAndCards reuses
+func (c *Client) RefreshAccountsAndCards(accessToken string) (*AccountsAndCardsResponse, error) {
+	c.productsMu.Lock()
+	defer c.productsMu.Unlock()
+	return c.refreshAccountsAndCards(accessToken)
+}
+
+// FetchAccountsAndCards fetches all accounts and cards of the current client
+// from the API, neither reusing nor storing them (e.g. for dry runs)
+func (c *Client) FetchAccountsAndCards(accessToken string) (*AccountsAndCardsResponse, error) {
+	return c.fetchAccountsAndCards(accessToken)
+}
+
+// refreshAccountsAndCards implements RefreshAccountsAndCards, with productsMu held
+func (c *Client) refreshAccountsAndCards(accessToken string) (*AccountsAndCardsResponse, error) {
+	resp, err := c.fetchAccountsAndCards(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	c.rememberProducts(resp)
+	if c.ProductsCache != nil {
+		if data, err := json.Marshal(resp); err == nil {
+			if err := c.ProductsCache.SaveProducts(c.ClientID, data); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to cache accounts and cards: %v\n", err)
+			}
+		}
+	}
+	return resp.clone(), nil
+}
+
+// loadProducts returns the accounts and cards stored in ProductsCache within
+// ProductsTTL, or nil
+func (c *Client) loadProducts() *AccountsAndCardsResponse {
+	if c.ProductsCache == nil || c.ProductsTTL <= 0 {
+		return nil
+	}
+	data, err := c.ProductsCache.LoadProducts(c.ClientID, time.Now().Add(-c.ProductsTTL))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load cached accounts and cards: %v\n", err)
+		return nil
+	}
+	if data == nil {
+		return nil
+	}
+	var resp AccountsAndCardsResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, "Debug: Using cached accounts and cards")
+	return &resp
+}
+
+// rememberProducts keeps the accounts and cards of the current client for
+// the rest of the run
+func (c *Client) rememberProducts(resp *AccountsAndCardsResponse) {
+	if c.products == nil {
+		c.products = make(map[string]*AccountsAndCardsResponse)
+	}
+	c.products[c.ClientID] = resp
+}
+
+// clone returns a copy of the response that callers may modify, e.g. by
+// setting available balances or appending goals
+func (r *AccountsAndCardsResponse) clone() *AccountsAndCardsResponse {
+	resp := *r
+	resp.Data.AccountsAndCards = append([]ProductInfo(nil), r.Data.AccountsAndCards...)
+	return &resp
+}
@@ -128,7 +128,7 @@ func (c *Client) LoadSession() (*SessionData, error) {
 // ValidateSession checks if the saved session is still valid by making a test API call
 func (c *Client) ValidateSession(accessToken string) bool {
 	// Use a short timeout to detect killed server-side sessions quickly
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.context(), 10*time.Second)
 	defer cancel()
 
 	// Use /api/users/info - same endpoint that InitializeSession uses successfully
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SwiftInfo holds the parts of a transaction's SWIFT details that matter when
+// reading an international transfer
+type SwiftInfo struct {
+	SenderBank   string `json:"senderBank,omitempty"`
+	Reference    string `json:"reference,omitempty"`
+	Fees         string `json:"fees,omitempty"`
+	Intermediary string `json:"intermediary,omitempty"`
+}
+
+// Empty reports whether none of the fields are known
+func (s *SwiftInfo) Empty() bool {
+	return s == nil || *s == SwiftInfo{}
+}
+
+// Keys the SWIFT details fields may come under, most specific first; matched
+// case-insensitively. MT103 field tags are accepted as well.
+var (
+	swiftSenderBankKeys   = []string{"senderBankName", "senderBank", "orderingInstitution", "orderingBank", "senderBic", "field52a", "52a"}
+	swiftReferenceKeys    = []string{"transactionReference", "senderReference", "reference", "remittanceInformation", "field20", "20"}
+	swiftFeesKeys         = []string{"chargesAmount", "senderCharges", "charges", "fees", "fee", "field71f", "71f"}
+	swiftChargeBearerKeys = []string{"chargeBearer", "detailsOfCharges", "field71a", "71a"}
+	swiftIntermediaryKeys = []string{"intermediaryBankName", "intermediaryBank", "intermediaryInstitution", "intermediary", "field56a", "56a"}
+)
+
+// ParseSwiftDetails extracts the sender bank, reference, fees and intermediary
+// bank from the SWIFT details JSON stored for a transaction. Returns nil if
+// the details are empty or not a JSON object.
+func ParseSwiftDetails(raw string) *SwiftInfo {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil || len(fields) == 0 {
+		return nil
+	}
+	lower := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		lower[strings.ToLower(k)] = v
+	}
+	lookup := func(keys []string) string {
+		for _, k := range keys {
+			if s := swiftValue(lower[strings.ToLower(k)]); s != "" {
+				return s
+			}
+		}
+		return ""
+	}
+
+	info := &SwiftInfo{
+		SenderBank:   lookup(swiftSenderBankKeys),
+		Reference:    lookup(swiftReferenceKeys),
+		Fees:         lookup(swiftFeesKeys),
+		Intermediary: lookup(swiftIntermediaryKeys),
+	}
+	if bearer := lookup(swiftChargeBearerKeys); bearer != "" {
+		if info.Fees == "" {
+			info.Fees = bearer
+		} else {
+			info.Fees += " (" + bearer + ")"
+		}
+	}
+	if info.Empty() {
+		return nil
+	}
+	return info
+}
+
+// swiftValue renders a SWIFT details value as text. Objects are joined from
+// their name/bic or amount/currency parts.
+func swiftValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}:
+		if amount, ok := v["amount"]; ok {
+			return strings.TrimSpace(swiftValue(amount) + " " + swiftValue(v["currency"]))
+		}
+		var parts []string
+		for _, k := range []string{"name", "bic", "swift", "address"} {
+			if s := swiftValue(v[k]); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -1,7 +1,9 @@
 package client
 
 import (
+	"context"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -49,16 +51,21 @@ type Transaction struct {
 	Year                       string                   `json:"year"`
 	Month                      string                   `json:"month"`
 	Extended                   *TransactionExtendedInfo `json:"extended,omitempty"`
+	// Stages of a card purchase linked in the local database: the settled
+	// purchase's pre-purchase authorization, or the authorization's settlement
+	AuthorizationID string `json:"authorizationId,omitempty"`
+	SettlementID    string `json:"settlementId,omitempty"`
 }
 
 // TransactionExtendedInfo holds additional transaction details from /api/transactions/{id}
 type TransactionExtendedInfo struct {
-	BeneficiaryName     string `json:"beneficiaryName,omitempty"`
-	BeneficiaryAddress  string `json:"beneficiaryAddress,omitempty"`
-	CreditAccountNumber string `json:"creditAccountNumber,omitempty"`
-	CardMaskedNumber    string `json:"cardMaskedNumber,omitempty"`
-	OperationID         string `json:"operationId,omitempty"`
-	SwiftDetails        string `json:"swiftDetails,omitempty"`
+	BeneficiaryName     string     `json:"beneficiaryName,omitempty"`
+	BeneficiaryAddress  string     `json:"beneficiaryAddress,omitempty"`
+	CreditAccountNumber string     `json:"creditAccountNumber,omitempty"`
+	CardMaskedNumber    string     `json:"cardMaskedNumber,omitempty"`
+	OperationID         string     `json:"operationId,omitempty"`
+	SwiftDetails        string     `json:"swiftDetails,omitempty"`
+	Swift               *SwiftInfo `json:"swift,omitempty"` // parsed from SwiftDetails
 }
 
 // TransactionDetailsResponse holds the response from /api/transactions/{id}
@@ -102,11 +109,12 @@ type ProductInfo struct {
 	Name             string  `json:"name"`
 	CardNumber       string  `json:"cardNumber,omitempty"`       // Cards only
 	AccountNumber    string  `json:"accountNumber,omitempty"`    // Accounts only
-	AccountID        string  `json:"accountId,omitempty"`        // Cards only: linked account ID
+	AccountID        string  `json:"accountId,omitempty"`        // Cards: linked account ID; goals: account funding them
 	Currency         string  `json:"currency"`
 	Balance          float64 `json:"balance"`
 	AvailableBalance float64 `json:"availableBalance,omitempty"` // Fetched separately
 	Status           string  `json:"status"`
+	ClientID         string  `json:"clientId,omitempty"` // Client the product belongs to, set by sync
 }
 
 // AvailableBalanceResponse holds the response from /api/accounts-and-cards/available-balance
@@ -207,13 +215,17 @@ type UserInfoResponse struct {
 type ClientsResponse struct {
 	Status string `json:"status"`
 	Data   struct {
-		Clients []struct {
-			ID      string `json:"id"`
-			Default bool   `json:"default"`
-		} `json:"clients"`
+		Clients []ClientInfo `json:"clients"`
 	} `json:"data"`
 }
 
+// ClientInfo is a client (personal or company) the user can act for
+type ClientInfo struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Default bool   `json:"default"`
+}
+
 // TransferTemplate represents a saved transfer template
 type TransferTemplate struct {
 	ID           string `json:"id"`
@@ -226,6 +238,15 @@ type TransferTemplate struct {
 		} `json:"creditTarget"`
 		Beneficiary string `json:"beneficiary"`
 	} `json:"data"`
+	GroupID   string `json:"groupId,omitempty"`   // Set by GetTemplates for grouped templates
+	GroupName string `json:"groupName,omitempty"` // Set by GetTemplates for grouped templates
+}
+
+// TemplateGroup is a named group of transfer templates
+type TemplateGroup struct {
+	ID        string             `json:"id"`
+	Name      string             `json:"name"`
+	Templates []TransferTemplate `json:"templates"`
 }
 
 // TemplatesResponse holds the response from /api/templates
@@ -233,18 +254,265 @@ type TemplatesResponse struct {
 	Status string `json:"status"`
 	Data   struct {
 		Templates []TransferTemplate `json:"templates"`
+		Groups    []TemplateGroup    `json:"groups,omitempty"` // Only with hasGroup=true
 	} `json:"data"`
 	ErrorMessages interface{} `json:"errorMessages"`
 }
 
 // Client represents the Ameriabank API client
 type Client struct {
-	HTTPClient     *http.Client
-	Username       string
-	Password       string
-	DebugDir       string
-	ClientID       string         // Consistent client ID for this session
-	APIBaseURL     string         // Base URL for API calls (defaults to APIBaseURL constant)
-	AuthBaseURL    string         // Base URL for auth calls (defaults to AuthBaseURL constant)
-	SessionStorage SessionStorage // Optional session persistence
+	HTTPClient       *http.Client
+	Username         string
+	Password         string
+	DebugDir         string
+	ClientID         string          // Consistent client ID for this session
+	APIBaseURL       string          // Base URL for API calls (defaults to APIBaseURL constant)
+	AuthBaseURL      string          // Base URL for auth calls (defaults to AuthBaseURL constant)
+	SessionStorage   SessionStorage  // Optional session persistence
+	EventsFromAmount float64         // Minimum amount of events returned by GetEventsPast (0: all)
+	Context          context.Context // Cancels requests in flight when done (nil: never)
+	ProductsCache    ProductsCache   // Optional accounts-and-cards persistence between runs
+	ProductsTTL      time.Duration   // How long ProductsCache entries are reused (0: never)
+
+	productsMu sync.Mutex
+	products   map[string]*AccountsAndCardsResponse // by client ID, for this run
+}
+
+// LoansResponse holds the response from /api/loans
+type LoansResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Loans []LoanInfo `json:"loans"`
+	} `json:"data"`
+	ErrorMessages interface{} `json:"errorMessages"`
+}
+
+// LoanInfo represents a loan product
+type LoanInfo struct {
+	ID                string  `json:"id"`
+	Name              string  `json:"name"`
+	AgreementNumber   string  `json:"agreementNumber,omitempty"`
+	Currency          string  `json:"currency"`
+	Amount            float64 `json:"amount"`            // Original principal
+	OutstandingAmount float64 `json:"outstandingAmount"` // Remaining principal
+	InterestRate      float64 `json:"interestRate"`      // Annual, percent
+	NextPaymentDate   string  `json:"nextPaymentDate,omitempty"`
+	NextPaymentAmount float64 `json:"nextPaymentAmount,omitempty"`
+	MaturityDate      string  `json:"maturityDate,omitempty"`
+	Status            string  `json:"status"`
+}
+
+// DepositsResponse holds the response from /api/deposits
+type DepositsResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Deposits []DepositInfo `json:"deposits"`
+	} `json:"data"`
+	ErrorMessages interface{} `json:"errorMessages"`
+}
+
+// DepositInfo represents a deposit product
+type DepositInfo struct {
+	ID                        string  `json:"id"`
+	Name                      string  `json:"name"`
+	AgreementNumber           string  `json:"agreementNumber,omitempty"`
+	Currency                  string  `json:"currency"`
+	Amount                    float64 `json:"amount"`       // Principal
+	InterestRate              float64 `json:"interestRate"` // Annual, percent
+	AccruedInterest           float64 `json:"accruedInterest,omitempty"`
+	NextInterestPaymentDate   string  `json:"nextInterestPaymentDate,omitempty"`
+	NextInterestPaymentAmount float64 `json:"nextInterestPaymentAmount,omitempty"`
+	MaturityDate              string  `json:"maturityDate,omitempty"`
+	Status                    string  `json:"status"`
+}
+
+// GoalsResponse holds the response from /api/savings-goals
+type GoalsResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Goals []GoalInfo `json:"goals"`
+	} `json:"data"`
+	ErrorMessages interface{} `json:"errorMessages"`
+}
+
+// GoalInfo represents a savings goal or card stash: money set aside from an
+// account and not included in its balance
+type GoalInfo struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	AccountID    string  `json:"accountId,omitempty"` // Account or card account the goal is funded from
+	Currency     string  `json:"currency"`
+	Balance      float64 `json:"balance"`
+	TargetAmount float64 `json:"targetAmount,omitempty"`
+	TargetDate   string  `json:"targetDate,omitempty"`
+	Status       string  `json:"status"`
+}
+
+// DepositDetailsResponse holds the response from /api/deposits/{id}
+type DepositDetailsResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Deposit DepositDetails `json:"deposit"`
+	} `json:"data"`
+	ErrorMessages interface{} `json:"errorMessages"`
+}
+
+// DepositDetails holds the terms of a deposit in addition to DepositInfo
+type DepositDetails struct {
+	DepositInfo
+	OpenDate              string `json:"openDate,omitempty"`
+	TermMonths            int    `json:"termMonths,omitempty"`
+	Capitalization        bool   `json:"capitalization"` // interest is added to the principal
+	Replenishable         bool   `json:"replenishable"`
+	InterestAccountNumber string `json:"interestAccountNumber,omitempty"` // account interest is paid to
+}
+
+// DepositScheduleResponse holds the response from /api/deposits/{id}/schedule
+type DepositScheduleResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Payments []InterestPayment `json:"payments"`
+	} `json:"data"`
+	ErrorMessages interface{} `json:"errorMessages"`
+}
+
+// InterestPayment is a past or scheduled interest payment of a deposit
+type InterestPayment struct {
+	Date   string  `json:"date"`
+	Amount float64 `json:"amount"`
+	Tax    float64 `json:"tax,omitempty"` // withheld income tax
+	Paid   bool    `json:"paid"`
+}
+
+// LoanDetailsResponse holds the response from /api/loans/{id}
+type LoanDetailsResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Loan LoanDetails `json:"loan"`
+	} `json:"data"`
+	ErrorMessages interface{} `json:"errorMessages"`
+}
+
+// LoanDetails holds a loan with its payment schedule
+type LoanDetails struct {
+	LoanInfo
+	OpenDate      string            `json:"openDate,omitempty"`
+	OverdueAmount float64           `json:"overdueAmount,omitempty"`
+	Schedule      []LoanInstallment `json:"schedule"`
+}
+
+// LoanInstallment is a past or scheduled loan payment
+type LoanInstallment struct {
+	Date      string  `json:"date"`
+	Principal float64 `json:"principal"`
+	Interest  float64 `json:"interest"`
+	Total     float64 `json:"total"`
+	Paid      bool    `json:"paid"`
+}
+
+// RequisitesResponse holds the response from /api/accounts/{id}/requisites
+type RequisitesResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Requisites Requisites `json:"requisites"`
+	} `json:"data"`
+	ErrorMessages interface{} `json:"errorMessages"`
+}
+
+// Requisites holds the details needed to receive transfers to an account
+type Requisites struct {
+	HolderName    string `json:"holderName"`
+	AccountNumber string `json:"accountNumber"`
+	IBAN          string `json:"iban,omitempty"`
+	Currency      string `json:"currency"`
+	BankName      string `json:"bankName"`
+	BankAddress   string `json:"bankAddress,omitempty"`
+	SWIFT         string `json:"swift,omitempty"`
+	TaxID         string `json:"taxId,omitempty"` // bank's taxpayer ID
+	// Correspondent bank for foreign currency transfers
+	CorrespondentBank    string `json:"correspondentBank,omitempty"`
+	CorrespondentSWIFT   string `json:"correspondentSwift,omitempty"`
+	CorrespondentAccount string `json:"correspondentAccount,omitempty"`
+}
+
+// ExchangeRatesResponse holds the response from /api/exchange-rates
+type ExchangeRatesResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Rates []ExchangeRate `json:"rates"`
+	} `json:"data"`
+	ErrorMessages interface{} `json:"errorMessages"`
+}
+
+// Exchange rate types
+const (
+	RateTypeCash    = "CASH"
+	RateTypeNonCash = "NON_CASH"
+	RateTypeCard    = "CARD"
+)
+
+// ExchangeRate is the bank's buy and sell rate of a currency in AMD
+type ExchangeRate struct {
+	Currency string  `json:"currency"`
+	Type     string  `json:"type"` // RateTypeCash, RateTypeNonCash or RateTypeCard
+	Buy      float64 `json:"buy"`  // AMD paid by the bank per unit
+	Sell     float64 `json:"sell"` // AMD charged by the bank per unit
+}
+
+// Mid returns the average of the buy and sell rates
+func (r ExchangeRate) Mid() float64 {
+	return (r.Buy + r.Sell) / 2
+}
+
+// Statement file formats produced by the bank
+const (
+	StatementFormatPDF = "PDF"
+	StatementFormatXLS = "XLS"
+)
+
+// StatementErrorResponse is returned by the statement endpoint instead of a
+// file when the statement can't be generated
+type StatementErrorResponse struct {
+	Status        string      `json:"status"`
+	ErrorMessages interface{} `json:"errorMessages"`
+}
+
+// Utility service types
+const (
+	UtilityElectricity = "ELECTRICITY"
+	UtilityGas         = "GAS"
+	UtilityWater       = "WATER"
+	UtilityPhone       = "PHONE"
+	UtilityInternet    = "INTERNET"
+)
+
+// UtilityPaymentsResponse holds the response from /api/utility-payments/history
+type UtilityPaymentsResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		TotalCount int              `json:"totalCount"`
+		Entries    []UtilityPayment `json:"entries"`
+	} `json:"data"`
+	ErrorMessages interface{} `json:"errorMessages"`
+}
+
+// UtilityPayment is a payment for a utility service (electricity, gas, phone, ...)
+type UtilityPayment struct {
+	ID               string `json:"id"`
+	ServiceType      string `json:"serviceType"` // UtilityElectricity, UtilityGas, ...
+	Provider         string `json:"provider"`
+	SubscriberNumber string `json:"subscriberNumber"`
+	Amount           Amount `json:"amount"`
+	DebitAccountID   string `json:"debitAccountId,omitempty"`
+	PaymentDate      string `json:"paymentDate"`
+	Status           string `json:"status"`
+}
+
+// CounterpartyAccount returns the account or card number on the other side of
+// the transaction: the debit account for income, the credit account otherwise
+func (t AccountTransaction) CounterpartyAccount() string {
+	if t.Direction() == DirectionCredit {
+		return t.DebitAccountNumber
+	}
+	return t.CreditAccountNumber
 }
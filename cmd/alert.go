@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/category"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/notify"
+	"github.com/ivan4th/ameriagrab/product"
+	"github.com/spf13/cobra"
+)
+
+var (
+	alertProduct     string
+	alertCurrency    string
+	alertCategory    string
+	alertRollover    bool
+	alertJSONOutput  bool
+	alertHistorySize int
+)
+
+var alertCmd = &cobra.Command{
+	Use:   "alert",
+	Short: "Manage alert rules evaluated after each sync",
+	Long: `Alert rules are evaluated after every sync (including daemon mode).
+Matched alerts are stored in the local database.
+
+Rule kinds:
+  balance-below <amount>   available balance of a product drops below amount (requires --product)
+  txn-above <amount>       a newly synced debit exceeds amount
+  new-counterparty         a newly synced transaction involves a counterparty never seen before
+  budget <amount>          total debits in the current month exceed amount (requires --currency);
+                           --category limits it to one spending category, --rollover carries
+                           unspent amounts over to the next month (see 'budget report')
+  payment-due <days>       a loan payment is due within the given number of days (--product takes a loan ID)`,
+}
+
+var alertAddCmd = &cobra.Command{
+	Use:   "add <kind> [amount]",
+	Short: "Add an alert rule",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		rule, err := parseAlertRule(database, args)
+		if err != nil {
+			return err
+		}
+
+		id, err := database.AddAlertRule(rule)
+		if err != nil {
+			return fmt.Errorf("adding alert rule: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Added alert rule #%d\n", id)
+		return nil
+	},
+}
+
+// parseAlertRule validates arguments and flags for 'alert add'
+func parseAlertRule(database *db.DB, args []string) (db.AlertRule, error) {
+	rule := db.AlertRule{
+		Kind:     args[0],
+		Currency: strings.ToUpper(alertCurrency),
+		Category: strings.ToLower(alertCategory),
+		Rollover: alertRollover,
+	}
+
+	needsAmount := rule.Kind != db.AlertNewCounterparty
+	switch {
+	case !isAlertKind(rule.Kind):
+		return rule, fmt.Errorf("unknown alert kind %q (expected one of: %s)", rule.Kind, strings.Join(db.AlertKinds, ", "))
+	case needsAmount && len(args) < 2:
+		return rule, fmt.Errorf("alert kind %s requires an amount", rule.Kind)
+	case !needsAmount && len(args) > 1:
+		return rule, fmt.Errorf("alert kind %s does not take an amount", rule.Kind)
+	case rule.Kind == db.AlertBalanceBelow && alertProduct == "":
+		return rule, fmt.Errorf("alert kind %s requires --product", rule.Kind)
+	case rule.Kind == db.AlertBudget && rule.Currency == "":
+		return rule, fmt.Errorf("alert kind %s requires --currency", rule.Kind)
+	case rule.Kind != db.AlertBudget && (rule.Category != "" || rule.Rollover):
+		return rule, fmt.Errorf("--category and --rollover only apply to budgets")
+	case rule.Category != "" && category.Icons[rule.Category] == "":
+		return rule, fmt.Errorf("unknown category %q (expected one of: %s)", rule.Category, strings.Join(categoryNames(), ", "))
+	}
+
+	if needsAmount {
+		amount, err := strconv.ParseFloat(args[1], 64)
+		if err != nil || amount < 0 {
+			return rule, fmt.Errorf("invalid amount %q", args[1])
+		}
+		rule.Threshold = amount
+	}
+
+	if rule.Kind == db.AlertPaymentDue {
+		// Loans are not products; match by loan ID directly
+		rule.ProductID = alertProduct
+		return rule, nil
+	}
+
+	if alertProduct != "" {
+		p, err := product.Resolve(database, alertProduct)
+		if err != nil {
+			return rule, err
+		}
+		rule.ProductID = p.ID
+	}
+
+	return rule, nil
+}
+
+// categoryNames returns the names of the spending categories, sorted
+func categoryNames() []string {
+	names := make([]string, 0, len(category.Icons))
+	for name := range category.Icons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isAlertKind(kind string) bool {
+	for _, k := range db.AlertKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+var alertListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List alert rules",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		rules, err := database.GetAlertRules()
+		if err != nil {
+			return fmt.Errorf("fetching alert rules: %w", err)
+		}
+
+		if alertJSONOutput {
+			out, err := json.MarshalIndent(rules, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling alert rules: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		if len(rules) == 0 {
+			fmt.Println("No alert rules defined. Use 'alert add' to create one.")
+			return nil
+		}
+		display.PrintAlertRules(rules)
+		return nil
+	},
+}
+
+var alertRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "Remove an alert rule",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid rule ID %q", args[0])
+		}
+
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		found, err := database.DeleteAlertRule(id)
+		if err != nil {
+			return fmt.Errorf("removing alert rule: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("alert rule #%d not found", id)
+		}
+		fmt.Fprintf(os.Stderr, "Removed alert rule #%d\n", id)
+		return nil
+	},
+}
+
+var alertHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show fired alerts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		alerts, err := database.GetAlerts(alertHistorySize)
+		if err != nil {
+			return fmt.Errorf("fetching alerts: %w", err)
+		}
+
+		if alertJSONOutput {
+			out, err := json.MarshalIndent(alerts, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling alerts: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		if len(alerts) == 0 {
+			fmt.Println("No alerts have fired yet.")
+			return nil
+		}
+		display.PrintAlerts(alerts)
+		return nil
+	},
+}
+
+// checkAlerts evaluates alert rules against transactions synced since the given time
+// and reports newly fired alerts, dispatching them through the notifier
+func checkAlerts(database *db.DB, since time.Time, notifier *notify.Notifier) {
+	alerts, err := database.EvaluateAlertRules(since, category.Default().Categorize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to evaluate alert rules: %v\n", err)
+		return
+	}
+	for _, a := range alerts {
+		fmt.Fprintf(os.Stderr, "ALERT: %s\n", a.Message)
+		sendNotification(notifier, notify.Message{
+			Title:    "ameriagrab alert: " + a.Kind,
+			Body:     a.Message,
+			Priority: notify.PriorityHigh,
+		})
+	}
+}
+
+func init() {
+	alertAddCmd.Flags().StringVarP(&alertProduct, "product", "p", "", "Product ID or name the rule applies to (default: all products)")
+	alertAddCmd.Flags().StringVarP(&alertCurrency, "currency", "c", "", "Only consider transactions in this currency")
+	alertAddCmd.Flags().StringVar(&alertCategory, "category", "", "Budget only: only count spending in this category")
+	alertAddCmd.Flags().BoolVar(&alertRollover, "rollover", false, "Budget only: carry unspent amounts over to the next month")
+	alertListCmd.Flags().BoolVarP(&alertJSONOutput, "json", "j", false, "Output as JSON")
+	alertHistoryCmd.Flags().BoolVarP(&alertJSONOutput, "json", "j", false, "Output as JSON")
+	alertHistoryCmd.Flags().IntVarP(&alertHistorySize, "size", "s", 50, "Number of alerts to show (0 for all)")
+
+	alertCmd.AddCommand(alertAddCmd)
+	alertCmd.AddCommand(alertListCmd)
+	alertCmd.AddCommand(alertRemoveCmd)
+	alertCmd.AddCommand(alertHistoryCmd)
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/notify"
+	"github.com/ivan4th/ameriagrab/telegram"
+	"github.com/spf13/cobra"
+)
+
+var botCmd = &cobra.Command{
+	Use:   "bot",
+	Short: "Run an interactive Telegram bot",
+	Long: `Runs a Telegram bot answering commands from whitelisted chats:
+
+  /balance       balances of all accounts and cards
+  /last [N]      last N transactions (default 10)
+  /search TEXT   transactions with TEXT in counterparty or details
+  /sync          download new transactions now
+
+The bot uses the notify.telegram.token bot token. Only chats listed in
+bot.allowed_chats (or AMERIA_BOT_ALLOWED_CHATS) are answered; if neither is set,
+the notify.telegram.chat_id chat is allowed. Messages from other chats are ignored.
+
+Run 'daemon --bot' instead to serve the bot alongside scheduled syncs.
+
+Environment variables:
+  AMERIA_DB_PATH               - Path to SQLite database file (required)
+  AMERIA_NOTIFY_TELEGRAM_TOKEN - Bot token (overrides config)
+  AMERIA_BOT_ALLOWED_CHATS     - Comma-separated chat IDs (overrides config)
+  AMERIA_USERNAME, AMERIA_PASSWORD - Credentials used by /sync`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		bot, err := newTelegramBot(database)
+		if err != nil {
+			return err
+		}
+
+		// The client is set up on the first /sync, so read-only use needs no login
+		var c *client.Client
+		var accessToken string
+		bot.Sync = func(ctx context.Context) error {
+			var err error
+			if c == nil {
+				c, accessToken, err = SetupClient(cmd.Context())
+			} else {
+				accessToken, err = ensureSession(c, accessToken)
+			}
+			if err != nil {
+				c = nil
+				return err
+			}
+			_, err = runSync(ctx, database, c, accessToken, "bot")
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Fprintln(os.Stderr, "Telegram bot started")
+		return bot.Run(ctx)
+	},
+}
+
+// newTelegramBot builds the bot from the config file, overridden by environment variables
+func newTelegramBot(database *db.DB) (*telegram.Bot, error) {
+	cfg := LoadConfig()
+	settings := notify.Settings{
+		TelegramToken:  cfg.Notify.Telegram.Token,
+		TelegramChatID: cfg.Notify.Telegram.ChatID,
+	}
+	settings.ApplyEnv()
+	if settings.TelegramToken == "" {
+		return nil, fmt.Errorf("no Telegram bot token (use 'config set notify.telegram.token' or AMERIA_NOTIFY_TELEGRAM_TOKEN)")
+	}
+
+	allowed := cfg.Bot.AllowedChats
+	if env := os.Getenv("AMERIA_BOT_ALLOWED_CHATS"); env != "" {
+		allowed = env
+	}
+	if allowed == "" {
+		allowed = settings.TelegramChatID
+	}
+	var chatIDs []int64
+	for _, s := range notify.SplitList(allowed) {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed chat ID %q (expected a number)", s)
+		}
+		chatIDs = append(chatIDs, id)
+	}
+	if len(chatIDs) == 0 {
+		return nil, fmt.Errorf("no allowed chats (use 'config set bot.allowed_chats' or AMERIA_BOT_ALLOWED_CHATS)")
+	}
+
+	bot := telegram.New(settings.TelegramToken, chatIDs, database)
+	bot.Logf = func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+	return bot, nil
+}
+
+// daemonSyncTrigger returns a bot sync function that asks the daemon loop to sync
+// immediately and waits for the result
+func daemonSyncTrigger(requests chan<- chan error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		reply := make(chan error, 1)
+		select {
+		case requests <- reply:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		select {
+		case err := <-reply:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
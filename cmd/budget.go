@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/category"
+	"github.com/ivan4th/ameriagrab/output"
+	"github.com/ivan4th/ameriagrab/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	budgetLast       string
+	budgetJSONOutput bool
+)
+
+var budgetCmd = &cobra.Command{
+	Use:   "budget",
+	Short: "Budgets: 'budget' alert rules over time",
+	Long: `Budgets are 'budget' alert rules (see 'alert add budget'): a monthly amount
+for spending in one currency, optionally on one product (--product) or in one
+spending category (--category). With --rollover, whatever is left of a month's
+budget is added to the next month's, starting with the month the budget was
+added; overspending isn't carried over.`,
+}
+
+var budgetReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Compare budgets with actual spending per month",
+	Long: `Prints each budget's last --last months up to the current one: the budget,
+the amount carried over from the previous month, the spending counted against
+it and what's left of both (negative when over budget). --last takes a
+number of months with an optional "m" suffix, or of years with a "y" suffix.
+
+Examples:
+  ameriagrab budget report
+  ameriagrab budget report --last 12m --output csv`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := display.CheckFormat(); err != nil {
+			return err
+		}
+		months, err := parseMonths(budgetLast)
+		if err != nil {
+			return err
+		}
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		now := time.Now()
+		from := time.Date(now.Year(), now.Month()+time.Month(1-months), 1, 0, 0, 0, 0, time.Local)
+		budgets, err := report.Budgets(database, from, now, category.Default().Categorize)
+		if err != nil {
+			return fmt.Errorf("computing budgets: %w", err)
+		}
+
+		if budgetJSONOutput {
+			out, err := json.MarshalIndent(budgets, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling budgets: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		if len(budgets) == 0 {
+			fmt.Println("No budgets defined. Use 'alert add budget' to create one.")
+			return nil
+		}
+		display.PrintBudgets(budgets)
+		return nil
+	},
+}
+
+// parseMonths parses a number of months for --last: a positive number with
+// an optional "m" suffix, or a number of years with a "y" suffix
+func parseMonths(s string) (int, error) {
+	count, unit := strings.TrimSuffix(s, "m"), 1
+	if years, ok := strings.CutSuffix(s, "y"); ok {
+		count, unit = years, 12
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid number of months %q", s)
+	}
+	return n * unit, nil
+}
+
+func init() {
+	budgetReportCmd.Flags().StringVar(&budgetLast, "last", "6m", "Number of months to show, e.g. 6m or 1y")
+	budgetReportCmd.Flags().BoolVarP(&budgetJSONOutput, "json", "j", false, "Output as JSON")
+	budgetReportCmd.Flags().StringVar(&display.Format, "output", output.FormatTable, "Table format: table, markdown, html or csv")
+
+	budgetCmd.AddCommand(budgetReportCmd)
+}
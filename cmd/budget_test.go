@@ -0,0 +1,24 @@
+package cmd
+
+import "testing"
+
+func TestParseMonths(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want int
+	}{
+		{"6", 6},
+		{"6m", 6},
+		{"1y", 12},
+		{"2y", 24},
+	} {
+		if got, err := parseMonths(tt.s); err != nil || got != tt.want {
+			t.Errorf("parseMonths(%q) = %d, %v, want %d", tt.s, got, err, tt.want)
+		}
+	}
+	for _, s := range []string{"", "m", "0m", "-1", "6w", "1.5y"} {
+		if _, err := parseMonths(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
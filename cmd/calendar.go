@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/calendar"
+	"github.com/spf13/cobra"
+)
+
+var calendarOutput string
+
+var calendarCmd = &cobra.Command{
+	Use:   "calendar",
+	Short: "Export upcoming payments as an iCalendar (.ics) feed",
+	Long: `Writes an iCalendar feed with all-day events for:
+
+  - the next payment of each loan
+  - predicted charges of recurring payments (weekly, monthly or yearly debits to
+    the same counterparty with a similar amount), repeated up to a year ahead
+
+Events keep stable UIDs, so re-importing or re-subscribing updates them in place.
+The same feed is served by 'serve' at /api/v1/calendar.ics?token=<token>.
+
+Environment variables:
+  AMERIA_DB_PATH - Path to SQLite database file (required)`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		cal, err := calendar.Feed(database, time.Now())
+		if err != nil {
+			return fmt.Errorf("building calendar: %w", err)
+		}
+
+		if calendarOutput == "" {
+			return cal.Write(os.Stdout, time.Now())
+		}
+		f, err := os.Create(calendarOutput)
+		if err != nil {
+			return fmt.Errorf("creating output file: %w", err)
+		}
+		if err := cal.Write(f, time.Now()); err != nil {
+			f.Close()
+			return fmt.Errorf("writing calendar: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("writing calendar: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d events to %s\n", len(cal.Events), calendarOutput)
+		return nil
+	},
+}
+
+func init() {
+	calendarCmd.Flags().StringVarP(&calendarOutput, "output", "o", "", "Output file (default: stdout)")
+}
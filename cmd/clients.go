@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/spf13/cobra"
+)
+
+// clientSelector is the --client flag: the client (personal or company) to act
+// for, and to restrict local data to. Empty means the session's default client
+// for API calls and all clients for local data.
+var clientSelector string
+
+var clientsJSONOutput bool
+
+var clientsCmd = &cobra.Command{
+	Use:   "clients",
+	Short: "List the clients (personal and company) the user can act for",
+	Long: `Lists the clients the user is attached to, e.g. a personal and a company
+client. Pass a client ID as --client to work with that client's products instead
+of the default one; 'sync --all-clients' syncs the products of every client into
+the same database.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, accessToken, err := SetupClient(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		clients, err := c.GetClients(accessToken)
+		if err != nil {
+			return fmt.Errorf("fetching clients: %w", err)
+		}
+
+		if clientsJSONOutput {
+			out, err := json.MarshalIndent(clients, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling clients: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+		display.PrintClients(clients, c.ClientID)
+		return nil
+	},
+}
+
+// selectedClientProducts returns the products of the client given by --client,
+// or all products if it's not set
+func selectedClientProducts(products []client.ProductInfo) []client.ProductInfo {
+	if clientSelector == "" {
+		return products
+	}
+	var selected []client.ProductInfo
+	for _, p := range products {
+		if p.ClientID == clientSelector {
+			selected = append(selected, p)
+		}
+	}
+	return selected
+}
+
+// checkSelectedClient returns an error if a stored product doesn't belong to the
+// client given by --client
+func checkSelectedClient(p *client.ProductInfo) error {
+	if clientSelector != "" && p.ClientID != clientSelector {
+		return fmt.Errorf("product %s doesn't belong to client %s", p.ID, clientSelector)
+	}
+	return nil
+}
+
+// syncClientIDs returns the clients whose products are synced: all of the user's
+// clients with --all-clients, otherwise just the current one
+func syncClientIDs(c interface {
+	GetClients(accessToken string) ([]client.ClientInfo, error)
+}, accessToken, current string) ([]string, error) {
+	if !syncAllClients {
+		return []string{current}, nil
+	}
+	clients, err := c.GetClients(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("fetching clients: %w", err)
+	}
+	ids := make([]string, len(clients))
+	for i, cl := range clients {
+		ids[i] = cl.ID
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no clients found")
+	}
+	return ids, nil
+}
+
+func init() {
+	clientsCmd.Flags().BoolVarP(&clientsJSONOutput, "json", "j", false, "Output as JSON")
+}
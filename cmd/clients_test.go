@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+type mockClientsClient struct {
+	clients []client.ClientInfo
+}
+
+func (m *mockClientsClient) GetClients(accessToken string) ([]client.ClientInfo, error) {
+	return m.clients, nil
+}
+
+func TestSyncClientIDs(t *testing.T) {
+	defer func() { syncAllClients = false }()
+	mockClient := &mockClientsClient{clients: []client.ClientInfo{{ID: "personal", Default: true}, {ID: "company"}}}
+
+	syncAllClients = false
+	ids, err := syncClientIDs(mockClient, "token", "personal")
+	if err != nil || len(ids) != 1 || ids[0] != "personal" {
+		t.Errorf("expected just the current client, got %v (%v)", ids, err)
+	}
+
+	syncAllClients = true
+	ids, err = syncClientIDs(mockClient, "token", "personal")
+	if err != nil || len(ids) != 2 || ids[1] != "company" {
+		t.Errorf("expected all clients, got %v (%v)", ids, err)
+	}
+}
+
+func TestSelectedClientProducts(t *testing.T) {
+	defer func() { clientSelector = "" }()
+	products := []client.ProductInfo{{ID: "p1", ClientID: "personal"}, {ID: "c1", ClientID: "company"}}
+
+	clientSelector = ""
+	if got := selectedClientProducts(products); len(got) != 2 {
+		t.Errorf("expected all products without --client, got %+v", got)
+	}
+	if err := checkSelectedClient(&products[1]); err != nil {
+		t.Errorf("unexpected error without --client: %v", err)
+	}
+
+	clientSelector = "company"
+	if got := selectedClientProducts(products); len(got) != 1 || got[0].ID != "c1" {
+		t.Errorf("expected only the company's products, got %+v", got)
+	}
+	if err := checkSelectedClient(&products[0]); err == nil {
+		t.Error("expected error for another client's product")
+	}
+}
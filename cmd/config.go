@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ivan4th/ameriagrab/config"
+	"github.com/ivan4th/ameriagrab/output"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and edit the config file",
+	Long: `View and edit the YAML config file without hand-editing it.
+
+The file is located at $AMERIA_CONFIG, or ameriagrab/config.yaml in the user
+config directory. Environment variables (e.g. AMERIA_NOTIFY_*) take precedence
+over values from the config file.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a config key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, _, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		value, err := cfg.Get(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a config key",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateConfig(args[0], args[1])
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a config key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateConfig(args[0], "")
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all config keys and their values",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, _, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+
+		keys := cfg.ListKeys()
+		entries := make([]output.ConfigEntry, len(keys))
+		for i, key := range keys {
+			value, _ := cfg.Get(key.Name)
+			if key.Secret && value != "" {
+				value = strings.Repeat("*", 8)
+			}
+			entries[i] = output.ConfigEntry{Key: key.Name, Value: value, Description: key.Description}
+		}
+		display.PrintConfig(entries)
+		return nil
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the config file path",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.Path()
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+	},
+}
+
+// loadConfigFile loads the config file, failing on errors (unlike LoadConfig)
+func loadConfigFile() (*config.Config, string, error) {
+	path, err := config.Path()
+	if err != nil {
+		return nil, "", err
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return cfg, path, nil
+}
+
+// updateConfig validates and stores a config value; an empty value unsets the key
+func updateConfig(key, value string) error {
+	cfg, path, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+	if err := cfg.Set(key, value); err != nil {
+		return err
+	}
+	if err := cfg.Save(path); err != nil {
+		return err
+	}
+	if value == "" {
+		fmt.Fprintf(os.Stderr, "Unset %s\n", key)
+	} else {
+		fmt.Fprintf(os.Stderr, "Set %s\n", key)
+	}
+	return nil
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configPathCmd)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	counterpartiesSearch     string
+	counterpartiesSort       string
+	counterpartiesLimit      int
+	counterpartiesRebuild    bool
+	counterpartiesJSONOutput bool
+)
+
+var counterpartiesCmd = &cobra.Command{
+	Use:   "counterparties",
+	Short: "List everyone money was sent to, with total volume and last transfer date",
+	Long: `Lists the counterparty directory: everyone outgoing transfers went to, with
+their masked card or account number, the total sent and the number and dates of
+the transfers, per currency. Transfers to the same card or account are counted
+together, named by the latest real beneficiary name or else by the matching
+transfer template.
+
+The directory is rebuilt from the stored transaction history after each sync;
+--rebuild rebuilds it first (e.g. after upgrading from a version without it).
+Transactions whose counterparty has no name take it from the directory.
+
+--search filters by name or number, --sort orders by volume (default), recent
+or name.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		if counterpartiesRebuild {
+			n, err := database.RebuildCounterparties()
+			if err != nil {
+				return fmt.Errorf("rebuilding counterparties: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Rebuilt directory of %d counterparties\n", n)
+		}
+
+		counterparties, err := database.GetCounterparties(db.CounterpartyOptions{
+			Search: counterpartiesSearch,
+			Sort:   counterpartiesSort,
+			Limit:  counterpartiesLimit,
+		})
+		if err != nil {
+			return fmt.Errorf("fetching counterparties: %w", err)
+		}
+
+		if counterpartiesJSONOutput {
+			out, err := json.MarshalIndent(counterparties, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling counterparties: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+		if len(counterparties) == 0 {
+			fmt.Println("No counterparties found.")
+			return nil
+		}
+		display.PrintCounterparties(counterparties)
+		return nil
+	},
+}
+
+func init() {
+	counterpartiesCmd.Flags().StringVar(&counterpartiesSearch, "search", "", "Filter by name or card/account number")
+	counterpartiesCmd.Flags().StringVar(&counterpartiesSort, "sort", db.CounterpartySortVolume, "Sort order: volume, recent or name")
+	counterpartiesCmd.Flags().IntVarP(&counterpartiesLimit, "limit", "n", 0, "Maximum number of counterparties (0 = all)")
+	counterpartiesCmd.Flags().BoolVar(&counterpartiesRebuild, "rebuild", false, "Rebuild the directory from the transaction history first")
+	counterpartiesCmd.Flags().BoolVarP(&counterpartiesJSONOutput, "json", "j", false, "Output as JSON")
+}
@@ -0,0 +1,369 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/backup"
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/schedule"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonEvery     time.Duration
+	daemonCron      string
+	daemonKeepAlive time.Duration
+	daemonSystemd   bool
+	daemonBackup    time.Duration
+	daemonBot       bool
+	daemonDigest    bool
+)
+
+// defaultDaemonKeepAlive is the default --keepalive interval
+const defaultDaemonKeepAlive = 10 * time.Minute
+
+// daemonBackupJob uploads encrypted database backups every --backup-every
+type daemonBackupJob struct {
+	remote     backup.Remote
+	passphrase string
+	last       time.Time
+}
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run sync periodically on a schedule",
+	Long: `Runs sync immediately and then repeatedly on a schedule until stopped.
+
+The schedule is given either as a fixed interval (--every 6h) or as a standard
+5-field cron expression (--cron "0 */6 * * *"). Between runs the session is kept
+alive by periodically validating the access token; if the session expires, a new
+login (with push confirmation) is performed before the next sync. A login that
+fails, e.g. because the push isn't confirmed in time or the bank can't be
+reached, is logged and retried at the next run; the daemon only exits when the
+credentials or the push confirmation are rejected.
+
+Products with a sync interval (see 'sync-interval') are only synced by the
+runs once it has elapsed, e.g. a salary card every 2h and a dormant account
+daily with --every 2h, which saves API calls. Syncs requested through the bot
+sync every product.
+
+Every run is logged to the sync_runs table. The daemon exits cleanly on SIGINT or
+SIGTERM. A sync in progress finishes the product it is syncing and skips the
+rest, so the run is logged as failed ("sync stopped after N of M products")
+while the products synced so far are kept.
+
+With --backup-every, an encrypted backup of the database is uploaded after the
+first sync and then after each sync once the interval has elapsed since the
+last successful backup (see
+'db backup' for remotes and the required AMERIA_BACKUP_PASSPHRASE). Backup
+failures are reported as warnings and retried after the next sync.
+
+With --digest, the previous month's digest (see 'report digest') is emailed after
+the first successful sync of each month.
+
+With --bot, the Telegram bot (see 'bot') is served alongside; its /sync command
+runs a sync immediately instead of waiting for the schedule.
+
+Use --systemd to print a systemd user unit file for running the daemon as a service.
+
+Environment variables:
+  AMERIA_DB_PATH - Path to SQLite database file (required)`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sched, err := daemonSchedule()
+		if err != nil {
+			return err
+		}
+		if syncAllClients && clientSelector != "" {
+			return fmt.Errorf("--all-clients can't be combined with --client")
+		}
+
+		if daemonSystemd {
+			return printSystemdUnit()
+		}
+
+		var backupJob *daemonBackupJob
+		if daemonBackup > 0 {
+			remote, passphrase, err := backupSettings()
+			if err != nil {
+				return err
+			}
+			backupJob = &daemonBackupJob{remote: remote, passphrase: passphrase}
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		var syncRequests chan chan error
+		if daemonBot {
+			bot, err := newTelegramBot(database)
+			if err != nil {
+				return err
+			}
+			syncRequests = make(chan chan error)
+			bot.Sync = daemonSyncTrigger(syncRequests)
+			go bot.Run(ctx)
+		}
+
+		c, accessToken, err := SetupClient(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				recordSyncFailure(database, "daemon", err)
+			}
+			return err
+		}
+
+		return runDaemon(ctx, database, c, accessToken, sched, backupJob, syncRequests)
+	},
+}
+
+// daemonSchedule builds the schedule from --every / --cron flags
+func daemonSchedule() (schedule.Schedule, error) {
+	if daemonCron != "" && daemonEvery != 0 {
+		return nil, fmt.Errorf("--every and --cron are mutually exclusive")
+	}
+	if daemonCron != "" {
+		sched, err := schedule.ParseCron(daemonCron)
+		if err != nil {
+			return nil, fmt.Errorf("parsing --cron: %w", err)
+		}
+		return sched, nil
+	}
+	if daemonEvery <= 0 {
+		return nil, fmt.Errorf("either --every or --cron must be specified")
+	}
+	if daemonEvery < time.Minute {
+		return nil, fmt.Errorf("--every must be at least 1m")
+	}
+	return schedule.Every(daemonEvery), nil
+}
+
+// runDaemon runs sync on the given schedule until the context is cancelled.
+// Requests received on syncRequests run an extra sync; its result is sent back on the request.
+func runDaemon(ctx context.Context, database *db.DB, c *client.Client, accessToken string, sched schedule.Schedule, backupJob *daemonBackupJob, syncRequests <-chan chan error) error {
+	fmt.Fprintln(os.Stderr, "Daemon started")
+	var reply chan error
+	for {
+		// Syncs requested by the bot don't wait for sync intervals
+		syncDueOnly = reply == nil
+		_, err := runSync(ctx, database, c, accessToken, "daemon")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: sync failed: %v\n", err)
+		} else if daemonDigest {
+			sendMonthlyDigest(database)
+		}
+		if reply != nil {
+			reply <- err
+		}
+		if ctx.Err() != nil {
+			fmt.Fprintln(os.Stderr, "Received shutdown signal, daemon stopped")
+			return nil
+		}
+		if backupJob != nil {
+			backupJob.run(ctx, database)
+		}
+
+		next := sched.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("schedule has no upcoming run times")
+		}
+		fmt.Fprintf(os.Stderr, "Next sync at %s\n", next.Format("2006-01-02 15:04:05"))
+
+		accessToken, reply, err = waitForNextRun(ctx, database, c, accessToken, sched, next, syncRequests)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			fmt.Fprintln(os.Stderr, "Received shutdown signal, daemon stopped")
+			return nil
+		}
+	}
+}
+
+// run uploads a backup if none succeeded within the backup interval
+func (j *daemonBackupJob) run(ctx context.Context, database *db.DB) {
+	if !j.last.IsZero() && time.Since(j.last) < daemonBackup {
+		return
+	}
+	name, err := backup.Upload(ctx, database, j.remote, j.passphrase)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: backup failed: %v\n", err)
+		return
+	}
+	j.last = time.Now()
+	fmt.Fprintf(os.Stderr, "Uploaded backup %s to %s\n", name, j.remote)
+}
+
+// waitForNextRun sleeps until the next run time, validating the session every
+// keep-alive interval and re-authenticating if it has expired. A failed
+// re-authentication is logged and tried again at the next run time (or sync
+// request), skipping the run it failed for; only errors the user has to fix
+// (see needsUser) end the wait with an error.
+// Returns early (with a nil error) if the context is cancelled, or with the
+// request if a sync is requested before the next run time.
+func waitForNextRun(ctx context.Context, database *db.DB, c *client.Client, accessToken string, sched schedule.Schedule, next time.Time, syncRequests <-chan chan error) (string, chan error, error) {
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	var keepAlive <-chan time.Time
+	if daemonKeepAlive > 0 {
+		ticker := time.NewTicker(daemonKeepAlive)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	// sessionFailed records a failed re-authentication and returns it if the
+	// daemon can't go on without the user. Keep-alives stop until the next
+	// run, so a login that isn't confirmed doesn't send a push every interval.
+	sessionFailed := func(err error) error {
+		recordSyncFailure(database, "daemon", err)
+		if needsUser(err) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: re-authentication failed, retrying at the next run: %v\n", err)
+		keepAlive = nil
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return accessToken, nil, nil
+		case <-timer.C:
+			token, err := ensureSession(c, accessToken)
+			if err == nil {
+				return token, nil, nil
+			}
+			if err := sessionFailed(err); err != nil {
+				return "", nil, err
+			}
+			if next = sched.Next(time.Now()); next.IsZero() {
+				return "", nil, fmt.Errorf("schedule has no upcoming run times")
+			}
+			fmt.Fprintf(os.Stderr, "Next sync at %s\n", next.Format("2006-01-02 15:04:05"))
+			timer.Reset(time.Until(next))
+		case reply := <-syncRequests:
+			token, err := ensureSession(c, accessToken)
+			if err == nil {
+				return token, reply, nil
+			}
+			reply <- err
+			if err := sessionFailed(err); err != nil {
+				return "", nil, err
+			}
+		case <-keepAlive:
+			token, err := ensureSession(c, accessToken)
+			if err == nil {
+				accessToken = token
+			} else if err := sessionFailed(err); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+}
+
+// needsUser reports whether a login error can't be fixed by trying again
+// later: rejected credentials or push confirmation, or a confirmation method
+// that isn't supported
+func needsUser(err error) bool {
+	return errors.Is(err, client.ErrLoginRejected) || errors.Is(err, client.ErrUnsupportedApprovalFlow)
+}
+
+// ensureSession validates the session and performs a fresh login if it is no longer valid
+func ensureSession(c *client.Client, accessToken string) (string, error) {
+	if c.ValidateSession(accessToken) {
+		return accessToken, nil
+	}
+	fmt.Fprintln(os.Stderr, "Session expired, re-authenticating...")
+	return authenticate(c)
+}
+
+// printSystemdUnit prints a systemd user unit running the daemon with the current schedule flags
+func printSystemdUnit() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("determining executable path: %w", err)
+	}
+
+	args := fmt.Sprintf("--every %s", daemonEvery)
+	if daemonCron != "" {
+		args = fmt.Sprintf("--cron %q", daemonCron)
+	}
+	if daemonBot {
+		args += " --bot"
+	}
+	if daemonDigest {
+		args += " --digest"
+	}
+	if daemonKeepAlive != defaultDaemonKeepAlive {
+		args += fmt.Sprintf(" --keepalive %s", daemonKeepAlive)
+	}
+	if syncSnapshotDaily {
+		args += " --snapshot-daily"
+	} else if syncSnapshot {
+		args += " --snapshot"
+	}
+	if syncVerbose {
+		args += " --verbose"
+	}
+	if syncAllClients {
+		args += " --all-clients"
+	} else if clientSelector != "" {
+		args += " --client " + clientSelector
+	}
+	if daemonBackup > 0 {
+		args += fmt.Sprintf(" --backup-every %s", daemonBackup)
+		if dbRemote != "" {
+			args += fmt.Sprintf(" --backup-remote %q", dbRemote)
+		}
+	}
+
+	fmt.Printf(`# Save as ~/.config/systemd/user/ameriagrab.service, then run:
+#   systemctl --user daemon-reload
+#   systemctl --user enable --now ameriagrab
+#
+# ~/.config/ameriagrab/env must define AMERIA_USERNAME, AMERIA_PASSWORD and AMERIA_DB_PATH.
+[Unit]
+Description=ameriagrab scheduled sync
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+ExecStart=%s daemon %s
+EnvironmentFile=%%h/.config/ameriagrab/env
+Restart=on-failure
+RestartSec=60
+
+[Install]
+WantedBy=default.target
+`, exe, args)
+	return nil
+}
+
+func init() {
+	daemonCmd.Flags().DurationVarP(&daemonEvery, "every", "e", 0, "Sync interval (e.g. 6h, 30m)")
+	daemonCmd.Flags().StringVar(&daemonCron, "cron", "", "Cron expression for sync times (e.g. \"0 */6 * * *\")")
+	daemonCmd.Flags().DurationVar(&daemonKeepAlive, "keepalive", defaultDaemonKeepAlive, "Session keep-alive interval (0 to disable)")
+	daemonCmd.Flags().DurationVar(&daemonBackup, "backup-every", 0, "Upload an encrypted database backup at this interval (e.g. 24h)")
+	daemonCmd.Flags().StringVar(&dbRemote, "backup-remote", "", "Backup remote (defaults to the backup.remote config key)")
+	daemonCmd.Flags().BoolVar(&daemonDigest, "digest", false, "Email the previous month's digest at the start of each month")
+	daemonCmd.Flags().BoolVar(&daemonBot, "bot", false, "Serve the Telegram bot alongside scheduled syncs")
+	daemonCmd.Flags().BoolVar(&daemonSystemd, "systemd", false, "Print a systemd unit file and exit")
+	daemonCmd.Flags().BoolVarP(&syncVerbose, "verbose", "v", false, "Verbose output")
+	daemonCmd.Flags().BoolVarP(&syncSnapshot, "snapshot", "s", false, "Create balance snapshot after each sync")
+	daemonCmd.Flags().BoolVar(&syncSnapshotDaily, "snapshot-daily", false, "Keep at most one snapshot per day, replacing the earlier one")
+	daemonCmd.Flags().BoolVar(&syncAllClients, "all-clients", false, "Sync the products of all clients (personal and company)")
+}
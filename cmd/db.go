@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/backup"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dbRemote string
+	dbOutput string
+
+	fixtureTransactions int
+	fixtureSeed         uint64
+
+	downgradeVersion int
+
+	diffJSONOutput bool
+
+	lintFix        bool
+	lintJSONOutput bool
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Database maintenance",
+}
+
+var dbBackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Upload an encrypted backup of the database",
+	Long: `Takes a consistent copy of the database, encrypts it with AES-256-GCM using a key
+derived from AMERIA_BACKUP_PASSPHRASE and uploads it to the remote under a
+timestamped name (ameria-YYYYMMDDTHHMMSSZ.db.enc). Older backups are kept;
+the LATEST file on the remote names the most recent one.
+
+Remotes:
+  s3://bucket/path      S3 or compatible storage. Credentials from AWS_ACCESS_KEY_ID and
+                        AWS_SECRET_ACCESS_KEY (and AWS_SESSION_TOKEN), region from AWS_REGION
+                        (default us-east-1), custom endpoint from AMERIA_S3_ENDPOINT.
+  webdav://host/path    WebDAV over HTTPS (webdav+http:// for plain HTTP). Credentials from
+                        the URL or AMERIA_WEBDAV_USER and AMERIA_WEBDAV_PASSWORD.
+
+The remote is taken from --remote or the backup.remote config key.
+Use 'daemon --backup-every' for scheduled backups.
+
+Environment variables:
+  AMERIA_DB_PATH           - Path to SQLite database file (required)
+  AMERIA_BACKUP_PASSPHRASE - Encryption passphrase (required)`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		remote, passphrase, err := backupSettings()
+		if err != nil {
+			return err
+		}
+
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		name, err := backup.Upload(context.Background(), database, remote, passphrase)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Uploaded backup %s to %s\n", name, remote)
+		return nil
+	},
+}
+
+var dbRestoreCmd = &cobra.Command{
+	Use:   "restore [name]",
+	Short: "Download and decrypt a backup",
+	Long: `Downloads a backup from the remote (the latest one unless a name is given),
+decrypts it with AMERIA_BACKUP_PASSPHRASE and writes the database to --output.
+The output file must not exist.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		remote, passphrase, err := backupSettings()
+		if err != nil {
+			return err
+		}
+		name := ""
+		if len(args) > 0 {
+			name = args[0]
+		}
+
+		restored, err := backup.Download(context.Background(), remote, name, dbOutput, passphrase)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Restored backup %s to %s\n", restored, dbOutput)
+		return nil
+	},
+}
+
+var dbFixtureCmd = &cobra.Command{
+	Use:   "fixture",
+	Short: "Create a database of generated transactions for profiling",
+	Long: `Creates a database at --output filled with generated cards, accounts and
+transactions (the same ones for the same --seed), to profile commands on a
+large database:
+
+  ameriagrab db fixture -o /tmp/big.db --transactions 200000
+  AMERIA_DB_PATH=/tmp/big.db ameriagrab get card-001 --local --combined
+
+The output file must not exist. The benchmarks of the db and report packages
+use the same data (go test -bench . ./db ./report).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := os.Stat(dbOutput); err == nil {
+			return fmt.Errorf("%s already exists", dbOutput)
+		}
+		database, err := db.Open(dbOutput)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer database.Close()
+
+		if err := database.GenerateFixture(db.FixtureOptions{Transactions: fixtureTransactions, Seed: fixtureSeed}); err != nil {
+			return fmt.Errorf("generating transactions: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Created %s with %d card transactions\n", dbOutput, fixtureTransactions)
+		return nil
+	},
+}
+
+var dbDowngradeCmd = &cobra.Command{
+	Use:   "downgrade",
+	Short: "Roll the database schema back for an older version of ameriagrab",
+	Long: `Rolls the database schema back to --to with the down migrations of this
+version of ameriagrab, so that an older one can use the database. Older
+versions refuse to open a database whose schema they aren't compatible with
+and name the schema version they need.
+
+Data stored in the rolled back tables and columns (e.g. pending holds or
+template groups) is lost; take a backup first ('db backup'). Some versions
+can't be rolled back, e.g. 18, which normalized stored dates; nothing is
+changed then. Running any command of this version migrates the database
+up again.
+
+Environment variables:
+  AMERIA_DB_PATH - Path to SQLite database file (required)`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		version, err := database.GetSchemaVersion()
+		if err != nil {
+			return err
+		}
+		if err := database.Downgrade(downgradeVersion); err != nil {
+			return &exitError{ExitDatabase, err}
+		}
+		fmt.Fprintf(os.Stderr, "Rolled the database schema back from version %d to %d\n", version, downgradeVersion)
+		return nil
+	},
+}
+
+var dbDiffCmd = &cobra.Command{
+	Use:   "diff <other.db>",
+	Short: "Compare products and transactions with another database",
+	Long: `Compares the products and transactions of the database with another database
+file (e.g. a copy synced on another machine) and lists the rows stored in only
+one of them, to see what consolidating the histories would bring in. Rows are
+matched by ID and date, as transactions are stored; changed fields of matching
+rows aren't reported.
+
+The other database is opened read-only and isn't migrated, so it may be at an
+older schema version. Use --output csv or --json to export the differences.
+
+Environment variables:
+  AMERIA_DB_PATH - Path to SQLite database file (required)`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := display.CheckFormat(); err != nil {
+			return err
+		}
+
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		other, err := db.OpenReadOnly(args[0])
+		if err != nil {
+			return fmt.Errorf("opening %s: %w", args[0], err)
+		}
+		defer other.Close()
+
+		diff, err := database.Diff(other)
+		if err != nil {
+			return fmt.Errorf("comparing databases: %w", err)
+		}
+
+		if diffJSONOutput {
+			out, err := json.MarshalIndent(diff, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling diff: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+		if len(diff.OnlyHere) == 0 && len(diff.OnlyOther) == 0 {
+			fmt.Println("The databases have the same products and transactions.")
+			return nil
+		}
+		display.PrintDatabaseDiff(diff, os.Getenv("AMERIA_DB_PATH"), args[0])
+		return nil
+	},
+}
+
+var dbLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Flag suspicious stored transactions",
+	Long: `Checks the stored transactions for rows that look wrong:
+
+  zero-amount       the amount is 0 or missing
+  missing-currency  the currency is missing
+  bad-date          the date is missing or can't be parsed
+  future-date       the date is more than a day ahead of now
+  empty-extended    a linked account transaction's extended info (beneficiary,
+                    SWIFT details, ...) was fetched, but all of it is empty
+
+--fix fixes the known-safe cases: a linked account transaction without a
+currency gets its account's (the card's account history is in the account
+currency), and empty extended info is marked as not fetched, so that the next
+sync fetches it again. The other issues need a look at the rows, and
+possibly 'sync --force' to download them again.
+
+Environment variables:
+  AMERIA_DB_PATH - Path to SQLite database file (required)`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := display.CheckFormat(); err != nil {
+			return err
+		}
+
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		issues, err := database.Lint(time.Now())
+		if err != nil {
+			return fmt.Errorf("checking the database: %w", err)
+		}
+
+		if lintJSONOutput {
+			if issues == nil {
+				issues = []db.LintIssue{}
+			}
+			out, err := json.MarshalIndent(issues, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling issues: %w", err)
+			}
+			fmt.Println(string(out))
+		} else if len(issues) == 0 {
+			fmt.Println("No suspicious rows found.")
+		} else {
+			display.PrintLintIssues(issues)
+		}
+
+		if lintFix {
+			fixed, err := database.FixLintIssues(issues)
+			if err != nil {
+				return fmt.Errorf("fixing issues: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Fixed %d rows\n", fixed)
+		}
+		return nil
+	},
+}
+
+// backupSettings resolves the backup remote and passphrase
+func backupSettings() (backup.Remote, string, error) {
+	passphrase := os.Getenv("AMERIA_BACKUP_PASSPHRASE")
+	if passphrase == "" {
+		return nil, "", fmt.Errorf("AMERIA_BACKUP_PASSPHRASE environment variable must be set")
+	}
+	remoteURL := dbRemote
+	if remoteURL == "" {
+		remoteURL = LoadConfig().Backup.Remote
+	}
+	if remoteURL == "" {
+		return nil, "", fmt.Errorf("no backup remote (use --remote or 'config set backup.remote')")
+	}
+	remote, err := backup.ParseRemote(remoteURL)
+	if err != nil {
+		return nil, "", err
+	}
+	return remote, passphrase, nil
+}
+
+func init() {
+	dbBackupCmd.Flags().StringVar(&dbRemote, "remote", "", "Remote location (s3://bucket/path or webdav://host/path)")
+	dbRestoreCmd.Flags().StringVar(&dbRemote, "remote", "", "Remote location (s3://bucket/path or webdav://host/path)")
+	dbRestoreCmd.Flags().StringVarP(&dbOutput, "output", "o", "", "Path of the restored database (must not exist)")
+	dbRestoreCmd.MarkFlagRequired("output")
+	dbFixtureCmd.Flags().StringVarP(&dbOutput, "output", "o", "", "Path of the new database (must not exist)")
+	dbFixtureCmd.Flags().IntVar(&fixtureTransactions, "transactions", 100000, "Number of card transactions")
+	dbFixtureCmd.Flags().Uint64Var(&fixtureSeed, "seed", 1, "Random seed")
+	dbFixtureCmd.MarkFlagRequired("output")
+	dbDowngradeCmd.Flags().IntVar(&downgradeVersion, "to", 0, "Schema version to roll back to")
+	dbDowngradeCmd.MarkFlagRequired("to")
+	dbCmd.AddCommand(dbBackupCmd)
+	dbCmd.AddCommand(dbRestoreCmd)
+	dbCmd.AddCommand(dbFixtureCmd)
+	dbDiffCmd.Flags().BoolVarP(&diffJSONOutput, "json", "j", false, "Output as JSON")
+	dbDiffCmd.Flags().StringVar(&display.Format, "output", output.FormatTable, "Table format: table, markdown, html or csv")
+	dbCmd.AddCommand(dbDowngradeCmd)
+	dbCmd.AddCommand(dbDiffCmd)
+	dbLintCmd.Flags().BoolVar(&lintFix, "fix", false, "Fix the known-safe issues")
+	dbLintCmd.Flags().BoolVarP(&lintJSONOutput, "json", "j", false, "Output as JSON")
+	dbLintCmd.Flags().StringVar(&display.Format, "output", output.FormatTable, "Table format: table, markdown, html or csv")
+	dbCmd.AddCommand(dbLintCmd)
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/category"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/notify"
+	"github.com/ivan4th/ameriagrab/output"
+	"github.com/ivan4th/ameriagrab/report"
+	"github.com/spf13/cobra"
+)
+
+// digestCursor records the last month (as YYYYMM) whose digest was emailed by the daemon
+const digestCursor = "digest"
+
+var (
+	digestMonth string
+	digestSend  bool
+	digestHTML  bool
+)
+
+var reportDigestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Monthly digest: totals, top merchants, categories and balance changes",
+	Long: `Summarizes a calendar month (the previous month by default): income and
+expenses per currency, top merchants, spending per category and the change of
+each product's balance.
+
+With --send, the digest is emailed as HTML and plain text using the SMTP
+notification settings (notify.smtp.*). Run 'daemon --digest' to email the
+previous month's digest automatically at the start of each month.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := display.CheckFormat(); err != nil {
+			return err
+		}
+		if display.Format == output.FormatMarkdown || display.Format == output.FormatCSV {
+			return fmt.Errorf("the digest can't be printed as %s (use --output html or plain text)", display.Format)
+		}
+		month := report.MonthStart(time.Now()).AddDate(0, -1, 0)
+		if digestMonth != "" {
+			var err error
+			month, err = time.ParseInLocation("2006-01", digestMonth, time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid --month %q (expected YYYY-MM)", digestMonth)
+			}
+		}
+
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		d, err := report.BuildDigest(database, month, category.Default())
+		if err != nil {
+			return fmt.Errorf("building digest: %w", err)
+		}
+
+		switch {
+		case digestSend:
+			if err := emailDigest(d); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Sent digest for %s\n", d.Month)
+		case reportJSONOutput:
+			out, err := json.MarshalIndent(d, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling digest: %w", err)
+			}
+			fmt.Println(string(out))
+		case digestHTML || display.Format == output.FormatHTML:
+			html, err := d.HTML()
+			if err != nil {
+				return err
+			}
+			fmt.Print(html)
+		default:
+			fmt.Print(d.Text())
+		}
+		return nil
+	},
+}
+
+// emailDigest sends the digest using the SMTP notification settings
+func emailDigest(d *report.Digest) error {
+	cfg := LoadConfig().Notify.SMTP
+	settings := notify.Settings{
+		SMTPAddr:     cfg.Addr,
+		SMTPUser:     cfg.User,
+		SMTPPassword: cfg.Password,
+		SMTPFrom:     cfg.From,
+		SMTPTo:       notify.SplitList(cfg.To),
+	}
+	settings.ApplyEnv()
+	if settings.SMTPAddr == "" || settings.SMTPFrom == "" || len(settings.SMTPTo) == 0 {
+		return fmt.Errorf("email is not configured (set notify.smtp.addr, notify.smtp.from and notify.smtp.to)")
+	}
+	sink := &notify.SMTPSink{
+		Addr:     settings.SMTPAddr,
+		Username: settings.SMTPUser,
+		Password: settings.SMTPPassword,
+		From:     settings.SMTPFrom,
+		To:       settings.SMTPTo,
+	}
+
+	html, err := d.HTML()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := sink.Send(ctx, notify.Message{Title: "ameriagrab: monthly digest for " + d.Month, Body: d.Text(), HTML: html}); err != nil {
+		return fmt.Errorf("sending digest: %w", err)
+	}
+	return nil
+}
+
+// sendMonthlyDigest emails the previous month's digest unless it was already sent.
+// Failures are reported as warnings and retried after the next sync.
+func sendMonthlyDigest(database *db.DB) {
+	month := report.MonthStart(time.Now()).AddDate(0, -1, 0)
+	key, _ := strconv.ParseInt(month.Format("200601"), 10, 64)
+
+	cursor, err := database.GetCursor(digestCursor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load digest state: %v\n", err)
+		return
+	}
+	if cursor["month"] >= key {
+		return
+	}
+
+	d, err := report.BuildDigest(database, month, category.Default())
+	if err == nil {
+		err = emailDigest(d)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: monthly digest failed: %v\n", err)
+		return
+	}
+	if err := database.SaveCursor(digestCursor, map[string]int64{"month": key}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save digest state: %v\n", err)
+	}
+	fmt.Fprintf(os.Stderr, "Sent monthly digest for %s\n", d.Month)
+}
+
+func init() {
+	reportDigestCmd.Flags().StringVar(&digestMonth, "month", "", "Month to summarize (YYYY-MM, default: previous month)")
+	reportDigestCmd.Flags().BoolVar(&digestSend, "send", false, "Email the digest using the SMTP notification settings")
+	reportDigestCmd.Flags().BoolVar(&digestHTML, "html", false, "Print the HTML version")
+	reportCmd.AddCommand(reportDigestCmd)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/i18n"
+)
+
+// Exit codes, distinct per kind of failure so wrappers and monitoring can react to them
+const (
+	ExitFailure     = 1 // any other error, including invalid usage
+	ExitAuth        = 2 // login or session failure
+	ExitPushTimeout = 3 // the push notification 2FA wasn't confirmed in time
+	ExitNetwork     = 4 // the bank couldn't be reached
+	ExitDatabase    = 5 // the local database couldn't be opened or used
+	ExitPartialSync = 6 // some products failed to sync, the others were synced
+	ExitInterrupted = 7 // stopped by SIGINT, SIGTERM or --timeout
+)
+
+// Error kinds reported by --error-format json, by exit code
+var errorKinds = map[int]string{
+	ExitFailure:     "error",
+	ExitAuth:        "auth",
+	ExitPushTimeout: "2fa_timeout",
+	ExitNetwork:     "network",
+	ExitDatabase:    "database",
+	ExitPartialSync: "partial_sync",
+	ExitInterrupted: "interrupted",
+}
+
+// Summaries of error kinds, printed in the language of the locale before the
+// error itself, which is usually in English
+var errorSummaries = map[int]string{
+	ExitAuth:        "login failed",
+	ExitPushTimeout: "the push notification wasn't confirmed in time",
+	ExitNetwork:     "couldn't reach the bank",
+	ExitDatabase:    "local database error",
+	ExitPartialSync: "some products failed to sync",
+	ExitInterrupted: "stopped before finishing",
+}
+
+// errorFormat is the format errors are printed in (--error-format)
+var errorFormat string
+
+// interruption returns the error a command stops with when its context is
+// cancelled by a signal or --timeout
+func interruption(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s: %w", commandTimeout, ctx.Err())
+	}
+	return fmt.Errorf("interrupted: %w", ctx.Err())
+}
+
+// exitError marks an error with the exit code it should produce
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// exitCode classifies an error returned by a command. More specific causes
+// win: a network failure while logging in is reported as a network error.
+func exitCode(err error) int {
+	var failure *syncFailure
+	var netErr net.Error
+	var marked *exitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, client.ErrPushTimeout):
+		return ExitPushTimeout
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		// Before network errors, which requests cancelled in flight fail with
+		return ExitInterrupted
+	case errors.As(err, &failure):
+		return ExitPartialSync
+	case errors.As(err, &netErr):
+		return ExitNetwork
+	case errors.As(err, &marked):
+		return marked.code
+	case db.IsSQLiteError(err):
+		return ExitDatabase
+	}
+	return ExitFailure
+}
+
+// jsonError is the error object printed by --error-format json. A partial
+// sync adds the number of products it tried and the ones that failed.
+type jsonError struct {
+	Error    string             `json:"error"`
+	Kind     string             `json:"kind"`
+	ExitCode int                `json:"exit_code"`
+	Total    int                `json:"total_products,omitempty"`
+	Failed   []productSyncError `json:"failed_products,omitempty"`
+}
+
+// newJSONError builds the --error-format json object for an error
+func newJSONError(err error) jsonError {
+	code := exitCode(err)
+	je := jsonError{Error: err.Error(), Kind: errorKinds[code], ExitCode: code}
+	var failure *syncFailure
+	if errors.As(err, &failure) {
+		je.Total = failure.Total
+		je.Failed = failure.Errors
+	}
+	return je
+}
+
+// Execute runs the root command, prints the error it fails with (if any)
+// in the --error-format and returns the process exit code. The command's
+// context is cancelled on SIGINT or SIGTERM.
+func Execute() int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// Another signal kills the process
+		<-ctx.Done()
+		stop()
+	}()
+	err := RootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	stopPager()
+	if err == nil {
+		return 0
+	}
+	code := exitCode(err)
+	if errorFormat != "json" {
+		if summary, ok := errorSummaries[code]; ok && i18n.T(summary) != summary {
+			fmt.Fprintf(os.Stderr, "%s %s: %v\n", i18n.T("Error:"), i18n.T(summary), err)
+		} else {
+			fmt.Fprintln(os.Stderr, i18n.T("Error:"), err)
+		}
+		return code
+	}
+	out, jerr := json.Marshal(newJSONError(err))
+	if jerr != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return code
+	}
+	fmt.Fprintln(os.Stderr, string(out))
+	return code
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestExitCode(t *testing.T) {
+	netErr := &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("connection refused")}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, tt := range []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, 0},
+		{"generic", errors.New("boom"), ExitFailure},
+		{"auth", &exitError{ExitAuth, errors.New("bad password")}, ExitAuth},
+		{"push timeout during login", &exitError{ExitAuth, fmt.Errorf("getting access token: %w", client.ErrPushTimeout)}, ExitPushTimeout},
+		{"network during login", &exitError{ExitAuth, fmt.Errorf("failed to get login page: %w", netErr)}, ExitNetwork},
+		{"network", fmt.Errorf("fetching accounts: %w", netErr), ExitNetwork},
+		{"database", &exitError{ExitDatabase, errors.New("AMERIA_DB_PATH environment variable must be set")}, ExitDatabase},
+		{"partial sync", fmt.Errorf("sync: %w", &syncFailure{Total: 2, Errors: []productSyncError{{ProductID: "p1"}}}), ExitPartialSync},
+		{"interrupted", fmt.Errorf("sync stopped: %w", interruption(cancelled)), ExitInterrupted},
+		{"request cancelled", fmt.Errorf("fetching accounts: %w", &url.Error{Op: "Get", URL: "https://example.com", Err: context.Canceled}), ExitInterrupted},
+		{"timed out", fmt.Errorf("fetching accounts: %w", context.DeadlineExceeded), ExitInterrupted},
+	} {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: expected exit code %d, got %d", tt.name, tt.want, got)
+		}
+	}
+
+	// Errors from SQLite itself, e.g. a query on a closed or broken database
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+	_, err = database.Exec("SELECT * FROM no_such_table")
+	if got := exitCode(fmt.Errorf("querying: %w", err)); got != ExitDatabase {
+		t.Errorf("expected SQLite errors to exit with %d, got %d", ExitDatabase, got)
+	}
+}
+
+func TestNewJSONError(t *testing.T) {
+	je := newJSONError(errors.New("boom"))
+	if je.Kind != "error" || je.ExitCode != ExitFailure || je.Total != 0 || je.Failed != nil {
+		t.Errorf("unexpected error object: %+v", je)
+	}
+
+	failure := &syncFailure{Total: 3, Errors: []productSyncError{{ProductID: "p1", Error: "timeout"}}}
+	je = newJSONError(fmt.Errorf("sync: %w", failure))
+	if je.Kind != "partial_sync" || je.ExitCode != ExitPartialSync || je.Total != 3 ||
+		len(je.Failed) != 1 || je.Failed[0].ProductID != "p1" {
+		t.Errorf("expected the failed products in the error object, got %+v", je)
+	}
+}
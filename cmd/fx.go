@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/money"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fxBase       string
+	fxRates      []string
+	fxDays       int
+	fxJSONOutput bool
+	fxRateDate   string
+)
+
+// FXPositionJSON is the JSON representation of a currency position
+type FXPositionJSON struct {
+	Currency  string   `json:"currency"`
+	Accounts  float64  `json:"accounts"`
+	Deposits  float64  `json:"deposits"`
+	Loans     float64  `json:"loans"`
+	Net       float64  `json:"net"`
+	BaseValue *float64 `json:"base_value"` // null if no rate is known
+}
+
+// FXJSON is the JSON output of the fx command
+type FXJSON struct {
+	Base      string           `json:"base"`
+	Positions []FXPositionJSON `json:"positions"`
+	Total     float64          `json:"total"`
+	Missing   []string         `json:"missing_rates,omitempty"`
+	Exchanges []db.LedgerEntry `json:"exchanges"`
+}
+
+var fxCmd = &cobra.Command{
+	Use:   "fx",
+	Short: "Show multi-currency position and recent exchanges",
+	Long: `Summarizes currency exposure across all products (account balances, deposits
+and outstanding loans), converted to a base currency, and lists recent
+currency exchange transactions. Reads from the local database.
+
+Exchange rates are stored as the value of one unit in AMD and can be set with --rate:
+  ameriagrab fx --rate USD=387.5 --rate EUR=420
+
+Each day's rates are kept for converting transactions at the rate of their date
+(get and report --convert). --date records the --rate values for a past day
+instead, leaving the current rates alone:
+  ameriagrab fx --rate USD=392 --date 2024-03-01`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		rateDate, err := parseDateFlag("date", fxRateDate)
+		if err != nil {
+			return err
+		}
+		if !rateDate.IsZero() && len(fxRates) == 0 {
+			return fmt.Errorf("--date requires --rate")
+		}
+		for _, r := range fxRates {
+			currency, rate, err := parseRate(r)
+			if err != nil {
+				return err
+			}
+			if !rateDate.IsZero() {
+				if err := database.SetHistoricalExchangeRate(currency, rate, "manual", rateDate); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Set %s rate on %s to %g %s\n", currency, fxRateDate, rate, db.LocalCurrency)
+				continue
+			}
+			if err := database.SetExchangeRate(currency, rate, "manual"); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Set %s rate to %g %s\n", currency, rate, db.LocalCurrency)
+		}
+
+		positions, err := database.GetCurrencyPositions()
+		if err != nil {
+			return fmt.Errorf("computing currency positions: %w", err)
+		}
+		rates, err := database.GetExchangeRates()
+		if err != nil {
+			return fmt.Errorf("fetching exchange rates: %w", err)
+		}
+		exchanges, err := database.GetExchangeTransactions(time.Now().AddDate(0, 0, -fxDays))
+		if err != nil {
+			return fmt.Errorf("fetching exchange transactions: %w", err)
+		}
+
+		base := strings.ToUpper(fxBase)
+		if cfg := LoadConfig(); !cmd.Flags().Changed("base") && cfg.BaseCurrency != "" {
+			base = cfg.BaseCurrency
+		}
+		if fxJSONOutput {
+			result := FXJSON{Base: base, Positions: []FXPositionJSON{}, Exchanges: exchanges}
+			var total money.Amount
+			for _, p := range positions {
+				pj := FXPositionJSON{Currency: p.Currency, Accounts: p.Accounts, Deposits: p.Deposits, Loans: p.Loans, Net: p.Net()}
+				if v, ok := rates.Convert(p.Net(), p.Currency, base); ok {
+					pj.BaseValue = &v
+					total += money.FromFloat(v)
+				} else {
+					result.Missing = append(result.Missing, p.Currency)
+				}
+				result.Positions = append(result.Positions, pj)
+			}
+			result.Total = total.Float()
+			out, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling fx summary: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		if len(positions) == 0 {
+			fmt.Println("No products found. Use 'sync' to download data first.")
+			return nil
+		}
+		display.PrintCurrencyPositions(positions, rates, base)
+
+		fmt.Printf("\nExchange transactions in the last %d days:\n", fxDays)
+		if len(exchanges) == 0 {
+			fmt.Println("  none")
+			return nil
+		}
+		display.PrintLedgerEntries(exchanges)
+		return nil
+	},
+}
+
+// rateHistoryStore reads the exchange rates of past dates
+type rateHistoryStore interface {
+	GetRateHistory() (*db.RateHistory, error)
+}
+
+// setupConversion makes transaction tables and JSON show amounts in currency
+// (--convert), converted at the stored exchange rates of their dates
+func setupConversion(database rateHistoryStore, currency string) (*db.RateHistory, error) {
+	rates, err := database.GetRateHistory()
+	if err != nil {
+		return nil, fmt.Errorf("fetching exchange rates: %w", err)
+	}
+	currency = strings.ToUpper(currency)
+	if _, ok := rates.Convert(1, currency, db.LocalCurrency, time.Now()); !ok {
+		return nil, fmt.Errorf("no exchange rate stored for %s (see 'rates --save' or 'fx --rate')", currency)
+	}
+	display.ConvertTo = currency
+	display.Convert = func(amount float64, from string, at time.Time) (float64, bool) {
+		return rates.Convert(amount, from, currency, at)
+	}
+	return rates, nil
+}
+
+// parseRate parses a CURRENCY=RATE pair
+func parseRate(s string) (string, float64, error) {
+	currency, value, ok := strings.Cut(s, "=")
+	if !ok || currency == "" {
+		return "", 0, fmt.Errorf("invalid rate %q (expected CURRENCY=RATE)", s)
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate <= 0 {
+		return "", 0, fmt.Errorf("invalid rate value in %q", s)
+	}
+	return strings.ToUpper(currency), rate, nil
+}
+
+func init() {
+	fxCmd.Flags().StringVarP(&fxBase, "base", "b", db.LocalCurrency, "Base currency for conversion (default: base_currency config setting or AMD)")
+	fxCmd.Flags().StringArrayVarP(&fxRates, "rate", "r", nil, "Set exchange rate as CURRENCY=RATE in AMD (repeatable)")
+	fxCmd.Flags().IntVarP(&fxDays, "days", "d", 30, "Show exchange transactions from the last N days")
+	fxCmd.Flags().StringVar(&fxRateDate, "date", "", "Record the --rate values for a past day (YYYY-MM-DD)")
+	fxCmd.Flags().BoolVarP(&fxJSONOutput, "json", "j", false, "Output as JSON")
+}
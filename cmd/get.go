@@ -1,338 +1,366 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
-	"os"
-	"sync"
+	"strings"
+	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
 	"github.com/ivan4th/ameriagrab/db"
 	"github.com/ivan4th/ameriagrab/output"
+	"github.com/ivan4th/ameriagrab/product"
+	"github.com/ivan4th/ameriagrab/report"
 	"github.com/spf13/cobra"
-	"golang.org/x/sync/errgroup"
 )
 
 var (
 	getSize            int
 	getPage            int
 	getJSONOutput      bool
+	getJSONRaw         bool
 	getForceAccountAPI bool
 	getLocal           bool
 	getExtended        bool
 	getWide            bool
 	getAscending       bool
 	getCombined        bool
+	getNew             bool
+	getConsumer        string
+	getBalance         bool
+	getShowStages      bool
+	getConvert         string
 )
 
 var getCmd = &cobra.Command{
-	Use:   "get <id>",
+	Use:   "get [id|name]",
 	Short: "Get transactions for a card or account",
-	Args:  cobra.ExactArgs(1),
+	Long: `Gets transactions for a card or account, given by ID or name.
+
+If none is given, the default_product config setting is used. The page_size
+config setting overrides the default of --size.
+
+With --new, only transactions inserted into the local database since the last
+'get --new' run are printed (all products unless an ID is given). Progress is
+tracked per --consumer name, so several scripts can each see every transaction once.
+
+With --local, cards' pending authorizations (holds) stored by the last sync are
+listed above the first page of transactions. A card purchase whose pre-purchase
+authorization was linked to its settlement by sync is listed once, as the
+settlement; --show-stages lists the authorization too, dimmed and left out of
+the totals.
+
+--columns picks and orders the table columns, e.g. --columns date,amount,category,details.
+Extended fields (card, account, address, operation) are only filled with --extended.
+--group-by day|month splits the table into days or months with subtotals.
+--relative-dates shows dates of the last week as "today 14:32", "yesterday" or
+"3 days ago"; JSON and --output markdown, html and csv keep absolute dates.
+
+--sort amount|date|type (with --desc to reverse) orders the displayed
+transactions, both with --local and from the API. Amounts sort by signed value,
+so the largest debits come first. Only the fetched page is sorted; use
+--asc to choose which end of the history is fetched.
+
+--balance adds a running balance column reconstructed backwards from the latest
+known balance (last sync or snapshot). It requires --local; for cards it follows
+the linked account transactions (implies -a unless --combined).
+
+--convert CURRENCY shows amounts in one currency, converted at the stored
+exchange rate of each transaction's date (see 'fx'), with the original amount
+in the next column (original_amount and original_currency in JSON). It
+requires --local.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		id := args[0]
+		if err := display.CheckFormat(); err != nil {
+			return err
+		}
+		if err := display.CheckColumns(); err != nil {
+			return err
+		}
+		if err := display.CheckGroupBy(); err != nil {
+			return err
+		}
+		if err := display.CheckSort(); err != nil {
+			return err
+		}
+		if getNew {
+			var id string
+			if len(args) > 0 {
+				id = args[0]
+			}
+			return getNewTransactions(id)
+		}
+
+		cfg := LoadConfig()
+		var id string
+		if len(args) > 0 {
+			id = args[0]
+		} else if id = cfg.DefaultProduct; id == "" {
+			return fmt.Errorf("product ID required (or set one with 'config set default_product <id>')")
+		}
+		if !cmd.Flags().Changed("size") && cfg.PageSize > 0 {
+			getSize = cfg.PageSize
+		}
 
 		// --combined requires --local
 		if getCombined && !getLocal {
 			return fmt.Errorf("--combined requires --local")
 		}
+		if getBalance && !getLocal {
+			return fmt.Errorf("--balance requires --local")
+		}
+		if getConvert != "" && !getLocal {
+			return fmt.Errorf("--convert requires --local")
+		}
 
 		// -c implies -x (combined mode should show receiver/sender info)
 		if getCombined {
 			getExtended = true
 		}
 
-		// -x implies -a for cards (extended info only available via linked account API)
-		if getExtended {
+		// -x implies -a for cards (extended info only available via linked account API);
+		// so does --balance, as card balances follow linked account transactions
+		if getExtended || getBalance {
 			getForceAccountAPI = true
 		}
 
 		if getLocal {
-			return getFromLocal(id)
+			database, err := OpenDatabase()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			return getTransactions(product.Local(database), database, id)
 		}
-		return getFromAPI(id)
+		c, accessToken, err := SetupClient(cmd.Context())
+		if err != nil {
+			return err
+		}
+		return getTransactions(product.Remote(c, accessToken), nil, id)
 	},
 }
 
-func getFromLocal(id string) error {
-	database, err := OpenDatabase()
-	if err != nil {
-		return err
-	}
-	defer database.Close()
+// localStore is what get reads locally besides products and transactions:
+// exchange rates for matching and --convert, balances for --balance and the
+// templates naming counterparties. *db.DB implements it.
+type localStore interface {
+	product.Store
+	report.BalanceStore
+	exchangeRateStore
+	rateHistoryStore
+	GetTemplateWithGroupByMaskedCard(maskedCard string) (name, group string, err error)
+	GetTemplateWithGroupByCounterparty(number string) (name, group string, err error)
+}
 
-	// Get product info (by ID or name)
-	product, err := database.GetProductByNameOrID(id)
+// getTransactions prints a page of a product's transactions read through svc,
+// from the local store or the API. The store, nil for the API, also names
+// counterparties and backs --balance and --convert.
+func getTransactions(svc *product.Service, database localStore, id string) error {
+	p, err := svc.Resolve(id)
 	if err != nil {
-		return fmt.Errorf("fetching product: %w", err)
+		return err
 	}
-	if product == nil {
-		return fmt.Errorf("product %q not found in database", id)
+	if database != nil {
+		if err := checkSelectedClient(p); err != nil {
+			return err
+		}
 	}
 
-	if product.ProductType == "CARD" {
-		var txns []client.Transaction
-		var err error
-		var totalCount int
-
-		if getCombined {
-			// Combined mode: merge card and linked account transactions
-			opts := db.CombinedTransactionsOptions{
-				Size:            getSize,
-				Page:            getPage,
-				IncludeExtended: getExtended,
-				Ascending:       getAscending,
-			}
-			txns, totalCount, err = database.GetCombinedTransactions(product.ID, opts)
-			if err != nil {
-				return fmt.Errorf("fetching combined transactions: %w", err)
-			}
-		} else if getForceAccountAPI {
-			// Get linked account transactions with pagination
-			// size=0 means no limit for DB
-			txns, err = database.GetLinkedAccountTransactions(product.ID, getSize, getPage, getExtended, getAscending)
-			if err != nil {
-				return fmt.Errorf("fetching linked account transactions: %w", err)
-			}
-			totalCount, err = database.CountLinkedAccountTransactions(product.ID)
-			if err != nil {
-				return fmt.Errorf("counting linked account transactions: %w", err)
-			}
-		} else {
-			// Get card transactions with pagination
-			// size=0 means no limit for DB
-			txns, err = database.GetCardTransactions(product.ID, getSize, getPage, getAscending)
-			if err != nil {
-				return fmt.Errorf("fetching card transactions: %w", err)
+	q := product.Query{
+		Source:     product.TransactionSource(*p, getForceAccountAPI),
+		Combined:   getCombined,
+		Size:       getSize,
+		Page:       getPage,
+		Ascending:  getAscending,
+		Extended:   getExtended,
+		ShowStages: getShowStages,
+	}
+	if database != nil {
+		if q.Match, err = matchOptions(database); err != nil {
+			return err
+		}
+		if getConvert != "" {
+			if _, err := setupConversion(database, getConvert); err != nil {
+				return err
 			}
-			totalCount, err = database.CountCardTransactions(product.ID)
+		}
+		if getBalance {
+			balances, _, err := report.RunningBalances(database, p.ID)
 			if err != nil {
-				return fmt.Errorf("counting card transactions: %w", err)
+				return fmt.Errorf("computing running balances: %w", err)
 			}
+			display.RunningBalance = balances.Lookup
 		}
+	}
+	txns, err := svc.Transactions(*p, q)
+	if err != nil {
+		return err
+	}
 
-		resp := &client.TransactionsResponse{
-			Status: "success",
+	if txns.Account != nil {
+		if getJSONOutput || getJSONRaw {
+			return printGetJSON(txns.Account, display.AccountHistoryJSON(txns.Account))
 		}
-		resp.Data.TotalCount = totalCount
-		resp.Data.Entries = txns
-
-		if getJSONOutput {
-			out, err := json.MarshalIndent(resp, "", "  ")
-			if err != nil {
-				return fmt.Errorf("marshaling response: %w", err)
+		var lookupFn output.TemplateLookupFunc
+		if database != nil {
+			lookupFn = func(number string) (string, string) {
+				name, group, _ := database.GetTemplateWithGroupByCounterparty(number)
+				return name, group
 			}
-			fmt.Println(string(out))
-		} else {
-			// Create template lookup function for combined mode
-			var lookupFn output.TemplateLookupFunc
-			if getCombined {
-				lookupFn = func(maskedCard string) string {
-					name, _ := database.GetTemplateByMaskedCard(maskedCard)
-					return name
-				}
-			}
-			output.PrintCardTransactionsWithLookup(resp, getExtended, getWide, lookupFn)
-		}
-	} else {
-		// For accounts, return account transactions from DB
-		txns, err := database.GetAccountTransactions(product.ID, getAscending)
-		if err != nil {
-			return fmt.Errorf("fetching account transactions: %w", err)
 		}
+		display.PrintAccountHistoryWithLookup(txns.Account, getWide, lookupFn)
+		return nil
+	}
 
-		resp := &client.HistoryResponse{
-			Status: "success",
+	if getJSONOutput || getJSONRaw {
+		return printGetJSON(txns.Card, display.CardTransactionsJSON(txns.Card))
+	}
+	if getPage == 0 {
+		if err := printPendingHolds(svc, *p); err != nil {
+			return err
 		}
-		resp.Data.Transactions = txns
-		resp.Data.HasNext = false
-		resp.Data.IsUpToDate = true
-
-		if getJSONOutput {
-			out, err := json.MarshalIndent(resp, "", "  ")
-			if err != nil {
-				return fmt.Errorf("marshaling response: %w", err)
-			}
-			fmt.Println(string(out))
-		} else {
-			output.PrintAccountHistory(resp, getWide)
+	}
+	// Name counterparties from transfer templates
+	var lookupFn output.TemplateLookupFunc
+	if database != nil {
+		lookupFn = func(maskedCard string) (string, string) {
+			name, group, _ := database.GetTemplateWithGroupByMaskedCard(maskedCard)
+			return name, group
 		}
 	}
-
+	// Stored extended info is always loaded, so counterparties show
+	// without -x whenever sync has fetched them
+	display.PrintCardTransactionsWithLookup(txns.Card, getExtended, getWide, lookupFn)
 	return nil
 }
 
-func getFromAPI(id string) error {
-	c, accessToken, err := SetupClient()
+// exchangeRateStore reads the latest exchange rates
+type exchangeRateStore interface {
+	GetExchangeRates() (db.ExchangeRates, error)
+}
+
+// matchOptions returns the tolerances for matching card transactions to
+// linked account transactions from the config, with the stored exchange
+// rates for matching across currencies
+func matchOptions(database exchangeRateStore) (db.MatchOptions, error) {
+	cfg := LoadConfig()
+	var opts db.MatchOptions
+	if cfg.Match.TimeTolerance != "" {
+		d, err := time.ParseDuration(cfg.Match.TimeTolerance)
+		if err != nil {
+			return opts, fmt.Errorf("invalid match.time_tolerance: %w", err)
+		}
+		opts.TimeTolerance = d
+	}
+	opts.FXTolerance = cfg.Match.FXTolerance / 100
+	rates, err := database.GetExchangeRates()
 	if err != nil {
-		return err
+		return opts, fmt.Errorf("fetching exchange rates: %w", err)
 	}
+	opts.Rates = rates
+	return opts, nil
+}
 
-	// First, determine if this is a card or account
-	resp, err := c.GetAccountsAndCards(accessToken)
+// getNewTransactions prints transactions inserted since the consumer's cursor and advances it
+func getNewTransactions(id string) error {
+	database, err := OpenDatabase()
 	if err != nil {
-		return fmt.Errorf("fetching accounts and cards: %w", err)
+		return err
 	}
+	defer database.Close()
 
-	var productType, accountID string
-	for _, p := range resp.Data.AccountsAndCards {
-		if p.ID == id {
-			productType = p.ProductType
-			accountID = p.AccountID
-			break
+	if getConvert != "" {
+		if _, err := setupConversion(database, getConvert); err != nil {
+			return err
 		}
 	}
 
-	if productType == "" {
-		return fmt.Errorf("ID %s not found in accounts or cards", id)
-	}
-
-	if productType == "CARD" && !getForceAccountAPI {
-		// Card: use settled events API
-		fmt.Fprintln(os.Stderr, "Fetching card transactions...")
-		txns, err := c.GetTransactions(accessToken, id)
+	cursor := getConsumer
+	var opts db.LedgerOptions
+	if id != "" {
+		p, err := product.Resolve(database, id)
 		if err != nil {
-			return fmt.Errorf("fetching card transactions: %w", err)
-		}
-		if getAscending {
-			reverseTransactions(txns.Data.Entries)
-		}
-		if getJSONOutput {
-			out, err := json.MarshalIndent(txns, "", "  ")
-			if err != nil {
-				return fmt.Errorf("marshaling response: %w", err)
-			}
-			fmt.Println(string(out))
-		} else {
-			output.PrintCardTransactions(txns, false, getWide)
-		}
-	} else if productType == "CARD" && getForceAccountAPI {
-		// Card with --account flag: use events/past API with linked account ID
-		if accountID == "" {
-			return fmt.Errorf("card %s has no linked account ID", id)
-		}
-		fmt.Fprintf(os.Stderr, "Fetching card account history (events/past) for account %s...\n", accountID)
-		// size=0 means use 1000 for API
-		apiSize := getSize
-		if apiSize == 0 {
-			apiSize = 1000
-		}
-		txns, err := c.GetEventsPast(accessToken, accountID, apiSize, getPage)
-		if err != nil {
-			return fmt.Errorf("fetching card account history: %w", err)
-		}
-
-		// Fetch extended info if requested
-		if getExtended && len(txns.Data.Entries) > 0 {
-			fmt.Fprintf(os.Stderr, "Fetching extended info for %d transactions...\n", len(txns.Data.Entries))
-			if err := fetchExtendedInfo(c, accessToken, txns.Data.Entries); err != nil {
-				return fmt.Errorf("fetching extended info: %w", err)
-			}
+			return err
 		}
+		opts.ProductID = p.ID
+		cursor += "/" + p.ID
+	}
 
-		if getAscending {
-			reverseTransactions(txns.Data.Entries)
+	entries, next, err := database.GetNewLedgerEntries(cursor, opts)
+	if err != nil {
+		return fmt.Errorf("fetching new transactions: %w", err)
+	}
+	if !getAscending {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
 		}
+	}
 
-		if getJSONOutput {
-			out, err := json.MarshalIndent(txns, "", "  ")
-			if err != nil {
-				return fmt.Errorf("marshaling response: %w", err)
-			}
-			fmt.Println(string(out))
-		} else {
-			output.PrintCardTransactions(txns, getExtended, getWide)
-		}
-	} else {
-		// Account: use history API
-		fmt.Fprintln(os.Stderr, "Fetching account history...")
-		// size=0 means use 1000 for API
-		apiSize := getSize
-		if apiSize == 0 {
-			apiSize = 1000
-		}
-		history, err := c.GetAccountHistory(accessToken, id, apiSize, getPage)
-		if err != nil {
-			return fmt.Errorf("fetching account history: %w", err)
+	if getJSONOutput || getJSONRaw {
+		if entries == nil {
+			entries = []db.LedgerEntry{}
 		}
-		if getAscending {
-			reverseAccountTransactions(history.Data.Transactions)
-		}
-		if getJSONOutput {
-			out, err := json.MarshalIndent(history, "", "  ")
-			if err != nil {
-				return fmt.Errorf("marshaling response: %w", err)
-			}
-			fmt.Println(string(out))
-		} else {
-			output.PrintAccountHistory(history, getWide)
+		if err := printGetJSON(entries, display.LedgerJSON(entries)); err != nil {
+			return err
 		}
+	} else if len(entries) > 0 {
+		display.PrintLedgerEntries(entries)
 	}
 
+	// Only advance the cursor once the transactions have been printed
+	if err := database.SaveCursor(cursor, next); err != nil {
+		return fmt.Errorf("saving cursor: %w", err)
+	}
 	return nil
 }
 
-// reverseTransactions reverses a slice of transactions in place
-func reverseTransactions(txns []client.Transaction) {
-	for i, j := 0, len(txns)-1; i < j; i, j = i+1, j-1 {
-		txns[i], txns[j] = txns[j], txns[i]
+// printPendingHolds prints a card's pending authorizations, marked PENDING,
+// followed by how they make up the available balance
+func printPendingHolds(svc *product.Service, card client.ProductInfo) error {
+	holds, err := svc.Holds(card)
+	if err != nil || len(holds) == 0 {
+		return err
 	}
-}
-
-// reverseAccountTransactions reverses a slice of account transactions in place
-func reverseAccountTransactions(txns []client.AccountTransaction) {
-	for i, j := 0, len(txns)-1; i < j; i, j = i+1, j-1 {
-		txns[i], txns[j] = txns[j], txns[i]
+	if card.AvailableBalance, err = svc.AvailableBalance(card); err != nil {
+		return err
 	}
-}
-
-// fetchExtendedInfo fetches extended info for transactions in parallel using errgroup
-func fetchExtendedInfo(c *client.Client, accessToken string, txns []client.Transaction) error {
-	g, _ := errgroup.WithContext(context.Background())
-	g.SetLimit(5)
-	var mu sync.Mutex
-
-	for i := range txns {
-		idx := i
-		g.Go(func() error {
-			details, err := c.GetTransactionDetails(accessToken, txns[idx].ID)
-			if err != nil {
-				return fmt.Errorf("fetching extended info for %s: %w", txns[idx].ID, err)
-			}
 
-			ext := &client.TransactionExtendedInfo{
-				BeneficiaryName:     details.Data.Transaction.BeneficiaryName,
-				BeneficiaryAddress:  details.Data.Transaction.BeneficiaryAddress,
-				CreditAccountNumber: details.Data.Transaction.CreditAccountNumber,
-			}
-			if details.Data.Transaction.AdditionalInfo != nil {
-				ext.CardMaskedNumber = details.Data.Transaction.AdditionalInfo.CardMaskedNumber
-				ext.OperationID = details.Data.Transaction.AdditionalInfo.ProcessedOperationID
-			}
-			if details.Data.Transaction.TransactionSwiftDetails != nil {
-				if swiftJSON, err := json.Marshal(details.Data.Transaction.TransactionSwiftDetails); err == nil {
-					ext.SwiftDetails = string(swiftJSON)
-				}
-			}
+	display.PrintHeading("Pending holds")
+	display.PrintCardHolds(holds, getWide)
+	fmt.Println()
+	display.PrintAvailableBalance(card, holds)
+	fmt.Println()
+	return nil
+}
 
-			mu.Lock()
-			txns[idx].Extended = ext
-			mu.Unlock()
-			return nil
-		})
+// printGetJSON prints the raw value with --json-raw, otherwise its --json schema form
+func printGetJSON(raw interface{}, normalized output.JSONTransactionList) error {
+	if getJSONRaw {
+		return display.PrintJSON(raw)
 	}
-	return g.Wait()
+	return display.PrintJSON(normalized)
 }
 
 func init() {
 	getCmd.Flags().IntVarP(&getSize, "size", "s", 50, "Number of transactions to fetch")
 	getCmd.Flags().IntVarP(&getPage, "page", "p", 0, "Page number (0-indexed)")
-	getCmd.Flags().BoolVarP(&getJSONOutput, "json", "j", false, "Output as JSON")
+	getCmd.Flags().BoolVarP(&getJSONOutput, "json", "j", false, "Output as JSON (versioned schema, see README)")
+	getCmd.Flags().BoolVar(&getJSONRaw, "json-raw", false, "Output the bank's responses as JSON (unstable format)")
 	getCmd.Flags().BoolVarP(&getForceAccountAPI, "account", "a", false, "Use account history API (even for cards)")
 	getCmd.Flags().BoolVarP(&getLocal, "local", "l", false, "Read from local database")
 	getCmd.Flags().BoolVarP(&getExtended, "extended", "x", false, "Fetch extended transaction info (implies -a for cards)")
 	getCmd.Flags().BoolVarP(&getWide, "wide", "w", false, "Disable column truncation in output")
 	getCmd.Flags().BoolVarP(&getAscending, "asc", "o", false, "Show oldest transactions first (ascending order)")
 	getCmd.Flags().BoolVarP(&getCombined, "combined", "c", false, "Combine card and linked account transactions (local only)")
+	getCmd.Flags().BoolVarP(&getNew, "new", "n", false, "Only show transactions added since the last --new run (local only)")
+	getCmd.Flags().StringVar(&getConsumer, "consumer", "default", "Cursor name used by --new")
+	getCmd.Flags().BoolVar(&getBalance, "balance", false, "Add a running balance column (local only)")
+	getCmd.Flags().StringVar(&getConvert, "convert", "", "Show amounts converted to this currency at the rates of their dates (local only)")
+	getCmd.Flags().BoolVar(&getShowStages, "show-stages", false, "List settled card purchases' authorizations too (local only)")
+	getCmd.Flags().StringVar(&display.Format, "output", output.FormatTable, "Table format: table, markdown, html or csv")
+	getCmd.Flags().StringVar(&display.SortBy, "sort", "", "Sort displayed transactions: amount, date or type")
+	getCmd.Flags().BoolVar(&display.SortDesc, "desc", false, "Reverse the --sort order (newest first without --sort)")
+	getCmd.Flags().StringVar(&display.GroupBy, "group-by", "", "Group transactions with subtotals: day or month")
+	getCmd.Flags().BoolVar(&display.RelativeDates, "relative-dates", false, "Show recent dates as today, yesterday or N days ago")
+	getCmd.Flags().StringSliceVar(&display.Columns, "columns", nil,
+		"Comma-separated columns to show, in order ("+strings.Join(output.ColumnNames(), ", ")+")")
 }
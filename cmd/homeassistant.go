@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/config"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/homeassistant"
+	"github.com/ivan4th/ameriagrab/mqtt"
+)
+
+// mqttCursor tracks which transactions were published as Home Assistant events
+const mqttCursor = "mqtt"
+
+// publishToHomeAssistant publishes discovery configs, balances and transaction
+// events synced since the last publish to the configured MQTT broker.
+// On first use existing history isn't published. Failures are reported as
+// warnings; unpublished transactions are retried after the next sync.
+func publishToHomeAssistant(database *db.DB) {
+	cfg := LoadConfig().MQTT
+	for name, field := range map[string]*string{
+		"AMERIA_MQTT_BROKER":   &cfg.Broker,
+		"AMERIA_MQTT_USERNAME": &cfg.Username,
+		"AMERIA_MQTT_PASSWORD": &cfg.Password,
+	} {
+		if v := os.Getenv(name); v != "" {
+			*field = v
+		}
+	}
+	if cfg.Broker == "" {
+		return
+	}
+	if err := doPublishToHomeAssistant(database, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Home Assistant: %v\n", err)
+	}
+}
+
+func doPublishToHomeAssistant(database *db.DB, cfg config.MQTTConfig) error {
+	products, err := database.GetProducts()
+	if err != nil {
+		return err
+	}
+	positions, err := database.GetCursor(mqttCursor)
+	if err != nil {
+		return err
+	}
+	entries, next, err := database.GetNewLedgerEntries(mqttCursor, db.LedgerOptions{})
+	if err != nil {
+		return err
+	}
+	if len(positions) == 0 {
+		entries = nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client, err := mqtt.Dial(ctx, mqtt.Options{
+		Broker:   cfg.Broker,
+		ClientID: fmt.Sprintf("ameriagrab-%d", os.Getpid()),
+		Username: cfg.Username,
+		Password: cfg.Password,
+	})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	p := &homeassistant.Publisher{
+		MQTT:            client,
+		TopicPrefix:     orDefault(cfg.TopicPrefix, homeassistant.DefaultTopicPrefix),
+		DiscoveryPrefix: orDefault(cfg.DiscoveryPrefix, homeassistant.DefaultDiscoveryPrefix),
+	}
+	if err := p.PublishProducts(products); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := p.PublishTransaction(e); err != nil {
+			return err
+		}
+	}
+	if len(entries) > 0 {
+		fmt.Fprintf(os.Stderr, "Published %d transactions to Home Assistant\n", len(entries))
+	}
+	return database.SaveCursor(mqttCursor, next)
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
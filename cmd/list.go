@@ -1,24 +1,54 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
 	"github.com/ivan4th/ameriagrab/output"
+	"github.com/ivan4th/ameriagrab/product"
 	"github.com/spf13/cobra"
 )
 
 var (
 	listJSONOutput bool
+	listJSONRaw    bool
 	listLocal      bool
+	listTrend      int
+
+	listBalanceMaxAge time.Duration
 )
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all accounts and cards",
+	Long: `Lists accounts and cards with their available balances.
+
+With --local, the products stored by the last sync are listed, followed by the
+stored loans and deposits (see 'loans' and 'deposits' for their own listings).
+
+Available balances are fetched in parallel. With AMERIA_DB_PATH set, they are
+stored with the products, and balances fetched within --balance-max-age are
+reused instead of asking the bank again. A balance that can't be fetched is
+reported as a warning and taken from the database if stored there.
+
+--trend N (with --local) adds a sparkline of each product's balance over its
+last N known balances (snapshots and the last sync) next to the balance.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := display.CheckFormat(); err != nil {
+			return err
+		}
+		if listTrend < 0 {
+			return fmt.Errorf("--trend must not be negative")
+		}
+		if listTrend > 0 && !listLocal {
+			return fmt.Errorf("--trend requires --local")
+		}
 		var resp *client.AccountsAndCardsResponse
+		var loans []client.LoanInfo
+		var deposits []client.DepositInfo
 
 		if listLocal {
 			// Load from local database
@@ -36,44 +66,150 @@ var listCmd = &cobra.Command{
 			resp = &client.AccountsAndCardsResponse{
 				Status: "success",
 			}
-			resp.Data.AccountsAndCards = products
+			resp.Data.AccountsAndCards = selectedClientProducts(products)
+
+			if listTrend > 0 {
+				display.BalanceTrends = make(map[string][]float64)
+				for _, p := range resp.Data.AccountsAndCards {
+					points, err := database.GetBalancePoints(p.ID)
+					if err != nil {
+						return fmt.Errorf("fetching balance history: %w", err)
+					}
+					points = points[max(0, len(points)-listTrend):]
+					for _, bp := range points {
+						display.BalanceTrends[p.ID] = append(display.BalanceTrends[p.ID], bp.Balance)
+					}
+				}
+			}
+
+			if !listJSONOutput && !listJSONRaw {
+				if loans, err = database.GetLoans(); err != nil {
+					return fmt.Errorf("fetching loans from database: %w", err)
+				}
+				if deposits, err = database.GetDeposits(); err != nil {
+					return fmt.Errorf("fetching deposits from database: %w", err)
+				}
+			}
 		} else {
 			// Fetch from API
-			c, accessToken, err := SetupClient()
+			c, accessToken, err := SetupClient(cmd.Context())
 			if err != nil {
 				return err
 			}
 
-			resp, err = c.GetAccountsAndCards(accessToken)
+			resp, err = c.RefreshAccountsAndCards(accessToken)
 			if err != nil {
 				return fmt.Errorf("fetching accounts and cards: %w", err)
 			}
+			resp.Data.AccountsAndCards = append(resp.Data.AccountsAndCards, fetchGoals(c, accessToken)...)
 
-			// Fetch available balance for each product
-			for i := range resp.Data.AccountsAndCards {
-				p := &resp.Data.AccountsAndCards[i]
-				balResp, err := c.GetAvailableBalance(accessToken, p.ProductType, p.ID)
-				if err != nil {
-					return fmt.Errorf("fetching available balance for %s: %w", p.ID, err)
-				}
-				p.AvailableBalance = balResp.Data.AvailableBalance
+			if err := listAvailableBalances(product.Remote(c, accessToken), resp.Data.AccountsAndCards); err != nil {
+				return err
 			}
 		}
 
-		if listJSONOutput {
-			out, err := json.MarshalIndent(resp, "", "  ")
-			if err != nil {
-				return fmt.Errorf("marshaling response: %w", err)
+		if listJSONRaw {
+			if err := display.PrintJSON(resp); err != nil {
+				return err
+			}
+		} else if listJSONOutput {
+			if err := display.PrintJSON(display.ProductsJSON(resp.Data.AccountsAndCards)); err != nil {
+				return err
 			}
-			fmt.Println(string(out))
 		} else {
-			output.PrintAccountsAndCards(resp)
+			display.PrintAccountsAndCards(resp)
+			if len(loans) > 0 {
+				fmt.Println()
+				display.PrintHeading("Loans")
+				display.PrintLoans(loans)
+			}
+			if len(deposits) > 0 {
+				fmt.Println()
+				display.PrintHeading("Deposits")
+				display.PrintDeposits(deposits)
+			}
 		}
 		return nil
 	},
 }
 
+// fetchGoals returns the client's savings goals and card stashes as products.
+// A fetch failure is reported as a warning.
+func fetchGoals(c *client.Client, accessToken string) []client.ProductInfo {
+	resp, err := c.GetGoals(accessToken)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to fetch savings goals: %v\n", err)
+		return nil
+	}
+	var goals []client.ProductInfo
+	for _, g := range resp.Data.Goals {
+		goals = append(goals, product.FromGoal(g))
+	}
+	return goals
+}
+
+// listAvailableBalances sets the available balances of products, reusing and
+// storing them in the database from AMERIA_DB_PATH if set
+func listAvailableBalances(svc *product.Service, products []client.ProductInfo) error {
+	var database *db.DB
+	stored := map[string]db.StoredBalance{}
+	if os.Getenv("AMERIA_DB_PATH") != "" {
+		var err error
+		if database, err = OpenDatabase(); err != nil {
+			return err
+		}
+		defer database.Close()
+		if stored, err = database.GetAvailableBalances(); err != nil {
+			return err
+		}
+	}
+
+	// Only balances not fetched recently are fetched again
+	now := time.Now()
+	var fetch []int
+	for i, p := range products {
+		b, ok := stored[p.ID]
+		if ok && listBalanceMaxAge > 0 && !b.FetchedAt.IsZero() && now.Sub(b.FetchedAt) < listBalanceMaxAge {
+			products[i].AvailableBalance = b.Balance
+			continue
+		}
+		fetch = append(fetch, i)
+	}
+	toFetch := make([]client.ProductInfo, len(fetch))
+	for j, i := range fetch {
+		toFetch[j] = products[i]
+	}
+	errs := svc.AvailableBalances(toFetch)
+
+	fetched := make(map[string]float64)
+	for j, i := range fetch {
+		p := &products[i]
+		if errs[j] == nil {
+			p.AvailableBalance = toFetch[j].AvailableBalance
+			fetched[p.ID] = p.AvailableBalance
+			continue
+		}
+		if b, ok := stored[p.ID]; ok {
+			p.AvailableBalance = b.Balance
+			fmt.Fprintf(os.Stderr, "Warning: %v; showing the stored balance\n", errs[j])
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", errs[j])
+		}
+	}
+
+	if database != nil && len(fetched) > 0 {
+		if err := database.SetAvailableBalances(fetched, now); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to store available balances: %v\n", err)
+		}
+	}
+	return nil
+}
+
 func init() {
-	listCmd.Flags().BoolVarP(&listJSONOutput, "json", "j", false, "Output as JSON")
+	listCmd.Flags().BoolVarP(&listJSONOutput, "json", "j", false, "Output as JSON (versioned schema, see README)")
+	listCmd.Flags().BoolVar(&listJSONRaw, "json-raw", false, "Output the bank's response as JSON (unstable format)")
 	listCmd.Flags().BoolVarP(&listLocal, "local", "l", false, "Read from local database")
+	listCmd.Flags().DurationVar(&listBalanceMaxAge, "balance-max-age", 0, "Reuse available balances stored within this long (e.g. 5m; 0: always fetch)")
+	listCmd.Flags().IntVar(&listTrend, "trend", 0, "Show a sparkline of the last N balances of each product (local only)")
+	listCmd.Flags().StringVar(&display.Format, "output", output.FormatTable, "Table format: table, markdown, html or csv")
 }
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
-	"github.com/ivan4th/ameriagrab/output"
 	"github.com/spf13/cobra"
 )
 
@@ -60,7 +59,7 @@ Snapshots are created using 'sync --snapshot'.`,
 			}
 			fmt.Println(string(out))
 		} else {
-			output.PrintSnapshots(snapshots)
+			display.PrintSnapshots(snapshots)
 		}
 
 		return nil
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/product"
+)
+
+func TestListAvailableBalances(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("productId") == "acc1" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"status":"SUCCESS","data":{"availableBalance":100}}`)
+	}))
+	defer server.Close()
+	c, err := client.NewClient("user", "pass", nil, "")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	c.APIBaseURL = server.URL
+
+	dbPath := filepath.Join(t.TempDir(), "ameria.db")
+	t.Setenv("AMERIA_DB_PATH", dbPath)
+	database, err := db.Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	stored := []client.ProductInfo{
+		{ID: "card1", ProductType: product.TypeCard, AvailableBalance: 10},
+		{ID: "acc1", ProductType: product.TypeAccount, AvailableBalance: 50},
+	}
+	if err := database.UpsertProducts(stored); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+	database.Close()
+
+	newProducts := func() []client.ProductInfo {
+		return []client.ProductInfo{
+			{ID: "card1", ProductType: product.TypeCard},
+			{ID: "acc1", ProductType: product.TypeAccount},
+			{ID: "acc2", ProductType: product.TypeAccount},
+		}
+	}
+	// A failed balance is taken from the database, fetched ones are stored
+	products := newProducts()
+	if err := listAvailableBalances(product.Remote(c, "token"), products); err != nil {
+		t.Fatalf("listAvailableBalances failed: %v", err)
+	}
+	if products[0].AvailableBalance != 100 || products[1].AvailableBalance != 50 || products[2].AvailableBalance != 100 {
+		t.Errorf("unexpected balances: %+v", products)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+
+	// Recently fetched balances are reused
+	listBalanceMaxAge = time.Hour
+	defer func() { listBalanceMaxAge = 0 }()
+	requests.Store(0)
+	products = newProducts()
+	if err := listAvailableBalances(product.Remote(c, "token"), products); err != nil {
+		t.Fatalf("listAvailableBalances failed: %v", err)
+	}
+	if products[0].AvailableBalance != 100 || products[1].AvailableBalance != 50 {
+		t.Errorf("unexpected balances: %+v", products)
+	}
+	// acc1 failed and acc2 isn't stored, so both are fetched again
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	loansJSONOutput bool
+	loansLocal      bool
+	loansSchedule   bool
+)
+
+var loansCmd = &cobra.Command{
+	Use:   "loans",
+	Short: "List loans with next payment date and amount",
+	Long: `Lists loans with outstanding principal, interest rate and the next scheduled payment.
+With --schedule, the past and scheduled payments of each loan follow.
+
+Loans and their schedules are stored by 'sync'; upcoming payments can trigger
+'payment-due' alerts.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var loans []client.LoanInfo
+		var details []client.LoanDetails
+
+		if loansLocal {
+			database, err := OpenDatabase()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+
+			loans, err = database.GetLoans()
+			if err != nil {
+				return fmt.Errorf("fetching loans from database: %w", err)
+			}
+			if loansSchedule {
+				for _, l := range loans {
+					d, err := database.GetLoanDetails(l.ID)
+					if err != nil {
+						return fmt.Errorf("fetching loan schedule from database: %w", err)
+					}
+					details = append(details, *d)
+				}
+			}
+		} else {
+			c, accessToken, err := SetupClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			resp, err := c.GetLoans(accessToken)
+			if err != nil {
+				return fmt.Errorf("fetching loans: %w", err)
+			}
+			loans = resp.Data.Loans
+			if loansSchedule {
+				details = fetchLoanDetails(c, accessToken, loans)
+			}
+		}
+
+		if loansJSONOutput {
+			var v interface{} = loans
+			if loansSchedule {
+				v = details
+			}
+			out, err := json.MarshalIndent(v, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling loans: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		if len(loans) == 0 {
+			fmt.Println("No loans found.")
+			return nil
+		}
+		display.PrintLoans(loans)
+		for _, d := range details {
+			fmt.Println()
+			display.PrintHeading(fmt.Sprintf("%s (%s)", d.Name, d.ID))
+			display.PrintLoanTerms(d)
+			if len(d.Schedule) > 0 {
+				fmt.Println()
+				display.PrintLoanSchedule(d.Schedule)
+			}
+		}
+		return nil
+	},
+}
+
+// depositSchedule is a deposit as printed by 'deposits --schedule --json'
+type depositSchedule struct {
+	client.DepositDetails
+	Schedule []client.InterestPayment `json:"schedule"`
+}
+
+var depositsCmd = &cobra.Command{
+	Use:   "deposits",
+	Short: "List deposits with next interest payment and maturity",
+	Long: `Lists deposits with principal, interest rate, accrued interest and maturity.
+With --schedule, the terms and the past and scheduled interest payments of
+each deposit follow.
+
+Deposits and their schedules are stored by 'sync'.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var deposits []client.DepositInfo
+		var schedules []depositSchedule
+
+		if loansLocal {
+			database, err := OpenDatabase()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+
+			deposits, err = database.GetDeposits()
+			if err != nil {
+				return fmt.Errorf("fetching deposits from database: %w", err)
+			}
+			if loansSchedule {
+				for _, dep := range deposits {
+					d, err := database.GetDepositDetails(dep.ID)
+					if err != nil {
+						return fmt.Errorf("fetching deposit terms from database: %w", err)
+					}
+					payments, err := database.GetDepositSchedule(dep.ID)
+					if err != nil {
+						return fmt.Errorf("fetching deposit schedule from database: %w", err)
+					}
+					schedules = append(schedules, depositSchedule{*d, payments})
+				}
+			}
+		} else {
+			c, accessToken, err := SetupClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			resp, err := c.GetDeposits(accessToken)
+			if err != nil {
+				return fmt.Errorf("fetching deposits: %w", err)
+			}
+			deposits = resp.Data.Deposits
+			if loansSchedule {
+				details, payments := fetchDepositDetails(c, accessToken, deposits)
+				for _, d := range details {
+					schedules = append(schedules, depositSchedule{d, payments[d.ID]})
+				}
+			}
+		}
+
+		if loansJSONOutput {
+			var v interface{} = deposits
+			if loansSchedule {
+				v = schedules
+			}
+			out, err := json.MarshalIndent(v, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling deposits: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		if len(deposits) == 0 {
+			fmt.Println("No deposits found.")
+			return nil
+		}
+		display.PrintDeposits(deposits)
+		for _, d := range schedules {
+			fmt.Println()
+			display.PrintHeading(fmt.Sprintf("%s (%s)", d.Name, d.ID))
+			display.PrintDepositTerms(d.DepositDetails)
+			if len(d.Schedule) > 0 {
+				fmt.Println()
+				display.PrintDepositSchedule(d.Schedule)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{loansCmd, depositsCmd} {
+		c.Flags().BoolVarP(&loansJSONOutput, "json", "j", false, "Output as JSON")
+		c.Flags().BoolVarP(&loansLocal, "local", "l", false, "Read from local database")
+		c.Flags().BoolVar(&loansSchedule, "schedule", false, "Show payment schedules")
+	}
+}
+
+// fetchLoanDetails fetches the terms and payment schedule of each loan. A loan
+// whose details can't be fetched is kept without them, with a warning.
+func fetchLoanDetails(c interface {
+	GetLoanDetails(accessToken, loanID string) (*client.LoanDetailsResponse, error)
+}, accessToken string, loans []client.LoanInfo) []client.LoanDetails {
+	details := make([]client.LoanDetails, len(loans))
+	for i, l := range loans {
+		details[i].LoanInfo = l
+		resp, err := c.GetLoanDetails(accessToken, l.ID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to fetch details of loan %s: %v\n", l.ID, err)
+			continue
+		}
+		// The list has the latest balances
+		d := resp.Data.Loan
+		details[i].OpenDate, details[i].OverdueAmount, details[i].Schedule = d.OpenDate, d.OverdueAmount, d.Schedule
+	}
+	return details
+}
+
+// fetchDepositDetails fetches the terms and interest payment schedule of each
+// deposit, returning the schedules by deposit ID. A deposit whose details or
+// schedule can't be fetched is kept without them, with a warning.
+func fetchDepositDetails(c interface {
+	GetDepositDetails(accessToken, depositID string) (*client.DepositDetailsResponse, error)
+	GetDepositSchedule(accessToken, depositID string) (*client.DepositScheduleResponse, error)
+}, accessToken string, deposits []client.DepositInfo) ([]client.DepositDetails, map[string][]client.InterestPayment) {
+	details := make([]client.DepositDetails, len(deposits))
+	schedules := make(map[string][]client.InterestPayment)
+	for i, dep := range deposits {
+		details[i].DepositInfo = dep
+		if resp, err := c.GetDepositDetails(accessToken, dep.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to fetch details of deposit %s: %v\n", dep.ID, err)
+		} else {
+			details[i] = resp.Data.Deposit
+			details[i].DepositInfo = dep
+		}
+		if resp, err := c.GetDepositSchedule(accessToken, dep.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to fetch schedule of deposit %s: %v\n", dep.ID, err)
+		} else {
+			schedules[dep.ID] = resp.Data.Payments
+		}
+	}
+	return details, schedules
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ivan4th/ameriagrab/mcp"
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Run an MCP server for AI assistants over stdio",
+	Long: `Runs a Model Context Protocol server on stdin/stdout, exposing read-only
+tools over the local database:
+
+  list_products        accounts and cards with balances
+  query_transactions   search transactions by product, date, type, amount and text
+  monthly_summary      income and expenses per month and currency
+
+Example assistant configuration:
+  {"command": "ameriagrab", "args": ["mcp"], "env": {"AMERIA_DB_PATH": "/path/to/ameria.db"}}`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		server := &mcp.Server{
+			Name:    "ameriagrab",
+			Version: "1.0",
+			Tools:   mcp.DatabaseTools(database),
+		}
+		return server.Serve(ctx, os.Stdin, os.Stdout)
+	},
+}
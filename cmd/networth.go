@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/output"
+	"github.com/ivan4th/ameriagrab/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	networthBase       string
+	networthHistory    bool
+	networthWidth      int
+	networthHeight     int
+	networthPNG        string
+	networthJSONOutput bool
+)
+
+// networthPNGWidth and networthPNGHeight are the size of the --png chart in pixels
+const networthPNGWidth, networthPNGHeight = 800, 300
+
+var networthCmd = &cobra.Command{
+	Use:   "networth",
+	Short: "Show total net worth and how it changed over time",
+	Long: `Sums the available balances of all products as of the last sync, converted
+to a base currency (--base, the base_currency config setting or AMD). Cards
+sharing the balance of a listed account are counted once. Reads from the
+local database.
+
+--history adds the net worth at each balance snapshot ('sync --snapshot')
+and prints it as a chart, --width columns and --height lines tall. Each
+snapshot is converted at the stored exchange rates of its day (see 'fx' and
+'rates --save'). With --output csv, markdown or html the points are printed
+as a table instead, and --png writes the chart as an image.
+
+Loans and deposits aren't part of snapshots, so they're left out; 'fx'
+shows the current position including them.
+
+Examples:
+  ameriagrab networth --history
+  ameriagrab networth --history --output csv > networth.csv
+  ameriagrab networth --history --base USD --png networth.png`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := display.CheckFormat(); err != nil {
+			return err
+		}
+		if networthWidth < 2 || networthHeight < 2 {
+			return fmt.Errorf("--width and --height must be at least 2")
+		}
+		if networthPNG != "" && !networthHistory {
+			return fmt.Errorf("--png requires --history")
+		}
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		base := strings.ToUpper(networthBase)
+		if cfg := LoadConfig(); !cmd.Flags().Changed("base") && cfg.BaseCurrency != "" {
+			base = cfg.BaseCurrency
+		}
+		rates, err := database.GetRateHistory()
+		if err != nil {
+			return fmt.Errorf("fetching exchange rates: %w", err)
+		}
+		if _, ok := rates.Convert(1, base, db.LocalCurrency, time.Now()); !ok {
+			return fmt.Errorf("no exchange rate stored for %s (see 'rates --save' or 'fx --rate')", base)
+		}
+		convert := func(amount float64, from string, at time.Time) (float64, bool) {
+			return rates.Convert(amount, from, base, at)
+		}
+
+		products, err := database.GetProducts()
+		if err != nil {
+			return fmt.Errorf("fetching products: %w", err)
+		}
+		var snapshots []db.Snapshot
+		if networthHistory {
+			if snapshots, err = database.GetSnapshots(); err != nil {
+				return fmt.Errorf("fetching snapshots: %w", err)
+			}
+		}
+		points := report.NetWorthHistory(snapshots, products, convert, time.Now())
+
+		if networthJSONOutput {
+			if points == nil {
+				points = []report.NetWorthPoint{}
+			}
+			result := struct {
+				Currency string                 `json:"currency"`
+				Points   []report.NetWorthPoint `json:"points"`
+			}{base, points}
+			out, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling net worth: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		if len(points) == 0 {
+			fmt.Println("No products found. Use 'sync' to download data first.")
+			return nil
+		}
+		if !networthHistory {
+			display.PrintNetWorth(points[len(points)-1], base)
+			return nil
+		}
+		if networthPNG != "" {
+			if err := writeNetWorthPNG(networthPNG, points); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Wrote %s\n", networthPNG)
+		}
+		display.PrintNetWorthHistory(points, base, networthWidth, networthHeight)
+		return nil
+	},
+}
+
+// writeNetWorthPNG writes the net worth chart of --png
+func writeNetWorthPNG(path string, points []report.NetWorthPoint) error {
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Total
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	if err := output.WriteChartPNG(f, values, networthPNGWidth, networthPNGHeight); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+func init() {
+	networthCmd.Flags().StringVarP(&networthBase, "base", "b", db.LocalCurrency, "Base currency for conversion (default: base_currency config setting or AMD)")
+	networthCmd.Flags().BoolVar(&networthHistory, "history", false, "Show net worth over time from balance snapshots")
+	networthCmd.Flags().IntVar(&networthWidth, "width", 60, "Chart width in columns (--history)")
+	networthCmd.Flags().IntVar(&networthHeight, "height", 10, "Chart height in lines (--history)")
+	networthCmd.Flags().StringVar(&networthPNG, "png", "", "Also write the --history chart to a PNG file")
+	networthCmd.Flags().BoolVarP(&networthJSONOutput, "json", "j", false, "Output as JSON")
+	networthCmd.Flags().StringVar(&display.Format, "output", output.FormatTable, "Table format: table, markdown, html or csv")
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/notify"
+)
+
+// maxAnnouncedTransactions limits how many transactions are listed in a single notification
+const maxAnnouncedTransactions = 20
+
+// newNotifier builds the notifier from the config file, overridden by environment variables.
+// Configuration errors are reported as warnings and disable notifications.
+func newNotifier() *notify.Notifier {
+	cfg := LoadConfig().Notify
+	settings := notify.Settings{
+		TelegramToken:  cfg.Telegram.Token,
+		TelegramChatID: cfg.Telegram.ChatID,
+		SMTPAddr:       cfg.SMTP.Addr,
+		SMTPUser:       cfg.SMTP.User,
+		SMTPPassword:   cfg.SMTP.Password,
+		SMTPFrom:       cfg.SMTP.From,
+		SMTPTo:         notify.SplitList(cfg.SMTP.To),
+		WebhookURL:     cfg.Webhook.URL,
+		Desktop:        cfg.Desktop,
+	}
+	settings.ApplyEnv()
+
+	n, err := notify.New(settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: notifications disabled: %v\n", err)
+		return nil
+	}
+	return n
+}
+
+// sendNotification delivers a message, reporting failures as warnings
+func sendNotification(notifier *notify.Notifier, msg notify.Message) {
+	if !notifier.Enabled() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := notifier.Notify(ctx, msg); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to send notification: %v\n", err)
+	}
+}
+
+// announceNewTransactions sends a notification listing transactions synced since the given time
+func announceNewTransactions(database *db.DB, since time.Time, notifier *notify.Notifier) {
+	if !notifier.Enabled() {
+		return
+	}
+
+	entries, err := database.GetLedgerEntries(db.LedgerOptions{SyncedSince: since})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load new transactions: %v\n", err)
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	sendNotification(notifier, notify.Message{
+		Title: fmt.Sprintf("ameriagrab: %d new transactions", len(entries)),
+		Body:  formatLedgerEntries(entries),
+	})
+}
+
+// formatLedgerEntries renders entries as one line each, newest first
+func formatLedgerEntries(entries []db.LedgerEntry) string {
+	var b strings.Builder
+	shown := 0
+	for i := len(entries) - 1; i >= 0 && shown < maxAnnouncedTransactions; i-- {
+		e := entries[i]
+		fmt.Fprintf(&b, "%s  %+.2f %s  %s\n", e.Date.Format("2006-01-02 15:04"), e.SignedAmount(), e.Currency, e.Counterparty)
+		shown++
+	}
+	if len(entries) > shown {
+		fmt.Fprintf(&b, "... and %d more\n", len(entries)-shown)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// warnSyncHealth sends a high-priority warning once the failed syncs in a row
+// reach the notify.sync_failures threshold, repeated at each multiple of it,
+// and a note when syncs succeed again after a warning. prior is the failure
+// streak before the run that ended with syncErr.
+func warnSyncHealth(prior db.SyncFailureStreak, syncErr error, notifier *notify.Notifier) {
+	threshold := LoadConfig().Notify.SyncFailureThreshold()
+	if threshold == 0 || !notifier.Enabled() {
+		return
+	}
+	if syncErr == nil {
+		if prior.Count >= threshold {
+			sendNotification(notifier, notify.Message{
+				Title: "ameriagrab: sync works again",
+				Body: fmt.Sprintf("Sync succeeded after %d failed runs since %s.",
+					prior.Count, prior.Since.Format("2006-01-02 15:04")),
+			})
+		}
+		return
+	}
+	count := prior.Count + 1
+	if count%threshold != 0 {
+		return
+	}
+	since := prior.Since
+	if prior.Count == 0 {
+		since = time.Now()
+	}
+	sendNotification(notifier, syncFailureMessage(count, since, syncErr, time.Now()))
+}
+
+// syncFailureMessage is the warning sent when count syncs in a row have failed
+// since the given time, the last one with syncErr
+func syncFailureMessage(count int, since time.Time, syncErr error, now time.Time) notify.Message {
+	var failingFor string
+	switch d := now.Sub(since); {
+	case d >= 48*time.Hour:
+		failingFor = fmt.Sprintf("%d days", int(d.Hours()/24))
+	case d >= 2*time.Hour:
+		failingFor = fmt.Sprintf("%d hours", int(d.Hours()))
+	default:
+		failingFor = fmt.Sprintf("%d runs", count)
+	}
+	title := "ameriagrab: sync has been failing for " + failingFor
+	switch exitCode(syncErr) {
+	case ExitAuth:
+		title += " — session probably expired"
+	case ExitNetwork:
+		title += " — the bank can't be reached"
+	}
+	return notify.Message{
+		Title: title,
+		Body: fmt.Sprintf("%d sync runs in a row have failed since %s.\n\nLast error: %v",
+			count, since.Format("2006-01-02 15:04"), syncErr),
+		Priority: notify.PriorityHigh,
+	}
+}
+
+// recordSyncFailure records a sync run that failed before it could start,
+// e.g. at login, and warns if syncs keep failing
+func recordSyncFailure(database *db.DB, trigger string, syncErr error) {
+	prior, err := database.GetSyncFailureStreak()
+	if err == nil {
+		var runID int64
+		if runID, err = database.StartSyncRun(trigger); err == nil {
+			err = database.FinishSyncRun(runID, db.SyncRunFailed, syncErr.Error())
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record sync run: %v\n", err)
+		return
+	}
+	warnSyncHealth(prior, syncErr, newNotifier())
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+// noPager disables paging of long output (--no-pager)
+var noPager bool
+
+// runningPager is the pager started for the current command, if any
+var runningPager *pagerProcess
+
+// pagerProcess is a pager reading the command's stdout through a pipe
+type pagerProcess struct {
+	cmd    *exec.Cmd
+	w      *os.File
+	stdout *os.File // the terminal
+}
+
+// pagerCommand returns the pager to run given the PAGER environment variable:
+// less by default, none if PAGER is set to cat or to an empty string
+func pagerCommand(pager string, set bool) string {
+	if !set {
+		return "less"
+	}
+	if pager == "cat" {
+		return ""
+	}
+	return pager
+}
+
+// pagedCommands print tables that can get long; their subcommands are paged, too
+func pagedCommands() []*cobra.Command {
+	return []*cobra.Command{getCmd, listCmd, reportCmd}
+}
+
+// wantsPager reports whether the output of c is paged
+func wantsPager(c *cobra.Command) bool {
+	for ; c != nil; c = c.Parent() {
+		if slices.Contains(pagedCommands(), c) {
+			return true
+		}
+	}
+	return false
+}
+
+// startPager sends stdout through the pager if it's a terminal, like git does.
+// Unless LESS is set, less quits right away when the output fits on the screen
+// and keeps colors.
+func startPager(c *cobra.Command) {
+	if noPager || !wantsPager(c) {
+		return
+	}
+	if fi, err := os.Stdout.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+	command := pagerCommand(os.LookupEnv("PAGER"))
+	if command == "" {
+		return
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: starting pager: %v\n", err)
+		return
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = r
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	if err := cmd.Start(); err != nil {
+		r.Close()
+		w.Close()
+		fmt.Fprintf(os.Stderr, "Warning: starting pager: %v\n", err)
+		return
+	}
+	r.Close()
+
+	runningPager = &pagerProcess{cmd: cmd, w: w, stdout: os.Stdout}
+	os.Stdout = w
+	display.Out, display.Paged = w, true
+}
+
+// stopPager waits for the user to quit the pager and restores stdout
+func stopPager() {
+	if runningPager == nil {
+		return
+	}
+	runningPager.w.Close()
+	runningPager.cmd.Wait()
+	os.Stdout = runningPager.stdout
+	display.Out, display.Paged = runningPager.stdout, false
+	runningPager = nil
+}
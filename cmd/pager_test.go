@@ -0,0 +1,27 @@
+package cmd
+
+import "testing"
+
+func TestPagerCommand(t *testing.T) {
+	for _, tt := range []struct {
+		pager string
+		set   bool
+		want  string
+	}{
+		{"", false, "less"},
+		{"", true, ""},
+		{"cat", true, ""},
+		{"most", true, "most"},
+	} {
+		if got := pagerCommand(tt.pager, tt.set); got != tt.want {
+			t.Errorf("PAGER=%q (set: %v): expected %q, got %q", tt.pager, tt.set, tt.want, got)
+		}
+	}
+
+	if !wantsPager(reportPeriodCmd) {
+		t.Error("report subcommands should be paged")
+	}
+	if wantsPager(syncCmd) {
+		t.Error("sync should not be paged")
+	}
+}
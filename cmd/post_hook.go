@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// postSyncHookTimeout limits how long the post-sync hook may run
+const postSyncHookTimeout = 5 * time.Minute
+
+// runPostSyncHook runs the configured post-sync hook (sync.post_hook, or
+// AMERIA_POST_SYNC_HOOK) with sh -c, passing the sync summary as JSON on stdin.
+// The trigger and outcome are also available as AMERIA_SYNC_TRIGGER and
+// AMERIA_SYNC_STATUS. The hook's output goes to stderr; failures are reported
+// as warnings.
+func runPostSyncHook(summary *syncSummary, trigger string, syncErr error) {
+	command := LoadConfig().Sync.PostHook
+	if env := os.Getenv("AMERIA_POST_SYNC_HOOK"); env != "" {
+		command = env
+	}
+	if command == "" {
+		return
+	}
+
+	payload := *summary
+	status := "success"
+	if syncErr != nil {
+		payload.Error = syncErr.Error()
+		status = "failed"
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: post-sync hook: marshaling summary: %v\n", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), postSyncHookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "AMERIA_SYNC_TRIGGER="+trigger, "AMERIA_SYNC_STATUS="+status)
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: post-sync hook failed: %v\n", err)
+	}
+}
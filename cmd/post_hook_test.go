@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunPostSyncHook(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AMERIA_CONFIG", filepath.Join(dir, "config.yaml"))
+	t.Setenv("AMERIA_POST_SYNC_HOOK", `cat > "$HOOK_DIR/summary.json"; echo "$AMERIA_SYNC_TRIGGER $AMERIA_SYNC_STATUS" > "$HOOK_DIR/env"`)
+	t.Setenv("HOOK_DIR", dir)
+
+	summary := newSyncSummary()
+	summary.addProduct(productSyncStats{ProductID: "acc1", Inserted: 3})
+	runPostSyncHook(summary, "daemon", errors.New("1 of 1 products failed to sync"))
+
+	data, err := os.ReadFile(filepath.Join(dir, "summary.json"))
+	if err != nil {
+		t.Fatalf("hook didn't write the summary: %v", err)
+	}
+	var got syncSummary
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid summary JSON %q: %v", data, err)
+	}
+	if got.Inserted != 3 || len(got.Products) != 1 || got.Error == "" {
+		t.Errorf("unexpected summary: %+v", got)
+	}
+	if summary.Error != "" {
+		t.Errorf("expected the caller's summary to be left alone, got error %q", summary.Error)
+	}
+
+	env, err := os.ReadFile(filepath.Join(dir, "env"))
+	if err != nil {
+		t.Fatalf("hook didn't write the environment: %v", err)
+	}
+	if strings.TrimSpace(string(env)) != "daemon failed" {
+		t.Errorf("expected trigger and status 'daemon failed', got %q", env)
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/spf13/cobra"
+)
+
+// bankRateSource marks exchange rates stored from the bank's rates
+const bankRateSource = "bank"
+
+var (
+	ratesJSONOutput bool
+	ratesSave       bool
+)
+
+var ratesCmd = &cobra.Command{
+	Use:   "rates",
+	Short: "Show the bank's exchange rates",
+	Long: `Shows the bank's current buy and sell rates in AMD for cash, non-cash and
+card operations.
+
+With --save, the non-cash mid rates are stored in the local database and used
+for conversions by 'fx' and reports. Full syncs store them as well.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, accessToken, err := SetupClient(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		resp, err := c.GetExchangeRates(accessToken)
+		if err != nil {
+			return fmt.Errorf("fetching exchange rates: %w", err)
+		}
+
+		if ratesSave {
+			database, err := OpenDatabase()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			n, err := storeBankRates(database, resp.Data.Rates)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Stored %d exchange rates\n", n)
+		}
+
+		if ratesJSONOutput {
+			out, err := json.MarshalIndent(resp.Data.Rates, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling exchange rates: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+		if len(resp.Data.Rates) == 0 {
+			fmt.Println("No exchange rates found.")
+			return nil
+		}
+		display.PrintExchangeRates(resp.Data.Rates)
+		return nil
+	},
+}
+
+// conversionRates picks the rate used for conversions for each currency:
+// the non-cash mid rate, or the mid rate of any other type if there's none
+func conversionRates(rates []client.ExchangeRate) map[string]float64 {
+	result := make(map[string]float64)
+	for _, r := range rates {
+		currency := strings.ToUpper(r.Currency)
+		if _, ok := result[currency]; ok && r.Type != client.RateTypeNonCash {
+			continue
+		}
+		if r.Mid() > 0 {
+			result[currency] = r.Mid()
+		}
+	}
+	return result
+}
+
+// storeBankRates stores the conversion rates of the bank's exchange rates,
+// replacing earlier ones (including manually set rates) of the same currencies
+func storeBankRates(database *db.DB, rates []client.ExchangeRate) (int, error) {
+	converted := conversionRates(rates)
+	for currency, rate := range converted {
+		if err := database.SetExchangeRate(currency, rate, bankRateSource); err != nil {
+			return 0, err
+		}
+	}
+	return len(converted), nil
+}
+
+func init() {
+	ratesCmd.Flags().BoolVarP(&ratesJSONOutput, "json", "j", false, "Output as JSON")
+	ratesCmd.Flags().BoolVarP(&ratesSave, "save", "s", false, "Store the rates in the local database for conversions")
+}
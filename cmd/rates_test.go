@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestConversionRates(t *testing.T) {
+	rates := conversionRates([]client.ExchangeRate{
+		{Currency: "USD", Type: client.RateTypeCash, Buy: 386, Sell: 392},
+		{Currency: "USD", Type: client.RateTypeNonCash, Buy: 387, Sell: 391},
+		{Currency: "USD", Type: client.RateTypeCard, Buy: 385, Sell: 393},
+		{Currency: "eur", Type: client.RateTypeCash, Buy: 420, Sell: 430},
+		{Currency: "RUB", Type: client.RateTypeNonCash},
+	})
+	if len(rates) != 2 {
+		t.Fatalf("expected 2 rates, got %v", rates)
+	}
+	if rates["USD"] != 389 {
+		t.Errorf("expected non-cash USD mid rate 389, got %v", rates["USD"])
+	}
+	if rates["EUR"] != 425 {
+		t.Errorf("expected EUR to fall back to the cash mid rate 425, got %v", rates["EUR"])
+	}
+}
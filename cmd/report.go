@@ -0,0 +1,361 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/i18n"
+	"github.com/ivan4th/ameriagrab/output"
+	"github.com/ivan4th/ameriagrab/product"
+	"github.com/ivan4th/ameriagrab/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportFrom       string
+	reportTo         string
+	reportProduct    string
+	reportJSONOutput bool
+	reportWindow     time.Duration
+	heatmapWeeks     int
+	incomeMonths     int
+	reportConvert    string
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Reports over locally synced transactions",
+}
+
+var reportDuplicatesCmd = &cobra.Command{
+	Use:   "duplicates",
+	Short: "Flag likely double charges",
+	Long: `Flags debits with the same merchant, amount and currency on the same product
+occurring within --window of each other, so they can be disputed.
+
+Card and linked account transactions are both checked; a double charge visible in
+both tables is reported once. Rows stored under several dates for the same
+transaction ID are not considered duplicates.
+
+--convert CURRENCY shows the charges converted at the stored exchange rate of
+their dates, with the original amounts next to them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := display.CheckFormat(); err != nil {
+			return err
+		}
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		opts, err := reportLedgerOptions(database)
+		if err != nil {
+			return err
+		}
+		opts.Sources = []string{db.SourceCard, db.SourceLinked, db.SourceAccount}
+
+		entries, err := database.GetLedgerEntries(opts)
+		if err != nil {
+			return fmt.Errorf("fetching transactions: %w", err)
+		}
+		groups := report.FindDuplicates(entries, reportWindow)
+		if reportConvert != "" {
+			rates, err := setupConversion(database, reportConvert)
+			if err != nil {
+				return err
+			}
+			report.ConvertDuplicates(groups, rates, reportConvert)
+		}
+
+		if reportJSONOutput {
+			out, err := json.MarshalIndent(groups, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling duplicates: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		if len(groups) == 0 {
+			fmt.Println(i18n.T("No likely duplicate charges found."))
+			return nil
+		}
+		display.PrintDuplicateGroups(groups)
+		return nil
+	},
+}
+
+var reportPeriodCmd = &cobra.Command{
+	Use:   "period",
+	Short: "Statement for a product over a period",
+	Long: `Produces a statement for one product between --from and --to (inclusive):
+opening balance, all transactions, totals and closing balance.
+
+Balances are reconstructed from the snapshot (or last sync) nearest to the end
+of the period plus the transactions in between, so keeping regular snapshots
+('sync --snapshot') improves accuracy.
+
+--group-by day|month splits the transactions into days or months with subtotals.
+
+Example:
+  ameriagrab report period --from 2024-06-01 --to 2024-06-30 --product <id>`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if reportFrom == "" || reportTo == "" || reportProduct == "" {
+			return fmt.Errorf("--from, --to and --product are required")
+		}
+		if err := display.CheckFormat(); err != nil {
+			return err
+		}
+		if err := display.CheckGroupBy(); err != nil {
+			return err
+		}
+
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		opts, err := reportLedgerOptions(database)
+		if err != nil {
+			return err
+		}
+		if !opts.From.Before(opts.To) {
+			return fmt.Errorf("--from must not be after --to")
+		}
+
+		st, err := report.Period(database, opts.ProductID, opts.From, opts.To)
+		if err != nil {
+			return fmt.Errorf("building statement: %w", err)
+		}
+
+		if reportJSONOutput {
+			out, err := json.MarshalIndent(st, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling statement: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		display.PrintPeriodStatement(st)
+		return nil
+	},
+}
+
+var reportHeatmapCmd = &cobra.Command{
+	Use:   "heatmap",
+	Short: "Calendar heatmap of daily spending",
+	Long: `Prints a calendar of daily spending for each currency, one row per week
+starting on Monday, with each day shaded by how much was spent compared to the
+other days of the period: · for no spending, then ░ ▒ ▓ █ for each quarter of
+the days with spending. Each week ends with its total; the legend shows the
+amount each shade goes up to. Currency exchanges aren't counted.
+
+The period is --from to --to (inclusive), by default the last --weeks weeks up
+to today. With --output markdown, html or csv the days show their amounts.
+
+--convert CURRENCY converts spending at the stored exchange rate of each day,
+so all currencies share one calendar.
+
+Example:
+  ameriagrab report heatmap --weeks 26 --product <id>`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := display.CheckFormat(); err != nil {
+			return err
+		}
+		if heatmapWeeks < 1 {
+			return fmt.Errorf("--weeks must be at least 1")
+		}
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		opts, err := reportLedgerOptions(database)
+		if err != nil {
+			return err
+		}
+		if opts.To.IsZero() {
+			now := time.Now()
+			opts.To = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.Local)
+		}
+		if opts.From.IsZero() {
+			// Whole weeks, the last one being the current one
+			lastDay := opts.To.AddDate(0, 0, -1)
+			monday := lastDay.AddDate(0, 0, -(int(lastDay.Weekday())+6)%7)
+			opts.From = monday.AddDate(0, 0, -7*(heatmapWeeks-1))
+		}
+		if !opts.From.Before(opts.To) {
+			return fmt.Errorf("--from must not be after --to")
+		}
+
+		entries, err := database.GetLedgerEntries(opts)
+		if err != nil {
+			return fmt.Errorf("fetching transactions: %w", err)
+		}
+		if reportConvert != "" {
+			rates, err := setupConversion(database, reportConvert)
+			if err != nil {
+				return err
+			}
+			rates.ConvertEntries(entries, reportConvert)
+		}
+		heatmaps := report.Heatmaps(entries, opts.From, opts.To)
+
+		if reportJSONOutput {
+			out, err := json.MarshalIndent(heatmaps, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling heatmaps: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		if len(heatmaps) == 0 {
+			fmt.Println(i18n.T("No spending found."))
+			return nil
+		}
+		display.PrintHeatmaps(heatmaps)
+		return nil
+	},
+}
+
+var reportIncomeCmd = &cobra.Command{
+	Use:   "income",
+	Short: "Salary-like income and the savings rate per month",
+	Long: `Finds salary-like income (large credits from one counterparty recurring
+monthly, such as a salary, pension or rent received) and sums each month's
+income and expenses per currency, with the savings rate: income minus
+expenses as a share of income.
+
+Transfers between own products are neither income nor expenses: currency
+exchanges, and debits matched by a credit of the same amount and currency on
+another product within 3 days.
+
+The period is --from to --to (inclusive), by default the last --months
+months including the current one. --convert CURRENCY converts amounts at
+the stored exchange rate of their dates, so all currencies share one rate.
+
+Example:
+  ameriagrab report income --months 24 --convert AMD`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := display.CheckFormat(); err != nil {
+			return err
+		}
+		if incomeMonths < 1 {
+			return fmt.Errorf("--months must be at least 1")
+		}
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		opts, err := reportLedgerOptions(database)
+		if err != nil {
+			return err
+		}
+		if opts.From.IsZero() {
+			opts.From = report.MonthStart(time.Now()).AddDate(0, 1-incomeMonths, 0)
+		}
+		if !opts.To.IsZero() && !opts.From.Before(opts.To) {
+			return fmt.Errorf("--from must not be after --to")
+		}
+
+		entries, err := database.GetLedgerEntries(opts)
+		if err != nil {
+			return fmt.Errorf("fetching transactions: %w", err)
+		}
+		if reportConvert != "" {
+			rates, err := setupConversion(database, reportConvert)
+			if err != nil {
+				return err
+			}
+			rates.ConvertEntries(entries, reportConvert)
+		}
+		r := report.Income(entries)
+
+		if reportJSONOutput {
+			out, err := json.MarshalIndent(r, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling income: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		if len(r.Months) == 0 {
+			fmt.Println(i18n.T("No transactions found."))
+			return nil
+		}
+		display.PrintIncome(r)
+		return nil
+	},
+}
+
+// reportLedgerOptions builds ledger options from the common report flags
+func reportLedgerOptions(database *db.DB) (db.LedgerOptions, error) {
+	opts := db.LedgerOptions{ClientID: clientSelector}
+	var err error
+
+	if opts.From, err = parseDateFlag("from", reportFrom); err != nil {
+		return opts, err
+	}
+	if opts.To, err = parseDateFlag("to", reportTo); err != nil {
+		return opts, err
+	}
+	if !opts.To.IsZero() {
+		// --to is inclusive
+		opts.To = opts.To.AddDate(0, 0, 1)
+	}
+
+	if reportProduct != "" {
+		p, err := product.Resolve(database, reportProduct)
+		if err != nil {
+			return opts, err
+		}
+		if err := checkSelectedClient(p); err != nil {
+			return opts, err
+		}
+		opts.ProductID = p.ID
+	}
+
+	return opts, nil
+}
+
+// parseDateFlag parses a YYYY-MM-DD flag value in local time; empty yields the zero time
+func parseDateFlag(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s date %q (expected YYYY-MM-DD)", name, value)
+	}
+	return t, nil
+}
+
+func init() {
+	reportCmd.PersistentFlags().StringVar(&reportFrom, "from", "", "Start date (YYYY-MM-DD, inclusive)")
+	reportCmd.PersistentFlags().StringVar(&reportTo, "to", "", "End date (YYYY-MM-DD, inclusive)")
+	reportCmd.PersistentFlags().StringVarP(&reportProduct, "product", "p", "", "Product ID or name (default: all products)")
+	reportCmd.PersistentFlags().BoolVarP(&reportJSONOutput, "json", "j", false, "Output as JSON")
+	reportCmd.PersistentFlags().StringVar(&display.Format, "output", output.FormatTable, "Table format: table, markdown, html or csv")
+	reportDuplicatesCmd.Flags().DurationVarP(&reportWindow, "window", "w", 24*time.Hour, "Maximum time between duplicate charges")
+	for _, c := range []*cobra.Command{reportDuplicatesCmd, reportHeatmapCmd, reportIncomeCmd} {
+		c.Flags().StringVar(&reportConvert, "convert", "", "Convert amounts to this currency at the rates of their dates")
+	}
+	reportHeatmapCmd.Flags().IntVar(&heatmapWeeks, "weeks", 12, "Number of weeks up to today shown without --from")
+	reportIncomeCmd.Flags().IntVar(&incomeMonths, "months", 12, "Number of months up to the current one shown without --from")
+	reportPeriodCmd.Flags().StringVar(&display.GroupBy, "group-by", "", "Group transactions with subtotals: day or month")
+
+	reportCmd.AddCommand(reportDuplicatesCmd)
+	reportCmd.AddCommand(reportPeriodCmd)
+	reportCmd.AddCommand(reportHeatmapCmd)
+	reportCmd.AddCommand(reportIncomeCmd)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/product"
+	"github.com/spf13/cobra"
+)
+
+var requisitesJSONOutput bool
+
+var requisitesCmd = &cobra.Command{
+	Use:   "requisites [id|name]",
+	Short: "Show account requisites (IBAN, SWIFT, bank details) for incoming transfers",
+	Long: `Shows the requisites needed to receive a transfer to an account: holder name,
+account number, IBAN, bank name and address, SWIFT code and, for foreign
+currency accounts, the correspondent bank.
+
+The product is given by ID or name; for a card, the requisites of its linked
+account are shown. If omitted, the default_product config setting is used.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var id string
+		if len(args) > 0 {
+			id = args[0]
+		} else if id = LoadConfig().DefaultProduct; id == "" {
+			return fmt.Errorf("product ID required (or set one with 'config set default_product <id>')")
+		}
+
+		c, accessToken, err := SetupClient(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		resp, err := c.GetAccountsAndCards(accessToken)
+		if err != nil {
+			return fmt.Errorf("fetching accounts and cards: %w", err)
+		}
+		accountID, err := requisitesAccountID(resp.Data.AccountsAndCards, id)
+		if err != nil {
+			return err
+		}
+
+		reqResp, err := c.GetRequisites(accessToken, accountID)
+		if err != nil {
+			return fmt.Errorf("fetching requisites: %w", err)
+		}
+
+		if requisitesJSONOutput {
+			out, err := json.MarshalIndent(reqResp.Data.Requisites, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling requisites: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+		display.PrintRequisites(reqResp.Data.Requisites)
+		return nil
+	},
+}
+
+// requisitesAccountID finds a product by ID or name and returns the ID of its
+// account (the linked account for cards)
+func requisitesAccountID(products []client.ProductInfo, id string) (string, error) {
+	p, err := product.Find(products, id)
+	if err != nil {
+		return "", err
+	}
+	return product.AccountID(*p)
+}
+
+func init() {
+	requisitesCmd.Flags().BoolVarP(&requisitesJSONOutput, "json", "j", false, "Output as JSON")
+}
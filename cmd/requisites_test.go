@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestRequisitesAccountID(t *testing.T) {
+	products := []client.ProductInfo{
+		{ID: "card1", ProductType: "CARD", Name: "Visa Gold", AccountID: "acc1"},
+		{ID: "card2", ProductType: "CARD", Name: "Virtual"},
+		{ID: "acc2", ProductType: "ACCOUNT", Name: "Savings"},
+	}
+
+	for id, want := range map[string]string{"card1": "acc1", "visa gold": "acc1", "acc2": "acc2", "Savings": "acc2"} {
+		if got, err := requisitesAccountID(products, id); err != nil || got != want {
+			t.Errorf("requisitesAccountID(%q) = %q, %v; want %q", id, got, err, want)
+		}
+	}
+	for _, id := range []string{"card2", "missing"} {
+		if _, err := requisitesAccountID(products, id); err == nil {
+			t.Errorf("requisitesAccountID(%q) should fail", id)
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/config"
 	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/i18n"
+	"github.com/ivan4th/ameriagrab/output"
+	"github.com/ivan4th/ameriagrab/product"
 	"github.com/spf13/cobra"
 )
 
@@ -19,11 +25,69 @@ Environment variables:
   AMERIA_USERNAME  - Ameriabank username (required)
   AMERIA_PASSWORD  - Ameriabank password (required)
   AMERIA_DEBUG_DIR - Directory to save debug files (optional)
-  AMERIA_DB_PATH   - Path to SQLite database for sync/local mode and session persistence (optional)`,
+  AMERIA_DB_PATH   - Path to SQLite database for sync/local mode and session persistence (optional)
+  AMERIA_CONFIG    - Path to config file (optional, see 'config path')
+
+Users attached to several clients (e.g. personal and company) can pick one with
+--client (see 'clients'); local data is then restricted to that client's products.
+
+SIGINT and SIGTERM, and --timeout, stop a command gracefully: requests in
+flight are cancelled, data stored so far is kept and a sync records how far
+it got and releases its lock. A second signal exits immediately.`,
+	// Errors are printed by Execute in the --error-format
+	SilenceErrors: true,
+	// Amounts, table headers and labels follow the locale config setting, the icons setting
+	// adds category icons and the numbers setting is the default of --numbers. A broken config file is reported by the commands
+	// that use the rest of it.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		switch errorFormat {
+		case "text":
+		case "json":
+			// The usage text would follow the error object
+			cmd.SilenceUsage = true
+		default:
+			return fmt.Errorf("invalid --error-format %q (expected text or json)", errorFormat)
+		}
+		if path, err := config.Path(); err == nil {
+			if cfg, err := config.Load(path); err == nil {
+				display.Locale = cfg.Locale
+				i18n.SetLocale(cfg.Locale)
+				display.Icons = cfg.Icons
+				product.Aliases = cfg.Aliases
+				if cfg.Numbers != "" && !cmd.Flags().Changed("numbers") {
+					display.Numbers = cfg.Numbers
+				}
+			}
+		}
+		if err := display.CheckNumbers(); err != nil {
+			return err
+		}
+		if commandTimeout < 0 {
+			return fmt.Errorf("invalid --timeout %s", commandTimeout)
+		}
+		if commandTimeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), commandTimeout)
+			cmd.SetContext(ctx)
+			cancelTimeout = cancel
+		}
+		startPager(cmd)
+		return nil
+	},
 }
 
-// SetupClient creates and authenticates the Ameriabank client
-func SetupClient() (*client.Client, string, error) {
+// commandTimeout stops the command after the given time (--timeout, 0: no limit)
+var commandTimeout time.Duration
+
+// cancelTimeout releases the timer of --timeout
+var cancelTimeout context.CancelFunc = func() {}
+
+// display prints tables and text with the rendering settings given by flags
+// and config. The pager replaces its Out while it runs.
+var display = output.Std()
+
+// SetupClient creates and authenticates the Ameriabank client. Its requests
+// are cancelled with ctx.
+func SetupClient(ctx context.Context) (*client.Client, string, error) {
 	username := os.Getenv("AMERIA_USERNAME")
 	password := os.Getenv("AMERIA_PASSWORD")
 	debugDir := os.Getenv("AMERIA_DEBUG_DIR")
@@ -38,7 +102,7 @@ func SetupClient() (*client.Client, string, error) {
 	if dbPath != "" {
 		database, err := db.Open(dbPath)
 		if err != nil {
-			return nil, "", fmt.Errorf("opening database for session: %w", err)
+			return nil, "", &exitError{ExitDatabase, fmt.Errorf("opening database for session: %w", err)}
 		}
 		sessionStorage = database
 		// Note: we don't close the database here - it will be used for the session
@@ -49,18 +113,51 @@ func SetupClient() (*client.Client, string, error) {
 	if err != nil {
 		return nil, "", fmt.Errorf("creating client: %w", err)
 	}
+	c.Context = ctx
+	if database, ok := sessionStorage.(*db.DB); ok {
+		c.ProductsCache = database
+		c.ProductsTTL = productsCacheTTL(LoadConfig())
+	}
+
+	accessToken, err := authenticate(c)
+	if err != nil {
+		return nil, "", err
+	}
+	if clientSelector != "" {
+		c.ClientID = clientSelector
+	}
+
+	return c, accessToken, nil
+}
+
+// productsCacheTTL returns how long cached accounts and cards are reused
+// (cache.products_ttl); invalid settings disable the cache
+func productsCacheTTL(cfg *config.Config) time.Duration {
+	if cfg.Cache.ProductsTTL == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(cfg.Cache.ProductsTTL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring cache.products_ttl: %v\n", err)
+		return 0
+	}
+	return d
+}
 
+// authenticate obtains an access token for the client, reusing a saved session
+// when possible and initializing the client ID after a fresh login
+func authenticate(c *client.Client) (string, error) {
 	fmt.Fprintln(os.Stderr, "Checking for saved session or logging in...")
 	accessToken, err := c.GetOrRefreshToken()
 	if err != nil {
-		return nil, "", fmt.Errorf("getting access token: %w", err)
+		return "", &exitError{ExitAuth, fmt.Errorf("getting access token: %w", err)}
 	}
 
 	// Initialize session with prerequisite API calls (only if clientID not restored)
 	if c.ClientID == "" {
 		fmt.Fprintln(os.Stderr, "Client ID not found in session, initializing...")
 		if err := c.InitializeSession(accessToken); err != nil {
-			return nil, "", fmt.Errorf("initializing session: %w", err)
+			return "", &exitError{ExitAuth, fmt.Errorf("initializing session: %w", err)}
 		}
 		if err := c.UpdateSessionClientID(); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to update session with client ID: %v\n", err)
@@ -69,27 +166,69 @@ func SetupClient() (*client.Client, string, error) {
 		fmt.Fprintf(os.Stderr, "Using restored Client ID: %s\n", c.ClientID)
 	}
 
-	return c, accessToken, nil
+	return accessToken, nil
+}
+
+// LoadConfig loads the config file. Errors are reported as warnings
+// and yield an empty config, so a broken file doesn't block commands.
+func LoadConfig() *config.Config {
+	path, err := config.Path()
+	if err == nil {
+		var cfg *config.Config
+		if cfg, err = config.Load(path); err == nil {
+			return cfg
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Warning: ignoring config: %v\n", err)
+	return &config.Config{}
 }
 
 // OpenDatabase opens the SQLite database from AMERIA_DB_PATH
 func OpenDatabase() (*db.DB, error) {
 	dbPath := os.Getenv("AMERIA_DB_PATH")
 	if dbPath == "" {
-		return nil, fmt.Errorf("AMERIA_DB_PATH environment variable must be set")
+		return nil, &exitError{ExitDatabase, fmt.Errorf("AMERIA_DB_PATH environment variable must be set")}
 	}
 
 	database, err := db.Open(dbPath)
 	if err != nil {
-		return nil, fmt.Errorf("opening database: %w", err)
+		return nil, &exitError{ExitDatabase, fmt.Errorf("opening database: %w", err)}
 	}
 
 	return database, nil
 }
 
 func init() {
+	RootCmd.PersistentFlags().StringVar(&clientSelector, "client", "", "Client ID to act for and to restrict local data to (see 'clients')")
+	RootCmd.PersistentFlags().StringVar(&errorFormat, "error-format", "text", "Error output format: text or json (see the exit codes in the README)")
+	RootCmd.PersistentFlags().DurationVar(&commandTimeout, "timeout", 0, "Stop the command gracefully after this long, like on SIGINT (e.g. 10m; 0: no limit)")
+	RootCmd.PersistentFlags().BoolVar(&noPager, "no-pager", false, "Don't page long output of get, list and report through $PAGER")
+	RootCmd.PersistentFlags().StringVar(&display.Numbers, "numbers", output.NumbersMasked, "Card and account numbers: masked, last4 or hidden (default from the numbers config setting)")
+	RootCmd.PersistentFlags().BoolVar(&display.NoColor, "no-color", false, "Disable colored table output (also disabled by NO_COLOR or when not a terminal)")
 	RootCmd.AddCommand(listCmd)
 	RootCmd.AddCommand(getCmd)
 	RootCmd.AddCommand(syncCmd)
+	RootCmd.AddCommand(syncIntervalCmd)
 	RootCmd.AddCommand(listSnapshotsCmd)
+	RootCmd.AddCommand(daemonCmd)
+	RootCmd.AddCommand(alertCmd)
+	RootCmd.AddCommand(budgetCmd)
+	RootCmd.AddCommand(loansCmd)
+	RootCmd.AddCommand(depositsCmd)
+	RootCmd.AddCommand(requisitesCmd)
+	RootCmd.AddCommand(fxCmd)
+	RootCmd.AddCommand(networthCmd)
+	RootCmd.AddCommand(ratesCmd)
+	RootCmd.AddCommand(statementCmd)
+	RootCmd.AddCommand(utilitiesCmd)
+	RootCmd.AddCommand(counterpartiesCmd)
+	RootCmd.AddCommand(clientsCmd)
+	RootCmd.AddCommand(reportCmd)
+	RootCmd.AddCommand(configCmd)
+	RootCmd.AddCommand(webCmd)
+	RootCmd.AddCommand(serveCmd)
+	RootCmd.AddCommand(mcpCmd)
+	RootCmd.AddCommand(dbCmd)
+	RootCmd.AddCommand(botCmd)
+	RootCmd.AddCommand(calendarCmd)
 }
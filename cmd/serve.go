@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/server"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveListen  string
+	serveToken   string
+	serveGraphQL bool
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a read-only JSON API over the local database",
+	Long: `Serves read-only JSON endpoints over the local database:
+
+  GET /api/v1/products[/{id}]
+  GET /api/v1/transactions?product=&from=&to=&source=&type=&currency=&min_amount=&max_amount=&q=&order=&limit=&offset=
+  GET /api/v1/snapshots
+  GET /api/v1/loans
+  GET /api/v1/deposits
+  GET /api/v1/reports/monthly?product=&from=&to=
+  GET /api/v1/reports/duplicates?product=&from=&to=&window=
+  GET /api/v1/reports/period?product=&from=&to=
+  GET /api/v1/reports/fx?base=
+  GET /api/v1/calendar.ics     (iCalendar feed of upcoming payments)
+
+With --graphql, GraphQL queries over products, transactions (with filters and
+summaries grouped by month, currency, product, counterparty or type) and
+snapshots are also served:
+
+  GET|POST /api/v1/graphql
+  GET      /api/v1/graphql/schema
+
+Requests must include "Authorization: Bearer <token>". The token is taken from
+--token or AMERIA_API_TOKEN; if neither is set, a random token is generated and
+printed at startup. The calendar feed also accepts the token as ?token=<token>,
+since calendar apps can't send headers.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := serveToken
+		if token == "" {
+			token = os.Getenv("AMERIA_API_TOKEN")
+		}
+		if token == "" {
+			buf := make([]byte, 16)
+			if _, err := rand.Read(buf); err != nil {
+				return fmt.Errorf("generating token: %w", err)
+			}
+			token = hex.EncodeToString(buf)
+			fmt.Fprintf(os.Stderr, "Generated API token: %s\n", token)
+		}
+
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		api := server.New(database, token)
+		if serveGraphQL {
+			api.EnableGraphQL()
+		}
+		httpServer := &http.Server{
+			Addr:              serveListen,
+			Handler:           api,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		return serveUntilSignal(cmd.Context(), httpServer)
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "Bearer token required by clients (default: $AMERIA_API_TOKEN or random)")
+	serveCmd.Flags().BoolVar(&serveGraphQL, "graphql", false, "Also serve the GraphQL endpoint at /api/v1/graphql")
+}
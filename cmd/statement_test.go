@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatementPeriod(t *testing.T) {
+	now := time.Date(2024, 7, 10, 15, 0, 0, 0, time.Local)
+	for _, tc := range []struct {
+		month    time.Time
+		from, to string
+	}{
+		{time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local), "2024-06-01", "2024-06-30"},
+		{time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local), "2024-02-01", "2024-02-29"},
+		// The current month ends today
+		{time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local), "2024-07-01", "2024-07-10"},
+	} {
+		from, to := statementPeriod(tc.month, now)
+		if from.Format("2006-01-02") != tc.from || to.Format("2006-01-02") != tc.to {
+			t.Errorf("statementPeriod(%s): expected %s..%s, got %s..%s", tc.month.Format("2006-01"),
+				tc.from, tc.to, from.Format("2006-01-02"), to.Format("2006-01-02"))
+		}
+	}
+
+	// A future month yields an empty period
+	from, to := statementPeriod(time.Date(2024, 8, 1, 0, 0, 0, 0, time.Local), now)
+	if !from.After(to) {
+		t.Errorf("expected empty period for a future month, got %s..%s", from, to)
+	}
+}
@@ -3,35 +3,150 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"sync"
+	"strings"
+	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/config"
 	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/product"
 	"github.com/spf13/cobra"
-	"golang.org/x/sync/errgroup"
 )
 
 var (
 	syncVerbose  bool
 	syncForce    bool
 	syncSnapshot bool
+	syncSince    string
+	syncOnly     string
+	syncDryRun   bool
+
+	syncSnapshotDaily bool
+	syncAllClients    bool
+	syncSkipTemplates bool
+	syncFailFast      bool
+	syncJSONOutput    bool
+
+	// syncProductIDs limits the sync to the products given as arguments
+	syncProductIDs map[string]bool
+
+	// syncDueOnly skips products whose sync interval hasn't elapsed (scheduled
+	// daemon runs and --due-only); doSync collects them in syncNotDue
+	syncDueOnly bool
+	syncNotDue  map[string]bool
+)
+
+// defaultSyncPageSize is the number of transactions requested per API page
+// unless set by --page-size or sync.page_size
+const defaultSyncPageSize = config.MaxPageSize
+
+var (
+	// syncPageSize and syncFromAmount are set by --page-size and --from-amount,
+	// falling back to the sync config section (see applySyncSettings)
+	syncPageSize      = defaultSyncPageSize
+	syncFromAmount    float64
+	syncPageSizeSet   bool
+	syncFromAmountSet bool
 )
 
-const syncPageSize = 1000
+// syncOverlap is subtracted from the last successful sync when choosing the default
+// window, since transactions can appear days after their operation date
+const syncOverlap = 7 * 24 * time.Hour
 
 var syncCmd = &cobra.Command{
-	Use:   "sync",
+	Use:   "sync [product-id|name ...]",
 	Short: "Sync all transactions to local database",
 	Long: `Downloads all accounts, cards, and their transactions to a local SQLite database.
 
-For cards, fetches both card transactions and linked account transactions.
-Uses page size of 1000 to efficiently download all history.
+For cards, fetches both card transactions and linked account transactions,
+and replaces the stored pending authorizations (holds) not settled yet.
+Available balances and transfer templates (used to name counterparties in local
+mode) are stored as well; --skip-templates leaves the templates alone.
+History is requested in pages of 1000 transactions; --page-size (or the
+sync.page_size config key) sets a smaller page size. Card events below
+--from-amount (or sync.from_amount) are not fetched; the default, 0, fetches all
+of them.
+
+Only history from --since (YYYY-MM-DD) on is requested. Without --since, the
+window starts a week before the last successful sync; the first sync, and
+--force, walk the full history.
+
+Transactions that were stored before but come back with changed fields (e.g.
+the state once settled, amended details or the settled amount) are updated,
+and each change is recorded in the transaction_changes table. With --force,
+paging doesn't stop at already stored transactions, and extended info is
+re-fetched for all of them.
+
+Since simple paging doesn't reach far back in the history, --backfill requests
+it in monthly date windows instead, from the current month backwards, until 12
+months in a row have no transactions (or --since is reached). Use it to capture
+the full multi-year history on a fresh install.
+
+Products given as arguments (by ID or name, as stored by an earlier sync) and
+--only cards|accounts limit transaction syncing to the selected products.
+Such partial syncs skip templates, loans and deposits, and don't advance the
+default window of later syncs.
+
+With --due-only, as in scheduled daemon runs, products with a sync interval
+(see 'sync-interval') are skipped until it has elapsed since they were last
+synced. A product's window reaches back to a week before its own last sync, so
+skipped runs don't leave gaps.
+
+With --dry-run, everything is fetched from the API as usual, but instead of
+writing to the database, the planned changes are printed: new and updated
+products, new transactions per product, and the number of templates, loans and
+deposits that would be stored. The database, including the accounts and cards
+cache, is left untouched.
+
+A product that fails to sync doesn't stop the others (unless --fail-fast is
+given), but the run is recorded as failed and the command exits with
+status 6 (see --error-format) after printing a one-line JSON summary of the failed products
+to stderr, e.g.:
+
+  {"total":3,"errors":[{"product_id":"...","product_name":"...","error":"..."}]}
+
+Only one sync (including daemon and bot syncs) runs against a database at a
+time; a second one fails, or waits for the first with --wait. Locks of crashed
+processes on this host, or older than 6 hours, are taken over.
+
+After each sync, successful or not, the command set by the sync.post_hook
+config key (or AMERIA_POST_SYNC_HOOK) is run with sh -c. It receives the JSON
+summary described below on stdin, and AMERIA_SYNC_TRIGGER and
+AMERIA_SYNC_STATUS (success or failed) in its environment.
+
+With --json, a summary is printed on stdout instead: per product the number of
+inserted and changed transactions, API pages fetched, duration and error, plus
+totals and the ID of the snapshot taken with --snapshot.
+
+--snapshot-daily creates a snapshot like --snapshot, but replaces any snapshots
+taken earlier on the same calendar day, so frequent scheduled syncs keep at
+most one snapshot per day.
 
 Environment variables:
-  AMERIA_DB_PATH - Path to SQLite database file (required)`,
+  AMERIA_DB_PATH        - Path to SQLite database file (required)
+  AMERIA_POST_SYNC_HOOK - Command run after each sync (overrides sync.post_hook)`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if syncOnly != "" && syncOnly != "cards" && syncOnly != "accounts" {
+			return fmt.Errorf("invalid --only %q (expected cards or accounts)", syncOnly)
+		}
+		if syncJSONOutput && syncDryRun {
+			return fmt.Errorf("--json can't be combined with --dry-run")
+		}
+		if syncAllClients && clientSelector != "" {
+			return fmt.Errorf("--all-clients can't be combined with --client")
+		}
+		syncPageSizeSet = cmd.Flags().Changed("page-size")
+		if syncPageSizeSet && (syncPageSize < 1 || syncPageSize > config.MaxPageSize) {
+			return fmt.Errorf("invalid --page-size %d (expected 1-%d)", syncPageSize, config.MaxPageSize)
+		}
+		syncFromAmountSet = cmd.Flags().Changed("from-amount")
+		if syncFromAmount < 0 {
+			return fmt.Errorf("invalid --from-amount %v (expected a non-negative number)", syncFromAmount)
+		}
+
 		// Open database
 		database, err := OpenDatabase()
 		if err != nil {
@@ -39,80 +154,586 @@ Environment variables:
 		}
 		defer database.Close()
 
-		// Setup client and authenticate
-		c, accessToken, err := SetupClient()
+		syncProductIDs, err = resolveSyncProducts(database, args)
 		if err != nil {
 			return err
 		}
 
-		// Fetch and store products
-		fmt.Fprintln(os.Stderr, "Fetching accounts and cards...")
-		resp, err := c.GetAccountsAndCards(accessToken)
+		trigger := "manual"
+		if syncPartial() {
+			trigger = db.SyncTriggerPartial
+		}
+
+		// Setup client and authenticate
+		c, accessToken, err := SetupClient(cmd.Context())
 		if err != nil {
-			return fmt.Errorf("fetching accounts and cards: %w", err)
+			if !syncDryRun && cmd.Context().Err() == nil {
+				recordSyncFailure(database, trigger, err)
+			}
+			return err
 		}
 
-		// Fetch available balance for each product
-		for i := range resp.Data.AccountsAndCards {
-			p := &resp.Data.AccountsAndCards[i]
-			balResp, err := c.GetAvailableBalance(accessToken, p.ProductType, p.ID)
+		if syncDryRun {
+			syncPlan = newDryRunPlan()
+			_, err := doSync(cmd.Context(), database, c, accessToken)
+			var failure *syncFailure
+			if err != nil && !errors.As(err, &failure) {
+				return err
+			}
+			syncPlan.Print(os.Stdout)
+			return err
+		}
+
+		summary, err := runSync(cmd.Context(), database, c, accessToken, trigger)
+		if syncJSONOutput {
+			if summary == nil {
+				summary = newSyncSummary()
+			}
 			if err != nil {
-				return fmt.Errorf("fetching available balance for %s: %w", p.ID, err)
+				summary.Error = err.Error()
 			}
-			p.AvailableBalance = balResp.Data.AvailableBalance
+			out, jerr := json.MarshalIndent(summary, "", "  ")
+			if jerr != nil {
+				return fmt.Errorf("marshaling summary: %w", jerr)
+			}
+			fmt.Println(string(out))
+			return err
 		}
+		if cmd.Context().Err() != nil && summary != nil {
+			fmt.Fprintf(os.Stderr, "Stored %d new and %d changed transactions before stopping\n", summary.Inserted, summary.Changed)
+		}
+		return err
+	},
+}
+
+// runSync syncs products, templates, loans, deposits and transactions into the database.
+// Overlapping runs against the same database are prevented by the sync lock.
+// Each run is recorded in the sync_runs table with the given trigger (repeated
+// failures are warned about, see warnSyncHealth),
+// and alert rules are evaluated after a sync that succeeded at least partially
+// (see syncFailure). New transactions are
+// then posted to the configured webhook and Home Assistant; daemon runs also
+// announce them through the configured notification sinks.
+//
+// Cancelling ctx stops the sync after the products synced so far: batches of
+// transactions being stored are committed, the run is recorded as failed and
+// the lock is released.
+func runSync(ctx context.Context, database *db.DB, c *client.Client, accessToken, trigger string) (*syncSummary, error) {
+	release, err := acquireSyncLock(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	startedAt := time.Unix(time.Now().Unix(), 0)
+	runID, err := database.StartSyncRun(trigger)
+	if err != nil {
+		return nil, err
+	}
 
-		if err := database.UpsertProducts(resp.Data.AccountsAndCards); err != nil {
-			return fmt.Errorf("storing products: %w", err)
+	// Products synced before a per-product failure are still processed
+	summary, syncErr := doSync(ctx, database, c, accessToken)
+	notifier := newNotifier()
+	var failure *syncFailure
+	if syncErr == nil || errors.As(syncErr, &failure) {
+		checkAlerts(database, startedAt, notifier)
+		deliverWebhook(database)
+		publishToHomeAssistant(database)
+		if trigger == "daemon" {
+			announceNewTransactions(database, startedAt, notifier)
 		}
-		fmt.Fprintf(os.Stderr, "Stored %d products\n", len(resp.Data.AccountsAndCards))
+	}
 
-		// Sync transfer templates
-		fmt.Fprintln(os.Stderr, "Syncing transfer templates...")
-		templates, err := c.GetTemplates(accessToken)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to fetch templates: %v\n", err)
+	status, errMsg := db.SyncRunSuccess, ""
+	if syncErr != nil {
+		status, errMsg = db.SyncRunFailed, syncErr.Error()
+	}
+	// The streak before this run, which is still running
+	prior, err := database.GetSyncFailureStreak()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if err := database.FinishSyncRun(runID, status, errMsg); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record sync run: %v\n", err)
+	} else if ctx.Err() == nil {
+		warnSyncHealth(prior, syncErr, notifier)
+	}
+	runPostSyncHook(summary, trigger, syncErr)
+
+	return summary, syncErr
+}
+
+// resolveSyncProducts resolves product IDs or names to a set of product IDs
+func resolveSyncProducts(database *db.DB, args []string) (map[string]bool, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	ids := make(map[string]bool, len(args))
+	for _, arg := range args {
+		p, err := product.Resolve(database, arg)
+		var notFound *product.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, fmt.Errorf("%w (run a full sync first)", err)
+		} else if err != nil {
+			return nil, err
+		}
+		ids[p.ID] = true
+	}
+	return ids, nil
+}
+
+// syncPartial reports whether the sync is limited to selected products
+func syncPartial() bool {
+	return syncOnly != "" || len(syncProductIDs) > 0
+}
+
+// syncSelected reports whether a product's transactions should be synced
+func syncSelected(p client.ProductInfo) bool {
+	switch {
+	case product.IsGoal(p):
+		return false
+	case syncOnly == "cards" && !product.IsCard(p):
+		return false
+	case syncOnly == "accounts" && product.IsCard(p):
+		return false
+	case len(syncProductIDs) > 0 && !syncProductIDs[p.ID]:
+		return false
+	case syncNotDue[p.ID]:
+		return false
+	}
+	return true
+}
+
+// applySyncSettings sets the page size and the events amount filter from the sync
+// config section, unless they were given as flags
+func applySyncSettings(c *client.Client) {
+	cfg := LoadConfig().Sync
+	if !syncPageSizeSet {
+		syncPageSize = defaultSyncPageSize
+		if cfg.PageSize > 0 {
+			syncPageSize = cfg.PageSize
+		}
+	}
+	if !syncFromAmountSet {
+		syncFromAmount = cfg.FromAmount
+	}
+	c.EventsFromAmount = syncFromAmount
+}
+
+// syncWindowStart returns the date history is synced from, or the zero time for full history
+func syncWindowStart(database *db.DB) (time.Time, error) {
+	if syncSince != "" {
+		return parseDateFlag("since", syncSince)
+	}
+	if syncForce || syncBackfill {
+		return time.Time{}, nil
+	}
+	last, err := database.LastSuccessfulSync()
+	if err != nil || last.IsZero() {
+		return time.Time{}, err
+	}
+	return last.Add(-syncOverlap), nil
+}
+
+// productWindowStart returns the date a product's history is synced from: the
+// sync window, moved back to cover the product's own last sync if runs since
+// skipped it (see syncDueOnly). An explicit --since is kept as is.
+func productWindowStart(since, lastSynced time.Time) time.Time {
+	if syncSince != "" {
+		return since
+	}
+	if start := lastSynced.Add(-syncOverlap); !lastSynced.IsZero() && beforeWindow(start, since) {
+		return start
+	}
+	return since
+}
+
+// beforeWindow reports whether a known date t lies before a sync window starting at since
+func beforeWindow(t, since time.Time) bool {
+	return !since.IsZero() && !t.IsZero() && t.Before(since)
+}
+
+// doSync performs the actual sync work. The returned summary is never nil.
+// Products are no longer synced once ctx is done; database writes aren't
+// cancelled, so what was fetched is stored.
+func doSync(ctx context.Context, database *db.DB, c *client.Client, accessToken string) (*syncSummary, error) {
+	summary := newSyncSummary()
+	defer func(start time.Time) { summary.Duration = time.Since(start).Seconds() }(time.Now())
+	applySyncSettings(c)
+
+	since, err := syncWindowStart(database)
+	if err != nil {
+		return summary, err
+	}
+	if !since.IsZero() {
+		fmt.Fprintf(os.Stderr, "Syncing history since %s\n", since.Format("2006-01-02"))
+	}
+
+	startedAt := time.Now()
+	syncStates, err := database.GetProductSyncStates()
+	if err != nil {
+		return summary, err
+	}
+	syncNotDue = nil
+	if syncDueOnly {
+		syncNotDue = make(map[string]bool)
+		for id, s := range syncStates {
+			if !s.Due(startedAt) {
+				syncNotDue[id] = true
+			}
+		}
+		if len(syncNotDue) > 0 {
+			fmt.Fprintf(os.Stderr, "Skipping %d products not due for sync\n", len(syncNotDue))
+		}
+	}
+
+	// Products are fetched and their transactions synced with the Client-Id
+	// they belong to; everything else uses the session's client
+	homeClientID := c.ClientID
+	defer func() { c.ClientID = homeClientID }()
+	clientIDs, err := syncClientIDs(c, accessToken, homeClientID)
+	if err != nil {
+		return summary, err
+	}
+
+	// Fetch and store products. Dry runs don't update the products cache.
+	fetchProducts := c.RefreshAccountsAndCards
+	if syncPlan != nil {
+		fetchProducts = c.FetchAccountsAndCards
+	}
+	var products []client.ProductInfo
+	for _, clientID := range clientIDs {
+		c.ClientID = clientID
+		if len(clientIDs) > 1 {
+			fmt.Fprintf(os.Stderr, "Fetching accounts and cards of client %s...\n", clientID)
 		} else {
-			if err := database.UpsertTemplates(templates.Data.Templates); err != nil {
-				return fmt.Errorf("storing templates: %w", err)
+			fmt.Fprintln(os.Stderr, "Fetching accounts and cards...")
+		}
+		resp, err := fetchProducts(accessToken)
+		if err != nil {
+			return summary, fmt.Errorf("fetching accounts and cards: %w", err)
+		}
+		for _, p := range append(resp.Data.AccountsAndCards, fetchGoals(c, accessToken)...) {
+			p.ClientID = clientID
+			products = append(products, p)
+		}
+	}
+
+	// Fetch available balance for each selected product, keeping the stored one
+	// for the rest. A product whose balance can't be fetched keeps its stored
+	// one too, and is reported as failed after its transactions are synced.
+	balanceErrs := make(map[string]error)
+	for i := range products {
+		p := &products[i]
+		c.ClientID = p.ClientID
+		if product.IsGoal(*p) {
+			// Goals come with their balance
+			continue
+		}
+		if syncSelected(*p) {
+			balance, err := product.Remote(c, accessToken).AvailableBalance(*p)
+			if err == nil {
+				p.AvailableBalance = balance
+				continue
 			}
-			if syncVerbose {
-				fmt.Fprintf(os.Stderr, "  Synced %d templates\n", len(templates.Data.Templates))
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			balanceErrs[p.ID] = err
+		}
+		stored, err := database.GetProductByID(p.ID)
+		if err != nil {
+			return summary, err
+		}
+		if stored != nil {
+			p.AvailableBalance = stored.AvailableBalance
+		}
+	}
+
+	if syncPlan != nil {
+		if err := syncPlan.addProducts(database, products); err != nil {
+			return summary, err
+		}
+	} else {
+		if err := database.UpsertProducts(products); err != nil {
+			return summary, fmt.Errorf("storing products: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Stored %d products\n", len(products))
+	}
+
+	c.ClientID = homeClientID
+	if !syncPartial() {
+		if err := syncTemplatesLoansDeposits(database, c, accessToken); err != nil {
+			return summary, err
+		}
+		if err := syncUtilityPayments(database, c, accessToken, since); err != nil {
+			return summary, err
+		}
+	}
+
+	// Sync transactions for each selected product, collecting per-product failures
+	var failures syncFailure
+	for _, p := range products {
+		if !syncSelected(p) {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		refreshSyncLock(database)
+		c.ClientID = p.ClientID
+		failures.Total++
+		started := time.Now()
+		productSince := productWindowStart(since, syncStates[p.ID].LastSyncedAt)
+		var stats productSyncStats
+		var err error
+		switch {
+		case syncBackfill && product.IsCard(p):
+			stats, err = backfillCard(database, c, accessToken, p.ID, p.AccountID, p.Name, productSince)
+		case syncBackfill:
+			stats, err = backfillAccount(database, c, accessToken, p.ID, p.Name, productSince)
+		case product.IsCard(p):
+			stats, err = syncCard(database, c, accessToken, p.ID, p.AccountID, p.Name, productSince)
+		default:
+			stats, err = syncAccount(database, c, accessToken, p.ID, p.Name, productSince)
+		}
+		if err == nil {
+			err = balanceErrs[p.ID]
+		}
+		if err == nil && syncPlan == nil {
+			if err := database.MarkProductSynced(p.ID, startedAt); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 			}
 		}
+		stats.ProductID, stats.ProductName, stats.ProductType = p.ID, p.Name, p.ProductType
+		stats.Duration = time.Since(started).Seconds()
+		if err != nil {
+			stats.Error = err.Error()
+		}
+		summary.addProduct(stats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: error syncing %s %s: %v\n", strings.ToLower(p.ProductType), p.ID, err)
+			failures.Errors = append(failures.Errors, productSyncError{ProductID: p.ID, ProductName: p.Name, Error: err.Error()})
+			if syncFailFast {
+				break
+			}
+		}
+	}
 
-		// Sync transactions for each product
-		for _, p := range resp.Data.AccountsAndCards {
-			if p.ProductType == "CARD" {
-				if err := syncCard(database, c, accessToken, p.ID, p.AccountID, p.Name); err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: error syncing card %s: %v\n", p.ID, err)
-				}
-			} else {
-				if err := syncAccount(database, c, accessToken, p.ID, p.Name); err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: error syncing account %s: %v\n", p.ID, err)
-				}
+	if ctx.Err() != nil {
+		synced := len(summary.Products) - summary.Failed
+		return summary, fmt.Errorf("sync stopped after %d of %d products: %w", synced, countSelected(products), interruption(ctx))
+	}
+
+	if syncPlan != nil {
+		fmt.Fprintln(os.Stderr, "Dry run complete, nothing was written")
+		return summary, failures.err()
+	}
+
+	if n, err := database.RebuildCounterparties(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to rebuild counterparties: %v\n", err)
+	} else {
+		fmt.Fprintf(os.Stderr, "Rebuilt directory of %d counterparties\n", n)
+	}
+
+	// Create snapshot if requested
+	switch {
+	case syncSnapshotDaily:
+		snapshotID, replaced, err := database.CreateDailySnapshot()
+		if err != nil {
+			return summary, fmt.Errorf("creating snapshot: %w", err)
+		}
+		summary.SnapshotID = snapshotID
+		fmt.Fprintf(os.Stderr, "Created snapshot #%d (replaced %d from today)\n", snapshotID, replaced)
+	case syncSnapshot:
+		snapshotID, err := database.CreateSnapshot()
+		if err != nil {
+			return summary, fmt.Errorf("creating snapshot: %w", err)
+		}
+		summary.SnapshotID = snapshotID
+		fmt.Fprintf(os.Stderr, "Created snapshot #%d\n", snapshotID)
+	}
+
+	if err := failures.err(); err != nil {
+		return summary, err
+	}
+	fmt.Fprintln(os.Stderr, "Sync complete!")
+	return summary, nil
+}
+
+// countSelected returns the number of products whose transactions are synced
+func countSelected(products []client.ProductInfo) int {
+	n := 0
+	for _, p := range products {
+		if syncSelected(p) {
+			n++
+		}
+	}
+	return n
+}
+
+// productSyncError is a failure to sync one product's transactions
+type productSyncError struct {
+	ProductID   string `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Error       string `json:"error"`
+}
+
+// syncFailure is returned when syncing some of the products failed;
+// the remaining products were synced normally
+type syncFailure struct {
+	Total  int                `json:"total"`
+	Errors []productSyncError `json:"errors"`
+}
+
+func (f *syncFailure) Error() string {
+	msgs := make([]string, len(f.Errors))
+	for i, e := range f.Errors {
+		msgs[i] = fmt.Sprintf("%s: %s", e.ProductID, e.Error)
+	}
+	return fmt.Sprintf("%d of %d products failed to sync: %s", len(f.Errors), f.Total, strings.Join(msgs, "; "))
+}
+
+// err returns f if any product failed, or nil
+func (f *syncFailure) err() error {
+	if len(f.Errors) == 0 {
+		return nil
+	}
+	return f
+}
+
+// syncTemplatesLoansDeposits stores transfer templates (unless --skip-templates), loans and deposits
+// with their terms and payment schedules. Fetch failures are reported as warnings.
+func syncTemplatesLoansDeposits(database *db.DB, c *client.Client, accessToken string) error {
+	// Sync transfer templates
+	if !syncSkipTemplates {
+		if err := syncTemplates(database, c, accessToken); err != nil {
+			return err
+		}
+	}
+
+	// Sync loans and deposits
+	fmt.Fprintln(os.Stderr, "Syncing loans and deposits...")
+	loans, err := c.GetLoans(accessToken)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to fetch loans: %v\n", err)
+	} else if syncPlan != nil {
+		syncPlan.Loans = len(loans.Data.Loans)
+	} else {
+		if err := database.ReplaceLoans(fetchLoanDetails(c, accessToken, loans.Data.Loans)); err != nil {
+			return fmt.Errorf("storing loans: %w", err)
+		}
+		if syncVerbose {
+			fmt.Fprintf(os.Stderr, "  Synced %d loans\n", len(loans.Data.Loans))
+		}
+	}
+	deposits, err := c.GetDeposits(accessToken)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to fetch deposits: %v\n", err)
+	} else if syncPlan != nil {
+		syncPlan.Deposits = len(deposits.Data.Deposits)
+	} else {
+		details, schedules := fetchDepositDetails(c, accessToken, deposits.Data.Deposits)
+		if err := database.ReplaceDeposits(details, schedules); err != nil {
+			return fmt.Errorf("storing deposits: %w", err)
+		}
+		if syncVerbose {
+			fmt.Fprintf(os.Stderr, "  Synced %d deposits\n", len(deposits.Data.Deposits))
+		}
+	}
+
+	// The bank's rates are used for currency conversions
+	rates, err := c.GetExchangeRates(accessToken)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to fetch exchange rates: %v\n", err)
+	} else if syncPlan == nil {
+		n, err := storeBankRates(database, rates.Data.Rates)
+		if err != nil {
+			return fmt.Errorf("storing exchange rates: %w", err)
+		}
+		if syncVerbose {
+			fmt.Fprintf(os.Stderr, "  Synced %d exchange rates\n", n)
+		}
+	}
+	return nil
+}
+
+// syncTemplates stores transfer templates, used to name counterparties in local mode.
+// A fetch failure is reported as a warning.
+func syncTemplates(database *db.DB, c *client.Client, accessToken string) error {
+	fmt.Fprintln(os.Stderr, "Syncing transfer templates...")
+	templates, err := c.GetTemplates(accessToken)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to fetch templates: %v\n", err)
+	} else if syncPlan != nil {
+		syncPlan.Templates = len(templates.Data.Templates)
+	} else {
+		if err := database.UpsertTemplates(templates.Data.Templates); err != nil {
+			return fmt.Errorf("storing templates: %w", err)
+		}
+		if syncVerbose {
+			fmt.Fprintf(os.Stderr, "  Synced %d templates\n", len(templates.Data.Templates))
+		}
+	}
+	return nil
+}
+
+// syncUtilityPayments stores utility payments made since the start of the sync
+// window, paging until a page brings nothing new (unless --force).
+// A fetch failure is reported as a warning.
+func syncUtilityPayments(database *db.DB, c interface {
+	GetUtilityPayments(accessToken string, size, page int, since time.Time) (*client.UtilityPaymentsResponse, error)
+}, accessToken string, since time.Time) error {
+	fmt.Fprintln(os.Stderr, "Syncing utility payments...")
+	existingIDs, err := database.GetExistingUtilityPaymentIDs()
+	if err != nil {
+		return err
+	}
+
+	newPayments, inserted := 0, 0
+	for page := 0; ; page++ {
+		resp, err := c.GetUtilityPayments(accessToken, syncPageSize, page, since)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to fetch utility payments: %v\n", err)
+			return nil
+		}
+		entries := resp.Data.Entries
+
+		pageNew := 0
+		for _, p := range entries {
+			if !existingIDs[p.ID] {
+				pageNew++
+				existingIDs[p.ID] = true
 			}
 		}
+		newPayments += pageNew
 
-		// Create snapshot if requested
-		if syncSnapshot {
-			snapshotID, err := database.CreateSnapshot()
+		if syncPlan == nil && len(entries) > 0 {
+			n, err := database.UpsertUtilityPayments(entries)
 			if err != nil {
-				return fmt.Errorf("creating snapshot: %w", err)
+				return fmt.Errorf("storing utility payments: %w", err)
 			}
-			fmt.Fprintf(os.Stderr, "Created snapshot #%d\n", snapshotID)
+			inserted += n
 		}
 
-		fmt.Fprintln(os.Stderr, "Sync complete!")
-		return nil
-	},
+		if len(entries) < syncPageSize || (pageNew == 0 && !syncForce) {
+			break
+		}
+	}
+
+	if syncPlan != nil {
+		syncPlan.UtilityPayments = newPayments
+	} else if syncVerbose {
+		fmt.Fprintf(os.Stderr, "  Synced %d new utility payments\n", inserted)
+	}
+	return nil
 }
 
 func syncCard(database *db.DB, c interface {
 	GetTransactions(accessToken, cardID string) (*client.TransactionsResponse, error)
-	GetEventsPast(accessToken, accountID string, size, page int) (*client.TransactionsResponse, error)
+	GetEventsUpcoming(accessToken, cardID string) (*client.TransactionsResponse, error)
+	GetEventsPast(accessToken, accountID string, size, page int, since time.Time) (*client.TransactionsResponse, error)
 	GetTransactionDetails(accessToken, transactionID string) (*client.TransactionDetailsResponse, error)
-}, accessToken, cardID, linkedAccountID, name string) error {
+}, accessToken, cardID, linkedAccountID, name string, since time.Time) (productSyncStats, error) {
+	var stats productSyncStats
 	if syncVerbose {
 		fmt.Fprintf(os.Stderr, "Syncing card: %s (%s)\n", name, cardID)
 	}
@@ -120,7 +741,7 @@ func syncCard(database *db.DB, c interface {
 	// Get existing card transaction keys for deduplication
 	existingCardKeys, err := database.GetExistingCardTxnKeys(cardID)
 	if err != nil {
-		return fmt.Errorf("getting existing card keys: %w", err)
+		return stats, fmt.Errorf("getting existing card keys: %w", err)
 	}
 
 	// Fetch card transactions (GetTransactions)
@@ -129,7 +750,23 @@ func syncCard(database *db.DB, c interface {
 	}
 	txnResp, err := c.GetTransactions(accessToken, cardID)
 	if err != nil {
-		return fmt.Errorf("fetching card transactions: %w", err)
+		return stats, fmt.Errorf("fetching card transactions: %w", err)
+	}
+	stats.Pages++
+
+	// Pending authorizations are replaced as a whole, since settled ones turn into card transactions
+	holdsResp, err := c.GetEventsUpcoming(accessToken, cardID)
+	if err != nil {
+		return stats, fmt.Errorf("fetching holds: %w", err)
+	}
+	stats.Pages++
+	if syncPlan == nil {
+		if err := database.ReplaceCardHolds(cardID, holdsResp.Data.Entries); err != nil {
+			return stats, fmt.Errorf("storing holds: %w", err)
+		}
+		if syncVerbose {
+			fmt.Fprintf(os.Stderr, "  Card %s: %d pending holds\n", name, len(holdsResp.Data.Entries))
+		}
 	}
 
 	// Filter new transactions (by composite key: id + operation_date)
@@ -141,30 +778,44 @@ func syncCard(database *db.DB, c interface {
 		}
 	}
 
-	if len(newTxns) > 0 {
-		inserted, err := database.InsertCardTransactions(cardID, newTxns)
+	if syncPlan != nil {
+		syncPlan.addTransactions(cardID, len(newTxns))
+	} else {
+		// Stored transactions are applied too, since their state and details may have changed
+		stats.Inserted, stats.Changed, err = database.ApplyCardTransactions(cardID, txnResp.Data.Entries)
 		if err != nil {
-			return fmt.Errorf("inserting card transactions: %w", err)
+			return stats, fmt.Errorf("storing card transactions: %w", err)
+		}
+		if _, err := database.LinkCardTxnStages(cardID); err != nil {
+			return stats, fmt.Errorf("linking purchase stages: %w", err)
+		}
+		if stats.Changed > 0 {
+			fmt.Fprintf(os.Stderr, "  Card %s: +%d card transactions, %d changed\n", name, stats.Inserted, stats.Changed)
+		} else if stats.Inserted > 0 {
+			fmt.Fprintf(os.Stderr, "  Card %s: +%d card transactions\n", name, stats.Inserted)
+		} else if syncVerbose {
+			fmt.Fprintf(os.Stderr, "  Card %s: no new card transactions\n", name)
 		}
-		fmt.Fprintf(os.Stderr, "  Card %s: +%d card transactions\n", name, inserted)
-	} else if syncVerbose {
-		fmt.Fprintf(os.Stderr, "  Card %s: no new card transactions\n", name)
 	}
 
 	// Fetch linked account transactions if available (GetEventsPast)
 	if linkedAccountID != "" {
-		if err := syncCardAccountTransactions(database, c, accessToken, cardID, linkedAccountID, name); err != nil {
-			return fmt.Errorf("syncing linked account: %w", err)
+		linked, err := syncCardAccountTransactions(database, c, accessToken, cardID, linkedAccountID, name, since)
+		stats.add(linked)
+		if err != nil {
+			return stats, fmt.Errorf("syncing linked account: %w", err)
 		}
 	}
 
-	return nil
+	stats.Unchanged = syncPlan == nil && stats.Inserted == 0 && stats.Changed == 0
+	return stats, nil
 }
 
 func syncCardAccountTransactions(database *db.DB, c interface {
-	GetEventsPast(accessToken, accountID string, size, page int) (*client.TransactionsResponse, error)
+	GetEventsPast(accessToken, accountID string, size, page int, since time.Time) (*client.TransactionsResponse, error)
 	GetTransactionDetails(accessToken, transactionID string) (*client.TransactionDetailsResponse, error)
-}, accessToken, cardID, accountID, name string) error {
+}, accessToken, cardID, accountID, name string, since time.Time) (productSyncStats, error) {
+	var stats productSyncStats
 	if syncVerbose {
 		fmt.Fprintf(os.Stderr, "  Fetching linked account transactions (account %s)...\n", accountID)
 	}
@@ -172,18 +823,18 @@ func syncCardAccountTransactions(database *db.DB, c interface {
 	// Get existing linked account transaction keys for this card
 	existingKeys, err := database.GetExistingLinkedAccountTxnKeys(cardID)
 	if err != nil {
-		return fmt.Errorf("getting existing linked account keys: %w", err)
+		return stats, fmt.Errorf("getting existing linked account keys: %w", err)
 	}
 
-	totalInserted := 0
-	var allNewTxns []client.Transaction
+	var allNewTxns []client.Transaction // transactions to fetch extended info for
 	page := 0
 
 	for {
-		resp, err := c.GetEventsPast(accessToken, accountID, syncPageSize, page)
+		resp, err := c.GetEventsPast(accessToken, accountID, syncPageSize, page, since)
 		if err != nil {
-			return fmt.Errorf("fetching events/past page %d: %w", page, err)
+			return stats, fmt.Errorf("fetching events/past page %d: %w", page, err)
 		}
+		stats.Pages++
 
 		if len(resp.Data.Entries) == 0 {
 			break
@@ -201,17 +852,26 @@ func syncCardAccountTransactions(database *db.DB, c interface {
 			}
 		}
 
-		if len(newTxns) > 0 {
-			inserted, err := database.InsertLinkedAccountTransactions(cardID, newTxns)
+		if syncPlan != nil {
+			syncPlan.addTransactions(cardID, len(newTxns))
+		} else {
+			inserted, changed, err := database.ApplyLinkedAccountTransactions(cardID, resp.Data.Entries)
 			if err != nil {
-				return fmt.Errorf("inserting linked account transactions: %w", err)
+				return stats, fmt.Errorf("storing linked account transactions: %w", err)
+			}
+			stats.Inserted += inserted
+			stats.Changed += changed
+			if syncForce {
+				allNewTxns = append(allNewTxns, resp.Data.Entries...)
+			} else {
+				allNewTxns = append(allNewTxns, newTxns...)
 			}
-			totalInserted += inserted
-			allNewTxns = append(allNewTxns, newTxns...)
 		}
 
-		// Stop if: less than page size returned, OR all transactions already existed
-		if len(resp.Data.Entries) < syncPageSize || allExist {
+		// Stop if: less than page size returned, all transactions already existed
+		// (unless forced), or the page (newest first) reaches past the sync window
+		oldest, _ := db.ParseDate(resp.Data.Entries[len(resp.Data.Entries)-1].OperationDate)
+		if len(resp.Data.Entries) < syncPageSize || (allExist && !syncForce) || beforeWindow(oldest, since) {
 			break
 		}
 
@@ -221,8 +881,10 @@ func syncCardAccountTransactions(database *db.DB, c interface {
 		}
 	}
 
-	if totalInserted > 0 {
-		fmt.Fprintf(os.Stderr, "  Card %s: +%d linked account transactions\n", name, totalInserted)
+	if stats.Changed > 0 {
+		fmt.Fprintf(os.Stderr, "  Card %s: +%d linked account transactions, %d changed\n", name, stats.Inserted, stats.Changed)
+	} else if stats.Inserted > 0 {
+		fmt.Fprintf(os.Stderr, "  Card %s: +%d linked account transactions\n", name, stats.Inserted)
 	} else if syncVerbose {
 		fmt.Fprintf(os.Stderr, "  Card %s: no new linked account transactions\n", name)
 	}
@@ -233,95 +895,65 @@ func syncCardAccountTransactions(database *db.DB, c interface {
 			fmt.Fprintf(os.Stderr, "  Fetching extended info for %d new transactions...\n", len(allNewTxns))
 		}
 		if err := fetchAndStoreExtendedInfo(database, c, accessToken, cardID, allNewTxns); err != nil {
-			return fmt.Errorf("fetching extended info: %w", err)
+			return stats, fmt.Errorf("fetching extended info: %w", err)
 		}
 	}
 
-	return nil
-}
-
-// fetchAndStoreExtendedInfo fetches extended info for transactions and stores in DB
-func fetchAndStoreExtendedInfo(database *db.DB, c interface {
-	GetTransactionDetails(accessToken, transactionID string) (*client.TransactionDetailsResponse, error)
-}, accessToken, cardID string, txns []client.Transaction) error {
-	g, _ := errgroup.WithContext(context.Background())
-	g.SetLimit(5)
-	var mu sync.Mutex
-	var extInfos []struct {
-		txnID         string
-		operationDate string
-		ext           *client.TransactionExtendedInfo
-	}
-
-	for i := range txns {
-		idx := i
-		g.Go(func() error {
-			details, err := c.GetTransactionDetails(accessToken, txns[idx].ID)
-			if err != nil {
-				return fmt.Errorf("fetching extended info for %s: %w", txns[idx].ID, err)
-			}
-
-			ext := &client.TransactionExtendedInfo{
-				BeneficiaryName:     details.Data.Transaction.BeneficiaryName,
-				BeneficiaryAddress:  details.Data.Transaction.BeneficiaryAddress,
-				CreditAccountNumber: details.Data.Transaction.CreditAccountNumber,
-			}
-			if details.Data.Transaction.AdditionalInfo != nil {
-				ext.CardMaskedNumber = details.Data.Transaction.AdditionalInfo.CardMaskedNumber
-				ext.OperationID = details.Data.Transaction.AdditionalInfo.ProcessedOperationID
+	// Retry stored transactions still without it, e.g. after a failed fetch
+	// or 'db lint --fix'
+	if syncPlan == nil {
+		missing, err := database.GetTransactionsNeedingExtendedInfo(cardID)
+		if err != nil {
+			return stats, err
+		}
+		if len(missing) > 0 {
+			if syncVerbose {
+				fmt.Fprintf(os.Stderr, "  Fetching missing extended info for %d transactions...\n", len(missing))
 			}
-			if details.Data.Transaction.TransactionSwiftDetails != nil {
-				if swiftJSON, err := json.Marshal(details.Data.Transaction.TransactionSwiftDetails); err == nil {
-					ext.SwiftDetails = string(swiftJSON)
-				}
+			if err := fetchAndStoreExtendedInfo(database, c, accessToken, cardID, missing); err != nil {
+				return stats, fmt.Errorf("fetching extended info: %w", err)
 			}
-
-			mu.Lock()
-			extInfos = append(extInfos, struct {
-				txnID         string
-				operationDate string
-				ext           *client.TransactionExtendedInfo
-			}{txns[idx].ID, txns[idx].OperationDate, ext})
-			mu.Unlock()
-			return nil
-		})
+		}
 	}
 
-	if err := g.Wait(); err != nil {
+	return stats, nil
+}
+
+// fetchAndStoreExtendedInfo fetches extended info for transactions and stores in DB
+func fetchAndStoreExtendedInfo(database *db.DB, c product.DetailsClient, accessToken, cardID string, txns []client.Transaction) error {
+	if err := product.FetchExtendedInfo(c, accessToken, txns); err != nil {
 		return err
 	}
-
-	// Store all extended info in DB
-	for _, info := range extInfos {
-		if err := database.UpdateTransactionExtendedInfo(cardID, info.txnID, info.operationDate, info.ext); err != nil {
-			return fmt.Errorf("storing extended info for %s: %w", info.txnID, err)
+	for _, t := range txns {
+		if err := database.UpdateTransactionExtendedInfo(cardID, t.ID, t.OperationDate, t.Extended); err != nil {
+			return fmt.Errorf("storing extended info for %s: %w", t.ID, err)
 		}
 	}
-
 	return nil
 }
 
 func syncAccount(database *db.DB, c interface {
-	GetAccountHistory(accessToken, accountID string, size, page int) (*client.HistoryResponse, error)
-}, accessToken, accountID, name string) error {
+	GetAccountHistory(accessToken, accountID string, size, page int, since time.Time) (*client.HistoryResponse, error)
+}, accessToken, accountID, name string, since time.Time) (productSyncStats, error) {
+	var stats productSyncStats
 	if syncVerbose {
 		fmt.Fprintf(os.Stderr, "Syncing account: %s (%s)\n", name, accountID)
 	}
 
 	// Get existing transaction IDs for deduplication
-	existingIDs, err := database.GetExistingAccountTxnIDs(accountID)
+	existingKeys, err := database.GetExistingAccountTxnKeys(accountID)
 	if err != nil {
-		return fmt.Errorf("getting existing IDs: %w", err)
+		return stats, fmt.Errorf("getting existing keys: %w", err)
 	}
 
-	totalInserted := 0
 	page := 0
 
 	for {
-		resp, err := c.GetAccountHistory(accessToken, accountID, syncPageSize, page)
+		resp, err := c.GetAccountHistory(accessToken, accountID, syncPageSize, page, since)
 		if err != nil {
-			return fmt.Errorf("fetching history page %d: %w", page, err)
+			return stats, fmt.Errorf("fetching history page %d: %w", page, err)
 		}
+		stats.Pages++
 
 		if len(resp.Data.Transactions) == 0 {
 			break
@@ -331,23 +963,32 @@ func syncAccount(database *db.DB, c interface {
 		var newTxns []client.AccountTransaction
 		allExist := true
 		for _, t := range resp.Data.Transactions {
-			if !existingIDs[t.ID] {
+			key := db.AccountTxnKey(t.ID, t.TransactionDate)
+			if !existingKeys[key] {
 				newTxns = append(newTxns, t)
-				existingIDs[t.ID] = true // Mark as seen
+				existingKeys[key] = true // Mark as seen
 				allExist = false
 			}
 		}
 
-		if len(newTxns) > 0 {
-			inserted, err := database.InsertAccountTransactions(accountID, newTxns)
+		if syncPlan != nil {
+			syncPlan.addTransactions(accountID, len(newTxns))
+		} else {
+			inserted, changed, err := database.ApplyAccountTransactions(accountID, resp.Data.Transactions)
 			if err != nil {
-				return fmt.Errorf("inserting transactions: %w", err)
+				return stats, fmt.Errorf("storing transactions: %w", err)
 			}
-			totalInserted += inserted
+			stats.Inserted += inserted
+			stats.Changed += changed
 		}
 
-		// Stop if: no more pages, or all transactions already existed
-		if !resp.Data.HasNext || allExist {
+		// Stop if: no more pages, all transactions already existed (unless forced),
+		// or the page (newest first) reaches past the sync window
+		var oldest time.Time
+		if ms := resp.Data.Transactions[len(resp.Data.Transactions)-1].TransactionDate; ms != 0 {
+			oldest = time.UnixMilli(ms)
+		}
+		if !resp.Data.HasNext || (allExist && !syncForce) || beforeWindow(oldest, since) {
 			break
 		}
 
@@ -357,17 +998,32 @@ func syncAccount(database *db.DB, c interface {
 		}
 	}
 
-	if totalInserted > 0 {
-		fmt.Fprintf(os.Stderr, "  Account %s: +%d transactions\n", name, totalInserted)
+	if stats.Changed > 0 {
+		fmt.Fprintf(os.Stderr, "  Account %s: +%d transactions, %d changed\n", name, stats.Inserted, stats.Changed)
+	} else if stats.Inserted > 0 {
+		fmt.Fprintf(os.Stderr, "  Account %s: +%d transactions\n", name, stats.Inserted)
 	} else if syncVerbose {
 		fmt.Fprintf(os.Stderr, "  Account %s: no new transactions\n", name)
 	}
 
-	return nil
+	return stats, nil
 }
 
 func init() {
 	syncCmd.Flags().BoolVarP(&syncVerbose, "verbose", "v", false, "Verbose output")
-	syncCmd.Flags().BoolVarP(&syncForce, "force", "f", false, "Force re-sync all transactions")
+	syncCmd.Flags().BoolVarP(&syncForce, "force", "f", false, "Re-fetch the full history, including extended info of stored transactions")
+	syncCmd.Flags().StringVar(&syncSince, "since", "", "Only sync history from this date on (YYYY-MM-DD)")
+	syncCmd.Flags().BoolVar(&syncBackfill, "backfill", false, "Fetch the full history month by month, newest first")
 	syncCmd.Flags().BoolVarP(&syncSnapshot, "snapshot", "s", false, "Create balance snapshot after sync")
+	syncCmd.Flags().BoolVar(&syncSnapshotDaily, "snapshot-daily", false, "Create a snapshot, replacing any taken earlier today")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Print what would be written without changing the database")
+	syncCmd.Flags().BoolVarP(&syncJSONOutput, "json", "j", false, "Print a JSON summary of the sync on stdout")
+	syncCmd.Flags().BoolVar(&syncWait, "wait", false, "Wait for a running sync to finish instead of failing")
+	syncCmd.Flags().BoolVar(&syncFailFast, "fail-fast", false, "Stop at the first product that fails to sync")
+	syncCmd.Flags().BoolVar(&syncDueOnly, "due-only", false, "Skip products whose sync interval hasn't elapsed (see sync-interval)")
+	syncCmd.Flags().BoolVar(&syncAllClients, "all-clients", false, "Sync the products of all clients (personal and company), not just the current one")
+	syncCmd.Flags().BoolVar(&syncSkipTemplates, "skip-templates", false, "Don't fetch transfer templates")
+	syncCmd.Flags().IntVar(&syncPageSize, "page-size", defaultSyncPageSize, "Transactions requested per API page (overrides sync.page_size)")
+	syncCmd.Flags().Float64Var(&syncFromAmount, "from-amount", 0, "Don't fetch card events below this amount (overrides sync.from_amount)")
+	syncCmd.Flags().StringVar(&syncOnly, "only", "", "Only sync transactions of cards or accounts")
 }
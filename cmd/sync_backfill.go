@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// syncBackfill makes sync walk the history backwards month by month instead of
+// paging from the newest transactions
+var syncBackfill bool
+
+// backfillMaxEmptyMonths is the number of consecutive months without any
+// transactions after which a backfill assumes it reached the start of the history
+const backfillMaxEmptyMonths = 12
+
+// backfillWindows calls fetch for calendar months from the one containing now
+// backwards, with inclusive date bounds, until fetch returns no entries for
+// backfillMaxEmptyMonths months in a row or the window reaches lowest (unless
+// zero). Returns the number of months fetched.
+func backfillWindows(now, lowest time.Time, fetch func(from, to time.Time) (int, error)) (int, error) {
+	now = now.Local()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	if !lowest.IsZero() {
+		lowest = lowest.Local()
+		lowest = time.Date(lowest.Year(), lowest.Month(), lowest.Day(), 0, 0, 0, 0, time.Local)
+	}
+
+	months, empty := 0, 0
+	for {
+		if !lowest.IsZero() && from.Before(lowest) {
+			from = lowest
+		}
+		n, err := fetch(from, to)
+		if err != nil {
+			return months, fmt.Errorf("fetching %s..%s: %w", from.Format("2006-01-02"), to.Format("2006-01-02"), err)
+		}
+		months++
+		if n == 0 {
+			empty++
+		} else {
+			empty = 0
+		}
+		if empty >= backfillMaxEmptyMonths || (!lowest.IsZero() && !from.After(lowest)) {
+			return months, nil
+		}
+		to = from.AddDate(0, 0, -1)
+		from = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.Local)
+	}
+}
+
+// backfillCard syncs a card's settled transactions and backfills its linked account
+func backfillCard(database *db.DB, c interface {
+	GetTransactions(accessToken, cardID string) (*client.TransactionsResponse, error)
+	GetEventsUpcoming(accessToken, cardID string) (*client.TransactionsResponse, error)
+	GetEventsPast(accessToken, accountID string, size, page int, since time.Time) (*client.TransactionsResponse, error)
+	GetEventsPastRange(accessToken, accountID string, size, page int, from, to time.Time) (*client.TransactionsResponse, error)
+	GetTransactionDetails(accessToken, transactionID string) (*client.TransactionDetailsResponse, error)
+}, accessToken, cardID, linkedAccountID, name string, lowest time.Time) (productSyncStats, error) {
+	stats, err := syncCard(database, c, accessToken, cardID, "", name, lowest)
+	if err != nil || linkedAccountID == "" {
+		return stats, err
+	}
+	linked, err := backfillCardAccountTransactions(database, c, accessToken, cardID, linkedAccountID, name, lowest)
+	stats.add(linked)
+	if err != nil {
+		return stats, fmt.Errorf("backfilling linked account: %w", err)
+	}
+	return stats, nil
+}
+
+func backfillCardAccountTransactions(database *db.DB, c interface {
+	GetEventsPastRange(accessToken, accountID string, size, page int, from, to time.Time) (*client.TransactionsResponse, error)
+	GetTransactionDetails(accessToken, transactionID string) (*client.TransactionDetailsResponse, error)
+}, accessToken, cardID, accountID, name string, lowest time.Time) (productSyncStats, error) {
+	var stats productSyncStats
+	existingKeys, err := database.GetExistingLinkedAccountTxnKeys(cardID)
+	if err != nil {
+		return stats, fmt.Errorf("getting existing linked account keys: %w", err)
+	}
+
+	var allNewTxns []client.Transaction // transactions to fetch extended info for
+	months, err := backfillWindows(time.Now(), lowest, func(from, to time.Time) (int, error) {
+		if syncVerbose {
+			fmt.Fprintf(os.Stderr, "  Fetching linked account transactions %s..%s...\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
+		}
+		n := 0
+		for page := 0; ; page++ {
+			resp, err := c.GetEventsPastRange(accessToken, accountID, syncPageSize, page, from, to)
+			if err != nil {
+				return n, fmt.Errorf("fetching events/past page %d: %w", page, err)
+			}
+			stats.Pages++
+			n += len(resp.Data.Entries)
+
+			var newTxns []client.Transaction
+			for _, t := range resp.Data.Entries {
+				key := db.TxnKey(t.ID, t.OperationDate)
+				if !existingKeys[key] {
+					newTxns = append(newTxns, t)
+					existingKeys[key] = true
+				}
+			}
+
+			if syncPlan != nil {
+				syncPlan.addTransactions(cardID, len(newTxns))
+			} else if len(resp.Data.Entries) > 0 {
+				inserted, changed, err := database.ApplyLinkedAccountTransactions(cardID, resp.Data.Entries)
+				if err != nil {
+					return n, fmt.Errorf("storing linked account transactions: %w", err)
+				}
+				stats.Inserted += inserted
+				stats.Changed += changed
+				if syncForce {
+					allNewTxns = append(allNewTxns, resp.Data.Entries...)
+				} else {
+					allNewTxns = append(allNewTxns, newTxns...)
+				}
+			}
+
+			if len(resp.Data.Entries) < syncPageSize {
+				return n, nil
+			}
+		}
+	})
+	fmt.Fprintf(os.Stderr, "  Card %s: backfilled %d months, +%d linked account transactions, %d changed\n",
+		name, months, stats.Inserted, stats.Changed)
+	if err != nil {
+		return stats, err
+	}
+
+	if len(allNewTxns) > 0 {
+		if syncVerbose {
+			fmt.Fprintf(os.Stderr, "  Fetching extended info for %d new transactions...\n", len(allNewTxns))
+		}
+		if err := fetchAndStoreExtendedInfo(database, c, accessToken, cardID, allNewTxns); err != nil {
+			return stats, fmt.Errorf("fetching extended info: %w", err)
+		}
+	}
+
+	return stats, nil
+}
+
+func backfillAccount(database *db.DB, c interface {
+	GetAccountHistoryRange(accessToken, accountID string, size, page int, from, to time.Time) (*client.HistoryResponse, error)
+}, accessToken, accountID, name string, lowest time.Time) (productSyncStats, error) {
+	var stats productSyncStats
+	if syncVerbose {
+		fmt.Fprintf(os.Stderr, "Backfilling account: %s (%s)\n", name, accountID)
+	}
+
+	existingKeys, err := database.GetExistingAccountTxnKeys(accountID)
+	if err != nil {
+		return stats, fmt.Errorf("getting existing keys: %w", err)
+	}
+
+	months, err := backfillWindows(time.Now(), lowest, func(from, to time.Time) (int, error) {
+		if syncVerbose {
+			fmt.Fprintf(os.Stderr, "  Fetching history %s..%s...\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
+		}
+		n := 0
+		for page := 0; ; page++ {
+			resp, err := c.GetAccountHistoryRange(accessToken, accountID, syncPageSize, page, from, to)
+			if err != nil {
+				return n, fmt.Errorf("fetching history page %d: %w", page, err)
+			}
+			stats.Pages++
+			n += len(resp.Data.Transactions)
+
+			newTxns := 0
+			for _, t := range resp.Data.Transactions {
+				key := db.AccountTxnKey(t.ID, t.TransactionDate)
+				if !existingKeys[key] {
+					newTxns++
+					existingKeys[key] = true
+				}
+			}
+
+			if syncPlan != nil {
+				syncPlan.addTransactions(accountID, newTxns)
+			} else if len(resp.Data.Transactions) > 0 {
+				inserted, changed, err := database.ApplyAccountTransactions(accountID, resp.Data.Transactions)
+				if err != nil {
+					return n, fmt.Errorf("storing transactions: %w", err)
+				}
+				stats.Inserted += inserted
+				stats.Changed += changed
+			}
+
+			if !resp.Data.HasNext || len(resp.Data.Transactions) == 0 {
+				return n, nil
+			}
+		}
+	})
+	fmt.Fprintf(os.Stderr, "  Account %s: backfilled %d months, +%d transactions, %d changed\n",
+		name, months, stats.Inserted, stats.Changed)
+	return stats, err
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestBackfillWindows(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.Local)
+	var windows []string
+	record := func(from, to time.Time) {
+		windows = append(windows, from.Format("2006-01-02")+".."+to.Format("2006-01-02"))
+	}
+
+	// Stops once the window reaches the lower bound
+	lowest := time.Date(2024, 1, 20, 0, 0, 0, 0, time.Local)
+	months, err := backfillWindows(now, lowest, func(from, to time.Time) (int, error) {
+		record(from, to)
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatalf("backfillWindows failed: %v", err)
+	}
+	want := []string{"2024-03-01..2024-03-15", "2024-02-01..2024-02-29", "2024-01-20..2024-01-31"}
+	if months != 3 || len(windows) != 3 || windows[0] != want[0] || windows[1] != want[1] || windows[2] != want[2] {
+		t.Errorf("expected windows %v, got %v (%d months)", want, windows, months)
+	}
+
+	// Without a lower bound, stops after backfillMaxEmptyMonths empty months in a row
+	months, err = backfillWindows(now, time.Time{}, func(from, to time.Time) (int, error) {
+		if from.Year() == 2023 && from.Month() == time.June {
+			return 5, nil
+		}
+		return 0, nil
+	})
+	if err != nil {
+		t.Fatalf("backfillWindows failed: %v", err)
+	}
+	// March 2024 back to June 2023 is 10 months, then 12 empty ones
+	if months != 10+backfillMaxEmptyMonths {
+		t.Errorf("expected %d months, got %d", 10+backfillMaxEmptyMonths, months)
+	}
+
+	if _, err := backfillWindows(now, time.Time{}, func(from, to time.Time) (int, error) {
+		return 0, errors.New("boom")
+	}); err == nil {
+		t.Error("expected fetch error to be returned")
+	}
+}
+
+// mockRangeClient serves account history by month of the requested window
+type mockRangeClient struct {
+	history map[string][]client.AccountTransaction // YYYY-MM -> transactions
+	windows int
+}
+
+func (m *mockRangeClient) GetAccountHistoryRange(accessToken, accountID string, size, page int, from, to time.Time) (*client.HistoryResponse, error) {
+	if page == 0 {
+		m.windows++
+	}
+	return makeHistoryResponse(false, m.history[from.Format("2006-01")]...), nil
+}
+
+func TestBackfillAccount(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	month := func(monthsAgo int) time.Time {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month()-time.Month(monthsAgo), 1, 12, 0, 0, 0, time.Local)
+	}
+	mockClient := &mockRangeClient{history: map[string][]client.AccountTransaction{
+		month(0).Format("2006-01"):  {{ID: "txn1", TransactionDate: month(0).UnixMilli()}},
+		month(5).Format("2006-01"):  {{ID: "txn2", TransactionDate: month(5).UnixMilli()}},
+		month(30).Format("2006-01"): {{ID: "txn3", TransactionDate: month(30).UnixMilli()}},
+	}}
+
+	syncVerbose = false
+	stats, err := backfillAccount(database, mockClient, "token", "acc1", "Test Account", time.Time{})
+	if err != nil {
+		t.Fatalf("backfillAccount failed: %v", err)
+	}
+
+	// txn3 lies beyond a year-long gap, so it's past the assumed start of the history
+	if stats.Inserted != 2 {
+		t.Errorf("expected 2 inserted transactions, got %d", stats.Inserted)
+	}
+	if mockClient.windows != 6+backfillMaxEmptyMonths {
+		t.Errorf("expected %d monthly windows, got %d", 6+backfillMaxEmptyMonths, mockClient.windows)
+	}
+	txns, err := database.GetAccountTransactions("acc1", false)
+	if err != nil {
+		t.Fatalf("GetAccountTransactions failed: %v", err)
+	}
+	if len(txns) != 2 {
+		t.Errorf("expected 2 stored transactions, got %d", len(txns))
+	}
+}
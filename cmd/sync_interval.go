@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/output"
+	"github.com/ivan4th/ameriagrab/product"
+	"github.com/spf13/cobra"
+)
+
+var syncIntervalJSONOutput bool
+
+var syncIntervalCmd = &cobra.Command{
+	Use:   "sync-interval",
+	Short: "Configure how often daemon mode syncs each product",
+	Long: `Sync intervals let scheduled syncs skip products that change rarely, e.g. a
+salary card every 2h and a dormant USD account daily, to save API calls. They are
+stored in the database.
+
+Daemon runs and 'sync --due-only' skip a product until its interval has elapsed
+since it was last synced; products without an interval are synced on every run.
+Intervals are Go durations (30m, 2h) or whole days (1d, 7d).`,
+}
+
+var syncIntervalSetCmd = &cobra.Command{
+	Use:   "set <product-id|name> <interval>",
+	Short: "Set a product's sync interval",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		interval, err := parseSyncInterval(args[1])
+		if err != nil {
+			return err
+		}
+
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		p, err := product.Resolve(database, args[0])
+		if err != nil {
+			return err
+		}
+		if err := database.SetSyncInterval(p.ID, interval); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s is synced every %s in daemon mode\n", p.Name, output.FormatInterval(interval))
+		return nil
+	},
+}
+
+var syncIntervalUnsetCmd = &cobra.Command{
+	Use:   "unset <product-id|name>",
+	Short: "Sync a product on every daemon run again",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		p, err := product.Resolve(database, args[0])
+		if err != nil {
+			return err
+		}
+		if err := database.SetSyncInterval(p.ID, 0); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s is synced on every daemon run\n", p.Name)
+		return nil
+	},
+}
+
+var syncIntervalListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List products with their sync intervals and when they were last synced",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		products, err := database.GetProducts()
+		if err != nil {
+			return fmt.Errorf("fetching products from database: %w", err)
+		}
+		states, err := database.GetProductSyncStates()
+		if err != nil {
+			return err
+		}
+
+		var synced []output.ProductSync
+		for _, p := range products {
+			if !product.IsGoal(p) {
+				synced = append(synced, output.ProductSync{Product: p, State: states[p.ID]})
+			}
+		}
+
+		if syncIntervalJSONOutput {
+			type productInterval struct {
+				ProductID       string     `json:"product_id"`
+				Name            string     `json:"name"`
+				IntervalSeconds int64      `json:"interval_seconds,omitempty"` // 0 = every run
+				LastSyncedAt    *time.Time `json:"last_synced_at,omitempty"`
+			}
+			list := []productInterval{}
+			for _, ps := range synced {
+				pi := productInterval{
+					ProductID:       ps.Product.ID,
+					Name:            ps.Product.Name,
+					IntervalSeconds: int64(ps.State.Interval / time.Second),
+				}
+				if !ps.State.LastSyncedAt.IsZero() {
+					pi.LastSyncedAt = &ps.State.LastSyncedAt
+				}
+				list = append(list, pi)
+			}
+			out, err := json.MarshalIndent(list, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling sync intervals: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+		if len(synced) == 0 {
+			fmt.Println("No products found. Run sync first.")
+			return nil
+		}
+		display.PrintSyncIntervals(synced, time.Now())
+		return nil
+	},
+}
+
+// parseSyncInterval parses a sync interval: a Go duration or a number of days
+// with a "d" suffix, at least a minute
+func parseSyncInterval(s string) (time.Duration, error) {
+	var interval time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid sync interval %q", s)
+		}
+		interval = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if interval, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid sync interval %q", s)
+		}
+	}
+	if interval < time.Minute {
+		return 0, fmt.Errorf("sync interval must be at least 1m, got %q", s)
+	}
+	return interval, nil
+}
+
+func init() {
+	syncIntervalListCmd.Flags().BoolVarP(&syncIntervalJSONOutput, "json", "j", false, "Output as JSON")
+	syncIntervalCmd.AddCommand(syncIntervalSetCmd)
+	syncIntervalCmd.AddCommand(syncIntervalUnsetCmd)
+	syncIntervalCmd.AddCommand(syncIntervalListCmd)
+}
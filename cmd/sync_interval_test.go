@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSyncInterval(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want time.Duration
+	}{
+		{"2h", 2 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"1d", 24 * time.Hour},
+		{"7d", 7 * 24 * time.Hour},
+	} {
+		if got, err := parseSyncInterval(tt.s); err != nil || got != tt.want {
+			t.Errorf("parseSyncInterval(%q) = %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+	for _, s := range []string{"", "daily", "xd", "30s", "0d"} {
+		if _, err := parseSyncInterval(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestProductWindowStart(t *testing.T) {
+	since := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+	if got := productWindowStart(since, time.Time{}); !got.Equal(since) {
+		t.Errorf("expected the sync window for a product never synced, got %v", got)
+	}
+	if got := productWindowStart(since, since.Add(7*24*time.Hour)); !got.Equal(since) {
+		t.Errorf("expected the sync window for a product synced by the last run, got %v", got)
+	}
+	// A product skipped by recent runs is synced from a week before its own last sync
+	lastSynced := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	if got := productWindowStart(since, lastSynced); !got.Equal(lastSynced.Add(-syncOverlap)) {
+		t.Errorf("expected the window to cover the last sync, got %v", got)
+	}
+	if got := productWindowStart(time.Time{}, lastSynced); !got.IsZero() {
+		t.Errorf("expected full history to stay full, got %v", got)
+	}
+
+	syncSince = "2024-06-10"
+	defer func() { syncSince = "" }()
+	if got := productWindowStart(since, lastSynced); !got.Equal(since) {
+		t.Errorf("expected --since to be kept, got %v", got)
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// syncWait makes a sync wait for a running one to finish instead of failing
+var syncWait bool
+
+const (
+	// syncLockStaleAfter is how long a lock is honored after it was last
+	// refreshed if its holder can't be checked
+	syncLockStaleAfter = 6 * time.Hour
+	// syncLockPollInterval is how often a waiting sync retries the lock
+	syncLockPollInterval = 5 * time.Second
+)
+
+// acquireSyncLock takes the database's sync lock, waiting for it with --wait.
+// Locks of processes that are gone from this host, or not refreshed (see
+// refreshSyncLock) within syncLockStaleAfter, are taken over. Waiting stops when ctx is done. The
+// returned function releases the lock.
+func acquireSyncLock(ctx context.Context, database *db.DB) (func(), error) {
+	host, _ := os.Hostname()
+	pid := os.Getpid()
+	waiting := false
+	for {
+		staleBefore := time.Now().Add(-syncLockStaleAfter)
+		holder, err := database.TryAcquireSyncLock(host, pid, staleBefore)
+		if err != nil {
+			return nil, err
+		}
+		if holder != nil && holder.Host == host && !processAlive(holder.PID) {
+			fmt.Fprintf(os.Stderr, "Taking over sync lock of exited process %d\n", holder.PID)
+			if err := database.ReleaseSyncLock(holder.Host, holder.PID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if holder == nil {
+			return func() {
+				if err := database.ReleaseSyncLock(host, pid); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+			}, nil
+		}
+
+		if !syncWait {
+			return nil, fmt.Errorf("another sync is running (pid %d on %s since %s, last active %s); use --wait to wait for it",
+				holder.PID, holder.Host, holder.AcquiredAt.Format("2006-01-02 15:04:05"), holder.ActiveAt.Format("2006-01-02 15:04:05"))
+		}
+		if !waiting {
+			fmt.Fprintf(os.Stderr, "Waiting for the sync of pid %d on %s to finish...\n", holder.PID, holder.Host)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for the sync lock: %w", interruption(ctx))
+		case <-time.After(syncLockPollInterval):
+		}
+	}
+}
+
+// refreshSyncLock marks the sync lock held by this process as still in use,
+// so that a sync running longer than syncLockStaleAfter isn't taken over
+func refreshSyncLock(database *db.DB) {
+	host, _ := os.Hostname()
+	if err := database.RefreshSyncLock(host, os.Getpid()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// processAlive reports whether a process with the given pid exists on this host
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestAcquireSyncLock(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+	host, _ := os.Hostname()
+
+	// Held by a live process elsewhere: fail without --wait
+	if holder, err := database.TryAcquireSyncLock("other-host", 1, time.Now().Add(-time.Hour)); err != nil || holder != nil {
+		t.Fatalf("failed to seed lock: holder %+v, err %v", holder, err)
+	}
+	syncWait = false
+	if _, err := acquireSyncLock(context.Background(), database); err == nil || !strings.Contains(err.Error(), "another sync is running") {
+		t.Fatalf("expected lock conflict, got %v", err)
+	}
+
+	// Waiting stops when the command is interrupted
+	syncWait = true
+	defer func() { syncWait = false }()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := acquireSyncLock(ctx, database); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected waiting to be interrupted, got %v", err)
+	}
+	syncWait = false
+
+	if err := database.ReleaseSyncLock("other-host", 1); err != nil {
+		t.Fatalf("ReleaseSyncLock failed: %v", err)
+	}
+
+	// Held by an exited process on this host: taken over
+	if _, err := database.TryAcquireSyncLock(host, 1<<30, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("failed to seed lock: %v", err)
+	}
+	release, err := acquireSyncLock(context.Background(), database)
+	if err != nil {
+		t.Fatalf("expected to take over the lock of an exited process, got %v", err)
+	}
+	release()
+
+	if holder, err := database.TryAcquireSyncLock("other-host", 1, time.Now().Add(-time.Hour)); err != nil || holder != nil {
+		t.Errorf("expected lock to be released, got holder %+v (err %v)", holder, err)
+	}
+}
+
+func TestProcessAlive(t *testing.T) {
+	if !processAlive(os.Getpid()) {
+		t.Error("expected the current process to be alive")
+	}
+	if processAlive(1 << 30) {
+		t.Error("expected a nonexistent pid not to be alive")
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/output"
+)
+
+// syncPlan collects what a dry-run sync would write; nil unless --dry-run is given
+var syncPlan *dryRunPlan
+
+// Product changes in a dry-run plan
+const (
+	planProductNew       = "new"
+	planProductUpdated   = "updated"
+	planProductUnchanged = "unchanged"
+)
+
+// dryRunPlanProduct is the planned change of one product and its transactions
+type dryRunPlanProduct struct {
+	ID              string
+	Name            string
+	ProductType     string
+	Change          string
+	NewTransactions int
+}
+
+// dryRunPlan is the list of changes a sync would make
+type dryRunPlan struct {
+	Products        []*dryRunPlanProduct
+	Templates       int // -1 if they couldn't be fetched
+	Loans           int
+	Deposits        int
+	UtilityPayments int // new ones only
+}
+
+func newDryRunPlan() *dryRunPlan {
+	return &dryRunPlan{Templates: -1, Loans: -1, Deposits: -1, UtilityPayments: -1}
+}
+
+// addProducts compares fetched products with the stored ones
+func (p *dryRunPlan) addProducts(database *db.DB, products []client.ProductInfo) error {
+	for _, fetched := range products {
+		stored, err := database.GetProductByID(fetched.ID)
+		if err != nil {
+			return err
+		}
+		change := planProductUnchanged
+		switch {
+		case stored == nil:
+			change = planProductNew
+		case *stored != fetched:
+			change = planProductUpdated
+		}
+		p.Products = append(p.Products, &dryRunPlanProduct{
+			ID:          fetched.ID,
+			Name:        fetched.Name,
+			ProductType: fetched.ProductType,
+			Change:      change,
+		})
+	}
+	return nil
+}
+
+// addTransactions records n new transactions for a product
+func (p *dryRunPlan) addTransactions(productID string, n int) {
+	for _, pp := range p.Products {
+		if pp.ID == productID {
+			pp.NewTransactions += n
+			return
+		}
+	}
+}
+
+// Print writes the plan as a table
+func (p *dryRunPlan) Print(w io.Writer) {
+	var table strings.Builder
+	fmt.Fprintln(&table, "PRODUCT\tTYPE\tCHANGE\tNEW TRANSACTIONS")
+	for _, pp := range p.Products {
+		fmt.Fprintf(&table, "%s\t%s\t%s\t%d\n", pp.Name, pp.ProductType, pp.Change, pp.NewTransactions)
+	}
+	output.WriteColumns(w, table.String())
+
+	count := func(n int) string {
+		if n < 0 {
+			return "not fetched"
+		}
+		return fmt.Sprintf("%d", n)
+	}
+	fmt.Fprintf(w, "\nTemplates to store: %s\n", count(p.Templates))
+	fmt.Fprintf(w, "Loans to replace: %s\n", count(p.Loans))
+	fmt.Fprintf(w, "Deposits to replace: %s\n", count(p.Deposits))
+	fmt.Fprintf(w, "New utility payments: %s\n", count(p.UtilityPayments))
+}
@@ -0,0 +1,48 @@
+package cmd
+
+// productSyncStats describes the transaction sync of one product
+type productSyncStats struct {
+	ProductID   string  `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	ProductType string  `json:"product_type"`
+	Inserted    int     `json:"inserted"`
+	Changed     int     `json:"changed"`             // stored transactions whose fields changed
+	Pages       int     `json:"pages"`               // API pages fetched
+	Unchanged   bool    `json:"unchanged,omitempty"` // card with nothing new or changed
+	Duration    float64 `json:"duration_seconds"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// add accumulates the counts of another sync of the same product
+func (s *productSyncStats) add(o productSyncStats) {
+	s.Inserted += o.Inserted
+	s.Changed += o.Changed
+	s.Pages += o.Pages
+}
+
+// syncSummary is the outcome of a sync, printed by sync --json
+type syncSummary struct {
+	Products   []productSyncStats `json:"products"`
+	Inserted   int                `json:"inserted"`
+	Changed    int                `json:"changed"`
+	Pages      int                `json:"pages"`
+	Failed     int                `json:"failed"`
+	Duration   float64            `json:"duration_seconds"`
+	SnapshotID int64              `json:"snapshot_id,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}
+
+func newSyncSummary() *syncSummary {
+	return &syncSummary{Products: []productSyncStats{}}
+}
+
+// addProduct records the stats of one product and updates the totals
+func (s *syncSummary) addProduct(stats productSyncStats) {
+	s.Products = append(s.Products, stats)
+	s.Inserted += stats.Inserted
+	s.Changed += stats.Changed
+	s.Pages += stats.Pages
+	if stats.Error != "" {
+		s.Failed++
+	}
+}
@@ -1,10 +1,21 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
 	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/notify"
 )
 
 // mockCardClient implements the interface used by syncCard
@@ -15,13 +26,21 @@ type mockCardClient struct {
 	eventsPastErr   error
 	details         map[string]*client.TransactionDetailsResponse // txnID -> response
 	detailsErr      error
+	holds           *client.TransactionsResponse
 }
 
 func (m *mockCardClient) GetTransactions(accessToken, cardID string) (*client.TransactionsResponse, error) {
 	return m.transactions, m.transactionsErr
 }
 
-func (m *mockCardClient) GetEventsPast(accessToken, accountID string, size, page int) (*client.TransactionsResponse, error) {
+func (m *mockCardClient) GetEventsUpcoming(accessToken, cardID string) (*client.TransactionsResponse, error) {
+	if m.holds != nil {
+		return m.holds, nil
+	}
+	return &client.TransactionsResponse{Status: "success"}, nil
+}
+
+func (m *mockCardClient) GetEventsPast(accessToken, accountID string, size, page int, since time.Time) (*client.TransactionsResponse, error) {
 	if m.eventsPastErr != nil {
 		return nil, m.eventsPastErr
 	}
@@ -45,9 +64,11 @@ func (m *mockCardClient) GetTransactionDetails(accessToken, transactionID string
 type mockAccountClient struct {
 	history    map[int]*client.HistoryResponse // page -> response
 	historyErr error
+	pages      []int // requested pages
 }
 
-func (m *mockAccountClient) GetAccountHistory(accessToken, accountID string, size, page int) (*client.HistoryResponse, error) {
+func (m *mockAccountClient) GetAccountHistory(accessToken, accountID string, size, page int, since time.Time) (*client.HistoryResponse, error) {
+	m.pages = append(m.pages, page)
 	if m.historyErr != nil {
 		return nil, m.historyErr
 	}
@@ -102,7 +123,7 @@ func TestSyncCard_NewTransactions(t *testing.T) {
 
 	// Run sync
 	syncVerbose = false
-	if err := syncCard(database, mockClient, "token", "card1", "acc1", "Test Card"); err != nil {
+	if _, err := syncCard(database, mockClient, "token", "card1", "acc1", "Test Card", time.Time{}); err != nil {
 		t.Fatalf("syncCard failed: %v", err)
 	}
 
@@ -146,12 +167,12 @@ func TestSyncCard_Deduplication(t *testing.T) {
 
 	// First sync
 	syncVerbose = false
-	if err := syncCard(database, mockClient, "token", "card1", "acc1", "Test Card"); err != nil {
+	if _, err := syncCard(database, mockClient, "token", "card1", "acc1", "Test Card", time.Time{}); err != nil {
 		t.Fatalf("first syncCard failed: %v", err)
 	}
 
 	// Second sync with same transactions (should not duplicate)
-	if err := syncCard(database, mockClient, "token", "card1", "acc1", "Test Card"); err != nil {
+	if _, err := syncCard(database, mockClient, "token", "card1", "acc1", "Test Card", time.Time{}); err != nil {
 		t.Fatalf("second syncCard failed: %v", err)
 	}
 
@@ -195,7 +216,7 @@ func TestSyncCard_IncrementalSync(t *testing.T) {
 	}
 
 	syncVerbose = false
-	if err := syncCard(database, mockClient, "token", "card1", "acc1", "Test Card"); err != nil {
+	if _, err := syncCard(database, mockClient, "token", "card1", "acc1", "Test Card", time.Time{}); err != nil {
 		t.Fatalf("first syncCard failed: %v", err)
 	}
 
@@ -206,7 +227,7 @@ func TestSyncCard_IncrementalSync(t *testing.T) {
 		client.Transaction{ID: "txn3", OperationDate: "2024-01-03T10:00:00Z", Details: "Purchase 3"},
 	)
 
-	if err := syncCard(database, mockClient, "token", "card1", "acc1", "Test Card"); err != nil {
+	if _, err := syncCard(database, mockClient, "token", "card1", "acc1", "Test Card", time.Time{}); err != nil {
 		t.Fatalf("second syncCard failed: %v", err)
 	}
 
@@ -220,6 +241,61 @@ func TestSyncCard_IncrementalSync(t *testing.T) {
 	}
 }
 
+func TestSyncCard_AppliesUnchangedCount(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	mockClient := &mockCardClient{
+		transactions: makeTransactionsResponse(
+			client.Transaction{ID: "txn1", OperationDate: "2024-01-01T10:00:00Z", State: "PENDING", Details: "Purchase 1"},
+			client.Transaction{ID: "txn2", OperationDate: "2024-01-02T10:00:00Z", State: "DONE", Details: "Purchase 2"},
+		),
+		holds: makeTransactionsResponse(client.Transaction{ID: "hold1", Details: "Pending"}),
+	}
+
+	syncVerbose = false
+	stats, err := syncCard(database, mockClient, "token", "card1", "acc1", "Test Card", time.Time{})
+	if err != nil {
+		t.Fatalf("first syncCard failed: %v", err)
+	}
+	if stats.Unchanged {
+		t.Error("expected the first sync not to be unchanged")
+	}
+
+	// A transaction settles without changing the count or newest date, and the
+	// linked account has a new transaction: both are stored
+	mockClient.transactions.Data.Entries[0].State = "DONE"
+	mockClient.eventsPast = map[int]*client.TransactionsResponse{
+		0: makeTransactionsResponse(client.Transaction{ID: "ev1", OperationDate: "2024-01-03T10:00:00Z"}),
+	}
+	mockClient.holds = makeTransactionsResponse()
+	stats, err = syncCard(database, mockClient, "token", "card1", "acc1", "Test Card", time.Time{})
+	if err != nil {
+		t.Fatalf("second syncCard failed: %v", err)
+	}
+	if stats.Unchanged || stats.Changed != 1 || stats.Inserted != 1 {
+		t.Errorf("expected 1 changed and 1 inserted transaction, got %+v", stats)
+	}
+	if count, _ := database.CountLinkedAccountTransactions("card1"); count != 1 {
+		t.Errorf("expected the linked account to be synced, got %d transactions", count)
+	}
+	if holds, _ := database.GetCardHolds("card1"); len(holds) != 0 {
+		t.Errorf("expected holds to be refreshed, got %+v", holds)
+	}
+
+	// Nothing new or changed
+	stats, err = syncCard(database, mockClient, "token", "card1", "acc1", "Test Card", time.Time{})
+	if err != nil {
+		t.Fatalf("third syncCard failed: %v", err)
+	}
+	if !stats.Unchanged {
+		t.Errorf("expected the card to be reported unchanged, got %+v", stats)
+	}
+}
+
 func TestSyncAccount_NewTransactions(t *testing.T) {
 	database, err := db.OpenInMemory()
 	if err != nil {
@@ -247,7 +323,7 @@ func TestSyncAccount_NewTransactions(t *testing.T) {
 	}
 
 	syncVerbose = false
-	if err := syncAccount(database, mockClient, "token", "acc1", "Test Account"); err != nil {
+	if _, err := syncAccount(database, mockClient, "token", "acc1", "Test Account", time.Time{}); err != nil {
 		t.Fatalf("syncAccount failed: %v", err)
 	}
 
@@ -291,9 +367,13 @@ func TestSyncAccount_Pagination(t *testing.T) {
 	}
 
 	syncVerbose = false
-	if err := syncAccount(database, mockClient, "token", "acc1", "Test Account"); err != nil {
+	stats, err := syncAccount(database, mockClient, "token", "acc1", "Test Account", time.Time{})
+	if err != nil {
 		t.Fatalf("syncAccount failed: %v", err)
 	}
+	if stats.Pages != 2 || stats.Inserted != 2 {
+		t.Errorf("expected 2 pages and 2 inserted transactions, got %+v", stats)
+	}
 
 	// Should have transactions from both pages
 	txns, err := database.GetAccountTransactions("acc1", false)
@@ -305,6 +385,66 @@ func TestSyncAccount_Pagination(t *testing.T) {
 	}
 }
 
+func TestSyncAccount_SinceWindow(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	ms := func(m time.Month, d int) int64 { return time.Date(2024, m, d, 12, 0, 0, 0, time.Local).UnixMilli() }
+	mockClient := &mockAccountClient{
+		history: map[int]*client.HistoryResponse{
+			0: makeHistoryResponse(true, client.AccountTransaction{ID: "txn1", TransactionDate: ms(3, 1)}),
+			1: makeHistoryResponse(true, client.AccountTransaction{ID: "txn2", TransactionDate: ms(1, 10)}),
+			2: makeHistoryResponse(false, client.AccountTransaction{ID: "txn3", TransactionDate: ms(1, 5)}),
+		},
+	}
+
+	syncVerbose = false
+	since := time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)
+	if _, err := syncAccount(database, mockClient, "token", "acc1", "Test Account", since); err != nil {
+		t.Fatalf("syncAccount failed: %v", err)
+	}
+
+	// Page 1 reaches past the window, so page 2 is never requested
+	if len(mockClient.pages) != 2 {
+		t.Errorf("expected pages 0 and 1 to be fetched, got %v", mockClient.pages)
+	}
+}
+
+func TestSyncWindowStart(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	syncSince, syncForce = "", false
+	defer func() { syncSince, syncForce = "", false }()
+
+	if since, err := syncWindowStart(database); err != nil || !since.IsZero() {
+		t.Errorf("expected full history before the first sync, got %v (err %v)", since, err)
+	}
+
+	id, _ := database.StartSyncRun("manual")
+	database.FinishSyncRun(id, db.SyncRunSuccess, "")
+	last, _ := database.LastSuccessfulSync()
+	if since, err := syncWindowStart(database); err != nil || !since.Equal(last.Add(-syncOverlap)) {
+		t.Errorf("expected window from the last successful sync, got %v (err %v)", since, err)
+	}
+
+	syncForce = true
+	if since, err := syncWindowStart(database); err != nil || !since.IsZero() {
+		t.Errorf("expected full history with --force, got %v (err %v)", since, err)
+	}
+
+	syncSince = "2024-01-15"
+	if since, err := syncWindowStart(database); err != nil || since.Format("2006-01-02") != "2024-01-15" {
+		t.Errorf("expected --since date, got %v (err %v)", since, err)
+	}
+}
+
 func TestSyncAccount_Deduplication(t *testing.T) {
 	database, err := db.OpenInMemory()
 	if err != nil {
@@ -333,12 +473,12 @@ func TestSyncAccount_Deduplication(t *testing.T) {
 	syncVerbose = false
 
 	// First sync
-	if err := syncAccount(database, mockClient, "token", "acc1", "Test Account"); err != nil {
+	if _, err := syncAccount(database, mockClient, "token", "acc1", "Test Account", time.Time{}); err != nil {
 		t.Fatalf("first syncAccount failed: %v", err)
 	}
 
 	// Second sync (same data)
-	if err := syncAccount(database, mockClient, "token", "acc1", "Test Account"); err != nil {
+	if _, err := syncAccount(database, mockClient, "token", "acc1", "Test Account", time.Time{}); err != nil {
 		t.Fatalf("second syncAccount failed: %v", err)
 	}
 
@@ -387,7 +527,7 @@ func TestSyncLinkedAccountTransactions_WithExtendedInfo(t *testing.T) {
 	}
 
 	syncVerbose = false
-	if err := syncCardAccountTransactions(database, mockClient, "token", "card1", "acc1", "Test Card"); err != nil {
+	if _, err := syncCardAccountTransactions(database, mockClient, "token", "card1", "acc1", "Test Card", time.Time{}); err != nil {
 		t.Fatalf("syncCardAccountTransactions failed: %v", err)
 	}
 
@@ -414,3 +554,639 @@ func TestSyncLinkedAccountTransactions_WithExtendedInfo(t *testing.T) {
 		t.Errorf("expected beneficiary 'John Doe', got %q", txns[0].Extended.BeneficiaryName)
 	}
 }
+
+func TestSyncLinkedAccountTransactions_RetriesMissingExtendedInfo(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	// Stored by an earlier sync whose extended info fetch failed
+	txn := client.Transaction{ID: "txn1", OperationDate: "2024-01-01T10:00:00Z", Details: "Transfer to card"}
+	if _, err := database.InsertLinkedAccountTransactions("card1", []client.Transaction{txn}); err != nil {
+		t.Fatalf("InsertLinkedAccountTransactions failed: %v", err)
+	}
+
+	detailsResp := &client.TransactionDetailsResponse{Status: "success"}
+	detailsResp.Data.Transaction.BeneficiaryName = "John Doe"
+	mockClient := &mockCardClient{
+		eventsPast: map[int]*client.TransactionsResponse{0: makeTransactionsResponse(txn)},
+		details:    map[string]*client.TransactionDetailsResponse{"txn1": detailsResp},
+	}
+
+	syncVerbose = false
+	stats, err := syncCardAccountTransactions(database, mockClient, "token", "card1", "acc1", "Test Card", time.Time{})
+	if err != nil {
+		t.Fatalf("syncCardAccountTransactions failed: %v", err)
+	}
+	if stats.Inserted != 0 {
+		t.Errorf("expected nothing new, got %d", stats.Inserted)
+	}
+	if missing, err := database.GetTransactionsNeedingExtendedInfo("card1"); err != nil || len(missing) != 0 {
+		t.Errorf("expected no transactions left without extended info, got %v, %v", missing, err)
+	}
+	txns, err := database.GetLinkedAccountTransactions("card1", 0, 0, true, false)
+	if err != nil {
+		t.Fatalf("GetLinkedAccountTransactions failed: %v", err)
+	}
+	if len(txns) != 1 || txns[0].Extended == nil || txns[0].Extended.BeneficiaryName != "John Doe" {
+		t.Errorf("expected the extended info to be fetched again, got %+v", txns)
+	}
+}
+
+func TestSyncSelection(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	products := []client.ProductInfo{
+		{ID: "card1", ProductType: "CARD", Name: "Visa Gold", Currency: "AMD"},
+		{ID: "acc1", ProductType: "ACCOUNT", Name: "Current", Currency: "USD"},
+	}
+	if err := database.UpsertProducts(products); err != nil {
+		t.Fatalf("UpsertProducts failed: %v", err)
+	}
+	defer func() { syncOnly, syncProductIDs = "", nil }()
+
+	if syncPartial() || !syncSelected(products[0]) || !syncSelected(products[1]) {
+		t.Error("expected all products to be selected by default")
+	}
+	// Goals have no transactions to sync
+	if syncSelected(client.ProductInfo{ID: "goal1", ProductType: "GOAL"}) {
+		t.Error("expected goals not to be selected")
+	}
+
+	syncOnly = "cards"
+	if !syncPartial() || !syncSelected(products[0]) || syncSelected(products[1]) {
+		t.Error("expected only cards to be selected with --only cards")
+	}
+	syncOnly = "accounts"
+	if syncSelected(products[0]) || !syncSelected(products[1]) {
+		t.Error("expected only accounts to be selected with --only accounts")
+	}
+	syncOnly = ""
+
+	syncProductIDs, err = resolveSyncProducts(database, []string{"visa gold"})
+	if err != nil {
+		t.Fatalf("resolveSyncProducts failed: %v", err)
+	}
+	if !syncPartial() || !syncSelected(products[0]) || syncSelected(products[1]) {
+		t.Errorf("expected only card1 to be selected, got %v", syncProductIDs)
+	}
+
+	if _, err := resolveSyncProducts(database, []string{"missing"}); err == nil {
+		t.Error("expected error for unknown product")
+	}
+}
+
+func TestSyncAccount_DryRun(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	products := []client.ProductInfo{
+		{ID: "acc1", ProductType: "ACCOUNT", Name: "Test Account", Balance: 100},
+		{ID: "acc2", ProductType: "ACCOUNT", Name: "Savings"},
+	}
+	if err := database.UpsertProducts(products[:1]); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+
+	syncPlan = newDryRunPlan()
+	defer func() { syncPlan = nil }()
+
+	fetched := []client.ProductInfo{products[0], products[1]}
+	fetched[0].Balance = 150
+	if err := syncPlan.addProducts(database, fetched); err != nil {
+		t.Fatalf("addProducts failed: %v", err)
+	}
+
+	mockClient := &mockAccountClient{
+		history: map[int]*client.HistoryResponse{
+			0: makeHistoryResponse(true, client.AccountTransaction{ID: "txn1"}),
+			1: makeHistoryResponse(false, client.AccountTransaction{ID: "txn2"}),
+		},
+	}
+	syncVerbose = false
+	if _, err := syncAccount(database, mockClient, "token", "acc1", "Test Account", time.Time{}); err != nil {
+		t.Fatalf("syncAccount failed: %v", err)
+	}
+
+	txns, err := database.GetAccountTransactions("acc1", false)
+	if err != nil {
+		t.Fatalf("GetAccountTransactions failed: %v", err)
+	}
+	if len(txns) != 0 {
+		t.Errorf("expected no transactions to be written in dry run, got %d", len(txns))
+	}
+
+	if got := syncPlan.Products[0]; got.Change != planProductUpdated || got.NewTransactions != 2 {
+		t.Errorf("unexpected plan for acc1: %+v", got)
+	}
+	if got := syncPlan.Products[1]; got.Change != planProductNew || got.NewTransactions != 0 {
+		t.Errorf("unexpected plan for acc2: %+v", got)
+	}
+
+	var buf bytes.Buffer
+	syncPlan.Print(&buf)
+	if !strings.Contains(buf.String(), "Test Account") || !strings.Contains(buf.String(), "Templates to store: not fetched") {
+		t.Errorf("unexpected plan output:\n%s", buf.String())
+	}
+}
+
+func TestSync_DryRunLeavesDatabaseUnchanged(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/accounts-and-cards":
+			fmt.Fprint(w, `{"status":"SUCCESS","data":{"accountsAndCards":[{"id":"acc1","productType":"ACCOUNT","name":"Test Account","currency":"AMD","balance":150}]}}`)
+		case "/api/history":
+			fmt.Fprint(w, `{"status":"SUCCESS","data":{"transactions":[{"id":"txn1","amount":{"amount":10,"currency":"AMD"}}],"hasNext":false,"isUpToDate":true}}`)
+		default:
+			fmt.Fprint(w, `{"status":"SUCCESS","data":{}}`)
+		}
+	}))
+	defer server.Close()
+
+	dbPath := filepath.Join(t.TempDir(), "ameria.db")
+	database, err := db.Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := database.UpsertProducts([]client.ProductInfo{{ID: "acc1", ProductType: "ACCOUNT", Name: "Test Account", Balance: 100}}); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+	database.Close()
+	before, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatalf("failed to read database: %v", err)
+	}
+
+	database, err = db.Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	c, err := client.NewClient("user", "pass", nil, "")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	c.APIBaseURL = server.URL
+	c.ProductsCache = database
+	syncPlan = newDryRunPlan()
+	defer func() { syncPlan = nil }()
+	syncVerbose = false
+	if _, err := doSync(context.Background(), database, c, "token"); err != nil {
+		t.Fatalf("doSync failed: %v", err)
+	}
+	database.Close()
+
+	if got := syncPlan.Products; len(got) != 1 || got[0].Change != planProductUpdated || got[0].NewTransactions != 1 {
+		t.Errorf("unexpected plan: %+v", got)
+	}
+	after, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatalf("failed to read database: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("dry run changed the database file")
+	}
+}
+
+func TestSync_SkipTemplatesKeepsStoredTemplates(t *testing.T) {
+	templatesRequested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/accounts-and-cards":
+			fmt.Fprint(w, `{"status":"SUCCESS","data":{"accountsAndCards":[{"id":"acc1","productType":"ACCOUNT","name":"Test Account","currency":"AMD","balance":150}]}}`)
+		case "/api/templates":
+			templatesRequested = true
+			fmt.Fprint(w, `{"status":"SUCCESS","data":{"templates":[]}}`)
+		default:
+			fmt.Fprint(w, `{"status":"SUCCESS","data":{}}`)
+		}
+	}))
+	defer server.Close()
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "ameria.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+	var template client.TransferTemplate
+	template.ID, template.Name = "tpl1", "Rent"
+	template.Data.CreditTarget.Number, template.Data.CreditTarget.Type = "1570000000000100", "ACCOUNT"
+	if err := database.UpsertTemplates([]client.TransferTemplate{template}); err != nil {
+		t.Fatalf("failed to store templates: %v", err)
+	}
+
+	c, err := client.NewClient("user", "pass", nil, "")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	c.APIBaseURL = server.URL
+	syncSkipTemplates = true
+	defer func() { syncSkipTemplates = false }()
+	syncVerbose = false
+	if _, err := doSync(context.Background(), database, c, "token"); err != nil {
+		t.Fatalf("doSync failed: %v", err)
+	}
+
+	if templatesRequested {
+		t.Error("expected templates not to be fetched with --skip-templates")
+	}
+	if name, err := database.GetTemplateByAccount("1570000000000100"); err != nil || name != "Rent" {
+		t.Errorf("expected the stored template to be kept, got %q (err %v)", name, err)
+	}
+}
+
+func TestSync_BalanceFailureDoesntStopOtherProducts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/accounts-and-cards":
+			fmt.Fprint(w, `{"status":"SUCCESS","data":{"accountsAndCards":[`+
+				`{"id":"acc1","productType":"ACCOUNT","name":"Broken","currency":"AMD"},`+
+				`{"id":"acc2","productType":"ACCOUNT","name":"Savings","currency":"AMD"}]}}`)
+		case "/api/accounts-and-cards/available-balance":
+			if r.URL.Query().Get("productId") == "acc1" {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `{"status":"SUCCESS","data":{"availableBalance":100}}`)
+		case "/api/history":
+			id := "txn-" + r.URL.Query().Get("accountIds")
+			fmt.Fprintf(w, `{"status":"SUCCESS","data":{"transactions":[{"id":%q,"amount":{"amount":10,"currency":"AMD"}}],"hasNext":false,"isUpToDate":true}}`, id)
+		default:
+			fmt.Fprint(w, `{"status":"SUCCESS","data":{}}`)
+		}
+	}))
+	defer server.Close()
+
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+	if err := database.UpsertProducts([]client.ProductInfo{{ID: "acc1", ProductType: "ACCOUNT", Name: "Broken", AvailableBalance: 50}}); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+	c, err := client.NewClient("user", "pass", nil, "")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	c.APIBaseURL = server.URL
+	syncVerbose = false
+
+	summary, err := doSync(context.Background(), database, c, "token")
+	var failure *syncFailure
+	if !errors.As(err, &failure) {
+		t.Fatalf("expected a sync failure, got %v", err)
+	}
+	if failure.Total != 2 || len(failure.Errors) != 1 || failure.Errors[0].ProductID != "acc1" {
+		t.Errorf("unexpected failure: %+v", failure)
+	}
+	if summary.Failed != 1 || len(summary.Products) != 2 || summary.Products[0].Error == "" || summary.Products[1].Error != "" {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+
+	// Both products' transactions are stored, the failed one keeps its balance
+	for _, id := range []string{"acc1", "acc2"} {
+		txns, err := database.GetAccountTransactions(id, false)
+		if err != nil {
+			t.Fatalf("GetAccountTransactions failed: %v", err)
+		}
+		if len(txns) != 1 {
+			t.Errorf("expected 1 transaction for %s, got %d", id, len(txns))
+		}
+	}
+	for id, want := range map[string]float64{"acc1": 50, "acc2": 100} {
+		p, err := database.GetProductByID(id)
+		if err != nil || p == nil {
+			t.Fatalf("GetProductByID(%s) failed: %v", id, err)
+		}
+		if p.AvailableBalance != want {
+			t.Errorf("expected available balance %v for %s, got %v", want, id, p.AvailableBalance)
+		}
+	}
+}
+
+func TestSyncAccount_Force(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if _, err := database.InsertAccountTransactions("acc1", []client.AccountTransaction{
+		{ID: "txn1", Status: "PENDING"},
+		{ID: "txn2", Status: "PENDING"},
+	}); err != nil {
+		t.Fatalf("InsertAccountTransactions failed: %v", err)
+	}
+
+	mockClient := &mockAccountClient{
+		history: map[int]*client.HistoryResponse{
+			0: makeHistoryResponse(true, client.AccountTransaction{ID: "txn1", Status: "COMPLETED"}),
+			1: makeHistoryResponse(false, client.AccountTransaction{ID: "txn2", Status: "COMPLETED"}),
+		},
+	}
+
+	syncVerbose = false
+	syncForce = true
+	defer func() { syncForce = false }()
+	if _, err := syncAccount(database, mockClient, "token", "acc1", "Test Account", time.Time{}); err != nil {
+		t.Fatalf("syncAccount failed: %v", err)
+	}
+
+	// Already stored pages don't stop paging when forced
+	if len(mockClient.pages) != 2 {
+		t.Errorf("expected both pages to be fetched, got %v", mockClient.pages)
+	}
+	txns, err := database.GetAccountTransactions("acc1", false)
+	if err != nil {
+		t.Fatalf("GetAccountTransactions failed: %v", err)
+	}
+	for _, txn := range txns {
+		if txn.Status != "COMPLETED" {
+			t.Errorf("expected %s to be refreshed, got status %q", txn.ID, txn.Status)
+		}
+	}
+}
+
+func TestSyncFailure(t *testing.T) {
+	var f syncFailure
+	f.Total = 3
+	if f.err() != nil {
+		t.Error("expected no error without failed products")
+	}
+
+	f.Errors = append(f.Errors, productSyncError{ProductID: "card1", ProductName: "Visa", Error: "timeout"})
+	err := f.err()
+	if err == nil {
+		t.Fatal("expected error with a failed product")
+	}
+	if got, want := err.Error(), "1 of 3 products failed to sync: card1: timeout"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	var failure *syncFailure
+	if !errors.As(fmt.Errorf("sync: %w", err), &failure) || len(failure.Errors) != 1 {
+		t.Error("expected wrapped error to unwrap to syncFailure")
+	}
+}
+
+func TestSyncSummary(t *testing.T) {
+	summary := newSyncSummary()
+	summary.addProduct(productSyncStats{ProductID: "card1", Inserted: 3, Pages: 2})
+	summary.addProduct(productSyncStats{ProductID: "acc1", Changed: 1, Pages: 1, Error: "timeout"})
+
+	if summary.Inserted != 3 || summary.Changed != 1 || summary.Pages != 3 || summary.Failed != 1 {
+		t.Errorf("unexpected totals: %+v", summary)
+	}
+	if len(summary.Products) != 2 {
+		t.Errorf("expected 2 products, got %d", len(summary.Products))
+	}
+}
+
+func TestSyncAccount_AppliesChanges(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if _, err := database.InsertAccountTransactions("acc1", []client.AccountTransaction{{ID: "txn1", Status: "PENDING"}}); err != nil {
+		t.Fatalf("InsertAccountTransactions failed: %v", err)
+	}
+
+	mockClient := &mockAccountClient{
+		history: map[int]*client.HistoryResponse{
+			0: makeHistoryResponse(false,
+				client.AccountTransaction{ID: "txn2", Status: "PENDING"},
+				client.AccountTransaction{ID: "txn1", Status: "COMPLETED"},
+			),
+		},
+	}
+	syncVerbose = false
+	stats, err := syncAccount(database, mockClient, "token", "acc1", "Test Account", time.Time{})
+	if err != nil {
+		t.Fatalf("syncAccount failed: %v", err)
+	}
+	if stats.Inserted != 1 || stats.Changed != 1 {
+		t.Errorf("expected 1 inserted and 1 changed transaction, got %+v", stats)
+	}
+
+	changes, err := database.GetTransactionChanges("acc1", "txn1", 0)
+	if err != nil {
+		t.Fatalf("GetTransactionChanges failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Field != "status" || changes[0].NewValue != "COMPLETED" {
+		t.Errorf("expected recorded status change, got %+v", changes)
+	}
+}
+
+// recordingSink collects the notifications sent to it
+type recordingSink struct {
+	messages []notify.Message
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(ctx context.Context, msg notify.Message) error {
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func TestAnnounceNewTransactions_SkipsChanged(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if _, err := database.InsertAccountTransactions("acc1", []client.AccountTransaction{{ID: "txn1", Status: "PENDING", BeneficiaryName: "Old Shop"}}); err != nil {
+		t.Fatalf("InsertAccountTransactions failed: %v", err)
+	}
+	if _, err := database.Exec("UPDATE account_transactions SET synced_at = ?", time.Now().Add(-time.Hour).Unix()); err != nil {
+		t.Fatalf("failed to backdate transaction: %v", err)
+	}
+
+	startedAt := time.Unix(time.Now().Unix(), 0)
+	mockClient := &mockAccountClient{
+		history: map[int]*client.HistoryResponse{
+			0: makeHistoryResponse(false,
+				client.AccountTransaction{ID: "txn2", Status: "PENDING", BeneficiaryName: "New Shop"},
+				client.AccountTransaction{ID: "txn1", Status: "COMPLETED", BeneficiaryName: "Old Shop"},
+			),
+		},
+	}
+	syncVerbose = false
+	if _, err := syncAccount(database, mockClient, "token", "acc1", "Test Account", time.Time{}); err != nil {
+		t.Fatalf("syncAccount failed: %v", err)
+	}
+
+	// The settled transaction isn't announced as new
+	sink := &recordingSink{}
+	announceNewTransactions(database, startedAt, &notify.Notifier{Sinks: []notify.Sink{sink}})
+	if len(sink.messages) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(sink.messages))
+	}
+	msg := sink.messages[0]
+	if msg.Title != "ameriagrab: 1 new transactions" || !strings.Contains(msg.Body, "New Shop") || strings.Contains(msg.Body, "Old Shop") {
+		t.Errorf("unexpected notification: %+v", msg)
+	}
+}
+
+// mockLoansClient serves loan and deposit details by ID
+type mockLoansClient struct {
+	loans     map[string]client.LoanDetails
+	deposits  map[string]client.DepositDetails
+	schedules map[string][]client.InterestPayment
+}
+
+func (m *mockLoansClient) GetLoanDetails(accessToken, loanID string) (*client.LoanDetailsResponse, error) {
+	l, ok := m.loans[loanID]
+	if !ok {
+		return nil, fmt.Errorf("loan details request failed with status 500")
+	}
+	resp := &client.LoanDetailsResponse{Status: "SUCCESS"}
+	resp.Data.Loan = l
+	return resp, nil
+}
+
+func (m *mockLoansClient) GetDepositDetails(accessToken, depositID string) (*client.DepositDetailsResponse, error) {
+	d, ok := m.deposits[depositID]
+	if !ok {
+		return nil, fmt.Errorf("deposit details request failed with status 500")
+	}
+	resp := &client.DepositDetailsResponse{Status: "SUCCESS"}
+	resp.Data.Deposit = d
+	return resp, nil
+}
+
+func (m *mockLoansClient) GetDepositSchedule(accessToken, depositID string) (*client.DepositScheduleResponse, error) {
+	payments, ok := m.schedules[depositID]
+	if !ok {
+		return nil, fmt.Errorf("deposit schedule request failed with status 500")
+	}
+	resp := &client.DepositScheduleResponse{Status: "SUCCESS"}
+	resp.Data.Payments = payments
+	return resp, nil
+}
+
+func TestFetchLoanAndDepositDetails(t *testing.T) {
+	schedule := []client.LoanInstallment{{Date: "2024-02-01", Principal: 90, Interest: 10, Total: 100}}
+	payments := []client.InterestPayment{{Date: "2024-02-01", Amount: 30, Tax: 3}}
+	mock := &mockLoansClient{
+		loans: map[string]client.LoanDetails{
+			"L1": {LoanInfo: client.LoanInfo{ID: "L1", OutstandingAmount: 1}, OpenDate: "2020-01-01", Schedule: schedule},
+		},
+		deposits: map[string]client.DepositDetails{
+			"D1": {DepositInfo: client.DepositInfo{ID: "D1", Amount: 1}, TermMonths: 12},
+		},
+		schedules: map[string][]client.InterestPayment{"D1": payments},
+	}
+
+	// Balances come from the list, a loan without details is kept
+	loans := fetchLoanDetails(mock, "token", []client.LoanInfo{{ID: "L1", OutstandingAmount: 500}, {ID: "L2"}})
+	if len(loans) != 2 || loans[0].OutstandingAmount != 500 || loans[0].OpenDate != "2020-01-01" || len(loans[0].Schedule) != 1 {
+		t.Errorf("unexpected loan details: %+v", loans)
+	}
+	if loans[1].ID != "L2" || loans[1].Schedule != nil {
+		t.Errorf("unexpected details of a failed loan: %+v", loans[1])
+	}
+
+	deposits, schedules := fetchDepositDetails(mock, "token", []client.DepositInfo{{ID: "D1", Amount: 5000}, {ID: "D2"}})
+	if len(deposits) != 2 || deposits[0].Amount != 5000 || deposits[0].TermMonths != 12 || deposits[1].ID != "D2" {
+		t.Errorf("unexpected deposit details: %+v", deposits)
+	}
+	if len(schedules) != 1 || len(schedules["D1"]) != 1 {
+		t.Errorf("unexpected deposit schedules: %+v", schedules)
+	}
+}
+
+// mockUtilityClient serves utility payment history pages
+type mockUtilityClient struct {
+	pages     map[int][]client.UtilityPayment
+	requested int
+}
+
+func (m *mockUtilityClient) GetUtilityPayments(accessToken string, size, page int, since time.Time) (*client.UtilityPaymentsResponse, error) {
+	m.requested++
+	resp := &client.UtilityPaymentsResponse{Status: "SUCCESS"}
+	resp.Data.Entries = m.pages[page]
+	return resp, nil
+}
+
+func TestSyncUtilityPayments(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	oldPageSize := syncPageSize
+	syncPageSize = 2
+	defer func() { syncPageSize = oldPageSize }()
+	syncVerbose = false
+
+	payment := func(id string) client.UtilityPayment {
+		return client.UtilityPayment{ID: id, ServiceType: client.UtilityGas, Provider: "Gazprom Armenia",
+			Amount: client.Amount{Currency: "AMD", Amount: 5000}, PaymentDate: "2024-01-10"}
+	}
+	mockClient := &mockUtilityClient{pages: map[int][]client.UtilityPayment{
+		0: {payment("u4"), payment("u3")},
+		1: {payment("u2"), payment("u1")},
+		2: {payment("u0")},
+	}}
+	if err := syncUtilityPayments(database, mockClient, "token", time.Time{}); err != nil {
+		t.Fatalf("syncUtilityPayments failed: %v", err)
+	}
+	if mockClient.requested != 3 {
+		t.Errorf("expected 3 pages, got %d", mockClient.requested)
+	}
+	stored, _ := database.GetUtilityPayments("", "")
+	if len(stored) != 5 {
+		t.Errorf("expected 5 stored payments, got %d", len(stored))
+	}
+
+	// The next sync stops at the first page without new payments
+	mockClient.pages[0] = []client.UtilityPayment{payment("u5"), payment("u4")}
+	mockClient.requested = 0
+	if err := syncUtilityPayments(database, mockClient, "token", time.Time{}); err != nil {
+		t.Fatalf("syncUtilityPayments failed: %v", err)
+	}
+	if mockClient.requested != 2 {
+		t.Errorf("expected 2 pages, got %d", mockClient.requested)
+	}
+	stored, _ = database.GetUtilityPayments("", "")
+	if len(stored) != 6 {
+		t.Errorf("expected 6 stored payments, got %d", len(stored))
+	}
+}
+
+func TestSyncFailureMessage(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+	authErr := &exitError{ExitAuth, errors.New("refresh token expired")}
+
+	msg := syncFailureMessage(3, now.Add(-75*time.Hour), authErr, now)
+	if msg.Title != "ameriagrab: sync has been failing for 3 days — session probably expired" {
+		t.Errorf("unexpected title %q", msg.Title)
+	}
+	if msg.Priority != notify.PriorityHigh {
+		t.Errorf("expected a high-priority message, got %v", msg.Priority)
+	}
+	if !strings.Contains(msg.Body, "3 sync runs in a row") || !strings.Contains(msg.Body, "refresh token expired") {
+		t.Errorf("unexpected body %q", msg.Body)
+	}
+
+	if msg := syncFailureMessage(4, now.Add(-5*time.Hour), errors.New("boom"), now); msg.Title != "ameriagrab: sync has been failing for 5 hours" {
+		t.Errorf("unexpected title %q", msg.Title)
+	}
+	if msg := syncFailureMessage(3, now.Add(-10*time.Minute), errors.New("boom"), now); msg.Title != "ameriagrab: sync has been failing for 3 runs" {
+		t.Errorf("unexpected title %q", msg.Title)
+	}
+}
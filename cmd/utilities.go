@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	utilitiesType       string
+	utilitiesProvider   string
+	utilitiesJSONOutput bool
+)
+
+var utilitiesCmd = &cobra.Command{
+	Use:   "utilities",
+	Short: "List utility payments (electricity, gas, phone, ...) stored by sync",
+	Long: `Lists utility payments from the local database, newest first, with totals
+per service and provider. Full syncs fetch them from the bank's utility payment
+history, which names the provider and subscriber number unlike the generic
+account history.
+
+--type filters by service (electricity, gas, water, phone, internet) and
+--provider by provider name.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		payments, err := database.GetUtilityPayments(strings.ToUpper(utilitiesType), utilitiesProvider)
+		if err != nil {
+			return fmt.Errorf("fetching utility payments: %w", err)
+		}
+
+		if utilitiesJSONOutput {
+			out, err := json.MarshalIndent(payments, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling utility payments: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+		if len(payments) == 0 {
+			fmt.Println("No utility payments found.")
+			return nil
+		}
+		display.PrintUtilityPayments(payments)
+		return nil
+	},
+}
+
+func init() {
+	utilitiesCmd.Flags().StringVar(&utilitiesType, "type", "", "Service type (electricity, gas, water, phone, internet)")
+	utilitiesCmd.Flags().StringVar(&utilitiesProvider, "provider", "", "Provider name")
+	utilitiesCmd.Flags().BoolVarP(&utilitiesJSONOutput, "json", "j", false, "Output as JSON")
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/web"
+	"github.com/spf13/cobra"
+)
+
+var webListen string
+
+var webCmd = &cobra.Command{
+	Use:   "web",
+	Short: "Serve a local web dashboard",
+	Long: `Serves a small dashboard with balances, recent transactions and a monthly
+income/expense chart from the local database.
+
+The dashboard has no authentication and listens on localhost by default.
+Run 'sync' (or 'daemon') to keep the data fresh.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := OpenDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		server := &http.Server{
+			Addr:              webListen,
+			Handler:           web.NewHandler(database),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		return serveUntilSignal(cmd.Context(), server)
+	},
+}
+
+// serveUntilSignal runs the HTTP server until SIGINT/SIGTERM or until ctx is
+// done, then shuts it down gracefully
+func serveUntilSignal(ctx context.Context, server *http.Server) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		fmt.Fprintf(os.Stderr, "Listening on http://%s\n", server.Addr)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("serving: %w", err)
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(os.Stderr, "Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("shutting down: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	webCmd.Flags().StringVar(&webListen, "listen", "127.0.0.1:8080", "Address to listen on")
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/webhook"
+)
+
+// deliverWebhook posts transactions not yet delivered to the configured webhook.
+// On first use the webhook cursor is set to the current position, so existing
+// history isn't sent. Failures are reported as warnings; undelivered
+// transactions stay queued until the next sync.
+func deliverWebhook(database *db.DB) {
+	cfg := LoadConfig().Webhook
+	if url := os.Getenv("AMERIA_WEBHOOK_URL"); url != "" {
+		cfg.URL = url
+	}
+	if secret := os.Getenv("AMERIA_WEBHOOK_SECRET"); secret != "" {
+		cfg.Secret = secret
+	}
+	if cfg.URL == "" {
+		return
+	}
+
+	positions, err := database.GetCursor(webhook.CursorName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: webhook: %v\n", err)
+		return
+	}
+	if len(positions) == 0 {
+		if err := webhook.Skip(database); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: webhook: %v\n", err)
+			return
+		}
+		fmt.Fprintln(os.Stderr, "Webhook enabled, transactions synced from now on will be delivered")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	delivered, err := webhook.NewSender(cfg.URL, cfg.Secret).Deliver(ctx, database)
+	if delivered > 0 {
+		fmt.Fprintf(os.Stderr, "Delivered %d transactions to webhook\n", delivered)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (will retry after next sync)\n", err)
+	}
+}
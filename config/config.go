@@ -0,0 +1,171 @@
+// Package config manages the ameriagrab YAML configuration file
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds user preferences. Environment variables take precedence
+// over values from the config file where both exist.
+type Config struct {
+	DefaultProduct string       `yaml:"default_product,omitempty"`
+	BaseCurrency   string       `yaml:"base_currency,omitempty"`
+	Locale         string       `yaml:"locale,omitempty"`
+	Icons          bool         `yaml:"icons,omitempty"`
+	Numbers        string       `yaml:"numbers,omitempty"` // masked, last4 or hidden
+	PageSize       int          `yaml:"page_size,omitempty"`
+	Sync           SyncConfig   `yaml:"sync,omitempty"`
+	Match          MatchConfig  `yaml:"match,omitempty"`
+	Notify         NotifyConfig `yaml:"notify,omitempty"`
+	Webhook        HookConfig   `yaml:"webhook,omitempty"`
+	MQTT           MQTTConfig   `yaml:"mqtt,omitempty"`
+	Backup         BackupConfig `yaml:"backup,omitempty"`
+	Bot            BotConfig    `yaml:"bot,omitempty"`
+	Cache          CacheConfig  `yaml:"cache,omitempty"`
+	// Aliases maps lowercase product aliases to the product IDs, names or
+	// numbers they stand for (alias.<name> keys)
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+}
+
+// SyncConfig holds settings of the sync command
+type SyncConfig struct {
+	PageSize   int     `yaml:"page_size,omitempty"`   // transactions requested per API page
+	FromAmount float64 `yaml:"from_amount,omitempty"` // smallest card event amount fetched
+	PostHook   string  `yaml:"post_hook,omitempty"`   // shell command run after each sync
+}
+
+// MatchConfig holds the tolerances used to match card transactions to
+// linked account transactions
+type MatchConfig struct {
+	TimeTolerance string  `yaml:"time_tolerance,omitempty"` // Go duration, e.g. 2m
+	FXTolerance   float64 `yaml:"fx_tolerance,omitempty"`   // percent
+}
+
+// NotifyConfig holds notification sink settings
+type NotifyConfig struct {
+	Telegram TelegramConfig `yaml:"telegram,omitempty"`
+	SMTP     SMTPConfig     `yaml:"smtp,omitempty"`
+	Webhook  WebhookConfig  `yaml:"webhook,omitempty"`
+	Desktop  bool           `yaml:"desktop,omitempty"`
+	// SyncFailures is the number of consecutive failed syncs that triggers a
+	// warning: 0 for DefaultSyncFailures, negative to disable
+	SyncFailures int `yaml:"sync_failures,omitempty"`
+}
+
+// DefaultSyncFailures is the default of notify.sync_failures
+const DefaultSyncFailures = 3
+
+// SyncFailureThreshold returns the number of consecutive failed syncs that
+// triggers a warning, or 0 if disabled
+func (c NotifyConfig) SyncFailureThreshold() int {
+	switch {
+	case c.SyncFailures == 0:
+		return DefaultSyncFailures
+	case c.SyncFailures < 0:
+		return 0
+	}
+	return c.SyncFailures
+}
+
+// TelegramConfig holds Telegram bot settings
+type TelegramConfig struct {
+	Token  string `yaml:"token,omitempty"`
+	ChatID string `yaml:"chat_id,omitempty"`
+}
+
+// SMTPConfig holds email settings
+type SMTPConfig struct {
+	Addr     string `yaml:"addr,omitempty"` // host:port
+	User     string `yaml:"user,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	From     string `yaml:"from,omitempty"`
+	To       string `yaml:"to,omitempty"` // comma-separated
+}
+
+// WebhookConfig holds JSON webhook settings
+type WebhookConfig struct {
+	URL string `yaml:"url,omitempty"`
+}
+
+// HookConfig holds settings of the webhook receiving new transactions after each sync
+type HookConfig struct {
+	URL    string `yaml:"url,omitempty"`
+	Secret string `yaml:"secret,omitempty"` // HMAC-SHA256 signing key
+}
+
+// MQTTConfig holds the MQTT broker used for Home Assistant integration
+type MQTTConfig struct {
+	Broker          string `yaml:"broker,omitempty"` // tcp://host:1883 or ssl://host:8883
+	Username        string `yaml:"username,omitempty"`
+	Password        string `yaml:"password,omitempty"`
+	TopicPrefix     string `yaml:"topic_prefix,omitempty"`
+	DiscoveryPrefix string `yaml:"discovery_prefix,omitempty"`
+}
+
+// BackupConfig holds remote backup settings
+type BackupConfig struct {
+	Remote string `yaml:"remote,omitempty"` // s3://bucket/path or webdav://host/path
+}
+
+// BotConfig holds interactive Telegram bot settings. The bot token is notify.telegram.token.
+type BotConfig struct {
+	AllowedChats string `yaml:"allowed_chats,omitempty"` // comma-separated chat IDs
+}
+
+// CacheConfig holds settings of data reused between runs
+type CacheConfig struct {
+	// ProductsTTL is how long accounts and cards fetched from the API are
+	// reused by later runs (Go duration; empty: not reused)
+	ProductsTTL string `yaml:"products_ttl,omitempty"`
+}
+
+// Path returns the config file path: AMERIA_CONFIG if set,
+// otherwise ameriagrab/config.yaml in the user config directory
+func Path() (string, error) {
+	if path := os.Getenv("AMERIA_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine config directory: %w", err)
+	}
+	return filepath.Join(dir, "ameriagrab", "config.yaml"), nil
+}
+
+// Load reads the config file. A missing file yields an empty config.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Save writes the config file, creating its directory if needed.
+// The file may contain credentials, so it is only readable by the owner.
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
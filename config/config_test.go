@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetAndGet(t *testing.T) {
+	cfg := &Config{}
+
+	valid := map[string]string{
+		"default_product":         "Salary card",
+		"base_currency":           "usd",
+		"locale":                  "hy_AM",
+		"icons":                   "true",
+		"numbers":                 "last4",
+		"page_size":               "200",
+		"sync.page_size":          "500",
+		"sync.from_amount":        "0.1",
+		"match.time_tolerance":    "5m",
+		"match.fx_tolerance":      "2.5",
+		"notify.telegram.chat_id": "-100123",
+		"notify.smtp.addr":        "smtp.example.com:587",
+		"notify.smtp.to":          "a@example.com, b@example.com",
+		"notify.webhook.url":      "https://example.com/hook",
+		"notify.desktop":          "true",
+		"webhook.url":             "http://localhost:5678/webhook/bank",
+		"mqtt.broker":             "tcp://homeassistant.local:1883",
+		"mqtt.topic_prefix":       "bank/ameria",
+		"backup.remote":           "s3://my-bucket/ameriagrab",
+		"bot.allowed_chats":       "12345, -100200300",
+	}
+	for key, value := range valid {
+		if err := cfg.Set(key, value); err != nil {
+			t.Errorf("Set(%s, %q) failed: %v", key, value, err)
+		}
+	}
+
+	if got, _ := cfg.Get("base_currency"); got != "USD" {
+		t.Errorf("expected base currency to be normalized to USD, got %q", got)
+	}
+	if cfg.PageSize != 200 || cfg.Sync.PageSize != 500 || cfg.Sync.FromAmount != 0.1 || !cfg.Notify.Desktop {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	invalid := map[string]string{
+		"base_currency":           "dollars",
+		"locale":                  "English",
+		"icons":                   "sometimes",
+		"numbers":                 "blurred",
+		"page_size":               "5000",
+		"sync.page_size":          "0",
+		"sync.from_amount":        "-1",
+		"match.time_tolerance":    "5",
+		"match.fx_tolerance":      "150",
+		"notify.telegram.chat_id": "my chat",
+		"notify.smtp.addr":        "smtp.example.com",
+		"notify.smtp.to":          "nobody",
+		"notify.webhook.url":      "ftp://example.com",
+		"notify.desktop":          "maybe",
+		"webhook.url":             "localhost:5678",
+		"mqtt.broker":             "http://homeassistant.local",
+		"mqtt.discovery_prefix":   "homeassistant/#",
+		"backup.remote":           "/mnt/backups",
+		"bot.allowed_chats":       "12345,@channel",
+		"no_such_key":             "x",
+	}
+	for key, value := range invalid {
+		if err := cfg.Set(key, value); err == nil {
+			t.Errorf("Set(%s, %q) should fail", key, value)
+		}
+	}
+
+	if err := cfg.Set("page_size", ""); err != nil || cfg.PageSize != 0 {
+		t.Errorf("expected page_size to be unset, got %d (err %v)", cfg.PageSize, err)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	cfg := &Config{}
+	for alias, target := range map[string]string{"alias.Salary": "card-001", "alias.usd-savings": "1234"} {
+		if err := cfg.Set(alias, target); err != nil {
+			t.Fatalf("Set(%s) failed: %v", alias, err)
+		}
+	}
+	if got, _ := cfg.Get("alias.salary"); got != "card-001" || cfg.Aliases["salary"] != "card-001" {
+		t.Errorf("expected the alias to be stored lowercase, got %q (%v)", got, cfg.Aliases)
+	}
+	if err := cfg.Set("alias.my card", "card-002"); err == nil {
+		t.Error("expected an invalid alias to be refused")
+	}
+
+	keys := cfg.ListKeys()
+	if len(keys) != len(Keys)+2 || keys[len(Keys)].Name != "alias.salary" || keys[len(Keys)+1].Name != "alias.usd-savings" {
+		t.Errorf("expected the aliases after the keys, got %d keys", len(keys))
+	}
+
+	if err := cfg.Set("alias.salary", ""); err != nil || len(cfg.Aliases) != 1 {
+		t.Errorf("expected the alias to be unset, got %v (err %v)", cfg.Aliases, err)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "config.yaml")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load of missing file failed: %v", err)
+	}
+	cfg.BaseCurrency = "EUR"
+	cfg.Notify.SMTP.Password = "secret"
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %o", info.Mode().Perm())
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.BaseCurrency != "EUR" || loaded.Notify.SMTP.Password != "secret" {
+		t.Errorf("unexpected loaded config: %+v", loaded)
+	}
+
+	if err := os.WriteFile(path, []byte("page_size: [1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected parse error")
+	}
+}
@@ -0,0 +1,559 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key describes a settable config key
+type Key struct {
+	Name        string
+	Description string
+	Secret      bool // masked in listings
+	get         func(c *Config) string
+	set         func(c *Config, value string) error
+}
+
+var (
+	currencyRe = regexp.MustCompile(`^[A-Z]{3}$`)
+	localeRe   = regexp.MustCompile(`^[a-z]{2}(_[A-Z]{2})?$`)
+	chatIDRe   = regexp.MustCompile(`^(-?[0-9]+|@[A-Za-z0-9_]{5,})$`)
+	aliasRe    = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+// AliasPrefix starts the keys of product aliases, e.g. alias.salary
+const AliasPrefix = "alias."
+
+// MaxPageSize is the largest page size accepted by the API
+const MaxPageSize = 1000
+
+// Keys lists all config keys in display order
+var Keys = []Key{
+	{
+		Name:        "default_product",
+		Description: "Product ID or name used when 'get' is called without one",
+		get:         func(c *Config) string { return c.DefaultProduct },
+		set:         func(c *Config, v string) error { c.DefaultProduct = v; return nil },
+	},
+	{
+		Name:        "base_currency",
+		Description: "Currency amounts are converted to (e.g. AMD, USD)",
+		get:         func(c *Config) string { return c.BaseCurrency },
+		set: func(c *Config, v string) error {
+			v = strings.ToUpper(v)
+			if v != "" && !currencyRe.MatchString(v) {
+				return fmt.Errorf("invalid currency code %q (expected 3 letters, e.g. USD)", v)
+			}
+			c.BaseCurrency = v
+			return nil
+		},
+	},
+	{
+		Name:        "locale",
+		Description: "Locale for numbers (e.g. en: 150,000.00, hy: 150 000,00) and the language of table headers and labels (en, ru or hy)",
+		get:         func(c *Config) string { return c.Locale },
+		set: func(c *Config, v string) error {
+			if v != "" && !localeRe.MatchString(v) {
+				return fmt.Errorf("invalid locale %q (expected e.g. en or en_US)", v)
+			}
+			c.Locale = v
+			return nil
+		},
+	},
+	{
+		Name:        "icons",
+		Description: "Prefix transactions in tables with a category icon (true/false)",
+		get: func(c *Config) string {
+			if !c.Icons {
+				return ""
+			}
+			return "true"
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.Icons = false
+				return nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid boolean %q (expected true or false)", v)
+			}
+			c.Icons = b
+			return nil
+		},
+	},
+	{
+		Name:        "numbers",
+		Description: "How card and account numbers are shown: masked (as the bank sends them), last4 or hidden",
+		get:         func(c *Config) string { return c.Numbers },
+		set: func(c *Config, v string) error {
+			switch v {
+			case "", "masked", "last4", "hidden":
+				c.Numbers = v
+				return nil
+			}
+			return fmt.Errorf("invalid number display %q (expected masked, last4 or hidden)", v)
+		},
+	},
+	{
+		Name:        "page_size",
+		Description: fmt.Sprintf("Default number of transactions shown by 'get' (1-%d)", MaxPageSize),
+		get: func(c *Config) string {
+			if c.PageSize == 0 {
+				return ""
+			}
+			return strconv.Itoa(c.PageSize)
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.PageSize = 0
+				return nil
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > MaxPageSize {
+				return fmt.Errorf("invalid page size %q (expected 1-%d)", v, MaxPageSize)
+			}
+			c.PageSize = n
+			return nil
+		},
+	},
+	{
+		Name:        "sync.page_size",
+		Description: fmt.Sprintf("Number of transactions 'sync' requests per API page (1-%d, default %d)", MaxPageSize, MaxPageSize),
+		get: func(c *Config) string {
+			if c.Sync.PageSize == 0 {
+				return ""
+			}
+			return strconv.Itoa(c.Sync.PageSize)
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.Sync.PageSize = 0
+				return nil
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > MaxPageSize {
+				return fmt.Errorf("invalid page size %q (expected 1-%d)", v, MaxPageSize)
+			}
+			c.Sync.PageSize = n
+			return nil
+		},
+	},
+	{
+		Name:        "sync.from_amount",
+		Description: "Smallest card transaction amount fetched by 'sync' (default 0: all)",
+		get: func(c *Config) string {
+			if c.Sync.FromAmount == 0 {
+				return ""
+			}
+			return strconv.FormatFloat(c.Sync.FromAmount, 'f', -1, 64)
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.Sync.FromAmount = 0
+				return nil
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 {
+				return fmt.Errorf("invalid amount %q (expected a non-negative number)", v)
+			}
+			c.Sync.FromAmount = f
+			return nil
+		},
+	},
+	{
+		Name:        "sync.post_hook",
+		Description: "Shell command run after each sync with a JSON summary on stdin",
+		get:         func(c *Config) string { return c.Sync.PostHook },
+		set:         func(c *Config, v string) error { c.Sync.PostHook = v; return nil },
+	},
+	{
+		Name:        "match.time_tolerance",
+		Description: "Largest time difference between a card transaction and the linked account transaction it's matched to (e.g. 5m, default 1m)",
+		get:         func(c *Config) string { return c.Match.TimeTolerance },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+					return fmt.Errorf("invalid duration %q (expected e.g. 90s or 5m)", v)
+				}
+			}
+			c.Match.TimeTolerance = v
+			return nil
+		},
+	},
+	{
+		Name:        "match.fx_tolerance",
+		Description: "Largest difference in percent between card and linked account amounts in different currencies, compared in AMD (default 3)",
+		get: func(c *Config) string {
+			if c.Match.FXTolerance == 0 {
+				return ""
+			}
+			return strconv.FormatFloat(c.Match.FXTolerance, 'f', -1, 64)
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.Match.FXTolerance = 0
+				return nil
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f <= 0 || f >= 100 {
+				return fmt.Errorf("invalid tolerance %q (expected a percentage, e.g. 3)", v)
+			}
+			c.Match.FXTolerance = f
+			return nil
+		},
+	},
+	{
+		Name:        "notify.telegram.token",
+		Description: "Telegram bot token",
+		Secret:      true,
+		get:         func(c *Config) string { return c.Notify.Telegram.Token },
+		set:         func(c *Config, v string) error { c.Notify.Telegram.Token = v; return nil },
+	},
+	{
+		Name:        "notify.telegram.chat_id",
+		Description: "Telegram chat ID or @channel",
+		get:         func(c *Config) string { return c.Notify.Telegram.ChatID },
+		set: func(c *Config, v string) error {
+			if v != "" && !chatIDRe.MatchString(v) {
+				return fmt.Errorf("invalid Telegram chat ID %q", v)
+			}
+			c.Notify.Telegram.ChatID = v
+			return nil
+		},
+	},
+	{
+		Name:        "notify.smtp.addr",
+		Description: "SMTP server as host:port",
+		get:         func(c *Config) string { return c.Notify.SMTP.Addr },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				if _, port, err := net.SplitHostPort(v); err != nil || port == "" {
+					return fmt.Errorf("invalid SMTP address %q (expected host:port)", v)
+				}
+			}
+			c.Notify.SMTP.Addr = v
+			return nil
+		},
+	},
+	{
+		Name:        "notify.smtp.user",
+		Description: "SMTP username (enables authentication)",
+		get:         func(c *Config) string { return c.Notify.SMTP.User },
+		set:         func(c *Config, v string) error { c.Notify.SMTP.User = v; return nil },
+	},
+	{
+		Name:        "notify.smtp.password",
+		Description: "SMTP password",
+		Secret:      true,
+		get:         func(c *Config) string { return c.Notify.SMTP.Password },
+		set:         func(c *Config, v string) error { c.Notify.SMTP.Password = v; return nil },
+	},
+	{
+		Name:        "notify.smtp.from",
+		Description: "Sender email address",
+		get:         func(c *Config) string { return c.Notify.SMTP.From },
+		set: func(c *Config, v string) error {
+			if v != "" && !strings.Contains(v, "@") {
+				return fmt.Errorf("invalid email address %q", v)
+			}
+			c.Notify.SMTP.From = v
+			return nil
+		},
+	},
+	{
+		Name:        "notify.smtp.to",
+		Description: "Recipient email addresses (comma-separated)",
+		get:         func(c *Config) string { return c.Notify.SMTP.To },
+		set: func(c *Config, v string) error {
+			for _, addr := range strings.Split(v, ",") {
+				if addr = strings.TrimSpace(addr); addr != "" && !strings.Contains(addr, "@") {
+					return fmt.Errorf("invalid email address %q", addr)
+				}
+			}
+			c.Notify.SMTP.To = v
+			return nil
+		},
+	},
+	{
+		Name:        "notify.webhook.url",
+		Description: "URL receiving notifications as JSON POST requests",
+		get:         func(c *Config) string { return c.Notify.Webhook.URL },
+		set: func(c *Config, v string) error {
+			if err := validateWebhookURL(v); err != nil {
+				return err
+			}
+			c.Notify.Webhook.URL = v
+			return nil
+		},
+	},
+	{
+		Name:        "notify.desktop",
+		Description: "Show desktop notifications (true/false)",
+		get: func(c *Config) string {
+			if !c.Notify.Desktop {
+				return ""
+			}
+			return "true"
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.Notify.Desktop = false
+				return nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid boolean %q (expected true or false)", v)
+			}
+			c.Notify.Desktop = b
+			return nil
+		},
+	},
+	{
+		Name:        "notify.sync_failures",
+		Description: fmt.Sprintf("Warn after this many consecutive failed syncs (default %d, off to disable)", DefaultSyncFailures),
+		get: func(c *Config) string {
+			switch {
+			case c.Notify.SyncFailures < 0:
+				return "off"
+			case c.Notify.SyncFailures == 0:
+				return ""
+			}
+			return strconv.Itoa(c.Notify.SyncFailures)
+		},
+		set: func(c *Config, v string) error {
+			switch v {
+			case "":
+				c.Notify.SyncFailures = 0
+				return nil
+			case "off":
+				c.Notify.SyncFailures = -1
+				return nil
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of failures %q (expected a positive number or off)", v)
+			}
+			c.Notify.SyncFailures = n
+			return nil
+		},
+	},
+	{
+		Name:        "webhook.url",
+		Description: "URL receiving new transactions as signed JSON batches after each sync",
+		get:         func(c *Config) string { return c.Webhook.URL },
+		set: func(c *Config, v string) error {
+			if err := validateWebhookURL(v); err != nil {
+				return err
+			}
+			c.Webhook.URL = v
+			return nil
+		},
+	},
+	{
+		Name:        "webhook.secret",
+		Description: "Key for the HMAC-SHA256 signature of webhook requests",
+		Secret:      true,
+		get:         func(c *Config) string { return c.Webhook.Secret },
+		set:         func(c *Config, v string) error { c.Webhook.Secret = v; return nil },
+	},
+	{
+		Name:        "mqtt.broker",
+		Description: "MQTT broker for Home Assistant (tcp://host:1883 or ssl://host:8883)",
+		get:         func(c *Config) string { return c.MQTT.Broker },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				u, err := url.Parse(v)
+				if err != nil || u.Host == "" || !mqttSchemes[u.Scheme] {
+					return fmt.Errorf("invalid MQTT broker %q (expected tcp://host:port or ssl://host:port)", v)
+				}
+			}
+			c.MQTT.Broker = v
+			return nil
+		},
+	},
+	{
+		Name:        "mqtt.username",
+		Description: "MQTT username",
+		get:         func(c *Config) string { return c.MQTT.Username },
+		set:         func(c *Config, v string) error { c.MQTT.Username = v; return nil },
+	},
+	{
+		Name:        "mqtt.password",
+		Description: "MQTT password",
+		Secret:      true,
+		get:         func(c *Config) string { return c.MQTT.Password },
+		set:         func(c *Config, v string) error { c.MQTT.Password = v; return nil },
+	},
+	{
+		Name:        "mqtt.topic_prefix",
+		Description: "Prefix of state and event topics (default ameriagrab)",
+		get:         func(c *Config) string { return c.MQTT.TopicPrefix },
+		set: func(c *Config, v string) error {
+			if err := validateTopicPrefix(v); err != nil {
+				return err
+			}
+			c.MQTT.TopicPrefix = v
+			return nil
+		},
+	},
+	{
+		Name:        "mqtt.discovery_prefix",
+		Description: "Home Assistant discovery prefix (default homeassistant)",
+		get:         func(c *Config) string { return c.MQTT.DiscoveryPrefix },
+		set: func(c *Config, v string) error {
+			if err := validateTopicPrefix(v); err != nil {
+				return err
+			}
+			c.MQTT.DiscoveryPrefix = v
+			return nil
+		},
+	},
+	{
+		Name:        "backup.remote",
+		Description: "Remote for 'db backup' (s3://bucket/path or webdav://host/path)",
+		get:         func(c *Config) string { return c.Backup.Remote },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				u, err := url.Parse(v)
+				if err != nil || u.Host == "" || !backupSchemes[u.Scheme] {
+					return fmt.Errorf("invalid backup remote %q (expected s3://bucket/path or webdav://host/path)", v)
+				}
+			}
+			c.Backup.Remote = v
+			return nil
+		},
+	},
+	{
+		Name:        "bot.allowed_chats",
+		Description: "Chat IDs allowed to use the Telegram bot (comma-separated)",
+		get:         func(c *Config) string { return c.Bot.AllowedChats },
+		set: func(c *Config, v string) error {
+			for _, id := range strings.Split(v, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+						return fmt.Errorf("invalid chat ID %q (expected a number)", id)
+					}
+				}
+			}
+			c.Bot.AllowedChats = v
+			return nil
+		},
+	},
+	{
+		Name:        "cache.products_ttl",
+		Description: "How long accounts and cards fetched from the API are reused by later commands (e.g. 5m; default: fetched by each command)",
+		get:         func(c *Config) string { return c.Cache.ProductsTTL },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+					return fmt.Errorf("invalid duration %q (expected e.g. 90s or 5m)", v)
+				}
+			}
+			c.Cache.ProductsTTL = v
+			return nil
+		},
+	},
+}
+
+var backupSchemes = map[string]bool{"s3": true, "webdav": true, "webdav+https": true, "webdav+http": true}
+
+var mqttSchemes = map[string]bool{"tcp": true, "mqtt": true, "ssl": true, "tls": true, "mqtts": true}
+
+// validateTopicPrefix rejects MQTT wildcards and leading/trailing slashes
+func validateTopicPrefix(v string) error {
+	if strings.ContainsAny(v, "+#") || strings.HasPrefix(v, "/") || strings.HasSuffix(v, "/") {
+		return fmt.Errorf("invalid topic prefix %q (no wildcards or leading/trailing slashes)", v)
+	}
+	return nil
+}
+
+// validateWebhookURL accepts an empty value or an absolute http(s) URL
+func validateWebhookURL(v string) error {
+	if v == "" {
+		return nil
+	}
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q (expected http:// or https://)", v)
+	}
+	return nil
+}
+
+// LookupKey returns the key with the given name
+func LookupKey(name string) (*Key, error) {
+	for i := range Keys {
+		if Keys[i].Name == name {
+			return &Keys[i], nil
+		}
+	}
+	if alias, ok := strings.CutPrefix(name, AliasPrefix); ok {
+		alias = strings.ToLower(alias)
+		if !aliasRe.MatchString(alias) {
+			return nil, fmt.Errorf("invalid alias %q (expected letters, digits, - and _)", alias)
+		}
+		key := aliasKey(alias)
+		return &key, nil
+	}
+	return nil, fmt.Errorf("unknown config key %q (run 'config list' to see available keys)", name)
+}
+
+// aliasKey returns the key of a product alias, which may be used wherever a
+// product ID or name is expected
+func aliasKey(alias string) Key {
+	return Key{
+		Name:        AliasPrefix + alias,
+		Description: "Product ID, name or last digits of the number the alias stands for",
+		get:         func(c *Config) string { return c.Aliases[alias] },
+		set: func(c *Config, v string) error {
+			if v == "" {
+				delete(c.Aliases, alias)
+				return nil
+			}
+			if c.Aliases == nil {
+				c.Aliases = make(map[string]string)
+			}
+			c.Aliases[alias] = v
+			return nil
+		},
+	}
+}
+
+// ListKeys returns Keys followed by the keys of the product aliases set in c,
+// sorted by alias
+func (c *Config) ListKeys() []Key {
+	keys := append([]Key(nil), Keys...)
+	aliases := make([]string, 0, len(c.Aliases))
+	for alias := range c.Aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		keys = append(keys, aliasKey(alias))
+	}
+	return keys
+}
+
+// Get returns the value of a key, or an empty string if unset
+func (c *Config) Get(name string) (string, error) {
+	key, err := LookupKey(name)
+	if err != nil {
+		return "", err
+	}
+	return key.get(c), nil
+}
+
+// Set validates and sets the value of a key. An empty value unsets the key.
+func (c *Config) Set(name, value string) error {
+	key, err := LookupKey(name)
+	if err != nil {
+		return err
+	}
+	return key.set(c, strings.TrimSpace(value))
+}
@@ -3,63 +3,99 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
 )
 
+// accountTxnInsertSQL inserts an account transaction, ignoring duplicates
+const accountTxnInsertSQL = `
+	INSERT OR IGNORE INTO account_transactions (
+		id, product_id, transaction_id, operation_id, status,
+		transaction_type, workflow_code, flow_direction,
+		transaction_date, settled_date, date, month, year,
+		debit_account_number, credit_account_number,
+		beneficiary_name, details, source_system,
+		transaction_amount_currency, transaction_amount_value,
+		settled_amount_currency, settled_amount_value,
+		domestic_amount_currency, domestic_amount_value,
+		synced_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+// accountTxnArgs returns the arguments of accountTxnInsertSQL
+func accountTxnArgs(productID string, t client.AccountTransaction, syncedAt int64) []interface{} {
+	return []interface{}{
+		t.ID,
+		productID,
+		t.TransactionID,
+		t.OperationID,
+		t.Status,
+		t.TransactionType,
+		t.WorkflowCode,
+		t.FlowDirection,
+		t.TransactionDate,
+		t.SettledDate,
+		t.Date,
+		t.Month,
+		t.Year,
+		t.DebitAccountNumber,
+		t.CreditAccountNumber,
+		t.BeneficiaryName,
+		t.Details,
+		t.SourceSystem,
+		t.TransactionAmount.Currency,
+		t.TransactionAmount.Value,
+		t.SettledAmount.Currency,
+		t.SettledAmount.Value,
+		t.DomesticAmount.Currency,
+		t.DomesticAmount.Value,
+		syncedAt,
+	}
+}
+
+// accountTxnWhere selects a stored account transaction by its key, ID and
+// transaction date (see AccountTxnKey)
+const accountTxnWhere = "id = ? AND transaction_date = ?"
+
+// accountTxnMutableColumns are the fields of a stored account transaction
+// that the bank may change later (e.g. the status and settled amount)
+var accountTxnMutableColumns = []string{
+	"status", "transaction_type", "workflow_code", "settled_date",
+	"beneficiary_name", "details",
+	"transaction_amount_currency", "transaction_amount_value",
+	"settled_amount_currency", "settled_amount_value",
+	"domestic_amount_currency", "domestic_amount_value",
+}
+
+// accountTxnMutableValues returns the values of accountTxnMutableColumns
+func accountTxnMutableValues(t client.AccountTransaction) []interface{} {
+	return []interface{}{
+		t.Status, t.TransactionType, t.WorkflowCode, t.SettledDate,
+		t.BeneficiaryName, t.Details,
+		t.TransactionAmount.Currency, t.TransactionAmount.Value,
+		t.SettledAmount.Currency, t.SettledAmount.Value,
+		t.DomesticAmount.Currency, t.DomesticAmount.Value,
+	}
+}
+
 // InsertAccountTransactions inserts account transactions, ignoring duplicates
 func (db *DB) InsertAccountTransactions(productID string, txns []client.AccountTransaction) (int, error) {
 	syncedAt := time.Now().Unix()
 	var inserted int
 
+	if err := db.prepare(accountTxnInsertSQL); err != nil {
+		return 0, err
+	}
 	err := db.WithTransaction(func(tx *sql.Tx) error {
-		stmt, err := tx.Prepare(`
-			INSERT OR IGNORE INTO account_transactions (
-				id, product_id, transaction_id, operation_id, status,
-				transaction_type, workflow_code, flow_direction,
-				transaction_date, settled_date, date, month, year,
-				debit_account_number, credit_account_number,
-				beneficiary_name, details, source_system,
-				transaction_amount_currency, transaction_amount_value,
-				settled_amount_currency, settled_amount_value,
-				domestic_amount_currency, domestic_amount_value,
-				synced_at
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		`)
+		stmt, err := db.txStmt(tx, accountTxnInsertSQL)
 		if err != nil {
-			return fmt.Errorf("failed to prepare statement: %w", err)
+			return err
 		}
-		defer stmt.Close()
 
 		for _, t := range txns {
-			result, err := stmt.Exec(
-				t.ID,
-				productID,
-				t.TransactionID,
-				t.OperationID,
-				t.Status,
-				t.TransactionType,
-				t.WorkflowCode,
-				t.FlowDirection,
-				t.TransactionDate,
-				t.SettledDate,
-				t.Date,
-				t.Month,
-				t.Year,
-				t.DebitAccountNumber,
-				t.CreditAccountNumber,
-				t.BeneficiaryName,
-				t.Details,
-				t.SourceSystem,
-				t.TransactionAmount.Currency,
-				t.TransactionAmount.Value,
-				t.SettledAmount.Currency,
-				t.SettledAmount.Value,
-				t.DomesticAmount.Currency,
-				t.DomesticAmount.Value,
-				syncedAt,
-			)
+			result, err := stmt.Exec(accountTxnArgs(productID, t, syncedAt)...)
 			if err != nil {
 				return fmt.Errorf("failed to insert transaction %s: %w", t.ID, err)
 			}
@@ -74,15 +110,71 @@ func (db *DB) InsertAccountTransactions(productID string, txns []client.AccountT
 	return inserted, err
 }
 
+// ApplyAccountTransactions inserts new account transactions and updates the changed
+// fields of stored ones, recording the changes (see GetTransactionChanges).
+// Returns the number of inserted and changed transactions.
+func (db *DB) ApplyAccountTransactions(productID string, txns []client.AccountTransaction) (inserted, changed int, err error) {
+	syncedAt := time.Now().Unix()
+
+	if err := db.prepare(accountTxnInsertSQL); err != nil {
+		return 0, 0, err
+	}
+	if err := db.prepareTxnChanges("account_transactions", accountTxnWhere, accountTxnMutableColumns); err != nil {
+		return 0, 0, err
+	}
+	err = db.WithTransaction(func(tx *sql.Tx) error {
+		stmt, err := db.txStmt(tx, accountTxnInsertSQL)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range txns {
+			result, err := stmt.Exec(accountTxnArgs(productID, t, syncedAt)...)
+			if err != nil {
+				return fmt.Errorf("failed to insert transaction %s: %w", t.ID, err)
+			}
+			if rowsAffected, _ := result.RowsAffected(); rowsAffected > 0 {
+				inserted++
+				continue
+			}
+			row := txnRow{
+				table:     "account_transactions",
+				source:    SourceAccount,
+				productID: productID,
+				id:        t.ID,
+				where:     accountTxnWhere,
+				whereArgs: []interface{}{t.ID, t.TransactionDate},
+			}
+			updated, err := db.applyTxnChanges(tx, row, accountTxnMutableColumns, accountTxnMutableValues(t), syncedAt)
+			if err != nil {
+				return err
+			}
+			if updated {
+				changed++
+			}
+		}
+		return nil
+	})
+
+	return inserted, changed, err
+}
+
 // GetAccountTransactions retrieves all account transactions for a product.
 // If ascending is true, returns oldest first; otherwise newest first.
 func (db *DB) GetAccountTransactions(productID string, ascending bool) ([]client.AccountTransaction, error) {
-	order := "DESC"
-	if ascending {
-		order = "ASC"
-	}
+	var txns []client.AccountTransaction
+	err := db.ForEachAccountTransaction(productID, ReadOptions{Ascending: ascending}, func(t client.AccountTransaction) error {
+		txns = append(txns, t)
+		return nil
+	})
+	return txns, err
+}
 
-	rows, err := db.Query(fmt.Sprintf(`
+// ForEachAccountTransaction calls fn for each account transaction of a
+// product as the rows are read, like ForEachCardTransaction
+func (db *DB) ForEachAccountTransaction(productID string, opts ReadOptions, fn func(client.AccountTransaction) error) error {
+	orderBy, limitArgs := opts.orderBy("transaction_date")
+	rows, err := db.query(`
 		SELECT id, transaction_id, operation_id, status,
 			   transaction_type, workflow_code, flow_direction,
 			   transaction_date, settled_date, date, month, year,
@@ -93,14 +185,12 @@ func (db *DB) GetAccountTransactions(productID string, ascending bool) ([]client
 			   domestic_amount_currency, domestic_amount_value
 		FROM account_transactions
 		WHERE product_id = ?
-		ORDER BY transaction_date %s
-	`, order), productID)
+		`+orderBy, append([]interface{}{productID}, limitArgs...)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query account transactions: %w", err)
+		return fmt.Errorf("failed to query account transactions: %w", err)
 	}
 	defer rows.Close()
 
-	var txns []client.AccountTransaction
 	for rows.Next() {
 		var t client.AccountTransaction
 		var txnAmtCurrency, settledAmtCurrency, domesticAmtCurrency sql.NullString
@@ -132,7 +222,7 @@ func (db *DB) GetAccountTransactions(productID string, ascending bool) ([]client
 			&domesticAmtValue,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+			return fmt.Errorf("failed to scan transaction: %w", err)
 		}
 
 		t.TransactionAmount = client.TransactionAmt{
@@ -148,46 +238,55 @@ func (db *DB) GetAccountTransactions(productID string, ascending bool) ([]client
 			Value:    domesticAmtValue.Float64,
 		}
 
-		txns = append(txns, t)
+		if err := fn(t); err != nil {
+			return err
+		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating transactions: %w", err)
+		return fmt.Errorf("error iterating transactions: %w", err)
 	}
 
-	return txns, nil
+	return nil
+}
+
+// AccountTxnKey creates a composite key from transaction ID and transaction
+// date (Unix milliseconds), the key account transactions are stored under
+func AccountTxnKey(id string, transactionDate int64) string {
+	return id + "|" + strconv.FormatInt(transactionDate, 10)
 }
 
-// GetExistingAccountTxnIDs returns a set of existing transaction IDs for a product
-func (db *DB) GetExistingAccountTxnIDs(productID string) (map[string]bool, error) {
-	rows, err := db.Query(`
-		SELECT id FROM account_transactions WHERE product_id = ?
+// GetExistingAccountTxnKeys returns a set of existing transaction keys (id|transaction_date) for a product
+func (db *DB) GetExistingAccountTxnKeys(productID string) (map[string]bool, error) {
+	rows, err := db.query(`
+		SELECT id, transaction_date FROM account_transactions WHERE product_id = ?
 	`, productID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query transaction IDs: %w", err)
+		return nil, fmt.Errorf("failed to query transaction keys: %w", err)
 	}
 	defer rows.Close()
 
-	ids := make(map[string]bool)
+	keys := make(map[string]bool)
 	for rows.Next() {
 		var id string
-		if err := rows.Scan(&id); err != nil {
-			return nil, fmt.Errorf("failed to scan ID: %w", err)
+		var transactionDate int64
+		if err := rows.Scan(&id, &transactionDate); err != nil {
+			return nil, fmt.Errorf("failed to scan key: %w", err)
 		}
-		ids[id] = true
+		keys[AccountTxnKey(id, transactionDate)] = true
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating IDs: %w", err)
+		return nil, fmt.Errorf("error iterating keys: %w", err)
 	}
 
-	return ids, nil
+	return keys, nil
 }
 
 // CountAccountTransactions returns the total count of account transactions for a product
 func (db *DB) CountAccountTransactions(productID string) (int, error) {
 	var count int
-	err := db.QueryRow(`
+	err := db.queryRow(`
 		SELECT COUNT(*) FROM account_transactions WHERE product_id = ?
 	`, productID).Scan(&count)
 	if err != nil {
@@ -0,0 +1,345 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// Alert rule kinds
+const (
+	AlertBalanceBelow    = "balance-below"
+	AlertTxnAbove        = "txn-above"
+	AlertNewCounterparty = "new-counterparty"
+	AlertBudget          = "budget"
+	AlertPaymentDue      = "payment-due"
+)
+
+// AlertKinds lists all supported alert rule kinds
+var AlertKinds = []string{AlertBalanceBelow, AlertTxnAbove, AlertNewCounterparty, AlertBudget, AlertPaymentDue}
+
+// AlertRule is a user-defined condition evaluated after each sync
+type AlertRule struct {
+	ID        int64
+	Kind      string
+	ProductID string  // empty = all products (not allowed for balance-below)
+	Threshold float64 // balance floor, transaction ceiling, monthly budget or days ahead
+	Currency  string  // empty = any currency
+	Category  string  // budget only: spending category, empty = all spending
+	Rollover  bool    // budget only: carry unspent amounts over to the next month
+	CreatedAt time.Time
+}
+
+// Alert is a fired alert
+type Alert struct {
+	ID      int64
+	RuleID  int64
+	Kind    string
+	FiredAt time.Time
+	Message string
+}
+
+// AddAlertRule stores a new alert rule and returns its ID
+func (db *DB) AddAlertRule(r AlertRule) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO alert_rules (kind, product_id, threshold, currency, category, rollover, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, r.Kind, nullString(r.ProductID), r.Threshold, nullString(r.Currency), nullString(r.Category), r.Rollover, time.Now().Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert alert rule: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get alert rule ID: %w", err)
+	}
+	return id, nil
+}
+
+// GetAlertRules returns all alert rules in creation order
+func (db *DB) GetAlertRules() ([]AlertRule, error) {
+	rows, err := db.Query(`
+		SELECT id, kind, product_id, threshold, currency, category, rollover, created_at
+		FROM alert_rules
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []AlertRule
+	for rows.Next() {
+		var r AlertRule
+		var productID, currency, category sql.NullString
+		var threshold sql.NullFloat64
+		var createdAt int64
+		if err := rows.Scan(&r.ID, &r.Kind, &productID, &threshold, &currency, &category, &r.Rollover, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		r.ProductID = productID.String
+		r.Threshold = threshold.Float64
+		r.Currency = currency.String
+		r.Category = category.String
+		r.CreatedAt = time.Unix(createdAt, 0)
+		rules = append(rules, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating alert rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// DeleteAlertRule removes an alert rule (and its fired alerts).
+// Returns false if no rule with the given ID exists.
+func (db *DB) DeleteAlertRule(id int64) (bool, error) {
+	result, err := db.Exec(`DELETE FROM alert_rules WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// GetAlerts returns fired alerts, newest first. If limit is 0, returns all alerts.
+func (db *DB) GetAlerts(limit int) ([]Alert, error) {
+	query := `
+		SELECT a.id, a.rule_id, r.kind, a.fired_at, a.message
+		FROM alerts a JOIN alert_rules r ON r.id = a.rule_id
+		ORDER BY a.fired_at DESC, a.id DESC
+	`
+	var rows *sql.Rows
+	var err error
+	if limit > 0 {
+		rows, err = db.Query(query+" LIMIT ?", limit)
+	} else {
+		rows, err = db.Query(query)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []Alert
+	for rows.Next() {
+		var a Alert
+		var firedAt int64
+		if err := rows.Scan(&a.ID, &a.RuleID, &a.Kind, &firedAt, &a.Message); err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		a.FiredAt = time.Unix(firedAt, 0)
+		alerts = append(alerts, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating alerts: %w", err)
+	}
+
+	return alerts, nil
+}
+
+// EvaluateAlertRules checks all rules against the current data and records newly fired alerts.
+// Transaction-based rules only consider transactions synced at or after since.
+// Each rule fires at most once per triggering item (transaction, counterparty, day or month).
+// categorize assigns spending categories for budgets limited to one.
+func (db *DB) EvaluateAlertRules(since time.Time, categorize CategorizeFunc) ([]Alert, error) {
+	rules, err := db.GetAlertRules()
+	if err != nil {
+		return nil, err
+	}
+
+	var fired []Alert
+	for _, rule := range rules {
+		candidates, err := db.evaluateRule(rule, since, categorize)
+		if err != nil {
+			return nil, fmt.Errorf("evaluating alert rule %d: %w", rule.ID, err)
+		}
+		for _, c := range candidates {
+			alert, err := db.recordAlert(rule, c.refKey, c.message)
+			if err != nil {
+				return nil, err
+			}
+			if alert != nil {
+				fired = append(fired, *alert)
+			}
+		}
+	}
+
+	return fired, nil
+}
+
+// alertCandidate is a potential alert produced by rule evaluation
+type alertCandidate struct {
+	refKey  string
+	message string
+}
+
+func (db *DB) evaluateRule(rule AlertRule, since time.Time, categorize CategorizeFunc) ([]alertCandidate, error) {
+	switch rule.Kind {
+	case AlertBalanceBelow:
+		return db.evaluateBalanceBelow(rule)
+	case AlertTxnAbove:
+		return db.evaluateTxnAbove(rule, since)
+	case AlertNewCounterparty:
+		return db.evaluateNewCounterparty(rule, since)
+	case AlertBudget:
+		return db.evaluateBudget(rule, categorize)
+	case AlertPaymentDue:
+		return db.evaluatePaymentDue(rule)
+	default:
+		return nil, fmt.Errorf("unknown alert kind %q", rule.Kind)
+	}
+}
+
+func (db *DB) evaluateBalanceBelow(rule AlertRule) ([]alertCandidate, error) {
+	product, err := db.GetProductByID(rule.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil || product.AvailableBalance >= rule.Threshold {
+		return nil, nil
+	}
+	return []alertCandidate{{
+		refKey: "balance:" + time.Now().Format("2006-01-02"),
+		message: fmt.Sprintf("Balance of %s is %.2f %s, below %.2f %s",
+			product.Name, product.AvailableBalance, product.Currency, rule.Threshold, product.Currency),
+	}}, nil
+}
+
+func (db *DB) evaluateTxnAbove(rule AlertRule, since time.Time) ([]alertCandidate, error) {
+	entries, err := db.GetLedgerEntries(LedgerOptions{ProductID: rule.ProductID, SyncedSince: since})
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []alertCandidate
+	for _, e := range entries {
+		if e.Credit || e.Amount <= rule.Threshold || !currencyMatches(rule.Currency, e.Currency) {
+			continue
+		}
+		candidates = append(candidates, alertCandidate{
+			refKey: "txn:" + e.Source + "|" + TxnKey(e.ID, e.Date.UTC().Format(time.RFC3339)),
+			message: fmt.Sprintf("Transaction of %.2f %s on %s exceeds %.2f: %s",
+				e.Amount, e.Currency, e.Date.Format("2006-01-02 15:04"), rule.Threshold, e.Details),
+		})
+	}
+	return candidates, nil
+}
+
+func (db *DB) evaluateNewCounterparty(rule AlertRule, since time.Time) ([]alertCandidate, error) {
+	entries, err := db.GetLedgerEntries(LedgerOptions{ProductID: rule.ProductID})
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, e := range entries {
+		if e.SyncedAt.Before(since) && e.Counterparty != "" {
+			known[strings.ToLower(e.Counterparty)] = true
+		}
+	}
+	// Everything is new on a fresh database; don't flood with alerts
+	if len(known) == 0 {
+		return nil, nil
+	}
+
+	var candidates []alertCandidate
+	for _, e := range entries {
+		key := strings.ToLower(e.Counterparty)
+		if e.SyncedAt.Before(since) || key == "" || known[key] {
+			continue
+		}
+		known[key] = true
+		candidates = append(candidates, alertCandidate{
+			refKey: "counterparty:" + key,
+			message: fmt.Sprintf("New counterparty: %s (%.2f %s on %s)",
+				e.Counterparty, e.SignedAmount(), e.Currency, e.Date.Format("2006-01-02")),
+		})
+	}
+	return candidates, nil
+}
+
+func (db *DB) evaluateBudget(rule AlertRule, categorize CategorizeFunc) ([]alertCandidate, error) {
+	now := time.Now()
+	months, err := db.GetBudgetMonths(rule, now, now, categorize)
+	if err != nil {
+		return nil, err
+	}
+	m := months[len(months)-1]
+	if m.Left() >= 0 {
+		return nil, nil
+	}
+
+	month := m.Month.Format("2006-01")
+	spending := "Spending"
+	if rule.Category != "" {
+		spending = "Spending on " + rule.Category
+	}
+	return []alertCandidate{{
+		refKey: "budget:" + month,
+		message: fmt.Sprintf("%s in %s is %.2f %s, exceeding budget of %.2f %s",
+			spending, month, m.Spent, rule.Currency, money.Add(m.Budget, m.Carried), rule.Currency),
+	}}, nil
+}
+
+func (db *DB) evaluatePaymentDue(rule AlertRule) ([]alertCandidate, error) {
+	loans, err := db.GetLoans()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	horizon := today.AddDate(0, 0, int(rule.Threshold))
+
+	var candidates []alertCandidate
+	for _, l := range loans {
+		if rule.ProductID != "" && rule.ProductID != l.ID {
+			continue
+		}
+		if l.NextPaymentDate == "" || !currencyMatches(rule.Currency, l.Currency) {
+			continue
+		}
+		due, err := time.ParseInLocation("2006-01-02", l.NextPaymentDate, time.Local)
+		if err != nil || due.Before(today) || due.After(horizon) {
+			continue
+		}
+		candidates = append(candidates, alertCandidate{
+			refKey: "payment:" + l.ID + "|" + l.NextPaymentDate,
+			message: fmt.Sprintf("Loan payment of %.2f %s for %s is due on %s",
+				l.NextPaymentAmount, l.Currency, l.Name, l.NextPaymentDate),
+		})
+	}
+	return candidates, nil
+}
+
+// recordAlert stores a fired alert. Returns nil if the alert already fired for this ref key.
+func (db *DB) recordAlert(rule AlertRule, refKey, message string) (*Alert, error) {
+	firedAt := time.Now()
+	result, err := db.Exec(`
+		INSERT OR IGNORE INTO alerts (rule_id, ref_key, fired_at, message) VALUES (?, ?, ?, ?)
+	`, rule.ID, refKey, firedAt.Unix(), message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record alert: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+	id, _ := result.LastInsertId()
+	return &Alert{
+		ID:      id,
+		RuleID:  rule.ID,
+		Kind:    rule.Kind,
+		FiredAt: time.Unix(firedAt.Unix(), 0),
+		Message: message,
+	}, nil
+}
+
+// currencyMatches returns true if the rule currency is empty or equals the transaction currency
+func currencyMatches(ruleCurrency, currency string) bool {
+	return ruleCurrency == "" || strings.EqualFold(ruleCurrency, currency)
+}
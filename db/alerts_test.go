@@ -0,0 +1,262 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+// setupAlertTestDB creates a database with one card and one account with transactions.
+// All existing transactions are marked as synced an hour ago.
+func setupAlertTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	products := []client.ProductInfo{
+		{ID: "card1", ProductType: "CARD", Name: "Main Card", Currency: "AMD", AvailableBalance: 5000},
+		{ID: "acc1", ProductType: "ACCOUNT", Name: "Savings", Currency: "USD", AvailableBalance: 1000},
+	}
+	if err := db.UpsertProducts(products); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+
+	cardTxns := []client.Transaction{
+		{ID: "c1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 1500}, Details: "Grocery Store", OperationDate: "2024-01-10T10:00:00Z"},
+	}
+	if _, err := db.InsertCardTransactions("card1", cardTxns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+	acctTxns := []client.AccountTransaction{
+		{ID: "a1", FlowDirection: "INCOME", BeneficiaryName: "Employer LLC", TransactionDate: 1704067200000, TransactionAmount: client.TransactionAmt{Currency: "USD", Value: 2000}},
+	}
+	if _, err := db.InsertAccountTransactions("acc1", acctTxns); err != nil {
+		t.Fatalf("failed to insert account transactions: %v", err)
+	}
+
+	markSyncedAt(t, db, time.Now().Add(-time.Hour))
+	return db
+}
+
+// markSyncedAt sets synced_at of all existing transactions
+func markSyncedAt(t *testing.T, db *DB, at time.Time) {
+	t.Helper()
+	for _, table := range []string{"card_transactions", "account_transactions"} {
+		if _, err := db.Exec("UPDATE "+table+" SET synced_at = ?", at.Unix()); err != nil {
+			t.Fatalf("failed to update synced_at: %v", err)
+		}
+	}
+}
+
+func TestGetLedgerEntries(t *testing.T) {
+	db := setupAlertTestDB(t)
+	defer db.Close()
+
+	entries, err := db.GetLedgerEntries(LedgerOptions{})
+	if err != nil {
+		t.Fatalf("GetLedgerEntries failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	// Sorted oldest first: account income (2024-01-01) then card debit (2024-01-10)
+	if entries[0].Source != SourceAccount || !entries[0].Credit || entries[0].Counterparty != "Employer LLC" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Source != SourceCard || entries[1].Credit || entries[1].SignedAmount() != -1500 {
+		t.Errorf("unexpected second entry: %+v", entries[1])
+	}
+	if entries[1].Counterparty != "Grocery Store" {
+		t.Errorf("expected card counterparty to fall back to details, got %q", entries[1].Counterparty)
+	}
+
+	filtered, err := db.GetLedgerEntries(LedgerOptions{
+		ProductID: "card1",
+		From:      time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("GetLedgerEntries failed: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].ID != "c1" {
+		t.Errorf("expected only c1 after filtering, got %+v", filtered)
+	}
+}
+
+func TestAlertRulesCRUD(t *testing.T) {
+	db := setupAlertTestDB(t)
+	defer db.Close()
+
+	id, err := db.AddAlertRule(AlertRule{Kind: AlertTxnAbove, Threshold: 1000, Currency: "AMD"})
+	if err != nil {
+		t.Fatalf("AddAlertRule failed: %v", err)
+	}
+
+	rules, err := db.GetAlertRules()
+	if err != nil {
+		t.Fatalf("GetAlertRules failed: %v", err)
+	}
+	if len(rules) != 1 || rules[0].ID != id || rules[0].Kind != AlertTxnAbove || rules[0].Threshold != 1000 {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+
+	found, err := db.DeleteAlertRule(id)
+	if err != nil || !found {
+		t.Fatalf("DeleteAlertRule failed: found=%v err=%v", found, err)
+	}
+	found, err = db.DeleteAlertRule(id)
+	if err != nil || found {
+		t.Errorf("expected second delete to report not found, got found=%v err=%v", found, err)
+	}
+}
+
+func TestEvaluateAlertRules(t *testing.T) {
+	db := setupAlertTestDB(t)
+	defer db.Close()
+
+	rules := []AlertRule{
+		{Kind: AlertBalanceBelow, ProductID: "card1", Threshold: 10000},
+		{Kind: AlertBalanceBelow, ProductID: "acc1", Threshold: 500},
+		{Kind: AlertTxnAbove, Threshold: 3000, Currency: "AMD"},
+		{Kind: AlertNewCounterparty},
+	}
+	for _, r := range rules {
+		if _, err := db.AddAlertRule(r); err != nil {
+			t.Fatalf("AddAlertRule failed: %v", err)
+		}
+	}
+
+	// Simulate a sync that adds new transactions
+	since := time.Unix(time.Now().Unix(), 0)
+	newTxns := []client.Transaction{
+		{ID: "c2", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 50000}, Details: "Electronics Shop", OperationDate: "2024-01-11T10:00:00Z"},
+		{ID: "c3", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 800}, Details: "Grocery Store", OperationDate: "2024-01-12T10:00:00Z"},
+	}
+	if _, err := db.InsertCardTransactions("card1", newTxns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+
+	fired, err := db.EvaluateAlertRules(since, nil)
+	if err != nil {
+		t.Fatalf("EvaluateAlertRules failed: %v", err)
+	}
+
+	kinds := make(map[string]int)
+	for _, a := range fired {
+		kinds[a.Kind]++
+	}
+	if kinds[AlertBalanceBelow] != 1 {
+		t.Errorf("expected 1 balance-below alert (card only), got %d", kinds[AlertBalanceBelow])
+	}
+	if kinds[AlertTxnAbove] != 1 {
+		t.Errorf("expected 1 txn-above alert, got %d", kinds[AlertTxnAbove])
+	}
+	if kinds[AlertNewCounterparty] != 1 {
+		t.Errorf("expected 1 new-counterparty alert (Electronics Shop), got %d", kinds[AlertNewCounterparty])
+	}
+
+	// Re-evaluating must not fire the same alerts again
+	fired, err = db.EvaluateAlertRules(since, nil)
+	if err != nil {
+		t.Fatalf("EvaluateAlertRules failed: %v", err)
+	}
+	if len(fired) != 0 {
+		t.Errorf("expected no alerts on re-evaluation, got %d", len(fired))
+	}
+
+	history, err := db.GetAlerts(0)
+	if err != nil {
+		t.Fatalf("GetAlerts failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Errorf("expected 3 stored alerts, got %d", len(history))
+	}
+}
+
+func TestEvaluateAlertRules_Budget(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.AddAlertRule(AlertRule{Kind: AlertBudget, Threshold: 1000, Currency: "AMD"}); err != nil {
+		t.Fatalf("AddAlertRule failed: %v", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	txns := []client.Transaction{
+		{ID: "b1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 700}, OperationDate: now},
+		{ID: "b2", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 600}, OperationDate: now},
+		{ID: "b3", AccountingType: "DEBIT", Amount: client.Amount{Currency: "USD", Amount: 5000}, OperationDate: now},
+	}
+	if _, err := db.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+
+	fired, err := db.EvaluateAlertRules(time.Now(), nil)
+	if err != nil {
+		t.Fatalf("EvaluateAlertRules failed: %v", err)
+	}
+	if len(fired) != 1 || fired[0].Kind != AlertBudget {
+		t.Fatalf("expected 1 budget alert, got %+v", fired)
+	}
+}
+
+func TestAlertRules_BudgetCategory(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.AddAlertRule(AlertRule{Kind: AlertBudget, Threshold: 100, Currency: "AMD", Category: "groceries", Rollover: true}); err != nil {
+		t.Fatalf("AddAlertRule failed: %v", err)
+	}
+	rules, err := db.GetAlertRules()
+	if err != nil {
+		t.Fatalf("GetAlertRules failed: %v", err)
+	}
+	if len(rules) != 1 || rules[0].Category != "groceries" || !rules[0].Rollover {
+		t.Fatalf("expected a groceries budget with rollover, got %+v", rules)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	txns := []client.Transaction{
+		{ID: "b1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 500}, Details: "Cafe", OperationDate: now},
+	}
+	if _, err := db.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+	categorize := func(e LedgerEntry) string {
+		if e.Details == "Cafe" {
+			return "restaurants"
+		}
+		return "groceries"
+	}
+
+	// Spending in other categories doesn't count against the budget
+	fired, err := db.EvaluateAlertRules(time.Now(), categorize)
+	if err != nil {
+		t.Fatalf("EvaluateAlertRules failed: %v", err)
+	}
+	if len(fired) != 0 {
+		t.Fatalf("expected no alerts, got %+v", fired)
+	}
+
+	txns[0].ID, txns[0].Details = "b2", "Market"
+	if _, err := db.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+	fired, err = db.EvaluateAlertRules(time.Now(), categorize)
+	if err != nil {
+		t.Fatalf("EvaluateAlertRules failed: %v", err)
+	}
+	if len(fired) != 1 || !strings.Contains(fired[0].Message, "groceries") {
+		t.Fatalf("expected 1 groceries budget alert, got %+v", fired)
+	}
+}
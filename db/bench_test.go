@@ -0,0 +1,103 @@
+package db
+
+import (
+	"flag"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+var benchTransactions = flag.Int("bench.transactions", 100000, "card transactions in the benchmark database")
+
+var (
+	benchOnce sync.Once
+	benchDB   *DB
+	benchErr  error
+)
+
+// benchDatabase returns the shared benchmark database, generating it on first use
+func benchDatabase(b *testing.B) *DB {
+	b.Helper()
+	benchOnce.Do(func() {
+		benchDB, benchErr = OpenInMemory()
+		if benchErr == nil {
+			benchErr = benchDB.GenerateFixture(FixtureOptions{
+				Transactions: *benchTransactions,
+				Seed:         1,
+				End:          time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC),
+			})
+		}
+	})
+	if benchErr != nil {
+		b.Fatalf("failed to generate the benchmark database: %v", benchErr)
+	}
+	return benchDB
+}
+
+// benchCardTransactions returns n generated card transactions not in the database
+func benchCardTransactions(n int) []client.Transaction {
+	card, _ := fixtureCardTransactions(newFixtureRand(2), 9, n, time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC))
+	return card
+}
+
+func BenchmarkInsertCardTransactions(b *testing.B) {
+	txns := benchCardTransactions(1000)
+	for b.Loop() {
+		b.StopTimer()
+		db, err := OpenInMemory()
+		if err != nil {
+			b.Fatalf("failed to open database: %v", err)
+		}
+		b.StartTimer()
+		if _, err := db.InsertCardTransactions("card-001", txns); err != nil {
+			b.Fatalf("InsertCardTransactions failed: %v", err)
+		}
+		b.StopTimer()
+		db.Close()
+		b.StartTimer()
+	}
+}
+
+func BenchmarkApplyCardTransactions(b *testing.B) {
+	// A sync page of transactions that are all stored already
+	db := benchDatabase(b)
+	txns, err := db.GetCardTransactions("card-001", 1000, 0, false)
+	if err != nil {
+		b.Fatalf("GetCardTransactions failed: %v", err)
+	}
+	for b.Loop() {
+		if _, _, err := db.ApplyCardTransactions("card-001", txns); err != nil {
+			b.Fatalf("ApplyCardTransactions failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkGetCardTransactionsPage(b *testing.B) {
+	db := benchDatabase(b)
+	for b.Loop() {
+		if _, err := db.GetCardTransactions("card-001", 100, 10, false); err != nil {
+			b.Fatalf("GetCardTransactions failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkGetCombinedTransactions(b *testing.B) {
+	db := benchDatabase(b)
+	opts := CombinedTransactionsOptions{Size: 100, IncludeExtended: true}
+	for b.Loop() {
+		if _, _, err := db.GetCombinedTransactions("card-001", opts); err != nil {
+			b.Fatalf("GetCombinedTransactions failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkGetLedgerEntries(b *testing.B) {
+	db := benchDatabase(b)
+	for b.Loop() {
+		if _, err := db.GetLedgerEntries(LedgerOptions{}); err != nil {
+			b.Fatalf("GetLedgerEntries failed: %v", err)
+		}
+	}
+}
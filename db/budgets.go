@@ -0,0 +1,89 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// CategorizeFunc returns the spending category of a ledger entry
+type CategorizeFunc func(LedgerEntry) string
+
+// BudgetMonth is one month of a budget rule: its budget, the unspent amount
+// carried over from the previous months and the spending counted against it
+type BudgetMonth struct {
+	Month   time.Time `json:"month"` // first day of the month
+	Budget  float64   `json:"budget"`
+	Carried float64   `json:"carried"`
+	Spent   float64   `json:"spent"`
+}
+
+// Left returns the part of the month's budget and carried amount not spent,
+// negative if the budget was exceeded
+func (m BudgetMonth) Left() float64 {
+	return money.Sub(money.Add(m.Budget, m.Carried), m.Spent)
+}
+
+// GetBudgetMonths returns the months of a budget rule from the month of from
+// to the month of to (both inclusive), oldest first. Spending is the debits of
+// the rule's product, currency and category. With rollover, whatever is left of
+// a month's budget is carried over to the next one, starting with the month the
+// rule was created in; overspending isn't carried over. categorize is only
+// needed for rules limited to a category.
+func (db *DB) GetBudgetMonths(rule AlertRule, from, to time.Time, categorize CategorizeFunc) ([]BudgetMonth, error) {
+	if rule.Kind != AlertBudget {
+		return nil, fmt.Errorf("alert rule %d is not a budget", rule.ID)
+	}
+	if rule.Category != "" && categorize == nil {
+		return nil, fmt.Errorf("budget %d needs a categorizer", rule.ID)
+	}
+
+	first, last := monthStart(from), monthStart(to)
+	created := monthStart(rule.CreatedAt)
+	start := first
+	if rule.Rollover && created.Before(start) {
+		start = created
+	}
+	if last.Before(start) {
+		return nil, nil
+	}
+
+	entries, err := db.GetLedgerEntries(LedgerOptions{ProductID: rule.ProductID, From: start, To: last.AddDate(0, 1, 0)})
+	if err != nil {
+		return nil, err
+	}
+
+	var months []BudgetMonth
+	index := make(map[string]int)
+	for m := start; !m.After(last); m = m.AddDate(0, 1, 0) {
+		index[m.Format("2006-01")] = len(months)
+		months = append(months, BudgetMonth{Month: m, Budget: rule.Threshold})
+	}
+	for _, e := range entries {
+		if e.Credit || !currencyMatches(rule.Currency, e.Currency) {
+			continue
+		}
+		if rule.Category != "" && categorize(e) != rule.Category {
+			continue
+		}
+		if i, ok := index[e.Date.Local().Format("2006-01")]; ok {
+			months[i].Spent = money.Add(months[i].Spent, e.Amount)
+		}
+	}
+
+	if rule.Rollover {
+		for i := 1; i < len(months); i++ {
+			if left := months[i-1].Left(); left > 0 && months[i].Month.After(created) {
+				months[i].Carried = left
+			}
+		}
+	}
+	return months[index[first.Format("2006-01")]:], nil
+}
+
+// monthStart returns the first day of the month of t in local time
+func monthStart(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+}
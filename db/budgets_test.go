@@ -0,0 +1,85 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestGetBudgetMonths(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	txns := []client.Transaction{
+		{ID: "g1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 600}, Details: "Market", OperationDate: "2024-01-10T12:00:00Z"},
+		{ID: "g2", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 1300}, Details: "Market", OperationDate: "2024-02-10T12:00:00Z"},
+		{ID: "r1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 5000}, Details: "Cafe", OperationDate: "2024-02-11T12:00:00Z"},
+		{ID: "g3", AccountingType: "DEBIT", Amount: client.Amount{Currency: "USD", Amount: 50}, Details: "Market", OperationDate: "2024-03-05T12:00:00Z"},
+		{ID: "c1", AccountingType: "CREDIT", Amount: client.Amount{Currency: "AMD", Amount: 700}, Details: "Market refund", OperationDate: "2024-03-06T12:00:00Z"},
+	}
+	if _, err := db.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+	categorize := func(e LedgerEntry) string {
+		if strings.Contains(e.Details, "Market") {
+			return "groceries"
+		}
+		return "restaurants"
+	}
+
+	rule := AlertRule{
+		ID: 1, Kind: AlertBudget, Threshold: 1000, Currency: "AMD", Category: "groceries", Rollover: true,
+		CreatedAt: time.Date(2024, 1, 3, 0, 0, 0, 0, time.Local),
+	}
+	from := time.Date(2024, 2, 15, 0, 0, 0, 0, time.Local)
+	to := time.Date(2024, 4, 20, 0, 0, 0, 0, time.Local)
+	check := func(rule AlertRule, want []BudgetMonth) {
+		t.Helper()
+		months, err := db.GetBudgetMonths(rule, from, to, categorize)
+		if err != nil {
+			t.Fatalf("GetBudgetMonths failed: %v", err)
+		}
+		if len(months) != len(want) {
+			t.Fatalf("expected %d months, got %+v", len(want), months)
+		}
+		for i, m := range months {
+			w := want[i]
+			if !m.Month.Equal(w.Month) || m.Budget != w.Budget || m.Carried != w.Carried || m.Spent != w.Spent {
+				t.Errorf("month %d: expected %+v, got %+v", i, w, m)
+			}
+		}
+	}
+	month := func(m time.Month) time.Time { return time.Date(2024, m, 1, 0, 0, 0, 0, time.Local) }
+
+	// January's 400 left are carried over to February, which is overspent
+	check(rule, []BudgetMonth{
+		{Month: month(2), Budget: 1000, Carried: 400, Spent: 1300},
+		{Month: month(3), Budget: 1000, Carried: 100, Spent: 0},
+		{Month: month(4), Budget: 1000, Carried: 1100, Spent: 0},
+	})
+
+	// Nothing is carried over into the month the budget was created in
+	rule.CreatedAt = month(2)
+	check(rule, []BudgetMonth{
+		{Month: month(2), Budget: 1000, Carried: 0, Spent: 1300},
+		{Month: month(3), Budget: 1000, Carried: 0, Spent: 0},
+		{Month: month(4), Budget: 1000, Carried: 1000, Spent: 0},
+	})
+
+	// Without rollover or a category, every month starts afresh and counts all spending
+	rule.Rollover, rule.Category = false, ""
+	check(rule, []BudgetMonth{
+		{Month: month(2), Budget: 1000, Spent: 6300},
+		{Month: month(3), Budget: 1000, Spent: 0},
+		{Month: month(4), Budget: 1000, Spent: 0},
+	})
+
+	if _, err := db.GetBudgetMonths(AlertRule{Kind: AlertBudget, Category: "groceries"}, from, to, nil); err == nil {
+		t.Error("expected an error for a category budget without a categorizer")
+	}
+}
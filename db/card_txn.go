@@ -8,44 +8,85 @@ import (
 	"github.com/ivan4th/ameriagrab/client"
 )
 
+// cardTxnInsertSQL inserts a card or linked account transaction (table is substituted), ignoring duplicates
+const cardTxnInsertSQL = `
+	INSERT OR IGNORE INTO %s (
+		id, product_id, transaction_type, accounting_type, state,
+		amount_currency, amount_value, correspondent_account_number,
+		correspondent_account_name, details, operation_date,
+		workflow_code, date, year, month, synced_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+// cardTxnArgs returns the arguments of cardTxnInsertSQL
+func cardTxnArgs(productID string, t client.Transaction, syncedAt int64) []interface{} {
+	return []interface{}{
+		t.ID,
+		productID,
+		t.TransactionType,
+		t.AccountingType,
+		t.State,
+		t.Amount.Currency,
+		t.Amount.Amount,
+		t.CorrespondentAccountNumber,
+		t.CorrespondentAccountName,
+		t.Details,
+		NormalizeDate(t.OperationDate),
+		t.WorkflowCode,
+		t.Date,
+		t.Year,
+		t.Month,
+		syncedAt,
+	}
+}
+
+// cardTxnWhere selects a stored card or linked account transaction
+const cardTxnWhere = "id = ? AND operation_date = ?"
+
+// cardTxnMutableColumns are the fields of a stored card or linked account
+// transaction that the bank may change later (e.g. the state once settled)
+var cardTxnMutableColumns = []string{
+	"transaction_type", "accounting_type", "state", "amount_currency", "amount_value",
+	"correspondent_account_number", "correspondent_account_name", "details", "workflow_code",
+}
+
+// cardTxnMutableValues returns the values of cardTxnMutableColumns
+func cardTxnMutableValues(t client.Transaction) []interface{} {
+	return []interface{}{
+		t.TransactionType, t.AccountingType, t.State, t.Amount.Currency, t.Amount.Amount,
+		t.CorrespondentAccountNumber, t.CorrespondentAccountName, t.Details, t.WorkflowCode,
+	}
+}
+
 // InsertCardTransactions inserts card transactions (from GetTransactions), ignoring duplicates
 func (db *DB) InsertCardTransactions(productID string, txns []client.Transaction) (int, error) {
+	return db.insertCardTransactions("card_transactions", productID, txns)
+}
+
+// ApplyCardTransactions inserts new card transactions and updates the changed fields
+// of stored ones, recording the changes (see GetTransactionChanges).
+// Returns the number of inserted and changed transactions.
+func (db *DB) ApplyCardTransactions(productID string, txns []client.Transaction) (inserted, changed int, err error) {
+	return db.applyCardTransactions("card_transactions", SourceCard, productID, txns)
+}
+
+// insertCardTransactions inserts card or linked account transactions into table, ignoring duplicates
+func (db *DB) insertCardTransactions(table, productID string, txns []client.Transaction) (int, error) {
 	syncedAt := time.Now().Unix()
 	var inserted int
 
+	insertSQL := fmt.Sprintf(cardTxnInsertSQL, table)
+	if err := db.prepare(insertSQL); err != nil {
+		return 0, err
+	}
 	err := db.WithTransaction(func(tx *sql.Tx) error {
-		stmt, err := tx.Prepare(`
-			INSERT OR IGNORE INTO card_transactions (
-				id, product_id, transaction_type, accounting_type, state,
-				amount_currency, amount_value, correspondent_account_number,
-				correspondent_account_name, details, operation_date,
-				workflow_code, date, year, month, synced_at
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		`)
+		stmt, err := db.txStmt(tx, insertSQL)
 		if err != nil {
-			return fmt.Errorf("failed to prepare statement: %w", err)
+			return err
 		}
-		defer stmt.Close()
 
 		for _, t := range txns {
-			result, err := stmt.Exec(
-				t.ID,
-				productID,
-				t.TransactionType,
-				t.AccountingType,
-				t.State,
-				t.Amount.Currency,
-				t.Amount.Amount,
-				t.CorrespondentAccountNumber,
-				t.CorrespondentAccountName,
-				t.Details,
-				t.OperationDate,
-				t.WorkflowCode,
-				t.Date,
-				t.Year,
-				t.Month,
-				syncedAt,
-			)
+			result, err := stmt.Exec(cardTxnArgs(productID, t, syncedAt)...)
 			if err != nil {
 				return fmt.Errorf("failed to insert transaction %s: %w", t.ID, err)
 			}
@@ -60,52 +101,117 @@ func (db *DB) InsertCardTransactions(productID string, txns []client.Transaction
 	return inserted, err
 }
 
-// GetCardTransactions retrieves card transactions for a product with optional pagination.
-// If size is 0, returns all transactions. Otherwise returns up to size transactions starting at page.
-// If ascending is true, returns oldest first; otherwise newest first.
-func (db *DB) GetCardTransactions(productID string, size, page int, ascending bool) ([]client.Transaction, error) {
-	var rows *sql.Rows
-	var err error
+// applyCardTransactions inserts card or linked account transactions into table,
+// updating and recording the changed fields of stored ones
+func (db *DB) applyCardTransactions(table, source, productID string, txns []client.Transaction) (inserted, changed int, err error) {
+	syncedAt := time.Now().Unix()
+
+	insertSQL := fmt.Sprintf(cardTxnInsertSQL, table)
+	if err := db.prepare(insertSQL); err != nil {
+		return 0, 0, err
+	}
+	if err := db.prepareTxnChanges(table, cardTxnWhere, cardTxnMutableColumns); err != nil {
+		return 0, 0, err
+	}
+	err = db.WithTransaction(func(tx *sql.Tx) error {
+		stmt, err := db.txStmt(tx, insertSQL)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range txns {
+			result, err := stmt.Exec(cardTxnArgs(productID, t, syncedAt)...)
+			if err != nil {
+				return fmt.Errorf("failed to insert transaction %s: %w", t.ID, err)
+			}
+			if rowsAffected, _ := result.RowsAffected(); rowsAffected > 0 {
+				inserted++
+				continue
+			}
+			row := txnRow{
+				table:         table,
+				source:        source,
+				productID:     productID,
+				id:            t.ID,
+				operationDate: NormalizeDate(t.OperationDate),
+				where:         cardTxnWhere,
+				whereArgs:     []interface{}{t.ID, NormalizeDate(t.OperationDate)},
+			}
+			updated, err := db.applyTxnChanges(tx, row, cardTxnMutableColumns, cardTxnMutableValues(t), syncedAt)
+			if err != nil {
+				return err
+			}
+			if updated {
+				changed++
+			}
+		}
+		return nil
+	})
+
+	return inserted, changed, err
+}
 
+// ReadOptions selects and orders the transactions read by the ForEach functions
+type ReadOptions struct {
+	Size            int  // transactions per page, 0 = all
+	Page            int  // page number, starting at 0 (with Size)
+	Ascending       bool // oldest first; newest first otherwise
+	IncludeExtended bool // linked account transactions: load the stored extended info
+	CollapseStages  bool // card transactions: leave out authorizations linked to their settlement
+}
+
+// orderBy returns the ORDER BY and LIMIT clauses for a table ordered by dateCol
+func (opts ReadOptions) orderBy(dateCol string) (string, []interface{}) {
 	order := "DESC"
-	if ascending {
+	if opts.Ascending {
 		order = "ASC"
 	}
+	clause := fmt.Sprintf("ORDER BY %[1]s %[2]s, id %[2]s, rowid %[2]s", dateCol, order)
+	if opts.Size == 0 {
+		return clause, nil
+	}
+	return clause + " LIMIT ? OFFSET ?", []interface{}{opts.Size, opts.Page * opts.Size}
+}
+
+// GetCardTransactions retrieves card transactions for a product with optional pagination.
+// If size is 0, returns all transactions. Otherwise returns up to size transactions starting at page.
+// If ascending is true, returns oldest first; otherwise newest first.
+func (db *DB) GetCardTransactions(productID string, size, page int, ascending bool) ([]client.Transaction, error) {
+	var txns []client.Transaction
+	err := db.ForEachCardTransaction(productID, ReadOptions{Size: size, Page: page, Ascending: ascending}, func(t client.Transaction) error {
+		txns = append(txns, t)
+		return nil
+	})
+	return txns, err
+}
 
-	if size == 0 {
-		// No limit - return all
-		rows, err = db.Query(fmt.Sprintf(`
-			SELECT id, transaction_type, accounting_type, state,
-				   amount_currency, amount_value, correspondent_account_number,
-				   correspondent_account_name, details, operation_date,
-				   workflow_code, date, year, month
-			FROM card_transactions
-			WHERE product_id = ?
-			ORDER BY operation_date %s
-		`, order), productID)
-	} else {
-		// Paginated query
-		offset := page * size
-		rows, err = db.Query(fmt.Sprintf(`
-			SELECT id, transaction_type, accounting_type, state,
-				   amount_currency, amount_value, correspondent_account_number,
-				   correspondent_account_name, details, operation_date,
-				   workflow_code, date, year, month
-			FROM card_transactions
-			WHERE product_id = ?
-			ORDER BY operation_date %s
-			LIMIT ? OFFSET ?
-		`, order), productID, size, offset)
+// ForEachCardTransaction calls fn for each card transaction of a product as
+// the rows are read, without loading them all into memory. An error returned
+// by fn stops the iteration and is returned. fn runs while the query holds its
+// database connection, so it must not use the database itself.
+func (db *DB) ForEachCardTransaction(productID string, opts ReadOptions, fn func(client.Transaction) error) error {
+	orderBy, limitArgs := opts.orderBy("operation_date")
+	where := "product_id = ?"
+	if opts.CollapseStages {
+		where += " AND NOT EXISTS (" + settlementSQL + ")"
 	}
+	rows, err := db.query(`
+		SELECT id, transaction_type, accounting_type, state,
+			   amount_currency, amount_value, correspondent_account_number,
+			   correspondent_account_name, details, operation_date,
+			   workflow_code, date, year, month,
+			   authorization_id, (`+settlementSQL+` LIMIT 1)
+		FROM card_transactions t
+		WHERE `+where+`
+		`+orderBy, append([]interface{}{productID}, limitArgs...)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query card transactions: %w", err)
+		return fmt.Errorf("failed to query card transactions: %w", err)
 	}
 	defer rows.Close()
 
-	var txns []client.Transaction
 	for rows.Next() {
 		var t client.Transaction
-		var currency sql.NullString
+		var currency, authorizationID, settlementID sql.NullString
 		var amount sql.NullFloat64
 
 		err := rows.Scan(
@@ -123,34 +229,41 @@ func (db *DB) GetCardTransactions(productID string, size, page int, ascending bo
 			&t.Date,
 			&t.Year,
 			&t.Month,
+			&authorizationID,
+			&settlementID,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+			return fmt.Errorf("failed to scan transaction: %w", err)
 		}
 
 		t.Amount = client.Amount{
 			Currency: currency.String,
 			Amount:   amount.Float64,
 		}
+		t.AuthorizationID = authorizationID.String
+		t.SettlementID = settlementID.String
 
-		txns = append(txns, t)
+		if err := fn(t); err != nil {
+			return err
+		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating transactions: %w", err)
+		return fmt.Errorf("error iterating transactions: %w", err)
 	}
 
-	return txns, nil
+	return nil
 }
 
-// TxnKey creates a composite key from transaction ID and operation date
+// TxnKey creates a composite key from transaction ID and operation date,
+// normalized as when stored
 func TxnKey(id, operationDate string) string {
-	return id + "|" + operationDate
+	return id + "|" + NormalizeDate(operationDate)
 }
 
 // GetExistingCardTxnKeys returns a set of existing transaction keys (id|operation_date) for a product
 func (db *DB) GetExistingCardTxnKeys(productID string) (map[string]bool, error) {
-	rows, err := db.Query(`
+	rows, err := db.query(`
 		SELECT id, operation_date FROM card_transactions WHERE product_id = ?
 	`, productID)
 	if err != nil {
@@ -177,7 +290,7 @@ func (db *DB) GetExistingCardTxnKeys(productID string) (map[string]bool, error)
 // CountCardTransactions returns the total count of card transactions for a product
 func (db *DB) CountCardTransactions(productID string) (int, error) {
 	var count int
-	err := db.QueryRow(`
+	err := db.queryRow(`
 		SELECT COUNT(*) FROM card_transactions WHERE product_id = ?
 	`, productID).Scan(&count)
 	if err != nil {
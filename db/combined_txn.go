@@ -2,8 +2,11 @@ package db
 
 import (
 	"fmt"
+	"math"
 	"sort"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/ivan4th/ameriagrab/client"
 )
@@ -17,12 +20,48 @@ var RealTimeTransactionTypes = map[string]bool{
 	"transfer:between-own-accounts": true,
 }
 
+// Default matching tolerances, used when MatchOptions leaves them unset
+const (
+	DefaultTimeTolerance = time.Minute
+	DefaultFXTolerance   = 0.03
+)
+
+// MatchOptions configures how card transactions are matched to linked
+// account transactions
+type MatchOptions struct {
+	// TimeTolerance is the largest time difference between a card
+	// transaction and its match (default DefaultTimeTolerance)
+	TimeTolerance time.Duration
+	// FXTolerance is the largest relative difference between amounts in
+	// different currencies after converting both to LocalCurrency, which
+	// covers the bank's FX margin and rate changes (default DefaultFXTolerance)
+	FXTolerance float64
+	// Rates converts amounts in different currencies. Without a rate for
+	// both currencies, transactions in different currencies don't match.
+	Rates ExchangeRates
+}
+
+func (o MatchOptions) timeTolerance() time.Duration {
+	if o.TimeTolerance > 0 {
+		return o.TimeTolerance
+	}
+	return DefaultTimeTolerance
+}
+
+func (o MatchOptions) fxTolerance() float64 {
+	if o.FXTolerance > 0 {
+		return o.FXTolerance
+	}
+	return DefaultFXTolerance
+}
+
 // CombinedTransactionsOptions configures GetCombinedTransactions behavior
 type CombinedTransactionsOptions struct {
 	Size            int
 	Page            int
 	IncludeExtended bool
 	Ascending       bool
+	Match           MatchOptions
 }
 
 // GetCombinedTransactions merges card and linked account transactions.
@@ -42,7 +81,7 @@ func (db *DB) GetCombinedTransactions(productID string, opts CombinedTransaction
 	}
 
 	// Merge transactions
-	combined := mergeTransactions(cardTxns, linkedTxns)
+	combined := mergeTransactions(cardTxns, linkedTxns, opts.Match)
 
 	// Sort by operation_date
 	sortTransactions(combined, opts.Ascending)
@@ -67,51 +106,172 @@ func (db *DB) GetCombinedTransactions(productID string, opts CombinedTransaction
 	return combined, totalCount, nil
 }
 
-// mergeTransactions implements the matching algorithm
-func mergeTransactions(cardTxns, linkedTxns []client.Transaction) []client.Transaction {
-	const timeTolerance = time.Minute
+// AttachCounterparties sets the extended info (beneficiary, card, SWIFT
+// details) of card transactions from the stored linked account transactions
+// they match, so that counterparties show without refetching them. Card
+// transactions without a match, or whose match has no extended info, are left as is.
+func (db *DB) AttachCounterparties(productID string, cardTxns []client.Transaction, opts MatchOptions) error {
+	linkedTxns, err := db.GetLinkedAccountTransactions(productID, 0, 0, true, false)
+	if err != nil {
+		return fmt.Errorf("fetching linked account transactions: %w", err)
+	}
+	m := newTxnMatcher(linkedTxns, opts)
+	for i := range cardTxns {
+		if matched := m.match(cardTxns[i]); matched != nil && matched.Extended != nil {
+			cardTxns[i].Extended = matched.Extended
+		}
+	}
+	return nil
+}
 
-	// Build lookup map for linked transactions by amount
-	linkedByAmount := make(map[float64][]*client.Transaction)
+// txnMatcher matches card transactions to linked account transactions.
+// Each linked transaction is matched at most once.
+type txnMatcher struct {
+	opts   MatchOptions
+	linked []linkedCandidate // sorted by time
+}
+
+type linkedCandidate struct {
+	txn  *client.Transaction
+	time time.Time
+	used bool
+}
+
+func newTxnMatcher(linkedTxns []client.Transaction, opts MatchOptions) *txnMatcher {
+	m := &txnMatcher{opts: opts}
 	for i := range linkedTxns {
-		amt := linkedTxns[i].Amount.Amount
-		linkedByAmount[amt] = append(linkedByAmount[amt], &linkedTxns[i])
+		t, err := ParseDate(linkedTxns[i].OperationDate)
+		if err != nil {
+			continue
+		}
+		m.linked = append(m.linked, linkedCandidate{txn: &linkedTxns[i], time: t})
 	}
+	sort.SliceStable(m.linked, func(i, j int) bool { return m.linked[i].time.Before(m.linked[j].time) })
+	return m
+}
 
-	matchedLinked := make(map[string]bool) // key: id|operationDate
-	var result []client.Transaction
+// match returns the best unused linked account transaction for a card
+// transaction within the time tolerance, or nil. Amounts in the same
+// currency must be equal; amounts in different currencies must be within
+// the FX tolerance once converted to LocalCurrency. Same-currency matches
+// win over FX ones, then the more similar correspondent name, then the
+// closer time.
+func (m *txnMatcher) match(cardTxn client.Transaction) *client.Transaction {
+	cardTime, err := ParseDate(cardTxn.OperationDate)
+	if err != nil {
+		return nil
+	}
+	tolerance := m.opts.timeTolerance()
+	first := sort.Search(len(m.linked), func(i int) bool {
+		return !m.linked[i].time.Before(cardTime.Add(-tolerance))
+	})
 
-	// Match card transactions to linked account transactions
-	for _, cardTxn := range cardTxns {
-		cardTime, err := time.Parse(time.RFC3339, cardTxn.OperationDate)
-		if err != nil {
-			// Can't parse time, keep card transaction as-is
-			result = append(result, cardTxn)
+	var best *linkedCandidate
+	var bestScore matchScore
+	for i := first; i < len(m.linked) && !m.linked[i].time.After(cardTime.Add(tolerance)); i++ {
+		c := &m.linked[i]
+		if c.used {
 			continue
 		}
+		score, ok := m.score(cardTxn, cardTime, c)
+		if ok && (best == nil || score.better(bestScore)) {
+			best, bestScore = c, score
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	best.used = true
+	return best.txn
+}
 
-		candidates := linkedByAmount[cardTxn.Amount.Amount]
-		var matched *client.Transaction
-		var minDiff time.Duration = timeTolerance + 1
+// matchScore ranks candidate matches of a card transaction
+type matchScore struct {
+	fx         bool
+	similarity float64
+	timeDiff   time.Duration
+}
 
-		for _, linked := range candidates {
-			linkedTime, err := time.Parse(time.RFC3339, linked.OperationDate)
-			if err != nil {
-				continue
-			}
+func (s matchScore) better(other matchScore) bool {
+	if s.fx != other.fx {
+		return !s.fx
+	}
+	if s.similarity != other.similarity {
+		return s.similarity > other.similarity
+	}
+	return s.timeDiff < other.timeDiff
+}
 
-			diff := cardTime.Sub(linkedTime)
-			if diff < 0 {
-				diff = -diff
-			}
+func (m *txnMatcher) score(cardTxn client.Transaction, cardTime time.Time, c *linkedCandidate) (matchScore, bool) {
+	score := matchScore{
+		similarity: nameSimilarity(cardTxn, *c.txn),
+		timeDiff:   cardTime.Sub(c.time).Abs(),
+	}
+	cardAmount, linkedAmount := cardTxn.Amount, c.txn.Amount
+	if strings.EqualFold(cardAmount.Currency, linkedAmount.Currency) || cardAmount.Currency == "" || linkedAmount.Currency == "" {
+		return score, math.Abs(cardAmount.Amount-linkedAmount.Amount) < 0.005
+	}
 
-			if diff <= timeTolerance && diff < minDiff {
-				matched = linked
-				minDiff = diff
-			}
+	score.fx = true
+	cardLocal, ok := m.opts.Rates.Convert(cardAmount.Amount, cardAmount.Currency, LocalCurrency)
+	if !ok {
+		return score, false
+	}
+	linkedLocal, ok := m.opts.Rates.Convert(linkedAmount.Amount, linkedAmount.Currency, LocalCurrency)
+	if !ok || linkedLocal == 0 || math.Signbit(cardLocal) != math.Signbit(linkedLocal) {
+		return score, false
+	}
+	return score, math.Abs(cardLocal-linkedLocal)/math.Abs(linkedLocal) <= m.opts.fxTolerance()
+}
+
+// nameSimilarity returns the share of words of the shorter correspondent
+// name (or details, when there's no name) found in the other one, from 0 to 1
+func nameSimilarity(a, b client.Transaction) float64 {
+	wordsA, wordsB := nameWords(a), nameWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+	if len(wordsA) > len(wordsB) {
+		wordsA, wordsB = wordsB, wordsA
+	}
+	common := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(wordsA))
+}
+
+// nameWords returns the uppercased words of at least 3 letters or digits
+// in a transaction's correspondent name, or in its details
+func nameWords(txn client.Transaction) map[string]bool {
+	name := txn.CorrespondentAccountName
+	if strings.TrimSpace(name) == "" {
+		name = txn.Details
+	}
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(w)) >= 3 {
+			words[w] = true
 		}
+	}
+	return words
+}
 
-		if matched != nil {
+// mergeTransactions implements the matching algorithm
+func mergeTransactions(cardTxns, linkedTxns []client.Transaction, opts MatchOptions) []client.Transaction {
+	m := newTxnMatcher(linkedTxns, opts)
+
+	matchedLinked := make(map[string]bool) // key: id|operationDate
+	var result []client.Transaction
+
+	// Match card transactions to linked account transactions; card
+	// transactions whose time can't be parsed are kept as they are
+	for _, cardTxn := range cardTxns {
+		if matched := m.match(cardTxn); matched != nil {
 			matchedLinked[TxnKey(matched.ID, matched.OperationDate)] = true
 			result = append(result, *matched)
 		} else {
@@ -141,14 +301,21 @@ func mergeTransactions(cardTxns, linkedTxns []client.Transaction) []client.Trans
 	return result
 }
 
-// sortTransactions sorts by operation_date
+// sortTransactions sorts by operation_date, then by ID, so that transactions
+// sharing a timestamp always come out in the same order
 func sortTransactions(txns []client.Transaction, ascending bool) {
-	sort.Slice(txns, func(i, j int) bool {
-		ti, _ := time.Parse(time.RFC3339, txns[i].OperationDate)
-		tj, _ := time.Parse(time.RFC3339, txns[j].OperationDate)
+	sort.SliceStable(txns, func(i, j int) bool {
+		ti, _ := ParseDate(txns[i].OperationDate)
+		tj, _ := ParseDate(txns[j].OperationDate)
+		if !ti.Equal(tj) {
+			if ascending {
+				return ti.Before(tj)
+			}
+			return ti.After(tj)
+		}
 		if ascending {
-			return ti.Before(tj)
+			return txns[i].ID < txns[j].ID
 		}
-		return ti.After(tj)
+		return txns[i].ID > txns[j].ID
 	})
 }
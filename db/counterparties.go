@@ -0,0 +1,284 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// Counterparty is someone outgoing transfers went to, with the transfers in
+// one currency aggregated
+type Counterparty struct {
+	Key       string    `json:"key"` // card key, account number or lowercased name, prefixed by its kind
+	Name      string    `json:"name,omitempty"`
+	Number    string    `json:"number,omitempty"` // masked card or account number
+	Currency  string    `json:"currency"`
+	Total     float64   `json:"total"`
+	Count     int       `json:"count"`
+	FirstDate time.Time `json:"first_date"`
+	LastDate  time.Time `json:"last_date"`
+}
+
+// Counterparty sort orders
+const (
+	CounterpartySortVolume = "volume" // largest total first
+	CounterpartySortRecent = "recent" // most recent transfer first
+	CounterpartySortName   = "name"
+)
+
+// CounterpartyOptions selects counterparties
+type CounterpartyOptions struct {
+	Search string // case-insensitive substring of the name or number, empty = all
+	Sort   string // CounterpartySortVolume (default), CounterpartySortRecent or CounterpartySortName
+	Limit  int    // 0 = no limit
+}
+
+// counterpartyKey identifies the counterparty of a transfer: by card (first 4
+// and last 3 digits, as masks differ), by account number, or by name if the
+// transfer has no number. Empty if the transfer has neither.
+func counterpartyKey(name, number string) string {
+	if strings.Contains(number, "*") {
+		if cardKey := extractCardKey(number); cardKey != "" {
+			return "card:" + cardKey
+		}
+	}
+	if number != "" {
+		return "account:" + number
+	}
+	if name != "" && name != PlaceholderBeneficiary {
+		return "name:" + strings.ToLower(name)
+	}
+	return ""
+}
+
+// transfer is an outgoing transfer read for the counterparty directory
+type transfer struct {
+	name, number, currency string
+	amount                 float64
+	date                   time.Time
+}
+
+// outgoingTransfers reads the debits of linked account histories and the
+// expenses of account histories. Card events are left out: they duplicate
+// the linked account history and name merchants.
+func (db *DB) outgoingTransfers() ([]transfer, error) {
+	var transfers []transfer
+
+	rows, err := db.Query(`
+		SELECT operation_date, accounting_type, amount_value, amount_currency,
+			   beneficiary_name, COALESCE(NULLIF(card_masked_number, ''), credit_account_number)
+		FROM card_linked_account_transactions`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query card_linked_account_transactions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var operationDate string
+		var accountingType, currency, name, number sql.NullString
+		var amount sql.NullFloat64
+		if err := rows.Scan(&operationDate, &accountingType, &amount, &currency, &name, &number); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		if client.DirectionOfAccountingType(accountingType.String) != client.DirectionDebit {
+			continue
+		}
+		date, _ := ParseDate(operationDate)
+		transfers = append(transfers, transfer{name.String, number.String, currency.String, amount.Float64, date})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+	rows.Close()
+
+	rows, err = db.Query(`
+		SELECT transaction_date, flow_direction, transaction_amount_value, transaction_amount_currency,
+			   beneficiary_name, credit_account_number
+		FROM account_transactions`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query account_transactions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var txnDate sql.NullInt64
+		var flowDirection, currency, name, number sql.NullString
+		var amount sql.NullFloat64
+		if err := rows.Scan(&txnDate, &flowDirection, &amount, &currency, &name, &number); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		if client.DirectionOfFlow(flowDirection.String) != client.DirectionDebit {
+			continue
+		}
+		var date time.Time
+		if txnDate.Int64 > 0 {
+			date = time.UnixMilli(txnDate.Int64)
+		}
+		transfers = append(transfers, transfer{name.String, number.String, currency.String, amount.Float64, date})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+
+	return transfers, nil
+}
+
+// RebuildCounterparties replaces the counterparty directory with one
+// aggregated from the stored transaction history, returning the number of
+// counterparties. A counterparty is named by its latest transfer with a real
+// beneficiary name, or else by the transfer template matching its number.
+func (db *DB) RebuildCounterparties() (int, error) {
+	transfers, err := db.outgoingTransfers()
+	if err != nil {
+		return 0, err
+	}
+	sort.SliceStable(transfers, func(i, j int) bool { return transfers[i].date.Before(transfers[j].date) })
+
+	type aggKey struct{ key, currency string }
+	byKey := make(map[aggKey]*Counterparty)
+	var counterparties []*Counterparty
+	for _, t := range transfers {
+		key := counterpartyKey(t.name, t.number)
+		if key == "" {
+			continue
+		}
+		cp, ok := byKey[aggKey{key, t.currency}]
+		if !ok {
+			cp = &Counterparty{Key: key, Currency: t.currency, FirstDate: t.date}
+			byKey[aggKey{key, t.currency}] = cp
+			counterparties = append(counterparties, cp)
+		}
+		cp.Total = money.Add(cp.Total, t.amount)
+		cp.Count++
+		cp.LastDate = t.date
+		if t.number != "" {
+			cp.Number = t.number
+		}
+		if t.name != "" && t.name != PlaceholderBeneficiary {
+			cp.Name = t.name
+		}
+	}
+
+	for _, cp := range counterparties {
+		if cp.Name != "" {
+			continue
+		}
+		name, group, err := db.GetTemplateWithGroupByCounterparty(cp.Number)
+		if err != nil {
+			return 0, err
+		}
+		cp.Name = TemplateLabel(name, group)
+	}
+
+	now := time.Now().Unix()
+	err = db.WithTransaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM counterparties"); err != nil {
+			return fmt.Errorf("failed to clear counterparties: %w", err)
+		}
+		stmt, err := tx.Prepare(`
+			INSERT INTO counterparties (key, currency, name, number, total, count, first_date, last_date, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+		for _, cp := range counterparties {
+			_, err := stmt.Exec(cp.Key, cp.Currency, cp.Name, cp.Number, cp.Total, cp.Count,
+				formatCounterpartyDate(cp.FirstDate), formatCounterpartyDate(cp.LastDate), now)
+			if err != nil {
+				return fmt.Errorf("failed to insert counterparty %s: %w", cp.Key, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(counterparties), nil
+}
+
+// formatCounterpartyDate formats a transfer date as stored, empty if unknown
+func formatCounterpartyDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// GetCounterparties returns counterparties from the directory built by
+// RebuildCounterparties
+func (db *DB) GetCounterparties(opts CounterpartyOptions) ([]Counterparty, error) {
+	query := `
+		SELECT key, currency, name, number, total, count, first_date, last_date
+		FROM counterparties WHERE 1 = 1`
+	var args []interface{}
+	if opts.Search != "" {
+		query += " AND (name LIKE ? OR number LIKE ?)"
+		pattern := "%" + opts.Search + "%"
+		args = append(args, pattern, pattern)
+	}
+	switch opts.Sort {
+	case "", CounterpartySortVolume:
+		query += " ORDER BY total DESC, key, currency"
+	case CounterpartySortRecent:
+		query += " ORDER BY last_date DESC, key, currency"
+	case CounterpartySortName:
+		query += " ORDER BY name COLLATE NOCASE, key, currency"
+	default:
+		return nil, fmt.Errorf("unknown counterparty sort order %q", opts.Sort)
+	}
+	if opts.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query counterparties: %w", err)
+	}
+	defer rows.Close()
+
+	var counterparties []Counterparty
+	for rows.Next() {
+		var cp Counterparty
+		var name, number, firstDate, lastDate sql.NullString
+		if err := rows.Scan(&cp.Key, &cp.Currency, &name, &number, &cp.Total, &cp.Count, &firstDate, &lastDate); err != nil {
+			return nil, fmt.Errorf("failed to scan counterparty: %w", err)
+		}
+		cp.Name = name.String
+		cp.Number = number.String
+		cp.FirstDate, _ = time.Parse(time.RFC3339, firstDate.String)
+		cp.LastDate, _ = time.Parse(time.RFC3339, lastDate.String)
+		counterparties = append(counterparties, cp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating counterparties: %w", err)
+	}
+
+	return counterparties, nil
+}
+
+// CounterpartyName returns the name the directory has for a masked card or
+// account number, empty if it has none
+func (db *DB) CounterpartyName(number string) (string, error) {
+	key := counterpartyKey("", number)
+	if key == "" {
+		return "", nil
+	}
+	var name string
+	err := db.queryRow(
+		"SELECT name FROM counterparties WHERE key = ? AND name != '' ORDER BY last_date DESC LIMIT 1",
+		key,
+	).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up counterparty: %w", err)
+	}
+	return name, nil
+}
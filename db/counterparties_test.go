@@ -0,0 +1,110 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestRebuildCounterparties(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	landlord := makeTemplate("id1", "Landlord", "1570000000000001", "ACCOUNT", "")
+	landlord.GroupName = "Household"
+	if err := db.UpsertTemplates([]client.TransferTemplate{landlord}); err != nil {
+		t.Fatalf("UpsertTemplates failed: %v", err)
+	}
+
+	linked := []client.Transaction{
+		{ID: "l1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 1000}, OperationDate: "2024-01-01T10:00:00Z"},
+		{ID: "l2", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 500}, OperationDate: "2024-02-01T10:00:00Z"},
+		{ID: "l3", AccountingType: "CREDIT", Amount: client.Amount{Currency: "AMD", Amount: 700}, OperationDate: "2024-02-02T10:00:00Z"},
+		{ID: "l4", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 300}, OperationDate: "2024-02-03T10:00:00Z"},
+	}
+	if _, err := db.InsertLinkedAccountTransactions("card1", linked); err != nil {
+		t.Fatalf("InsertLinkedAccountTransactions failed: %v", err)
+	}
+	// Masks of the same card differ between transfers; the later transfer
+	// shows the placeholder name
+	extended := map[string]*client.TransactionExtendedInfo{
+		"l1": {BeneficiaryName: "Alice A", CardMaskedNumber: "4454********6615"},
+		"l2": {BeneficiaryName: PlaceholderBeneficiary, CardMaskedNumber: "44543********615"},
+		"l3": {BeneficiaryName: "Alice A", CardMaskedNumber: "4454********6615"},
+	}
+	for _, txn := range linked {
+		if ext, ok := extended[txn.ID]; ok {
+			if err := db.UpdateTransactionExtendedInfo("card1", txn.ID, txn.OperationDate, ext); err != nil {
+				t.Fatalf("UpdateTransactionExtendedInfo failed: %v", err)
+			}
+		}
+	}
+
+	accountTxns := []client.AccountTransaction{
+		{ID: "a1", FlowDirection: "EXPENSE", CreditAccountNumber: "1570000000000001", TransactionDate: 1704067200000,
+			TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 100.1}},
+		{ID: "a2", FlowDirection: "EXPENSE", CreditAccountNumber: "1570000000000001", TransactionDate: 1706832000000,
+			TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 200.2}},
+		{ID: "a3", FlowDirection: "INCOME", DebitAccountNumber: "1570000000000009", TransactionDate: 1706745600000,
+			TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 5000}},
+		{ID: "a4", FlowDirection: "EXPENSE", CreditAccountNumber: "1570000000000001", TransactionDate: 1706745600000,
+			TransactionAmount: client.TransactionAmt{Currency: "USD", Value: 10}},
+	}
+	if _, err := db.InsertAccountTransactions("acc1", accountTxns); err != nil {
+		t.Fatalf("InsertAccountTransactions failed: %v", err)
+	}
+
+	// Rebuilding twice gives the same directory
+	for range 2 {
+		n, err := db.RebuildCounterparties()
+		if err != nil {
+			t.Fatalf("RebuildCounterparties failed: %v", err)
+		}
+		if n != 3 {
+			t.Fatalf("expected 3 counterparties, got %d", n)
+		}
+	}
+
+	cps, err := db.GetCounterparties(CounterpartyOptions{})
+	if err != nil {
+		t.Fatalf("GetCounterparties failed: %v", err)
+	}
+	if len(cps) != 3 {
+		t.Fatalf("expected 3 counterparties, got %+v", cps)
+	}
+	alice := cps[0]
+	if alice.Key != "card:4454615" || alice.Name != "Alice A" || alice.Number != "44543********615" ||
+		alice.Total != 1500 || alice.Count != 2 || alice.LastDate.Format("2006-01-02") != "2024-02-01" {
+		t.Errorf("unexpected card counterparty: %+v", alice)
+	}
+	landlordAMD := cps[1]
+	if landlordAMD.Name != "Landlord [Household]" || landlordAMD.Currency != "AMD" || landlordAMD.Total != 300.3 ||
+		landlordAMD.Count != 2 || landlordAMD.FirstDate.Format("2006-01-02") != "2024-01-01" {
+		t.Errorf("unexpected account counterparty: %+v", landlordAMD)
+	}
+	if cps[2].Key != "account:1570000000000001" || cps[2].Currency != "USD" {
+		t.Errorf("expected the USD transfers counted separately, got %+v", cps[2])
+	}
+
+	cps, err = db.GetCounterparties(CounterpartyOptions{Search: "alice"})
+	if err != nil || len(cps) != 1 || cps[0].Name != "Alice A" {
+		t.Errorf("expected Alice by search, got %+v, %v", cps, err)
+	}
+	cps, err = db.GetCounterparties(CounterpartyOptions{Sort: CounterpartySortRecent, Limit: 1})
+	if err != nil || len(cps) != 1 || cps[0].Name != "Landlord [Household]" {
+		t.Errorf("expected the most recent counterparty, got %+v, %v", cps, err)
+	}
+	if _, err := db.GetCounterparties(CounterpartyOptions{Sort: "bogus"}); err == nil {
+		t.Error("expected an error for an unknown sort order")
+	}
+
+	if name, err := db.CounterpartyName("4454********6615"); err != nil || name != "Alice A" {
+		t.Errorf("expected Alice by card number, got %q, %v", name, err)
+	}
+	if name, err := db.CounterpartyName("1570000000000009"); err != nil || name != "" {
+		t.Errorf("expected no name for an unknown account, got %q, %v", name, err)
+	}
+}
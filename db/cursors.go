@@ -0,0 +1,80 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetCursor returns the last consumed rowid per ledger source for a named cursor.
+// A cursor that was never saved yields an empty map (everything is new).
+func (db *DB) GetCursor(name string) (map[string]int64, error) {
+	rows, err := db.Query(`SELECT source, position FROM cursors WHERE name = ?`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cursor: %w", err)
+	}
+	defer rows.Close()
+
+	positions := make(map[string]int64)
+	for rows.Next() {
+		var source string
+		var position int64
+		if err := rows.Scan(&source, &position); err != nil {
+			return nil, fmt.Errorf("failed to scan cursor: %w", err)
+		}
+		positions[source] = position
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cursor: %w", err)
+	}
+
+	return positions, nil
+}
+
+// SaveCursor stores the last consumed rowid per ledger source for a named cursor
+func (db *DB) SaveCursor(name string, positions map[string]int64) error {
+	updatedAt := time.Now().Unix()
+
+	return db.WithTransaction(func(tx *sql.Tx) error {
+		for source, position := range positions {
+			_, err := tx.Exec(`
+				INSERT INTO cursors (name, source, position, updated_at) VALUES (?, ?, ?, ?)
+				ON CONFLICT(name, source) DO UPDATE SET
+					position = excluded.position,
+					updated_at = excluded.updated_at
+			`, name, source, position, updatedAt)
+			if err != nil {
+				return fmt.Errorf("failed to save cursor: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetNewLedgerEntries returns entries inserted since the cursor was last advanced,
+// along with the cursor positions after them. The caller saves the positions with
+// SaveCursor once the entries have been consumed.
+func (db *DB) GetNewLedgerEntries(cursor string, opts LedgerOptions) ([]LedgerEntry, map[string]int64, error) {
+	positions, err := db.GetCursor(cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts.AfterRowIDs = positions
+	entries, err := db.GetLedgerEntries(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next := make(map[string]int64, len(positions))
+	for source, position := range positions {
+		next[source] = position
+	}
+	for _, e := range entries {
+		if e.RowID > next[e.Source] {
+			next[e.Source] = e.RowID
+		}
+	}
+	return entries, next, nil
+}
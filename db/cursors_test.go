@@ -0,0 +1,58 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestGetNewLedgerEntries(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	insert := func(id string) {
+		t.Helper()
+		txns := []client.Transaction{{ID: id, AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 100}, OperationDate: "2024-01-10T10:00:00Z"}}
+		if _, err := db.InsertCardTransactions("card1", txns); err != nil {
+			t.Fatalf("failed to insert card transactions: %v", err)
+		}
+	}
+	consume := func(cursor string) []string {
+		t.Helper()
+		entries, next, err := db.GetNewLedgerEntries(cursor, LedgerOptions{})
+		if err != nil {
+			t.Fatalf("GetNewLedgerEntries failed: %v", err)
+		}
+		if err := db.SaveCursor(cursor, next); err != nil {
+			t.Fatalf("SaveCursor failed: %v", err)
+		}
+		var ids []string
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	insert("t1")
+	insert("t2")
+	if ids := consume("script"); len(ids) != 2 {
+		t.Errorf("first read should return all transactions, got %v", ids)
+	}
+	if ids := consume("script"); len(ids) != 0 {
+		t.Errorf("second read should return nothing, got %v", ids)
+	}
+
+	insert("t3")
+	insert("t2") // duplicate, ignored
+	if ids := consume("script"); len(ids) != 1 || ids[0] != "t3" {
+		t.Errorf("expected only t3, got %v", ids)
+	}
+
+	// Cursors are independent per consumer
+	if ids := consume("chat"); len(ids) != 3 {
+		t.Errorf("new consumer should see all transactions, got %v", ids)
+	}
+}
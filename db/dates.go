@@ -0,0 +1,40 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// BankLocation is the time zone of the bank (Armenia, UTC+4 without DST),
+// assumed for dates that come without one
+var BankLocation = time.FixedZone("AMT", 4*60*60)
+
+// dateLayouts are the operation date formats accepted from the API, tried in order
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// ParseDate parses an operation date in any of the formats the API uses.
+// Dates without a time zone are in BankLocation.
+func ParseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, BankLocation); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
+
+// NormalizeDate returns an operation date as UTC RFC 3339, the format dates
+// are stored in, so that they sort and compare as strings. Dates that can't
+// be parsed are returned as is.
+func NormalizeDate(s string) string {
+	t, err := ParseDate(s)
+	if err != nil {
+		return s
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -2,29 +2,42 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
 )
 
 // DB wraps a SQLite database connection
 type DB struct {
 	*sql.DB
+
+	stmtMu sync.Mutex
+	stmts  map[string]*sql.Stmt // see prepared
+}
+
+// IsSQLiteError reports whether err was caused by SQLite, e.g. a locked or corrupt database
+func IsSQLiteError(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr)
 }
 
 // Open opens or creates a SQLite database at the given path
 func Open(path string) (*DB, error) {
-	sqlDB, err := sql.Open("sqlite", path)
+	// Foreign keys are enabled per connection, so through the DSN, which
+	// applies it to every connection of the pool (ON DELETE CASCADE of
+	// snapshot products and fired alerts depends on it)
+	dsn := path + "?_pragma=foreign_keys(1)"
+	if strings.Contains(path, "?") {
+		dsn = path + "&_pragma=foreign_keys(1)"
+	}
+	sqlDB, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Enable foreign keys
-	if _, err := sqlDB.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		sqlDB.Close()
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
-	}
-
 	// Enable WAL mode for better concurrency
 	if _, err := sqlDB.Exec("PRAGMA journal_mode = WAL"); err != nil {
 		sqlDB.Close()
@@ -67,3 +80,11 @@ func (db *DB) WithTransaction(fn func(*sql.Tx) error) error {
 
 	return nil
 }
+
+// BackupTo writes a consistent copy of the database to path, which must not exist
+func (db *DB) BackupTo(path string) error {
+	if _, err := db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
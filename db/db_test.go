@@ -1,8 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
 )
@@ -82,6 +88,139 @@ func TestMigrationIdempotent(t *testing.T) {
 	}
 }
 
+func TestMigrationRefusesIncompatibleSchema(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// A newer binary added a compatible version
+	if _, err := db.Exec("INSERT INTO schema_version (version) VALUES (?)", schemaVersion+1); err != nil {
+		t.Fatalf("failed to record version: %v", err)
+	}
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("expected a compatible newer schema to be accepted: %v", err)
+	}
+	if version, _ := db.GetSchemaVersion(); version != schemaVersion+1 {
+		t.Errorf("expected schema version %d to be kept, got %d", schemaVersion+1, version)
+	}
+
+	// ... then a breaking one
+	if _, err := db.Exec("UPDATE schema_compat SET min_version = ?", schemaVersion+1); err != nil {
+		t.Fatalf("failed to record compatible version: %v", err)
+	}
+	var tooNew *SchemaTooNewError
+	if err := db.Migrate(); !errors.As(err, &tooNew) || tooNew.Version != schemaVersion+1 || tooNew.MinCompatible != schemaVersion+1 {
+		t.Fatalf("expected SchemaTooNewError, got %v", err)
+	}
+	if !strings.Contains(tooNew.Error(), "upgrade ameriagrab") {
+		t.Errorf("unexpected message: %s", tooNew.Error())
+	}
+
+	// Without a record the database is only usable by binaries knowing its version
+	if _, err := db.Exec("DELETE FROM schema_compat"); err != nil {
+		t.Fatalf("failed to delete compatible version: %v", err)
+	}
+	if err := db.Migrate(); !errors.As(err, &tooNew) {
+		t.Errorf("expected SchemaTooNewError without a compatible version, got %v", err)
+	}
+}
+
+func TestDowngrade(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db := &DB{DB: sqlDB}
+	defer db.Close()
+
+	// Version 18 is irreversible
+	if err := db.migrateTo(17); err != nil {
+		t.Fatalf("failed to migrate to version 17: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO products (id, product_type, name, currency, client_id, synced_at) VALUES ('p1', 'CARD', 'Card', 'AMD', 'c1', 0)"); err != nil {
+		t.Fatalf("failed to insert product: %v", err)
+	}
+	if err := db.Downgrade(8); err == nil || !strings.Contains(err.Error(), "version 9 can't be rolled back") {
+		t.Fatalf("expected version 9 to be irreversible, got %v", err)
+	}
+	if version, _ := db.GetSchemaVersion(); version != 17 {
+		t.Fatalf("expected a failed downgrade to change nothing, got version %d", version)
+	}
+
+	if err := db.Downgrade(12); err != nil {
+		t.Fatalf("Downgrade failed: %v", err)
+	}
+	if version, _ := db.GetSchemaVersion(); version != 12 {
+		t.Errorf("expected schema version 12, got %d", version)
+	}
+	var name string
+	if err := db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'card_holds'").Scan(&name); err != sql.ErrNoRows {
+		t.Errorf("expected card_holds to be dropped, got %v", err)
+	}
+	if err := db.QueryRow("SELECT name FROM products WHERE id = 'p1'").Scan(&name); err != nil || name != "Card" {
+		t.Errorf("expected the product to be kept, got %q, %v", name, err)
+	}
+
+	// Down migrations are reversible
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate up again: %v", err)
+	}
+	if version, _ := db.GetSchemaVersion(); version != schemaVersion {
+		t.Errorf("expected schema version %d, got %d", schemaVersion, version)
+	}
+	if err := db.Downgrade(17); err == nil || !strings.Contains(err.Error(), "version 18 can't be rolled back") {
+		t.Errorf("expected version 18 to be irreversible, got %v", err)
+	}
+	if minVersion, err := db.minCompatibleVersion(schemaVersion); err != nil || minVersion != 18 {
+		t.Errorf("expected compatible version 18, got %d, %v", minVersion, err)
+	}
+}
+
+func TestOpen_ForeignKeysOnEveryConnection(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "ameria.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Hold one connection so that the next one is new to the pool
+	ctx := context.Background()
+	first, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("failed to get a connection: %v", err)
+	}
+	defer first.Close()
+	second, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("failed to get a connection: %v", err)
+	}
+	defer second.Close()
+	for i, conn := range []*sql.Conn{first, second} {
+		var enabled int
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enabled); err != nil || enabled != 1 {
+			t.Errorf("connection %d: expected foreign keys to be enabled, got %d (err %v)", i, enabled, err)
+		}
+	}
+
+	// Deleting a rule through any connection deletes its alerts
+	id, err := db.AddAlertRule(AlertRule{Kind: AlertTxnAbove, Threshold: 1000, Currency: "AMD"})
+	if err != nil {
+		t.Fatalf("AddAlertRule failed: %v", err)
+	}
+	if _, err := second.ExecContext(ctx, "INSERT INTO alerts (rule_id, ref_key, fired_at, message) VALUES (?, 'k', 0, 'm')", id); err != nil {
+		t.Fatalf("failed to insert alert: %v", err)
+	}
+	if _, err := second.ExecContext(ctx, "DELETE FROM alert_rules WHERE id = ?", id); err != nil {
+		t.Fatalf("failed to delete rule: %v", err)
+	}
+	var count int
+	if err := first.QueryRowContext(ctx, "SELECT COUNT(*) FROM alerts").Scan(&count); err != nil || count != 0 {
+		t.Errorf("expected the rule's alerts to be deleted, got %d (err %v)", count, err)
+	}
+}
+
 func TestWithTransaction_Success(t *testing.T) {
 	db, err := OpenInMemory()
 	if err != nil {
@@ -337,6 +476,48 @@ func TestUpsertProducts(t *testing.T) {
 	}
 }
 
+func TestUpsertProducts_ClientID(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	products := []client.ProductInfo{
+		{ID: "acc1", ProductType: "ACCOUNT", Name: "Personal", Currency: "AMD", ClientID: "personal"},
+		{ID: "acc2", ProductType: "ACCOUNT", Name: "Company", Currency: "AMD", ClientID: "company"},
+	}
+	if err := db.UpsertProducts(products); err != nil {
+		t.Fatalf("UpsertProducts failed: %v", err)
+	}
+	// Products upserted without a client keep the stored one
+	if err := db.UpsertProducts([]client.ProductInfo{{ID: "acc2", ProductType: "ACCOUNT", Name: "Company LLC", Currency: "AMD"}}); err != nil {
+		t.Fatalf("UpsertProducts failed: %v", err)
+	}
+
+	p, err := db.GetProductByNameOrID("Company LLC")
+	if err != nil || p == nil {
+		t.Fatalf("GetProductByNameOrID failed: %v", err)
+	}
+	if p.ClientID != "company" {
+		t.Errorf("expected client company, got %q", p.ClientID)
+	}
+
+	if _, _, err := db.ApplyAccountTransactions("acc1", []client.AccountTransaction{{ID: "t1", TransactionDate: 1704067200000}}); err != nil {
+		t.Fatalf("ApplyAccountTransactions failed: %v", err)
+	}
+	if _, _, err := db.ApplyAccountTransactions("acc2", []client.AccountTransaction{{ID: "t2", TransactionDate: 1704067200000}}); err != nil {
+		t.Fatalf("ApplyAccountTransactions failed: %v", err)
+	}
+	entries, err := db.GetLedgerEntries(LedgerOptions{ClientID: "company"})
+	if err != nil {
+		t.Fatalf("GetLedgerEntries failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ProductID != "acc2" {
+		t.Errorf("expected only the company's transaction, got %+v", entries)
+	}
+}
+
 func TestInsertCardTransactions_Deduplication(t *testing.T) {
 	db, err := OpenInMemory()
 	if err != nil {
@@ -499,6 +680,101 @@ func TestGetExistingCardTxnKeys(t *testing.T) {
 	}
 }
 
+func TestApplyTransactions(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	card := []client.Transaction{{
+		ID:            "txn-001",
+		State:         "PENDING",
+		Amount:        client.Amount{Currency: "AMD", Amount: 5000},
+		Details:       "Coffee Shop",
+		OperationDate: "2024-01-15T10:00:00",
+	}}
+	if inserted, changed, err := db.ApplyCardTransactions("card-001", card); err != nil || inserted != 1 || changed != 0 {
+		t.Fatalf("ApplyCardTransactions: inserted %d, changed %d, err %v", inserted, changed, err)
+	}
+	if _, _, err := db.ApplyLinkedAccountTransactions("card-001", card); err != nil {
+		t.Fatalf("ApplyLinkedAccountTransactions failed: %v", err)
+	}
+
+	// Unchanged transactions are left alone
+	if inserted, changed, err := db.ApplyCardTransactions("card-001", card); err != nil || inserted != 0 || changed != 0 {
+		t.Fatalf("ApplyCardTransactions: inserted %d, changed %d, err %v", inserted, changed, err)
+	}
+
+	card[0].State = "SETTLED"
+	card[0].Details = "Coffee Shop Yerevan"
+	if inserted, changed, err := db.ApplyCardTransactions("card-001", card); err != nil || inserted != 0 || changed != 1 {
+		t.Fatalf("ApplyCardTransactions: inserted %d, changed %d, err %v", inserted, changed, err)
+	}
+	if _, changed, err := db.ApplyLinkedAccountTransactions("card-001", card); err != nil || changed != 1 {
+		t.Fatalf("ApplyLinkedAccountTransactions: changed %d, err %v", changed, err)
+	}
+	cardTxns, err := db.GetCardTransactions("card-001", 0, 0, false)
+	if err != nil {
+		t.Fatalf("GetCardTransactions failed: %v", err)
+	}
+	if len(cardTxns) != 1 || cardTxns[0].State != "SETTLED" || cardTxns[0].Details != "Coffee Shop Yerevan" {
+		t.Errorf("expected updated card transaction, got %+v", cardTxns)
+	}
+
+	account := []client.AccountTransaction{{
+		ID:                "atxn-001",
+		Status:            "PENDING",
+		TransactionDate:   1705315200000,
+		TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 50000},
+	}}
+	if _, _, err := db.ApplyAccountTransactions("acct-001", account); err != nil {
+		t.Fatalf("ApplyAccountTransactions failed: %v", err)
+	}
+	account[0].Status = "COMPLETED"
+	account[0].SettledAmount = client.TransactionAmt{Currency: "AMD", Value: 49900}
+	if inserted, changed, err := db.ApplyAccountTransactions("acct-001", account); err != nil || inserted != 0 || changed != 1 {
+		t.Fatalf("ApplyAccountTransactions: inserted %d, changed %d, err %v", inserted, changed, err)
+	}
+	accountTxns, err := db.GetAccountTransactions("acct-001", false)
+	if err != nil {
+		t.Fatalf("GetAccountTransactions failed: %v", err)
+	}
+	if len(accountTxns) != 1 || accountTxns[0].Status != "COMPLETED" || accountTxns[0].SettledAmount.Value != 49900 {
+		t.Errorf("expected updated account transaction, got %+v", accountTxns)
+	}
+
+	changes, err := db.GetTransactionChanges("card-001", "txn-001", 0)
+	if err != nil {
+		t.Fatalf("GetTransactionChanges failed: %v", err)
+	}
+	// state and details, in both card and linked account transactions
+	if len(changes) != 4 {
+		t.Fatalf("expected 4 card changes, got %+v", changes)
+	}
+	fields := map[string]TransactionChange{}
+	for _, c := range changes {
+		if c.Source == SourceCard {
+			fields[c.Field] = c
+		}
+	}
+	if c := fields["state"]; c.OldValue != "PENDING" || c.NewValue != "SETTLED" || c.OperationDate != "2024-01-15T06:00:00Z" {
+		t.Errorf("unexpected state change: %+v", c)
+	}
+
+	changes, err = db.GetTransactionChanges("acct-001", "", 0)
+	if err != nil {
+		t.Fatalf("GetTransactionChanges failed: %v", err)
+	}
+	// status, settled amount currency and value
+	if len(changes) != 3 {
+		t.Errorf("expected 3 account changes, got %+v", changes)
+	}
+	if limited, _ := db.GetTransactionChanges("", "", 2); len(limited) != 2 {
+		t.Errorf("expected 2 changes with limit, got %d", len(limited))
+	}
+}
+
 func TestInsertAccountTransactions_Deduplication(t *testing.T) {
 	db, err := OpenInMemory()
 	if err != nil {
@@ -891,6 +1167,484 @@ func TestGetExistingLinkedAccountTxnKeys(t *testing.T) {
 	}
 }
 
+func TestLinkedAccountTransactionSwiftDetails(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	txns := []client.Transaction{
+		{ID: "lat-001", OperationDate: "2024-01-15T10:00:00", Details: "Incoming SWIFT"},
+		{ID: "lat-002", OperationDate: "2024-01-16T11:00:00", Details: "Older SWIFT"},
+	}
+	if _, err := db.InsertLinkedAccountTransactions("card-001", txns); err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+
+	raw := `{"senderBankName":"Example Bank NA","transactionReference":"INV-42","charges":{"amount":15,"currency":"USD"},"chargeBearer":"SHA"}`
+	if err := db.UpdateTransactionExtendedInfo("card-001", "lat-001", "2024-01-15T10:00:00",
+		&client.TransactionExtendedInfo{SwiftDetails: raw}); err != nil {
+		t.Fatalf("UpdateTransactionExtendedInfo failed: %v", err)
+	}
+	var senderBank, fees string
+	if err := db.QueryRow("SELECT swift_sender_bank, swift_fees FROM card_linked_account_transactions WHERE id = 'lat-001'").
+		Scan(&senderBank, &fees); err != nil {
+		t.Fatalf("failed to query SWIFT columns: %v", err)
+	}
+	if senderBank != "Example Bank NA" || fees != "15 USD (SHA)" {
+		t.Errorf("unexpected SWIFT columns: sender bank %q, fees %q", senderBank, fees)
+	}
+
+	// A row stored before the SWIFT columns existed is parsed when read
+	if _, err := db.Exec(`UPDATE card_linked_account_transactions SET swift_details = ?, extended_fetched = 1 WHERE id = 'lat-002'`,
+		`{"orderingInstitution":"Old Bank","intermediaryBank":"Correspondent Bank"}`); err != nil {
+		t.Fatalf("failed to store old row: %v", err)
+	}
+
+	result, err := db.GetLinkedAccountTransactions("card-001", 0, 0, true, true)
+	if err != nil {
+		t.Fatalf("GetLinkedAccountTransactions failed: %v", err)
+	}
+	if len(result) != 2 || result[0].Extended == nil || result[1].Extended == nil {
+		t.Fatalf("expected 2 transactions with extended info, got %+v", result)
+	}
+	if swift := result[0].Extended.Swift; swift == nil || swift.Reference != "INV-42" || swift.SenderBank != "Example Bank NA" {
+		t.Errorf("unexpected SWIFT info for lat-001: %+v", swift)
+	}
+	if swift := result[1].Extended.Swift; swift == nil || swift.SenderBank != "Old Bank" || swift.Intermediary != "Correspondent Bank" {
+		t.Errorf("unexpected SWIFT info for lat-002: %+v", swift)
+	}
+}
+
+func TestAttachCounterparties(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	linked := []client.Transaction{
+		{ID: "lat-001", OperationDate: "2024-01-15T10:00:20Z", Amount: client.Amount{Currency: "AMD", Amount: 5000}},
+		{ID: "lat-002", OperationDate: "2024-01-16T11:00:00Z", Amount: client.Amount{Currency: "AMD", Amount: 700}},
+	}
+	if _, err := db.InsertLinkedAccountTransactions("card-001", linked); err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+	if err := db.UpdateTransactionExtendedInfo("card-001", "lat-001", "2024-01-15T10:00:20Z",
+		&client.TransactionExtendedInfo{BeneficiaryName: "Landlord"}); err != nil {
+		t.Fatalf("UpdateTransactionExtendedInfo failed: %v", err)
+	}
+
+	cardTxns := []client.Transaction{
+		{ID: "c1", OperationDate: "2024-01-15T10:00:00Z", Amount: client.Amount{Currency: "AMD", Amount: 5000}},
+		{ID: "c2", OperationDate: "2024-01-16T11:00:00Z", Amount: client.Amount{Currency: "AMD", Amount: 700}},
+		{ID: "c3", OperationDate: "2024-01-15T10:05:00Z", Amount: client.Amount{Currency: "AMD", Amount: 5000}},
+	}
+	if err := db.AttachCounterparties("card-001", cardTxns, MatchOptions{}); err != nil {
+		t.Fatalf("AttachCounterparties failed: %v", err)
+	}
+	if ext := cardTxns[0].Extended; ext == nil || ext.BeneficiaryName != "Landlord" {
+		t.Errorf("expected the matching linked transaction's beneficiary, got %+v", ext)
+	}
+	// lat-002 has no extended info, and c3 is too far apart in time
+	if cardTxns[1].Extended != nil || cardTxns[2].Extended != nil {
+		t.Errorf("expected no extended info for unmatched transactions, got %+v, %+v", cardTxns[1].Extended, cardTxns[2].Extended)
+	}
+}
+
+func TestMergeTransactions_Matching(t *testing.T) {
+	rates := ExchangeRates{"USD": 390, "EUR": 420}
+	amount := func(currency string, value float64) client.Amount {
+		return client.Amount{Currency: currency, Amount: value}
+	}
+	for _, tt := range []struct {
+		name    string
+		card    client.Transaction
+		linked  []client.Transaction
+		opts    MatchOptions
+		matched string // ID of the linked transaction used, or "" for none
+	}{
+		{
+			name:    "same amount",
+			card:    client.Transaction{ID: "c", OperationDate: "2024-01-15T10:00:00Z", Amount: amount("AMD", 5000)},
+			linked:  []client.Transaction{{ID: "l", OperationDate: "2024-01-15T10:00:30Z", Amount: amount("AMD", 5000)}},
+			matched: "l",
+		},
+		{
+			name:   "too far apart",
+			card:   client.Transaction{ID: "c", OperationDate: "2024-01-15T10:00:00Z", Amount: amount("AMD", 5000)},
+			linked: []client.Transaction{{ID: "l", OperationDate: "2024-01-15T10:03:00Z", Amount: amount("AMD", 5000)}},
+		},
+		{
+			name:    "configured time tolerance",
+			card:    client.Transaction{ID: "c", OperationDate: "2024-01-15T10:00:00Z", Amount: amount("AMD", 5000)},
+			linked:  []client.Transaction{{ID: "l", OperationDate: "2024-01-15T10:03:00Z", Amount: amount("AMD", 5000)}},
+			opts:    MatchOptions{TimeTolerance: 5 * time.Minute},
+			matched: "l",
+		},
+		{
+			name:    "FX purchase",
+			card:    client.Transaction{ID: "c", OperationDate: "2024-01-15T10:00:00Z", Amount: amount("USD", 10)},
+			linked:  []client.Transaction{{ID: "l", OperationDate: "2024-01-15T10:00:10Z", Amount: amount("AMD", 3950)}},
+			opts:    MatchOptions{Rates: rates},
+			matched: "l",
+		},
+		{
+			name:    "FX between foreign currencies",
+			card:    client.Transaction{ID: "c", OperationDate: "2024-01-15T10:00:00Z", Amount: amount("EUR", 10)},
+			linked:  []client.Transaction{{ID: "l", OperationDate: "2024-01-15T10:00:10Z", Amount: amount("USD", 10.9)}},
+			opts:    MatchOptions{Rates: rates},
+			matched: "l",
+		},
+		{
+			name:   "FX beyond tolerance",
+			card:   client.Transaction{ID: "c", OperationDate: "2024-01-15T10:00:00Z", Amount: amount("USD", 10)},
+			linked: []client.Transaction{{ID: "l", OperationDate: "2024-01-15T10:00:10Z", Amount: amount("AMD", 4300)}},
+			opts:   MatchOptions{Rates: rates},
+		},
+		{
+			name:    "FX with configured tolerance",
+			card:    client.Transaction{ID: "c", OperationDate: "2024-01-15T10:00:00Z", Amount: amount("USD", 10)},
+			linked:  []client.Transaction{{ID: "l", OperationDate: "2024-01-15T10:00:10Z", Amount: amount("AMD", 4300)}},
+			opts:    MatchOptions{Rates: rates, FXTolerance: 0.1},
+			matched: "l",
+		},
+		{
+			name:   "FX without rates",
+			card:   client.Transaction{ID: "c", OperationDate: "2024-01-15T10:00:00Z", Amount: amount("USD", 10)},
+			linked: []client.Transaction{{ID: "l", OperationDate: "2024-01-15T10:00:10Z", Amount: amount("AMD", 3900)}},
+		},
+		{
+			name: "same currency wins over FX",
+			card: client.Transaction{ID: "c", OperationDate: "2024-01-15T10:00:00Z", Amount: amount("USD", 10)},
+			linked: []client.Transaction{
+				{ID: "fx", OperationDate: "2024-01-15T10:00:00Z", Amount: amount("AMD", 3900)},
+				{ID: "usd", OperationDate: "2024-01-15T10:00:40Z", Amount: amount("USD", 10)},
+			},
+			opts:    MatchOptions{Rates: rates},
+			matched: "usd",
+		},
+		{
+			name: "similar name wins over closer time",
+			card: client.Transaction{ID: "c", OperationDate: "2024-01-15T10:00:00Z", Amount: amount("USD", 10),
+				CorrespondentAccountName: "AMAZON.COM*RT4 SEATTLE"},
+			linked: []client.Transaction{
+				{ID: "other", OperationDate: "2024-01-15T10:00:05Z", Amount: amount("AMD", 3910), Details: "Purchase, NETFLIX.COM"},
+				{ID: "amazon", OperationDate: "2024-01-15T10:00:50Z", Amount: amount("AMD", 3920), Details: "Purchase, AMAZON.COM SEATTLE US"},
+			},
+			opts:    MatchOptions{Rates: rates},
+			matched: "amazon",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			result := mergeTransactions([]client.Transaction{tt.card}, tt.linked, tt.opts)
+			if len(result) == 0 {
+				t.Fatalf("expected the card transaction or its match, got nothing")
+			}
+			want := tt.matched
+			if want == "" {
+				want = tt.card.ID
+			}
+			if result[0].ID != want {
+				t.Errorf("expected %s, got %s", want, result[0].ID)
+			}
+		})
+	}
+}
+
+func TestMergeTransactions_MatchesOnce(t *testing.T) {
+	// Two equal card purchases can't both take the same linked transaction
+	cardTxns := []client.Transaction{
+		{ID: "c1", OperationDate: "2024-01-15T10:00:00Z", Amount: client.Amount{Currency: "AMD", Amount: 5000}},
+		{ID: "c2", OperationDate: "2024-01-15T10:00:20Z", Amount: client.Amount{Currency: "AMD", Amount: 5000}},
+	}
+	linkedTxns := []client.Transaction{
+		{ID: "l1", OperationDate: "2024-01-15T10:00:10Z", Amount: client.Amount{Currency: "AMD", Amount: 5000}},
+	}
+	result := mergeTransactions(cardTxns, linkedTxns, MatchOptions{})
+	if len(result) != 2 || result[0].ID != "l1" || result[1].ID != "c2" {
+		t.Errorf("expected l1 and c2, got %+v", result)
+	}
+}
+
+func TestSameTimestampOrder(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Inserted out of ID order, all at the same time
+	var txns []client.Transaction
+	for _, id := range []string{"t2", "t3", "t1"} {
+		txns = append(txns, client.Transaction{
+			ID:            id,
+			OperationDate: "2024-01-15T10:00:00Z",
+			Amount:        client.Amount{Currency: "AMD", Amount: 1000},
+		})
+	}
+	if _, err := db.InsertCardTransactions("card-001", txns); err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+
+	ids := func(txns []client.Transaction) string {
+		var result []string
+		for _, txn := range txns {
+			result = append(result, txn.ID)
+		}
+		return strings.Join(result, ",")
+	}
+	for _, ascending := range []bool{true, false} {
+		want := "t1,t2,t3"
+		if !ascending {
+			want = "t3,t2,t1"
+		}
+		stored, err := db.GetCardTransactions("card-001", 0, 0, ascending)
+		if err != nil {
+			t.Fatalf("GetCardTransactions failed: %v", err)
+		}
+		if got := ids(stored); got != want {
+			t.Errorf("ascending=%v: expected %s from the database, got %s", ascending, want, got)
+		}
+		sorted := append([]client.Transaction(nil), txns...)
+		sortTransactions(sorted, ascending)
+		if got := ids(sorted); got != want {
+			t.Errorf("ascending=%v: expected %s from sortTransactions, got %s", ascending, want, got)
+		}
+	}
+
+	entries, err := db.GetLedgerEntries(LedgerOptions{ProductID: "card-001"})
+	if err != nil {
+		t.Fatalf("GetLedgerEntries failed: %v", err)
+	}
+	if len(entries) != 3 || entries[0].ID != "t1" || entries[1].ID != "t2" || entries[2].ID != "t3" {
+		t.Errorf("expected ledger entries ordered by ID, got %+v", entries)
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"2024-01-15T10:30:00Z", "2024-01-15T10:30:00Z"},
+		{"2024-01-15T10:30:00+04:00", "2024-01-15T06:30:00Z"},
+		{"2024-01-15T10:30:00.123+04:00", "2024-01-15T06:30:00Z"},
+		// Without a time zone: the bank's
+		{"2024-01-15T10:30:00", "2024-01-15T06:30:00Z"},
+		{"2024-01-15T02:30:00.5", "2024-01-14T22:30:00Z"},
+		{"2024-01-15 10:30:00", "2024-01-15T06:30:00Z"},
+		{"15.01.2024", "15.01.2024"},
+		{"", ""},
+	} {
+		if got := NormalizeDate(tt.in); got != tt.want {
+			t.Errorf("NormalizeDate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeDatesMigration(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Rows stored verbatim before dates were normalized
+	for _, row := range []struct{ id, date string }{
+		{"t1", "2024-01-15T10:30:00"},
+		{"t2", "2024-01-15T10:30:00+04:00"},
+		{"t3", "2024-01-15T06:30:00Z"},
+		{"t4", "not a date"},
+		// The same transaction in two formats
+		{"t5", "2024-01-16T12:00:00"},
+		{"t5", "2024-01-16T08:00:00Z"},
+	} {
+		if _, err := db.Exec(`INSERT INTO card_transactions (id, product_id, operation_date, synced_at) VALUES (?, 'card-001', ?, 0)`,
+			row.id, row.date); err != nil {
+			t.Fatalf("failed to insert: %v", err)
+		}
+	}
+	if _, err := db.Exec(migrations[17]); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+
+	rows, err := db.Query(`SELECT id, operation_date FROM card_transactions ORDER BY id`)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var id, date string
+		if err := rows.Scan(&id, &date); err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+		got = append(got, id+" "+date)
+	}
+	want := "t1 2024-01-15T06:30:00Z,t2 2024-01-15T06:30:00Z,t3 2024-01-15T06:30:00Z,t4 not a date,t5 2024-01-16T08:00:00Z"
+	if strings.Join(got, ",") != want {
+		t.Errorf("expected %s, got %s", want, strings.Join(got, ","))
+	}
+}
+
+func TestNormalizeDatesMigration_MergesDuplicates(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db := &DB{DB: sqlDB}
+	defer db.Close()
+
+	if err := db.migrateTo(17); err != nil {
+		t.Fatalf("failed to migrate to version 17: %v", err)
+	}
+	// The same transaction stored twice in different date formats: the copy
+	// synced last has the current state, the older one the details
+	for _, stmt := range []string{
+		`INSERT INTO card_transactions (id, product_id, state, operation_date, synced_at) VALUES
+			('c1', 'card-001', 'SETTLED', '2024-01-16T12:00:00', 200),
+			('c1', 'card-001', 'PENDING', '2024-01-16T08:00:00Z', 100)`,
+		`INSERT INTO card_linked_account_transactions (id, product_id, state, operation_date, synced_at,
+			beneficiary_name, swift_fees, extended_fetched) VALUES
+			('l1', 'card-001', 'PENDING', '2024-01-16T08:00:00Z', 100, 'ACME LLC', 'OUR', 1),
+			('l1', 'card-001', 'SETTLED', '2024-01-16T12:00:00', 200, NULL, NULL, 0)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("failed to insert: %v", err)
+		}
+	}
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	var count int
+	var state, date string
+	var syncedAt int64
+	err = db.QueryRow(`SELECT COUNT(*), MAX(state), MAX(operation_date), MAX(synced_at) FROM card_transactions`).
+		Scan(&count, &state, &date, &syncedAt)
+	if err != nil || count != 1 || state != "SETTLED" || date != "2024-01-16T08:00:00Z" || syncedAt != 200 {
+		t.Errorf("expected the card transaction synced last, got %d rows: %s %s %d (err %v)", count, state, date, syncedAt, err)
+	}
+
+	var beneficiary, fees string
+	var fetched int
+	err = db.QueryRow(`SELECT COUNT(*), MAX(state), MAX(synced_at), MAX(beneficiary_name), MAX(swift_fees), MAX(extended_fetched)
+		FROM card_linked_account_transactions`).Scan(&count, &state, &syncedAt, &beneficiary, &fees, &fetched)
+	if err != nil || count != 1 || state != "SETTLED" || syncedAt != 200 {
+		t.Errorf("expected the linked transaction synced last, got %d rows: %s %d (err %v)", count, state, syncedAt, err)
+	}
+	if beneficiary != "ACME LLC" || fees != "OUR" || fetched != 1 {
+		t.Errorf("expected the details of the older copy to be kept, got %q, %q, fetched %d", beneficiary, fees, fetched)
+	}
+}
+
+func TestForEachTransaction(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var card []client.Transaction
+	var account []client.AccountTransaction
+	for i := 1; i <= 5; i++ {
+		card = append(card, client.Transaction{
+			ID:            fmt.Sprintf("t%d", i),
+			OperationDate: fmt.Sprintf("2024-01-%02dT10:00:00Z", i),
+			Amount:        client.Amount{Currency: "AMD", Amount: float64(i * 100)},
+		})
+		account = append(account, client.AccountTransaction{
+			ID:              fmt.Sprintf("a%d", i),
+			TransactionDate: time.Date(2024, 1, i, 10, 0, 0, 0, time.UTC).UnixMilli(),
+		})
+	}
+	if _, err := db.InsertCardTransactions("card-001", card); err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+	if _, err := db.InsertLinkedAccountTransactions("card-001", card); err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+	if _, err := db.InsertAccountTransactions("acct-001", account); err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+
+	for _, tt := range []struct {
+		opts ReadOptions
+		want string
+	}{
+		{ReadOptions{}, "t5,t4,t3,t2,t1"},
+		{ReadOptions{Ascending: true}, "t1,t2,t3,t4,t5"},
+		{ReadOptions{Size: 2, Page: 1}, "t3,t2"},
+		{ReadOptions{Size: 2, Page: 2, Ascending: true, IncludeExtended: true}, "t5"},
+	} {
+		var cardIDs, linkedIDs []string
+		if err := db.ForEachCardTransaction("card-001", tt.opts, func(txn client.Transaction) error {
+			cardIDs = append(cardIDs, txn.ID)
+			return nil
+		}); err != nil {
+			t.Fatalf("ForEachCardTransaction failed: %v", err)
+		}
+		if err := db.ForEachLinkedAccountTransaction("card-001", tt.opts, func(txn client.Transaction) error {
+			linkedIDs = append(linkedIDs, txn.ID)
+			return nil
+		}); err != nil {
+			t.Fatalf("ForEachLinkedAccountTransaction failed: %v", err)
+		}
+		if strings.Join(cardIDs, ",") != tt.want || strings.Join(linkedIDs, ",") != tt.want {
+			t.Errorf("%+v: expected %s, got %v and %v", tt.opts, tt.want, cardIDs, linkedIDs)
+		}
+	}
+
+	// An error from fn stops the iteration
+	errStop := errors.New("stop")
+	var accountIDs []string
+	err = db.ForEachAccountTransaction("acct-001", ReadOptions{Ascending: true}, func(txn client.AccountTransaction) error {
+		accountIDs = append(accountIDs, txn.ID)
+		if len(accountIDs) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop || strings.Join(accountIDs, ",") != "a1,a2" {
+		t.Errorf("expected to stop after a2 with errStop, got %v (err %v)", accountIDs, err)
+	}
+}
+
+func TestPreparedStatementCache(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	txns := []client.Transaction{
+		{ID: "t1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 100}, OperationDate: "2024-06-01T10:00:00+04:00"},
+	}
+	for range 3 {
+		if _, _, err := db.ApplyCardTransactions("card-001", txns); err != nil {
+			t.Fatalf("ApplyCardTransactions failed: %v", err)
+		}
+		if n, err := db.CountCardTransactions("card-001"); err != nil || n != 1 {
+			t.Fatalf("CountCardTransactions = %d, %v", n, err)
+		}
+		txns[0].Amount.Amount += 10
+	}
+	// The insert, the stored row lookup, the change record and the count
+	if len(db.stmts) != 4 {
+		t.Errorf("expected 4 cached statements, got %d", len(db.stmts))
+	}
+	if changes, err := db.GetTransactionChanges("card-001", "t1", 0); err != nil || len(changes) != 2 {
+		t.Errorf("expected 2 recorded changes, got %d (err %v)", len(changes), err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if db.stmts != nil {
+		t.Error("expected Close to drop the cached statements")
+	}
+}
+
 func TestGetProductByNameOrID_ByID(t *testing.T) {
 	db, err := OpenInMemory()
 	if err != nil {
@@ -1139,3 +1893,246 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestSyncRuns(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if last, err := db.LastSuccessfulSync(); err != nil || !last.IsZero() {
+		t.Errorf("expected no successful sync, got %v (err %v)", last, err)
+	}
+
+	id1, err := db.StartSyncRun("manual")
+	if err != nil {
+		t.Fatalf("StartSyncRun failed: %v", err)
+	}
+	if err := db.FinishSyncRun(id1, SyncRunSuccess, ""); err != nil {
+		t.Fatalf("FinishSyncRun failed: %v", err)
+	}
+
+	id2, err := db.StartSyncRun("daemon")
+	if err != nil {
+		t.Fatalf("StartSyncRun failed: %v", err)
+	}
+	if err := db.FinishSyncRun(id2, SyncRunFailed, "network error"); err != nil {
+		t.Fatalf("FinishSyncRun failed: %v", err)
+	}
+
+	runs, err := db.GetSyncRuns(0)
+	if err != nil {
+		t.Fatalf("GetSyncRuns failed: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 sync runs, got %d", len(runs))
+	}
+
+	// Newest first
+	if runs[0].ID != id2 || runs[0].Trigger != "daemon" {
+		t.Errorf("expected newest run to be daemon run %d, got %+v", id2, runs[0])
+	}
+	if runs[0].Status != SyncRunFailed || runs[0].Error != "network error" {
+		t.Errorf("expected failed run with error, got status %q error %q", runs[0].Status, runs[0].Error)
+	}
+	if runs[1].Status != SyncRunSuccess || runs[1].Error != "" {
+		t.Errorf("expected successful run without error, got status %q error %q", runs[1].Status, runs[1].Error)
+	}
+	if runs[1].FinishedAt.IsZero() {
+		t.Error("expected finished_at to be set")
+	}
+	if last, err := db.LastSuccessfulSync(); err != nil || !last.Equal(runs[1].StartedAt) {
+		t.Errorf("expected last successful sync at %v, got %v (err %v)", runs[1].StartedAt, last, err)
+	}
+
+	// Later partial runs don't move the last full sync
+	if _, err := db.Exec(`INSERT INTO sync_runs (trigger, started_at, status) VALUES (?, ?, ?)`,
+		SyncTriggerPartial, runs[1].StartedAt.Unix()+3600, SyncRunSuccess); err != nil {
+		t.Fatalf("inserting partial run failed: %v", err)
+	}
+	if last, err := db.LastSuccessfulSync(); err != nil || !last.Equal(runs[1].StartedAt) {
+		t.Errorf("expected partial run to be ignored, got %v (err %v)", last, err)
+	}
+
+	limited, err := db.GetSyncRuns(1)
+	if err != nil {
+		t.Fatalf("GetSyncRuns failed: %v", err)
+	}
+	if len(limited) != 1 {
+		t.Errorf("expected 1 sync run with limit, got %d", len(limited))
+	}
+}
+
+func TestGetSyncFailureStreak(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	run := func(status, errMsg string, startedAt int64) {
+		t.Helper()
+		if _, err := db.Exec(`INSERT INTO sync_runs (trigger, started_at, finished_at, status, error) VALUES (?, ?, ?, ?, ?)`,
+			"daemon", startedAt, startedAt+60, status, nullString(errMsg)); err != nil {
+			t.Fatalf("inserting sync run failed: %v", err)
+		}
+	}
+	if streak, err := db.GetSyncFailureStreak(); err != nil || streak.Count != 0 {
+		t.Errorf("expected no failures, got %+v (err %v)", streak, err)
+	}
+
+	run(SyncRunFailed, "old failure", 1000)
+	run(SyncRunSuccess, "", 2000)
+	run(SyncRunFailed, "session expired", 3000)
+	run(SyncRunRunning, "", 3500)
+	run(SyncRunFailed, "session expired again", 4000)
+	streak, err := db.GetSyncFailureStreak()
+	if err != nil {
+		t.Fatalf("GetSyncFailureStreak failed: %v", err)
+	}
+	if streak.Count != 2 || streak.Since.Unix() != 3000 || streak.LastError != "session expired again" {
+		t.Errorf("unexpected streak: %+v", streak)
+	}
+
+	run(SyncRunSuccess, "", 5000)
+	if streak, err := db.GetSyncFailureStreak(); err != nil || streak.Count != 0 {
+		t.Errorf("expected a success to end the streak, got %+v (err %v)", streak, err)
+	}
+}
+
+func TestAccountTxnKeyMigration(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db := &DB{DB: sqlDB}
+	defer db.Close()
+
+	if err := db.migrateTo(21); err != nil {
+		t.Fatalf("failed to migrate to version 21: %v", err)
+	}
+	for _, row := range []struct {
+		id   string
+		date interface{}
+	}{{"a1", 1705315200000}, {"a2", nil}} {
+		if _, err := db.Exec(`INSERT INTO account_transactions (id, product_id, transaction_date, details, synced_at) VALUES (?, 'acct-001', ?, 'old', 0)`,
+			row.id, row.date); err != nil {
+			t.Fatalf("failed to insert: %v", err)
+		}
+	}
+	var rowID int64
+	if err := db.QueryRow("SELECT rowid FROM account_transactions WHERE id = 'a2'").Scan(&rowID); err != nil {
+		t.Fatalf("failed to query rowid: %v", err)
+	}
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	// Rowids are kept for cursors and a missing date becomes 0
+	var migratedRowID, date int64
+	if err := db.QueryRow("SELECT rowid, transaction_date FROM account_transactions WHERE id = 'a2'").Scan(&migratedRowID, &date); err != nil {
+		t.Fatalf("failed to query migrated row: %v", err)
+	}
+	if migratedRowID != rowID || date != 0 {
+		t.Errorf("expected rowid %d and date 0, got %d and %d", rowID, migratedRowID, date)
+	}
+
+	// A reused ID with another date is a transaction of its own
+	reused := []client.AccountTransaction{
+		{ID: "a1", TransactionDate: 1705315200000, Details: "changed"},
+		{ID: "a1", TransactionDate: 1710000000000, Details: "reused"},
+	}
+	inserted, changed, err := db.ApplyAccountTransactions("acct-001", reused)
+	if err != nil {
+		t.Fatalf("ApplyAccountTransactions failed: %v", err)
+	}
+	if inserted != 1 || changed != 1 {
+		t.Errorf("expected 1 inserted and 1 changed transaction, got %d and %d", inserted, changed)
+	}
+	keys, err := db.GetExistingAccountTxnKeys("acct-001")
+	if err != nil {
+		t.Fatalf("GetExistingAccountTxnKeys failed: %v", err)
+	}
+	for _, key := range []string{AccountTxnKey("a1", 1705315200000), AccountTxnKey("a1", 1710000000000), AccountTxnKey("a2", 0)} {
+		if !keys[key] {
+			t.Errorf("expected key %s, got %v", key, keys)
+		}
+	}
+
+	// Rolling back keeps the first transaction stored under an ID
+	if err := db.Downgrade(21); err != nil {
+		t.Fatalf("Downgrade failed: %v", err)
+	}
+	var details string
+	var count int
+	if err := db.QueryRow("SELECT details, COUNT(*) FROM account_transactions WHERE id = 'a1'").Scan(&details, &count); err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	if details != "changed" || count != 1 {
+		t.Errorf("expected the first a1 to be kept, got %q (%d rows)", details, count)
+	}
+}
+
+func TestGetProductByNameOrID_ByNumberSuffix(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	products := []client.ProductInfo{
+		{ID: "card-001", ProductType: "CARD", Name: "Visa", CardNumber: "4454********6615", Currency: "AMD"},
+		{ID: "card-002", ProductType: "CARD", Name: "Master", CardNumber: "5100********1234", Currency: "AMD"},
+		{ID: "acc-001", ProductType: "ACCOUNT", Name: "Savings", AccountNumber: "1570000000001234", Currency: "USD"},
+		{ID: "acc-002", ProductType: "ACCOUNT", Name: "2024", AccountNumber: "1570000000002024", Currency: "USD"},
+	}
+	if err := db.UpsertProducts(products); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+
+	p, err := db.GetProductByNameOrID("6615")
+	if err != nil || p == nil || p.ID != "card-001" {
+		t.Errorf("expected card-001 by card number suffix, got %+v, %v", p, err)
+	}
+	p, err = db.GetProductByNameOrID("00001234")
+	if err != nil || p == nil || p.ID != "acc-001" {
+		t.Errorf("expected acc-001 by account number suffix, got %+v, %v", p, err)
+	}
+	// Names take priority over numbers
+	p, err = db.GetProductByNameOrID("2024")
+	if err != nil || p == nil || p.ID != "acc-002" {
+		t.Errorf("expected acc-002 by name, got %+v, %v", p, err)
+	}
+	// Too few digits to be a number
+	if p, err := db.GetProductByNameOrID("615"); err != nil || p != nil {
+		t.Errorf("expected no product for 3 digits, got %+v, %v", p, err)
+	}
+	if _, err := db.GetProductByNameOrID("1234"); err == nil || !strings.Contains(err.Error(), "ambiguous number") {
+		t.Errorf("expected an ambiguous number error, got %v", err)
+	}
+}
+
+func TestProductsCache(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if data, err := db.LoadProducts("client1", time.Time{}); err != nil || data != nil {
+		t.Errorf("expected no cached products, got %q, %v", data, err)
+	}
+	if err := db.SaveProducts("client1", []byte(`{"status":"SUCCESS"}`)); err != nil {
+		t.Fatalf("SaveProducts failed: %v", err)
+	}
+	if data, err := db.LoadProducts("client1", time.Now().Add(-time.Minute)); err != nil || string(data) != `{"status":"SUCCESS"}` {
+		t.Errorf("expected the cached products, got %q, %v", data, err)
+	}
+	if data, err := db.LoadProducts("client1", time.Now().Add(time.Minute)); err != nil || data != nil {
+		t.Errorf("expected products fetched before since to be ignored, got %q, %v", data, err)
+	}
+	if data, err := db.LoadProducts("client2", time.Time{}); err != nil || data != nil {
+		t.Errorf("expected no products of another client, got %q, %v", data, err)
+	}
+}
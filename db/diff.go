@@ -0,0 +1,144 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OpenReadOnly opens an existing database for reading without migrating it,
+// e.g. a copy from another machine that may be at a different schema version
+func OpenReadOnly(path string) (*DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	sqlDB, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return &DB{DB: sqlDB}, nil
+}
+
+// DiffRow is a product or transaction stored in only one of two databases
+type DiffRow struct {
+	Table     string  `json:"table"`
+	ProductID string  `json:"product_id"`
+	ID        string  `json:"id"`
+	Date      string  `json:"date,omitempty"` // UTC RFC 3339
+	Amount    float64 `json:"amount"`         // balance for products
+	Currency  string  `json:"currency,omitempty"`
+	Details   string  `json:"details,omitempty"` // name for products
+}
+
+// DatabaseDiff holds the rows stored in only one of two databases, ordered
+// by table, date and ID
+type DatabaseDiff struct {
+	OnlyHere  []DiffRow `json:"only_here"`  // in the database Diff is called on
+	OnlyOther []DiffRow `json:"only_other"` // in the other database
+}
+
+// diffTables are the tables compared by Diff. Rows are identified by table
+// and key: the ID, plus the date for transaction tables, whose IDs repeat.
+var diffTables = []struct {
+	table string
+	query string
+	keyed bool // key includes the date
+}{
+	{"products", "SELECT id, id, NULL, balance, currency, name FROM products", false},
+	{"card_transactions", `SELECT product_id, id, operation_date, amount_value, amount_currency, details
+		FROM card_transactions`, true},
+	{"card_linked_account_transactions", `SELECT product_id, id, operation_date, amount_value, amount_currency, details
+		FROM card_linked_account_transactions`, true},
+	{"account_transactions", `SELECT product_id, id, transaction_date, transaction_amount_value,
+		transaction_amount_currency, details FROM account_transactions`, true},
+}
+
+// Diff compares the products and transactions of two databases, returning
+// the rows stored in only one of them. Dates are compared normalized, so
+// databases from before version 18 compare with newer ones.
+func (db *DB) Diff(other *DB) (*DatabaseDiff, error) {
+	diff := &DatabaseDiff{}
+	for _, t := range diffTables {
+		here, err := db.diffRows(t.table, t.query, t.keyed)
+		if err != nil {
+			return nil, err
+		}
+		there, err := other.diffRows(t.table, t.query, t.keyed)
+		if err != nil {
+			return nil, fmt.Errorf("other database: %w", err)
+		}
+		diff.OnlyHere = append(diff.OnlyHere, missingRows(here, there)...)
+		diff.OnlyOther = append(diff.OnlyOther, missingRows(there, here)...)
+	}
+	return diff, nil
+}
+
+// diffRows reads the rows of a table compared by Diff, by key
+func (db *DB) diffRows(table, query string, keyed bool) (map[string]DiffRow, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]DiffRow)
+	for rows.Next() {
+		r := DiffRow{Table: table}
+		var date, currency, details sql.NullString
+		var amount sql.NullFloat64
+		if err := rows.Scan(&r.ProductID, &r.ID, &date, &amount, &currency, &details); err != nil {
+			return nil, fmt.Errorf("failed to scan %s row: %w", table, err)
+		}
+		r.Date = diffDate(date.String)
+		r.Amount = amount.Float64
+		r.Currency = currency.String
+		r.Details = details.String
+		key := r.ID
+		if keyed {
+			key += "\x00" + r.Date
+		}
+		result[key] = r
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating %s: %w", table, err)
+	}
+
+	return result, nil
+}
+
+// diffDate normalizes an operation date, or an account transaction date in
+// Unix milliseconds, to UTC RFC 3339
+func diffDate(s string) string {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if ms <= 0 {
+			return ""
+		}
+		return time.UnixMilli(ms).UTC().Format(time.RFC3339)
+	}
+	return NormalizeDate(s)
+}
+
+// missingRows returns the rows of a not in b, ordered by date and ID
+func missingRows(a, b map[string]DiffRow) []DiffRow {
+	var missing []DiffRow
+	for key, r := range a {
+		if _, ok := b[key]; !ok {
+			missing = append(missing, r)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Date != missing[j].Date {
+			return missing[i].Date < missing[j].Date
+		}
+		return missing[i].ID < missing[j].ID
+	})
+	return missing
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestDiff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other.db")
+	other, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	card := client.ProductInfo{ID: "card1", ProductType: "CARD", Name: "Visa", Currency: "AMD"}
+	account := client.ProductInfo{ID: "acc1", ProductType: "ACCOUNT", Name: "Savings", Currency: "AMD"}
+	if err := db.UpsertProducts([]client.ProductInfo{card}); err != nil {
+		t.Fatalf("UpsertProducts failed: %v", err)
+	}
+	if err := other.UpsertProducts([]client.ProductInfo{card, account}); err != nil {
+		t.Fatalf("UpsertProducts failed: %v", err)
+	}
+
+	// c1 is in both, with the same operation date written differently;
+	// c2 has the same ID but another date in the other database
+	here := []client.Transaction{
+		{ID: "c1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 100}, OperationDate: "2024-03-01T12:00:00Z"},
+		{ID: "c2", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 200}, OperationDate: "2024-03-02T12:00:00Z", Details: "Cafe"},
+	}
+	there := []client.Transaction{
+		{ID: "c1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 100}, OperationDate: "2024-03-01T16:00:00+04:00"},
+		{ID: "c2", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 300}, OperationDate: "2024-03-05T12:00:00Z"},
+	}
+	if _, err := db.InsertCardTransactions("card1", here); err != nil {
+		t.Fatalf("InsertCardTransactions failed: %v", err)
+	}
+	if _, err := other.InsertCardTransactions("card1", there); err != nil {
+		t.Fatalf("InsertCardTransactions failed: %v", err)
+	}
+	at := time.Date(2024, 3, 3, 9, 0, 0, 0, time.UTC)
+	accountTxns := []client.AccountTransaction{{
+		ID:                "a1",
+		FlowDirection:     "INCOME",
+		TransactionDate:   at.UnixMilli(),
+		TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 5000},
+	}}
+	if _, err := other.InsertAccountTransactions("acc1", accountTxns); err != nil {
+		t.Fatalf("InsertAccountTransactions failed: %v", err)
+	}
+	other.Close()
+
+	other, err = OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("OpenReadOnly failed: %v", err)
+	}
+	defer other.Close()
+	if _, err := other.Exec("DELETE FROM products"); err == nil {
+		t.Error("expected the read-only database to refuse writes")
+	}
+
+	diff, err := db.Diff(other)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(diff.OnlyHere) != 1 {
+		t.Fatalf("expected 1 row only here, got %+v", diff.OnlyHere)
+	}
+	if r := diff.OnlyHere[0]; r.Table != "card_transactions" || r.ID != "c2" || r.Date != "2024-03-02T12:00:00Z" || r.Amount != 200 || r.Details != "Cafe" {
+		t.Errorf("unexpected row only here: %+v", r)
+	}
+	var got []string
+	for _, r := range diff.OnlyOther {
+		got = append(got, r.Table+"/"+r.ID+"@"+r.Date)
+	}
+	want := []string{
+		"products/acc1@",
+		"card_transactions/c2@2024-03-05T12:00:00Z",
+		"account_transactions/a1@2024-03-03T09:00:00Z",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("rows only in the other database = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d only in the other database = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	if _, err := OpenReadOnly(filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Error("expected an error opening a missing database")
+	}
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+// FixtureOptions configures GenerateFixture
+type FixtureOptions struct {
+	Cards        int       // cards, each with a linked account (default 2)
+	Accounts     int       // current accounts (default 1)
+	Transactions int       // card transactions over all cards (default 100000)
+	Seed         uint64    // the same seed generates the same data
+	End          time.Time // time of the newest transaction (default now)
+}
+
+// fixtureMerchants are the counterparties of generated transactions. The
+// monthly ones recur on the same day each month.
+var fixtureMerchants = []struct {
+	name    string
+	amount  float64 // typical amount in AMD
+	monthly bool
+}{
+	{"YEREVAN CITY SUPERMARKET", 12000, false},
+	{"SAS SUPERMARKET", 8000, false},
+	{"GG TAXI", 1500, false},
+	{"YANDEX GO", 1800, false},
+	{"COFFEE HOUSE", 2500, false},
+	{"TASHIR PIZZA", 6000, false},
+	{"ALFA PHARM", 4500, false},
+	{"WILDBERRIES", 15000, false},
+	{"NETFLIX.COM", 4000, true},
+	{"UCOM", 9000, true},
+	{"FITNESS CLUB", 25000, true},
+}
+
+// GenerateFixture fills the database with deterministic, realistic-looking
+// products and transactions, for benchmarks and for profiling commands on a
+// large database. Each card transaction gets a linked account counterpart
+// with the same amount a few seconds later; every tenth linked account
+// transaction is a transfer without a card counterpart. Accounts get as many
+// transactions as an average card.
+func (db *DB) GenerateFixture(opts FixtureOptions) error {
+	if opts.Cards <= 0 {
+		opts.Cards = 2
+	}
+	if opts.Accounts <= 0 {
+		opts.Accounts = 1
+	}
+	if opts.Transactions <= 0 {
+		opts.Transactions = 100000
+	}
+	if opts.End.IsZero() {
+		opts.End = time.Now()
+	}
+	rng := newFixtureRand(opts.Seed)
+
+	var products []client.ProductInfo
+	for i := range opts.Cards {
+		products = append(products, client.ProductInfo{
+			ProductType: "CARD",
+			ID:          fmt.Sprintf("card-%03d", i+1),
+			Name:        fmt.Sprintf("Card %d", i+1),
+			CardNumber:  fmt.Sprintf("4083****%04d", 1000+i),
+			AccountID:   fmt.Sprintf("card-account-%03d", i+1),
+			Currency:    "AMD",
+			Balance:     float64(rng.IntN(2000000)),
+			Status:      "ACTIVE",
+		})
+	}
+	for i := range opts.Accounts {
+		products = append(products, client.ProductInfo{
+			ProductType:   "ACCOUNT",
+			ID:            fmt.Sprintf("account-%03d", i+1),
+			Name:          fmt.Sprintf("Account %d", i+1),
+			AccountNumber: fmt.Sprintf("1570000000%06d", i+1),
+			Currency:      "AMD",
+			Balance:       float64(rng.IntN(5000000)),
+			Status:        "ACTIVE",
+		})
+	}
+	for i := range products {
+		products[i].AvailableBalance = products[i].Balance
+	}
+	if err := db.UpsertProducts(products); err != nil {
+		return err
+	}
+
+	perProduct := opts.Transactions / opts.Cards
+	for i := range opts.Cards {
+		card, linked := fixtureCardTransactions(rng, i, perProduct, opts.End)
+		if _, err := db.InsertCardTransactions(products[i].ID, card); err != nil {
+			return err
+		}
+		if _, err := db.InsertLinkedAccountTransactions(products[i].ID, linked); err != nil {
+			return err
+		}
+	}
+	for i := range opts.Accounts {
+		txns := fixtureAccountTransactions(rng, i, perProduct, opts.End)
+		if _, err := db.InsertAccountTransactions(products[opts.Cards+i].ID, txns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newFixtureRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, 0))
+}
+
+// fixtureCardTransactions generates n card transactions of a card, newest
+// first, with their linked account counterparts and some transfers
+func fixtureCardTransactions(rng *rand.Rand, card, n int, end time.Time) (cardTxns, linkedTxns []client.Transaction) {
+	at := end
+	for j := range n {
+		// About 8 purchases a day
+		at = at.Add(-time.Duration(rng.Int64N(int64(6 * time.Hour))))
+		m := fixtureMerchants[rng.IntN(len(fixtureMerchants))]
+		date := at
+		if m.monthly {
+			date = time.Date(at.Year(), at.Month(), 5, 9, 0, 0, 0, time.UTC)
+		}
+		amount := float64(int(m.amount * (0.5 + rng.Float64())))
+		id := fmt.Sprintf("c%d-%07d", card, j)
+		txn := client.Transaction{
+			ID:                       id,
+			TransactionType:          "PURCHASE",
+			AccountingType:           "DEBIT",
+			State:                    "SETTLED",
+			Amount:                   client.Amount{Currency: "AMD", Amount: amount},
+			CorrespondentAccountName: m.name,
+			Details:                  "Purchase, " + m.name,
+			OperationDate:            date.UTC().Format(time.RFC3339),
+		}
+		cardTxns = append(cardTxns, txn)
+
+		txn.ID = "l" + id
+		txn.TransactionType = "card"
+		txn.OperationDate = date.Add(time.Duration(rng.IntN(30)) * time.Second).UTC().Format(time.RFC3339)
+		linkedTxns = append(linkedTxns, txn)
+
+		if j%10 == 0 {
+			linkedTxns = append(linkedTxns, client.Transaction{
+				ID:                       fmt.Sprintf("t%d-%07d", card, j),
+				TransactionType:          "transfer:to-card",
+				AccountingType:           "CREDIT",
+				State:                    "SETTLED",
+				Amount:                   client.Amount{Currency: "AMD", Amount: float64(10000 * (1 + rng.IntN(50)))},
+				CorrespondentAccountName: "SALARY",
+				Details:                  "Transfer to card",
+				OperationDate:            at.Add(-time.Minute).UTC().Format(time.RFC3339),
+			})
+		}
+	}
+	return cardTxns, linkedTxns
+}
+
+// fixtureAccountTransactions generates n account transactions, newest first
+func fixtureAccountTransactions(rng *rand.Rand, account, n int, end time.Time) []client.AccountTransaction {
+	var txns []client.AccountTransaction
+	at := end
+	for j := range n {
+		at = at.Add(-time.Duration(rng.Int64N(int64(6 * time.Hour))))
+		direction, beneficiary := "EXPENSE", fixtureMerchants[rng.IntN(len(fixtureMerchants))].name
+		if j%5 == 0 {
+			direction, beneficiary = "INCOME", "EMPLOYER LLC"
+		}
+		amount := client.TransactionAmt{Currency: "AMD", Value: float64(1000 * (1 + rng.IntN(100)))}
+		txns = append(txns, client.AccountTransaction{
+			ID:                fmt.Sprintf("a%d-%07d", account, j),
+			Status:            "COMPLETED",
+			TransactionType:   "TRANSFER",
+			FlowDirection:     direction,
+			TransactionDate:   at.UnixMilli(),
+			SettledDate:       at.UnixMilli(),
+			BeneficiaryName:   beneficiary,
+			Details:           "Payment to " + beneficiary,
+			TransactionAmount: amount,
+			SettledAmount:     amount,
+			DomesticAmount:    amount,
+		})
+	}
+	return txns
+}
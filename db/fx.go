@@ -0,0 +1,316 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// LocalCurrency is the currency exchange rates are quoted in
+const LocalCurrency = "AMD"
+
+// ExchangeRates maps currency codes to their value in LocalCurrency
+type ExchangeRates map[string]float64
+
+// Convert converts an amount between currencies.
+// Returns false if a rate for either currency is missing.
+func (r ExchangeRates) Convert(amount float64, from, to string) (float64, bool) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return amount, true
+	}
+	fromRate, ok := r.rate(from)
+	if !ok {
+		return 0, false
+	}
+	toRate, ok := r.rate(to)
+	if !ok {
+		return 0, false
+	}
+	return amount * fromRate / toRate, true
+}
+
+func (r ExchangeRates) rate(currency string) (float64, bool) {
+	if currency == LocalCurrency {
+		return 1, true
+	}
+	rate, ok := r[currency]
+	return rate, ok && rate > 0
+}
+
+// SetExchangeRate stores the value of one unit of currency in LocalCurrency,
+// recording it as today's rate as well (see RateHistory)
+func (db *DB) SetExchangeRate(currency string, rate float64, source string) error {
+	return db.WithTransaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO exchange_rates (currency, rate, source, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(currency) DO UPDATE SET
+				rate = excluded.rate,
+				source = excluded.source,
+				updated_at = excluded.updated_at
+		`, strings.ToUpper(currency), rate, nullString(source), time.Now().Unix())
+		if err != nil {
+			return fmt.Errorf("failed to store exchange rate: %w", err)
+		}
+		return recordExchangeRate(tx, currency, rate, source, time.Now())
+	})
+}
+
+// SetHistoricalExchangeRate records the rate of currency on the day of at
+// without changing the current rate
+func (db *DB) SetHistoricalExchangeRate(currency string, rate float64, source string, at time.Time) error {
+	return db.WithTransaction(func(tx *sql.Tx) error {
+		return recordExchangeRate(tx, currency, rate, source, at)
+	})
+}
+
+// recordExchangeRate stores the rate of currency on the local day of at,
+// replacing one recorded earlier that day
+func recordExchangeRate(tx *sql.Tx, currency string, rate float64, source string, at time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO exchange_rate_history (currency, day, rate, source) VALUES (?, ?, ?, ?)
+		ON CONFLICT(currency, day) DO UPDATE SET
+			rate = excluded.rate,
+			source = excluded.source
+	`, strings.ToUpper(currency), at.Local().Format(rateDayLayout), rate, nullString(source))
+	if err != nil {
+		return fmt.Errorf("failed to record exchange rate: %w", err)
+	}
+	return nil
+}
+
+// GetExchangeRates returns all stored exchange rates
+func (db *DB) GetExchangeRates() (ExchangeRates, error) {
+	rows, err := db.Query(`SELECT currency, rate FROM exchange_rates`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exchange rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := make(ExchangeRates)
+	for rows.Next() {
+		var currency string
+		var rate float64
+		if err := rows.Scan(&currency, &rate); err != nil {
+			return nil, fmt.Errorf("failed to scan exchange rate: %w", err)
+		}
+		rates[currency] = rate
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating exchange rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// rateDayLayout is the format of the days of exchange_rate_history
+const rateDayLayout = "2006-01-02"
+
+// datedRate is the rate of a currency recorded on a day
+type datedRate struct {
+	day  string
+	rate float64
+}
+
+// RateHistory converts amounts at the exchange rates of their dates, as
+// recorded each day rates were stored. Dates before the first recorded day
+// take the earliest rate; currencies without history take the current rate.
+type RateHistory struct {
+	days    map[string][]datedRate // by currency, oldest first
+	current ExchangeRates
+}
+
+// GetRateHistory returns the recorded exchange rates with the current ones
+func (db *DB) GetRateHistory() (*RateHistory, error) {
+	current, err := db.GetExchangeRates()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT currency, day, rate FROM exchange_rate_history ORDER BY currency, day`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exchange rate history: %w", err)
+	}
+	defer rows.Close()
+
+	h := &RateHistory{days: make(map[string][]datedRate), current: current}
+	for rows.Next() {
+		var currency string
+		var r datedRate
+		if err := rows.Scan(&currency, &r.day, &r.rate); err != nil {
+			return nil, fmt.Errorf("failed to scan exchange rate: %w", err)
+		}
+		if r.rate > 0 {
+			h.days[currency] = append(h.days[currency], r)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating exchange rate history: %w", err)
+	}
+
+	return h, nil
+}
+
+// rate returns the value of one unit of currency in LocalCurrency on the day of at
+func (h *RateHistory) rate(currency string, at time.Time) (float64, bool) {
+	if currency == LocalCurrency {
+		return 1, true
+	}
+	days := h.days[currency]
+	if len(days) == 0 {
+		return h.current.rate(currency)
+	}
+	day := at.Local().Format(rateDayLayout)
+	// The last day recorded on or before the date, or the first one
+	i := sort.Search(len(days), func(i int) bool { return days[i].day > day })
+	if i > 0 {
+		i--
+	}
+	return days[i].rate, true
+}
+
+// Convert converts an amount between currencies at the rates of the day of at.
+// Returns false if a rate for either currency is missing.
+func (h *RateHistory) Convert(amount float64, from, to string, at time.Time) (float64, bool) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return amount, true
+	}
+	fromRate, ok := h.rate(from, at)
+	if !ok {
+		return 0, false
+	}
+	toRate, ok := h.rate(to, at)
+	if !ok {
+		return 0, false
+	}
+	return money.FromFloat(amount * fromRate / toRate).Float(), true
+}
+
+// ConvertEntries converts entries to currency at the rates of their dates,
+// keeping the original amount and currency. Entries without a rate are left
+// as they are; their number is returned.
+func (h *RateHistory) ConvertEntries(entries []LedgerEntry, currency string) int {
+	currency = strings.ToUpper(currency)
+	var missing int
+	for i := range entries {
+		e := &entries[i]
+		if strings.EqualFold(e.Currency, currency) {
+			continue
+		}
+		amount, ok := h.Convert(e.Amount, e.Currency, currency, e.Date)
+		if !ok {
+			missing++
+			continue
+		}
+		e.OriginalAmount, e.OriginalCurrency = e.Amount, e.Currency
+		e.Amount, e.Currency = amount, currency
+	}
+	return missing
+}
+
+// CurrencyPosition is the total held in one currency
+type CurrencyPosition struct {
+	Currency string
+	Accounts float64 // available balance of accounts and standalone cards
+	Deposits float64
+	Loans    float64 // outstanding principal
+}
+
+// Net returns the holdings minus loans
+func (p CurrencyPosition) Net() float64 {
+	return money.Sub(money.Add(p.Accounts, p.Deposits), p.Loans)
+}
+
+// GetCurrencyPositions sums balances, deposits and loans per currency, sorted by currency.
+// Cards whose linked account is also a product are skipped, since they share its balance.
+func (db *DB) GetCurrencyPositions() ([]CurrencyPosition, error) {
+	products, err := db.GetProducts()
+	if err != nil {
+		return nil, err
+	}
+	deposits, err := db.GetDeposits()
+	if err != nil {
+		return nil, err
+	}
+	loans, err := db.GetLoans()
+	if err != nil {
+		return nil, err
+	}
+
+	byCurrency := make(map[string]*CurrencyPosition)
+	position := func(currency string) *CurrencyPosition {
+		currency = strings.ToUpper(currency)
+		p, ok := byCurrency[currency]
+		if !ok {
+			p = &CurrencyPosition{Currency: currency}
+			byCurrency[currency] = p
+		}
+		return p
+	}
+
+	accountIDs := make(map[string]bool)
+	for _, p := range products {
+		if p.ProductType != "CARD" {
+			accountIDs[p.ID] = true
+		}
+	}
+	for _, p := range products {
+		if p.ProductType == "CARD" && accountIDs[p.AccountID] {
+			continue
+		}
+		pos := position(p.Currency)
+		pos.Accounts = money.Add(pos.Accounts, p.AvailableBalance)
+	}
+	for _, d := range deposits {
+		pos := position(d.Currency)
+		pos.Deposits = money.Add(pos.Deposits, d.Amount)
+	}
+	for _, l := range loans {
+		pos := position(l.Currency)
+		pos.Loans = money.Add(pos.Loans, l.OutstandingAmount)
+	}
+
+	positions := make([]CurrencyPosition, 0, len(byCurrency))
+	for _, p := range byCurrency {
+		positions = append(positions, *p)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].Currency < positions[j].Currency
+	})
+	return positions, nil
+}
+
+// exchangeKeywords identify currency exchange transactions by type or details
+var exchangeKeywords = []string{"exchange", "conversion", "convert", "փոխանակ"}
+
+// IsExchange returns true if the entry looks like a currency exchange
+func (e LedgerEntry) IsExchange() bool {
+	text := strings.ToLower(e.Type + " " + e.Details)
+	for _, kw := range exchangeKeywords {
+		if strings.Contains(text, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetExchangeTransactions returns currency exchange transactions on or after from, oldest first
+func (db *DB) GetExchangeTransactions(from time.Time) ([]LedgerEntry, error) {
+	entries, err := db.GetLedgerEntries(LedgerOptions{From: from})
+	if err != nil {
+		return nil, err
+	}
+	var result []LedgerEntry
+	for _, e := range entries {
+		if e.IsExchange() {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestExchangeRatesConvert(t *testing.T) {
+	rates := ExchangeRates{"USD": 400, "EUR": 440}
+
+	tests := []struct {
+		amount   float64
+		from, to string
+		want     float64
+		ok       bool
+	}{
+		{100, "USD", "AMD", 40000, true},
+		{40000, "AMD", "USD", 100, true},
+		{100, "eur", "USD", 110, true},
+		{5, "RUB", "RUB", 5, true},
+		{5, "RUB", "AMD", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := rates.Convert(tt.amount, tt.from, tt.to)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Convert(%v, %s, %s) = %v, %v; want %v, %v", tt.amount, tt.from, tt.to, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExchangeRatesStorage(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SetExchangeRate("usd", 390, "manual"); err != nil {
+		t.Fatalf("SetExchangeRate failed: %v", err)
+	}
+	if err := db.SetExchangeRate("USD", 395, "manual"); err != nil {
+		t.Fatalf("SetExchangeRate failed: %v", err)
+	}
+
+	rates, err := db.GetExchangeRates()
+	if err != nil {
+		t.Fatalf("GetExchangeRates failed: %v", err)
+	}
+	if len(rates) != 1 || rates["USD"] != 395 {
+		t.Errorf("unexpected rates: %v", rates)
+	}
+}
+
+func TestGetCurrencyPositions(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	products := []client.ProductInfo{
+		{ID: "card1", ProductType: "CARD", AccountID: "acc1", Currency: "AMD", AvailableBalance: 1000},
+		{ID: "acc1", ProductType: "ACCOUNT", Currency: "AMD", AvailableBalance: 1000},
+		{ID: "card2", ProductType: "CARD", AccountID: "hidden", Currency: "USD", AvailableBalance: 50},
+	}
+	if err := db.UpsertProducts(products); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+	if err := db.ReplaceDeposits([]client.DepositDetails{{DepositInfo: client.DepositInfo{ID: "D1", Currency: "USD", Amount: 500}}}, nil); err != nil {
+		t.Fatalf("ReplaceDeposits failed: %v", err)
+	}
+	if err := db.ReplaceLoans([]client.LoanDetails{{LoanInfo: client.LoanInfo{ID: "L1", Currency: "AMD", OutstandingAmount: 300}}}); err != nil {
+		t.Fatalf("ReplaceLoans failed: %v", err)
+	}
+
+	positions, err := db.GetCurrencyPositions()
+	if err != nil {
+		t.Fatalf("GetCurrencyPositions failed: %v", err)
+	}
+	want := []CurrencyPosition{
+		{Currency: "AMD", Accounts: 1000, Loans: 300},
+		{Currency: "USD", Accounts: 50, Deposits: 500},
+	}
+	if len(positions) != len(want) {
+		t.Fatalf("expected %d positions, got %+v", len(want), positions)
+	}
+	for i := range want {
+		if positions[i] != want[i] {
+			t.Errorf("position %d: expected %+v, got %+v", i, want[i], positions[i])
+		}
+	}
+	if positions[0].Net() != 700 {
+		t.Errorf("expected AMD net 700, got %v", positions[0].Net())
+	}
+}
+
+func TestGetExchangeTransactions(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now().UTC()
+	txns := []client.Transaction{
+		{ID: "x1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 40000}, Details: "Currency exchange USD", OperationDate: now.Format(time.RFC3339)},
+		{ID: "x2", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 500}, Details: "Coffee", OperationDate: now.Format(time.RFC3339)},
+		{ID: "x3", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 500}, Details: "Conversion", OperationDate: now.AddDate(0, -3, 0).Format(time.RFC3339)},
+	}
+	if _, err := db.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+
+	entries, err := db.GetExchangeTransactions(now.AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("GetExchangeTransactions failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != "x1" {
+		t.Errorf("expected only x1, got %+v", entries)
+	}
+}
+
+func TestRateHistory(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	day := func(s string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d.Add(12 * time.Hour)
+	}
+	if err := db.SetExchangeRate("USD", 390, "manual"); err != nil {
+		t.Fatalf("SetExchangeRate failed: %v", err)
+	}
+	if err := db.SetExchangeRate("EUR", 420, "manual"); err != nil {
+		t.Fatalf("SetExchangeRate failed: %v", err)
+	}
+	for _, r := range []struct {
+		day  string
+		rate float64
+	}{{"2024-03-01", 400}, {"2024-06-01", 380}} {
+		if err := db.SetHistoricalExchangeRate("usd", r.rate, "manual", day(r.day)); err != nil {
+			t.Fatalf("SetHistoricalExchangeRate failed: %v", err)
+		}
+	}
+
+	rates, err := db.GetExchangeRates()
+	if err != nil {
+		t.Fatalf("GetExchangeRates failed: %v", err)
+	}
+	if rates["USD"] != 390 {
+		t.Errorf("historical rates shouldn't change the current one, got %v", rates["USD"])
+	}
+
+	h, err := db.GetRateHistory()
+	if err != nil {
+		t.Fatalf("GetRateHistory failed: %v", err)
+	}
+	tests := []struct {
+		amount   float64
+		from, to string
+		at       string
+		want     float64
+		ok       bool
+	}{
+		{10, "USD", "AMD", "2024-01-15", 4000, true}, // before the first day: earliest rate
+		{10, "USD", "AMD", "2024-03-01", 4000, true},
+		{10, "USD", "AMD", "2024-05-31", 4000, true},
+		{10, "USD", "AMD", "2024-06-01", 3800, true},
+		{4200, "AMD", "EUR", "2024-03-01", 10, true}, // no history: current rate
+		{10, "RUB", "AMD", "2024-03-01", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := h.Convert(tt.amount, tt.from, tt.to, day(tt.at))
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Convert(%v, %s, %s, %s) = %v, %v; want %v, %v", tt.amount, tt.from, tt.to, tt.at, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	entries := []LedgerEntry{
+		{Date: day("2024-03-02"), Amount: 10, Currency: "USD"},
+		{Date: day("2024-03-02"), Amount: 500, Currency: "AMD"},
+		{Date: day("2024-03-02"), Amount: 5, Currency: "RUB"},
+	}
+	if missing := h.ConvertEntries(entries, "amd"); missing != 1 {
+		t.Errorf("expected 1 entry without a rate, got %d", missing)
+	}
+	if e := entries[0]; e.Amount != 4000 || e.Currency != "AMD" || e.OriginalAmount != 10 || e.OriginalCurrency != "USD" {
+		t.Errorf("unexpected converted entry: %+v", e)
+	}
+	if e := entries[1]; e.OriginalCurrency != "" || e.Amount != 500 {
+		t.Errorf("entries in the target currency should be left alone, got %+v", e)
+	}
+	if e := entries[2]; e.OriginalCurrency != "" || e.Currency != "RUB" {
+		t.Errorf("entries without a rate should be left alone, got %+v", e)
+	}
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+// ReplaceCardHolds replaces the stored pending authorizations of a card
+// (settled holds disappear from the API and show up as card transactions)
+func (db *DB) ReplaceCardHolds(productID string, holds []client.Transaction) error {
+	syncedAt := time.Now().Unix()
+
+	return db.WithTransaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM card_holds WHERE product_id = ?", productID); err != nil {
+			return fmt.Errorf("failed to clear card holds: %w", err)
+		}
+
+		stmt, err := tx.Prepare(`
+			INSERT INTO card_holds (
+				product_id, id, transaction_type, accounting_type, state,
+				amount_currency, amount_value, details, operation_date, order_index, synced_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for i, h := range holds {
+			_, err := stmt.Exec(
+				productID, h.ID, h.TransactionType, h.AccountingType, h.State,
+				h.Amount.Currency, h.Amount.Amount, h.Details, NormalizeDate(h.OperationDate), i, syncedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert card hold %s: %w", h.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetCardHolds retrieves the stored pending authorizations of a card in API order
+func (db *DB) GetCardHolds(productID string) ([]client.Transaction, error) {
+	rows, err := db.query(`
+		SELECT id, transaction_type, accounting_type, state,
+			   amount_currency, amount_value, details, operation_date
+		FROM card_holds
+		WHERE product_id = ?
+		ORDER BY order_index
+	`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query card holds: %w", err)
+	}
+	defer rows.Close()
+
+	var holds []client.Transaction
+	for rows.Next() {
+		var h client.Transaction
+		var id, txnType, accountingType, state, currency, details, operationDate sql.NullString
+		var amount sql.NullFloat64
+		if err := rows.Scan(&id, &txnType, &accountingType, &state, &currency, &amount, &details, &operationDate); err != nil {
+			return nil, fmt.Errorf("failed to scan card hold: %w", err)
+		}
+		h.ID = id.String
+		h.TransactionType = txnType.String
+		h.AccountingType = accountingType.String
+		h.State = state.String
+		h.Amount.Currency = currency.String
+		h.Amount.Amount = amount.Float64
+		h.Details = details.String
+		h.OperationDate = operationDate.String
+		holds = append(holds, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating card holds: %w", err)
+	}
+
+	return holds, nil
+}
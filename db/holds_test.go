@@ -0,0 +1,48 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestReplaceCardHolds(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	hold := func(id string, amount float64) client.Transaction {
+		return client.Transaction{
+			ID: id, TransactionType: "purchase:pos", AccountingType: "DEBIT", State: "pending",
+			Amount: client.Amount{Currency: "AMD", Amount: amount}, Details: "Shop " + id,
+			OperationDate: "2024-01-15T10:00:00Z",
+		}
+	}
+	if err := db.ReplaceCardHolds("card1", []client.Transaction{hold("h2", 2000), hold("h1", 1000)}); err != nil {
+		t.Fatalf("ReplaceCardHolds failed: %v", err)
+	}
+	if err := db.ReplaceCardHolds("card2", []client.Transaction{hold("h3", 3000)}); err != nil {
+		t.Fatalf("ReplaceCardHolds failed: %v", err)
+	}
+
+	got, err := db.GetCardHolds("card1")
+	if err != nil {
+		t.Fatalf("GetCardHolds failed: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "h2" || got[0].Amount.Amount != 2000 || got[1].Details != "Shop h1" {
+		t.Errorf("unexpected holds: %+v", got)
+	}
+
+	// Settled holds disappear on the next sync; other cards are left alone
+	if err := db.ReplaceCardHolds("card1", nil); err != nil {
+		t.Fatalf("ReplaceCardHolds failed: %v", err)
+	}
+	if got, _ := db.GetCardHolds("card1"); len(got) != 0 {
+		t.Errorf("expected no holds for card1, got %+v", got)
+	}
+	if got, _ := db.GetCardHolds("card2"); len(got) != 1 || got[0].ID != "h3" {
+		t.Errorf("expected card2 holds to be kept, got %+v", got)
+	}
+}
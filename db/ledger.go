@@ -0,0 +1,351 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+// Ledger entry sources (transaction tables)
+const (
+	SourceCard    = "card"
+	SourceLinked  = "linked"
+	SourceAccount = "account"
+)
+
+// LedgerEntry is a normalized view of a transaction from any transaction table
+type LedgerEntry struct {
+	Source       string    `json:"source"` // SourceCard, SourceLinked or SourceAccount
+	ProductID    string    `json:"product_id"`
+	ID           string    `json:"id"`
+	Date         time.Time `json:"date"`
+	Type         string    `json:"type,omitempty"`
+	Credit       bool      `json:"credit"` // true for incoming money
+	Amount       float64   `json:"amount"`
+	Currency     string    `json:"currency"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	Details      string    `json:"details,omitempty"`
+	Workflow     string    `json:"workflow,omitempty"` // the bank's workflow code
+	SyncedAt     time.Time `json:"synced_at"`
+	RowID        int64     `json:"-"` // SQLite rowid in the source table, increases with insertion
+	// Amount and currency before conversion to another currency (--convert)
+	OriginalAmount   float64 `json:"original_amount,omitempty"`
+	OriginalCurrency string  `json:"original_currency,omitempty"`
+}
+
+// Direction returns the direction of the entry
+func (e LedgerEntry) Direction() client.Direction {
+	if e.Credit {
+		return client.DirectionCredit
+	}
+	return client.DirectionDebit
+}
+
+// SignedAmount returns the amount with a negative sign for debits
+func (e LedgerEntry) SignedAmount() float64 {
+	return e.Direction().Signed(e.Amount)
+}
+
+// LedgerOptions filters ledger entries
+type LedgerOptions struct {
+	ProductID   string           // empty = all products
+	ClientID    string           // only products of this client, empty = all clients
+	From        time.Time        // inclusive, zero = unbounded
+	To          time.Time        // exclusive, zero = unbounded
+	SyncedSince time.Time        // only entries synced at or after this time, zero = all
+	Sources     []string         // default: card and account transactions
+	AfterRowIDs map[string]int64 // per source, only entries inserted after this rowid
+}
+
+// GetLedgerEntries returns normalized transactions from the selected sources,
+// sorted by date (oldest first). Card and account transactions are used by default;
+// linked account transactions overlap with card transactions and must be requested explicitly.
+// Card authorizations settled by a linked purchase are left out, as with ReadOptions.CollapseStages.
+func (db *DB) GetLedgerEntries(opts LedgerOptions) ([]LedgerEntry, error) {
+	sources := opts.Sources
+	if len(sources) == 0 {
+		sources = []string{SourceCard, SourceAccount}
+	}
+
+	var entries []LedgerEntry
+	for _, source := range sources {
+		var sourceEntries []LedgerEntry
+		var err error
+		switch source {
+		case SourceCard:
+			sourceEntries, err = db.cardLedgerEntries("card_transactions", SourceCard, opts)
+		case SourceLinked:
+			sourceEntries, err = db.cardLedgerEntries("card_linked_account_transactions", SourceLinked, opts)
+		case SourceAccount:
+			sourceEntries, err = db.accountLedgerEntries(opts)
+		default:
+			return nil, fmt.Errorf("unknown ledger source %q", source)
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, sourceEntries...)
+	}
+
+	// Entries sharing a date are ordered by ID and then by insertion, so
+	// that running balances and diffs don't change between runs
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.RowID < b.RowID
+	})
+
+	return entries, nil
+}
+
+// matches applies the date and sync time filters of the options
+func (opts LedgerOptions) matches(e LedgerEntry) bool {
+	if !opts.From.IsZero() && e.Date.Before(opts.From) {
+		return false
+	}
+	if !opts.To.IsZero() && !e.Date.Before(opts.To) {
+		return false
+	}
+	if !opts.SyncedSince.IsZero() && e.SyncedAt.Before(opts.SyncedSince) {
+		return false
+	}
+	return true
+}
+
+// cardLedgerEntries reads entries from card_transactions or card_linked_account_transactions
+func (db *DB) cardLedgerEntries(table, source string, opts LedgerOptions) ([]LedgerEntry, error) {
+	beneficiaryCol := "NULL"
+	if source == SourceLinked {
+		beneficiaryCol = "beneficiary_name"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT rowid, product_id, id, operation_date, transaction_type, accounting_type,
+			   amount_value, amount_currency, correspondent_account_name, %s,
+			   details, workflow_code, synced_at
+		FROM %s t
+		WHERE rowid > ?
+	`, beneficiaryCol, table)
+	args := []interface{}{opts.AfterRowIDs[source]}
+	if source == SourceCard {
+		// An authorization is counted once, as the purchase settling it
+		query += " AND NOT EXISTS (" + settlementSQL + ")"
+	}
+	if opts.ProductID != "" {
+		query += " AND product_id = ?"
+		args = append(args, opts.ProductID)
+	}
+	if opts.ClientID != "" {
+		query += " AND product_id IN (SELECT id FROM products WHERE client_id = ?)"
+		args = append(args, opts.ClientID)
+	}
+	query += " ORDER BY rowid"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var entries []LedgerEntry
+	for rows.Next() {
+		var e LedgerEntry
+		var operationDate string
+		var txnType, accountingType, currency, correspondent, beneficiary, details, workflow sql.NullString
+		var amount sql.NullFloat64
+		var syncedAt int64
+
+		err := rows.Scan(&e.RowID, &e.ProductID, &e.ID, &operationDate, &txnType, &accountingType,
+			&amount, &currency, &correspondent, &beneficiary, &details, &workflow, &syncedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+
+		e.Source = source
+		e.Date, _ = ParseDate(operationDate)
+		e.Type = txnType.String
+		e.Credit = client.DirectionOfAccountingType(accountingType.String) == client.DirectionCredit
+		e.Amount = amount.Float64
+		e.Currency = currency.String
+		e.Details = details.String
+		e.Workflow = workflow.String
+		e.SyncedAt = time.Unix(syncedAt, 0)
+
+		// Prefer explicit counterparty fields, falling back to details (merchant name)
+		switch {
+		case beneficiary.String != "":
+			e.Counterparty = beneficiary.String
+		case correspondent.String != "":
+			e.Counterparty = correspondent.String
+		default:
+			e.Counterparty = e.Details
+		}
+
+		if opts.matches(e) {
+			entries = append(entries, e)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+
+	return entries, nil
+}
+
+// accountLedgerEntries reads entries from account_transactions
+func (db *DB) accountLedgerEntries(opts LedgerOptions) ([]LedgerEntry, error) {
+	query := `
+		SELECT rowid, product_id, id, transaction_date, transaction_type, flow_direction,
+			   transaction_amount_value, transaction_amount_currency,
+			   beneficiary_name, details, workflow_code, synced_at, debit_account_number, credit_account_number
+		FROM account_transactions
+		WHERE rowid > ?
+	`
+	args := []interface{}{opts.AfterRowIDs[SourceAccount]}
+	if opts.ProductID != "" {
+		query += " AND product_id = ?"
+		args = append(args, opts.ProductID)
+	}
+	if opts.ClientID != "" {
+		query += " AND product_id IN (SELECT id FROM products WHERE client_id = ?)"
+		args = append(args, opts.ClientID)
+	}
+	query += " ORDER BY rowid"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query account_transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []LedgerEntry
+	var unnamed []int    // entries without a beneficiary name, to be named from templates
+	var numbers []string // counterparty account numbers of unnamed entries
+	for rows.Next() {
+		var e LedgerEntry
+		var txnDate sql.NullInt64
+		var txnType, flowDirection, currency, beneficiary, details, workflow sql.NullString
+		var debitAccount, creditAccount sql.NullString
+		var amount sql.NullFloat64
+		var syncedAt int64
+
+		err := rows.Scan(&e.RowID, &e.ProductID, &e.ID, &txnDate, &txnType, &flowDirection,
+			&amount, &currency, &beneficiary, &details, &workflow, &syncedAt, &debitAccount, &creditAccount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+
+		e.Source = SourceAccount
+		if txnDate.Int64 > 0 {
+			e.Date = time.UnixMilli(txnDate.Int64)
+		}
+		e.Type = txnType.String
+		e.Credit = client.DirectionOfFlow(flowDirection.String) == client.DirectionCredit
+		e.Amount = amount.Float64
+		e.Currency = currency.String
+		e.Counterparty = beneficiary.String
+		e.Details = details.String
+		e.Workflow = workflow.String
+		e.SyncedAt = time.Unix(syncedAt, 0)
+
+		if opts.matches(e) {
+			if e.Counterparty == "" || e.Counterparty == PlaceholderBeneficiary {
+				t := client.AccountTransaction{
+					FlowDirection:       flowDirection.String,
+					DebitAccountNumber:  debitAccount.String,
+					CreditAccountNumber: creditAccount.String,
+				}
+				unnamed = append(unnamed, len(entries))
+				numbers = append(numbers, t.CounterpartyAccount())
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+	rows.Close()
+
+	// Name counterparties from transfer templates, or the counterparty
+	// directory, once the rows are released
+	labels := make(map[string]string)
+	for i, idx := range unnamed {
+		label, ok := labels[numbers[i]]
+		if !ok {
+			name, group, err := db.GetTemplateWithGroupByCounterparty(numbers[i])
+			if err != nil {
+				return nil, err
+			}
+			label = TemplateLabel(name, group)
+			if label == "" {
+				// Other transfers to the same number may have named it
+				if label, err = db.CounterpartyName(numbers[i]); err != nil {
+					return nil, err
+				}
+			}
+			labels[numbers[i]] = label
+		}
+		if label != "" {
+			entries[idx].Counterparty = label
+		}
+	}
+
+	return entries, nil
+}
+
+// EntryFilter selects ledger entries by direction, currency, amount and text
+type EntryFilter struct {
+	Type      string  // "credit", "debit" or empty for both
+	Currency  string  // empty = any currency
+	MinAmount float64 // 0 = no lower bound
+	MaxAmount float64 // 0 = no upper bound
+	Search    string  // case-insensitive substring of counterparty or details
+}
+
+// Validate checks the filter type
+func (f EntryFilter) Validate() error {
+	if f.Type != "" && f.Type != "credit" && f.Type != "debit" {
+		return fmt.Errorf("invalid transaction type %q (expected credit or debit)", f.Type)
+	}
+	return nil
+}
+
+// Matches returns true if the entry passes the filter
+func (f EntryFilter) Matches(e LedgerEntry) bool {
+	switch {
+	case f.Type == "credit" && !e.Credit, f.Type == "debit" && e.Credit:
+		return false
+	case f.Currency != "" && !strings.EqualFold(f.Currency, e.Currency):
+		return false
+	case f.MinAmount != 0 && e.Amount < f.MinAmount, f.MaxAmount != 0 && e.Amount > f.MaxAmount:
+		return false
+	case f.Search != "" && !strings.Contains(strings.ToLower(e.Counterparty+" "+e.Details), strings.ToLower(f.Search)):
+		return false
+	}
+	return true
+}
+
+// Apply returns the entries that pass the filter
+func (f EntryFilter) Apply(entries []LedgerEntry) []LedgerEntry {
+	matched := make([]LedgerEntry, 0, len(entries))
+	for _, e := range entries {
+		if f.Matches(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
@@ -3,61 +3,20 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
 )
 
 // InsertLinkedAccountTransactions inserts card linked account transactions (from GetEventsPast), ignoring duplicates
 func (db *DB) InsertLinkedAccountTransactions(productID string, txns []client.Transaction) (int, error) {
-	syncedAt := time.Now().Unix()
-	var inserted int
-
-	err := db.WithTransaction(func(tx *sql.Tx) error {
-		stmt, err := tx.Prepare(`
-			INSERT OR IGNORE INTO card_linked_account_transactions (
-				id, product_id, transaction_type, accounting_type, state,
-				amount_currency, amount_value, correspondent_account_number,
-				correspondent_account_name, details, operation_date,
-				workflow_code, date, year, month, synced_at
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		`)
-		if err != nil {
-			return fmt.Errorf("failed to prepare statement: %w", err)
-		}
-		defer stmt.Close()
-
-		for _, t := range txns {
-			result, err := stmt.Exec(
-				t.ID,
-				productID,
-				t.TransactionType,
-				t.AccountingType,
-				t.State,
-				t.Amount.Currency,
-				t.Amount.Amount,
-				t.CorrespondentAccountNumber,
-				t.CorrespondentAccountName,
-				t.Details,
-				t.OperationDate,
-				t.WorkflowCode,
-				t.Date,
-				t.Year,
-				t.Month,
-				syncedAt,
-			)
-			if err != nil {
-				return fmt.Errorf("failed to insert transaction %s: %w", t.ID, err)
-			}
-			rowsAffected, _ := result.RowsAffected()
-			if rowsAffected > 0 {
-				inserted++
-			}
-		}
-		return nil
-	})
+	return db.insertCardTransactions("card_linked_account_transactions", productID, txns)
+}
 
-	return inserted, err
+// ApplyLinkedAccountTransactions inserts new card linked account transactions and
+// updates the changed fields of stored ones (extended info is kept), recording the
+// changes. Returns the number of inserted and changed transactions.
+func (db *DB) ApplyLinkedAccountTransactions(productID string, txns []client.Transaction) (inserted, changed int, err error) {
+	return db.applyCardTransactions("card_linked_account_transactions", SourceLinked, productID, txns)
 }
 
 // GetLinkedAccountTransactions retrieves card linked account transactions for a product with optional pagination.
@@ -65,57 +24,51 @@ func (db *DB) InsertLinkedAccountTransactions(productID string, txns []client.Tr
 // If includeExtended is true, also loads extended info for transactions that have it.
 // If ascending is true, returns oldest first; otherwise newest first.
 func (db *DB) GetLinkedAccountTransactions(productID string, size, page int, includeExtended, ascending bool) ([]client.Transaction, error) {
-	var rows *sql.Rows
-	var err error
+	var txns []client.Transaction
+	opts := ReadOptions{Size: size, Page: page, Ascending: ascending, IncludeExtended: includeExtended}
+	err := db.ForEachLinkedAccountTransaction(productID, opts, func(t client.Transaction) error {
+		txns = append(txns, t)
+		return nil
+	})
+	return txns, err
+}
 
+// ForEachLinkedAccountTransaction calls fn for each card linked account
+// transaction of a product as the rows are read, like ForEachCardTransaction
+func (db *DB) ForEachLinkedAccountTransaction(productID string, opts ReadOptions, fn func(client.Transaction) error) error {
 	cols := `id, transaction_type, accounting_type, state,
 			 amount_currency, amount_value, correspondent_account_number,
 			 correspondent_account_name, details, operation_date,
 			 workflow_code, date, year, month`
-	if includeExtended {
+	if opts.IncludeExtended {
 		cols += `, beneficiary_name, beneficiary_address, credit_account_number,
-				  card_masked_number, ext_operation_id, swift_details, extended_fetched`
-	}
-
-	order := "DESC"
-	if ascending {
-		order = "ASC"
+				  card_masked_number, ext_operation_id, swift_details, extended_fetched,
+				  swift_sender_bank, swift_reference, swift_fees, swift_intermediary`
 	}
 
-	if size == 0 {
-		// No limit - return all
-		rows, err = db.Query(fmt.Sprintf(`
-			SELECT %s
-			FROM card_linked_account_transactions
-			WHERE product_id = ?
-			ORDER BY operation_date %s
-		`, cols, order), productID)
-	} else {
-		// Paginated query
-		offset := page * size
-		rows, err = db.Query(fmt.Sprintf(`
-			SELECT %s
-			FROM card_linked_account_transactions
-			WHERE product_id = ?
-			ORDER BY operation_date %s
-			LIMIT ? OFFSET ?
-		`, cols, order), productID, size, offset)
-	}
+	orderBy, limitArgs := opts.orderBy("operation_date")
+	rows, err := db.query(fmt.Sprintf(`
+		SELECT %s
+		FROM card_linked_account_transactions
+		WHERE product_id = ?
+		%s
+	`, cols, orderBy), append([]interface{}{productID}, limitArgs...)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query linked account transactions: %w", err)
+		return fmt.Errorf("failed to query linked account transactions: %w", err)
 	}
 	defer rows.Close()
 
-	var txns []client.Transaction
 	for rows.Next() {
 		var t client.Transaction
 		var currency sql.NullString
 		var amount sql.NullFloat64
 
-		if includeExtended {
+		if opts.IncludeExtended {
 			var beneficiaryName, beneficiaryAddress, creditAccountNumber sql.NullString
 			var cardMaskedNumber, extOperationID, swiftDetails sql.NullString
 			var extendedFetched sql.NullInt64
+			var swift client.SwiftInfo
+			var swiftSenderBank, swiftReference, swiftFees, swiftIntermediary sql.NullString
 
 			err := rows.Scan(
 				&t.ID,
@@ -139,9 +92,13 @@ func (db *DB) GetLinkedAccountTransactions(productID string, size, page int, inc
 				&extOperationID,
 				&swiftDetails,
 				&extendedFetched,
+				&swiftSenderBank,
+				&swiftReference,
+				&swiftFees,
+				&swiftIntermediary,
 			)
 			if err != nil {
-				return nil, fmt.Errorf("failed to scan transaction: %w", err)
+				return fmt.Errorf("failed to scan transaction: %w", err)
 			}
 
 			// Only set Extended if we actually fetched it
@@ -154,6 +111,18 @@ func (db *DB) GetLinkedAccountTransactions(productID string, size, page int, inc
 					OperationID:         extOperationID.String,
 					SwiftDetails:        swiftDetails.String,
 				}
+				swift = client.SwiftInfo{
+					SenderBank:   swiftSenderBank.String,
+					Reference:    swiftReference.String,
+					Fees:         swiftFees.String,
+					Intermediary: swiftIntermediary.String,
+				}
+				if !swift.Empty() {
+					t.Extended.Swift = &swift
+				} else {
+					// Stored before the SWIFT columns were added
+					t.Extended.Swift = client.ParseSwiftDetails(swiftDetails.String)
+				}
 			}
 		} else {
 			err := rows.Scan(
@@ -173,7 +142,7 @@ func (db *DB) GetLinkedAccountTransactions(productID string, size, page int, inc
 				&t.Month,
 			)
 			if err != nil {
-				return nil, fmt.Errorf("failed to scan transaction: %w", err)
+				return fmt.Errorf("failed to scan transaction: %w", err)
 			}
 		}
 
@@ -182,19 +151,21 @@ func (db *DB) GetLinkedAccountTransactions(productID string, size, page int, inc
 			Amount:   amount.Float64,
 		}
 
-		txns = append(txns, t)
+		if err := fn(t); err != nil {
+			return err
+		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating transactions: %w", err)
+		return fmt.Errorf("error iterating transactions: %w", err)
 	}
 
-	return txns, nil
+	return nil
 }
 
 // GetExistingLinkedAccountTxnKeys returns a set of existing transaction keys (id|operation_date) for a product
 func (db *DB) GetExistingLinkedAccountTxnKeys(productID string) (map[string]bool, error) {
-	rows, err := db.Query(`
+	rows, err := db.query(`
 		SELECT id, operation_date FROM card_linked_account_transactions WHERE product_id = ?
 	`, productID)
 	if err != nil {
@@ -221,7 +192,7 @@ func (db *DB) GetExistingLinkedAccountTxnKeys(productID string) (map[string]bool
 // CountLinkedAccountTransactions returns the total count of linked account transactions for a product
 func (db *DB) CountLinkedAccountTransactions(productID string) (int, error) {
 	var count int
-	err := db.QueryRow(`
+	err := db.queryRow(`
 		SELECT COUNT(*) FROM card_linked_account_transactions WHERE product_id = ?
 	`, productID).Scan(&count)
 	if err != nil {
@@ -230,9 +201,17 @@ func (db *DB) CountLinkedAccountTransactions(productID string) (int, error) {
 	return count, nil
 }
 
-// UpdateTransactionExtendedInfo updates extended info for a linked account transaction
+// UpdateTransactionExtendedInfo updates extended info for a linked account transaction.
+// SWIFT columns are filled from ext.Swift, or parsed from ext.SwiftDetails if it's nil.
 func (db *DB) UpdateTransactionExtendedInfo(productID, txnID, operationDate string, ext *client.TransactionExtendedInfo) error {
-	_, err := db.Exec(`
+	swift := ext.Swift
+	if swift == nil {
+		swift = client.ParseSwiftDetails(ext.SwiftDetails)
+	}
+	if swift == nil {
+		swift = &client.SwiftInfo{}
+	}
+	_, err := db.exec(`
 		UPDATE card_linked_account_transactions
 		SET beneficiary_name = ?,
 			beneficiary_address = ?,
@@ -240,11 +219,16 @@ func (db *DB) UpdateTransactionExtendedInfo(productID, txnID, operationDate stri
 			card_masked_number = ?,
 			ext_operation_id = ?,
 			swift_details = ?,
+			swift_sender_bank = ?,
+			swift_reference = ?,
+			swift_fees = ?,
+			swift_intermediary = ?,
 			extended_fetched = 1
 		WHERE product_id = ? AND id = ? AND operation_date = ?
 	`, ext.BeneficiaryName, ext.BeneficiaryAddress, ext.CreditAccountNumber,
 		ext.CardMaskedNumber, ext.OperationID, ext.SwiftDetails,
-		productID, txnID, operationDate)
+		nullString(swift.SenderBank), nullString(swift.Reference), nullString(swift.Fees), nullString(swift.Intermediary),
+		productID, txnID, NormalizeDate(operationDate))
 	if err != nil {
 		return fmt.Errorf("failed to update extended info: %w", err)
 	}
@@ -253,7 +237,7 @@ func (db *DB) UpdateTransactionExtendedInfo(productID, txnID, operationDate stri
 
 // GetTransactionsNeedingExtendedInfo returns transactions that haven't had extended info fetched yet
 func (db *DB) GetTransactionsNeedingExtendedInfo(productID string) ([]client.Transaction, error) {
-	rows, err := db.Query(`
+	rows, err := db.query(`
 		SELECT id, operation_date
 		FROM card_linked_account_transactions
 		WHERE product_id = ? AND (extended_fetched IS NULL OR extended_fetched = 0)
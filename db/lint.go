@@ -0,0 +1,193 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Lint issue kinds
+const (
+	LintZeroAmount      = "zero-amount"
+	LintMissingCurrency = "missing-currency"
+	LintBadDate         = "bad-date"
+	LintFutureDate      = "future-date"
+	LintEmptyExtended   = "empty-extended"
+)
+
+// LintFutureSlack is how far in the future a transaction may be dated before
+// Lint flags it, allowing for clocks and time zones being a little off
+const LintFutureSlack = 24 * time.Hour
+
+// LintIssue is a suspicious stored transaction found by Lint
+type LintIssue struct {
+	Kind      string `json:"kind"`
+	Table     string `json:"table"`
+	ProductID string `json:"product_id"`
+	ID        string `json:"id"`
+	Date      string `json:"date"` // as stored
+	Message   string `json:"message"`
+	Fixable   bool   `json:"fixable"` // FixLintIssues can fix it
+}
+
+// lintTables are the transaction tables checked by Lint
+var lintTables = []struct {
+	table, amount, currency, date string
+	millis                        bool // the date is in Unix milliseconds
+}{
+	{"card_transactions", "amount_value", "amount_currency", "operation_date", false},
+	{"card_linked_account_transactions", "amount_value", "amount_currency", "operation_date", false},
+	{"account_transactions", "transaction_amount_value", "transaction_amount_currency", "transaction_date", true},
+}
+
+// Lint checks the stored transactions for rows that look wrong: a zero or
+// missing amount or currency, a date that can't be parsed or is later than
+// now (plus LintFutureSlack), and linked account transactions marked as
+// having extended info with all of it empty. The issues of each table are
+// ordered by product and date.
+//
+// Only two kinds are fixable: a missing currency of a linked account
+// transaction, which is in the currency of the card's account, and empty
+// extended info, which sync fetches again once it's no longer marked as
+// fetched (see FixLintIssues).
+func (db *DB) Lint(now time.Time) ([]LintIssue, error) {
+	currencies := make(map[string]string)
+	products, err := db.GetProducts()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		currencies[p.ID] = p.Currency
+	}
+
+	var issues []LintIssue
+	for _, t := range lintTables {
+		rows, err := db.Query(fmt.Sprintf(`
+			SELECT product_id, id, %[1]s, %[2]s, %[3]s FROM %[4]s ORDER BY product_id, %[1]s, id
+		`, t.date, t.amount, t.currency, t.table))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", t.table, err)
+		}
+		for rows.Next() {
+			var productID, id string
+			var date, currency sql.NullString
+			var amount sql.NullFloat64
+			if err := rows.Scan(&productID, &id, &date, &amount, &currency); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan %s row: %w", t.table, err)
+			}
+			issue := func(kind, message string) *LintIssue {
+				issues = append(issues, LintIssue{
+					Kind: kind, Table: t.table, ProductID: productID, ID: id, Date: date.String, Message: message,
+				})
+				return &issues[len(issues)-1]
+			}
+
+			if !amount.Valid {
+				issue(LintZeroAmount, "no amount")
+			} else if amount.Float64 == 0 {
+				issue(LintZeroAmount, "amount is 0")
+			}
+			if currency.String == "" {
+				if c := currencies[productID]; t.table == "card_linked_account_transactions" && c != "" {
+					issue(LintMissingCurrency, "no currency, the account's is "+c).Fixable = true
+				} else {
+					issue(LintMissingCurrency, "no currency")
+				}
+			}
+			if at, ok := lintDate(date.String, t.millis); !ok {
+				issue(LintBadDate, fmt.Sprintf("unparseable date %q", date.String))
+			} else if at.After(now.Add(LintFutureSlack)) {
+				issue(LintFutureDate, "dated "+at.Local().Format("2006-01-02 15:04"))
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error iterating %s: %w", t.table, err)
+		}
+	}
+
+	rows, err := db.Query(`
+		SELECT product_id, id, operation_date FROM card_linked_account_transactions
+		WHERE extended_fetched = 1
+			AND COALESCE(beneficiary_name, '') = '' AND COALESCE(beneficiary_address, '') = ''
+			AND COALESCE(credit_account_number, '') = '' AND COALESCE(card_masked_number, '') = ''
+			AND COALESCE(ext_operation_id, '') = '' AND COALESCE(swift_details, '') = ''
+		ORDER BY product_id, operation_date, id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query extended info: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		i := LintIssue{Kind: LintEmptyExtended, Table: "card_linked_account_transactions", Fixable: true,
+			Message: "extended info fetched but empty"}
+		if err := rows.Scan(&i.ProductID, &i.ID, &i.Date); err != nil {
+			return nil, fmt.Errorf("failed to scan linked account transaction: %w", err)
+		}
+		issues = append(issues, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating linked account transactions: %w", err)
+	}
+
+	return issues, nil
+}
+
+// lintDate parses a stored transaction date: an operation date, or Unix
+// milliseconds if millis is set. Missing dates and zero milliseconds don't parse.
+func lintDate(s string, millis bool) (time.Time, bool) {
+	if !millis {
+		t, err := ParseDate(s)
+		return t, err == nil
+	}
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// FixLintIssues fixes the fixable issues (see Lint) in one transaction: sets
+// the missing currency of linked account transactions to their account's and
+// marks linked account transactions with empty extended info as not fetched,
+// so that the next sync fetches it again. Returns the number of fixed rows.
+func (db *DB) FixLintIssues(issues []LintIssue) (int, error) {
+	var fixed int
+	err := db.WithTransaction(func(tx *sql.Tx) error {
+		for _, i := range issues {
+			if !i.Fixable {
+				continue
+			}
+			var result sql.Result
+			var err error
+			switch i.Kind {
+			case LintMissingCurrency:
+				result, err = tx.Exec(`
+					UPDATE card_linked_account_transactions
+					SET amount_currency = (SELECT currency FROM products WHERE id = product_id)
+					WHERE product_id = ? AND id = ? AND operation_date = ? AND COALESCE(amount_currency, '') = ''
+				`, i.ProductID, i.ID, i.Date)
+			case LintEmptyExtended:
+				result, err = tx.Exec(`
+					UPDATE card_linked_account_transactions SET extended_fetched = 0
+					WHERE product_id = ? AND id = ? AND operation_date = ?
+				`, i.ProductID, i.ID, i.Date)
+			default:
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to fix %s of %s %s: %w", i.Kind, i.Table, i.ID, err)
+			}
+			n, _ := result.RowsAffected()
+			fixed += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return fixed, nil
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestLint(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.UpsertProducts([]client.ProductInfo{{ID: "card1", ProductType: "CARD", Name: "Main Card", Currency: "AMD"}}); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	amd := client.Amount{Currency: "AMD", Amount: 100}
+	if _, err := db.InsertCardTransactions("card1", []client.Transaction{
+		{ID: "ok", Amount: amd, OperationDate: "2024-05-01T10:00:00Z"},
+		{ID: "zero", Amount: client.Amount{Currency: "AMD"}, OperationDate: "2024-05-02T10:00:00Z"},
+		{ID: "future", Amount: amd, OperationDate: "2024-06-10T10:00:00Z"},
+		{ID: "baddate", Amount: amd, OperationDate: "yesterday"},
+	}); err != nil {
+		t.Fatalf("InsertCardTransactions failed: %v", err)
+	}
+	if _, err := db.InsertLinkedAccountTransactions("card1", []client.Transaction{
+		{ID: "nocurrency", Amount: client.Amount{Amount: 100}, OperationDate: "2024-05-03T10:00:00Z"},
+		{ID: "emptyext", Amount: amd, OperationDate: "2024-05-04T10:00:00Z"},
+		{ID: "fullext", Amount: amd, OperationDate: "2024-05-05T10:00:00Z"},
+	}); err != nil {
+		t.Fatalf("InsertLinkedAccountTransactions failed: %v", err)
+	}
+	if err := db.UpdateTransactionExtendedInfo("card1", "emptyext", "2024-05-04T10:00:00Z", &client.TransactionExtendedInfo{}); err != nil {
+		t.Fatalf("UpdateTransactionExtendedInfo failed: %v", err)
+	}
+	if err := db.UpdateTransactionExtendedInfo("card1", "fullext", "2024-05-05T10:00:00Z", &client.TransactionExtendedInfo{OperationID: "op1"}); err != nil {
+		t.Fatalf("UpdateTransactionExtendedInfo failed: %v", err)
+	}
+	if _, err := db.InsertAccountTransactions("acc1", []client.AccountTransaction{
+		{ID: "nodate", TransactionAmount: client.TransactionAmt{Currency: "USD", Value: 5}},
+	}); err != nil {
+		t.Fatalf("InsertAccountTransactions failed: %v", err)
+	}
+
+	issues, err := db.Lint(now)
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	type found struct{ kind, id string }
+	want := []found{
+		{LintZeroAmount, "zero"},
+		{LintFutureDate, "future"},
+		{LintBadDate, "baddate"},
+		{LintMissingCurrency, "nocurrency"},
+		{LintBadDate, "nodate"},
+		{LintEmptyExtended, "emptyext"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %+v", len(want), issues)
+	}
+	fixable := 0
+	for i, issue := range issues {
+		if (found{issue.Kind, issue.ID}) != want[i] {
+			t.Errorf("issue %d: expected %v, got %+v", i, want[i], issue)
+		}
+		if issue.Fixable {
+			fixable++
+		}
+	}
+	if fixable != 2 {
+		t.Errorf("expected 2 fixable issues, got %d", fixable)
+	}
+
+	fixed, err := db.FixLintIssues(issues)
+	if err != nil {
+		t.Fatalf("FixLintIssues failed: %v", err)
+	}
+	if fixed != 2 {
+		t.Errorf("expected 2 fixed rows, got %d", fixed)
+	}
+	if issues, err = db.Lint(now); err != nil || len(issues) != len(want)-2 {
+		t.Errorf("expected only the unfixable issues left, got %+v, %v", issues, err)
+	}
+	if missing, err := db.GetTransactionsNeedingExtendedInfo("card1"); err != nil || len(missing) != 2 {
+		t.Errorf("expected the empty extended info to be fetched again, got %+v, %v", missing, err)
+	}
+}
@@ -0,0 +1,314 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+// ReplaceLoans replaces all stored loans and their payment schedules with the
+// given set (closed loans disappear from the API)
+func (db *DB) ReplaceLoans(loans []client.LoanDetails) error {
+	syncedAt := time.Now().Unix()
+
+	return db.WithTransaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM loans"); err != nil {
+			return fmt.Errorf("failed to clear loans: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM loan_schedule"); err != nil {
+			return fmt.Errorf("failed to clear loan schedules: %w", err)
+		}
+
+		stmt, err := tx.Prepare(`
+			INSERT INTO loans (
+				id, name, agreement_number, currency, amount, outstanding_amount,
+				interest_rate, next_payment_date, next_payment_amount, maturity_date,
+				status, open_date, overdue_amount, order_index, synced_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+		scheduleStmt, err := tx.Prepare(`
+			INSERT INTO loan_schedule (loan_id, order_index, date, principal, interest, total, paid)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer scheduleStmt.Close()
+
+		for i, l := range loans {
+			_, err := stmt.Exec(
+				l.ID, l.Name, nullString(l.AgreementNumber), l.Currency, l.Amount, l.OutstandingAmount,
+				l.InterestRate, nullString(l.NextPaymentDate), l.NextPaymentAmount, nullString(l.MaturityDate),
+				l.Status, nullString(l.OpenDate), l.OverdueAmount, i, syncedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert loan %s: %w", l.ID, err)
+			}
+			for j, p := range l.Schedule {
+				if _, err := scheduleStmt.Exec(l.ID, j, p.Date, p.Principal, p.Interest, p.Total, p.Paid); err != nil {
+					return fmt.Errorf("failed to insert schedule of loan %s: %w", l.ID, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// GetLoans retrieves all stored loans in API order
+func (db *DB) GetLoans() ([]client.LoanInfo, error) {
+	rows, err := db.Query(`
+		SELECT id, name, agreement_number, currency, amount, outstanding_amount,
+			   interest_rate, next_payment_date, next_payment_amount, maturity_date, status
+		FROM loans
+		ORDER BY order_index
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query loans: %w", err)
+	}
+	defer rows.Close()
+
+	var loans []client.LoanInfo
+	for rows.Next() {
+		var l client.LoanInfo
+		var name, agreementNumber, currency, nextPaymentDate, maturityDate, status sql.NullString
+		var amount, outstanding, rate, nextPaymentAmount sql.NullFloat64
+
+		err := rows.Scan(&l.ID, &name, &agreementNumber, &currency, &amount, &outstanding,
+			&rate, &nextPaymentDate, &nextPaymentAmount, &maturityDate, &status)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan loan: %w", err)
+		}
+
+		l.Name = name.String
+		l.AgreementNumber = agreementNumber.String
+		l.Currency = currency.String
+		l.Amount = amount.Float64
+		l.OutstandingAmount = outstanding.Float64
+		l.InterestRate = rate.Float64
+		l.NextPaymentDate = nextPaymentDate.String
+		l.NextPaymentAmount = nextPaymentAmount.Float64
+		l.MaturityDate = maturityDate.String
+		l.Status = status.String
+
+		loans = append(loans, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating loans: %w", err)
+	}
+
+	return loans, nil
+}
+
+// ReplaceDeposits replaces all stored deposits with the given set, with the
+// interest payment schedules given by deposit ID
+func (db *DB) ReplaceDeposits(deposits []client.DepositDetails, schedules map[string][]client.InterestPayment) error {
+	syncedAt := time.Now().Unix()
+
+	return db.WithTransaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM deposits"); err != nil {
+			return fmt.Errorf("failed to clear deposits: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM deposit_schedule"); err != nil {
+			return fmt.Errorf("failed to clear deposit schedules: %w", err)
+		}
+
+		stmt, err := tx.Prepare(`
+			INSERT INTO deposits (
+				id, name, agreement_number, currency, amount, interest_rate, accrued_interest,
+				next_interest_payment_date, next_interest_payment_amount, maturity_date,
+				status, open_date, term_months, capitalization, replenishable, interest_account_number,
+				order_index, synced_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+		scheduleStmt, err := tx.Prepare(`
+			INSERT INTO deposit_schedule (deposit_id, order_index, date, amount, tax, paid)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer scheduleStmt.Close()
+
+		for i, d := range deposits {
+			_, err := stmt.Exec(
+				d.ID, d.Name, nullString(d.AgreementNumber), d.Currency, d.Amount, d.InterestRate, d.AccruedInterest,
+				nullString(d.NextInterestPaymentDate), d.NextInterestPaymentAmount, nullString(d.MaturityDate),
+				d.Status, nullString(d.OpenDate), d.TermMonths, d.Capitalization, d.Replenishable, nullString(d.InterestAccountNumber),
+				i, syncedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert deposit %s: %w", d.ID, err)
+			}
+			for j, p := range schedules[d.ID] {
+				if _, err := scheduleStmt.Exec(d.ID, j, p.Date, p.Amount, p.Tax, p.Paid); err != nil {
+					return fmt.Errorf("failed to insert schedule of deposit %s: %w", d.ID, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// GetDeposits retrieves all stored deposits in API order
+func (db *DB) GetDeposits() ([]client.DepositInfo, error) {
+	rows, err := db.Query(`
+		SELECT id, name, agreement_number, currency, amount, interest_rate, accrued_interest,
+			   next_interest_payment_date, next_interest_payment_amount, maturity_date, status
+		FROM deposits
+		ORDER BY order_index
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deposits: %w", err)
+	}
+	defer rows.Close()
+
+	var deposits []client.DepositInfo
+	for rows.Next() {
+		var d client.DepositInfo
+		var name, agreementNumber, currency, nextDate, maturityDate, status sql.NullString
+		var amount, rate, accrued, nextAmount sql.NullFloat64
+
+		err := rows.Scan(&d.ID, &name, &agreementNumber, &currency, &amount, &rate, &accrued,
+			&nextDate, &nextAmount, &maturityDate, &status)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deposit: %w", err)
+		}
+
+		d.Name = name.String
+		d.AgreementNumber = agreementNumber.String
+		d.Currency = currency.String
+		d.Amount = amount.Float64
+		d.InterestRate = rate.Float64
+		d.AccruedInterest = accrued.Float64
+		d.NextInterestPaymentDate = nextDate.String
+		d.NextInterestPaymentAmount = nextAmount.Float64
+		d.MaturityDate = maturityDate.String
+		d.Status = status.String
+
+		deposits = append(deposits, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deposits: %w", err)
+	}
+
+	return deposits, nil
+}
+
+// GetLoanDetails retrieves a stored loan with its terms and payment schedule,
+// or nil if there is no such loan
+func (db *DB) GetLoanDetails(loanID string) (*client.LoanDetails, error) {
+	var openDate sql.NullString
+	var overdue sql.NullFloat64
+	err := db.QueryRow("SELECT open_date, overdue_amount FROM loans WHERE id = ?", loanID).Scan(&openDate, &overdue)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query loan %s: %w", loanID, err)
+	}
+	loans, err := db.GetLoans()
+	if err != nil {
+		return nil, err
+	}
+	details := &client.LoanDetails{OpenDate: openDate.String, OverdueAmount: overdue.Float64}
+	for _, l := range loans {
+		if l.ID == loanID {
+			details.LoanInfo = l
+		}
+	}
+
+	rows, err := db.Query(`
+		SELECT date, principal, interest, total, paid
+		FROM loan_schedule WHERE loan_id = ?
+		ORDER BY order_index
+	`, loanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedule of loan %s: %w", loanID, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p client.LoanInstallment
+		var principal, interest, total sql.NullFloat64
+		if err := rows.Scan(&p.Date, &principal, &interest, &total, &p.Paid); err != nil {
+			return nil, fmt.Errorf("failed to scan loan installment: %w", err)
+		}
+		p.Principal, p.Interest, p.Total = principal.Float64, interest.Float64, total.Float64
+		details.Schedule = append(details.Schedule, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating loan schedule: %w", err)
+	}
+	return details, nil
+}
+
+// GetDepositDetails retrieves the terms of a stored deposit, or nil if there
+// is no such deposit
+func (db *DB) GetDepositDetails(depositID string) (*client.DepositDetails, error) {
+	var openDate, interestAccount sql.NullString
+	var termMonths sql.NullInt64
+	details := &client.DepositDetails{}
+	err := db.QueryRow(`
+		SELECT open_date, term_months, capitalization, replenishable, interest_account_number
+		FROM deposits WHERE id = ?
+	`, depositID).Scan(&openDate, &termMonths, &details.Capitalization, &details.Replenishable, &interestAccount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deposit %s: %w", depositID, err)
+	}
+	deposits, err := db.GetDeposits()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deposits {
+		if d.ID == depositID {
+			details.DepositInfo = d
+		}
+	}
+	details.OpenDate = openDate.String
+	details.TermMonths = int(termMonths.Int64)
+	details.InterestAccountNumber = interestAccount.String
+	return details, nil
+}
+
+// GetDepositSchedule retrieves the stored interest payment schedule of a deposit
+func (db *DB) GetDepositSchedule(depositID string) ([]client.InterestPayment, error) {
+	rows, err := db.Query(`
+		SELECT date, amount, tax, paid
+		FROM deposit_schedule WHERE deposit_id = ?
+		ORDER BY order_index
+	`, depositID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedule of deposit %s: %w", depositID, err)
+	}
+	defer rows.Close()
+
+	var payments []client.InterestPayment
+	for rows.Next() {
+		var p client.InterestPayment
+		var amount, tax sql.NullFloat64
+		if err := rows.Scan(&p.Date, &amount, &tax, &p.Paid); err != nil {
+			return nil, fmt.Errorf("failed to scan interest payment: %w", err)
+		}
+		p.Amount, p.Tax = amount.Float64, tax.Float64
+		payments = append(payments, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deposit schedule: %w", err)
+	}
+	return payments, nil
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestReplaceLoans(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	loans := []client.LoanDetails{
+		{
+			LoanInfo: client.LoanInfo{ID: "L2", Name: "Mortgage", Currency: "AMD", OutstandingAmount: 9000000, InterestRate: 11, NextPaymentDate: "2024-02-01", NextPaymentAmount: 120000},
+			OpenDate: "2020-02-01",
+			Schedule: []client.LoanInstallment{
+				{Date: "2024-01-01", Principal: 90000, Interest: 30000, Total: 120000, Paid: true},
+				{Date: "2024-02-01", Principal: 91000, Interest: 29000, Total: 120000},
+			},
+		},
+		{LoanInfo: client.LoanInfo{ID: "L1", Name: "Car Loan", Currency: "USD", OutstandingAmount: 3000, InterestRate: 9.5}, OverdueAmount: 50},
+	}
+	if err := db.ReplaceLoans(loans); err != nil {
+		t.Fatalf("ReplaceLoans failed: %v", err)
+	}
+
+	got, err := db.GetLoans()
+	if err != nil {
+		t.Fatalf("GetLoans failed: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "L2" || got[0].NextPaymentAmount != 120000 || got[1].NextPaymentDate != "" {
+		t.Errorf("unexpected loans: %+v", got)
+	}
+
+	details, err := db.GetLoanDetails("L2")
+	if err != nil {
+		t.Fatalf("GetLoanDetails failed: %v", err)
+	}
+	if !reflect.DeepEqual(*details, loans[0]) {
+		t.Errorf("unexpected loan details:\n got %+v\nwant %+v", *details, loans[0])
+	}
+
+	// Closed loans disappear on the next sync, with their schedules
+	if err := db.ReplaceLoans(loans[1:]); err != nil {
+		t.Fatalf("ReplaceLoans failed: %v", err)
+	}
+	got, _ = db.GetLoans()
+	if len(got) != 1 || got[0].ID != "L1" {
+		t.Errorf("expected only L1 after replace, got %+v", got)
+	}
+	if details, err := db.GetLoanDetails("L2"); err != nil || details != nil {
+		t.Errorf("expected no details of a closed loan, got %+v, %v", details, err)
+	}
+	if details, err := db.GetLoanDetails("L1"); err != nil || details.OverdueAmount != 50 || details.Schedule != nil {
+		t.Errorf("unexpected details of L1: %+v, %v", details, err)
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM loan_schedule").Scan(&n); err != nil || n != 0 {
+		t.Errorf("expected no stored installments, got %d (%v)", n, err)
+	}
+}
+
+func TestReplaceDeposits(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	deposits := []client.DepositDetails{
+		{
+			DepositInfo:           client.DepositInfo{ID: "D1", Name: "Term Deposit", Currency: "USD", Amount: 5000, InterestRate: 8, AccruedInterest: 12.5, MaturityDate: "2025-01-01"},
+			OpenDate:              "2024-01-01",
+			TermMonths:            12,
+			Capitalization:        true,
+			InterestAccountNumber: "1570000000000100",
+		},
+	}
+	schedule := []client.InterestPayment{
+		{Date: "2024-02-01", Amount: 33.3, Tax: 3.3, Paid: true},
+		{Date: "2024-03-01", Amount: 33.5},
+	}
+	if err := db.ReplaceDeposits(deposits, map[string][]client.InterestPayment{"D1": schedule}); err != nil {
+		t.Fatalf("ReplaceDeposits failed: %v", err)
+	}
+
+	got, err := db.GetDeposits()
+	if err != nil {
+		t.Fatalf("GetDeposits failed: %v", err)
+	}
+	if len(got) != 1 || got[0] != deposits[0].DepositInfo {
+		t.Errorf("unexpected deposits: %+v", got)
+	}
+	details, err := db.GetDepositDetails("D1")
+	if err != nil {
+		t.Fatalf("GetDepositDetails failed: %v", err)
+	}
+	if *details != deposits[0] {
+		t.Errorf("unexpected deposit details: %+v", *details)
+	}
+	payments, err := db.GetDepositSchedule("D1")
+	if err != nil {
+		t.Fatalf("GetDepositSchedule failed: %v", err)
+	}
+	if !reflect.DeepEqual(payments, schedule) {
+		t.Errorf("unexpected deposit schedule: %+v", payments)
+	}
+
+	if details, err := db.GetDepositDetails("missing"); err != nil || details != nil {
+		t.Errorf("expected no details of an unknown deposit, got %+v, %v", details, err)
+	}
+}
+
+func TestEvaluateAlertRules_PaymentDue(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.AddAlertRule(AlertRule{Kind: AlertPaymentDue, Threshold: 3}); err != nil {
+		t.Fatalf("AddAlertRule failed: %v", err)
+	}
+
+	now := time.Now()
+	loans := []client.LoanDetails{
+		{LoanInfo: client.LoanInfo{ID: "soon", Name: "Soon", Currency: "AMD", NextPaymentDate: now.AddDate(0, 0, 2).Format("2006-01-02"), NextPaymentAmount: 100}},
+		{LoanInfo: client.LoanInfo{ID: "later", Name: "Later", Currency: "AMD", NextPaymentDate: now.AddDate(0, 0, 10).Format("2006-01-02"), NextPaymentAmount: 100}},
+		{LoanInfo: client.LoanInfo{ID: "past", Name: "Past", Currency: "AMD", NextPaymentDate: now.AddDate(0, 0, -1).Format("2006-01-02"), NextPaymentAmount: 100}},
+	}
+	if err := db.ReplaceLoans(loans); err != nil {
+		t.Fatalf("ReplaceLoans failed: %v", err)
+	}
+
+	fired, err := db.EvaluateAlertRules(now, nil)
+	if err != nil {
+		t.Fatalf("EvaluateAlertRules failed: %v", err)
+	}
+	if len(fired) != 1 || fired[0].Kind != AlertPaymentDue {
+		t.Fatalf("expected 1 payment-due alert, got %+v", fired)
+	}
+
+	// The same payment does not fire twice
+	fired, err = db.EvaluateAlertRules(now, nil)
+	if err != nil {
+		t.Fatalf("EvaluateAlertRules failed: %v", err)
+	}
+	if len(fired) != 0 {
+		t.Errorf("expected no repeated alerts, got %+v", fired)
+	}
+}
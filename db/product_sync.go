@@ -0,0 +1,85 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// syncIntervalSlack is how much earlier than its interval a product is due
+// again, so that runs on a schedule matching the interval don't skip it
+// when they start slightly early
+const syncIntervalSlack = time.Minute
+
+// ProductSyncState is how often a product is synced in daemon mode and when
+// its transactions were last synced
+type ProductSyncState struct {
+	ProductID    string
+	Interval     time.Duration // 0 = every run
+	LastSyncedAt time.Time     // zero if never synced
+}
+
+// Due reports whether the product should be synced by a scheduled run at now
+func (s ProductSyncState) Due(now time.Time) bool {
+	return s.Interval <= 0 || s.LastSyncedAt.IsZero() || now.Sub(s.LastSyncedAt) >= s.Interval-syncIntervalSlack
+}
+
+// SetSyncInterval sets how often a product is synced in daemon mode; 0 syncs
+// it on every run
+func (db *DB) SetSyncInterval(productID string, interval time.Duration) error {
+	var seconds interface{}
+	if interval > 0 {
+		seconds = int64(interval / time.Second)
+	}
+	_, err := db.Exec(`
+		INSERT INTO product_sync (product_id, interval_seconds) VALUES (?, ?)
+		ON CONFLICT(product_id) DO UPDATE SET interval_seconds = excluded.interval_seconds
+	`, productID, seconds)
+	if err != nil {
+		return fmt.Errorf("failed to set sync interval: %w", err)
+	}
+	return nil
+}
+
+// MarkProductSynced records that a product's transactions were synced by a
+// run started at the given time
+func (db *DB) MarkProductSynced(productID string, at time.Time) error {
+	_, err := db.exec(`
+		INSERT INTO product_sync (product_id, last_synced_at) VALUES (?, ?)
+		ON CONFLICT(product_id) DO UPDATE SET last_synced_at = excluded.last_synced_at
+	`, productID, at.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to record product sync: %w", err)
+	}
+	return nil
+}
+
+// GetProductSyncStates returns the sync state of each product that has one,
+// by product ID
+func (db *DB) GetProductSyncStates() (map[string]ProductSyncState, error) {
+	rows, err := db.Query("SELECT product_id, interval_seconds, last_synced_at FROM product_sync")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product sync states: %w", err)
+	}
+	defer rows.Close()
+
+	states := make(map[string]ProductSyncState)
+	for rows.Next() {
+		var s ProductSyncState
+		var interval, lastSynced sql.NullInt64
+		if err := rows.Scan(&s.ProductID, &interval, &lastSynced); err != nil {
+			return nil, fmt.Errorf("failed to scan product sync state: %w", err)
+		}
+		s.Interval = time.Duration(interval.Int64) * time.Second
+		if lastSynced.Valid {
+			s.LastSyncedAt = time.Unix(lastSynced.Int64, 0)
+		}
+		states[s.ProductID] = s
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating product sync states: %w", err)
+	}
+
+	return states, nil
+}
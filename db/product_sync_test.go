@@ -0,0 +1,56 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProductSyncStates(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Unix(time.Now().Unix(), 0)
+	if err := db.SetSyncInterval("card1", 2*time.Hour); err != nil {
+		t.Fatalf("SetSyncInterval failed: %v", err)
+	}
+	if err := db.MarkProductSynced("card1", now.Add(-time.Hour)); err != nil {
+		t.Fatalf("MarkProductSynced failed: %v", err)
+	}
+	if err := db.MarkProductSynced("acc1", now); err != nil {
+		t.Fatalf("MarkProductSynced failed: %v", err)
+	}
+
+	states, err := db.GetProductSyncStates()
+	if err != nil {
+		t.Fatalf("GetProductSyncStates failed: %v", err)
+	}
+	card := states["card1"]
+	if card.Interval != 2*time.Hour || !card.LastSyncedAt.Equal(now.Add(-time.Hour)) {
+		t.Errorf("unexpected card state: %+v", card)
+	}
+	if card.Due(now) {
+		t.Error("expected the card not to be due an hour after its sync")
+	}
+	// Runs on a matching schedule may start a little early
+	if !card.Due(now.Add(time.Hour - 30*time.Second)) {
+		t.Error("expected the card to be due at its interval")
+	}
+	if acc := states["acc1"]; acc.Interval != 0 || !acc.Due(now) {
+		t.Errorf("expected a product without an interval to be due, got %+v", acc)
+	}
+	if !(ProductSyncState{Interval: time.Hour}).Due(now) {
+		t.Error("expected a product never synced to be due")
+	}
+
+	// Unsetting keeps the last sync
+	if err := db.SetSyncInterval("card1", 0); err != nil {
+		t.Fatalf("SetSyncInterval failed: %v", err)
+	}
+	states, _ = db.GetProductSyncStates()
+	if card := states["card1"]; card.Interval != 0 || card.LastSyncedAt.IsZero() {
+		t.Errorf("unexpected card state after unsetting: %+v", card)
+	}
+}
@@ -16,8 +16,8 @@ func (db *DB) UpsertProducts(products []client.ProductInfo) error {
 		stmt, err := tx.Prepare(`
 			INSERT INTO products (
 				id, product_type, name, card_number, account_number,
-				account_id, currency, balance, available_balance, status, order_index, synced_at
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				account_id, currency, balance, available_balance, status, client_id, order_index, synced_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			ON CONFLICT(id) DO UPDATE SET
 				product_type = excluded.product_type,
 				name = excluded.name,
@@ -28,6 +28,7 @@ func (db *DB) UpsertProducts(products []client.ProductInfo) error {
 				balance = excluded.balance,
 				available_balance = excluded.available_balance,
 				status = excluded.status,
+				client_id = COALESCE(excluded.client_id, products.client_id),
 				order_index = excluded.order_index,
 				synced_at = excluded.synced_at
 		`)
@@ -48,6 +49,7 @@ func (db *DB) UpsertProducts(products []client.ProductInfo) error {
 				p.Balance,
 				p.AvailableBalance,
 				p.Status,
+				nullString(p.ClientID),
 				i, // order_index preserves API order
 				syncedAt,
 			)
@@ -61,9 +63,9 @@ func (db *DB) UpsertProducts(products []client.ProductInfo) error {
 
 // GetProducts retrieves all products from the database in API order
 func (db *DB) GetProducts() ([]client.ProductInfo, error) {
-	rows, err := db.Query(`
+	rows, err := db.query(`
 		SELECT id, product_type, name, card_number, account_number,
-			   account_id, currency, balance, available_balance, status
+			   account_id, currency, balance, available_balance, status, client_id
 		FROM products
 		ORDER BY order_index
 	`)
@@ -75,7 +77,7 @@ func (db *DB) GetProducts() ([]client.ProductInfo, error) {
 	var products []client.ProductInfo
 	for rows.Next() {
 		var p client.ProductInfo
-		var cardNumber, accountNumber, accountID sql.NullString
+		var cardNumber, accountNumber, accountID, clientID sql.NullString
 		var availableBalance sql.NullFloat64
 
 		err := rows.Scan(
@@ -89,6 +91,7 @@ func (db *DB) GetProducts() ([]client.ProductInfo, error) {
 			&p.Balance,
 			&availableBalance,
 			&p.Status,
+			&clientID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
@@ -98,6 +101,7 @@ func (db *DB) GetProducts() ([]client.ProductInfo, error) {
 		p.AccountNumber = accountNumber.String
 		p.AccountID = accountID.String
 		p.AvailableBalance = availableBalance.Float64
+		p.ClientID = clientID.String
 
 		products = append(products, p)
 	}
@@ -109,15 +113,66 @@ func (db *DB) GetProducts() ([]client.ProductInfo, error) {
 	return products, nil
 }
 
+// StoredBalance is the stored available balance of a product
+type StoredBalance struct {
+	Balance   float64
+	FetchedAt time.Time // when list fetched it (zero if stored by sync)
+}
+
+// GetAvailableBalances returns the stored available balances of products by ID
+func (db *DB) GetAvailableBalances() (map[string]StoredBalance, error) {
+	rows, err := db.query(`
+		SELECT id, available_balance, available_balance_at FROM products
+		WHERE available_balance IS NOT NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query available balances: %w", err)
+	}
+	defer rows.Close()
+
+	balances := make(map[string]StoredBalance)
+	for rows.Next() {
+		var id string
+		var b StoredBalance
+		var fetchedAt sql.NullInt64
+		if err := rows.Scan(&id, &b.Balance, &fetchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan available balance: %w", err)
+		}
+		if fetchedAt.Valid {
+			b.FetchedAt = time.Unix(fetchedAt.Int64, 0)
+		}
+		balances[id] = b
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating available balances: %w", err)
+	}
+	return balances, nil
+}
+
+// SetAvailableBalances stores available balances of products by ID, fetched at
+// the given time. Products that aren't stored are skipped.
+func (db *DB) SetAvailableBalances(balances map[string]float64, fetchedAt time.Time) error {
+	return db.WithTransaction(func(tx *sql.Tx) error {
+		for id, balance := range balances {
+			_, err := tx.Exec("UPDATE products SET available_balance = ?, available_balance_at = ? WHERE id = ?",
+				balance, fetchedAt.Unix(), id)
+			if err != nil {
+				return fmt.Errorf("failed to store available balance of %s: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
 // GetProductByID retrieves a single product by ID
 func (db *DB) GetProductByID(id string) (*client.ProductInfo, error) {
 	var p client.ProductInfo
-	var cardNumber, accountNumber, accountID sql.NullString
+	var cardNumber, accountNumber, accountID, clientID sql.NullString
 	var availableBalance sql.NullFloat64
 
-	err := db.QueryRow(`
+	err := db.queryRow(`
 		SELECT id, product_type, name, card_number, account_number,
-			   account_id, currency, balance, available_balance, status
+			   account_id, currency, balance, available_balance, status, client_id
 		FROM products WHERE id = ?
 	`, id).Scan(
 		&p.ID,
@@ -130,6 +185,7 @@ func (db *DB) GetProductByID(id string) (*client.ProductInfo, error) {
 		&p.Balance,
 		&availableBalance,
 		&p.Status,
+		&clientID,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -142,11 +198,15 @@ func (db *DB) GetProductByID(id string) (*client.ProductInfo, error) {
 	p.AccountNumber = accountNumber.String
 	p.AccountID = accountID.String
 	p.AvailableBalance = availableBalance.Float64
+	p.ClientID = clientID.String
 
 	return &p, nil
 }
 
-// GetProductByNameOrID retrieves a product by ID first, then by name if not found
+// GetProductByNameOrID retrieves a product by ID first, then by name
+// (case-insensitive), then by the last digits of its card or account number
+// (see IsNumberSuffix). Names and numbers matching several products are
+// ambiguous.
 func (db *DB) GetProductByNameOrID(identifier string) (*client.ProductInfo, error) {
 	// First try by ID
 	product, err := db.GetProductByID(identifier)
@@ -157,21 +217,68 @@ func (db *DB) GetProductByNameOrID(identifier string) (*client.ProductInfo, erro
 		return product, nil
 	}
 
-	// Then try by name (case-insensitive)
-	rows, err := db.Query(`
-		SELECT id, product_type, name, card_number, account_number,
-			   account_id, currency, balance, available_balance, status
-		FROM products WHERE LOWER(name) = LOWER(?)
-	`, identifier)
+	// Then try by name, then by number
+	kind := "name"
+	products, err := db.queryProducts(productSelectSQL+" WHERE LOWER(name) = LOWER(?)", identifier)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query product by name: %w", err)
 	}
+	if len(products) == 0 && IsNumberSuffix(identifier) {
+		kind = "number"
+		products, err = db.queryProducts(productSelectSQL+" WHERE card_number LIKE '%' || ? OR account_number LIKE '%' || ?",
+			identifier, identifier)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query product by number: %w", err)
+		}
+	}
+
+	if len(products) == 0 {
+		return nil, nil
+	}
+
+	if len(products) > 1 {
+		ids := make([]string, len(products))
+		for i, p := range products {
+			ids[i] = p.ID
+		}
+		return nil, fmt.Errorf("ambiguous %s %q matches multiple products: %v", kind, identifier, ids)
+	}
+
+	return &products[0], nil
+}
+
+// IsNumberSuffix reports whether a product identifier can be the last digits
+// of a card or account number: at least 4 digits
+func IsNumberSuffix(s string) bool {
+	if len(s) < 4 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// productSelectSQL selects the columns scanned by queryProducts
+const productSelectSQL = `
+	SELECT id, product_type, name, card_number, account_number,
+		   account_id, currency, balance, available_balance, status, client_id
+	FROM products`
+
+// queryProducts returns the products selected by a query on productSelectSQL
+func (db *DB) queryProducts(query string, args ...interface{}) ([]client.ProductInfo, error) {
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var products []client.ProductInfo
 	for rows.Next() {
 		var p client.ProductInfo
-		var cardNumber, accountNumber, accountID sql.NullString
+		var cardNumber, accountNumber, accountID, clientID sql.NullString
 		var availableBalance sql.NullFloat64
 
 		err := rows.Scan(
@@ -185,6 +292,7 @@ func (db *DB) GetProductByNameOrID(identifier string) (*client.ProductInfo, erro
 			&p.Balance,
 			&availableBalance,
 			&p.Status,
+			&clientID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
@@ -194,6 +302,7 @@ func (db *DB) GetProductByNameOrID(identifier string) (*client.ProductInfo, erro
 		p.AccountNumber = accountNumber.String
 		p.AccountID = accountID.String
 		p.AvailableBalance = availableBalance.Float64
+		p.ClientID = clientID.String
 
 		products = append(products, p)
 	}
@@ -202,19 +311,7 @@ func (db *DB) GetProductByNameOrID(identifier string) (*client.ProductInfo, erro
 		return nil, fmt.Errorf("error iterating products: %w", err)
 	}
 
-	if len(products) == 0 {
-		return nil, nil
-	}
-
-	if len(products) > 1 {
-		ids := make([]string, len(products))
-		for i, p := range products {
-			ids[i] = p.ID
-		}
-		return nil, fmt.Errorf("ambiguous name %q matches multiple products: %v", identifier, ids)
-	}
-
-	return &products[0], nil
+	return products, nil
 }
 
 // nullString returns a sql.NullString for empty strings
@@ -1,11 +1,14 @@
 package db
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 )
 
 // Current schema version
-const schemaVersion = 5
+const schemaVersion = 29
 
 // migrations is a list of SQL statements to run for each version
 var migrations = []string{
@@ -169,10 +172,539 @@ var migrations = []string{
 		updated_at INTEGER NOT NULL
 	);
 	`,
+	// Version 6: Sync run log (used by sync and daemon mode)
+	`
+	CREATE TABLE IF NOT EXISTS sync_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		trigger TEXT NOT NULL,
+		started_at INTEGER NOT NULL,
+		finished_at INTEGER,
+		status TEXT NOT NULL,
+		error TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_sync_runs_started ON sync_runs(started_at);
+	`,
+	// Version 7: Alert rules and fired alerts
+	`
+	CREATE TABLE IF NOT EXISTS alert_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		product_id TEXT,
+		threshold REAL,
+		currency TEXT,
+		created_at INTEGER NOT NULL
+	);
+
+	-- Fired alerts; ref_key identifies what triggered the alert so it only fires once
+	CREATE TABLE IF NOT EXISTS alerts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		rule_id INTEGER NOT NULL,
+		ref_key TEXT NOT NULL,
+		fired_at INTEGER NOT NULL,
+		message TEXT NOT NULL,
+		UNIQUE (rule_id, ref_key),
+		FOREIGN KEY (rule_id) REFERENCES alert_rules(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_alerts_fired ON alerts(fired_at);
+	`,
+	// Version 8: Loans and deposits
+	`
+	CREATE TABLE IF NOT EXISTS loans (
+		id TEXT PRIMARY KEY,
+		name TEXT,
+		agreement_number TEXT,
+		currency TEXT,
+		amount REAL,
+		outstanding_amount REAL,
+		interest_rate REAL,
+		next_payment_date TEXT,
+		next_payment_amount REAL,
+		maturity_date TEXT,
+		status TEXT,
+		order_index INTEGER NOT NULL DEFAULT 0,
+		synced_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS deposits (
+		id TEXT PRIMARY KEY,
+		name TEXT,
+		agreement_number TEXT,
+		currency TEXT,
+		amount REAL,
+		interest_rate REAL,
+		accrued_interest REAL,
+		next_interest_payment_date TEXT,
+		next_interest_payment_amount REAL,
+		maturity_date TEXT,
+		status TEXT,
+		order_index INTEGER NOT NULL DEFAULT 0,
+		synced_at INTEGER NOT NULL
+	);
+	`,
+	// Version 9: Exchange rates (AMD per unit of currency)
+	`
+	CREATE TABLE IF NOT EXISTS exchange_rates (
+		currency TEXT PRIMARY KEY,
+		rate REAL NOT NULL,
+		source TEXT,
+		updated_at INTEGER NOT NULL
+	);
+	`,
+	// Version 10: Per-consumer cursors for reading only new transactions
+	`
+	CREATE TABLE IF NOT EXISTS cursors (
+		name TEXT NOT NULL,
+		source TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (name, source)
+	);
+	`,
+	// Version 11: Advisory lock preventing overlapping sync runs
+	`
+	CREATE TABLE IF NOT EXISTS sync_lock (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		host TEXT NOT NULL,
+		pid INTEGER NOT NULL,
+		acquired_at INTEGER NOT NULL
+	);
+	`,
+	// Version 12: History of changes to stored transactions (e.g. pending -> settled)
+	`
+	CREATE TABLE IF NOT EXISTS transaction_changes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source TEXT NOT NULL,
+		product_id TEXT NOT NULL,
+		txn_id TEXT NOT NULL,
+		operation_date TEXT,
+		field TEXT NOT NULL,
+		old_value TEXT,
+		new_value TEXT,
+		changed_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_txn_changes_txn ON transaction_changes(product_id, txn_id);
+	`,
+	// Version 13: Pending card authorizations, replaced on each sync
+	`
+	CREATE TABLE IF NOT EXISTS card_holds (
+		product_id TEXT NOT NULL,
+		id TEXT,
+		transaction_type TEXT,
+		accounting_type TEXT,
+		state TEXT,
+		amount_currency TEXT,
+		amount_value REAL,
+		details TEXT,
+		operation_date TEXT,
+		order_index INTEGER NOT NULL DEFAULT 0,
+		synced_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_card_holds_product ON card_holds(product_id);
+	`,
+	// Version 14: Template groups
+	`
+	ALTER TABLE transfer_templates ADD COLUMN group_id TEXT;
+	ALTER TABLE transfer_templates ADD COLUMN group_name TEXT;
+	`,
+	// Version 15: Utility payments (electricity, gas, phone, ...) tagged by provider
+	`
+	CREATE TABLE IF NOT EXISTS utility_payments (
+		id TEXT PRIMARY KEY,
+		service_type TEXT,
+		provider TEXT,
+		subscriber_number TEXT,
+		amount_currency TEXT,
+		amount_value REAL,
+		debit_account_id TEXT,
+		payment_date TEXT,
+		status TEXT,
+		synced_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_utility_payments_provider ON utility_payments(provider);
+	CREATE INDEX IF NOT EXISTS idx_utility_payments_date ON utility_payments(payment_date);
+	`,
+	// Version 16: Client (personal or company) each product belongs to
+	`
+	ALTER TABLE products ADD COLUMN client_id TEXT;
+	CREATE INDEX IF NOT EXISTS idx_products_client ON products(client_id);
+	`,
+	// Version 17: SWIFT details parsed into columns. Rows stored before this
+	// version are parsed from swift_details when read.
+	`
+	ALTER TABLE card_linked_account_transactions ADD COLUMN swift_sender_bank TEXT;
+	ALTER TABLE card_linked_account_transactions ADD COLUMN swift_reference TEXT;
+	ALTER TABLE card_linked_account_transactions ADD COLUMN swift_fees TEXT;
+	ALTER TABLE card_linked_account_transactions ADD COLUMN swift_intermediary TEXT;
+	`,
+	// Version 18: Operation dates normalized to UTC RFC 3339 (see NormalizeDate).
+	// Dates without a time zone are in the bank's (+04:00); unparseable ones are
+	// kept. Rows that become duplicates of each other are merged (see
+	// mergeNormalizedDatesSQL).
+	mergeNormalizedDatesSQL("card_transactions") +
+		mergeNormalizedDatesSQL("card_linked_account_transactions",
+			"beneficiary_name", "beneficiary_address", "credit_account_number", "card_masked_number",
+			"ext_operation_id", "swift_details", "swift_sender_bank", "swift_reference", "swift_fees",
+			"swift_intermediary") + `
+	UPDATE card_holds SET operation_date = ` + normalizedDateSQL + `;
+	UPDATE transaction_changes SET operation_date = ` + normalizedDateSQL + `;
+	`,
+	// Version 19: When the available balance was fetched by list, which
+	// reuses recent ones
+	`
+	ALTER TABLE products ADD COLUMN available_balance_at INTEGER;
+	`,
+	// Version 20: Counterparties outgoing transfers went to, aggregated per
+	// currency from the transaction history (see RebuildCounterparties)
+	`
+	CREATE TABLE IF NOT EXISTS counterparties (
+		key TEXT NOT NULL,
+		currency TEXT NOT NULL,
+		name TEXT,
+		number TEXT,
+		total REAL NOT NULL,
+		count INTEGER NOT NULL,
+		first_date TEXT,
+		last_date TEXT,
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (key, currency)
+	);
+	`,
+	// Version 21: Per-product sync intervals for daemon mode and when each
+	// product's transactions were last synced
+	`
+	CREATE TABLE IF NOT EXISTS product_sync (
+		product_id TEXT PRIMARY KEY,
+		interval_seconds INTEGER,
+		last_synced_at INTEGER
+	);
+	`,
+	// Version 22: Account transactions keyed by (id, transaction_date) like
+	// the card tables, so that a reused ID doesn't drop a transaction. Rowids
+	// are kept for the cursors (see AfterRowIDs).
+	`
+	CREATE TABLE account_transactions_new (
+		id TEXT NOT NULL,
+		product_id TEXT NOT NULL,
+		transaction_id TEXT,
+		operation_id TEXT,
+		status TEXT,
+		transaction_type TEXT,
+		workflow_code TEXT,
+		flow_direction TEXT,
+		transaction_date INTEGER NOT NULL DEFAULT 0,
+		settled_date INTEGER,
+		date TEXT,
+		month TEXT,
+		year TEXT,
+		debit_account_number TEXT,
+		credit_account_number TEXT,
+		beneficiary_name TEXT,
+		details TEXT,
+		source_system TEXT,
+		transaction_amount_currency TEXT,
+		transaction_amount_value REAL,
+		settled_amount_currency TEXT,
+		settled_amount_value REAL,
+		domestic_amount_currency TEXT,
+		domestic_amount_value REAL,
+		synced_at INTEGER NOT NULL,
+		PRIMARY KEY (id, transaction_date)
+	);
+	INSERT INTO account_transactions_new (rowid, id, product_id, transaction_id, operation_id, status,
+		transaction_type, workflow_code, flow_direction,
+		transaction_date, settled_date, date, month, year,
+		debit_account_number, credit_account_number,
+		beneficiary_name, details, source_system,
+		transaction_amount_currency, transaction_amount_value,
+		settled_amount_currency, settled_amount_value,
+		domestic_amount_currency, domestic_amount_value,
+		synced_at)
+	SELECT rowid, id, product_id, transaction_id, operation_id, status,
+		transaction_type, workflow_code, flow_direction,
+		COALESCE(transaction_date, 0), settled_date, date, month, year,
+		debit_account_number, credit_account_number,
+		beneficiary_name, details, source_system,
+		transaction_amount_currency, transaction_amount_value,
+		settled_amount_currency, settled_amount_value,
+		domestic_amount_currency, domestic_amount_value,
+		synced_at
+	FROM account_transactions;
+	DROP TABLE account_transactions;
+	ALTER TABLE account_transactions_new RENAME TO account_transactions;
+	CREATE INDEX IF NOT EXISTS idx_acct_txn_product_date ON account_transactions(product_id, transaction_date);
+	`,
+	// Version 23: Card purchases settling a pre-purchase authorization point
+	// to it, so both stages are kept as one purchase (see LinkCardTxnStages)
+	`
+	ALTER TABLE card_transactions ADD COLUMN authorization_id TEXT;
+	ALTER TABLE card_transactions ADD COLUMN authorization_date TEXT;
+	CREATE INDEX IF NOT EXISTS idx_card_txn_authorization ON card_transactions(product_id, authorization_id, authorization_date);
+	`,
+	// Version 24: Exchange rates by day, for converting amounts at the rate
+	// of their date (see RateHistory), starting with the current ones
+	`
+	CREATE TABLE IF NOT EXISTS exchange_rate_history (
+		currency TEXT NOT NULL,
+		day TEXT NOT NULL,
+		rate REAL NOT NULL,
+		source TEXT,
+		PRIMARY KEY (currency, day)
+	);
+	INSERT OR IGNORE INTO exchange_rate_history (currency, day, rate, source)
+	SELECT currency, date(updated_at, 'unixepoch', 'localtime'), rate, source FROM exchange_rates;
+	`,
+	// Version 25: Accounts and cards fetched from the API, reused by later
+	// runs for a short while (see LoadProducts)
+	`
+	CREATE TABLE IF NOT EXISTS products_cache (
+		client_id TEXT PRIMARY KEY,
+		response_json TEXT NOT NULL,
+		fetched_at INTEGER NOT NULL
+	);
+	`,
+	// Version 26: Budgets may be limited to a spending category and carry
+	// unspent amounts over to the next month (see GetBudgetMonths)
+	`
+	ALTER TABLE alert_rules ADD COLUMN category TEXT;
+	ALTER TABLE alert_rules ADD COLUMN rollover INTEGER NOT NULL DEFAULT 0;
+	`,
+	// Version 27: When stored transactions last changed during sync, keeping
+	// synced_at as the time they were first stored (see applyTxnChanges)
+	`
+	ALTER TABLE card_transactions ADD COLUMN updated_at INTEGER;
+	ALTER TABLE card_linked_account_transactions ADD COLUMN updated_at INTEGER;
+	ALTER TABLE account_transactions ADD COLUMN updated_at INTEGER;
+	`,
+	// Version 28: Terms and payment schedules of loans and deposits
+	`
+	ALTER TABLE loans ADD COLUMN open_date TEXT;
+	ALTER TABLE loans ADD COLUMN overdue_amount REAL;
+	ALTER TABLE deposits ADD COLUMN open_date TEXT;
+	ALTER TABLE deposits ADD COLUMN term_months INTEGER;
+	ALTER TABLE deposits ADD COLUMN capitalization INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE deposits ADD COLUMN replenishable INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE deposits ADD COLUMN interest_account_number TEXT;
+
+	CREATE TABLE IF NOT EXISTS loan_schedule (
+		loan_id TEXT NOT NULL,
+		order_index INTEGER NOT NULL,
+		date TEXT NOT NULL,
+		principal REAL,
+		interest REAL,
+		total REAL,
+		paid INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (loan_id, order_index)
+	);
+
+	CREATE TABLE IF NOT EXISTS deposit_schedule (
+		deposit_id TEXT NOT NULL,
+		order_index INTEGER NOT NULL,
+		date TEXT NOT NULL,
+		amount REAL,
+		tax REAL,
+		paid INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (deposit_id, order_index)
+	);
+	`,
+	// Version 29: When the sync holding the lock last showed it's still
+	// running, so long syncs aren't taken over as stale (see RefreshSyncLock)
+	`
+	ALTER TABLE sync_lock ADD COLUMN refreshed_at INTEGER;
+	`,
+}
+
+// normalizedDateSQL is operation_date normalized like NormalizeDate does
+const normalizedDateSQL = `COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', CASE WHEN substr(operation_date, 20) GLOB '*[Z+-]*'
+		THEN operation_date ELSE operation_date || '+04:00' END), operation_date)`
+
+// mergeNormalizedDatesSQL normalizes the operation dates of a card table keyed
+// by (id, operation_date). Rows of a transaction whose dates only differ in
+// format are merged into the one synced last, which keeps its values and
+// takes the extended columns (filled from transaction details) it lacks from
+// the others; it counts as fetched if any of them was.
+func mergeNormalizedDatesSQL(table string, extended ...string) string {
+	var fill strings.Builder
+	for _, col := range extended {
+		fmt.Fprintf(&fill, `
+		%[1]s = COALESCE(%[1]s, (SELECT d.%[1]s FROM %[2]s d JOIN merged_dates m ON m.rid = d.rowid
+			WHERE m.keep = %[2]s.rowid AND d.%[1]s IS NOT NULL)),`, col, table)
+	}
+	if len(extended) > 0 {
+		fmt.Fprintf(&fill, `
+		extended_fetched = (SELECT MAX(COALESCE(d.extended_fetched, 0)) FROM %[1]s d JOIN merged_dates m ON m.rid = d.rowid
+			WHERE m.keep = %[1]s.rowid),`, table)
+	}
+
+	query := `
+	CREATE TEMP TABLE normalized_dates AS
+		SELECT rowid AS rid, id, ` + normalizedDateSQL + ` AS date, synced_at FROM ` + table + `;
+	CREATE INDEX temp.idx_normalized_dates ON normalized_dates(id, date);
+	CREATE TEMP TABLE merged_dates AS
+		SELECT n.rid, (SELECT k.rid FROM normalized_dates k WHERE k.id = n.id AND k.date = n.date
+			ORDER BY k.synced_at DESC, k.rid DESC LIMIT 1) AS keep
+		FROM normalized_dates n;
+	`
+	if fill.Len() > 0 {
+		query += `UPDATE ` + table + ` SET` + strings.TrimSuffix(fill.String(), ",") + `
+	WHERE rowid IN (SELECT keep FROM merged_dates WHERE rid != keep);
+	`
+	}
+	return query + `DELETE FROM ` + table + ` WHERE rowid IN (SELECT rid FROM merged_dates WHERE rid != keep);
+	UPDATE ` + table + ` SET operation_date = ` + normalizedDateSQL + `;
+	DROP TABLE normalized_dates;
+	DROP TABLE merged_dates;
+	`
+}
+
+// breakingVersions are the schema versions that older binaries can't use a
+// database at: ones that change how stored data is represented rather than
+// add to it. Version 18 normalized operation dates, which older binaries
+// store as received, so they would store transactions twice.
+var breakingVersions = []int{18}
+
+// minCompatibleVersion returns the oldest schema version a binary may support
+// to use a database at the given version
+func minCompatibleVersion(version int) int {
+	minVersion := 1
+	for _, v := range breakingVersions {
+		if v <= version && v > minVersion {
+			minVersion = v
+		}
+	}
+	return minVersion
+}
+
+// downMigrations undo the migration to a version (see Downgrade). A database
+// can't be rolled back past a version without one; version 18 rewrote dates
+// in place and is irreversible. New migrations should come with a down
+// migration, unless they are breaking.
+var downMigrations = map[int]string{
+	10: `DROP TABLE IF EXISTS cursors;`,
+	11: `DROP TABLE IF EXISTS sync_lock;`,
+	12: `DROP TABLE IF EXISTS transaction_changes;`,
+	13: `DROP TABLE IF EXISTS card_holds;`,
+	14: `
+	ALTER TABLE transfer_templates DROP COLUMN group_id;
+	ALTER TABLE transfer_templates DROP COLUMN group_name;
+	`,
+	15: `DROP TABLE IF EXISTS utility_payments;`,
+	16: `
+	DROP INDEX IF EXISTS idx_products_client;
+	ALTER TABLE products DROP COLUMN client_id;
+	`,
+	17: `
+	ALTER TABLE card_linked_account_transactions DROP COLUMN swift_sender_bank;
+	ALTER TABLE card_linked_account_transactions DROP COLUMN swift_reference;
+	ALTER TABLE card_linked_account_transactions DROP COLUMN swift_fees;
+	ALTER TABLE card_linked_account_transactions DROP COLUMN swift_intermediary;
+	`,
+	19: `ALTER TABLE products DROP COLUMN available_balance_at;`,
+	20: `DROP TABLE IF EXISTS counterparties;`,
+	21: `DROP TABLE IF EXISTS product_sync;`,
+	// Of the transactions sharing an ID, the first stored is kept
+	22: `
+	CREATE TABLE account_transactions_old (
+		id TEXT NOT NULL PRIMARY KEY,
+		product_id TEXT NOT NULL,
+		transaction_id TEXT,
+		operation_id TEXT,
+		status TEXT,
+		transaction_type TEXT,
+		workflow_code TEXT,
+		flow_direction TEXT,
+		transaction_date INTEGER,
+		settled_date INTEGER,
+		date TEXT,
+		month TEXT,
+		year TEXT,
+		debit_account_number TEXT,
+		credit_account_number TEXT,
+		beneficiary_name TEXT,
+		details TEXT,
+		source_system TEXT,
+		transaction_amount_currency TEXT,
+		transaction_amount_value REAL,
+		settled_amount_currency TEXT,
+		settled_amount_value REAL,
+		domestic_amount_currency TEXT,
+		domestic_amount_value REAL,
+		synced_at INTEGER NOT NULL
+	);
+	INSERT OR IGNORE INTO account_transactions_old (rowid, id, product_id, transaction_id, operation_id, status,
+		transaction_type, workflow_code, flow_direction,
+		transaction_date, settled_date, date, month, year,
+		debit_account_number, credit_account_number,
+		beneficiary_name, details, source_system,
+		transaction_amount_currency, transaction_amount_value,
+		settled_amount_currency, settled_amount_value,
+		domestic_amount_currency, domestic_amount_value,
+		synced_at)
+	SELECT rowid, id, product_id, transaction_id, operation_id, status,
+		transaction_type, workflow_code, flow_direction,
+		transaction_date, settled_date, date, month, year,
+		debit_account_number, credit_account_number,
+		beneficiary_name, details, source_system,
+		transaction_amount_currency, transaction_amount_value,
+		settled_amount_currency, settled_amount_value,
+		domestic_amount_currency, domestic_amount_value,
+		synced_at
+	FROM account_transactions ORDER BY rowid;
+	DROP TABLE account_transactions;
+	ALTER TABLE account_transactions_old RENAME TO account_transactions;
+	CREATE INDEX IF NOT EXISTS idx_acct_txn_product_date ON account_transactions(product_id, transaction_date);
+	`,
+	23: `
+	DROP INDEX IF EXISTS idx_card_txn_authorization;
+	ALTER TABLE card_transactions DROP COLUMN authorization_id;
+	ALTER TABLE card_transactions DROP COLUMN authorization_date;
+	`,
+	24: `DROP TABLE IF EXISTS exchange_rate_history;`,
+	25: `DROP TABLE IF EXISTS products_cache;`,
+	26: `
+	ALTER TABLE alert_rules DROP COLUMN category;
+	ALTER TABLE alert_rules DROP COLUMN rollover;
+	`,
+	27: `
+	ALTER TABLE card_transactions DROP COLUMN updated_at;
+	ALTER TABLE card_linked_account_transactions DROP COLUMN updated_at;
+	ALTER TABLE account_transactions DROP COLUMN updated_at;
+	`,
+	28: `
+	DROP TABLE IF EXISTS loan_schedule;
+	DROP TABLE IF EXISTS deposit_schedule;
+	ALTER TABLE loans DROP COLUMN open_date;
+	ALTER TABLE loans DROP COLUMN overdue_amount;
+	ALTER TABLE deposits DROP COLUMN open_date;
+	ALTER TABLE deposits DROP COLUMN term_months;
+	ALTER TABLE deposits DROP COLUMN capitalization;
+	ALTER TABLE deposits DROP COLUMN replenishable;
+	ALTER TABLE deposits DROP COLUMN interest_account_number;
+	`,
+	29: `ALTER TABLE sync_lock DROP COLUMN refreshed_at;`,
+}
+
+// SchemaTooNewError is returned when opening a database migrated by a newer
+// binary in a way this one can't handle
+type SchemaTooNewError struct {
+	Version       int // schema version of the database
+	Supported     int // latest schema version this binary knows
+	MinCompatible int // oldest schema version a binary may support to use the database
 }
 
-// Migrate runs all pending migrations
+func (e *SchemaTooNewError) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than this version of ameriagrab supports (%d); "+
+		"upgrade ameriagrab to one supporting schema version %d or later, or roll the database back "+
+		"with 'ameriagrab db downgrade' of the newer version", e.Version, e.Supported, e.MinCompatible)
+}
+
+// Migrate runs all pending migrations. A database at a newer schema version
+// is left as is if this binary is compatible with it, and refused otherwise.
 func (db *DB) Migrate() error {
+	return db.migrateTo(schemaVersion)
+}
+
+// migrateTo runs the pending migrations up to the given version
+func (db *DB) migrateTo(target int) error {
 	// Create schema_version table if it doesn't exist
 	_, err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS schema_version (
@@ -182,25 +714,112 @@ func (db *DB) Migrate() error {
 	if err != nil {
 		return fmt.Errorf("failed to create schema_version table: %w", err)
 	}
+	// The oldest schema version binaries using the database must support,
+	// checked by binaries that don't know the current one
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_compat (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			min_version INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_compat table: %w", err)
+	}
 
-	// Get current version
-	var currentVersion int
-	row := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version")
-	if err := row.Scan(&currentVersion); err != nil {
-		return fmt.Errorf("failed to get current schema version: %w", err)
+	currentVersion, err := db.GetSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if currentVersion > schemaVersion {
+		minVersion, err := db.minCompatibleVersion(currentVersion)
+		if err != nil {
+			return err
+		}
+		if minVersion > schemaVersion {
+			return &SchemaTooNewError{Version: currentVersion, Supported: schemaVersion, MinCompatible: minVersion}
+		}
+		return nil
 	}
 
-	// Run pending migrations
-	for i := currentVersion; i < len(migrations); i++ {
-		version := i + 1
-		if _, err := db.Exec(migrations[i]); err != nil {
-			return fmt.Errorf("failed to run migration %d: %w", version, err)
+	// Run pending migrations, each in a transaction so that a failed one
+	// leaves the database at the previous version
+	for version := currentVersion + 1; version <= target; version++ {
+		err := db.WithTransaction(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migrations[version-1]); err != nil {
+				return fmt.Errorf("failed to run migration %d: %w", version, err)
+			}
+			if _, err := tx.Exec("INSERT INTO schema_version (version) VALUES (?)", version); err != nil {
+				return fmt.Errorf("failed to record migration %d: %w", version, err)
+			}
+			return setMinCompatibleVersion(tx, version)
+		})
+		if err != nil {
+			return err
 		}
-		if _, err := db.Exec("INSERT INTO schema_version (version) VALUES (?)", version); err != nil {
-			return fmt.Errorf("failed to record migration %d: %w", version, err)
+	}
+
+	return nil
+}
+
+// minCompatibleVersion returns the oldest schema version a binary may support
+// to use the database at currentVersion, as recorded by the binary that
+// migrated it. Without a record the database is taken to need currentVersion.
+func (db *DB) minCompatibleVersion(currentVersion int) (int, error) {
+	var minVersion int
+	err := db.QueryRow("SELECT min_version FROM schema_compat WHERE id = 1").Scan(&minVersion)
+	if errors.Is(err, sql.ErrNoRows) {
+		return currentVersion, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get compatible schema version: %w", err)
+	}
+	return minVersion, nil
+}
+
+// setMinCompatibleVersion records the oldest schema version binaries using a
+// database at the given version must support
+func setMinCompatibleVersion(tx *sql.Tx, version int) error {
+	_, err := tx.Exec(`
+		INSERT INTO schema_compat (id, min_version) VALUES (1, ?)
+		ON CONFLICT (id) DO UPDATE SET min_version = excluded.min_version`, minCompatibleVersion(version))
+	if err != nil {
+		return fmt.Errorf("failed to record compatible schema version: %w", err)
+	}
+	return nil
+}
+
+// Downgrade rolls the database back to the given schema version with the down
+// migrations, so that an older binary can use it. Nothing is changed if any
+// of the versions can't be rolled back. Opening the database with this binary
+// migrates it up again.
+func (db *DB) Downgrade(target int) error {
+	currentVersion, err := db.GetSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if target < 0 || target > currentVersion {
+		return fmt.Errorf("can't downgrade schema version %d to %d", currentVersion, target)
+	}
+	for version := currentVersion; version > target; version-- {
+		if _, ok := downMigrations[version]; !ok {
+			return fmt.Errorf("schema version %d can't be rolled back", version)
 		}
 	}
 
+	for version := currentVersion; version > target; version-- {
+		err := db.WithTransaction(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(downMigrations[version]); err != nil {
+				return fmt.Errorf("failed to roll back migration %d: %w", version, err)
+			}
+			if _, err := tx.Exec("DELETE FROM schema_version WHERE version = ?", version); err != nil {
+				return fmt.Errorf("failed to record rollback of migration %d: %w", version, err)
+			}
+			return setMinCompatibleVersion(tx, version-1)
+		})
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
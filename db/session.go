@@ -1,7 +1,10 @@
 package db
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
@@ -60,3 +63,31 @@ func (db *DB) UpdateClientID(clientID string) error {
 	`, clientID, time.Now().Unix())
 	return err
 }
+
+// SaveProducts stores an accounts-and-cards response for a client ID
+func (db *DB) SaveProducts(clientID string, data []byte) error {
+	_, err := db.Exec(`
+		INSERT OR REPLACE INTO products_cache (client_id, response_json, fetched_at)
+		VALUES (?, ?, ?)
+	`, clientID, string(data), time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to cache products: %w", err)
+	}
+	return nil
+}
+
+// LoadProducts returns the accounts-and-cards response stored for a client ID
+// if it was fetched after since, or nil
+func (db *DB) LoadProducts(clientID string, since time.Time) ([]byte, error) {
+	var data string
+	err := db.QueryRow(`
+		SELECT response_json FROM products_cache WHERE client_id = ? AND fetched_at > ?
+	`, clientID, since.Unix()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cached products: %w", err)
+	}
+	return []byte(data), nil
+}
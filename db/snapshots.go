@@ -17,10 +17,46 @@ type Snapshot struct {
 
 // CreateSnapshot creates a new snapshot by copying current product data
 func (db *DB) CreateSnapshot() (int64, error) {
-	createdAt := time.Now().Unix()
+	id, _, err := db.createSnapshot(time.Now(), false)
+	return id, err
+}
+
+// CreateDailySnapshot creates a new snapshot like CreateSnapshot, replacing any
+// snapshots taken earlier on the same calendar day (in local time), so at most one
+// snapshot per day is kept. Returns the number of replaced snapshots.
+func (db *DB) CreateDailySnapshot() (id int64, replaced int, err error) {
+	return db.createSnapshot(time.Now(), true)
+}
+
+func (db *DB) createSnapshot(now time.Time, daily bool) (int64, int, error) {
+	createdAt := now.Unix()
 
 	var snapshotID int64
+	var replaced int
 	err := db.WithTransaction(func(tx *sql.Tx) error {
+		if daily {
+			now = now.Local()
+			dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+			dayEnd := dayStart.AddDate(0, 0, 1)
+			if _, err := tx.Exec(`
+				DELETE FROM snapshot_products WHERE snapshot_id IN (
+					SELECT id FROM snapshots WHERE created_at >= ? AND created_at < ?
+				)
+			`, dayStart.Unix(), dayEnd.Unix()); err != nil {
+				return fmt.Errorf("failed to delete same-day snapshot products: %w", err)
+			}
+			result, err := tx.Exec(`DELETE FROM snapshots WHERE created_at >= ? AND created_at < ?`,
+				dayStart.Unix(), dayEnd.Unix())
+			if err != nil {
+				return fmt.Errorf("failed to delete same-day snapshots: %w", err)
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to count deleted snapshots: %w", err)
+			}
+			replaced = int(n)
+		}
+
 		// Create snapshot record
 		result, err := tx.Exec(`INSERT INTO snapshots (created_at) VALUES (?)`, createdAt)
 		if err != nil {
@@ -50,13 +86,13 @@ func (db *DB) CreateSnapshot() (int64, error) {
 		return nil
 	})
 
-	return snapshotID, err
+	return snapshotID, replaced, err
 }
 
 // GetSnapshots returns all snapshots with their products, in ascending chronological order
 func (db *DB) GetSnapshots() ([]Snapshot, error) {
 	// First get all snapshots
-	rows, err := db.Query(`SELECT id, created_at FROM snapshots ORDER BY created_at ASC`)
+	rows, err := db.Query(`SELECT id, created_at FROM snapshots ORDER BY created_at ASC, id ASC`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query snapshots: %w", err)
 	}
@@ -141,3 +177,46 @@ func (db *DB) CountSnapshots() (int, error) {
 	}
 	return count, nil
 }
+
+// BalancePoint is a known balance of a product at a point in time
+type BalancePoint struct {
+	At         time.Time `json:"at"`
+	Balance    float64   `json:"balance"`
+	SnapshotID int64     `json:"snapshot_id"` // 0 for the balance stored by the last sync
+}
+
+// GetBalancePoints returns the known balances of a product from snapshots and
+// the last sync, in ascending chronological order
+func (db *DB) GetBalancePoints(productID string) ([]BalancePoint, error) {
+	rows, err := db.Query(`
+		SELECT s.id, s.created_at, sp.balance
+		FROM snapshot_products sp JOIN snapshots s ON s.id = sp.snapshot_id
+		WHERE sp.product_id = ?
+		UNION ALL
+		SELECT 0, synced_at, balance FROM products WHERE id = ?
+		ORDER BY 2
+	`, productID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balance points: %w", err)
+	}
+	defer rows.Close()
+
+	var points []BalancePoint
+	for rows.Next() {
+		var p BalancePoint
+		var at int64
+		var balance sql.NullFloat64
+		if err := rows.Scan(&p.SnapshotID, &at, &balance); err != nil {
+			return nil, fmt.Errorf("failed to scan balance point: %w", err)
+		}
+		p.At = time.Unix(at, 0)
+		p.Balance = balance.Float64
+		points = append(points, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating balance points: %w", err)
+	}
+
+	return points, nil
+}
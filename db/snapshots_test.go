@@ -0,0 +1,59 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestCreateDailySnapshot(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.UpsertProducts([]client.ProductInfo{{ID: "P1", ProductType: "CARD", Name: "Card", Currency: "AMD", Balance: 100}}); err != nil {
+		t.Fatalf("UpsertProducts failed: %v", err)
+	}
+
+	morning := time.Date(2024, 3, 10, 8, 0, 0, 0, time.Local)
+	yesterday, _, err := db.createSnapshot(morning.AddDate(0, 0, -1), true)
+	if err != nil {
+		t.Fatalf("createSnapshot failed: %v", err)
+	}
+	if _, _, err := db.createSnapshot(morning, false); err != nil {
+		t.Fatalf("createSnapshot failed: %v", err)
+	}
+	if _, _, err := db.createSnapshot(morning.Add(2*time.Hour), false); err != nil {
+		t.Fatalf("createSnapshot failed: %v", err)
+	}
+
+	evening, replaced, err := db.createSnapshot(morning.Add(12*time.Hour), true)
+	if err != nil {
+		t.Fatalf("createSnapshot failed: %v", err)
+	}
+	if replaced != 2 {
+		t.Errorf("expected 2 same-day snapshots to be replaced, got %d", replaced)
+	}
+
+	snapshots, err := db.GetSnapshots()
+	if err != nil {
+		t.Fatalf("GetSnapshots failed: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].ID != yesterday || snapshots[1].ID != evening {
+		t.Fatalf("expected yesterday's and the evening snapshot, got %+v", snapshots)
+	}
+	if len(snapshots[1].Products) != 1 || snapshots[1].Products[0].Balance != 100 {
+		t.Errorf("unexpected snapshot products: %+v", snapshots[1].Products)
+	}
+
+	var orphans int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM snapshot_products WHERE snapshot_id NOT IN (SELECT id FROM snapshots)`).Scan(&orphans); err != nil {
+		t.Fatalf("failed to count snapshot products: %v", err)
+	}
+	if orphans != 0 {
+		t.Errorf("expected replaced snapshot products to be deleted, got %d left", orphans)
+	}
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// AuthorizationPrefix starts the type of a card's pre-purchase authorization;
+// the purchase settling it has the same type without "pre-"
+const AuthorizationPrefix = "pre-purchase:"
+
+// StageWindow is how long after a pre-purchase authorization the purchase
+// settling it may be dated
+const StageWindow = 30 * 24 * time.Hour
+
+// IsAuthorization reports whether a card transaction is a pre-purchase authorization
+func IsAuthorization(t client.Transaction) bool {
+	return strings.HasPrefix(t.TransactionType, AuthorizationPrefix)
+}
+
+// stageRow is a stored card transaction considered by LinkCardTxnStages
+type stageRow struct {
+	id, txnType, currency, details, operationDate string
+	amount                                        money.Amount
+	date                                          time.Time
+}
+
+// LinkCardTxnStages links a card's pre-purchase authorizations to the purchases
+// settling them, so both are kept as stages of one purchase. An authorization
+// is settled by the earliest unlinked purchase of the same kind, amount and
+// currency within StageWindow after it, preferably one with the same details.
+// Returns the number of newly linked purchases.
+func (db *DB) LinkCardTxnStages(productID string) (int, error) {
+	rows, err := db.query(`
+		SELECT id, transaction_type, amount_currency, amount_value, details, operation_date
+		FROM card_transactions t
+		WHERE product_id = ? AND CASE WHEN transaction_type LIKE ?
+			THEN NOT EXISTS (`+settlementSQL+`)
+			ELSE authorization_id IS NULL END
+		ORDER BY operation_date, rowid
+	`, productID, AuthorizationPrefix+"%")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query card transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var auths, purchases []stageRow
+	for rows.Next() {
+		var r stageRow
+		var txnType, currency, details sql.NullString
+		var amount sql.NullFloat64
+		if err := rows.Scan(&r.id, &txnType, &currency, &amount, &details, &r.operationDate); err != nil {
+			return 0, fmt.Errorf("failed to scan card transaction: %w", err)
+		}
+		r.txnType, r.currency, r.details = txnType.String, currency.String, details.String
+		r.amount = money.FromFloat(amount.Float64)
+		if r.date, err = ParseDate(r.operationDate); err != nil {
+			continue
+		}
+		if strings.HasPrefix(r.txnType, AuthorizationPrefix) {
+			auths = append(auths, r)
+		} else {
+			purchases = append(purchases, r)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating card transactions: %w", err)
+	}
+	rows.Close()
+
+	type link struct{ purchase, auth stageRow }
+	var links []link
+	used := make([]bool, len(purchases))
+	for _, a := range auths {
+		settledType := strings.TrimPrefix(a.txnType, "pre-")
+		best := -1
+		for i, p := range purchases {
+			if used[i] || p.txnType != settledType || p.currency != a.currency || p.amount != a.amount ||
+				p.date.Before(a.date) || p.date.Sub(a.date) > StageWindow {
+				continue
+			}
+			if best < 0 {
+				best = i
+			}
+			if p.details == a.details {
+				best = i
+				break
+			}
+		}
+		if best >= 0 {
+			used[best] = true
+			links = append(links, link{purchases[best], a})
+		}
+	}
+	if len(links) == 0 {
+		return 0, nil
+	}
+
+	err = db.WithTransaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`
+			UPDATE card_transactions SET authorization_id = ?, authorization_date = ?
+			WHERE product_id = ? AND ` + cardTxnWhere)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, l := range links {
+			if _, err := stmt.Exec(l.auth.id, l.auth.operationDate, productID, l.purchase.id, l.purchase.operationDate); err != nil {
+				return fmt.Errorf("failed to link card transaction %s: %w", l.purchase.id, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(links), nil
+}
+
+// settlementSQL selects the ID of the purchase settling the authorization t
+const settlementSQL = `
+	SELECT s.id FROM card_transactions s
+	WHERE s.product_id = t.product_id AND s.authorization_id = t.id AND s.authorization_date = t.operation_date`
+
+// CountCardTxnStages returns the number of a card's purchases linked to their
+// authorization, i.e. the transactions ReadOptions.CollapseStages leaves out
+func (db *DB) CountCardTxnStages(productID string) (int, error) {
+	var count int
+	err := db.queryRow(`
+		SELECT COUNT(*) FROM card_transactions WHERE product_id = ? AND authorization_id IS NOT NULL
+	`, productID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count linked purchases: %w", err)
+	}
+	return count, nil
+}
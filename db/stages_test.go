@@ -0,0 +1,86 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestLinkCardTxnStages(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	txn := func(id, txnType string, amount float64, details, date string) client.Transaction {
+		return client.Transaction{
+			ID: id, TransactionType: txnType, AccountingType: "DEBIT", State: "SETTLED",
+			Amount: client.Amount{Currency: "AMD", Amount: amount}, Details: details,
+			OperationDate: date,
+		}
+	}
+	txns := []client.Transaction{
+		txn("a1", "pre-purchase:pos", 1000, "SHOP", "2024-01-10T10:00:00Z"),
+		txn("p0", "purchase:pos", 1000, "CAFE", "2024-01-11T10:00:00Z"),
+		txn("p1", "purchase:pos", 1000, "SHOP", "2024-01-12T10:00:00Z"),
+		// Settled before it was authorized, and a different amount
+		txn("a2", "pre-purchase:pos", 500, "SHOP", "2024-01-13T10:00:00Z"),
+		txn("p2", "purchase:pos", 500, "SHOP", "2024-01-09T10:00:00Z"),
+		txn("p3", "purchase:pos", 501, "SHOP", "2024-01-14T10:00:00Z"),
+		// Settled after StageWindow
+		txn("a3", "pre-purchase:atm", 2000, "ATM", "2024-01-01T10:00:00Z"),
+		txn("p4", "purchase:atm", 2000, "ATM", "2024-03-01T10:00:00Z"),
+	}
+	if _, err := db.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("InsertCardTransactions failed: %v", err)
+	}
+
+	linked, err := db.LinkCardTxnStages("card1")
+	if err != nil {
+		t.Fatalf("LinkCardTxnStages failed: %v", err)
+	}
+	if linked != 1 {
+		t.Errorf("expected 1 linked purchase, got %d", linked)
+	}
+	// Linking again finds nothing new
+	if linked, err := db.LinkCardTxnStages("card1"); err != nil || linked != 0 {
+		t.Errorf("expected nothing to link again, got %d, %v", linked, err)
+	}
+	if count, err := db.CountCardTxnStages("card1"); err != nil || count != 1 {
+		t.Errorf("expected 1 linked purchase, got %d, %v", count, err)
+	}
+
+	read := func(collapse bool) map[string]client.Transaction {
+		byID := make(map[string]client.Transaction)
+		err := db.ForEachCardTransaction("card1", ReadOptions{CollapseStages: collapse}, func(t client.Transaction) error {
+			byID[t.ID] = t
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ForEachCardTransaction failed: %v", err)
+		}
+		return byID
+	}
+
+	all := read(false)
+	if len(all) != len(txns) {
+		t.Fatalf("expected %d transactions, got %d", len(txns), len(all))
+	}
+	if all["p1"].AuthorizationID != "a1" || all["a1"].SettlementID != "p1" {
+		t.Errorf("expected a1 and p1 linked, got %+v and %+v", all["a1"], all["p1"])
+	}
+	for _, id := range []string{"p0", "a2", "p2", "p3", "a3", "p4"} {
+		if all[id].AuthorizationID != "" || all[id].SettlementID != "" {
+			t.Errorf("expected %s unlinked, got %+v", id, all[id])
+		}
+	}
+
+	collapsed := read(true)
+	if _, ok := collapsed["a1"]; ok || len(collapsed) != len(txns)-1 {
+		t.Errorf("expected only the linked authorization left out, got %d transactions", len(collapsed))
+	}
+	if _, ok := collapsed["a2"]; !ok {
+		t.Error("expected the unsettled authorization to be kept")
+	}
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// prepared returns the statement for query, preparing it on first use and
+// caching it for the life of the DB. database/sql prepares a statement again
+// on each connection it runs on, so cached statements stay valid as the pool
+// changes. Only queries with a bounded set of texts should be cached; the
+// cache is never trimmed.
+func (db *DB) prepared(query string) (*sql.Stmt, error) {
+	db.stmtMu.Lock()
+	defer db.stmtMu.Unlock()
+	if stmt, ok := db.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	if db.stmts == nil {
+		db.stmts = make(map[string]*sql.Stmt)
+	}
+	db.stmts[query] = stmt
+	return stmt, nil
+}
+
+// queryRow is QueryRow with a cached statement
+func (db *DB) queryRow(query string, args ...interface{}) *sql.Row {
+	stmt, err := db.prepared(query)
+	if err != nil {
+		// QueryRow reports the same error on Scan
+		return db.QueryRow(query, args...)
+	}
+	return stmt.QueryRow(args...)
+}
+
+// query is Query with a cached statement
+func (db *DB) query(query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := db.prepared(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Query(args...)
+}
+
+// exec is Exec with a cached statement
+func (db *DB) exec(query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := db.prepared(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Exec(args...)
+}
+
+// prepare caches statements for queries run in a transaction that is about
+// to begin (see txStmt)
+func (db *DB) prepare(queries ...string) error {
+	for _, query := range queries {
+		if _, err := db.prepared(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// txStmt returns the statement for query bound to tx: the cached one if it
+// was prepared before the transaction began, otherwise one prepared on tx.
+// Statements can't be cached from within a transaction, since preparing on
+// the pool takes a second connection, and each connection to an in-memory
+// database sees a database of its own. The statement is closed with tx.
+func (db *DB) txStmt(tx *sql.Tx, query string) (*sql.Stmt, error) {
+	db.stmtMu.Lock()
+	stmt, ok := db.stmts[query]
+	db.stmtMu.Unlock()
+	if ok {
+		return tx.Stmt(stmt), nil
+	}
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	return stmt, nil
+}
+
+// Close closes the cached statements and the database
+func (db *DB) Close() error {
+	db.stmtMu.Lock()
+	var errs []error
+	for _, stmt := range db.stmts {
+		errs = append(errs, stmt.Close())
+	}
+	db.stmts = nil
+	db.stmtMu.Unlock()
+	return errors.Join(append(errs, db.DB.Close())...)
+}
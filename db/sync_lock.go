@@ -0,0 +1,73 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SyncLock describes the process holding the sync lock
+type SyncLock struct {
+	Host       string
+	PID        int
+	AcquiredAt time.Time
+	// ActiveAt is when the holder last refreshed the lock (see RefreshSyncLock),
+	// or AcquiredAt if it hasn't
+	ActiveAt time.Time
+}
+
+// TryAcquireSyncLock takes the sync lock for the given process unless another one
+// holds it. A lock neither acquired nor refreshed since staleBefore is taken over.
+// Returns nil if the lock was acquired, or the current holder otherwise.
+func (db *DB) TryAcquireSyncLock(host string, pid int, staleBefore time.Time) (*SyncLock, error) {
+	result, err := db.Exec(`
+		INSERT INTO sync_lock (id, host, pid, acquired_at) VALUES (1, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			host = excluded.host,
+			pid = excluded.pid,
+			acquired_at = excluded.acquired_at,
+			refreshed_at = NULL
+		WHERE MAX(sync_lock.acquired_at, COALESCE(sync_lock.refreshed_at, 0)) < ?
+	`, host, pid, time.Now().Unix(), staleBefore.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire sync lock: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil, nil
+	}
+
+	var lock SyncLock
+	var acquiredAt, activeAt int64
+	err = db.QueryRow(`
+		SELECT host, pid, acquired_at, MAX(acquired_at, COALESCE(refreshed_at, 0)) FROM sync_lock WHERE id = 1
+	`).Scan(&lock.Host, &lock.PID, &acquiredAt, &activeAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Released in the meantime
+		return db.TryAcquireSyncLock(host, pid, staleBefore)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync lock: %w", err)
+	}
+	lock.AcquiredAt = time.Unix(acquiredAt, 0)
+	lock.ActiveAt = time.Unix(activeAt, 0)
+	return &lock, nil
+}
+
+// RefreshSyncLock records that the given process, if it holds the sync lock,
+// is still running, so that the lock isn't taken over as stale
+func (db *DB) RefreshSyncLock(host string, pid int) error {
+	_, err := db.Exec(`UPDATE sync_lock SET refreshed_at = ? WHERE host = ? AND pid = ?`, time.Now().Unix(), host, pid)
+	if err != nil {
+		return fmt.Errorf("failed to refresh sync lock: %w", err)
+	}
+	return nil
+}
+
+// ReleaseSyncLock releases the sync lock if it's held by the given process
+func (db *DB) ReleaseSyncLock(host string, pid int) error {
+	if _, err := db.Exec(`DELETE FROM sync_lock WHERE host = ? AND pid = ?`, host, pid); err != nil {
+		return fmt.Errorf("failed to release sync lock: %w", err)
+	}
+	return nil
+}
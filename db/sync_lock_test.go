@@ -0,0 +1,59 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncLock(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	staleBefore := time.Now().Add(-time.Hour)
+	if holder, err := db.TryAcquireSyncLock("host1", 100, staleBefore); err != nil || holder != nil {
+		t.Fatalf("expected to acquire the lock, got holder %+v (err %v)", holder, err)
+	}
+
+	holder, err := db.TryAcquireSyncLock("host2", 200, staleBefore)
+	if err != nil {
+		t.Fatalf("TryAcquireSyncLock failed: %v", err)
+	}
+	if holder == nil || holder.Host != "host1" || holder.PID != 100 {
+		t.Fatalf("expected lock to be held by host1/100, got %+v", holder)
+	}
+
+	// Releasing someone else's lock is a no-op
+	if err := db.ReleaseSyncLock("host2", 200); err != nil {
+		t.Fatalf("ReleaseSyncLock failed: %v", err)
+	}
+	if holder, _ := db.TryAcquireSyncLock("host2", 200, staleBefore); holder == nil {
+		t.Fatal("expected lock to still be held")
+	}
+
+	// A refreshed lock isn't stale, however long ago it was acquired
+	if _, err := db.Exec(`UPDATE sync_lock SET acquired_at = ?`, time.Now().Add(-2*time.Hour).Unix()); err != nil {
+		t.Fatalf("failed to age the lock: %v", err)
+	}
+	if err := db.RefreshSyncLock("host1", 100); err != nil {
+		t.Fatalf("RefreshSyncLock failed: %v", err)
+	}
+	holder, err = db.TryAcquireSyncLock("host2", 200, staleBefore)
+	if err != nil || holder == nil || holder.ActiveAt.Before(staleBefore) || !holder.AcquiredAt.Before(staleBefore) {
+		t.Fatalf("expected the refreshed lock to be held, got %+v (err %v)", holder, err)
+	}
+
+	// A stale lock is taken over
+	if holder, err := db.TryAcquireSyncLock("host2", 200, time.Now().Add(time.Hour)); err != nil || holder != nil {
+		t.Fatalf("expected to take over the stale lock, got holder %+v (err %v)", holder, err)
+	}
+
+	if err := db.ReleaseSyncLock("host2", 200); err != nil {
+		t.Fatalf("ReleaseSyncLock failed: %v", err)
+	}
+	if holder, err := db.TryAcquireSyncLock("host1", 100, staleBefore); err != nil || holder != nil {
+		t.Fatalf("expected to acquire the released lock, got holder %+v (err %v)", holder, err)
+	}
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Sync run statuses
+const (
+	SyncRunRunning = "running"
+	SyncRunSuccess = "success"
+	SyncRunFailed  = "failed"
+)
+
+// SyncTriggerPartial is the trigger of sync runs limited to selected products
+const SyncTriggerPartial = "partial"
+
+// SyncRun represents a single recorded sync run
+type SyncRun struct {
+	ID         int64
+	Trigger    string // "manual", "daemon", "bot" or SyncTriggerPartial
+	StartedAt  time.Time
+	FinishedAt time.Time // zero if still running
+	Status     string
+	Error      string
+}
+
+// StartSyncRun records the start of a sync run and returns its ID
+func (db *DB) StartSyncRun(trigger string) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO sync_runs (trigger, started_at, status) VALUES (?, ?, ?)
+	`, trigger, time.Now().Unix(), SyncRunRunning)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record sync run: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sync run ID: %w", err)
+	}
+	return id, nil
+}
+
+// FinishSyncRun marks a sync run as finished with the given status and error message
+func (db *DB) FinishSyncRun(id int64, status, errMsg string) error {
+	_, err := db.Exec(`
+		UPDATE sync_runs SET finished_at = ?, status = ?, error = ? WHERE id = ?
+	`, time.Now().Unix(), status, nullString(errMsg), id)
+	if err != nil {
+		return fmt.Errorf("failed to update sync run: %w", err)
+	}
+	return nil
+}
+
+// GetSyncRuns returns the most recent sync runs, newest first.
+// If limit is 0, returns all runs.
+func (db *DB) GetSyncRuns(limit int) ([]SyncRun, error) {
+	query := `
+		SELECT id, trigger, started_at, finished_at, status, error
+		FROM sync_runs
+		ORDER BY started_at DESC, id DESC
+	`
+	var rows *sql.Rows
+	var err error
+	if limit > 0 {
+		rows, err = db.Query(query+" LIMIT ?", limit)
+	} else {
+		rows, err = db.Query(query)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []SyncRun
+	for rows.Next() {
+		var r SyncRun
+		var startedAt int64
+		var finishedAt sql.NullInt64
+		var errMsg sql.NullString
+		if err := rows.Scan(&r.ID, &r.Trigger, &startedAt, &finishedAt, &r.Status, &errMsg); err != nil {
+			return nil, fmt.Errorf("failed to scan sync run: %w", err)
+		}
+		r.StartedAt = time.Unix(startedAt, 0)
+		if finishedAt.Valid {
+			r.FinishedAt = time.Unix(finishedAt.Int64, 0)
+		}
+		r.Error = errMsg.String
+		runs = append(runs, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// LastSuccessfulSync returns the start time of the most recent successful full sync run,
+// or the zero time if there is none. Partial runs are ignored since they skip some products.
+func (db *DB) LastSuccessfulSync() (time.Time, error) {
+	var startedAt sql.NullInt64
+	err := db.QueryRow(`SELECT MAX(started_at) FROM sync_runs WHERE status = ? AND trigger != ?`,
+		SyncRunSuccess, SyncTriggerPartial).Scan(&startedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to query last successful sync: %w", err)
+	}
+	if !startedAt.Valid {
+		return time.Time{}, nil
+	}
+	return time.Unix(startedAt.Int64, 0), nil
+}
+
+// SyncFailureStreak describes the failed sync runs since the last successful one
+type SyncFailureStreak struct {
+	Count     int       // consecutive failed runs, 0 if the last finished run succeeded
+	Since     time.Time // start of the first failed run of the streak
+	LastError string
+}
+
+// GetSyncFailureStreak returns the failed sync runs recorded after the last
+// successful one. Runs still running are ignored.
+func (db *DB) GetSyncFailureStreak() (SyncFailureStreak, error) {
+	var streak SyncFailureStreak
+	var since sql.NullInt64
+	err := db.QueryRow(`
+		SELECT COUNT(*), MIN(started_at) FROM sync_runs
+		WHERE status = ? AND id > (SELECT COALESCE(MAX(id), 0) FROM sync_runs WHERE status = ?)
+	`, SyncRunFailed, SyncRunSuccess).Scan(&streak.Count, &since)
+	if err != nil {
+		return streak, fmt.Errorf("failed to query failed sync runs: %w", err)
+	}
+	if streak.Count == 0 {
+		return streak, nil
+	}
+	streak.Since = time.Unix(since.Int64, 0)
+
+	var errMsg sql.NullString
+	err = db.QueryRow(`
+		SELECT error FROM sync_runs WHERE status = ? ORDER BY id DESC LIMIT 1
+	`, SyncRunFailed).Scan(&errMsg)
+	if err != nil {
+		return streak, fmt.Errorf("failed to query last sync error: %w", err)
+	}
+	streak.LastError = errMsg.String
+	return streak, nil
+}
@@ -2,6 +2,8 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
@@ -41,8 +43,9 @@ func (db *DB) UpsertTemplates(templates []client.TransferTemplate) error {
 		// Insert new templates
 		stmt, err := tx.Prepare(`
 			INSERT INTO transfer_templates (
-				id, name, workflow_code, masked_card_number, account_number, beneficiary, card_key, synced_at
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				id, name, workflow_code, masked_card_number, account_number, beneficiary, card_key,
+				group_id, group_name, synced_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`)
 		if err != nil {
 			return err
@@ -69,6 +72,8 @@ func (db *DB) UpsertTemplates(templates []client.TransferTemplate) error {
 				accountNumber,
 				t.Data.Beneficiary,
 				cardKey,
+				nullString(t.GroupID),
+				nullString(t.GroupName),
 				now,
 			)
 			if err != nil {
@@ -93,7 +98,7 @@ func (db *DB) GetTemplateByMaskedCard(maskedCard string) (string, error) {
 	}
 
 	var name string
-	err := db.QueryRow(
+	err := db.queryRow(
 		"SELECT name FROM transfer_templates WHERE card_key = ?",
 		cardKey,
 	).Scan(&name)
@@ -106,6 +111,69 @@ func (db *DB) GetTemplateByMaskedCard(maskedCard string) (string, error) {
 	return name, nil
 }
 
+// GetTemplateWithGroupByMaskedCard looks up a template by masked card number like
+// GetTemplateByMaskedCard, also returning the name of its group (empty if ungrouped)
+func (db *DB) GetTemplateWithGroupByMaskedCard(maskedCard string) (name, group string, err error) {
+	cardKey := extractCardKey(maskedCard)
+	if cardKey == "" {
+		return "", "", nil
+	}
+
+	var groupName sql.NullString
+	err = db.queryRow(
+		"SELECT name, group_name FROM transfer_templates WHERE card_key = ?",
+		cardKey,
+	).Scan(&name, &groupName)
+	if err != nil {
+		// No template found is not an error
+		return "", "", nil
+	}
+
+	return name, groupName.String, nil
+}
+
+// GetTemplateWithGroupByCounterparty looks up a template by the account number or
+// masked card number of a counterparty, returning its name and group name
+// (both empty if no template matches)
+func (db *DB) GetTemplateWithGroupByCounterparty(number string) (name, group string, err error) {
+	if number == "" {
+		return "", "", nil
+	}
+	// Masked numbers are cards; full ones may be accounts
+	if strings.Contains(number, "*") {
+		return db.GetTemplateWithGroupByMaskedCard(number)
+	}
+
+	var groupName sql.NullString
+	err = db.queryRow(
+		"SELECT name, group_name FROM transfer_templates WHERE account_number = ?",
+		number,
+	).Scan(&name, &groupName)
+	if err != nil {
+		// No template found is not an error
+		return "", "", nil
+	}
+
+	return name, groupName.String, nil
+}
+
+// TemplateLabel formats a template name with its group for counterparty display
+func TemplateLabel(name, group string) string {
+	switch {
+	case name != "" && group != "":
+		return fmt.Sprintf("%s [%s]", name, group)
+	case name != "":
+		return name
+	case group != "":
+		return "[" + group + "]"
+	}
+	return ""
+}
+
+// PlaceholderBeneficiary is the beneficiary name the bank shows when the real
+// one is unknown
+const PlaceholderBeneficiary = "Firstname Lastname"
+
 // GetTemplateByAccount looks up a template by account number
 func (db *DB) GetTemplateByAccount(accountNumber string) (string, error) {
 	if accountNumber == "" {
@@ -113,7 +181,7 @@ func (db *DB) GetTemplateByAccount(accountNumber string) (string, error) {
 	}
 
 	var name string
-	err := db.QueryRow(
+	err := db.queryRow(
 		"SELECT name FROM transfer_templates WHERE account_number = ?",
 		accountNumber,
 	).Scan(&name)
@@ -129,6 +197,6 @@ func (db *DB) GetTemplateByAccount(accountNumber string) (string, error) {
 // CountTemplates returns the number of stored templates
 func (db *DB) CountTemplates() (int, error) {
 	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM transfer_templates").Scan(&count)
+	err := db.queryRow("SELECT COUNT(*) FROM transfer_templates").Scan(&count)
 	return count, err
 }
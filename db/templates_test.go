@@ -397,3 +397,87 @@ func TestUpsertTemplates_Empty(t *testing.T) {
 		t.Errorf("expected 0 templates after empty sync, got %d", count)
 	}
 }
+
+func TestUpsertTemplates_Groups(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	grouped := makeTemplate("id1", "Mom", "4454********6615", "CARD", "")
+	grouped.GroupID = "g1"
+	grouped.GroupName = "Family"
+	templates := []client.TransferTemplate{
+		grouped,
+		makeTemplate("id2", "Bob", "5555********1234", "CARD", ""),
+	}
+	if err := db.UpsertTemplates(templates); err != nil {
+		t.Fatalf("UpsertTemplates failed: %v", err)
+	}
+
+	name, group, err := db.GetTemplateWithGroupByMaskedCard("44543********615")
+	if err != nil {
+		t.Fatalf("GetTemplateWithGroupByMaskedCard failed: %v", err)
+	}
+	if name != "Mom" || group != "Family" {
+		t.Errorf("expected Mom in Family, got %q in %q", name, group)
+	}
+
+	name, group, _ = db.GetTemplateWithGroupByMaskedCard("5555********1234")
+	if name != "Bob" || group != "" {
+		t.Errorf("expected ungrouped Bob, got %q in %q", name, group)
+	}
+}
+
+func TestGetTemplateWithGroupByCounterparty(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	landlord := makeTemplate("id1", "Landlord", "1570000000000001", "ACCOUNT", "")
+	landlord.GroupName = "Household"
+	templates := []client.TransferTemplate{
+		landlord,
+		makeTemplate("id2", "Alice", "4454********6615", "CARD", ""),
+	}
+	if err := db.UpsertTemplates(templates); err != nil {
+		t.Fatalf("UpsertTemplates failed: %v", err)
+	}
+
+	name, group, _ := db.GetTemplateWithGroupByCounterparty("1570000000000001")
+	if name != "Landlord" || group != "Household" {
+		t.Errorf("expected Landlord in Household, got %q in %q", name, group)
+	}
+	name, _, _ = db.GetTemplateWithGroupByCounterparty("44543********615")
+	if name != "Alice" {
+		t.Errorf("expected Alice by masked card, got %q", name)
+	}
+	name, _, _ = db.GetTemplateWithGroupByCounterparty("1570000000000002")
+	if name != "" {
+		t.Errorf("expected no template, got %q", name)
+	}
+
+	// Account ledger entries without a beneficiary are named from templates
+	txns := []client.AccountTransaction{
+		{ID: "t1", FlowDirection: "EXPENSE", CreditAccountNumber: "1570000000000001", TransactionDate: 1704067200000},
+		{ID: "t2", FlowDirection: "INCOME", DebitAccountNumber: "44543********615", BeneficiaryName: PlaceholderBeneficiary, TransactionDate: 1704067200000},
+		{ID: "t3", FlowDirection: "EXPENSE", CreditAccountNumber: "1570000000000001", BeneficiaryName: "Real Name", TransactionDate: 1704067200000},
+	}
+	if _, _, err := db.ApplyAccountTransactions("acc1", txns); err != nil {
+		t.Fatalf("ApplyAccountTransactions failed: %v", err)
+	}
+	entries, err := db.GetLedgerEntries(LedgerOptions{Sources: []string{SourceAccount}})
+	if err != nil {
+		t.Fatalf("GetLedgerEntries failed: %v", err)
+	}
+	got := make(map[string]string)
+	for _, e := range entries {
+		got[e.ID] = e.Counterparty
+	}
+	if got["t1"] != "Landlord [Household]" || got["t2"] != "Alice" || got["t3"] != "Real Name" {
+		t.Errorf("unexpected counterparties: %v", got)
+	}
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TransactionChange is a change of one field of a stored transaction seen during sync
+type TransactionChange struct {
+	ID            int64     `json:"id"`
+	Source        string    `json:"source"` // SourceCard, SourceLinked or SourceAccount
+	ProductID     string    `json:"product_id"`
+	TxnID         string    `json:"txn_id"`
+	OperationDate string    `json:"operation_date,omitempty"` // card and linked account transactions only
+	Field         string    `json:"field"`
+	OldValue      string    `json:"old_value"`
+	NewValue      string    `json:"new_value"`
+	ChangedAt     time.Time `json:"changed_at"`
+}
+
+// txnRow identifies a stored transaction for applyTxnChanges
+type txnRow struct {
+	table         string
+	source        string
+	productID     string
+	id            string
+	operationDate string
+	where         string // condition selecting the row
+	whereArgs     []interface{}
+}
+
+const insertTxnChangeSQL = `
+	INSERT INTO transaction_changes (
+		source, product_id, txn_id, operation_date, field, old_value, new_value, changed_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+// txnSelectSQL selects the given columns of the row of table matching where
+func txnSelectSQL(table, where string, columns []string) string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(columns, ", "), table, where)
+}
+
+// prepareTxnChanges caches the statements applyTxnChanges runs for rows of
+// table matching where, before the transaction applying them begins
+func (db *DB) prepareTxnChanges(table, where string, columns []string) error {
+	return db.prepare(txnSelectSQL(table, where, columns), insertTxnChangeSQL)
+}
+
+// applyTxnChanges compares the given columns of a stored transaction with new values.
+// Changed columns are updated and recorded in transaction_changes, and
+// updated_at is set to changedAt. synced_at stays the time the transaction was
+// first stored, so that a changed one isn't taken for a new one.
+// Reports whether anything changed.
+func (db *DB) applyTxnChanges(tx *sql.Tx, row txnRow, columns []string, values []interface{}, changedAt int64) (bool, error) {
+	stored := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range stored {
+		dest[i] = &stored[i]
+	}
+	selectStmt, err := db.txStmt(tx, txnSelectSQL(row.table, row.where, columns))
+	if err != nil {
+		return false, err
+	}
+	err = selectStmt.QueryRow(row.whereArgs...).Scan(dest...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query transaction %s: %w", row.id, err)
+	}
+
+	var sets []string
+	var args []interface{}
+	for i, col := range columns {
+		oldValue, newValue := formatTxnValue(stored[i]), formatTxnValue(values[i])
+		if oldValue == newValue {
+			continue
+		}
+		sets = append(sets, col+" = ?")
+		args = append(args, values[i])
+		insertStmt, err := db.txStmt(tx, insertTxnChangeSQL)
+		if err != nil {
+			return false, err
+		}
+		_, err = insertStmt.Exec(row.source, row.productID, row.id, nullString(row.operationDate), col, oldValue, newValue, changedAt)
+		if err != nil {
+			return false, fmt.Errorf("failed to record change of transaction %s: %w", row.id, err)
+		}
+	}
+	if len(sets) == 0 {
+		return false, nil
+	}
+
+	args = append(append(args, changedAt), row.whereArgs...)
+	update := fmt.Sprintf("UPDATE %s SET %s, updated_at = ? WHERE %s", row.table, strings.Join(sets, ", "), row.where)
+	if _, err := tx.Exec(update, args...); err != nil {
+		return false, fmt.Errorf("failed to update transaction %s: %w", row.id, err)
+	}
+	return true, nil
+}
+
+// formatTxnValue renders a column value for comparison and change history
+func formatTxnValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// GetTransactionChanges returns recorded transaction changes, newest first.
+// Empty productID or txnID match any product or transaction; limit 0 means no limit.
+func (db *DB) GetTransactionChanges(productID, txnID string, limit int) ([]TransactionChange, error) {
+	query := `
+		SELECT id, source, product_id, txn_id, operation_date, field, old_value, new_value, changed_at
+		FROM transaction_changes WHERE 1 = 1`
+	var args []interface{}
+	if productID != "" {
+		query += " AND product_id = ?"
+		args = append(args, productID)
+	}
+	if txnID != "" {
+		query += " AND txn_id = ?"
+		args = append(args, txnID)
+	}
+	query += " ORDER BY id DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transaction changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []TransactionChange
+	for rows.Next() {
+		var c TransactionChange
+		var operationDate, oldValue, newValue sql.NullString
+		var changedAt int64
+		if err := rows.Scan(&c.ID, &c.Source, &c.ProductID, &c.TxnID, &operationDate,
+			&c.Field, &oldValue, &newValue, &changedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction change: %w", err)
+		}
+		c.OperationDate = operationDate.String
+		c.OldValue = oldValue.String
+		c.NewValue = newValue.String
+		c.ChangedAt = time.Unix(changedAt, 0)
+		changes = append(changes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transaction changes: %w", err)
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+// UpsertUtilityPayments stores utility payments, updating ones already stored
+// (their status may change). Returns the number of newly inserted payments.
+func (db *DB) UpsertUtilityPayments(payments []client.UtilityPayment) (int, error) {
+	syncedAt := time.Now().Unix()
+	inserted := 0
+
+	err := db.WithTransaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT INTO utility_payments (
+				id, service_type, provider, subscriber_number, amount_currency, amount_value,
+				debit_account_id, payment_date, status, synced_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				service_type = excluded.service_type,
+				provider = excluded.provider,
+				subscriber_number = excluded.subscriber_number,
+				amount_currency = excluded.amount_currency,
+				amount_value = excluded.amount_value,
+				debit_account_id = excluded.debit_account_id,
+				payment_date = excluded.payment_date,
+				status = excluded.status,
+				synced_at = excluded.synced_at
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, p := range payments {
+			var exists bool
+			if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM utility_payments WHERE id = ?)", p.ID).Scan(&exists); err != nil {
+				return fmt.Errorf("failed to check utility payment %s: %w", p.ID, err)
+			}
+			_, err := stmt.Exec(
+				p.ID, p.ServiceType, p.Provider, nullString(p.SubscriberNumber), p.Amount.Currency, p.Amount.Amount,
+				nullString(p.DebitAccountID), p.PaymentDate, p.Status, syncedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to store utility payment %s: %w", p.ID, err)
+			}
+			if !exists {
+				inserted++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
+
+// GetUtilityPayments retrieves stored utility payments, newest first.
+// Empty serviceType or provider match any; provider matching is case-insensitive.
+func (db *DB) GetUtilityPayments(serviceType, provider string) ([]client.UtilityPayment, error) {
+	query := `
+		SELECT id, service_type, provider, subscriber_number, amount_currency, amount_value,
+			   debit_account_id, payment_date, status
+		FROM utility_payments WHERE 1 = 1`
+	var args []interface{}
+	if serviceType != "" {
+		query += " AND service_type = ?"
+		args = append(args, serviceType)
+	}
+	if provider != "" {
+		query += " AND provider = ? COLLATE NOCASE"
+		args = append(args, provider)
+	}
+	query += " ORDER BY payment_date DESC, id DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query utility payments: %w", err)
+	}
+	defer rows.Close()
+
+	var payments []client.UtilityPayment
+	for rows.Next() {
+		var p client.UtilityPayment
+		var serviceType, provider, subscriber, currency, debitAccount, paymentDate, status sql.NullString
+		var amount sql.NullFloat64
+		if err := rows.Scan(&p.ID, &serviceType, &provider, &subscriber, &currency, &amount,
+			&debitAccount, &paymentDate, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan utility payment: %w", err)
+		}
+		p.ServiceType = serviceType.String
+		p.Provider = provider.String
+		p.SubscriberNumber = subscriber.String
+		p.Amount.Currency = currency.String
+		p.Amount.Amount = amount.Float64
+		p.DebitAccountID = debitAccount.String
+		p.PaymentDate = paymentDate.String
+		p.Status = status.String
+		payments = append(payments, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating utility payments: %w", err)
+	}
+
+	return payments, nil
+}
+
+// GetExistingUtilityPaymentIDs returns the IDs of all stored utility payments
+func (db *DB) GetExistingUtilityPaymentIDs() (map[string]bool, error) {
+	rows, err := db.Query("SELECT id FROM utility_payments")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query utility payment IDs: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan utility payment ID: %w", err)
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
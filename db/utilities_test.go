@@ -0,0 +1,61 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/client"
+)
+
+func TestUpsertUtilityPayments(t *testing.T) {
+	db, err := OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	payment := func(id, serviceType, provider, date, status string, amount float64) client.UtilityPayment {
+		return client.UtilityPayment{
+			ID: id, ServiceType: serviceType, Provider: provider, SubscriberNumber: "123",
+			Amount: client.Amount{Currency: "AMD", Amount: amount}, PaymentDate: date, Status: status,
+		}
+	}
+	inserted, err := db.UpsertUtilityPayments([]client.UtilityPayment{
+		payment("u1", client.UtilityElectricity, "ENA", "2024-01-05", "PENDING", 12000),
+		payment("u2", client.UtilityGas, "Gazprom Armenia", "2024-01-10", "DONE", 8500),
+	})
+	if err != nil {
+		t.Fatalf("UpsertUtilityPayments failed: %v", err)
+	}
+	if inserted != 2 {
+		t.Errorf("expected 2 inserted, got %d", inserted)
+	}
+
+	// Seen again with a new status, plus a new payment
+	inserted, err = db.UpsertUtilityPayments([]client.UtilityPayment{
+		payment("u1", client.UtilityElectricity, "ENA", "2024-01-05", "DONE", 12000),
+		payment("u3", client.UtilityElectricity, "ENA", "2024-02-05", "DONE", 14000),
+	})
+	if err != nil {
+		t.Fatalf("UpsertUtilityPayments failed: %v", err)
+	}
+	if inserted != 1 {
+		t.Errorf("expected 1 inserted, got %d", inserted)
+	}
+
+	all, err := db.GetUtilityPayments("", "")
+	if err != nil {
+		t.Fatalf("GetUtilityPayments failed: %v", err)
+	}
+	if len(all) != 3 || all[0].ID != "u3" || all[2].ID != "u1" || all[2].Status != "DONE" {
+		t.Errorf("unexpected payments: %+v", all)
+	}
+
+	gas, _ := db.GetUtilityPayments(client.UtilityGas, "")
+	if len(gas) != 1 || gas[0].Provider != "Gazprom Armenia" || gas[0].Amount.Amount != 8500 {
+		t.Errorf("unexpected gas payments: %+v", gas)
+	}
+	ena, _ := db.GetUtilityPayments("", "ena")
+	if len(ena) != 2 {
+		t.Errorf("expected 2 ENA payments, got %d", len(ena))
+	}
+}
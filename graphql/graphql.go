@@ -0,0 +1,447 @@
+// Package graphql executes GraphQL queries against a schema of Go resolvers.
+//
+// It implements the query subset of the language: nested selections, aliases,
+// arguments, variables, fragments and the @include/@skip directives.
+// Mutations, subscriptions and introspection (other than __typename) are not supported.
+// Argument values are not checked against declared types; resolvers coerce them
+// with the typed accessors of Params.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Object is an object type of the schema
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Field is a field of an object type
+type Field struct {
+	Type    *Object  // type of the value (or list elements); nil for scalars
+	Args    []string // accepted argument names
+	Resolve func(p Params) (interface{}, error)
+}
+
+// Params are passed to resolvers
+type Params struct {
+	Context context.Context
+	Source  interface{} // value of the parent object
+	Args    map[string]interface{}
+}
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Error is an error in a response
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Response is the result of executing a request
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// Execute runs a query against the root query object. Request errors (syntax,
+// unknown fields or variables) yield a response without data; resolver errors
+// null the failing field and are reported alongside the data.
+func Execute(ctx context.Context, query *Object, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return errorResponse(err)
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return errorResponse(err)
+	}
+	vars, err := coerceVariables(op, req.Variables)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	e := &executor{ctx: ctx, doc: doc, op: op, vars: vars}
+	fields, err := e.collectFields(query, op.selection)
+	if err == nil {
+		err = e.validate(query, fields)
+	}
+	if err != nil {
+		return errorResponse(err)
+	}
+	data := e.executeFields(query, nil, fields, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func errorResponse(err error) *Response {
+	return &Response{Errors: []Error{{Message: err.Error()}}}
+}
+
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, fmt.Errorf("operationName is required for documents with several operations")
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// coerceVariables applies defaults and checks that non-null variables are provided
+func coerceVariables(op *operation, provided map[string]interface{}) (map[string]interface{}, error) {
+	vars := make(map[string]interface{}, len(op.variables))
+	for _, def := range op.variables {
+		v, ok := provided[def.name]
+		if !ok || v == nil {
+			if def.defValue != nil {
+				v, ok = def.defValue, true
+			} else if def.nonNull {
+				return nil, fmt.Errorf("variable $%s is required", def.name)
+			}
+		}
+		if ok {
+			vars[def.name] = v
+		}
+	}
+	return vars, nil
+}
+
+type executor struct {
+	ctx    context.Context
+	doc    *document
+	op     *operation
+	vars   map[string]interface{}
+	errors []Error
+}
+
+// collectedField is a field selection with sub-selections of all
+// selections sharing its response key merged
+type collectedField struct {
+	key      string
+	sel      selection
+	children []selection
+}
+
+// collectFields flattens fragments and applies directives, in query order
+func (e *executor) collectFields(obj *Object, sels []selection) ([]*collectedField, error) {
+	var fields []*collectedField
+	byKey := make(map[string]*collectedField)
+	visited := make(map[string]bool)
+
+	var collect func(sels []selection) error
+	collect = func(sels []selection) error {
+		for _, sel := range sels {
+			include, err := e.included(sel)
+			if err != nil {
+				return err
+			}
+			if !include {
+				continue
+			}
+
+			switch {
+			case sel.spread != "":
+				frag, ok := e.doc.fragments[sel.spread]
+				if !ok {
+					return fmt.Errorf("unknown fragment %q", sel.spread)
+				}
+				if visited[sel.spread] || frag.typeCond != obj.Name {
+					continue
+				}
+				visited[sel.spread] = true
+				if err := collect(frag.selection); err != nil {
+					return err
+				}
+			case sel.inline:
+				if sel.typeCond != "" && sel.typeCond != obj.Name {
+					continue
+				}
+				if err := collect(sel.children); err != nil {
+					return err
+				}
+			default:
+				key := sel.responseKey()
+				if f, ok := byKey[key]; ok {
+					if f.sel.name != sel.name {
+						return fmt.Errorf("fields %q and %q conflict in %s", f.sel.name, sel.name, key)
+					}
+					f.children = append(f.children, sel.children...)
+					continue
+				}
+				f := &collectedField{key: key, sel: sel, children: sel.children}
+				byKey[key] = f
+				fields = append(fields, f)
+			}
+		}
+		return nil
+	}
+	return fields, collect(sels)
+}
+
+// included evaluates @include and @skip
+func (e *executor) included(sel selection) (bool, error) {
+	for name, want := range map[string]bool{"include": true, "skip": false} {
+		args, ok := sel.directives[name]
+		if !ok {
+			continue
+		}
+		v, err := e.resolveValue(args["if"])
+		if err != nil {
+			return false, err
+		}
+		b, ok := v.(bool)
+		if !ok {
+			return false, fmt.Errorf("@%s requires a boolean \"if\" argument", name)
+		}
+		if b != want {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// validate checks fields and arguments against the schema before execution
+func (e *executor) validate(obj *Object, fields []*collectedField) error {
+	for _, f := range fields {
+		if f.sel.name == "__typename" {
+			continue
+		}
+		def, ok := obj.Fields[f.sel.name]
+		if !ok {
+			return fmt.Errorf("cannot query field %q on type %s", f.sel.name, obj.Name)
+		}
+		for arg, v := range f.sel.arguments {
+			if !contains(def.Args, arg) {
+				return fmt.Errorf("unknown argument %q on field %s.%s", arg, obj.Name, f.sel.name)
+			}
+			if _, err := e.resolveValue(v); err != nil {
+				return err
+			}
+		}
+		if def.Type == nil {
+			if len(f.children) > 0 {
+				return fmt.Errorf("field %s.%s is a scalar and cannot have a selection", obj.Name, f.sel.name)
+			}
+			continue
+		}
+		if len(f.children) == 0 {
+			return fmt.Errorf("field %s.%s of type %s must have a selection", obj.Name, f.sel.name, def.Type.Name)
+		}
+		sub, err := e.collectFields(def.Type, f.children)
+		if err != nil {
+			return err
+		}
+		if err := e.validate(def.Type, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *executor) executeFields(obj *Object, source interface{}, fields []*collectedField, path []interface{}) *orderedMap {
+	result := &orderedMap{}
+	for _, f := range fields {
+		fieldPath := append(append([]interface{}{}, path...), f.key)
+		if f.sel.name == "__typename" {
+			result.set(f.key, obj.Name)
+			continue
+		}
+		def := obj.Fields[f.sel.name]
+
+		args := make(map[string]interface{}, len(f.sel.arguments))
+		var err error
+		for name, v := range f.sel.arguments {
+			if args[name], err = e.resolveValue(v); err != nil {
+				break
+			}
+		}
+		var value interface{}
+		if err == nil {
+			value, err = def.Resolve(Params{Context: e.ctx, Source: source, Args: args})
+		}
+		if err != nil {
+			e.errors = append(e.errors, Error{Message: err.Error(), Path: fieldPath})
+			result.set(f.key, nil)
+			continue
+		}
+		result.set(f.key, e.complete(def.Type, value, f.children, fieldPath))
+	}
+	return result
+}
+
+// complete applies the sub-selection to an object value or to each element of a list
+func (e *executor) complete(typ *Object, value interface{}, children []selection, path []interface{}) interface{} {
+	if typ == nil || value == nil {
+		return value
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	// collectFields can't fail here: the selection was validated
+	fields, _ := e.collectFields(typ, children)
+	if rv.Kind() != reflect.Slice {
+		return e.executeFields(typ, value, fields, path)
+	}
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = e.executeFields(typ, rv.Index(i).Interface(), fields, append(append([]interface{}{}, path...), i))
+	}
+	return list
+}
+
+// resolveValue substitutes variables and converts literals to plain Go values
+func (e *executor) resolveValue(v value) (interface{}, error) {
+	switch v := v.(type) {
+	case variableRef:
+		if !e.declared(string(v)) {
+			return nil, fmt.Errorf("variable $%s is not defined", v)
+		}
+		return e.vars[string(v)], nil
+	case enumValue:
+		return string(v), nil
+	case []value:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if list[i], err = e.resolveValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case map[string]value:
+		obj := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			var err error
+			if obj[k], err = e.resolveValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	}
+	return v, nil
+}
+
+// declared reports whether the operation declares the variable;
+// omitted nullable variables resolve to null
+func (e *executor) declared(name string) bool {
+	for _, def := range e.op.variables {
+		if def.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a string argument, or an empty string if it is absent or null
+func (p Params) String(name string) (string, error) {
+	switch v := p.Args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("argument %q must be a string", name)
+}
+
+// Float returns a numeric argument, or def if it is absent or null
+func (p Params) Float(name string, def float64) (float64, error) {
+	switch v := p.Args[name].(type) {
+	case nil:
+		return def, nil
+	case float64:
+		return v, nil
+	case json.Number:
+		return v.Float64()
+	}
+	return 0, fmt.Errorf("argument %q must be a number", name)
+}
+
+// Int returns an integer argument, or def if it is absent or null
+func (p Params) Int(name string, def int) (int, error) {
+	switch v := p.Args[name].(type) {
+	case nil:
+		return def, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	case json.Number:
+		if n, err := strconv.Atoi(v.String()); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q must be an integer", name)
+}
+
+// Bool returns a boolean argument, or def if it is absent or null
+func (p Params) Bool(name string, def bool) (bool, error) {
+	switch v := p.Args[name].(type) {
+	case nil:
+		return def, nil
+	case bool:
+		return v, nil
+	}
+	return false, fmt.Errorf("argument %q must be a boolean", name)
+}
+
+// orderedMap is a JSON object that keeps fields in selection order
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if m.values == nil {
+		m.values = make(map[string]interface{})
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		v, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
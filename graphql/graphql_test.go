@@ -0,0 +1,141 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testAuthor struct {
+	Name  string
+	Books []string
+}
+
+func testSchema() *Object {
+	book := &Object{Name: "Book", Fields: map[string]*Field{
+		"title": {Resolve: func(p Params) (interface{}, error) { return p.Source.(string), nil }},
+		"failing": {Resolve: func(p Params) (interface{}, error) {
+			return nil, fmt.Errorf("boom")
+		}},
+	}}
+	author := &Object{Name: "Author", Fields: map[string]*Field{
+		"name": {Resolve: func(p Params) (interface{}, error) { return p.Source.(testAuthor).Name, nil }},
+		"books": {Type: book, Args: []string{"limit"}, Resolve: func(p Params) (interface{}, error) {
+			books := p.Source.(testAuthor).Books
+			limit, err := p.Int("limit", len(books))
+			if err != nil {
+				return nil, err
+			}
+			return books[:min(limit, len(books))], nil
+		}},
+	}}
+	authors := []testAuthor{
+		{Name: "Saroyan", Books: []string{"My Name Is Aram", "The Human Comedy"}},
+		{Name: "Tumanyan", Books: []string{"Anush"}},
+	}
+	return &Object{Name: "Query", Fields: map[string]*Field{
+		"authors": {Type: author, Resolve: func(p Params) (interface{}, error) { return authors, nil }},
+		"author": {Type: author, Args: []string{"name"}, Resolve: func(p Params) (interface{}, error) {
+			name, err := p.String("name")
+			if err != nil {
+				return nil, err
+			}
+			for _, a := range authors {
+				if a.Name == name {
+					return a, nil
+				}
+			}
+			return nil, nil
+		}},
+	}}
+}
+
+func execute(t *testing.T, req Request) (string, []Error) {
+	t.Helper()
+	resp := Execute(context.Background(), testSchema(), req)
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatalf("failed to marshal data: %v", err)
+	}
+	return string(data), resp.Errors
+}
+
+func TestExecute(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		req       Request
+		expected  string
+		errorPath string
+	}{
+		{
+			name:     "nested lists with aliases and arguments",
+			req:      Request{Query: `{ authors { name first: books(limit: 1) { title } } }`},
+			expected: `{"authors":[{"name":"Saroyan","first":[{"title":"My Name Is Aram"}]},{"name":"Tumanyan","first":[{"title":"Anush"}]}]}`,
+		},
+		{
+			name: "variables, fragments and directives",
+			req: Request{
+				Query: `query Q($name: String!, $withBooks: Boolean = false) {
+					author(name: $name) { ...A books @include(if: $withBooks) { title } }
+				}
+				fragment A on Author { __typename name }`,
+				Variables: map[string]interface{}{"name": "Tumanyan", "withBooks": true},
+			},
+			expected: `{"author":{"__typename":"Author","name":"Tumanyan","books":[{"title":"Anush"}]}}`,
+		},
+		{
+			name:     "null object",
+			req:      Request{Query: `{ author(name: "nobody") { name } }`},
+			expected: `{"author":null}`,
+		},
+		{
+			name:      "resolver error nulls the field",
+			req:       Request{Query: `{ author(name: "Tumanyan") { books { failing } } }`},
+			expected:  `{"author":{"books":[{"failing":null}]}}`,
+			errorPath: `["author","books",0,"failing"]`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, errs := execute(t, tc.req)
+			if data != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, data)
+			}
+			if tc.errorPath == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 {
+				t.Fatalf("expected one error, got %v", errs)
+			}
+			if path, _ := json.Marshal(errs[0].Path); string(path) != tc.errorPath {
+				t.Errorf("expected error path %s, got %s", tc.errorPath, path)
+			}
+		})
+	}
+}
+
+func TestExecute_RequestErrors(t *testing.T) {
+	for query, expected := range map[string]string{
+		`{ authors { name `:                                "unexpected end",
+		`{ authors { age } }`:                              `cannot query field "age"`,
+		`{ authors }`:                                      "must have a selection",
+		`{ authors { name { x } } }`:                       "cannot have a selection",
+		`{ author(id: 1) { name } }`:                       `unknown argument "id"`,
+		`query($n: String!) { author(name: $n) { name } }`: "variable $n is required",
+		`{ author(name: $n) { name } }`:                    "variable $n is not defined",
+		`mutation { x }`:                                   "not supported",
+		`{ ...A } fragment A on Query { ...A }`:            "spreads itself",
+	} {
+		data, errs := execute(t, Request{Query: query})
+		if data != "null" {
+			t.Errorf("%s: expected no data, got %s", query, data)
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0].Message, expected) {
+			t.Errorf("%s: expected error containing %q, got %v", query, expected, errs)
+		}
+	}
+}
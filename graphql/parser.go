@@ -0,0 +1,596 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed query document
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	name      string
+	variables []variableDef
+	selection []selection
+}
+
+type variableDef struct {
+	name     string
+	nonNull  bool
+	defValue value // nil if no default
+}
+
+type fragment struct {
+	typeCond  string
+	selection []selection
+}
+
+// selection is a field, a fragment spread or an inline fragment
+type selection struct {
+	// field
+	alias     string
+	name      string
+	arguments map[string]value
+	children  []selection
+
+	// fragment spread (spread != "") or inline fragment (inline == true)
+	spread   string
+	inline   bool
+	typeCond string
+
+	directives map[string]map[string]value
+}
+
+// responseKey is the name of the field in the result
+func (s selection) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+// value is a literal or a variable reference in a query
+type value interface{}
+
+// variableRef refers to an operation variable
+type variableRef string
+
+// enumValue is an unquoted name used as a value
+type enumValue string
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+// parse parses a query document
+func parse(src string) (*document, error) {
+	p := &parser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.tok.kind == tokName && p.tok.text == "fragment":
+			name, frag, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.fragments[name]; dup {
+				return nil, fmt.Errorf("duplicate fragment %q", name)
+			}
+			doc.fragments[name] = frag
+		case p.tok.kind == tokName && (p.tok.text == "mutation" || p.tok.text == "subscription"):
+			return nil, fmt.Errorf("%s operations are not supported", p.tok.text)
+		case p.tok.kind == tokName && p.tok.text == "query", p.is("{"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document contains no operations")
+	}
+	if err := doc.checkFragmentCycles(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// checkFragmentCycles rejects fragments that spread themselves, directly or indirectly
+func (d *document) checkFragmentCycles() error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var visit func(sels []selection) error
+	visit = func(sels []selection) error {
+		for _, sel := range sels {
+			if sel.spread == "" {
+				if err := visit(sel.children); err != nil {
+					return err
+				}
+				continue
+			}
+			frag, ok := d.fragments[sel.spread]
+			if !ok || state[sel.spread] == done {
+				continue
+			}
+			if state[sel.spread] == visiting {
+				return fmt.Errorf("fragment %q spreads itself", sel.spread)
+			}
+			state[sel.spread] = visiting
+			if err := visit(frag.selection); err != nil {
+				return err
+			}
+			state[sel.spread] = done
+		}
+		return nil
+	}
+	for _, frag := range d.fragments {
+		if err := visit(frag.selection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	op := &operation{}
+	if p.is("{") {
+		sel, err := p.parseSelectionSet()
+		op.selection = sel
+		return op, err
+	}
+
+	// "query"
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokName {
+		op.name = p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if p.is("(") {
+		defs, err := p.parseVariableDefs()
+		if err != nil {
+			return nil, err
+		}
+		op.variables = defs
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	sel, err := p.parseSelectionSet()
+	op.selection = sel
+	return op, err
+}
+
+func (p *parser) parseVariableDefs() ([]variableDef, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var defs []variableDef
+	for !p.is(")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		nonNull, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		def := variableDef{name: name, nonNull: nonNull}
+		if p.is("=") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if def.defValue, err = p.parseValue(true); err != nil {
+				return nil, err
+			}
+		}
+		defs = append(defs, def)
+	}
+	return defs, p.next()
+}
+
+// parseType skips a type reference, returning whether it is non-null.
+// Values are coerced by the resolvers, so type names are not checked.
+func (p *parser) parseType() (bool, error) {
+	if p.is("[") {
+		if err := p.next(); err != nil {
+			return false, err
+		}
+		if _, err := p.parseType(); err != nil {
+			return false, err
+		}
+		if err := p.expect("]"); err != nil {
+			return false, err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return false, err
+	}
+	if p.is("!") {
+		return true, p.next()
+	}
+	return false, nil
+}
+
+func (p *parser) parseFragment() (string, *fragment, error) {
+	// "fragment"
+	if err := p.next(); err != nil {
+		return "", nil, err
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return "", nil, err
+	}
+	if p.tok.kind != tokName || p.tok.text != "on" {
+		return "", nil, p.unexpected()
+	}
+	if err := p.next(); err != nil {
+		return "", nil, err
+	}
+	typeCond, err := p.expectName()
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return "", nil, err
+	}
+	sel, err := p.parseSelectionSet()
+	return name, &fragment{typeCond: typeCond, selection: sel}, err
+}
+
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []selection
+	for !p.is("}") {
+		sel, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, fmt.Errorf("empty selection set at position %d", p.tok.pos)
+	}
+	return sels, p.next()
+}
+
+func (p *parser) parseSelection() (selection, error) {
+	var sel selection
+	var err error
+
+	if p.is("...") {
+		if err := p.next(); err != nil {
+			return sel, err
+		}
+		if p.tok.kind == tokName && p.tok.text != "on" {
+			sel.spread = p.tok.text
+			if err := p.next(); err != nil {
+				return sel, err
+			}
+			sel.directives, err = p.parseDirectives()
+			return sel, err
+		}
+		sel.inline = true
+		if p.tok.kind == tokName {
+			// "on"
+			if err := p.next(); err != nil {
+				return sel, err
+			}
+			if sel.typeCond, err = p.expectName(); err != nil {
+				return sel, err
+			}
+		}
+		if sel.directives, err = p.parseDirectives(); err != nil {
+			return sel, err
+		}
+		sel.children, err = p.parseSelectionSet()
+		return sel, err
+	}
+
+	if sel.name, err = p.expectName(); err != nil {
+		return sel, err
+	}
+	if p.is(":") {
+		if err := p.next(); err != nil {
+			return sel, err
+		}
+		sel.alias = sel.name
+		if sel.name, err = p.expectName(); err != nil {
+			return sel, err
+		}
+	}
+	if p.is("(") {
+		if sel.arguments, err = p.parseArguments(); err != nil {
+			return sel, err
+		}
+	}
+	if sel.directives, err = p.parseDirectives(); err != nil {
+		return sel, err
+	}
+	if p.is("{") {
+		sel.children, err = p.parseSelectionSet()
+	}
+	return sel, err
+}
+
+func (p *parser) parseArguments() (map[string]value, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	args := make(map[string]value)
+	for !p.is(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.parseValue(false); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.next()
+}
+
+func (p *parser) parseDirectives() (map[string]map[string]value, error) {
+	var directives map[string]map[string]value
+	for p.is("@") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		args := map[string]value{}
+		if p.is("(") {
+			if args, err = p.parseArguments(); err != nil {
+				return nil, err
+			}
+		}
+		if directives == nil {
+			directives = make(map[string]map[string]value)
+		}
+		directives[name] = args
+	}
+	return directives, nil
+}
+
+// parseValue parses a value literal; constant values (defaults) may not contain variables
+func (p *parser) parseValue(constant bool) (value, error) {
+	tok := p.tok
+	switch {
+	case p.is("$") && !constant:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		return variableRef(name), err
+	case p.is("["):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		list := []value{}
+		for !p.is("]") {
+			v, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.next()
+	case p.is("{"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		obj := map[string]value{}
+		for !p.is("}") {
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.parseValue(constant); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.next()
+	case tok.kind == tokInt, tok.kind == tokFloat:
+		return json.Number(tok.text), p.next()
+	case tok.kind == tokString:
+		return tok.text, p.next()
+	case tok.kind == tokName:
+		var v value
+		switch tok.text {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			v = enumValue(tok.text)
+		}
+		return v, p.next()
+	}
+	return nil, p.unexpected()
+}
+
+func (p *parser) is(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.text == punct
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.is(punct) {
+		return p.unexpected()
+	}
+	return p.next()
+}
+
+func (p *parser) expectName() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.unexpected()
+	}
+	name := p.tok.text
+	return name, p.next()
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return fmt.Errorf("syntax error: unexpected end of query")
+	}
+	return fmt.Errorf("syntax error: unexpected %q at position %d", p.tok.text, p.tok.pos)
+}
+
+// next reads the next token, skipping whitespace, commas and comments
+func (p *parser) next() error {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+		} else if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		} else {
+			break
+		}
+	}
+
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return nil
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{kind: tokPunct, text: "...", pos: start}
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		p.pos++
+		p.tok = token{kind: tokPunct, text: string(c), pos: start}
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: tokName, text: p.src[start:p.pos], pos: start}
+	case c == '-' || isDigit(c):
+		return p.lexNumber()
+	case c == '"':
+		return p.lexString()
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		return fmt.Errorf("syntax error: unexpected character %q at position %d", r, start)
+	}
+	return nil
+}
+
+func (p *parser) lexNumber() error {
+	start := p.pos
+	kind := tokInt
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() {
+		for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+			p.pos++
+		}
+	}
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		kind = tokFloat
+		p.pos++
+		digits()
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		kind = tokFloat
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+	}
+	text := p.src[start:p.pos]
+	if !json.Valid([]byte(text)) {
+		return fmt.Errorf("syntax error: invalid number %q at position %d", text, start)
+	}
+	p.tok = token{kind: kind, text: text, pos: start}
+	return nil
+}
+
+func (p *parser) lexString() error {
+	start := p.pos
+	p.pos++ // opening quote
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '\n':
+			return fmt.Errorf("syntax error: unterminated string at position %d", start)
+		case '"':
+			p.pos++
+			// GraphQL string escapes are a subset of JSON's
+			var s string
+			if err := json.Unmarshal([]byte(p.src[start:p.pos]), &s); err != nil {
+				return fmt.Errorf("syntax error: invalid string at position %d", start)
+			}
+			p.tok = token{kind: tokString, text: s, pos: start}
+			return nil
+		}
+		p.pos++
+	}
+	return fmt.Errorf("syntax error: unterminated string at position %d", start)
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
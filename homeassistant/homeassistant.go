@@ -0,0 +1,148 @@
+// Package homeassistant publishes balances and transactions to MQTT
+// using Home Assistant discovery
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// Default topic prefixes
+const (
+	DefaultTopicPrefix     = "ameriagrab"
+	DefaultDiscoveryPrefix = "homeassistant"
+)
+
+// MessagePublisher sends MQTT messages (implemented by mqtt.Client)
+type MessagePublisher interface {
+	Publish(topic string, payload []byte, retain bool) error
+}
+
+// Publisher announces products as Home Assistant devices with a balance sensor
+// and a transaction event entity each
+type Publisher struct {
+	MQTT            MessagePublisher
+	TopicPrefix     string
+	DiscoveryPrefix string
+}
+
+// ProductState is the retained state of a product, used by the balance sensor
+type ProductState struct {
+	Name             string    `json:"name"`
+	Type             string    `json:"type"`
+	Currency         string    `json:"currency"`
+	Balance          float64   `json:"balance"`
+	AvailableBalance float64   `json:"available_balance"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TransactionEvent is the payload of a transaction event; event_type is "credit" or "debit"
+type TransactionEvent struct {
+	EventType    string    `json:"event_type"`
+	ID           string    `json:"id"`
+	Date         time.Time `json:"date"`
+	Amount       float64   `json:"amount"`
+	Currency     string    `json:"currency"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	Details      string    `json:"details,omitempty"`
+}
+
+var topicUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// objectID returns a product ID usable in topics and unique IDs
+func objectID(productID string) string {
+	return "ameriagrab_" + topicUnsafe.ReplaceAllString(productID, "_")
+}
+
+func (p *Publisher) stateTopic(productID string) string {
+	return fmt.Sprintf("%s/%s/state", p.TopicPrefix, objectID(productID))
+}
+
+func (p *Publisher) eventTopic(productID string) string {
+	return fmt.Sprintf("%s/%s/transaction", p.TopicPrefix, objectID(productID))
+}
+
+// PublishProducts publishes discovery configs and current balances (all retained)
+func (p *Publisher) PublishProducts(products []client.ProductInfo) error {
+	now := time.Now().UTC()
+	for _, product := range products {
+		id := objectID(product.ID)
+		device := map[string]interface{}{
+			"identifiers":  []string{id},
+			"name":         product.Name,
+			"manufacturer": "Ameriabank",
+			"model":        product.ProductType,
+		}
+
+		sensor := map[string]interface{}{
+			"name":                  "Balance",
+			"unique_id":             id + "_balance",
+			"state_topic":           p.stateTopic(product.ID),
+			"value_template":        "{{ value_json.available_balance }}",
+			"json_attributes_topic": p.stateTopic(product.ID),
+			"unit_of_measurement":   product.Currency,
+			"device_class":          "monetary",
+			"device":                device,
+		}
+		if err := p.publishJSON(fmt.Sprintf("%s/sensor/%s/balance/config", p.DiscoveryPrefix, id), sensor, true); err != nil {
+			return err
+		}
+
+		event := map[string]interface{}{
+			"name":        "Transaction",
+			"unique_id":   id + "_transaction",
+			"state_topic": p.eventTopic(product.ID),
+			"event_types": []string{"credit", "debit"},
+			"device":      device,
+		}
+		if err := p.publishJSON(fmt.Sprintf("%s/event/%s/transaction/config", p.DiscoveryPrefix, id), event, true); err != nil {
+			return err
+		}
+
+		state := ProductState{
+			Name:             product.Name,
+			Type:             product.ProductType,
+			Currency:         product.Currency,
+			Balance:          product.Balance,
+			AvailableBalance: product.AvailableBalance,
+			UpdatedAt:        now,
+		}
+		if err := p.publishJSON(p.stateTopic(product.ID), state, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PublishTransaction publishes a transaction event for its product (not retained)
+func (p *Publisher) PublishTransaction(e db.LedgerEntry) error {
+	event := TransactionEvent{
+		EventType:    "debit",
+		ID:           e.ID,
+		Date:         e.Date,
+		Amount:       e.Amount,
+		Currency:     e.Currency,
+		Counterparty: e.Counterparty,
+		Details:      e.Details,
+	}
+	if e.Credit {
+		event.EventType = "credit"
+	}
+	return p.publishJSON(p.eventTopic(e.ProductID), event, false)
+}
+
+func (p *Publisher) publishJSON(topic string, v interface{}, retain bool) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	if err := p.MQTT.Publish(topic, payload, retain); err != nil {
+		return fmt.Errorf("publishing to %s: %w", topic, err)
+	}
+	return nil
+}
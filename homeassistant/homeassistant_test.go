@@ -0,0 +1,56 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+type recordingPublisher struct {
+	payloads map[string]map[string]interface{}
+	retained map[string]bool
+}
+
+func (r *recordingPublisher) Publish(topic string, payload []byte, retain bool) error {
+	var v map[string]interface{}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return err
+	}
+	r.payloads[topic] = v
+	r.retained[topic] = retain
+	return nil
+}
+
+func TestPublisher(t *testing.T) {
+	rec := &recordingPublisher{payloads: map[string]map[string]interface{}{}, retained: map[string]bool{}}
+	p := &Publisher{MQTT: rec, TopicPrefix: DefaultTopicPrefix, DiscoveryPrefix: DefaultDiscoveryPrefix}
+
+	products := []client.ProductInfo{{ID: "card.1", ProductType: "CARD", Name: "Main Card", Currency: "AMD", Balance: 5000, AvailableBalance: 4500}}
+	if err := p.PublishProducts(products); err != nil {
+		t.Fatalf("PublishProducts failed: %v", err)
+	}
+
+	sensor := rec.payloads["homeassistant/sensor/ameriagrab_card_1/balance/config"]
+	if sensor == nil || sensor["state_topic"] != "ameriagrab/ameriagrab_card_1/state" || sensor["unit_of_measurement"] != "AMD" {
+		t.Errorf("unexpected sensor config %v", sensor)
+	}
+	if event := rec.payloads["homeassistant/event/ameriagrab_card_1/transaction/config"]; event == nil || event["state_topic"] != "ameriagrab/ameriagrab_card_1/transaction" {
+		t.Errorf("unexpected event config %v", event)
+	}
+	state := rec.payloads["ameriagrab/ameriagrab_card_1/state"]
+	if state == nil || state["available_balance"] != 4500.0 || !rec.retained["ameriagrab/ameriagrab_card_1/state"] {
+		t.Errorf("unexpected state %v", state)
+	}
+
+	entry := db.LedgerEntry{ProductID: "card.1", ID: "t1", Date: time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC), Amount: 2500, Currency: "AMD", Counterparty: "Electronics"}
+	if err := p.PublishTransaction(entry); err != nil {
+		t.Fatalf("PublishTransaction failed: %v", err)
+	}
+	topic := "ameriagrab/ameriagrab_card_1/transaction"
+	if ev := rec.payloads[topic]; ev["event_type"] != "debit" || ev["amount"] != 2500.0 || rec.retained[topic] {
+		t.Errorf("unexpected event %v (retained %v)", ev, rec.retained[topic])
+	}
+}
@@ -0,0 +1,127 @@
+package i18n
+
+var armenian = map[string]string{
+	// Table headers
+	"ACCOUNT":       "ՀԱՇԻՎ",
+	"ACCOUNTS":      "ՀԱՇԻՎՆԵՐ",
+	"ACCRUED":       "ՀԱՇՎԵԳՐՎԱԾ",
+	"ADDRESS":       "ՀԱՍՑԵ",
+	"AMOUNT":        "ԳՈՒՄԱՐ",
+	"BALANCE":       "ՄՆԱՑՈՐԴ",
+	"BENEFICIARY":   "ՇԱՀԱՌՈՒ",
+	"BUY":           "ԱՌՔ",
+	"CARD":          "ՔԱՐՏ",
+	"CATEGORY":      "ԿԱՏԵԳՈՐԻԱ",
+	"COUNTERPARTY":  "ԿՈՆՏՐԱԳԵՆՏ",
+	"CURRENCY":      "ԱՐԺՈՒՅԹ",
+	"CURRENT":       "ԸՆԹԱՑԻԿ",
+	"DATE":          "ԱՄՍԱԹԻՎ",
+	"DEFAULT":       "ԼՌԵԼՅԱՅՆ",
+	"DEPOSITS":      "ԱՎԱՆԴՆԵՐ",
+	"DESCRIPTION":   "ՆԿԱՐԱԳՐՈՒԹՅՈՒՆ",
+	"DETAILS":       "ՆՊԱՏԱԿ",
+	"FIRED":         "ԳՈՐԾԱՐԿՎԵԼ Է",
+	"KEY":           "ԲԱՆԱԼԻ",
+	"KIND":          "ՏԵՍԱԿ",
+	"LOANS":         "ՎԱՐԿԵՐ",
+	"MATURITY":      "ՄԱՐՄԱՆ ԺԱՄԿԵՏ",
+	"MESSAGE":       "ՀԱՂՈՐԴԱԳՐՈՒԹՅՈՒՆ",
+	"NAME":          "ԱՆՎԱՆՈՒՄ",
+	"NET":           "ԶՈՒՏ",
+	"NEXT INTEREST": "ՀԱՋՈՐԴ ՏՈԿՈՍ",
+	"NEXT PAYMENT":  "ՀԱՋՈՐԴ ՎՃԱՐՈՒՄ",
+	"NUMBER":        "ՀԱՄԱՐ",
+	"OPERATION":     "ԳՈՐԾԱՐՔ",
+	"OUTSTANDING":   "ՊԱՐՏՔԻ ՄՆԱՑՈՐԴ",
+	"PRINCIPAL":     "ՄԱՅՐ ԳՈՒՄԱՐ",
+	"PRODUCT":       "ՊՐՈԴՈՒԿՏ",
+	"PROVIDER":      "ՄԱՏԱԿԱՐԱՐ",
+	"RATE":          "ՏՈԿՈՍԱԴՐՈՒՅՔ",
+	"RULE":          "ԿԱՆՈՆ",
+	"SELL":          "ՎԱՃԱՌՔ",
+	"SERVICE":       "ԾԱՌԱՅՈՒԹՅՈՒՆ",
+	"SOURCE":        "ԱՂԲՅՈՒՐ",
+	"STATE":         "ՎԻՃԱԿ",
+	"STATUS":        "ԿԱՐԳԱՎԻՃԱԿ",
+	"SUBSCRIBER":    "ԲԱԺԱՆՈՐԴ",
+	"THRESHOLD":     "ՇԵՄ",
+	"TOTAL":         "ԸՆԴԱՄԵՆԸ",
+	"TREND":         "ԴԻՆԱՄԻԿԱ",
+	"TYPE":          "ՏԵՍԱԿ",
+	"VALUE":         "ԱՐԺԵՔ",
+	"IN %s":         "%s-ՈՎ",
+
+	// Headings and labels
+	"Pending holds":          "Սառեցված գումարներ",
+	"Loans":                  "Վարկեր",
+	"Deposits":               "Ավանդներ",
+	"SWIFT transfers":        "SWIFT փոխանցումներ",
+	"Sender bank":            "Ուղարկողի բանկ",
+	"Reference":              "Հղում",
+	"Fees":                   "Միջնորդավճարներ",
+	"Intermediary":           "Միջնորդ բանկ",
+	"Balance":                "Մնացորդ",
+	"Other":                  "Այլ",
+	"Available":              "Հասանելի",
+	"Totals":                 "Հանրագումարներ",
+	"subtotal":               "միջանկյալ գումար",
+	"debits":                 "ելքեր",
+	"credits":                "մուտքեր",
+	"today %s":               "այսօր %s",
+	"yesterday":              "երեկ",
+	"%d days ago":            "%d օր առաջ",
+	"net":                    "զուտ",
+	"Total: %d transactions": "Ընդամենը գործարքներ: %d",
+	"(more transactions available, use --page to paginate)":                "(կան այլ գործարքներ, էջերով անցնելու համար օգտագործեք --page)",
+	"(%d pending holds in other currencies are included in Other)":         "(այլ արժույթներով սառեցված գումարները ներառված են «Այլ»-ում: %d)",
+	"Missing exchange rates for %s (set with --rate), excluded from total": "%s փոխարժեքները բացակայում են (նշեք --rate-ով), չեն ներառվել ընդամենում",
+
+	// Requisites
+	"Beneficiary":           "Շահառու",
+	"Account":               "Հաշիվ",
+	"Currency":              "Արժույթ",
+	"Bank":                  "Բանկ",
+	"Bank address":          "Բանկի հասցե",
+	"Bank tax ID":           "Բանկի ՀՎՀՀ",
+	"Correspondent bank":    "Թղթակից բանկ",
+	"Correspondent SWIFT":   "Թղթակից բանկի SWIFT",
+	"Correspondent account": "Թղթակցային հաշիվ",
+
+	// Reports
+	"Statement: %s (%s)": "Քաղվածք: %s (%s)",
+	"Period:    %s - %s": "Ժամանակահատված: %s - %s",
+	"Currency:  %s":      "Արժույթ: %s",
+	"Opening balance":    "Սկզբնական մնացորդ",
+	"Closing balance":    "Վերջնական մնացորդ",
+	"Total credits":      "Ընդամենը մուտքեր",
+	"Total debits":       "Ընդամենը ելքեր",
+	"last sync":          "վերջին համաժամեցման",
+	"snapshot #%d":       "#%d պատկերի",
+	"%d transactions. Balances reconstructed from %s on %s.":      "Գործարքներ: %d: Մնացորդները վերականգնված են ըստ %s, %s դրությամբ:",
+	"Note: %d transactions in other currencies are not included.": "Նշում. այլ արժույթներով գործարքները ներառված չեն: %d",
+	"%d groups of likely duplicate charges, possible overcharge:": "Հավանական կրկնակի գանձումների խմբեր: %d, հնարավոր գերավճար:",
+	"No likely duplicate charges found.":                          "Հավանական կրկնակի գանձումներ չեն գտնվել:",
+	"Monthly digest for %s":                                       "%s ամսվա ամփոփում",
+	"Summary":                                                     "Ամփոփում",
+	"No transactions":                                             "Գործարքներ չկան",
+	"income":                                                      "եկամուտ",
+	"expenses":                                                    "ծախսեր",
+	"Income":                                                      "Եկամուտ",
+	"Expenses":                                                    "Ծախսեր",
+	"Net":                                                         "Զուտ",
+	"Top merchants":                                               "Հիմնական վաճառողներ",
+	"Categories":                                                  "Կատեգորիաներ",
+	"Balances":                                                    "Մնացորդներ",
+	"Product":                                                     "Պրոդուկտ",
+	"Opening":                                                     "Սկիզբ",
+	"Closing":                                                     "Վերջ",
+	"Change":                                                      "Փոփոխություն",
+
+	// Errors
+	"Error:":       "Սխալ:",
+	"login failed": "մուտքը ձախողվեց",
+	"the push notification wasn't confirmed in time": "push ծանուցումը ժամանակին չի հաստատվել",
+	"couldn't reach the bank":                        "չհաջողվեց կապվել բանկի հետ",
+	"local database error":                           "տեղական տվյալների բազայի սխալ",
+	"some products failed to sync":                   "որոշ պրոդուկտներ չհաջողվեց համաժամեցնել",
+}
@@ -0,0 +1,44 @@
+// Package i18n translates table headers, labels and error summaries of
+// human-readable output. Messages are looked up by their English text, so
+// untranslated messages are printed in English.
+package i18n
+
+import (
+	"sort"
+	"strings"
+)
+
+// catalogs map English messages to their translations, by language code
+var catalogs = map[string]map[string]string{
+	"ru": russian,
+	"hy": armenian,
+}
+
+// language is the language of the current locale; English if empty or not translated
+var language string
+
+// SetLocale selects the language of a locale such as ru or hy_AM (the locale
+// config setting). Languages without a catalog, and the empty locale, select English.
+func SetLocale(locale string) {
+	lang, _, _ := strings.Cut(locale, "_")
+	language = strings.ToLower(lang)
+}
+
+// T returns the translation of an English message into the selected language,
+// or the message itself if it isn't translated
+func T(msg string) string {
+	if s, ok := catalogs[language][msg]; ok {
+		return s
+	}
+	return msg
+}
+
+// Languages returns the codes of the languages output is available in
+func Languages() []string {
+	langs := []string{"en"}
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
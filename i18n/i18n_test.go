@@ -0,0 +1,49 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var verbRe = regexp.MustCompile(`%[-+ #0-9.]*[a-z%]`)
+
+func TestCatalogs(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for msg, translated := range catalog {
+			if !slices.Equal(verbRe.FindAllString(msg, -1), verbRe.FindAllString(translated, -1)) {
+				t.Errorf("%s: %q and its translation %q have different format verbs", lang, msg, translated)
+			}
+		}
+		// All languages translate the same messages
+		for other, otherCatalog := range catalogs {
+			for msg := range catalog {
+				if _, ok := otherCatalog[msg]; !ok {
+					t.Errorf("%q is translated into %s but not into %s", msg, lang, other)
+				}
+			}
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	defer SetLocale("")
+	for _, tt := range []struct {
+		locale, msg, want string
+	}{
+		{"", "AMOUNT", "AMOUNT"},
+		{"en_US", "AMOUNT", "AMOUNT"},
+		{"ru", "AMOUNT", "СУММА"},
+		{"hy_AM", "AMOUNT", "ԳՈՒՄԱՐ"},
+		{"de", "AMOUNT", "AMOUNT"},
+		{"ru", "not translated", "not translated"},
+	} {
+		SetLocale(tt.locale)
+		if got := T(tt.msg); got != tt.want {
+			t.Errorf("locale %q: T(%q) = %q, want %q", tt.locale, tt.msg, got, tt.want)
+		}
+	}
+	if got := Languages(); !slices.Equal(got, []string{"en", "hy", "ru"}) {
+		t.Errorf("unexpected languages %v", got)
+	}
+}
@@ -0,0 +1,127 @@
+package i18n
+
+var russian = map[string]string{
+	// Table headers
+	"ACCOUNT":       "СЧЁТ",
+	"ACCOUNTS":      "СЧЕТА",
+	"ACCRUED":       "НАЧИСЛЕНО",
+	"ADDRESS":       "АДРЕС",
+	"AMOUNT":        "СУММА",
+	"BALANCE":       "ОСТАТОК",
+	"BENEFICIARY":   "ПОЛУЧАТЕЛЬ",
+	"BUY":           "ПОКУПКА",
+	"CARD":          "КАРТА",
+	"CATEGORY":      "КАТЕГОРИЯ",
+	"COUNTERPARTY":  "КОНТРАГЕНТ",
+	"CURRENCY":      "ВАЛЮТА",
+	"CURRENT":       "ТЕКУЩИЙ",
+	"DATE":          "ДАТА",
+	"DEFAULT":       "ПО УМОЛЧАНИЮ",
+	"DEPOSITS":      "ДЕПОЗИТЫ",
+	"DESCRIPTION":   "ОПИСАНИЕ",
+	"DETAILS":       "НАЗНАЧЕНИЕ",
+	"FIRED":         "СРАБОТАЛО",
+	"KEY":           "КЛЮЧ",
+	"KIND":          "ВИД",
+	"LOANS":         "КРЕДИТЫ",
+	"MATURITY":      "ПОГАШЕНИЕ",
+	"MESSAGE":       "СООБЩЕНИЕ",
+	"NAME":          "НАЗВАНИЕ",
+	"NET":           "САЛЬДО",
+	"NEXT INTEREST": "СЛЕД. ПРОЦЕНТЫ",
+	"NEXT PAYMENT":  "СЛЕД. ПЛАТЁЖ",
+	"NUMBER":        "НОМЕР",
+	"OPERATION":     "ОПЕРАЦИЯ",
+	"OUTSTANDING":   "ОСТАТОК ДОЛГА",
+	"PRINCIPAL":     "СУММА КРЕДИТА",
+	"PRODUCT":       "ПРОДУКТ",
+	"PROVIDER":      "ПОСТАВЩИК",
+	"RATE":          "СТАВКА",
+	"RULE":          "ПРАВИЛО",
+	"SELL":          "ПРОДАЖА",
+	"SERVICE":       "УСЛУГА",
+	"SOURCE":        "ИСТОЧНИК",
+	"STATE":         "СОСТОЯНИЕ",
+	"STATUS":        "СТАТУС",
+	"SUBSCRIBER":    "АБОНЕНТ",
+	"THRESHOLD":     "ПОРОГ",
+	"TOTAL":         "ИТОГО",
+	"TREND":         "ДИНАМИКА",
+	"TYPE":          "ТИП",
+	"VALUE":         "ЗНАЧЕНИЕ",
+	"IN %s":         "В %s",
+
+	// Headings and labels
+	"Pending holds":          "Заблокированные суммы",
+	"Loans":                  "Кредиты",
+	"Deposits":               "Депозиты",
+	"SWIFT transfers":        "SWIFT-переводы",
+	"Sender bank":            "Банк отправителя",
+	"Reference":              "Референс",
+	"Fees":                   "Комиссии",
+	"Intermediary":           "Банк-посредник",
+	"Balance":                "Остаток",
+	"Other":                  "Прочее",
+	"Available":              "Доступно",
+	"Totals":                 "Итоги",
+	"subtotal":               "подытог",
+	"debits":                 "списания",
+	"credits":                "зачисления",
+	"today %s":               "сегодня %s",
+	"yesterday":              "вчера",
+	"%d days ago":            "%d дн. назад",
+	"net":                    "сальдо",
+	"Total: %d transactions": "Всего транзакций: %d",
+	"(more transactions available, use --page to paginate)":                "(есть ещё транзакции, листайте страницы с помощью --page)",
+	"(%d pending holds in other currencies are included in Other)":         "(заблокированные суммы в других валютах включены в «Прочее»: %d)",
+	"Missing exchange rates for %s (set with --rate), excluded from total": "Нет курсов для %s (задайте через --rate), не учтены в итоге",
+
+	// Requisites
+	"Beneficiary":           "Получатель",
+	"Account":               "Счёт",
+	"Currency":              "Валюта",
+	"Bank":                  "Банк",
+	"Bank address":          "Адрес банка",
+	"Bank tax ID":           "ИНН банка",
+	"Correspondent bank":    "Банк-корреспондент",
+	"Correspondent SWIFT":   "SWIFT банка-корреспондента",
+	"Correspondent account": "Корреспондентский счёт",
+
+	// Reports
+	"Statement: %s (%s)": "Выписка: %s (%s)",
+	"Period:    %s - %s": "Период:  %s - %s",
+	"Currency:  %s":      "Валюта:  %s",
+	"Opening balance":    "Входящий остаток",
+	"Closing balance":    "Исходящий остаток",
+	"Total credits":      "Всего зачислений",
+	"Total debits":       "Всего списаний",
+	"last sync":          "последней синхронизации",
+	"snapshot #%d":       "снимку #%d",
+	"%d transactions. Balances reconstructed from %s on %s.":      "Транзакций: %d. Остатки восстановлены по %s на %s.",
+	"Note: %d transactions in other currencies are not included.": "Примечание: транзакции в других валютах не учтены: %d.",
+	"%d groups of likely duplicate charges, possible overcharge:": "Групп вероятных двойных списаний: %d, возможная переплата:",
+	"No likely duplicate charges found.":                          "Вероятных двойных списаний не найдено.",
+	"Monthly digest for %s":                                       "Сводка за %s",
+	"Summary":                                                     "Итоги месяца",
+	"No transactions":                                             "Нет транзакций",
+	"income":                                                      "доходы",
+	"expenses":                                                    "расходы",
+	"Income":                                                      "Доходы",
+	"Expenses":                                                    "Расходы",
+	"Net":                                                         "Сальдо",
+	"Top merchants":                                               "Основные продавцы",
+	"Categories":                                                  "Категории",
+	"Balances":                                                    "Остатки",
+	"Product":                                                     "Продукт",
+	"Opening":                                                     "Начало",
+	"Closing":                                                     "Конец",
+	"Change":                                                      "Изменение",
+
+	// Errors
+	"Error:":       "Ошибка:",
+	"login failed": "ошибка входа",
+	"the push notification wasn't confirmed in time": "push-уведомление не подтверждено вовремя",
+	"couldn't reach the bank":                        "не удалось связаться с банком",
+	"local database error":                           "ошибка локальной базы данных",
+	"some products failed to sync":                   "часть продуктов не синхронизирована",
+}
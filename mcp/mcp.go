@@ -0,0 +1,162 @@
+// Package mcp implements a Model Context Protocol server over stdio,
+// exposing read-only tools over the local database to AI assistants
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ProtocolVersion is the MCP protocol revision implemented by the server
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Tool is a callable tool exposed to the client
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+	// Handler receives the raw arguments and returns a JSON-serializable result
+	Handler func(ctx context.Context, args json.RawMessage) (interface{}, error) `json:"-"`
+}
+
+// Server dispatches MCP requests to tools
+type Server struct {
+	Name    string
+	Version string
+	Tools   []Tool
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// textContent is a tool result content item
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Serve reads newline-delimited JSON-RPC messages from r and writes responses to w
+// until r is exhausted or ctx is cancelled
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		resp := s.handle(ctx, line)
+		if resp == nil {
+			continue
+		}
+		if err := enc.Encode(resp); err != nil {
+			return fmt.Errorf("failed to write response: %w", err)
+		}
+	}
+	return scanner.Err()
+}
+
+// handle processes one message. Returns nil for notifications.
+func (s *Server) handle(ctx context.Context, msg []byte) *response {
+	var req request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "parse error"}}
+	}
+	if req.ID == nil {
+		// Notifications (e.g. notifications/initialized) need no response
+		return nil
+	}
+
+	resp := &response{JSONRPC: "2.0", ID: req.ID}
+	if req.JSONRPC != "2.0" {
+		resp.Error = &rpcError{codeInvalidRequest, "invalid request"}
+		return resp
+	}
+
+	switch req.Method {
+	case "initialize":
+		resp.Result = map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": s.Name, "version": s.Version},
+		}
+	case "ping":
+		resp.Result = map[string]interface{}{}
+	case "tools/list":
+		resp.Result = map[string]interface{}{"tools": s.Tools}
+	case "tools/call":
+		resp.Result, resp.Error = s.callTool(ctx, req.Params)
+	default:
+		resp.Error = &rpcError{codeMethodNotFound, "method not found: " + req.Method}
+	}
+	return resp
+}
+
+// callTool runs a tool. Tool failures are reported in the result with isError set,
+// so the assistant can see and react to them; protocol errors use JSON-RPC errors.
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (interface{}, *rpcError) {
+	var call struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, &rpcError{codeInvalidParams, "invalid params"}
+	}
+
+	for _, tool := range s.Tools {
+		if tool.Name != call.Name {
+			continue
+		}
+		args := call.Arguments
+		if len(args) == 0 {
+			args = json.RawMessage("{}")
+		}
+		result, err := tool.Handler(ctx, args)
+		if err != nil {
+			return map[string]interface{}{
+				"content": []textContent{{Type: "text", Text: err.Error()}},
+				"isError": true,
+			}, nil
+		}
+		text, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, &rpcError{codeInvalidParams, "failed to encode result: " + err.Error()}
+		}
+		return map[string]interface{}{
+			"content": []textContent{{Type: "text", Text: string(text)}},
+		}, nil
+	}
+	return nil, &rpcError{codeInvalidParams, "unknown tool: " + call.Name}
+}
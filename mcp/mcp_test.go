@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// roundTrip sends messages to a server backed by a test database and returns decoded responses
+func roundTrip(t *testing.T, messages ...string) []map[string]interface{} {
+	t.Helper()
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.UpsertProducts([]client.ProductInfo{{ID: "card1", ProductType: "CARD", Name: "Main Card", Currency: "AMD", Balance: 5000}}); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+	txns := []client.Transaction{
+		{ID: "t1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 100}, Details: "Grocery Store", OperationDate: "2024-06-01T10:00:00Z"},
+		{ID: "t2", AccountingType: "CREDIT", Amount: client.Amount{Currency: "AMD", Amount: 900}, Details: "Salary", OperationDate: "2024-06-05T10:00:00Z"},
+	}
+	if _, err := database.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+
+	server := &Server{Name: "test", Version: "1", Tools: DatabaseTools(database)}
+	var out bytes.Buffer
+	if err := server.Serve(context.Background(), strings.NewReader(strings.Join(messages, "\n")), &out); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	var responses []map[string]interface{}
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var resp map[string]interface{}
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+// toolText extracts the text content of a tools/call response
+func toolText(t *testing.T, resp map[string]interface{}) (string, bool) {
+	t.Helper()
+	result, ok := resp["result"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("response has no result: %v", resp)
+	}
+	content := result["content"].([]interface{})
+	isError, _ := result["isError"].(bool)
+	return content[0].(map[string]interface{})["text"].(string), isError
+}
+
+func TestInitializeAndList(t *testing.T) {
+	responses := roundTrip(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"no/such/method"}`,
+	)
+	if len(responses) != 3 {
+		t.Fatalf("expected 3 responses (notification gets none), got %d", len(responses))
+	}
+
+	init := responses[0]["result"].(map[string]interface{})
+	if init["protocolVersion"] != ProtocolVersion {
+		t.Errorf("unexpected protocol version: %v", init["protocolVersion"])
+	}
+
+	tools := responses[1]["result"].(map[string]interface{})["tools"].([]interface{})
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.(map[string]interface{})["name"].(string))
+	}
+	if strings.Join(names, ",") != "list_products,query_transactions,monthly_summary" {
+		t.Errorf("unexpected tools: %v", names)
+	}
+
+	if responses[2]["error"].(map[string]interface{})["code"].(float64) != codeMethodNotFound {
+		t.Errorf("expected method not found error, got %v", responses[2])
+	}
+}
+
+func TestToolCalls(t *testing.T) {
+	responses := roundTrip(t,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"query_transactions","arguments":{"type":"debit","query":"grocery"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"monthly_summary","arguments":{"product":"Main Card"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"query_transactions","arguments":{"from":"yesterday"}}}`,
+	)
+
+	text, isError := toolText(t, responses[0])
+	var query struct {
+		Total        int              `json:"total_matching"`
+		Transactions []db.LedgerEntry `json:"transactions"`
+	}
+	if err := json.Unmarshal([]byte(text), &query); err != nil || isError {
+		t.Fatalf("unexpected query_transactions result: %s", text)
+	}
+	if query.Total != 1 || query.Transactions[0].ID != "t1" {
+		t.Errorf("expected only t1, got %+v", query)
+	}
+
+	text, _ = toolText(t, responses[1])
+	if !strings.Contains(text, `"income": 900`) || !strings.Contains(text, `"expenses": 100`) {
+		t.Errorf("unexpected monthly_summary result: %s", text)
+	}
+
+	text, isError = toolText(t, responses[2])
+	if !isError || !strings.Contains(text, "invalid from date") {
+		t.Errorf("expected tool error for bad date, got %s (isError=%v)", text, isError)
+	}
+}
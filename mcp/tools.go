@@ -0,0 +1,173 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/product"
+	"github.com/ivan4th/ameriagrab/report"
+)
+
+// maxTransactions caps query_transactions results to keep responses within assistant context
+const maxTransactions = 500
+
+// Store is what the database tools read: products and the ledger. *db.DB
+// implements it; the tools only read.
+type Store interface {
+	product.ProductStore
+	GetLedgerEntries(opts db.LedgerOptions) ([]db.LedgerEntry, error)
+}
+
+var _ Store = (*db.DB)(nil)
+
+// DatabaseTools returns the read-only tools over the local database
+func DatabaseTools(database Store) []Tool {
+	return []Tool{
+		{
+			Name:        "list_products",
+			Description: "List all bank accounts and cards with their current balances and currencies.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+			Handler: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				return database.GetProducts()
+			},
+		},
+		{
+			Name: "query_transactions",
+			Description: "Search transactions across all accounts and cards, newest first. " +
+				"Amounts are positive; 'credit' is true for incoming money.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"product": {"type": "string", "description": "Product ID or name"},
+					"from": {"type": "string", "description": "Start date YYYY-MM-DD (inclusive)"},
+					"to": {"type": "string", "description": "End date YYYY-MM-DD (inclusive)"},
+					"type": {"type": "string", "enum": ["credit", "debit"]},
+					"currency": {"type": "string", "description": "Currency code, e.g. AMD"},
+					"min_amount": {"type": "number"},
+					"max_amount": {"type": "number"},
+					"query": {"type": "string", "description": "Case-insensitive text in counterparty or details"},
+					"limit": {"type": "integer", "description": "Maximum number of results (default 50, max 500)"}
+				}
+			}`),
+			Handler: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				return queryTransactions(database, args)
+			},
+		},
+		{
+			Name:        "monthly_summary",
+			Description: "Total income and expenses per month and currency.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"product": {"type": "string", "description": "Product ID or name"},
+					"from": {"type": "string", "description": "Start date YYYY-MM-DD (inclusive)"},
+					"to": {"type": "string", "description": "End date YYYY-MM-DD (inclusive)"}
+				}
+			}`),
+			Handler: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				var params struct {
+					Product string `json:"product"`
+					From    string `json:"from"`
+					To      string `json:"to"`
+				}
+				if err := json.Unmarshal(args, &params); err != nil {
+					return nil, fmt.Errorf("invalid arguments: %w", err)
+				}
+				opts, err := ledgerOptions(database, params.Product, params.From, params.To)
+				if err != nil {
+					return nil, err
+				}
+				entries, err := database.GetLedgerEntries(opts)
+				if err != nil {
+					return nil, err
+				}
+				return report.Monthly(entries), nil
+			},
+		},
+	}
+}
+
+func queryTransactions(database Store, args json.RawMessage) (interface{}, error) {
+	var params struct {
+		Product   string  `json:"product"`
+		From      string  `json:"from"`
+		To        string  `json:"to"`
+		Type      string  `json:"type"`
+		Currency  string  `json:"currency"`
+		MinAmount float64 `json:"min_amount"`
+		MaxAmount float64 `json:"max_amount"`
+		Query     string  `json:"query"`
+		Limit     int     `json:"limit"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	opts, err := ledgerOptions(database, params.Product, params.From, params.To)
+	if err != nil {
+		return nil, err
+	}
+	filter := db.EntryFilter{
+		Type:      params.Type,
+		Currency:  params.Currency,
+		MinAmount: params.MinAmount,
+		MaxAmount: params.MaxAmount,
+		Search:    params.Query,
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	entries, err := database.GetLedgerEntries(opts)
+	if err != nil {
+		return nil, err
+	}
+	matched := filter.Apply(entries)
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > maxTransactions {
+		limit = maxTransactions
+	}
+	result := make([]db.LedgerEntry, 0, limit)
+	for i := len(matched) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, matched[i])
+	}
+
+	return map[string]interface{}{
+		"total_matching": len(matched),
+		"transactions":   result,
+	}, nil
+}
+
+// ledgerOptions resolves the product and date range arguments shared by tools
+func ledgerOptions(database Store, productID, from, to string) (db.LedgerOptions, error) {
+	var opts db.LedgerOptions
+	if productID != "" {
+		p, err := product.Resolve(database, productID)
+		if err != nil {
+			return opts, err
+		}
+		opts.ProductID = p.ID
+	}
+	if from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			return opts, fmt.Errorf("invalid from date %q (expected YYYY-MM-DD)", from)
+		}
+		opts.From = t
+	}
+	if to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			return opts, fmt.Errorf("invalid to date %q (expected YYYY-MM-DD)", to)
+		}
+		opts.To = t.AddDate(0, 0, 1)
+	}
+	return opts, nil
+}
@@ -0,0 +1,31 @@
+// Package money sums amounts exactly, in hundredths of the currency unit, so
+// that totals over thousands of transactions don't drift the way float64
+// sums do and statements reconcile to the luma
+package money
+
+import "math"
+
+// Amount is an amount of money in hundredths of the currency unit (luma for
+// AMD, cents for USD). Sums and differences of Amounts are exact.
+type Amount int64
+
+// FromFloat rounds an amount in currency units to hundredths
+func FromFloat(f float64) Amount {
+	return Amount(math.Round(f * 100))
+}
+
+// Float returns the amount in currency units
+func (a Amount) Float() float64 {
+	return float64(a) / 100
+}
+
+// Add returns a + b rounded to hundredths. Accumulating a float64 total with
+// Add keeps it equal to the exact sum.
+func Add(a, b float64) float64 {
+	return (FromFloat(a) + FromFloat(b)).Float()
+}
+
+// Sub returns a - b rounded to hundredths
+func Sub(a, b float64) float64 {
+	return (FromFloat(a) - FromFloat(b)).Float()
+}
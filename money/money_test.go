@@ -0,0 +1,30 @@
+package money
+
+import "testing"
+
+func TestSum(t *testing.T) {
+	// 0.1 + 0.2 != 0.3 in float64, and the error grows with every addition
+	var floatSum, sum float64
+	var exact Amount
+	for range 10000 {
+		floatSum += 0.1
+		sum = Add(sum, 0.1)
+		exact += FromFloat(0.1)
+	}
+	if floatSum == 1000 {
+		t.Fatal("expected the float64 sum to drift")
+	}
+	if sum != 1000 || exact.Float() != 1000 {
+		t.Errorf("expected 1000, got %v and %v", sum, exact.Float())
+	}
+
+	if got := Sub(0.3, 0.1); got != 0.2 {
+		t.Errorf("Sub(0.3, 0.1) = %v, want 0.2", got)
+	}
+	if got := Add(1000000.05, -0.01); got != 1000000.04 {
+		t.Errorf("Add(1000000.05, -0.01) = %v, want 1000000.04", got)
+	}
+	if got := FromFloat(-12.345); got != -1235 {
+		t.Errorf("FromFloat(-12.345) = %d, want -1235", got)
+	}
+}
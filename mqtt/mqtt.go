@@ -0,0 +1,250 @@
+// Package mqtt is a minimal MQTT 3.1.1 client that publishes messages with QoS 1
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Packet types
+const (
+	packetConnect    = 1
+	packetConnAck    = 2
+	packetPublish    = 3
+	packetPubAck     = 4
+	packetDisconnect = 14
+)
+
+// Options configures a connection
+type Options struct {
+	Broker   string // tcp://host:1883, mqtt://host, or ssl://, tls://, mqtts:// for TLS (default port 8883)
+	ClientID string
+	Username string
+	Password string
+	Timeout  time.Duration // per operation, default 10s
+}
+
+// Client is a connection to a broker
+type Client struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+	nextID  uint16
+}
+
+// Dial connects to the broker and performs the MQTT handshake
+func Dial(ctx context.Context, opts Options) (*Client, error) {
+	u, err := url.Parse(opts.Broker)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid broker URL %q (expected e.g. tcp://host:1883)", opts.Broker)
+	}
+	useTLS := false
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS = true
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		port := "1883"
+		if useTLS {
+			port = "8883"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if useTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname()}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to broker: %w", err)
+	}
+
+	c := &Client{conn: conn, r: bufio.NewReader(conn), timeout: timeout}
+	if err := c.connect(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) connect(opts Options) error {
+	var body []byte
+	body = appendString(body, "MQTT")
+	body = append(body, 4) // protocol level 3.1.1
+	flags := byte(0x02)    // clean session
+	if opts.Username != "" {
+		flags |= 0x80
+		if opts.Password != "" {
+			flags |= 0x40
+		}
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, 0) // keep-alive disabled: connections are short-lived
+	body = appendString(body, opts.ClientID)
+	if opts.Username != "" {
+		body = appendString(body, opts.Username)
+		if opts.Password != "" {
+			body = appendString(body, opts.Password)
+		}
+	}
+	if err := c.write(packetConnect<<4, body); err != nil {
+		return err
+	}
+
+	typ, resp, err := c.read()
+	if err != nil {
+		return err
+	}
+	if typ != packetConnAck || len(resp) != 2 {
+		return fmt.Errorf("unexpected response to connect (packet type %d)", typ)
+	}
+	if code := resp[1]; code != 0 {
+		return fmt.Errorf("broker refused connection: %s", connectError(code))
+	}
+	return nil
+}
+
+func connectError(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad username or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("return code %d", code)
+}
+
+// Publish sends a message with QoS 1 and waits for the broker's acknowledgement
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	id := c.nextID
+
+	header := byte(packetPublish<<4 | 0x02) // QoS 1
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	body = binary.BigEndian.AppendUint16(body, id)
+	body = append(body, payload...)
+	if err := c.write(header, body); err != nil {
+		return err
+	}
+
+	for {
+		typ, resp, err := c.read()
+		if err != nil {
+			return err
+		}
+		if typ == packetPubAck && len(resp) == 2 && binary.BigEndian.Uint16(resp) == id {
+			return nil
+		}
+		// Nothing else is expected on a publish-only connection; skip it
+	}
+}
+
+// Close disconnects from the broker
+func (c *Client) Close() error {
+	c.write(packetDisconnect<<4, nil)
+	return c.conn.Close()
+}
+
+func (c *Client) write(header byte, body []byte) error {
+	packet := []byte{header}
+	packet = appendLength(packet, len(body))
+	packet = append(packet, body...)
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(packet); err != nil {
+		return fmt.Errorf("failed to write to broker: %w", err)
+	}
+	return nil
+}
+
+// read reads a packet, returning its type and body
+func (c *Client) read() (byte, []byte, error) {
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	header, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, readError(err)
+	}
+	length, err := readLength(c.r)
+	if err != nil {
+		return 0, nil, readError(err)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, readError(err)
+	}
+	return header >> 4, body, nil
+}
+
+func readError(err error) error {
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("broker closed the connection")
+	}
+	return fmt.Errorf("failed to read from broker: %w", err)
+}
+
+// appendString appends a length-prefixed UTF-8 string
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// appendLength appends the variable-length remaining length of a packet
+func appendLength(b []byte, n int) []byte {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func readLength(r io.ByteReader) (int, error) {
+	n, multiplier := 0, 1
+	for i := 0; i < 4; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			return n, nil
+		}
+		multiplier *= 128
+	}
+	return 0, fmt.Errorf("malformed packet length")
+}
@@ -0,0 +1,96 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+type publishedMessage struct {
+	topic   string
+	payload string
+	retain  bool
+}
+
+// fakeBroker accepts one connection, acknowledges CONNECT and PUBLISH packets
+// and sends received messages to the returned channel
+func fakeBroker(t *testing.T, connectCode byte) (string, <-chan publishedMessage) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan publishedMessage, 10)
+	go func() {
+		defer close(messages)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			header, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			length, err := readLength(r)
+			if err != nil {
+				return
+			}
+			body := make([]byte, length)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return
+			}
+			switch header >> 4 {
+			case packetConnect:
+				conn.Write([]byte{packetConnAck << 4, 2, 0, connectCode})
+			case packetPublish:
+				topicLen := int(binary.BigEndian.Uint16(body))
+				topic := string(body[2 : 2+topicLen])
+				id := body[2+topicLen : 4+topicLen]
+				messages <- publishedMessage{topic: topic, payload: string(body[4+topicLen:]), retain: header&0x01 != 0}
+				conn.Write([]byte{packetPubAck << 4, 2, id[0], id[1]})
+			case packetDisconnect:
+				return
+			}
+		}
+	}()
+	return "tcp://" + ln.Addr().String(), messages
+}
+
+func TestPublish(t *testing.T) {
+	broker, messages := fakeBroker(t, 0)
+
+	c, err := Dial(context.Background(), Options{Broker: broker, ClientID: "test", Username: "u", Password: "p"})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	if err := c.Publish("a/b", []byte("hello"), true); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	large := make([]byte, 300) // remaining length takes two bytes
+	if err := c.Publish("a/c", large, false); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	c.Close()
+
+	if m := <-messages; m.topic != "a/b" || m.payload != "hello" || !m.retain {
+		t.Errorf("unexpected message %+v", m)
+	}
+	if m := <-messages; m.topic != "a/c" || len(m.payload) != 300 || m.retain {
+		t.Errorf("unexpected message on %s with %d bytes", m.topic, len(m.payload))
+	}
+}
+
+func TestDial_Refused(t *testing.T) {
+	broker, _ := fakeBroker(t, 4)
+	if _, err := Dial(context.Background(), Options{Broker: broker, ClientID: "test"}); err == nil || err.Error() != "broker refused connection: bad username or password" {
+		t.Errorf("expected refused connection, got %v", err)
+	}
+}
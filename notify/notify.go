@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Priority indicates how urgent a notification is
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	PriorityHigh
+)
+
+func (p Priority) String() string {
+	if p == PriorityHigh {
+		return "high"
+	}
+	return "normal"
+}
+
+// Message is a notification to be delivered through all configured sinks
+type Message struct {
+	Title    string
+	Body     string
+	HTML     string // optional HTML version of Body, used by email
+	Priority Priority
+}
+
+// Sink delivers notifications to a single destination
+type Sink interface {
+	// Name identifies the sink in error messages
+	Name() string
+	// Send delivers a message
+	Send(ctx context.Context, msg Message) error
+}
+
+// Notifier dispatches messages to multiple sinks
+type Notifier struct {
+	Sinks []Sink
+}
+
+// Enabled returns true if at least one sink is configured
+func (n *Notifier) Enabled() bool {
+	return n != nil && len(n.Sinks) > 0
+}
+
+// Notify sends the message to every sink. A failing sink doesn't prevent
+// delivery to the others; all errors are returned joined.
+func (n *Notifier) Notify(ctx context.Context, msg Message) error {
+	if n == nil {
+		return nil
+	}
+	var errs []error
+	for _, s := range n.Sinks {
+		if err := s.Send(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Settings configures the notification sinks. Sinks whose settings are empty are skipped.
+type Settings struct {
+	TelegramToken  string
+	TelegramChatID string
+	SMTPAddr       string // host:port
+	SMTPUser       string // optional, enables PLAIN auth
+	SMTPPassword   string
+	SMTPFrom       string
+	SMTPTo         []string
+	WebhookURL     string
+	Desktop        bool
+}
+
+// ApplyEnv overrides settings with environment variables that are set:
+//
+//	AMERIA_NOTIFY_TELEGRAM_TOKEN, AMERIA_NOTIFY_TELEGRAM_CHAT_ID - Telegram bot
+//	AMERIA_NOTIFY_SMTP_ADDR (host:port), AMERIA_NOTIFY_SMTP_USER, AMERIA_NOTIFY_SMTP_PASSWORD,
+//	AMERIA_NOTIFY_SMTP_FROM, AMERIA_NOTIFY_SMTP_TO (comma-separated) - email
+//	AMERIA_NOTIFY_WEBHOOK_URL - JSON webhook
+//	AMERIA_NOTIFY_DESKTOP=1 - desktop notifications (0 disables)
+func (s *Settings) ApplyEnv() {
+	for name, field := range map[string]*string{
+		"AMERIA_NOTIFY_TELEGRAM_TOKEN":   &s.TelegramToken,
+		"AMERIA_NOTIFY_TELEGRAM_CHAT_ID": &s.TelegramChatID,
+		"AMERIA_NOTIFY_SMTP_ADDR":        &s.SMTPAddr,
+		"AMERIA_NOTIFY_SMTP_USER":        &s.SMTPUser,
+		"AMERIA_NOTIFY_SMTP_PASSWORD":    &s.SMTPPassword,
+		"AMERIA_NOTIFY_SMTP_FROM":        &s.SMTPFrom,
+		"AMERIA_NOTIFY_WEBHOOK_URL":      &s.WebhookURL,
+	} {
+		if v := os.Getenv(name); v != "" {
+			*field = v
+		}
+	}
+	if to := os.Getenv("AMERIA_NOTIFY_SMTP_TO"); to != "" {
+		s.SMTPTo = SplitList(to)
+	}
+	if desktop := os.Getenv("AMERIA_NOTIFY_DESKTOP"); desktop != "" {
+		s.Desktop = desktop == "1"
+	}
+}
+
+// New builds a notifier from settings
+func New(s Settings) (*Notifier, error) {
+	n := &Notifier{}
+
+	if s.TelegramToken != "" {
+		if s.TelegramChatID == "" {
+			return nil, fmt.Errorf("telegram chat ID must be set when the bot token is set")
+		}
+		n.Sinks = append(n.Sinks, NewTelegramSink(s.TelegramToken, s.TelegramChatID))
+	}
+
+	if s.SMTPAddr != "" {
+		if s.SMTPFrom == "" || len(s.SMTPTo) == 0 {
+			return nil, fmt.Errorf("SMTP sender and recipients must be set when the SMTP address is set")
+		}
+		n.Sinks = append(n.Sinks, &SMTPSink{
+			Addr:     s.SMTPAddr,
+			Username: s.SMTPUser,
+			Password: s.SMTPPassword,
+			From:     s.SMTPFrom,
+			To:       s.SMTPTo,
+		})
+	}
+
+	if s.WebhookURL != "" {
+		n.Sinks = append(n.Sinks, NewWebhookSink(s.WebhookURL))
+	}
+
+	if s.Desktop {
+		n.Sinks = append(n.Sinks, &DesktopSink{})
+	}
+
+	return n, nil
+}
+
+// FromEnv builds a notifier from environment variables only (see Settings.ApplyEnv)
+func FromEnv() (*Notifier, error) {
+	var s Settings
+	s.ApplyEnv()
+	return New(s)
+}
+
+// SplitList splits a comma-separated list, dropping empty items
+func SplitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordingSink records messages it receives, optionally failing
+type recordingSink struct {
+	name     string
+	messages []Message
+	err      error
+}
+
+func (s *recordingSink) Name() string { return s.name }
+
+func (s *recordingSink) Send(ctx context.Context, msg Message) error {
+	s.messages = append(s.messages, msg)
+	return s.err
+}
+
+func TestNotifier_DeliversToAllSinks(t *testing.T) {
+	failing := &recordingSink{name: "failing", err: errors.New("boom")}
+	ok := &recordingSink{name: "ok"}
+	n := &Notifier{Sinks: []Sink{failing, ok}}
+
+	err := n.Notify(context.Background(), Message{Title: "t", Body: "b"})
+	if err == nil || !strings.Contains(err.Error(), "failing: boom") {
+		t.Errorf("expected error from failing sink, got %v", err)
+	}
+	if len(ok.messages) != 1 {
+		t.Errorf("expected healthy sink to receive message despite other failure, got %d", len(ok.messages))
+	}
+}
+
+func TestNotifier_Nil(t *testing.T) {
+	var n *Notifier
+	if n.Enabled() {
+		t.Error("nil notifier should not be enabled")
+	}
+	if err := n.Notify(context.Background(), Message{}); err != nil {
+		t.Errorf("nil notifier should not fail: %v", err)
+	}
+}
+
+func TestTelegramSink(t *testing.T) {
+	var gotPath string
+	var gotPayload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&gotPayload)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	s := NewTelegramSink("123:ABC", "42")
+	s.BaseURL = server.URL
+
+	if err := s.Send(context.Background(), Message{Title: "Title", Body: "Body", Priority: PriorityHigh}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if gotPath != "/bot123:ABC/sendMessage" {
+		t.Errorf("unexpected path %q", gotPath)
+	}
+	if gotPayload["chat_id"] != "42" || gotPayload["text"] != "Title\n\nBody" {
+		t.Errorf("unexpected payload %v", gotPayload)
+	}
+	if gotPayload["disable_notification"] != false {
+		t.Errorf("high priority message should not be silent, got %v", gotPayload["disable_notification"])
+	}
+}
+
+func TestWebhookSink_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("server error"))
+	}))
+	defer server.Close()
+
+	err := NewWebhookSink(server.URL).Send(context.Background(), Message{Title: "t"})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected status error, got %v", err)
+	}
+}
+
+func TestSMTPSink_BuildMessage(t *testing.T) {
+	s := &SMTPSink{From: "bot@example.com", To: []string{"a@example.com", "b@example.com"}}
+	msg := string(s.buildMessage(Message{Title: "Subject line", Body: "line1\nline2", Priority: PriorityHigh}))
+
+	for _, want := range []string{
+		"From: bot@example.com\r\n",
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: Subject line\r\n",
+		"X-Priority: 1\r\n",
+		"\r\n\r\nline1\r\nline2\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestSMTPSink_BuildMessageHTML(t *testing.T) {
+	s := &SMTPSink{From: "bot@example.com", To: []string{"a@example.com"}}
+	msg := string(s.buildMessage(Message{Title: "Дайджест", Body: "plain", HTML: "<p>html</p>"}))
+
+	for _, want := range []string{
+		"Subject: =?utf-8?q?",
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\nplain\r\n",
+		"Content-Type: text/html; charset=UTF-8\r\n\r\n<p>html</p>\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("AMERIA_NOTIFY_TELEGRAM_TOKEN", "token")
+	t.Setenv("AMERIA_NOTIFY_TELEGRAM_CHAT_ID", "1")
+	t.Setenv("AMERIA_NOTIFY_WEBHOOK_URL", "http://localhost/hook")
+	t.Setenv("AMERIA_NOTIFY_SMTP_ADDR", "")
+	t.Setenv("AMERIA_NOTIFY_DESKTOP", "")
+
+	n, err := FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv failed: %v", err)
+	}
+	if len(n.Sinks) != 2 || n.Sinks[0].Name() != "telegram" || n.Sinks[1].Name() != "webhook" {
+		t.Errorf("unexpected sinks: %v", n.Sinks)
+	}
+
+	t.Setenv("AMERIA_NOTIFY_TELEGRAM_CHAT_ID", "")
+	if _, err := FromEnv(); err == nil {
+		t.Error("expected error when Telegram chat ID is missing")
+	}
+}
+
+func TestSettingsApplyEnv(t *testing.T) {
+	t.Setenv("AMERIA_NOTIFY_WEBHOOK_URL", "http://env/hook")
+	t.Setenv("AMERIA_NOTIFY_DESKTOP", "0")
+	t.Setenv("AMERIA_NOTIFY_TELEGRAM_TOKEN", "")
+
+	s := Settings{TelegramToken: "cfg-token", TelegramChatID: "1", WebhookURL: "http://cfg/hook", Desktop: true}
+	s.ApplyEnv()
+
+	if s.TelegramToken != "cfg-token" {
+		t.Errorf("unset env variable should keep config value, got %q", s.TelegramToken)
+	}
+	if s.WebhookURL != "http://env/hook" {
+		t.Errorf("env variable should override config value, got %q", s.WebhookURL)
+	}
+	if s.Desktop {
+		t.Error("AMERIA_NOTIFY_DESKTOP=0 should disable desktop notifications")
+	}
+}
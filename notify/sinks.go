@@ -0,0 +1,188 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// TelegramAPIBaseURL is the Telegram Bot API endpoint
+const TelegramAPIBaseURL = "https://api.telegram.org"
+
+// TelegramSink sends messages via a Telegram bot
+type TelegramSink struct {
+	Token      string
+	ChatID     string
+	BaseURL    string // defaults to TelegramAPIBaseURL
+	HTTPClient *http.Client
+}
+
+// NewTelegramSink creates a Telegram sink for the given bot token and chat
+func NewTelegramSink(token, chatID string) *TelegramSink {
+	return &TelegramSink{
+		Token:      token,
+		ChatID:     chatID,
+		BaseURL:    TelegramAPIBaseURL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *TelegramSink) Name() string { return "telegram" }
+
+func (s *TelegramSink) Send(ctx context.Context, msg Message) error {
+	text := msg.Body
+	if msg.Title != "" {
+		text = msg.Title + "\n\n" + msg.Body
+	}
+	payload := map[string]interface{}{
+		"chat_id":              s.ChatID,
+		"text":                 text,
+		"disable_notification": msg.Priority != PriorityHigh,
+	}
+	return postJSON(ctx, s.HTTPClient, fmt.Sprintf("%s/bot%s/sendMessage", s.BaseURL, s.Token), payload, nil)
+}
+
+// WebhookSink POSTs messages as JSON to a URL
+type WebhookSink struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// NewWebhookSink creates a webhook sink for the given URL
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		URL:        url,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *WebhookSink) Name() string { return "webhook" }
+
+func (s *WebhookSink) Send(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"title":    msg.Title,
+		"body":     msg.Body,
+		"priority": msg.Priority.String(),
+	}
+	return postJSON(ctx, s.HTTPClient, s.URL, payload, nil)
+}
+
+// postJSON sends a JSON POST request and fails on non-2xx responses
+func postJSON(ctx context.Context, httpClient *http.Client, url string, payload interface{}, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 500))
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// SMTPSink sends messages by email
+type SMTPSink struct {
+	Addr     string // host:port
+	Username string // optional, enables PLAIN auth
+	Password string
+	From     string
+	To       []string
+}
+
+func (s *SMTPSink) Name() string { return "smtp" }
+
+func (s *SMTPSink) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host := s.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	return smtp.SendMail(s.Addr, auth, s.From, s.To, s.buildMessage(msg))
+}
+
+// buildMessage renders an RFC 5322 message: plain text, or multipart/alternative
+// with plain text and HTML parts if the message has an HTML version
+func (s *SMTPSink) buildMessage(msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if msg.Priority == PriorityHigh {
+		b.WriteString("X-Priority: 1\r\n")
+	}
+	b.WriteString("MIME-Version: 1.0\r\n")
+	text := strings.ReplaceAll(msg.Body, "\n", "\r\n") + "\r\n"
+	if msg.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(text)
+		return []byte(b.String())
+	}
+
+	var buf [12]byte
+	rand.Read(buf[:])
+	boundary := "ameriagrab-" + hex.EncodeToString(buf[:])
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n", boundary)
+	b.WriteString("\r\n")
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s", boundary, text)
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s\r\n", boundary, strings.ReplaceAll(msg.HTML, "\n", "\r\n"))
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return []byte(b.String())
+}
+
+// DesktopSink shows desktop notifications via notify-send (Linux) or osascript (macOS)
+type DesktopSink struct{}
+
+func (s *DesktopSink) Name() string { return "desktop" }
+
+func (s *DesktopSink) Send(ctx context.Context, msg Message) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", msg.Body, msg.Title)
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	case "linux", "freebsd", "openbsd":
+		urgency := "normal"
+		if msg.Priority == PriorityHigh {
+			urgency = "critical"
+		}
+		cmd = exec.CommandContext(ctx, "notify-send", "-u", urgency, msg.Title, msg.Body)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
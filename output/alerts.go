@@ -0,0 +1,114 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/i18n"
+	"github.com/ivan4th/ameriagrab/report"
+)
+
+// PrintAlertRules prints alert rules in human-readable table format
+func (o *Output) PrintAlertRules(rules []db.AlertRule) {
+	w := o.newTable(false)
+	w.header("ID\tKIND\tPRODUCT\tTHRESHOLD\tCURRENCY\tCATEGORY")
+	for _, r := range rules {
+		product := r.ProductID
+		if product == "" {
+			product = "*"
+		}
+		threshold := "-"
+		switch r.Kind {
+		case db.AlertNewCounterparty:
+		case db.AlertPaymentDue:
+			threshold = fmt.Sprintf("%.0f days", r.Threshold)
+		default:
+			threshold = o.FormatAmount(r.Threshold)
+		}
+		if r.Rollover {
+			threshold += " (rollover)"
+		}
+		currency := r.Currency
+		if currency == "" {
+			currency = "*"
+		}
+		cat := "-"
+		if r.Kind == db.AlertBudget {
+			cat = r.Category
+			if cat == "" {
+				cat = "*"
+			}
+		}
+		w.row(rowStyle{}, "%d\t%s\t%s\t%s\t%s\t%s\n", r.ID, r.Kind, product, threshold, currency, cat)
+	}
+	w.flush()
+}
+
+// PrintAlerts prints fired alerts in human-readable table format
+func (o *Output) PrintAlerts(alerts []db.Alert) {
+	w := o.newTable(false)
+	w.header("FIRED\tRULE\tKIND\tMESSAGE")
+	for _, a := range alerts {
+		w.row(rowStyle{}, "%s\t#%d\t%s\t%s\n",
+			a.FiredAt.Format("2006-01-02 15:04"), a.RuleID, a.Kind, a.Message)
+	}
+	w.flush()
+}
+
+// PrintBudgets prints each budget's months under a heading naming the
+// budget, with what's left highlighted in red when over budget. CSV output
+// is a single table with a RULE column instead.
+func (o *Output) PrintBudgets(budgets []report.Budget) {
+	if o.Format == FormatCSV {
+		w := o.newTable(false)
+		w.header("RULE\tCATEGORY\tCURRENCY\tMONTH\tBUDGET\tCARRIED\tSPENT\tLEFT")
+		for _, b := range budgets {
+			for _, m := range b.Months {
+				w.row(rowStyle{}, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", b.Rule.ID, b.Rule.Category, b.Rule.Currency,
+					m.Month.Format("2006-01"), o.FormatAmount(m.Budget), o.FormatAmount(m.Carried), o.FormatAmount(m.Spent), o.FormatAmount(m.Left()))
+			}
+		}
+		w.flush()
+		return
+	}
+
+	for i, b := range budgets {
+		if i > 0 {
+			fmt.Fprintln(o.Out)
+		}
+		o.PrintHeading(o.budgetTitle(b.Rule))
+
+		w := o.newTable(false)
+		w.header("MONTH\tBUDGET\tCARRIED\tSPENT\tLEFT")
+		var over int
+		for _, m := range b.Months {
+			left := o.FormatAmount(m.Left())
+			style := rowStyle{}
+			if m.Left() < 0 {
+				style = rowStyle{style: ansiRed, cell: left}
+				over++
+			}
+			w.row(style, "%s\t%s\t%s\t%s\t%s\n", m.Month.Format("2006-01"),
+				o.FormatAmount(m.Budget), o.FormatAmount(m.Carried), o.FormatAmount(m.Spent), left)
+		}
+		w.flush()
+
+		fmt.Fprintln(o.Out)
+		o.printText("Over budget in %d of %d months", over, len(b.Months))
+	}
+}
+
+// budgetTitle names a budget by its ID, amount and what it applies to
+func (o *Output) budgetTitle(rule db.AlertRule) string {
+	title := fmt.Sprintf("%s #%d: %s %s", i18n.T("Budget"), rule.ID, o.FormatAmount(rule.Threshold), rule.Currency)
+	if rule.Category != "" {
+		title += ", " + rule.Category
+	}
+	if rule.ProductID != "" {
+		title += ", " + rule.ProductID
+	}
+	if rule.Rollover {
+		title += ", " + i18n.T("rollover")
+	}
+	return title
+}
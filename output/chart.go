@@ -0,0 +1,143 @@
+package output
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// chartBlocks are the partial fills of a chart cell, empty first
+var chartBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// Chart renders values as a bar chart height lines tall, one column per
+// value, scaled between their minimum and maximum so that trends show even
+// when the values are far from zero. Lines are returned top first.
+func Chart(values []float64, height int) []string {
+	if len(values) == 0 || height < 1 {
+		return nil
+	}
+	steps := height * (len(chartBlocks) - 1)
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	levels := make([]int, len(values))
+	for i, v := range values {
+		// The minimum still gets a sliver, flat lines are drawn halfway
+		levels[i] = steps / 2
+		if hi > lo {
+			levels[i] = 1 + int((v-lo)/(hi-lo)*float64(steps-1)+0.5)
+		}
+	}
+	lines := make([]string, height)
+	for row := range lines {
+		base := (height - 1 - row) * (len(chartBlocks) - 1)
+		var b strings.Builder
+		for _, level := range levels {
+			b.WriteRune(chartBlocks[min(max(level-base, 0), len(chartBlocks)-1)])
+		}
+		lines[row] = b.String()
+	}
+	return lines
+}
+
+// resample returns the indexes of at most width of n values, the last of each
+// run of values sharing a column, so the latest value is always kept
+func resample(n, width int) []int {
+	if width < 1 || n <= width {
+		indexes := make([]int, n)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes
+	}
+	indexes := make([]int, width)
+	for i := range indexes {
+		indexes[i] = (i+1)*n/width - 1
+	}
+	return indexes
+}
+
+// Chart colors of WriteChartPNG
+var (
+	chartBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	chartGrid       = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+	chartFill       = color.RGBA{0xc6, 0xdb, 0xef, 0xff}
+	chartLine       = color.RGBA{0x21, 0x66, 0xac, 0xff}
+)
+
+// chartMargin is the space around the plot area of WriteChartPNG, in pixels
+const chartMargin = 10
+
+// WriteChartPNG writes values as a width×height PNG line chart, scaled
+// between their minimum and maximum like Chart, with the area below the line
+// filled. The image has no labels; print the values alongside it.
+func WriteChartPNG(w io.Writer, values []float64, width, height int) error {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, chartBackground)
+		}
+	}
+	plotW, plotH := width-2*chartMargin, height-2*chartMargin
+	if len(values) == 0 || plotW < 2 || plotH < 2 {
+		return png.Encode(w, img)
+	}
+	for i := 0; i <= 4; i++ {
+		y := chartMargin + i*(plotH-1)/4
+		for x := chartMargin; x < chartMargin+plotW; x++ {
+			img.Set(x, y, chartGrid)
+		}
+	}
+
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	point := func(i int) (int, int) {
+		x := chartMargin + plotW/2
+		if len(values) > 1 {
+			x = chartMargin + i*(plotW-1)/(len(values)-1)
+		}
+		y := chartMargin + plotH/2
+		if hi > lo {
+			y = chartMargin + int((hi-values[i])/(hi-lo)*float64(plotH-1)+0.5)
+		}
+		return x, y
+	}
+
+	// The line is drawn from column to column, filling below it
+	x0, y0 := point(0)
+	columnY := map[int]int{x0: y0}
+	for i := 1; i < len(values); i++ {
+		x1, y1 := point(i)
+		for x := x0; x <= x1; x++ {
+			y := y0
+			if x1 > x0 {
+				y = y0 + (y1-y0)*(x-x0)/(x1-x0)
+			}
+			columnY[x] = y
+		}
+		x0, y0 = x1, y1
+	}
+	bottom := chartMargin + plotH - 1
+	for x, y := range columnY {
+		for fy := y + 1; fy <= bottom; fy++ {
+			img.Set(x, fy, chartFill)
+		}
+	}
+	for x, y := range columnY {
+		// Join steep segments to the previous column
+		top, end := y, y
+		if prev, ok := columnY[x-1]; ok {
+			top, end = min(y, prev), max(y, prev)
+		}
+		for ly := top; ly <= end; ly++ {
+			img.Set(x, ly, chartLine)
+			img.Set(x, ly+1, chartLine)
+		}
+	}
+	return png.Encode(w, img)
+}
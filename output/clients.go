@@ -0,0 +1,21 @@
+package output
+
+import "github.com/ivan4th/ameriagrab/client"
+
+// PrintClients prints the user's clients, marking the default one and the
+// one currently in use
+func (o *Output) PrintClients(clients []client.ClientInfo, current string) {
+	w := o.newTable(false)
+	w.header("ID\tNAME\tDEFAULT\tCURRENT")
+	for _, c := range clients {
+		def, cur := "", ""
+		if c.Default {
+			def = "*"
+		}
+		if c.ID == current {
+			cur = "*"
+		}
+		w.row(rowStyle{}, "%s\t%s\t%s\t%s\n", c.ID, c.Name, def, cur)
+	}
+	w.flush()
+}
@@ -0,0 +1,68 @@
+package output
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// ANSI styles
+const (
+	ansiReset = "\033[0m"
+	ansiBold  = "\033[1m"
+	ansiDim   = "\033[2m"
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+)
+
+// colorEnabled reports whether output written to w should be colored: only
+// terminals (and the pager) are
+func (o *Output) colorEnabled(w io.Writer) bool {
+	if o.NoColor || os.Getenv("NO_COLOR") != "" || o.Format != FormatTable {
+		return false
+	}
+	if o.Paged && w == o.Out {
+		return true
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in the given ANSI style if color is enabled for w
+func (o *Output) paint(w io.Writer, style, s string) string {
+	if style == "" || !o.colorEnabled(w) {
+		return s
+	}
+	return style + s + ansiReset
+}
+
+// amountStyle returns the style of a signed amount: green for credits, red for debits
+func amountStyle(sign string) string {
+	if sign == "+" {
+		return ansiGreen
+	}
+	return ansiRed
+}
+
+// rowStyle is the style of a whole row, or of one cell of it if cell is set
+type rowStyle struct {
+	style string
+	cell  string
+}
+
+// applyRowStyle styles a formatted table line, keeping its trailing newline unstyled
+func applyRowStyle(line string, s rowStyle) string {
+	text := strings.TrimSuffix(line, "\n")
+	nl := line[len(text):]
+	if s.cell == "" {
+		return s.style + text + ansiReset + nl
+	}
+	if i := strings.Index(text, s.cell); i >= 0 {
+		return text[:i] + s.style + s.cell + ansiReset + text[i+len(s.cell):] + nl
+	}
+	return line
+}
@@ -0,0 +1,398 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/category"
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/i18n"
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// relativeDate describes a "2006-01-02 15:04" local date relative to now if
+// it's less than a week old, and returns other dates unchanged
+func relativeDate(date string, now time.Time) string {
+	t, err := time.ParseInLocation("2006-01-02 15:04", date, now.Location())
+	if err != nil {
+		return date
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+	days := int(today.Sub(day).Hours()/24 + 0.5) // DST days aren't 24 hours long
+	switch {
+	case days == 0 && !t.After(now):
+		return fmt.Sprintf(i18n.T("today %s"), t.Format("15:04"))
+	case days == 1:
+		return i18n.T("yesterday")
+	case days > 1 && days < 7:
+		return fmt.Sprintf(i18n.T("%d days ago"), days)
+	}
+	return date
+}
+
+// displayDate is the date shown in the date column
+func (o *Output) displayDate(date string) string {
+	if o.RelativeDates && o.Format == FormatTable {
+		return relativeDate(date, time.Now())
+	}
+	return date
+}
+
+// Table grouping modes (--group-by)
+const (
+	GroupByDay   = "day"
+	GroupByMonth = "month"
+)
+
+// CheckGroupBy returns an error if GroupBy is not a known grouping
+func (o *Output) CheckGroupBy() error {
+	switch o.GroupBy {
+	case "", GroupByDay, GroupByMonth:
+		return nil
+	}
+	return fmt.Errorf("invalid --group-by %q (expected %s or %s)", o.GroupBy, GroupByDay, GroupByMonth)
+}
+
+// dateGroup returns the group of a "2006-01-02 15:04" date in the GroupBy mode
+func (o *Output) dateGroup(date string) string {
+	n := len("2006-01-02")
+	if o.GroupBy == GroupByMonth {
+		n = len("2006-01")
+	}
+	if len(date) < n {
+		return date
+	}
+	return date[:n]
+}
+
+// Table sort keys (--sort)
+const (
+	SortByAmount = "amount"
+	SortByDate   = "date"
+	SortByType   = "type"
+)
+
+// CheckSort returns an error if SortBy is not a known sort key
+func (o *Output) CheckSort() error {
+	switch o.SortBy {
+	case "", SortByAmount, SortByDate, SortByType:
+		return nil
+	}
+	return fmt.Errorf("invalid --sort %q (expected %s, %s or %s)", o.SortBy, SortByAmount, SortByDate, SortByType)
+}
+
+// sortTxnRows orders rows by SortBy and SortDesc, keeping the original order of equal rows
+func (o *Output) sortTxnRows(rows []txnRow) {
+	key := o.SortBy
+	if key == "" {
+		if !o.SortDesc {
+			return
+		}
+		key = SortByDate
+	}
+	less := func(a, b txnRow) bool {
+		switch key {
+		case SortByAmount:
+			return a.Direction.Signed(a.Amount) < b.Direction.Signed(b.Amount)
+		case SortByType:
+			return a.Type < b.Type
+		}
+		return a.Date < b.Date
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if o.SortDesc {
+			return less(rows[j], rows[i])
+		}
+		return less(rows[i], rows[j])
+	})
+}
+
+// BalanceLookupFunc returns the balance after a transaction, if known
+type BalanceLookupFunc func(id string, date time.Time) (float64, bool)
+
+// ConvertFunc converts an amount in a currency to ConvertTo at the rate of
+// the given time, if one is known
+type ConvertFunc func(amount float64, currency string, at time.Time) (float64, bool)
+
+// convertAmount converts an amount with Convert, returning it unchanged if
+// Convert isn't set or has no rate
+func (o *Output) convertAmount(amount float64, currency string, at time.Time) (float64, string, bool) {
+	if o.Convert == nil || at.IsZero() || strings.EqualFold(currency, o.ConvertTo) {
+		return amount, currency, false
+	}
+	converted, ok := o.Convert(amount, currency, at)
+	if !ok {
+		return amount, currency, false
+	}
+	return converted, o.ConvertTo, true
+}
+
+// txnRow is a transaction prepared for a transaction table
+type txnRow struct {
+	ID           string
+	Date         string
+	Type         string
+	Direction    client.Direction
+	Amount       float64
+	Currency     string
+	Details      string
+	Workflow     string // the bank's workflow code, for the category
+	Counterparty string // beneficiary or receiver, named from templates if possible
+	State        string
+	Pending      bool
+	Product      string
+	Card         string // masked card number
+	Account      string // counterparty account number
+	Address      string // beneficiary address
+	Operation    string // processed operation ID
+	// Correspondent account of card transactions, often the only hint of the counterparty
+	Correspondent        string
+	CorrespondentAccount string
+	Balance              string // running balance, formatted
+	Original             string // amount and currency before conversion (--convert), formatted
+}
+
+// convert converts the amount of the transaction at the given time to
+// ConvertTo, keeping the original one
+func (r *txnRow) convert(o *Output, at time.Time) {
+	amount, currency, ok := o.convertAmount(r.Amount, r.Currency, at)
+	if !ok {
+		return
+	}
+	r.Original = o.formatSigned(r.Direction.Sign(), r.Amount) + " " + r.Currency
+	r.Amount, r.Currency = amount, currency
+}
+
+// setBalance looks up the running balance after the transaction at the given time
+func (r *txnRow) setBalance(o *Output, at time.Time) {
+	if o.RunningBalance == nil || at.IsZero() {
+		return
+	}
+	if balance, ok := o.RunningBalance(r.ID, at); ok {
+		r.Balance = o.FormatAmount(balance)
+	}
+}
+
+func (r txnRow) category() string {
+	return category.Default().Categorize(db.LedgerEntry{Counterparty: r.Counterparty, Details: r.Details, Workflow: r.Workflow})
+}
+
+// txnColumn is a column that can be selected with --columns
+type txnColumn struct {
+	header string
+	width  int // truncation width unless --wide, 0 for none
+	value  func(o *Output, r txnRow) string
+}
+
+var txnColumns = map[string]txnColumn{
+	"date": {"DATE", 0, func(o *Output, r txnRow) string { return o.displayDate(r.Date) }},
+	"id":   {"ID", 0, func(o *Output, r txnRow) string { return r.ID }},
+	"type": {"TYPE", 0, func(o *Output, r txnRow) string { return r.Type }},
+	"amount": {"AMOUNT", 0, func(o *Output, r txnRow) string {
+		return o.formatSigned(r.Direction.Sign(), r.Amount) + " " + r.Currency
+	}},
+	"value": {"AMOUNT", 0, func(o *Output, r txnRow) string {
+		return strings.TrimPrefix(o.formatSigned(r.Direction.Sign(), r.Amount), "+")
+	}},
+	"currency": {"CURRENCY", 0, func(o *Output, r txnRow) string { return r.Currency }},
+	// details are truncated to 50 characters if there's no counterparty column
+	"details":               {"DETAILS", 40, func(o *Output, r txnRow) string { return r.Details }},
+	"counterparty":          {"COUNTERPARTY", 0, func(o *Output, r txnRow) string { return r.Counterparty }},
+	"beneficiary":           {"BENEFICIARY", 30, func(o *Output, r txnRow) string { return r.Counterparty }},
+	"state":                 {"STATE", 0, func(o *Output, r txnRow) string { return r.State }},
+	"product":               {"PRODUCT", 0, func(o *Output, r txnRow) string { return r.Product }},
+	"card":                  {"CARD", 0, func(o *Output, r txnRow) string { return r.Card }},
+	"account":               {"ACCOUNT", 0, func(o *Output, r txnRow) string { return r.Account }},
+	"address":               {"ADDRESS", 30, func(o *Output, r txnRow) string { return r.Address }},
+	"operation":             {"OPERATION", 0, func(o *Output, r txnRow) string { return r.Operation }},
+	"correspondent":         {"CORRESPONDENT", 30, func(o *Output, r txnRow) string { return r.Correspondent }},
+	"correspondent_account": {"CORRESPONDENT ACCOUNT", 0, func(o *Output, r txnRow) string { return r.CorrespondentAccount }},
+	"balance":               {"BALANCE", 0, func(o *Output, r txnRow) string { return r.Balance }},
+	"original":              {"ORIGINAL", 0, func(o *Output, r txnRow) string { return r.Original }},
+	"category":              {"CATEGORY", 0, func(o *Output, r txnRow) string { return r.category() }},
+}
+
+// ColumnNames returns the names accepted by --columns
+func ColumnNames() []string {
+	var names []string
+	for name := range txnColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckColumns returns an error if Columns contains an unknown column
+func (o *Output) CheckColumns() error {
+	for _, name := range o.Columns {
+		if _, ok := txnColumns[name]; !ok {
+			return fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(ColumnNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// printTxnTable prints transactions with the selected Columns, or the given default ones
+func (o *Output) printTxnTable(rows []txnRow, defaults []string, wide bool) {
+	o.sortTxnRows(rows)
+	names := defaults
+	if len(o.Columns) > 0 {
+		names = o.Columns
+	} else if o.RunningBalance != nil || o.Convert != nil {
+		names = nil
+		for _, name := range defaults {
+			names = append(names, name)
+			if name == "amount" || name == "value" {
+				if o.RunningBalance != nil {
+					names = append(names, "balance")
+				}
+				if o.Convert != nil {
+					names = append(names, "original")
+				}
+			}
+		}
+	}
+	detailsWidth := 50
+	var headers []string
+	for _, name := range names {
+		headers = append(headers, txnColumns[name].header)
+		if name == "counterparty" || name == "beneficiary" {
+			detailsWidth = txnColumns["details"].width
+		}
+	}
+
+	w := o.newTable(false)
+	w.header(strings.Join(headers, "\t"))
+	var group []txnRow
+	for _, r := range rows {
+		// With --group-by, each day or month starts with a separator row
+		// and ends with its subtotals
+		if key := o.dateGroup(r.Date); o.GroupBy != "" && (len(group) == 0 || key != o.dateGroup(group[0].Date)) {
+			o.writeSubtotals(w, names, group)
+			group = nil
+			cells := make([]string, len(names))
+			cells[0] = key
+			w.row(rowStyle{style: ansiBold}, "%s\n", strings.Join(cells, "\t"))
+		}
+		group = append(group, r)
+
+		var style rowStyle
+		cells := make([]string, len(names))
+		for i, name := range names {
+			col := txnColumns[name]
+			cells[i] = col.value(o, r)
+			width := col.width
+			if name == "details" {
+				width = detailsWidth
+			}
+			if !wide && width > 0 {
+				cells[i] = TruncateString(cells[i], width)
+			}
+			// Settled amounts are colored by direction
+			if (name == "amount" || name == "value") && style.cell == "" {
+				style = rowStyle{style: amountStyle(r.Direction.Sign()), cell: cells[i]}
+			}
+		}
+		if o.Icons && o.Format != FormatCSV {
+			cells[0] = category.Icon(r.category()) + " " + cells[0]
+		}
+		// Pending transactions are dimmed
+		if r.Pending {
+			style = rowStyle{style: ansiDim}
+		}
+		w.row(style, "%s\n", strings.Join(cells, "\t"))
+	}
+	if o.GroupBy != "" {
+		o.writeSubtotals(w, names, group)
+	}
+	w.flush()
+}
+
+// currencyTotal sums the settled transactions of one currency
+type currencyTotal struct {
+	Currency string
+	Debits   float64
+	Credits  float64
+}
+
+// signedNet returns the net amount of the currency with its sign
+func (t currencyTotal) signedNet(o *Output) string {
+	net := money.Sub(t.Credits, t.Debits)
+	if net < 0 {
+		return o.formatSigned("-", net)
+	}
+	return o.formatSigned("+", net)
+}
+
+// txnTotals sums rows per currency, sorted by currency. Pending transactions
+// are left out, as they'd be counted again once they settle.
+func txnTotals(rows []txnRow) []currencyTotal {
+	byCurrency := make(map[string]*currencyTotal)
+	var totals []*currencyTotal
+	for _, r := range rows {
+		if r.Pending {
+			continue
+		}
+		t := byCurrency[r.Currency]
+		if t == nil {
+			t = &currencyTotal{Currency: r.Currency}
+			byCurrency[r.Currency] = t
+			totals = append(totals, t)
+		}
+		if r.Direction == client.DirectionCredit {
+			t.Credits = money.Add(t.Credits, r.Amount)
+		} else {
+			t.Debits = money.Add(t.Debits, r.Amount)
+		}
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Currency < totals[j].Currency })
+	result := make([]currencyTotal, len(totals))
+	for i, t := range totals {
+		result[i] = *t
+	}
+	return result
+}
+
+// writeSubtotals writes the net amount per currency of a group of rows into
+// the amount column, if one is shown
+func (o *Output) writeSubtotals(w *table, names []string, group []txnRow) {
+	if !slices.Contains(names, "amount") && !slices.Contains(names, "value") {
+		return
+	}
+	for _, t := range txnTotals(group) {
+		cells := make([]string, len(names))
+		for i, name := range names {
+			switch name {
+			case "amount":
+				cells[i] = t.signedNet(o) + " " + t.Currency
+			case "value":
+				cells[i] = strings.TrimPrefix(t.signedNet(o), "+")
+			case "currency":
+				cells[i] = t.Currency
+			}
+		}
+		if cells[0] == "" {
+			cells[0] = i18n.T("subtotal")
+		}
+		w.row(rowStyle{style: ansiDim}, "%s\n", strings.Join(cells, "\t"))
+	}
+}
+
+// printTxnTotals writes the total debits, credits and net amount per currency
+// of the displayed transactions
+func (o *Output) printTxnTotals(w io.Writer, rows []txnRow) {
+	var text strings.Builder
+	for _, t := range txnTotals(rows) {
+		fmt.Fprintf(&text, "%s\t%s %s\t%s %s\t%s %s\n", t.Currency,
+			i18n.T("debits"), o.formatSigned("-", t.Debits), i18n.T("credits"), o.formatSigned("+", t.Credits), i18n.T("net"), t.signedNet(o))
+	}
+	if text.Len() > 0 {
+		WriteColumns(w, text.String())
+	}
+}
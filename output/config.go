@@ -0,0 +1,22 @@
+package output
+
+// ConfigEntry is a config key with its current value for display
+type ConfigEntry struct {
+	Key         string
+	Value       string
+	Description string
+}
+
+// PrintConfig prints config keys, values and descriptions in table format
+func (o *Output) PrintConfig(entries []ConfigEntry) {
+	w := o.newTable(false)
+	w.header("KEY\tVALUE\tDESCRIPTION")
+	for _, e := range entries {
+		value := e.Value
+		if value == "" {
+			value = "-"
+		}
+		w.row(rowStyle{}, "%s\t%s\t%s\n", e.Key, value, e.Description)
+	}
+	w.flush()
+}
@@ -0,0 +1,28 @@
+package output
+
+import (
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// PrintCounterparties prints the counterparty directory in human-readable
+// table format
+func (o *Output) PrintCounterparties(counterparties []db.Counterparty) {
+	w := o.newTable(false)
+	w.header("NAME\tCARD/ACCOUNT\tTOTAL\tTRANSFERS\tFIRST\tLAST")
+	for _, cp := range counterparties {
+		w.row(rowStyle{}, "%s\t%s\t%s %s\t%d\t%s\t%s\n",
+			orDash(TruncateString(cp.Name, 30)), orDash(o.DisplayNumber(cp.Number)), o.FormatAmount(cp.Total), cp.Currency,
+			cp.Count, formatDay(cp.FirstDate), formatDay(cp.LastDate))
+	}
+	w.flush()
+}
+
+// formatDay formats the date of t, or "-" if it's unknown
+func formatDay(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,79 @@
+package output
+
+import (
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// PrintDatabaseDiff prints the rows stored in only one of two databases,
+// labeled here and other, followed by the counts per table
+func (o *Output) PrintDatabaseDiff(diff *db.DatabaseDiff, here, other string) {
+	sides := []struct {
+		label string
+		rows  []db.DiffRow
+	}{{here, diff.OnlyHere}, {other, diff.OnlyOther}}
+
+	w := o.newTable(false)
+	w.header("ONLY IN\tTABLE\tPRODUCT\tID\tDATE\tAMOUNT\tDETAILS")
+	for _, side := range sides {
+		for _, r := range side.rows {
+			w.row(rowStyle{}, "%s\t%s\t%s\t%s\t%s\t%s %s\t%s\n",
+				side.label, r.Table, r.ProductID, r.ID, formatDiffDate(r.Date),
+				o.FormatAmount(r.Amount), r.Currency, orDash(TruncateString(r.Details, 40)))
+		}
+	}
+	w.flush()
+
+	for _, side := range sides {
+		counts := make(map[string]int)
+		var tables []string
+		for _, r := range side.rows {
+			if counts[r.Table] == 0 {
+				tables = append(tables, r.Table)
+			}
+			counts[r.Table]++
+		}
+		o.printText("Only in %s: %d rows", side.label, len(side.rows))
+		for _, table := range tables {
+			o.printText("  %s: %d", table, counts[table])
+		}
+	}
+}
+
+// formatDiffDate formats a date of a diff row in local time, or "-" if it has none
+func formatDiffDate(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// PrintLintIssues prints suspicious stored transactions found by db lint,
+// followed by the counts per kind
+func (o *Output) PrintLintIssues(issues []db.LintIssue) {
+	w := o.newTable(false)
+	w.header("KIND\tTABLE\tPRODUCT\tID\tDATE\tMESSAGE\tFIXABLE")
+	counts := make(map[string]int)
+	var kinds []string
+	var fixable int
+	for _, i := range issues {
+		fix := "-"
+		if i.Fixable {
+			fix = "yes"
+			fixable++
+		}
+		w.row(rowStyle{}, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", i.Kind, i.Table, i.ProductID, i.ID, orDash(i.Date), i.Message, fix)
+		if counts[i.Kind] == 0 {
+			kinds = append(kinds, i.Kind)
+		}
+		counts[i.Kind]++
+	}
+	w.flush()
+
+	o.printText("%d issues, %d fixable with --fix", len(issues), fixable)
+	for _, kind := range kinds {
+		o.printText("  %s: %d", kind, counts[kind])
+	}
+}
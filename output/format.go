@@ -2,55 +2,43 @@ package output
 
 import (
 	"fmt"
-	"os"
 	"strings"
-	"text/tabwriter"
 	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
 	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/i18n"
+	"github.com/ivan4th/ameriagrab/textwidth"
 )
 
-// TruncateString truncates a string to maxLen characters, adding "..." if truncated
+// TruncateString truncates a string to maxLen terminal cells, adding "..." if
+// truncated. Multibyte characters are never split and wide (East Asian)
+// characters count as two cells.
 func TruncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	if maxLen <= 3 {
-		return s[:maxLen]
-	}
-	return s[:maxLen-3] + "..."
+	return textwidth.Truncate(s, maxLen)
 }
 
-// TemplateLookupFunc is a function that looks up a template name by masked card number
-type TemplateLookupFunc func(maskedCard string) string
+// TemplateLookupFunc is a function that looks up a template name and its group
+// name (empty if ungrouped) by masked card number or account number
+type TemplateLookupFunc func(number string) (name, group string)
 
 // PrintCardTransactions prints card transactions in human-readable table format
-func PrintCardTransactions(txns *client.TransactionsResponse, showExtended, wide bool) {
-	PrintCardTransactionsWithLookup(txns, showExtended, wide, nil)
+func (o *Output) PrintCardTransactions(txns *client.TransactionsResponse, showExtended, wide bool) {
+	o.PrintCardTransactionsWithLookup(txns, showExtended, wide, nil)
 }
 
 // PrintCardTransactionsWithLookup prints card transactions with optional template name lookup
-func PrintCardTransactionsWithLookup(txns *client.TransactionsResponse, showExtended, wide bool, lookupFn TemplateLookupFunc) {
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	if showExtended {
-		fmt.Fprintln(w, "DATE\tTYPE\tAMOUNT\tDETAILS\tCOUNTERPARTY")
-	} else {
-		fmt.Fprintln(w, "DATE\tTYPE\tAMOUNT\tDETAILS")
-	}
+func (o *Output) PrintCardTransactionsWithLookup(txns *client.TransactionsResponse, showExtended, wide bool, lookupFn TemplateLookupFunc) {
+	var rows []txnRow
+	hasCounterparty, hasCorrespondent := false, false
 	for _, t := range txns.Data.Entries {
-		// Format amount with +/- sign based on accounting type
-		sign := "-"
-		if t.AccountingType == "CREDIT" {
-			sign = "+"
-		}
-		amount := fmt.Sprintf("%s%.2f %s", sign, t.Amount.Amount, t.Amount.Currency)
-
 		// Parse and format date
 		date := t.Date
+		var at time.Time
 		if t.OperationDate != "" {
-			if parsed, err := time.Parse(time.RFC3339, t.OperationDate); err == nil {
-				date = parsed.Format("2006-01-02 15:04")
+			if parsed, err := db.ParseDate(t.OperationDate); err == nil {
+				date = parsed.Local().Format("2006-01-02 15:04")
+				at = parsed
 			}
 		}
 
@@ -60,30 +48,92 @@ func PrintCardTransactionsWithLookup(txns *client.TransactionsResponse, showExte
 		txType = strings.ReplaceAll(txType, "purchasecompletion:", "pcomp:")
 		txType = strings.ReplaceAll(txType, "purchase:", "p:")
 
-		details := t.Details
-		if !wide {
-			if showExtended {
-				details = TruncateString(details, 40)
-			} else {
-				details = TruncateString(details, 50)
-			}
+		r := txnRow{
+			ID:                   t.ID,
+			Date:                 date,
+			Type:                 txType,
+			Direction:            t.Direction(),
+			Amount:               t.Amount.Amount,
+			Currency:             t.Amount.Currency,
+			Details:              t.Details,
+			Workflow:             t.WorkflowCode,
+			State:                t.State,
+			Pending:              isPendingTransaction(t),
+			Correspondent:        t.CorrespondentAccountName,
+			CorrespondentAccount: o.DisplayNumber(t.CorrespondentAccountNumber),
+		}
+		if t.Extended != nil {
+			r.Counterparty = o.formatReceiverWithLookup(t.Extended, lookupFn)
+			r.Card = o.DisplayNumber(t.Extended.CardMaskedNumber)
+			r.Account = o.DisplayNumber(t.Extended.CreditAccountNumber)
+			r.Address = t.Extended.BeneficiaryAddress
+			r.Operation = t.Extended.OperationID
 		}
+		hasCounterparty = hasCounterparty || r.Counterparty != ""
+		hasCorrespondent = hasCorrespondent || r.Correspondent != "" || r.CorrespondentAccount != ""
+		r.setBalance(o, at)
+		r.convert(o, at)
+		rows = append(rows, r)
+	}
+	// Counterparties known without -x (stored by sync) get their column, too
+	columns := []string{"date", "type", "amount", "details"}
+	if showExtended || hasCounterparty {
+		columns = append(columns, "counterparty")
+	}
+	// Correspondent accounts are shown with -x or --wide
+	if (showExtended || wide) && hasCorrespondent {
+		columns = append(columns, "correspondent", "correspondent_account")
+	}
+	o.printTxnTable(rows, columns, wide)
+	if showExtended {
+		o.printSwiftDetails(txns.Data.Entries)
+	}
+	fmt.Fprintf(o.Err, "\n%s\n", o.paint(o.Err, ansiBold, fmt.Sprintf(i18n.T("Total: %d transactions"), txns.Data.TotalCount)))
+	o.printTxnTotals(o.Err, rows)
+}
+
+// isPendingTransaction reports whether a card transaction is an authorization
+// that hasn't settled yet, or one listed next to its settlement (get
+// --show-stages); either way its amount isn't counted
+func isPendingTransaction(t client.Transaction) bool {
+	return strings.EqualFold(t.State, "PENDING") || db.IsAuthorization(t)
+}
 
-		if showExtended {
-			receiver := formatReceiverWithLookup(t.Extended, lookupFn)
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-				date, txType, amount, details, receiver)
-		} else {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
-				date, txType, amount, details)
+// printSwiftDetails prints a block with the SWIFT details of each international
+// transfer among the transactions
+func (o *Output) printSwiftDetails(entries []client.Transaction) {
+	first := true
+	for _, t := range entries {
+		if t.Extended == nil || t.Extended.Swift.Empty() {
+			continue
+		}
+		if first {
+			fmt.Fprintln(o.Out)
+			o.PrintHeading("SWIFT transfers")
+			first = false
+		}
+		date := t.Date
+		if parsed, err := db.ParseDate(t.OperationDate); err == nil {
+			date = parsed.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintln(o.Out)
+		o.printText("  %s  %s %s  %s", date, o.formatSigned(t.Direction().Sign(), t.Amount.Amount), t.Amount.Currency, t.Details)
+		swift := t.Extended.Swift
+		for _, f := range []struct{ label, value string }{
+			{"Sender bank", swift.SenderBank},
+			{"Reference", swift.Reference},
+			{"Fees", swift.Fees},
+			{"Intermediary", swift.Intermediary},
+		} {
+			if f.value != "" {
+				o.printText("    %-14s%s", i18n.T(f.label)+":", f.value)
+			}
 		}
 	}
-	w.Flush()
-	fmt.Fprintf(os.Stderr, "\nTotal: %d transactions\n", txns.Data.TotalCount)
 }
 
 // formatReceiverWithLookup formats the receiver info, using template lookup if available
-func formatReceiverWithLookup(ext *client.TransactionExtendedInfo, lookupFn TemplateLookupFunc) string {
+func (o *Output) formatReceiverWithLookup(ext *client.TransactionExtendedInfo, lookupFn TemplateLookupFunc) string {
 	if ext == nil {
 		return ""
 	}
@@ -94,14 +144,21 @@ func formatReceiverWithLookup(ext *client.TransactionExtendedInfo, lookupFn Temp
 
 	// Check if name is valid (not empty or placeholder)
 	name := ext.BeneficiaryName
-	hasValidName := name != "" && name != "Firstname Lastname"
+	hasValidName := name != "" && name != db.PlaceholderBeneficiary
 
 	// Try template lookup for card number if no valid beneficiary name
 	if !hasValidName && lookupFn != nil && cardNum != "" {
-		if templateName := lookupFn(cardNum); templateName != "" {
+		templateName, group := lookupFn(cardNum)
+		shortCard := o.DisplayNumber(shortenMaskedCard(cardNum))
+		switch {
+		case templateName != "" && group != "":
+			// Found grouped template: show "TemplateName [Group] (****1234)"
+			return fmt.Sprintf("%s [%s] (%s)", templateName, group, shortCard)
+		case templateName != "":
 			// Found template: show "TemplateName (****1234)"
-			shortCard := shortenMaskedCard(cardNum)
 			return fmt.Sprintf("%s (%s)", templateName, shortCard)
+		case group != "":
+			return fmt.Sprintf("[%s] (%s)", group, shortCard)
 		}
 	}
 
@@ -110,6 +167,7 @@ func formatReceiverWithLookup(ext *client.TransactionExtendedInfo, lookupFn Temp
 	if acctOrCard == "" {
 		acctOrCard = acctNum
 	}
+	acctOrCard = o.DisplayNumber(acctOrCard)
 
 	if hasValidName && acctOrCard != "" {
 		return fmt.Sprintf("%s (%s)", name, acctOrCard)
@@ -132,21 +190,21 @@ func shortenMaskedCard(masked string) string {
 }
 
 // PrintAccountHistory prints account history in human-readable table format
-func PrintAccountHistory(history *client.HistoryResponse, wide bool) {
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "DATE\tTYPE\tAMOUNT\tBENEFICIARY\tDETAILS")
-	for _, t := range history.Data.Transactions {
-		// Format amount with +/- sign based on flow direction
-		sign := "-"
-		if t.FlowDirection == "INCOME" {
-			sign = "+"
-		}
-		amount := fmt.Sprintf("%s%.2f %s", sign, t.TransactionAmount.Value, t.TransactionAmount.Currency)
+func (o *Output) PrintAccountHistory(history *client.HistoryResponse, wide bool) {
+	o.PrintAccountHistoryWithLookup(history, wide, nil)
+}
 
+// PrintAccountHistoryWithLookup prints account history, naming beneficiaries the
+// bank doesn't know from templates matching the counterparty account or card
+func (o *Output) PrintAccountHistoryWithLookup(history *client.HistoryResponse, wide bool, lookupFn TemplateLookupFunc) {
+	var rows []txnRow
+	for _, t := range history.Data.Transactions {
 		// Format date from timestamp
 		date := t.Date
+		var at time.Time
 		if t.TransactionDate > 0 {
-			date = time.UnixMilli(t.TransactionDate).Format("2006-01-02 15:04")
+			at = time.UnixMilli(t.TransactionDate)
+			date = at.Format("2006-01-02 15:04")
 		}
 
 		// Shorten transaction type for display
@@ -154,58 +212,95 @@ func PrintAccountHistory(history *client.HistoryResponse, wide bool) {
 		txType = strings.ReplaceAll(txType, "transfer:", "xfer:")
 
 		beneficiary := t.BeneficiaryName
-		details := t.Details
-		if !wide {
-			beneficiary = TruncateString(beneficiary, 30)
-			details = TruncateString(details, 40)
+		if (beneficiary == "" || beneficiary == db.PlaceholderBeneficiary) && lookupFn != nil {
+			if label := db.TemplateLabel(lookupFn(t.CounterpartyAccount())); label != "" {
+				beneficiary = label
+			}
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-			date, txType, amount, beneficiary, details)
+		r := txnRow{
+			ID:           t.ID,
+			Date:         date,
+			Type:         txType,
+			Direction:    t.Direction(),
+			Amount:       t.TransactionAmount.Value,
+			Currency:     t.TransactionAmount.Currency,
+			Details:      t.Details,
+			Workflow:     t.WorkflowCode,
+			Counterparty: beneficiary,
+			State:        t.Status,
+			Pending:      strings.EqualFold(t.Status, "PENDING"),
+			Account:      o.DisplayNumber(t.CounterpartyAccount()),
+		}
+		r.setBalance(o, at)
+		r.convert(o, at)
+		rows = append(rows, r)
+	}
+	o.printTxnTable(rows, []string{"date", "type", "amount", "beneficiary", "details"}, wide)
+	if len(rows) > 0 {
+		fmt.Fprintln(o.Err)
+		o.printTxnTotals(o.Err, rows)
 	}
-	w.Flush()
 	if history.Data.HasNext {
-		fmt.Fprintln(os.Stderr, "\n(more transactions available, use --page to paginate)")
+		fmt.Fprintln(o.Err, "\n"+i18n.T("(more transactions available, use --page to paginate)"))
 	}
 }
 
 // PrintAccountsAndCards prints accounts and cards in human-readable table format
-func PrintAccountsAndCards(resp *client.AccountsAndCardsResponse) {
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "TYPE\tID\tNUMBER\tNAME\tCURRENCY\tBALANCE\tSTATUS")
+func (o *Output) PrintAccountsAndCards(resp *client.AccountsAndCardsResponse) {
+	w := o.newTable(false)
+	if o.BalanceTrends != nil {
+		w.header("TYPE\tID\tNUMBER\tNAME\tCURRENCY\tBALANCE\tTREND\tSTATUS")
+	} else {
+		w.header("TYPE\tID\tNUMBER\tNAME\tCURRENCY\tBALANCE\tSTATUS")
+	}
 	for _, p := range resp.Data.AccountsAndCards {
 		number := p.CardNumber
 		if p.ProductType == "ACCOUNT" {
 			number = p.AccountNumber
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%.2f\t%s\n",
-			p.ProductType, p.ID, number, p.Name, p.Currency, p.AvailableBalance, p.Status)
+		number = o.DisplayNumber(number)
+		// Inactive products are dimmed, negative balances shown in red
+		var style rowStyle
+		switch {
+		case p.Status != "" && p.Status != "ACTIVE":
+			style = rowStyle{style: ansiDim}
+		case p.AvailableBalance < 0:
+			style = rowStyle{style: ansiRed, cell: o.FormatAmount(p.AvailableBalance)}
+		}
+		balance := o.FormatAmount(p.AvailableBalance)
+		if o.BalanceTrends != nil {
+			balance += "\t" + Sparkline(o.BalanceTrends[p.ID])
+		}
+		w.row(style, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			p.ProductType, p.ID, number, p.Name, p.Currency, balance, p.Status)
 	}
-	w.Flush()
+	w.flush()
 }
 
 // PrintSnapshots prints snapshots grouped by date in human-readable format
-func PrintSnapshots(snapshots []db.Snapshot) {
+func (o *Output) PrintSnapshots(snapshots []db.Snapshot) {
 	for i, s := range snapshots {
 		// Print date header
-		fmt.Printf("=== %s ===\n", s.CreatedAt.Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(o.Out, "=== %s ===\n", s.CreatedAt.Format("2006-01-02 15:04:05"))
 
 		// Print products table
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "TYPE\tID\tNUMBER\tNAME\tCURRENCY\tBALANCE\tSTATUS")
+		w := o.newTable(false)
+		w.header("TYPE\tID\tNUMBER\tNAME\tCURRENCY\tBALANCE\tSTATUS")
 		for _, p := range s.Products {
 			number := p.CardNumber
 			if p.ProductType == "ACCOUNT" {
 				number = p.AccountNumber
 			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%.2f\t%s\n",
-				p.ProductType, p.ID, number, p.Name, p.Currency, p.AvailableBalance, p.Status)
+			number = o.DisplayNumber(number)
+			w.row(rowStyle{}, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				p.ProductType, p.ID, number, p.Name, p.Currency, o.FormatAmount(p.AvailableBalance), p.Status)
 		}
-		w.Flush()
+		w.flush()
 
 		// Add blank line between snapshots (except after the last one)
 		if i < len(snapshots)-1 {
-			fmt.Println()
+			fmt.Fprintln(o.Out)
 		}
 	}
 }
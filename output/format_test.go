@@ -2,11 +2,22 @@ package output
 
 import (
 	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"text/tabwriter"
+	"time"
 
 	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/i18n"
+	"github.com/ivan4th/ameriagrab/report"
+	"github.com/ivan4th/ameriagrab/textwidth"
 )
 
 func TestTruncateString(t *testing.T) {
@@ -74,20 +85,15 @@ func TestPrintCardTransactions_Table(t *testing.T) {
 		},
 	}
 
-	// Capture stdout
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	PrintCardTransactions(txns, false, false)
-
-	w.Close()
-	os.Stdout = oldStdout
-
-	var buf bytes.Buffer
-	buf.ReadFrom(r)
+	var buf, errBuf bytes.Buffer
+	New(&buf, &errBuf).PrintCardTransactions(txns, false, false)
 	output := buf.String()
 
+	// The count and totals don't end up in the table output
+	if !strings.Contains(errBuf.String(), "Total: 2 transactions") || strings.Contains(output, "Total:") {
+		t.Errorf("expected the total on the error writer, got:\n%s", errBuf.String())
+	}
+
 	// Verify output contains expected elements
 	if !strings.Contains(output, "DATE") {
 		t.Error("output should contain DATE header")
@@ -158,18 +164,8 @@ func TestPrintAccountHistory_Table(t *testing.T) {
 		},
 	}
 
-	// Capture stdout
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	PrintAccountHistory(history, false)
-
-	w.Close()
-	os.Stdout = oldStdout
-
 	var buf bytes.Buffer
-	buf.ReadFrom(r)
+	New(&buf, io.Discard).PrintAccountHistory(history, false)
 	output := buf.String()
 
 	// Verify output contains expected elements
@@ -223,18 +219,8 @@ func TestPrintAccountsAndCards(t *testing.T) {
 		},
 	}
 
-	// Capture stdout
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	PrintAccountsAndCards(resp)
-
-	w.Close()
-	os.Stdout = oldStdout
-
 	var buf bytes.Buffer
-	buf.ReadFrom(r)
+	New(&buf, io.Discard).PrintAccountsAndCards(resp)
 	output := buf.String()
 
 	// Verify output contains expected elements
@@ -282,3 +268,794 @@ func TestTruncateString_EdgeCases(t *testing.T) {
 		t.Error("TruncateString should truncate long strings properly")
 	}
 }
+
+func TestPrintAvailableBalance(t *testing.T) {
+	holds := []client.Transaction{
+		{AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 3000}},
+		{AccountingType: "CREDIT", Amount: client.Amount{Currency: "AMD", Amount: 500}},
+		{AccountingType: "DEBIT", Amount: client.Amount{Currency: "USD", Amount: 10}},
+	}
+	total, other := HoldsTotal(holds, "AMD")
+	if total != -2500 || other != 1 {
+		t.Errorf("expected -2500 AMD and 1 hold in other currencies, got %.2f and %d", total, other)
+	}
+
+	var buf bytes.Buffer
+	New(&buf, io.Discard).PrintAvailableBalance(client.ProductInfo{Currency: "AMD", Balance: 100000, AvailableBalance: 93500}, holds)
+	output := buf.String()
+
+	for _, want := range []string{"100000.00 AMD", "-2500.00 AMD", "-4000.00 AMD", "93500.00 AMD", "1 pending holds in other currencies"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestPrintAccountHistoryWithLookup(t *testing.T) {
+	history := &client.HistoryResponse{}
+	history.Data.Transactions = []client.AccountTransaction{
+		{ID: "t1", FlowDirection: "EXPENSE", CreditAccountNumber: "1570000000000001"},
+		{ID: "t2", FlowDirection: "INCOME", DebitAccountNumber: "1570000000000002", BeneficiaryName: "Known Sender"},
+	}
+	lookup := func(number string) (string, string) {
+		if number == "1570000000000001" {
+			return "Landlord", "Household"
+		}
+		return "", ""
+	}
+
+	var buf bytes.Buffer
+	New(&buf, io.Discard).PrintAccountHistoryWithLookup(history, true, lookup)
+	output := buf.String()
+
+	if !strings.Contains(output, "Landlord [Household]") {
+		t.Errorf("output should name the beneficiary from the template, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Known Sender") {
+		t.Errorf("output should keep the bank's beneficiary name, got:\n%s", output)
+	}
+}
+
+func TestPrintCardTransactions_SwiftDetails(t *testing.T) {
+	txns := &client.TransactionsResponse{}
+	txns.Data.Entries = []client.Transaction{
+		{ID: "t1", AccountingType: "CREDIT", Details: "Incoming transfer", OperationDate: "2024-01-15T10:30:00Z",
+			Amount: client.Amount{Currency: "USD", Amount: 1000},
+			Extended: &client.TransactionExtendedInfo{Swift: &client.SwiftInfo{
+				SenderBank: "Example Bank NA", Reference: "INV-42", Fees: "15 USD (SHA)"}}},
+		{ID: "t2", AccountingType: "DEBIT", Details: "Coffee", Extended: &client.TransactionExtendedInfo{}},
+	}
+
+	var buf bytes.Buffer
+	New(&buf, io.Discard).PrintCardTransactions(txns, true, false)
+	output := buf.String()
+
+	for _, want := range []string{"SWIFT transfers:", "+1000.00 USD  Incoming transfer", "Sender bank:  Example Bank NA", "Reference:    INV-42", "Fees:         15 USD (SHA)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "Intermediary:") || strings.Contains(output, "Coffee\n    ") {
+		t.Errorf("output should only list known SWIFT fields of SWIFT transfers, got:\n%s", output)
+	}
+}
+
+func TestPrintCardTransactions_StoredCounterparty(t *testing.T) {
+	txns := &client.TransactionsResponse{}
+	txns.Data.Entries = []client.Transaction{
+		{ID: "t1", AccountingType: "DEBIT", Details: "Rent", Amount: client.Amount{Currency: "AMD", Amount: 5000},
+			Extended: &client.TransactionExtendedInfo{BeneficiaryName: "Landlord"}},
+		{ID: "t2", AccountingType: "DEBIT", Details: "Coffee", Amount: client.Amount{Currency: "AMD", Amount: 700}},
+	}
+
+	var buf bytes.Buffer
+	New(&buf, io.Discard).PrintCardTransactions(txns, false, false)
+	if !strings.Contains(buf.String(), "COUNTERPARTY") || !strings.Contains(buf.String(), "Landlord") {
+		t.Errorf("stored counterparties should be shown without extended mode, got:\n%s", buf.String())
+	}
+
+	txns.Data.Entries[0].Extended = nil
+	buf.Reset()
+	New(&buf, io.Discard).PrintCardTransactions(txns, false, false)
+	if strings.Contains(buf.String(), "COUNTERPARTY") {
+		t.Errorf("no counterparty column expected without counterparties, got:\n%s", buf.String())
+	}
+}
+
+func TestColorTable(t *testing.T) {
+	// A pipe is not a terminal, so nothing is colored
+	r, w, _ := os.Pipe()
+	defer r.Close()
+	defer w.Close()
+	if New(w, io.Discard).colorEnabled(w) {
+		t.Error("color should be disabled for a pipe")
+	}
+	// Neither is any other writer
+	var buf bytes.Buffer
+	table := New(&buf, io.Discard).newTable(false)
+	table.header("A\tB")
+	table.row(rowStyle{style: ansiGreen, cell: "+1.00"}, "%s\t%s\n", "x", "+1.00")
+	table.flush()
+	if strings.Contains(buf.String(), "\033[") {
+		t.Errorf("expected no escape sequences, got %q", buf.String())
+	}
+
+	if got := applyRowStyle("x  +1.00  y\n", rowStyle{style: ansiGreen, cell: "+1.00"}); got != "x  "+ansiGreen+"+1.00"+ansiReset+"  y\n" {
+		t.Errorf("unexpected styled cell: %q", got)
+	}
+	if got := applyRowStyle("x  y\n", rowStyle{style: ansiDim}); got != ansiDim+"x  y"+ansiReset+"\n" {
+		t.Errorf("unexpected styled row: %q", got)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if Std().colorEnabled(os.Stdout) {
+		t.Error("NO_COLOR should disable color")
+	}
+}
+
+func TestPrintAccountsAndCards_MarkdownAndHTML(t *testing.T) {
+	resp := &client.AccountsAndCardsResponse{}
+	resp.Data.AccountsAndCards = []client.ProductInfo{
+		{ProductType: "CARD", ID: "c1", CardNumber: "4454********6615", Name: "Main | card", Currency: "AMD", AvailableBalance: 1500, Status: "ACTIVE"},
+	}
+	capture := func(format string) string {
+		var buf bytes.Buffer
+		o := New(&buf, io.Discard)
+		o.Format = format
+		o.PrintAccountsAndCards(resp)
+		return buf.String()
+	}
+
+	md := capture(FormatMarkdown)
+	wantMD := "| TYPE | ID | NUMBER | NAME | CURRENCY | BALANCE | STATUS |\n" +
+		"| --- | --- | --- | --- | --- | --- | --- |\n" +
+		`| CARD | c1 | 4454\*\*\*\*\*\*\*\*6615 | Main \| card | AMD | 1500.00 | ACTIVE |` + "\n"
+	if md != wantMD {
+		t.Errorf("unexpected markdown:\n%s\nwant:\n%s", md, wantMD)
+	}
+
+	html := capture(FormatHTML)
+	for _, want := range []string{"<table>", "<th>TYPE</th>", "<td>Main | card</td>", "<td>1500.00</td>", "</table>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML should contain %q, got:\n%s", want, html)
+		}
+	}
+
+	o := Std()
+	o.Format = "xlsx"
+	if err := o.CheckFormat(); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestPrintPeriodStatement_CSV(t *testing.T) {
+	st := &report.PeriodStatement{
+		ProductID:      "acc1",
+		ProductName:    "Savings",
+		Currency:       "AMD",
+		From:           time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local),
+		To:             time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local),
+		OpeningBalance: 1000,
+		ClosingBalance: 900,
+		Debits:         100,
+		Transactions: []db.LedgerEntry{
+			{ID: "t1", Date: time.Date(2024, 6, 5, 10, 0, 0, 0, time.Local), Amount: 100, Counterparty: "Shop, \"Main\" St"},
+		},
+	}
+	var buf, errBuf bytes.Buffer
+	o := New(&buf, &errBuf)
+	o.Format = FormatCSV
+	o.PrintPeriodStatement(st)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v\n%s", err, buf.String())
+	}
+	if len(records) != 6 || strings.Join(records[1], ",") != "DATE,DETAILS,AMOUNT" {
+		t.Errorf("unexpected records: %q", records)
+	}
+	if records[2][1] != `Shop, "Main" St` {
+		t.Errorf("details should survive quoting, got %q", records[2][1])
+	}
+	// The text around the table goes to stderr
+	if !strings.Contains(errBuf.String(), "Statement: Savings (acc1)") {
+		t.Errorf("expected the statement header on the error writer, got:\n%s", errBuf.String())
+	}
+}
+
+func TestPrintPeriodStatement_Translated(t *testing.T) {
+	i18n.SetLocale("ru")
+	defer i18n.SetLocale("")
+
+	st := &report.PeriodStatement{
+		ProductID:   "acc1",
+		ProductName: "Savings",
+		Currency:    "AMD",
+		From:        time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local),
+		To:          time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local),
+	}
+	var buf bytes.Buffer
+	New(&buf, io.Discard).PrintPeriodStatement(st)
+	for _, want := range []string{"Выписка: Savings (acc1)", "Входящий остаток", "ДАТА", "НАЗНАЧЕНИЕ", "Исходящий остаток", "последней синхронизации"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output should contain %q, got:\n%s", want, buf.String())
+		}
+	}
+
+	// CSV column names stay in English
+	buf.Reset()
+	o := New(&buf, io.Discard)
+	o.Format = FormatCSV
+	o.PrintPeriodStatement(st)
+	if !strings.Contains(buf.String(), "DATE,DETAILS,AMOUNT") {
+		t.Errorf("CSV headers should not be translated, got:\n%s", buf.String())
+	}
+}
+
+func TestPrintCardTransactions_Columns(t *testing.T) {
+	txns := &client.TransactionsResponse{}
+	txns.Data.Entries = []client.Transaction{
+		{ID: "t1", AccountingType: "DEBIT", Details: "YEREVAN CITY SUPERMARKET", Amount: client.Amount{Currency: "AMD", Amount: 4200},
+			Extended: &client.TransactionExtendedInfo{CardMaskedNumber: "4454********6615"}},
+	}
+
+	var buf bytes.Buffer
+	o := New(&buf, io.Discard)
+	o.Columns = []string{"amount", "category", "card", "id"}
+	if err := o.CheckColumns(); err != nil {
+		t.Fatalf("CheckColumns failed: %v", err)
+	}
+	o.PrintCardTransactions(txns, false, false)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected header and one row, got:\n%s", buf.String())
+	}
+	if got := strings.Fields(lines[0]); strings.Join(got, ",") != "AMOUNT,CATEGORY,CARD,ID" {
+		t.Errorf("unexpected header: %q", lines[0])
+	}
+	if got := strings.Fields(lines[1]); strings.Join(got, ",") != "-4200.00,AMD,groceries,4454********6615,t1" {
+		t.Errorf("unexpected row: %q", lines[1])
+	}
+
+	// Rows are prefixed with category icons
+	o.Icons = true
+	buf.Reset()
+	o.PrintCardTransactions(txns, false, false)
+	if !strings.Contains(buf.String(), "🛒 -4200.00 AMD") {
+		t.Errorf("expected the groceries icon before the first cell, got:\n%s", buf.String())
+	}
+
+	o.Columns = []string{"date", "bogus"}
+	if err := o.CheckColumns(); err == nil || !strings.Contains(err.Error(), "bogus") {
+		t.Errorf("expected an error naming the unknown column, got %v", err)
+	}
+}
+
+func TestPrintAccountHistory_RunningBalance(t *testing.T) {
+	history := &client.HistoryResponse{}
+	history.Data.Transactions = []client.AccountTransaction{
+		{ID: "t1", FlowDirection: "INCOME", TransactionDate: 1717200000000, TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 500}},
+		{ID: "t2", FlowDirection: "EXPENSE", TransactionDate: 1717300000000, TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 200}},
+	}
+
+	var buf bytes.Buffer
+	o := New(&buf, io.Discard)
+	o.RunningBalance = func(id string, date time.Time) (float64, bool) {
+		if id == "t1" && date.Equal(time.UnixMilli(1717200000000)) {
+			return 1300, true
+		}
+		return 0, false
+	}
+	o.PrintAccountHistory(history, false)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and two rows, got:\n%s", buf.String())
+	}
+	if got := strings.Fields(lines[0]); strings.Join(got, ",") != "DATE,TYPE,AMOUNT,BALANCE,BENEFICIARY,DETAILS" {
+		t.Errorf("balance column should follow the amount, got header %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "+500.00 AMD  1300.00") {
+		t.Errorf("expected balance after t1, got %q", lines[1])
+	}
+	if strings.Contains(lines[2], "1300.00") {
+		t.Errorf("unknown balance should be left empty, got %q", lines[2])
+	}
+}
+
+func TestPrintTxnTotals(t *testing.T) {
+	rows := []txnRow{
+		{Direction: client.DirectionCredit, Amount: 1000, Currency: "AMD"},
+		{Amount: 250, Currency: "AMD"},
+		{Amount: 10, Currency: "USD"},
+		{Amount: 5, Currency: "USD", Pending: true},
+	}
+
+	var buf bytes.Buffer
+	Std().printTxnTotals(&buf, rows)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per currency, got:\n%s", buf.String())
+	}
+	if got := strings.Join(strings.Fields(lines[0]), " "); got != "AMD debits -250.00 credits +1000.00 net +750.00" {
+		t.Errorf("unexpected AMD totals: %q", got)
+	}
+	// The pending transaction is left out
+	if got := strings.Join(strings.Fields(lines[1]), " "); got != "USD debits -10.00 credits +0.00 net -10.00" {
+		t.Errorf("unexpected USD totals: %q", got)
+	}
+}
+
+func TestPrintLedgerEntries_GroupBy(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2024, 6, d, h, 0, 0, 0, time.Local) }
+	entries := []db.LedgerEntry{
+		{ID: "t1", Date: day(1, 10), Amount: 100, Currency: "AMD", Credit: true},
+		{ID: "t2", Date: day(1, 12), Amount: 30, Currency: "AMD"},
+		{ID: "t3", Date: day(2, 9), Amount: 50, Currency: "AMD"},
+	}
+
+	var buf bytes.Buffer
+	o := New(&buf, io.Discard)
+	o.GroupBy = GroupByDay
+	o.PrintLedgerEntries(entries)
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n")[1:] {
+		got = append(got, strings.Join(strings.Fields(line), " "))
+	}
+	want := []string{
+		"2024-06-01",
+		"2024-06-01 10:00 100.00 AMD",
+		"2024-06-01 12:00 -30.00 AMD",
+		"subtotal 70.00 AMD",
+		"2024-06-02",
+		"2024-06-02 09:00 -50.00 AMD",
+		"subtotal -50.00 AMD",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected grouped rows:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	o.GroupBy = "week"
+	if err := o.CheckGroupBy(); err == nil {
+		t.Error("expected an error for an unknown grouping")
+	}
+}
+
+func TestSortTxnRows(t *testing.T) {
+	rows := func() []txnRow {
+		return []txnRow{
+			{ID: "a", Date: "2024-06-02 10:00", Type: "p:", Amount: 50},
+			{ID: "b", Date: "2024-06-01 10:00", Type: "xfer:", Amount: 500, Direction: client.DirectionCredit},
+			{ID: "c", Date: "2024-06-03 10:00", Type: "p:", Amount: 200},
+		}
+	}
+	ids := func(rows []txnRow) string {
+		var s string
+		for _, r := range rows {
+			s += r.ID
+		}
+		return s
+	}
+	for _, tt := range []struct {
+		sortBy string
+		desc   bool
+		want   string
+	}{
+		{"", false, "abc"},
+		{"", true, "cab"},
+		{SortByDate, false, "bac"},
+		{SortByAmount, false, "cab"},
+		{SortByAmount, true, "bac"},
+		{SortByType, false, "acb"},
+	} {
+		o := &Output{SortBy: tt.sortBy, SortDesc: tt.desc}
+		r := rows()
+		o.sortTxnRows(r)
+		if got := ids(r); got != tt.want {
+			t.Errorf("--sort %q desc=%v: expected %s, got %s", tt.sortBy, tt.desc, tt.want, got)
+		}
+	}
+
+	if err := (&Output{SortBy: "size"}).CheckSort(); err == nil {
+		t.Error("expected an error for an unknown sort key")
+	}
+}
+
+func TestCardTransactionsJSON(t *testing.T) {
+	txns := &client.TransactionsResponse{}
+	txns.Data.TotalCount = 3
+	txns.Data.Entries = []client.Transaction{
+		{ID: "t1", AccountingType: "CREDIT", OperationDate: "2024-01-15T10:30:00+04:00",
+			Amount: client.Amount{Currency: "USD", Amount: 1000},
+			Extended: &client.TransactionExtendedInfo{Swift: &client.SwiftInfo{SenderBank: "Example Bank NA"}}},
+		{ID: "t2", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 5000}, Extended: &client.TransactionExtendedInfo{}},
+	}
+
+	var buf bytes.Buffer
+	o := New(&buf, io.Discard)
+	if err := o.PrintJSON(o.CardTransactionsJSON(txns)); err != nil {
+		t.Fatalf("PrintJSON: %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if got["schema_version"] != float64(SchemaVersion) || got["has_more"] != true {
+		t.Errorf("unexpected list fields: %v", got)
+	}
+	list := got["transactions"].([]interface{})
+	first, second := list[0].(map[string]interface{}), list[1].(map[string]interface{})
+	if first["date"] != "2024-01-15T10:30:00+04:00" || first["direction"] != "credit" || first["signed_amount"] != float64(1000) {
+		t.Errorf("unexpected credit: %v", first)
+	}
+	if swift := first["swift"].(map[string]interface{}); swift["sender_bank"] != "Example Bank NA" {
+		t.Errorf("unexpected SWIFT details: %v", swift)
+	}
+	if second["amount"] != float64(5000) || second["signed_amount"] != float64(-5000) {
+		t.Errorf("unexpected debit amounts: %v", second)
+	}
+	for _, key := range []string{"date", "swift", "counterparty", "Extended"} {
+		if _, ok := second[key]; ok {
+			t.Errorf("empty field %q should be left out: %v", key, second)
+		}
+	}
+
+	buf.Reset()
+	o.PrintJSON(o.LedgerJSON(nil))
+	if !strings.Contains(buf.String(), `"transactions": []`) {
+		t.Errorf("an empty list should have an empty transactions array, got:\n%s", buf.String())
+	}
+}
+
+func TestRelativeDate(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+	for _, tt := range []struct {
+		date, want string
+	}{
+		{"2024-06-10 09:15", "today 09:15"},
+		{"2024-06-09 23:59", "yesterday"},
+		{"2024-06-07 10:00", "3 days ago"},
+		{"2024-06-03 10:00", "2024-06-03 10:00"},
+		{"2024-06-10 18:00", "2024-06-10 18:00"},
+		{"2024-06-11 10:00", "2024-06-11 10:00"},
+		{"pending", "pending"},
+	} {
+		if got := relativeDate(tt.date, now); got != tt.want {
+			t.Errorf("relativeDate(%q) = %q, want %q", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayNumber(t *testing.T) {
+	for _, tt := range []struct {
+		mode, number, want string
+	}{
+		{NumbersMasked, "4454********6615", "4454********6615"},
+		{NumbersLast4, "4454********6615", "****6615"},
+		{NumbersLast4, "1570012345678900", "****8900"},
+		{NumbersHidden, "1570012345678900", "****"},
+		{NumbersHidden, "", ""},
+	} {
+		o := &Output{Numbers: tt.mode}
+		if got := o.DisplayNumber(tt.number); got != tt.want {
+			t.Errorf("%s: DisplayNumber(%q) = %q, want %q", tt.mode, tt.number, got, tt.want)
+		}
+	}
+
+	resp := &client.AccountsAndCardsResponse{}
+	resp.Data.AccountsAndCards = []client.ProductInfo{
+		{ID: "1", ProductType: "ACCOUNT", AccountNumber: "1570012345678900", Name: "Savings"},
+	}
+	var buf bytes.Buffer
+	o := New(&buf, io.Discard)
+	o.Numbers = NumbersHidden
+	o.PrintAccountsAndCards(resp)
+	if strings.Contains(buf.String(), "8900") {
+		t.Errorf("hidden numbers should not be printed, got:\n%s", buf.String())
+	}
+	if p := o.ProductsJSON(resp.Data.AccountsAndCards).Products[0]; p.Number != "****" {
+		t.Errorf("expected a hidden number in JSON, got %q", p.Number)
+	}
+
+	o.Numbers = "partial"
+	if err := o.CheckNumbers(); err == nil {
+		t.Error("expected an error for an unknown number display mode")
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	for _, tt := range []struct {
+		values []float64
+		want   string
+	}{
+		{nil, ""},
+		{[]float64{100}, ""},
+		{[]float64{0, 70, 35, 10}, "▁█▅▂"},
+		{[]float64{5, 5, 5}, "▁▁▁"},
+		{[]float64{-10, 10}, "▁█"},
+	} {
+		if got := Sparkline(tt.values); got != tt.want {
+			t.Errorf("Sparkline(%v) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+
+	resp := &client.AccountsAndCardsResponse{}
+	resp.Data.AccountsAndCards = []client.ProductInfo{{ID: "1", ProductType: "ACCOUNT", Name: "Savings"}, {ID: "2", ProductType: "CARD", Name: "Card"}}
+	var buf bytes.Buffer
+	o := New(&buf, io.Discard)
+	o.BalanceTrends = map[string][]float64{"1": {100, 200}}
+	o.PrintAccountsAndCards(resp)
+	if !strings.Contains(buf.String(), "TREND") || !strings.Contains(buf.String(), "▁█") {
+		t.Errorf("expected a trend column, got:\n%s", buf.String())
+	}
+}
+
+func TestAlignColumns(t *testing.T) {
+	// ASCII text is laid out exactly like text/tabwriter does
+	lines := []string{"DATE\tTYPE\tAMOUNT", "2024-01-15\tp:\t-100.00 AMD", "\t\t", "x\ty", "long cell here\ta\tb\tc", "Total\t\t5.00\t"}
+	for _, alignRight := range []bool{false, true} {
+		var flags uint
+		if alignRight {
+			flags = tabwriter.AlignRight
+		}
+		var buf bytes.Buffer
+		tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', flags)
+		for _, l := range lines {
+			fmt.Fprintln(tw, l)
+		}
+		tw.Flush()
+		want := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		got := alignColumns(lines, alignRight)
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("alignRight=%v: got\n%s\nwant\n%s", alignRight, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+
+	// Armenian and wide CJK text keep the following columns aligned
+	got := alignColumns([]string{"Երևան Սիթի\tA", "東京\tB", "Cafe\tC"}, false)
+	for _, l := range got {
+		if i := textwidth.Width(l[:strings.LastIndex(l, " ")+1]); i != 12 {
+			t.Errorf("second column of %q starts at cell %d, want 12", l, i)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		v      float64
+		locale string
+		want   string
+	}{
+		{150000, "", "150000.00"},
+		{150000, "en", "150,000.00"},
+		{150000, "en_US", "150,000.00"},
+		{150000, "hy", "150 000,00"},
+		{-1234567.891, "ru_RU", "-1 234 567,89"},
+		{150000, "de", "150.000,00"},
+		{150000, "de_CH", "150'000.00"},
+		{999.999, "en", "1,000.00"},
+		{12.5, "hy", "12,50"},
+		{-0.001, "en", "0.00"},
+	}
+	for _, tt := range tests {
+		if got := formatAmount(tt.v, tt.locale); got != tt.want {
+			t.Errorf("formatAmount(%v, %q) = %q, want %q", tt.v, tt.locale, got, tt.want)
+		}
+	}
+
+	if got := (&Output{Locale: "hy"}).formatSigned("+", 150000); got != "+150 000,00" {
+		t.Errorf("formatSigned = %q", got)
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		2 * time.Hour:              "2h",
+		90 * time.Minute:           "1h30m",
+		45 * time.Minute:           "45m",
+		7 * 24 * time.Hour:         "7d",
+		36 * time.Hour:             "36h",
+		time.Hour + 30*time.Second: "1h0m30s",
+	} {
+		if got := FormatInterval(d); got != want {
+			t.Errorf("FormatInterval(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestPrintAccountHistory_Convert(t *testing.T) {
+	history := &client.HistoryResponse{}
+	history.Data.Transactions = []client.AccountTransaction{
+		{ID: "t1", FlowDirection: "EXPENSE", TransactionDate: 1717200000000, TransactionAmount: client.TransactionAmt{Currency: "USD", Value: 10}},
+		{ID: "t2", FlowDirection: "INCOME", TransactionDate: 1717300000000, TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 200}},
+	}
+
+	var buf, totals bytes.Buffer
+	o := New(&buf, &totals)
+	o.ConvertTo = "AMD"
+	o.Convert = func(amount float64, currency string, at time.Time) (float64, bool) {
+		return amount * 400, currency == "USD"
+	}
+	o.PrintAccountHistory(history, false)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and two rows, got:\n%s", buf.String())
+	}
+	if got := strings.Fields(lines[0]); strings.Join(got, ",") != "DATE,TYPE,AMOUNT,ORIGINAL,BENEFICIARY,DETAILS" {
+		t.Errorf("original column should follow the amount, got header %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "-4000.00 AMD  -10.00 USD") {
+		t.Errorf("expected converted amount and original, got %q", lines[1])
+	}
+	if !strings.Contains(totals.String(), "debits -4000.00") {
+		t.Errorf("expected totals in the converted currency, got:\n%s", totals.String())
+	}
+
+	list := o.AccountHistoryJSON(history)
+	if jt := list.Transactions[0]; jt.Amount != 4000 || jt.SignedAmount != -4000 || jt.Currency != "AMD" ||
+		jt.OriginalAmount != 10 || jt.OriginalCurrency != "USD" {
+		t.Errorf("unexpected converted JSON transaction: %+v", jt)
+	}
+}
+
+func TestPrintCardTransactions_Correspondent(t *testing.T) {
+	txns := &client.TransactionsResponse{}
+	txns.Data.Entries = []client.Transaction{
+		{
+			ID: "t1", TransactionType: "purchase:pos", AccountingType: "DEBIT", State: "SETTLED",
+			Amount: client.Amount{Currency: "AMD", Amount: 1500}, Details: "POS purchase",
+			OperationDate: "2024-06-01T10:00:00Z", CorrespondentAccountName: "YEREVAN CITY",
+			CorrespondentAccountNumber: "1570012345678900",
+		},
+	}
+
+	var buf bytes.Buffer
+	New(&buf, io.Discard).PrintCardTransactions(txns, false, false)
+	if strings.Contains(buf.String(), "YEREVAN CITY") {
+		t.Errorf("correspondent should only be shown with -x or --wide, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	New(&buf, io.Discard).PrintCardTransactions(txns, false, true)
+	if !strings.Contains(buf.String(), "CORRESPONDENT ACCOUNT") || !strings.Contains(buf.String(), "YEREVAN CITY") {
+		t.Errorf("expected correspondent columns with --wide, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	o := New(&buf, io.Discard)
+	o.Columns = []string{"date", "correspondent"}
+	o.PrintCardTransactions(txns, false, false)
+	if !strings.Contains(buf.String(), "YEREVAN CITY") {
+		t.Errorf("expected the selected correspondent column, got:\n%s", buf.String())
+	}
+
+	list := o.CardTransactionsJSON(txns)
+	if jt := list.Transactions[0]; jt.Correspondent != "YEREVAN CITY" || jt.CorrespondentAccount == "" {
+		t.Errorf("expected correspondent fields in JSON, got %+v", jt)
+	}
+}
+
+func TestChart(t *testing.T) {
+	lines := Chart([]float64{10, 20, 15, 30}, 2)
+	want := []string{
+		" ▁ █",
+		"▁█▅█",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("unexpected chart:\n got %q\nwant %q", lines, want)
+	}
+	if lines := Chart([]float64{5, 5}, 2); !reflect.DeepEqual(lines, []string{"  ", "██"}) {
+		t.Errorf("expected a flat chart drawn halfway, got %q", lines)
+	}
+	if got := resample(10, 4); !reflect.DeepEqual(got, []int{1, 4, 6, 9}) {
+		t.Errorf("unexpected resampled indexes %v", got)
+	}
+}
+
+func TestPrintNetWorthHistory(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 6, d, 12, 0, 0, 0, time.UTC) }
+	points := []report.NetWorthPoint{
+		{Date: day(1), Total: 1000},
+		{Date: day(2), Total: 1500},
+		{Date: day(3), Total: 900, Missing: []string{"EUR"}},
+	}
+	var buf bytes.Buffer
+	New(&buf, io.Discard).PrintNetWorthHistory(points, "AMD", 60, 2)
+	want := "1500.00 ┤ █ \n" +
+		" 900.00 ┤▄█▁\n" +
+		"        └───\n" +
+		"         2024-06-01\n" +
+		"\n" +
+		"Net worth: 900.00 AMD (-100.00 since 2024-06-01)\n" +
+		"Left out for lack of an exchange rate: EUR\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected chart:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	o := New(&buf, io.Discard)
+	o.Format = FormatCSV
+	o.PrintNetWorthHistory(points, "AMD", 60, 2)
+	if got := buf.String(); !strings.HasPrefix(got, "DATE,NET WORTH,CURRENCY\n2024-06-01,1000.00,AMD\n") {
+		t.Errorf("unexpected CSV:\n%s", got)
+	}
+}
+
+func TestPrintBudgets(t *testing.T) {
+	month := func(m time.Month) time.Time { return time.Date(2024, m, 1, 0, 0, 0, 0, time.UTC) }
+	budgets := []report.Budget{{
+		Rule: db.AlertRule{ID: 3, Kind: db.AlertBudget, Threshold: 1000, Currency: "AMD", Category: "groceries", Rollover: true},
+		Months: []db.BudgetMonth{
+			{Month: month(1), Budget: 1000, Spent: 600},
+			{Month: month(2), Budget: 1000, Carried: 400, Spent: 1500},
+		},
+	}}
+	var buf bytes.Buffer
+	New(&buf, io.Discard).PrintBudgets(budgets)
+	want := "Budget #3: 1000.00 AMD, groceries, rollover:\n" +
+		"MONTH    BUDGET   CARRIED  SPENT    LEFT\n" +
+		"2024-01  1000.00  0.00     600.00   400.00\n" +
+		"2024-02  1000.00  400.00   1500.00  -100.00\n" +
+		"\n" +
+		"Over budget in 1 of 2 months\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected budgets:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	o := New(&buf, io.Discard)
+	o.Format = FormatCSV
+	o.PrintBudgets(budgets)
+	want = "RULE,CATEGORY,CURRENCY,MONTH,BUDGET,CARRIED,SPENT,LEFT\n" +
+		"3,groceries,AMD,2024-01,1000.00,0.00,600.00,400.00\n" +
+		"3,groceries,AMD,2024-02,1000.00,400.00,1500.00,-100.00\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected CSV:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPrintIncome(t *testing.T) {
+	r := report.IncomeReport{
+		Sources: []report.IncomeSource{{Counterparty: "Employer LLC", Currency: "AMD", Amount: 500000, Count: 3, LastDate: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)}},
+		Months: []report.MonthSavings{
+			{Month: "2024-02", Currency: "AMD", Income: 500000, Salary: 500000, Expenses: 400000},
+			{Month: "2024-03", Currency: "AMD", Income: 500000, Salary: 500000, Expenses: 600000},
+		},
+	}
+	var buf bytes.Buffer
+	New(&buf, io.Discard).PrintIncome(r)
+	want := "Salary-like income:\n" +
+		"COUNTERPARTY  AMOUNT     CURRENCY  COUNT  LAST\n" +
+		"Employer LLC  500000.00  AMD       3      2024-03-10\n" +
+		"\n" +
+		"Savings:\n" +
+		"MONTH    CURRENCY  INCOME     SALARY     EXPENSES   SAVINGS     RATE\n" +
+		"2024-02  AMD       500000.00  500000.00  400000.00  100000.00   20%\n" +
+		"2024-03  AMD       500000.00  500000.00  600000.00  -100000.00  -20%\n" +
+		"\n" +
+		"Savings rate in AMD: 0% on average  █▁\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected income:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	o := New(&buf, io.Discard)
+	o.Format = FormatCSV
+	o.PrintIncome(r)
+	want = "MONTH,CURRENCY,INCOME,SALARY,EXPENSES,SAVINGS,RATE\n" +
+		"2024-02,AMD,500000.00,500000.00,400000.00,100000.00,20%\n" +
+		"2024-03,AMD,500000.00,500000.00,600000.00,-100000.00,-20%\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected CSV:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPrintCurrencyPositions_TotalMatchesValues(t *testing.T) {
+	positions := []db.CurrencyPosition{{Currency: "EUR", Accounts: 1}, {Currency: "USD", Accounts: 1}}
+	rates := db.ExchangeRates{"EUR": 450.004, "USD": 400.004}
+	var buf bytes.Buffer
+	New(&buf, io.Discard).PrintCurrencyPositions(positions, rates, "AMD")
+	// The total is the sum of the values shown, not of the unrounded ones
+	if !strings.Contains(buf.String(), "450.00") || !strings.Contains(buf.String(), "850.00") {
+		t.Errorf("expected a total of 850.00, got:\n%s", buf.String())
+	}
+}
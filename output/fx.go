@@ -0,0 +1,36 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/i18n"
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// PrintCurrencyPositions prints holdings per currency with their value in the base currency
+func (o *Output) PrintCurrencyPositions(positions []db.CurrencyPosition, rates db.ExchangeRates, base string) {
+	w := o.newTable(true)
+	w.header("CURRENCY\tACCOUNTS\tDEPOSITS\tLOANS\tNET\t" + fmt.Sprintf(i18n.T("IN %s"), base) + "\t")
+
+	var total money.Amount
+	var missing []string
+	for _, p := range positions {
+		value := "?"
+		if v, ok := rates.Convert(p.Net(), p.Currency, base); ok {
+			value = o.FormatAmount(v)
+			total += money.FromFloat(v)
+		} else {
+			missing = append(missing, p.Currency)
+		}
+		w.row(rowStyle{}, "%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			p.Currency, o.FormatAmount(p.Accounts), o.FormatAmount(p.Deposits), o.FormatAmount(p.Loans), o.FormatAmount(p.Net()), value)
+	}
+	w.row(rowStyle{style: ansiBold}, "%s\t\t\t\t\t%s\t\n", i18n.T("TOTAL"), o.FormatAmount(total.Float()))
+	w.flush()
+
+	if len(missing) > 0 {
+		fmt.Fprintf(o.Out, i18n.T("Missing exchange rates for %s (set with --rate), excluded from total")+"\n", strings.Join(missing, ", "))
+	}
+}
@@ -0,0 +1,59 @@
+package output
+
+import (
+	"math"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/i18n"
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// PrintCardHolds prints pending card authorizations in human-readable table format
+func (o *Output) PrintCardHolds(holds []client.Transaction, wide bool) {
+	w := o.newTable(false)
+	w.header("DATE\tSTATUS\tAMOUNT\tDETAILS")
+	for _, h := range holds {
+		date := h.OperationDate
+		if parsed, err := db.ParseDate(h.OperationDate); err == nil {
+			date = parsed.Local().Format("2006-01-02 15:04")
+		}
+		details := h.Details
+		if !wide {
+			details = TruncateString(details, 50)
+		}
+		w.row(rowStyle{}, "%s\tPENDING\t%s %s\t%s\n", date, o.formatSigned(h.Direction().Sign(), h.Amount.Amount), h.Amount.Currency, details)
+	}
+	w.flush()
+}
+
+// HoldsTotal returns the net amount of pending holds in the given currency
+// (negative for debits) and the number of holds in other currencies
+func HoldsTotal(holds []client.Transaction, currency string) (total float64, other int) {
+	for _, h := range holds {
+		if h.Amount.Currency != currency {
+			other++
+			continue
+		}
+		total = money.Add(total, h.SignedAmount())
+	}
+	return total, other
+}
+
+// PrintAvailableBalance explains a product's available balance as its balance
+// adjusted by pending holds and whatever else the bank reserves
+func (o *Output) PrintAvailableBalance(p client.ProductInfo, holds []client.Transaction) {
+	pending, other := HoldsTotal(holds, p.Currency)
+	w := o.newTable(true)
+	w.row(rowStyle{}, "%s:\t%s %s\t\n", i18n.T("Balance"), o.FormatAmount(p.Balance), p.Currency)
+	w.row(rowStyle{}, "%s:\t%s %s\t\n", i18n.T("Pending holds"), o.FormatAmount(pending), p.Currency)
+	// Blocked amounts, credit limits and holds in other currencies
+	if rest := money.Sub(money.Sub(p.AvailableBalance, p.Balance), pending); math.Abs(rest) >= 0.005 {
+		w.row(rowStyle{}, "%s:\t%s %s\t\n", i18n.T("Other"), o.FormatAmount(rest), p.Currency)
+	}
+	w.row(rowStyle{}, "%s:\t%s %s\t\n", i18n.T("Available"), o.FormatAmount(p.AvailableBalance), p.Currency)
+	w.flush()
+	if other > 0 {
+		o.printText("(%d pending holds in other currencies are included in Other)", other)
+	}
+}
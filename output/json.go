@@ -0,0 +1,233 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/product"
+)
+
+// SchemaVersion is the version of the --json output schema. It only changes
+// on incompatible changes; fields may be added within a version. Dates are
+// RFC 3339 (ISO 8601) strings, and empty optional fields are left out.
+const SchemaVersion = 1
+
+// JSONTransaction is a transaction in --json output
+type JSONTransaction struct {
+	ID                   string           `json:"id"`
+	ProductID            string           `json:"product_id,omitempty"`
+	Source               string           `json:"source,omitempty"` // card, linked or account; only for get --new
+	Date                 string           `json:"date,omitempty"`
+	Type                 string           `json:"type,omitempty"`
+	State                string           `json:"state,omitempty"`
+	Direction            client.Direction `json:"direction"`
+	Amount               float64          `json:"amount"` // never negative
+	SignedAmount         float64          `json:"signed_amount"`
+	Currency             string           `json:"currency"`
+	Details              string           `json:"details,omitempty"`
+	Counterparty         string           `json:"counterparty,omitempty"`
+	CounterpartyAccount  string           `json:"counterparty_account,omitempty"`
+	Card                 string           `json:"card,omitempty"` // masked card number
+	OperationID          string           `json:"operation_id,omitempty"`
+	Correspondent        string           `json:"correspondent,omitempty"` // card transactions: correspondent account name
+	CorrespondentAccount string           `json:"correspondent_account,omitempty"`
+	Swift                *JSONSwift       `json:"swift,omitempty"`
+	AuthorizationID      string           `json:"authorization_id,omitempty"` // settled card purchase: its pre-purchase authorization
+	SettlementID         string           `json:"settlement_id,omitempty"`    // card authorization: the purchase settling it
+	OriginalAmount       float64          `json:"original_amount,omitempty"`  // before conversion (--convert), never negative
+	OriginalCurrency     string           `json:"original_currency,omitempty"`
+}
+
+// convert converts the amounts of the transaction at the given time to
+// ConvertTo, keeping the original ones
+func (jt *JSONTransaction) convert(o *Output, at time.Time) {
+	amount, currency, ok := o.convertAmount(jt.Amount, jt.Currency, at)
+	if !ok {
+		return
+	}
+	jt.OriginalAmount, jt.OriginalCurrency = jt.Amount, jt.Currency
+	jt.Amount, jt.Currency = amount, currency
+	jt.SignedAmount = jt.Direction.Signed(amount)
+}
+
+// JSONSwift holds the SWIFT details of an international transfer
+type JSONSwift struct {
+	SenderBank   string `json:"sender_bank,omitempty"`
+	Reference    string `json:"reference,omitempty"`
+	Fees         string `json:"fees,omitempty"`
+	Intermediary string `json:"intermediary,omitempty"`
+}
+
+// JSONTransactionList is the --json output of get
+type JSONTransactionList struct {
+	SchemaVersion int               `json:"schema_version"`
+	TotalCount    int               `json:"total_count"`
+	HasMore       bool              `json:"has_more,omitempty"`
+	Transactions  []JSONTransaction `json:"transactions"`
+}
+
+// JSONProduct is a card or account in --json output
+type JSONProduct struct {
+	ID               string  `json:"id"`
+	Type             string  `json:"type"` // card, account or goal
+	Name             string  `json:"name"`
+	Number           string  `json:"number,omitempty"`            // card or account number, shown per the numbers setting
+	LinkedAccountID  string  `json:"linked_account_id,omitempty"` // cards: linked account; goals: account funding them
+	Currency         string  `json:"currency"`
+	Balance          float64 `json:"balance"`
+	AvailableBalance float64 `json:"available_balance"`
+	Status           string  `json:"status,omitempty"`
+	ClientID         string  `json:"client_id,omitempty"`
+}
+
+// JSONProductList is the --json output of list
+type JSONProductList struct {
+	SchemaVersion int           `json:"schema_version"`
+	Products      []JSONProduct `json:"products"`
+}
+
+// jsonDate formats a time as RFC 3339, or returns "" for the zero time
+func jsonDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// CardTransactionsJSON converts card or linked account transactions
+func (o *Output) CardTransactionsJSON(resp *client.TransactionsResponse) JSONTransactionList {
+	list := JSONTransactionList{SchemaVersion: SchemaVersion, TotalCount: resp.Data.TotalCount, Transactions: []JSONTransaction{}}
+	for _, t := range resp.Data.Entries {
+		jt := JSONTransaction{
+			ID:                   t.ID,
+			Type:                 t.TransactionType,
+			State:                t.State,
+			Direction:            t.Direction(),
+			Amount:               t.Amount.Amount,
+			SignedAmount:         t.SignedAmount(),
+			Currency:             t.Amount.Currency,
+			Details:              t.Details,
+			AuthorizationID:      t.AuthorizationID,
+			SettlementID:         t.SettlementID,
+			Correspondent:        t.CorrespondentAccountName,
+			CorrespondentAccount: o.DisplayNumber(t.CorrespondentAccountNumber),
+		}
+		if parsed, err := db.ParseDate(t.OperationDate); err == nil {
+			jt.Date = jsonDate(parsed)
+			jt.convert(o, parsed)
+		}
+		if ext := t.Extended; ext != nil {
+			jt.Counterparty = ext.BeneficiaryName
+			jt.CounterpartyAccount = o.DisplayNumber(ext.CreditAccountNumber)
+			jt.Card = o.DisplayNumber(ext.CardMaskedNumber)
+			jt.OperationID = ext.OperationID
+			if ext.Swift != nil && !ext.Swift.Empty() {
+				jt.Swift = &JSONSwift{
+					SenderBank:   ext.Swift.SenderBank,
+					Reference:    ext.Swift.Reference,
+					Fees:         ext.Swift.Fees,
+					Intermediary: ext.Swift.Intermediary,
+				}
+			}
+		}
+		list.Transactions = append(list.Transactions, jt)
+	}
+	list.HasMore = len(list.Transactions) < list.TotalCount
+	return list
+}
+
+// AccountHistoryJSON converts account history transactions
+func (o *Output) AccountHistoryJSON(history *client.HistoryResponse) JSONTransactionList {
+	list := JSONTransactionList{SchemaVersion: SchemaVersion, HasMore: history.Data.HasNext, Transactions: []JSONTransaction{}}
+	for _, t := range history.Data.Transactions {
+		jt := JSONTransaction{
+			ID:                  t.ID,
+			Type:                t.TransactionType,
+			State:               t.Status,
+			Direction:           t.Direction(),
+			Amount:              t.TransactionAmount.Value,
+			SignedAmount:        t.SignedAmount(),
+			Currency:            t.TransactionAmount.Currency,
+			Details:             t.Details,
+			Counterparty:        t.BeneficiaryName,
+			CounterpartyAccount: o.DisplayNumber(t.CounterpartyAccount()),
+			OperationID:         t.OperationID,
+		}
+		if t.TransactionDate > 0 {
+			jt.Date = jsonDate(time.UnixMilli(t.TransactionDate))
+			jt.convert(o, time.UnixMilli(t.TransactionDate))
+		}
+		list.Transactions = append(list.Transactions, jt)
+	}
+	list.TotalCount = len(list.Transactions)
+	return list
+}
+
+// LedgerJSON converts normalized transactions
+func (o *Output) LedgerJSON(entries []db.LedgerEntry) JSONTransactionList {
+	list := JSONTransactionList{SchemaVersion: SchemaVersion, TotalCount: len(entries), Transactions: []JSONTransaction{}}
+	for _, e := range entries {
+		jt := JSONTransaction{
+			ID:               e.ID,
+			ProductID:        e.ProductID,
+			Source:           e.Source,
+			Date:             jsonDate(e.Date),
+			Type:             e.Type,
+			Direction:        e.Direction(),
+			Amount:           e.Amount,
+			SignedAmount:     e.SignedAmount(),
+			Currency:         e.Currency,
+			Details:          e.Details,
+			Counterparty:     e.Counterparty,
+			OriginalAmount:   e.OriginalAmount,
+			OriginalCurrency: e.OriginalCurrency,
+		}
+		if e.OriginalCurrency == "" {
+			jt.convert(o, e.Date)
+		}
+		list.Transactions = append(list.Transactions, jt)
+	}
+	return list
+}
+
+// ProductsJSON converts cards and accounts
+func (o *Output) ProductsJSON(products []client.ProductInfo) JSONProductList {
+	list := JSONProductList{SchemaVersion: SchemaVersion, Products: []JSONProduct{}}
+	for _, p := range products {
+		jp := JSONProduct{
+			ID:               p.ID,
+			Type:             "account",
+			Name:             p.Name,
+			Number:           o.DisplayNumber(p.AccountNumber),
+			Currency:         p.Currency,
+			Balance:          p.Balance,
+			AvailableBalance: p.AvailableBalance,
+			Status:           p.Status,
+			ClientID:         p.ClientID,
+		}
+		switch {
+		case product.IsCard(p):
+			jp.Type = "card"
+			jp.Number = o.DisplayNumber(p.CardNumber)
+			jp.LinkedAccountID = p.AccountID
+		case product.IsGoal(p):
+			jp.Type = "goal"
+			jp.LinkedAccountID = p.AccountID
+		}
+		list.Products = append(list.Products, jp)
+	}
+	return list
+}
+
+// PrintJSON writes v as indented JSON
+func (o *Output) PrintJSON(v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling JSON: %w", err)
+	}
+	fmt.Fprintln(o.Out, string(out))
+	return nil
+}
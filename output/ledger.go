@@ -0,0 +1,37 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// PrintLedgerEntries prints normalized transactions in human-readable table format
+func (o *Output) PrintLedgerEntries(entries []db.LedgerEntry) {
+	var rows []txnRow
+	for _, e := range entries {
+		r := txnRow{
+			ID:           e.ID,
+			Date:         e.Date.Format("2006-01-02 15:04"),
+			Type:         e.Type,
+			Direction:    e.Direction(),
+			Amount:       e.Amount,
+			Currency:     e.Currency,
+			Details:      e.Details,
+			Workflow:     e.Workflow,
+			Counterparty: e.Counterparty,
+			Product:      e.ProductID,
+		}
+		if e.OriginalCurrency != "" {
+			r.Original = o.formatSigned(r.Direction.Sign(), e.OriginalAmount) + " " + e.OriginalCurrency
+		} else {
+			r.convert(o, e.Date)
+		}
+		rows = append(rows, r)
+	}
+	o.printTxnTable(rows, []string{"date", "product", "value", "currency", "details"}, false)
+	if len(rows) > 0 {
+		fmt.Fprintln(o.Err)
+		o.printTxnTotals(o.Err, rows)
+	}
+}
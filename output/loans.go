@@ -0,0 +1,127 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/i18n"
+)
+
+// PrintLoans prints loans in human-readable table format
+func (o *Output) PrintLoans(loans []client.LoanInfo) {
+	w := o.newTable(false)
+	w.header("ID\tNAME\tCURRENCY\tPRINCIPAL\tOUTSTANDING\tRATE\tNEXT PAYMENT\tAMOUNT\tMATURITY")
+	for _, l := range loans {
+		w.row(rowStyle{}, "%s\t%s\t%s\t%s\t%s\t%.2f%%\t%s\t%s\t%s\n",
+			l.ID, TruncateString(l.Name, 30), l.Currency, o.FormatAmount(l.Amount), o.FormatAmount(l.OutstandingAmount), l.InterestRate,
+			orDash(l.NextPaymentDate), o.FormatAmount(l.NextPaymentAmount), orDash(l.MaturityDate))
+	}
+	w.flush()
+}
+
+// PrintDeposits prints deposits in human-readable table format
+func (o *Output) PrintDeposits(deposits []client.DepositInfo) {
+	w := o.newTable(false)
+	w.header("ID\tNAME\tCURRENCY\tPRINCIPAL\tRATE\tACCRUED\tNEXT INTEREST\tAMOUNT\tMATURITY")
+	for _, d := range deposits {
+		w.row(rowStyle{}, "%s\t%s\t%s\t%s\t%.2f%%\t%s\t%s\t%s\t%s\n",
+			d.ID, TruncateString(d.Name, 30), d.Currency, o.FormatAmount(d.Amount), d.InterestRate, o.FormatAmount(d.AccruedInterest),
+			orDash(d.NextInterestPaymentDate), o.FormatAmount(d.NextInterestPaymentAmount), orDash(d.MaturityDate))
+	}
+	w.flush()
+}
+
+// PrintLoanTerms prints the terms of a loan one per line, leaving out unknown ones
+func (o *Output) PrintLoanTerms(l client.LoanDetails) {
+	overdue := ""
+	if l.OverdueAmount != 0 {
+		overdue = o.FormatAmount(l.OverdueAmount) + " " + l.Currency
+	}
+	o.printTerms([]term{
+		{"Opened", l.OpenDate},
+		{"Overdue", overdue},
+	})
+}
+
+// PrintDepositTerms prints the terms of a deposit one per line, leaving out unknown ones
+func (o *Output) PrintDepositTerms(d client.DepositDetails) {
+	months := ""
+	if d.TermMonths > 0 {
+		months = fmt.Sprintf("%d months", d.TermMonths)
+	}
+	o.printTerms([]term{
+		{"Opened", d.OpenDate},
+		{"Term", months},
+		{"Capitalization", yesNo(d.Capitalization)},
+		{"Replenishable", yesNo(d.Replenishable)},
+		{"Interest account", d.InterestAccountNumber},
+	})
+}
+
+// term is a labeled value printed by printTerms
+type term struct{ label, value string }
+
+// printTerms prints labeled values like PrintRequisites
+func (o *Output) printTerms(terms []term) {
+	w := o.newTable(false)
+	for _, t := range terms {
+		if t.value != "" {
+			w.row(rowStyle{}, "%s:\t%s\n", i18n.T(t.label), t.value)
+		}
+	}
+	w.flush()
+}
+
+// PrintLoanSchedule prints the past and scheduled payments of a loan
+func (o *Output) PrintLoanSchedule(schedule []client.LoanInstallment) {
+	w := o.newTable(false)
+	w.header("DATE\tPRINCIPAL\tINTEREST\tTOTAL\tPAID")
+	for _, p := range schedule {
+		w.row(paidStyle(p.Paid), "%s\t%s\t%s\t%s\t%s\n",
+			p.Date, o.FormatAmount(p.Principal), o.FormatAmount(p.Interest), o.FormatAmount(p.Total), paidMark(p.Paid))
+	}
+	w.flush()
+}
+
+// PrintDepositSchedule prints the past and scheduled interest payments of a deposit
+func (o *Output) PrintDepositSchedule(payments []client.InterestPayment) {
+	w := o.newTable(false)
+	w.header("DATE\tINTEREST\tTAX\tPAID")
+	for _, p := range payments {
+		w.row(paidStyle(p.Paid), "%s\t%s\t%s\t%s\n",
+			p.Date, o.FormatAmount(p.Amount), o.FormatAmount(p.Tax), paidMark(p.Paid))
+	}
+	w.flush()
+}
+
+// paidStyle dims the payments already made
+func paidStyle(paid bool) rowStyle {
+	if paid {
+		return rowStyle{style: ansiDim}
+	}
+	return rowStyle{}
+}
+
+// paidMark renders whether a scheduled payment was made
+func paidMark(paid bool) string {
+	if paid {
+		return "yes"
+	}
+	return "-"
+}
+
+// yesNo renders a flag of a product's terms
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// orDash returns s, or "-" if s is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
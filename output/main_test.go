@@ -0,0 +1,13 @@
+package output
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Dates are shown in local time; the expected output is in UTC
+	time.Local = time.UTC
+	os.Exit(m.Run())
+}
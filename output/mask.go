@@ -0,0 +1,33 @@
+package output
+
+import "fmt"
+
+// Card and account number display modes (numbers config setting, --numbers)
+const (
+	NumbersMasked = "masked" // as the bank sends them: cards masked, accounts in full
+	NumbersLast4  = "last4"  // only the last 4 digits, e.g. ****6615
+	NumbersHidden = "hidden" // no digits at all
+)
+
+// CheckNumbers returns an error if Numbers is not a known display mode
+func (o *Output) CheckNumbers() error {
+	switch o.Numbers {
+	case NumbersMasked, NumbersLast4, NumbersHidden:
+		return nil
+	}
+	return fmt.Errorf("invalid --numbers %q (expected %s, %s or %s)", o.Numbers, NumbersMasked, NumbersLast4, NumbersHidden)
+}
+
+// DisplayNumber renders a card or account number in the Numbers mode
+func (o *Output) DisplayNumber(number string) string {
+	if number == "" {
+		return ""
+	}
+	switch o.Numbers {
+	case NumbersLast4:
+		return shortenMaskedCard(number)
+	case NumbersHidden:
+		return "****"
+	}
+	return number
+}
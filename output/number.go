@@ -0,0 +1,67 @@
+package output
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Languages writing 150 000,00 and 150.000,00; all others write 150,000.00
+var (
+	spaceGroupLanguages = map[string]bool{
+		"hy": true, "ru": true, "uk": true, "be": true, "kk": true, "ka": true, "fr": true, "cs": true, "sk": true,
+		"pl": true, "sv": true, "fi": true, "nb": true, "no": true, "bg": true, "lt": true, "lv": true, "et": true, "hu": true,
+	}
+	dotGroupLanguages = map[string]bool{
+		"de": true, "it": true, "es": true, "nl": true, "pt": true, "tr": true, "id": true, "da": true, "el": true,
+		"ro": true, "hr": true, "sl": true, "sr": true, "az": true,
+	}
+)
+
+// numberSeparators returns the thousands and decimal separators of a locale
+func numberSeparators(locale string) (group, decimal string) {
+	lang, region, _ := strings.Cut(locale, "_")
+	switch {
+	case locale == "":
+		return "", "."
+	case region == "CH" && (lang == "de" || lang == "fr" || lang == "it"):
+		return "'", "."
+	case spaceGroupLanguages[lang]:
+		return " ", ","
+	case dotGroupLanguages[lang]:
+		return ".", ","
+	default:
+		return ",", "."
+	}
+}
+
+// FormatAmount formats v with two decimals using the separators of Locale
+func (o *Output) FormatAmount(v float64) string {
+	return formatAmount(v, o.Locale)
+}
+
+func formatAmount(v float64, locale string) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', 2, 64)
+	group, decimal := numberSeparators(locale)
+	intPart, frac, _ := strings.Cut(s, ".")
+	if group != "" {
+		var b strings.Builder
+		for i, d := range intPart {
+			if i > 0 && (len(intPart)-i)%3 == 0 {
+				b.WriteString(group)
+			}
+			b.WriteRune(d)
+		}
+		intPart = b.String()
+	}
+	sign := ""
+	if math.Signbit(v) && s != "0.00" {
+		sign = "-"
+	}
+	return sign + intPart + decimal + frac
+}
+
+// formatSigned formats an amount with an explicit + or - sign
+func (o *Output) formatSigned(sign string, v float64) string {
+	return sign + o.FormatAmount(math.Abs(v))
+}
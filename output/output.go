@@ -0,0 +1,81 @@
+package output
+
+import (
+	"io"
+	"os"
+)
+
+// Output prints tables and the text around them. Tables and text go to Out;
+// totals and notes that shouldn't end up in piped output go to Err. The other
+// fields are the rendering settings given by flags and config.
+type Output struct {
+	Out io.Writer
+	Err io.Writer
+
+	// Format is the format tables are printed in (--output)
+	Format string
+
+	// NoColor disables colored output even on a terminal (--no-color). Setting
+	// the NO_COLOR environment variable has the same effect.
+	NoColor bool
+
+	// Paged is set while Out is a pipe to a pager on a terminal, which shows colors
+	Paged bool
+
+	// Locale selects the thousands and decimal separators of amounts in table
+	// output (the locale config setting, e.g. en, hy or de_CH). Empty keeps
+	// plain numbers without thousands separators. JSON output is never affected.
+	Locale string
+
+	// Numbers is how card and account numbers are shown in tables, JSON and
+	// CSV. Requisites are always shown in full, as they're meant to be shared.
+	Numbers string
+
+	// Columns selects and orders the columns of transaction tables (--columns).
+	// Empty means the default columns of each table.
+	Columns []string
+
+	// Icons prefixes transaction table rows with the icon of their category
+	// (icons config setting), except in CSV
+	Icons bool
+
+	// RelativeDates shows recent dates in plain-text transaction tables as
+	// "today 14:32", "yesterday" or "3 days ago" (--relative-dates). Markdown,
+	// HTML, CSV and JSON keep absolute dates.
+	RelativeDates bool
+
+	// GroupBy splits transaction tables into groups of days or months with
+	// subtotals (--group-by). Empty means no grouping.
+	GroupBy string
+
+	// SortBy orders the rows of transaction tables at display time (--sort);
+	// empty keeps the order they were fetched in unless SortDesc is set
+	SortBy string
+
+	// SortDesc reverses the SortBy order, sorting by date if SortBy is empty (--desc)
+	SortDesc bool
+
+	// RunningBalance adds a balance column after the amount to transaction
+	// tables with default columns when set (get --balance)
+	RunningBalance BalanceLookupFunc
+
+	// ConvertTo is the currency transaction tables and JSON show amounts in
+	// when Convert is set (--convert); tables with default columns add the
+	// original amount after the amount
+	ConvertTo string
+	Convert   ConvertFunc
+
+	// BalanceTrends holds recent balances of products by ID, oldest first; when
+	// set, product tables get a trend column after the balance (list --trend)
+	BalanceTrends map[string][]float64
+}
+
+// New returns an Output writing to out and err with the default settings
+func New(out, err io.Writer) *Output {
+	return &Output{Out: out, Err: err, Format: FormatTable, Numbers: NumbersMasked}
+}
+
+// Std returns an Output writing to the current os.Stdout and os.Stderr
+func Std() *Output {
+	return New(os.Stdout, os.Stderr)
+}
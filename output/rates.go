@@ -0,0 +1,13 @@
+package output
+
+import "github.com/ivan4th/ameriagrab/client"
+
+// PrintExchangeRates prints exchange rates in human-readable table format
+func (o *Output) PrintExchangeRates(rates []client.ExchangeRate) {
+	w := o.newTable(false)
+	w.header("CURRENCY\tTYPE\tBUY\tSELL")
+	for _, r := range rates {
+		w.row(rowStyle{}, "%s\t%s\t%s\t%s\n", r.Currency, r.Type, o.FormatAmount(r.Buy), o.FormatAmount(r.Sell))
+	}
+	w.flush()
+}
@@ -0,0 +1,337 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/i18n"
+	"github.com/ivan4th/ameriagrab/money"
+	"github.com/ivan4th/ameriagrab/report"
+)
+
+// PrintDuplicateGroups prints likely double charges, one block per group
+func (o *Output) PrintDuplicateGroups(groups []report.DuplicateGroup) {
+	// Converted charges (--convert) show their original amounts, too
+	converted := false
+	for _, g := range groups {
+		for _, e := range g.Entries {
+			converted = converted || e.OriginalCurrency != ""
+		}
+	}
+
+	w := o.newTable(false)
+	if converted {
+		w.header("DATE\tPRODUCT\tSOURCE\tID\tAMOUNT\tORIGINAL\tCOUNTERPARTY")
+	} else {
+		w.header("DATE\tPRODUCT\tSOURCE\tID\tAMOUNT\tCOUNTERPARTY")
+	}
+	for i, g := range groups {
+		if i > 0 {
+			w.row(rowStyle{}, "\t\t\t\t\t\n")
+		}
+		for _, e := range g.Entries {
+			amount := o.FormatAmount(e.Amount) + " " + e.Currency
+			if converted {
+				var original string
+				if e.OriginalCurrency != "" {
+					original = o.FormatAmount(e.OriginalAmount) + " " + e.OriginalCurrency
+				}
+				amount += "\t" + original
+			}
+			w.row(rowStyle{}, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				e.Date.Format("2006-01-02 15:04"), e.ProductID, e.Source, e.ID, amount,
+				TruncateString(e.Counterparty, 40))
+		}
+	}
+	w.flush()
+
+	totals := make(map[string]float64)
+	var currencies []string
+	for _, g := range groups {
+		if _, ok := totals[g.Currency]; !ok {
+			currencies = append(currencies, g.Currency)
+		}
+		totals[g.Currency] = money.Add(totals[g.Currency], g.Extra())
+	}
+	summary := fmt.Sprintf(i18n.T("%d groups of likely duplicate charges, possible overcharge:"), len(groups))
+	for _, c := range currencies {
+		summary += fmt.Sprintf(" %s %s", o.FormatAmount(totals[c]), c)
+	}
+	fmt.Fprintln(o.Out)
+	o.printText("%s", summary)
+}
+
+// PrintPeriodStatement prints a period statement suitable for sending to an accountant
+func (o *Output) PrintPeriodStatement(st *report.PeriodStatement) {
+	lastDay := st.To.AddDate(0, 0, -1)
+	o.printText("Statement: %s (%s)", st.ProductName, st.ProductID)
+	o.printText("Period:    %s - %s", st.From.Format("2006-01-02"), lastDay.Format("2006-01-02"))
+	o.printText("Currency:  %s", st.Currency)
+	fmt.Fprintln(o.Out)
+
+	w := o.newTable(false)
+	w.row(rowStyle{style: ansiBold}, "%s\t%s\t%s\n", i18n.T("Opening balance"), st.From.Format("2006-01-02"), o.FormatAmount(st.OpeningBalance))
+	w.row(rowStyle{}, "\t\t\n")
+	w.header("DATE\tDETAILS\tAMOUNT")
+	var group string
+	var subtotal float64
+	for i, e := range st.Transactions {
+		date := e.Date.Format("2006-01-02 15:04")
+		// With --group-by, each day or month starts with a separator row
+		// and ends with its subtotal
+		if o.GroupBy != "" && (i == 0 || o.dateGroup(date) != group) {
+			if i > 0 {
+				w.row(rowStyle{style: ansiDim}, "%s\t\t%s\n", i18n.T("subtotal"), o.FormatAmount(subtotal))
+			}
+			group, subtotal = o.dateGroup(date), 0
+			w.row(rowStyle{style: ansiBold}, "%s\t\t\n", group)
+		}
+		subtotal = money.Add(subtotal, e.SignedAmount())
+
+		details := e.Counterparty
+		if e.Details != "" && e.Details != e.Counterparty {
+			details += " - " + e.Details
+		}
+		w.row(rowStyle{}, "%s\t%s\t%s\n", date, TruncateString(details, 60), o.FormatAmount(e.SignedAmount()))
+	}
+	if o.GroupBy != "" && len(st.Transactions) > 0 {
+		w.row(rowStyle{style: ansiDim}, "%s\t\t%s\n", i18n.T("subtotal"), o.FormatAmount(subtotal))
+	}
+	w.row(rowStyle{}, "\t\t\n")
+	w.row(rowStyle{}, "%s\t\t%s\n", i18n.T("Total credits"), o.FormatAmount(st.Credits))
+	w.row(rowStyle{}, "%s\t\t%s\n", i18n.T("Total debits"), o.FormatAmount(-st.Debits))
+	w.row(rowStyle{style: ansiBold}, "%s\t%s\t%s\n", i18n.T("Closing balance"), lastDay.Format("2006-01-02"), o.FormatAmount(st.ClosingBalance))
+	w.flush()
+
+	anchor := i18n.T("last sync")
+	if st.Anchor.SnapshotID != 0 {
+		anchor = fmt.Sprintf(i18n.T("snapshot #%d"), st.Anchor.SnapshotID)
+	}
+	fmt.Fprintln(o.Out)
+	o.printText("%d transactions. Balances reconstructed from %s on %s.",
+		len(st.Transactions), anchor, st.Anchor.At.Format("2006-01-02 15:04"))
+	if st.Excluded > 0 {
+		o.printText("Note: %d transactions in other currencies are not included.", st.Excluded)
+	}
+}
+
+// heatBlocks are the cells of a spending heatmap by level, none first
+var heatBlocks = []string{"·", "░", "▒", "▓", "█"}
+
+// PrintHeatmaps prints each currency's daily spending as a calendar, one row
+// per week starting on Monday, followed by the amounts each level goes up to.
+// Tables other than plain text show the amounts instead of levels.
+func (o *Output) PrintHeatmaps(heatmaps []report.Heatmap) {
+	for i, h := range heatmaps {
+		if i > 0 {
+			fmt.Fprintln(o.Out)
+		}
+		o.PrintHeading(h.Currency)
+
+		w := o.newTable(false)
+		w.header("WEEK\tMON\tTUE\tWED\tTHU\tFRI\tSAT\tSUN\tTOTAL")
+		var cells [7]string
+		var week time.Time
+		var weekTotal float64
+		flushWeek := func() {
+			w.row(rowStyle{}, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", week.Format("2006-01-02"),
+				cells[0], cells[1], cells[2], cells[3], cells[4], cells[5], cells[6], o.FormatAmount(weekTotal))
+			cells, weekTotal = [7]string{}, 0
+		}
+		levelMax := make([]float64, report.HeatmapLevels+1)
+		var spendingDays int
+		for j, d := range h.Days {
+			weekday := (int(d.Date.Weekday()) + 6) % 7 // Monday first
+			if j > 0 && weekday == 0 {
+				flushWeek()
+			}
+			if j == 0 || weekday == 0 {
+				week = d.Date.AddDate(0, 0, -weekday)
+			}
+			if o.Format == FormatTable {
+				cells[weekday] = heatBlocks[d.Level]
+			} else {
+				cells[weekday] = o.FormatAmount(d.Amount)
+			}
+			weekTotal = money.Add(weekTotal, d.Amount)
+			levelMax[d.Level] = max(levelMax[d.Level], d.Amount)
+			if d.Level > 0 {
+				spendingDays++
+			}
+		}
+		if len(h.Days) > 0 {
+			flushWeek()
+		}
+		w.flush()
+
+		fmt.Fprintln(o.Out)
+		if o.Format == FormatTable {
+			legend := heatBlocks[0] + " " + i18n.T("none")
+			for level := 1; level <= report.HeatmapLevels; level++ {
+				if levelMax[level] > 0 {
+					legend += fmt.Sprintf("  %s %s %s", heatBlocks[level], i18n.T("up to"), o.FormatAmount(levelMax[level]))
+				}
+			}
+			o.printText("%s", legend)
+		}
+		o.printText("Total: %s %s on %d of %d days", o.FormatAmount(h.Total), h.Currency, spendingDays, len(h.Days))
+	}
+}
+
+// PrintNetWorth prints the current net worth in currency
+func (o *Output) PrintNetWorth(p report.NetWorthPoint, currency string) {
+	o.printText("Net worth: %s %s", o.FormatAmount(p.Total), currency)
+	o.printMissingRates([]report.NetWorthPoint{p})
+}
+
+// PrintNetWorthHistory prints net worth over time in currency as a chart
+// width columns and height lines tall, with the first and last dates below
+// it. With --output markdown, html or csv it prints a table of the points.
+func (o *Output) PrintNetWorthHistory(points []report.NetWorthPoint, currency string, width, height int) {
+	if len(points) == 0 {
+		return
+	}
+	if o.Format != FormatTable {
+		w := o.newTable(false)
+		w.header("DATE\tNET WORTH\tCURRENCY")
+		for _, p := range points {
+			w.row(rowStyle{}, "%s\t%s\t%s\n", p.Date.Format("2006-01-02"), o.FormatAmount(p.Total), currency)
+		}
+		w.flush()
+	} else {
+		var values []float64
+		var first, last time.Time
+		for i, index := range resample(len(points), width) {
+			values = append(values, points[index].Total)
+			if i == 0 {
+				first = points[index].Date
+			}
+			last = points[index].Date
+		}
+		lo, hi := values[0], values[0]
+		for _, v := range values {
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		top, bottom := o.FormatAmount(hi), o.FormatAmount(lo)
+		labelWidth := max(len(top), len(bottom))
+		lines := Chart(values, height)
+		for i, line := range lines {
+			label, axis := "", "│"
+			switch {
+			case i == 0:
+				label, axis = top, "┤"
+			case i == len(lines)-1:
+				label, axis = bottom, "┤"
+			}
+			fmt.Fprintf(o.Out, "%*s %s%s\n", labelWidth, label, axis, line)
+		}
+		fmt.Fprintf(o.Out, "%*s └%s\n", labelWidth, "", strings.Repeat("─", len(values)))
+		dates := first.Format("2006-01-02")
+		if lastDate := last.Format("2006-01-02"); len(values) > 2*len(dates) {
+			dates += strings.Repeat(" ", len(values)-2*len(dates)) + lastDate
+		}
+		fmt.Fprintf(o.Out, "%*s  %s\n", labelWidth, "", dates)
+		fmt.Fprintln(o.Out)
+	}
+
+	latest, earliest := points[len(points)-1], points[0]
+	change := money.Sub(latest.Total, earliest.Total)
+	sign := "+"
+	if change < 0 {
+		sign = "-"
+	}
+	o.printText("Net worth: %s %s (%s since %s)", o.FormatAmount(latest.Total), currency,
+		o.formatSigned(sign, change), earliest.Date.Format("2006-01-02"))
+	o.printMissingRates(points)
+}
+
+// printMissingRates notes the currencies net worth points left out
+func (o *Output) printMissingRates(points []report.NetWorthPoint) {
+	missing := make(map[string]bool)
+	for _, p := range points {
+		for _, c := range p.Missing {
+			missing[c] = true
+		}
+	}
+	if len(missing) > 0 {
+		currencies := make([]string, 0, len(missing))
+		for c := range missing {
+			currencies = append(currencies, c)
+		}
+		sort.Strings(currencies)
+		o.printText("Left out for lack of an exchange rate: %s", strings.Join(currencies, ", "))
+	}
+}
+
+// PrintIncome prints the salary-like income sources and each month's income,
+// expenses and savings rate, followed by each currency's savings rate trend
+// and average. CSV output only has the months.
+func (o *Output) PrintIncome(r report.IncomeReport) {
+	if o.Format != FormatCSV {
+		o.PrintHeading("Salary-like income")
+		if len(r.Sources) == 0 {
+			o.printText("No large monthly credits found.")
+		} else {
+			w := o.newTable(false)
+			w.header("COUNTERPARTY\tAMOUNT\tCURRENCY\tCOUNT\tLAST")
+			for _, s := range r.Sources {
+				w.row(rowStyle{}, "%s\t%s\t%s\t%d\t%s\n", s.Counterparty, o.FormatAmount(s.Amount), s.Currency,
+					s.Count, s.LastDate.Format("2006-01-02"))
+			}
+			w.flush()
+		}
+		fmt.Fprintln(o.Out)
+		o.PrintHeading("Savings")
+	}
+
+	w := o.newTable(false)
+	w.header("MONTH\tCURRENCY\tINCOME\tSALARY\tEXPENSES\tSAVINGS\tRATE")
+	type total struct {
+		income, savings float64
+		rates           []float64
+	}
+	totals := make(map[string]*total)
+	var currencies []string
+	for _, m := range r.Months {
+		rate := "-"
+		t, ok := totals[m.Currency]
+		if !ok {
+			t = &total{}
+			totals[m.Currency] = t
+			currencies = append(currencies, m.Currency)
+		}
+		if v, ok := m.Rate(); ok {
+			rate = fmt.Sprintf("%.0f%%", v*100)
+			t.income = money.Add(t.income, m.Income)
+			t.savings = money.Add(t.savings, m.Savings())
+			t.rates = append(t.rates, v)
+		}
+		style := rowStyle{}
+		savings := o.FormatAmount(m.Savings())
+		if m.Savings() < 0 {
+			style = rowStyle{style: ansiRed, cell: savings}
+		}
+		w.row(style, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", m.Month, m.Currency, o.FormatAmount(m.Income),
+			o.FormatAmount(m.Salary), o.FormatAmount(m.Expenses), savings, rate)
+	}
+	w.flush()
+
+	if o.Format == FormatCSV {
+		return
+	}
+	sort.Strings(currencies)
+	fmt.Fprintln(o.Out)
+	for _, c := range currencies {
+		t := totals[c]
+		if t.income <= 0 {
+			continue
+		}
+		line := fmt.Sprintf(i18n.T("Savings rate in %s: %.0f%% on average"), c, t.savings/t.income*100)
+		if trend := Sparkline(t.rates); trend != "" && o.Format == FormatTable {
+			line += "  " + trend
+		}
+		o.printText("%s", line)
+	}
+}
@@ -0,0 +1,31 @@
+package output
+
+import (
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/i18n"
+)
+
+// PrintRequisites prints account requisites one per line, ready to copy and paste.
+// Empty fields are left out.
+func (o *Output) PrintRequisites(r client.Requisites) {
+	w := o.newTable(false)
+	fields := []struct{ label, value string }{
+		{"Beneficiary", r.HolderName},
+		{"Account", r.AccountNumber},
+		{"IBAN", r.IBAN},
+		{"Currency", r.Currency},
+		{"Bank", r.BankName},
+		{"Bank address", r.BankAddress},
+		{"SWIFT", r.SWIFT},
+		{"Bank tax ID", r.TaxID},
+		{"Correspondent bank", r.CorrespondentBank},
+		{"Correspondent SWIFT", r.CorrespondentSWIFT},
+		{"Correspondent account", r.CorrespondentAccount},
+	}
+	for _, f := range fields {
+		if f.value != "" {
+			w.row(rowStyle{}, "%s:\t%s\n", i18n.T(f.label), f.value)
+		}
+	}
+	w.flush()
+}
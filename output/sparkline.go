@@ -0,0 +1,27 @@
+package output
+
+import "strings"
+
+// sparkBlocks are the bar heights of a sparkline, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a line of bars scaled between their minimum and
+// maximum. Fewer than two values have no trend and render as "".
+func Sparkline(values []float64) string {
+	if len(values) < 2 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if hi > lo {
+			i = int((v-lo)/(hi-lo)*float64(len(sparkBlocks)-1) + 0.5)
+		}
+		b.WriteRune(sparkBlocks[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package output
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// ProductSync is a product with its sync interval and last sync
+type ProductSync struct {
+	Product client.ProductInfo
+	State   db.ProductSyncState
+}
+
+// PrintSyncIntervals prints products with their sync intervals, when they
+// were last synced and when scheduled syncs pick them up next
+func (o *Output) PrintSyncIntervals(products []ProductSync, now time.Time) {
+	w := o.newTable(false)
+	w.header("ID\tNAME\tINTERVAL\tLAST SYNCED\tNEXT")
+	for _, ps := range products {
+		interval, next := "every run", "next run"
+		if ps.State.Interval > 0 {
+			interval = FormatInterval(ps.State.Interval)
+			if !ps.State.Due(now) {
+				next = ps.State.LastSyncedAt.Add(ps.State.Interval).Format("2006-01-02 15:04")
+			}
+		}
+		last := "-"
+		if !ps.State.LastSyncedAt.IsZero() {
+			last = ps.State.LastSyncedAt.Format("2006-01-02 15:04")
+		}
+		w.row(rowStyle{}, "%s\t%s\t%s\t%s\t%s\n", ps.Product.ID, TruncateString(ps.Product.Name, 30), interval, last, next)
+	}
+	w.flush()
+}
+
+// FormatInterval formats a duration as whole days (7d) or without zero
+// minutes and seconds (2h, 1h30m)
+func FormatInterval(d time.Duration) string {
+	if d > 0 && d%(24*time.Hour) == 0 {
+		return strconv.FormatInt(int64(d/(24*time.Hour)), 10) + "d"
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,280 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/ivan4th/ameriagrab/i18n"
+	"github.com/ivan4th/ameriagrab/textwidth"
+)
+
+// Table output formats
+const (
+	FormatTable    = "table"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatCSV      = "csv"
+)
+
+// CheckFormat returns an error if Format is not a known table output format
+func (o *Output) CheckFormat() error {
+	switch o.Format {
+	case FormatTable, FormatMarkdown, FormatHTML, FormatCSV:
+		return nil
+	}
+	return fmt.Errorf("invalid --output %q (expected %s, %s, %s or %s)", o.Format, FormatTable, FormatMarkdown, FormatHTML, FormatCSV)
+}
+
+// table collects tab-separated rows and prints them in the selected Format.
+// As plain text, rows are aligned by display width and then styled, so escape
+// sequences don't affect cell widths.
+type table struct {
+	o          *Output
+	out        io.Writer
+	alignRight bool
+	rows       []string
+	styles     []rowStyle
+	headerRow  int // -1 if the table has no header
+}
+
+func (o *Output) newTable(alignRight bool) *table {
+	return &table{o: o, out: o.Out, alignRight: alignRight, headerRow: -1}
+}
+
+// header writes the header row, which is shown in bold. Headers are translated
+// except in CSV, whose column names scripts rely on.
+func (t *table) header(line string) {
+	if t.o.Format != FormatCSV {
+		cells := strings.Split(line, "\t")
+		for i, c := range cells {
+			cells[i] = i18n.T(c)
+		}
+		line = strings.Join(cells, "\t")
+	}
+	t.headerRow = len(t.rows)
+	t.row(rowStyle{style: ansiBold}, "%s\n", line)
+}
+
+// row writes a tab-separated row terminated by a newline
+func (t *table) row(style rowStyle, format string, args ...interface{}) {
+	t.rows = append(t.rows, fmt.Sprintf(format, args...))
+	t.styles = append(t.styles, style)
+}
+
+// flush writes out the table
+func (t *table) flush() {
+	switch t.o.Format {
+	case FormatMarkdown:
+		t.writeMarkdown()
+	case FormatHTML:
+		t.writeHTML()
+	case FormatCSV:
+		t.writeCSV()
+	default:
+		t.writeText()
+	}
+	t.rows, t.styles, t.headerRow = nil, nil, -1
+}
+
+func (t *table) writeText() {
+	lines := make([]string, len(t.rows))
+	for i, r := range t.rows {
+		lines[i] = strings.TrimSuffix(r, "\n")
+	}
+	color := t.o.colorEnabled(t.out)
+	for i, line := range alignColumns(lines, t.alignRight) {
+		line += "\n"
+		if color && t.styles[i].style != "" {
+			line = applyRowStyle(line, t.styles[i])
+		}
+		io.WriteString(t.out, line)
+	}
+}
+
+// columnPadding is the space between aligned columns
+const columnPadding = 2
+
+// alignColumns aligns tab-separated lines like text/tabwriter does, but
+// measures cells by their display width, so that text in any script lines up.
+// Every tab terminates a cell; a column is aligned across each block of
+// consecutive lines that have a cell in it. Text after the last tab is
+// not aligned.
+func alignColumns(lines []string, alignRight bool) []string {
+	cells := make([][]string, len(lines))
+	for i, line := range lines {
+		cells[i] = strings.Split(line, "\t")
+	}
+	out := make([]string, len(lines))
+	var widths []int
+
+	// write formats lines [from, to) using the column widths of the enclosing blocks
+	write := func(from, to int) {
+		for i := from; i < to; i++ {
+			var b strings.Builder
+			for j, c := range cells[i] {
+				switch {
+				case j >= len(widths):
+					b.WriteString(c)
+				case alignRight:
+					b.WriteString(textwidth.PadLeft(c, widths[j]))
+				default:
+					b.WriteString(textwidth.PadRight(c, widths[j]))
+				}
+			}
+			out[i] = b.String()
+		}
+	}
+
+	var format func(from, to int)
+	format = func(from, to int) {
+		column := len(widths)
+		for i := from; i < to; i++ {
+			if column >= len(cells[i])-1 {
+				continue
+			}
+			// Line i starts a block of lines with a cell in this column
+			write(from, i)
+			from = i
+			width := 0
+			for ; i < to && column < len(cells[i])-1; i++ {
+				width = max(width, textwidth.Width(cells[i][column])+columnPadding)
+			}
+			widths = append(widths, width)
+			format(from, i)
+			widths = widths[:len(widths)-1]
+			from = i
+		}
+		write(from, to)
+	}
+	format(0, len(lines))
+	return out
+}
+
+// WriteColumns writes tab-separated text to w with its columns aligned by
+// display width
+func WriteColumns(w io.Writer, text string) {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for _, line := range alignColumns(lines, false) {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// cells splits a row into cells. Blank separator rows have no cells.
+func (t *table) cells(row string) []string {
+	row = strings.TrimSuffix(row, "\n")
+	if strings.Trim(row, "\t ") == "" {
+		return nil
+	}
+	// Rows of right-aligned tables end with a tab that terminates the last cell
+	cells := strings.Split(strings.TrimSuffix(row, "\t"), "\t")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+func (t *table) writeMarkdown() {
+	escape := strings.NewReplacer("|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`")
+	line := func(cells []string) {
+		for i := range cells {
+			cells[i] = escape.Replace(cells[i])
+		}
+		fmt.Fprintf(t.out, "| %s |\n", strings.Join(cells, " | "))
+	}
+	width := 0
+	for _, r := range t.rows {
+		width = max(width, len(t.cells(r)))
+	}
+	if width == 0 {
+		return
+	}
+	header := make([]string, width)
+	if t.headerRow >= 0 {
+		copy(header, t.cells(t.rows[t.headerRow]))
+	}
+	line(header)
+	sep := make([]string, width)
+	for i := range sep {
+		sep[i] = "---"
+	}
+	fmt.Fprintf(t.out, "| %s |\n", strings.Join(sep, " | "))
+	for i, r := range t.rows {
+		if cells := t.cells(r); i != t.headerRow && cells != nil {
+			line(append(cells, make([]string, width-len(cells))...))
+		}
+	}
+}
+
+func (t *table) writeHTML() {
+	fmt.Fprintln(t.out, "<table>")
+	for i, r := range t.rows {
+		cells := t.cells(r)
+		if cells == nil {
+			continue
+		}
+		tag := "td"
+		if i == t.headerRow {
+			tag = "th"
+		}
+		var b strings.Builder
+		b.WriteString("  <tr>")
+		for _, c := range cells {
+			fmt.Fprintf(&b, "<%s>%s</%s>", tag, html.EscapeString(c), tag)
+		}
+		b.WriteString("</tr>")
+		fmt.Fprintln(t.out, b.String())
+	}
+	fmt.Fprintln(t.out, "</table>")
+}
+
+// writeCSV writes the rows as CSV records of equal length, skipping blank separator rows
+func (t *table) writeCSV() {
+	width := 0
+	for _, r := range t.rows {
+		width = max(width, len(t.cells(r)))
+	}
+	w := csv.NewWriter(t.out)
+	for _, r := range t.rows {
+		if cells := t.cells(r); cells != nil {
+			w.Write(append(cells, make([]string, width-len(cells))...))
+		}
+	}
+	w.Flush()
+}
+
+// PrintHeading prints the title of the table that follows
+func (o *Output) PrintHeading(title string) {
+	title = i18n.T(title)
+	switch o.Format {
+	case FormatMarkdown:
+		fmt.Fprintf(o.Out, "### %s\n\n", title)
+	case FormatHTML:
+		fmt.Fprintf(o.Out, "<h3>%s</h3>\n", html.EscapeString(title))
+	case FormatCSV:
+		// Keep stdout pure CSV
+		fmt.Fprintln(o.Err, title+":")
+	default:
+		fmt.Fprintln(o.Out, title+":")
+	}
+}
+
+// printText prints a line of text accompanying a table, translating the format
+func (o *Output) printText(format string, args ...interface{}) {
+	s := fmt.Sprintf(i18n.T(format), args...)
+	switch o.Format {
+	case FormatMarkdown:
+		// Keep separate lines apart within a paragraph
+		fmt.Fprintf(o.Out, "%s  \n", strings.TrimLeft(s, " "))
+	case FormatHTML:
+		if s = strings.TrimSpace(s); s != "" {
+			fmt.Fprintf(o.Out, "<p>%s</p>\n", html.EscapeString(s))
+		}
+	case FormatCSV:
+		fmt.Fprintln(o.Err, s)
+	default:
+		fmt.Fprintln(o.Out, s)
+	}
+}
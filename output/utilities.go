@@ -0,0 +1,47 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/i18n"
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// PrintUtilityPayments prints utility payments in human-readable table format,
+// followed by totals per provider
+func (o *Output) PrintUtilityPayments(payments []client.UtilityPayment) {
+	w := o.newTable(false)
+	w.header("DATE\tSERVICE\tPROVIDER\tSUBSCRIBER\tAMOUNT\tSTATUS")
+	type key struct{ service, provider, currency string }
+	totals := make(map[key]float64)
+	for _, p := range payments {
+		w.row(rowStyle{}, "%s\t%s\t%s\t%s\t%s %s\t%s\n", p.PaymentDate, p.ServiceType, p.Provider,
+			p.SubscriberNumber, o.FormatAmount(p.Amount.Amount), p.Amount.Currency, p.Status)
+		k := key{p.ServiceType, p.Provider, p.Amount.Currency}
+		totals[k] = money.Add(totals[k], p.Amount.Amount)
+	}
+	w.flush()
+
+	keys := make([]key, 0, len(totals))
+	for k := range totals {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].service != keys[j].service {
+			return keys[i].service < keys[j].service
+		}
+		if keys[i].provider != keys[j].provider {
+			return keys[i].provider < keys[j].provider
+		}
+		return keys[i].currency < keys[j].currency
+	})
+
+	fmt.Fprintf(o.Out, "\n%s:\n", i18n.T("Totals"))
+	w = o.newTable(false)
+	for _, k := range keys {
+		w.row(rowStyle{}, "  %s\t%s\t%s %s\n", k.service, k.provider, o.FormatAmount(totals[k]), k.currency)
+	}
+	w.flush()
+}
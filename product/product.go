@@ -0,0 +1,158 @@
+// Package product knows how the bank's product types map to data sources: it
+// resolves cards and accounts by ID or name, picks the table or API endpoint
+// holding their transactions and fetches them. Commands, reports and the
+// servers go through it, so a new data source is added in one place.
+package product
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// Product types as sent by the bank, and TypeGoal for savings goals and card
+// stashes, which the bank lists separately
+const (
+	TypeCard    = "CARD"
+	TypeAccount = "ACCOUNT"
+	TypeGoal    = "GOAL"
+)
+
+// IsCard reports whether p is a card; everything else is an account
+func IsCard(p client.ProductInfo) bool {
+	return p.ProductType == TypeCard
+}
+
+// IsGoal reports whether p is a savings goal or card stash. Goals have a
+// balance but no transaction history of their own.
+func IsGoal(p client.ProductInfo) bool {
+	return p.ProductType == TypeGoal
+}
+
+// FromGoal returns a savings goal as a product, with AccountID set to the
+// account it's funded from. Goals have no separate available balance.
+func FromGoal(g client.GoalInfo) client.ProductInfo {
+	return client.ProductInfo{
+		ProductType:      TypeGoal,
+		ID:               g.ID,
+		Name:             g.Name,
+		AccountID:        g.AccountID,
+		Currency:         g.Currency,
+		Balance:          g.Balance,
+		AvailableBalance: g.Balance,
+		Status:           g.Status,
+	}
+}
+
+// Sources returns the ledger sources holding a product's transactions: card
+// events and the linked account history for cards, the history of accounts
+func Sources(p client.ProductInfo) []string {
+	if IsCard(p) {
+		return []string{db.SourceCard, db.SourceLinked}
+	}
+	return []string{db.SourceAccount}
+}
+
+// HistorySource returns the ledger source with every movement on the
+// product's account, which balances are reconstructed from. For cards this
+// is the linked account history, as card events leave out transfers.
+func HistorySource(p client.ProductInfo) string {
+	if IsCard(p) {
+		return db.SourceLinked
+	}
+	return db.SourceAccount
+}
+
+// TransactionSource returns the ledger source a product's transactions are
+// read from: card events for cards, or their linked account history if
+// linked is set, and the history of accounts
+func TransactionSource(p client.ProductInfo, linked bool) string {
+	if IsCard(p) && !linked {
+		return db.SourceCard
+	}
+	return HistorySource(p)
+}
+
+// NotFoundError is returned when no product matches an ID or name
+type NotFoundError struct {
+	ID    string
+	Where string // "database" or "accounts or cards" (the API)
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("product %q not found in %s", e.ID, e.Where)
+}
+
+// Aliases maps lowercase aliases to the product IDs, names or numbers they
+// stand for (the alias.* config settings); Resolve and Find look them up first
+var Aliases map[string]string
+
+// dealias returns the product an alias stands for, or idOrName if it's no alias
+func dealias(idOrName string) string {
+	if target, ok := Aliases[strings.ToLower(idOrName)]; ok {
+		return target
+	}
+	return idOrName
+}
+
+// Resolve returns the stored product with the given alias, ID, name or last
+// digits of its number (see db.GetProductByNameOrID)
+func Resolve(products ProductStore, idOrName string) (*client.ProductInfo, error) {
+	idOrName = dealias(idOrName)
+	p, err := products.GetProductByNameOrID(idOrName)
+	if err != nil {
+		return nil, fmt.Errorf("fetching product: %w", err)
+	}
+	if p == nil {
+		return nil, &NotFoundError{ID: idOrName, Where: "database"}
+	}
+	return p, nil
+}
+
+// AccountID returns the ID of the account holding a product's money: the
+// linked account for cards
+func AccountID(p client.ProductInfo) (string, error) {
+	if !IsCard(p) {
+		return p.ID, nil
+	}
+	if p.AccountID == "" {
+		return "", fmt.Errorf("card %s has no linked account", p.ID)
+	}
+	return p.AccountID, nil
+}
+
+// Find returns the product with the given alias or ID, or the only one with
+// the given name (case-insensitive) or last digits of its number, like
+// db.GetProductByNameOrID does for stored ones
+func Find(products []client.ProductInfo, idOrName string) (*client.ProductInfo, error) {
+	idOrName = dealias(idOrName)
+	var byName, byNumber []client.ProductInfo
+	for _, p := range products {
+		if p.ID == idOrName {
+			return &p, nil
+		}
+		if strings.EqualFold(p.Name, idOrName) {
+			byName = append(byName, p)
+		}
+		if db.IsNumberSuffix(idOrName) && (strings.HasSuffix(p.CardNumber, idOrName) || strings.HasSuffix(p.AccountNumber, idOrName)) {
+			byNumber = append(byNumber, p)
+		}
+	}
+	kind, matches := "name", byName
+	if len(byName) == 0 {
+		kind, matches = "number", byNumber
+	}
+	switch len(matches) {
+	case 0:
+		return nil, &NotFoundError{ID: idOrName, Where: "accounts or cards"}
+	case 1:
+		return &matches[0], nil
+	}
+	ids := make([]string, len(matches))
+	for i, p := range matches {
+		ids[i] = p.ID
+	}
+	return nil, fmt.Errorf("ambiguous %s %q matches multiple products: %v", kind, idOrName, ids)
+}
@@ -0,0 +1,215 @@
+package product
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+var (
+	testCard    = client.ProductInfo{ID: "card1", ProductType: TypeCard, Name: "Visa", AccountID: "acc-linked", Currency: "AMD"}
+	testAccount = client.ProductInfo{ID: "acc1", ProductType: TypeAccount, Name: "Savings", Currency: "AMD"}
+)
+
+func TestSources(t *testing.T) {
+	tests := []struct {
+		p       client.ProductInfo
+		linked  bool
+		source  string
+		history string
+	}{
+		{testCard, false, db.SourceCard, db.SourceLinked},
+		{testCard, true, db.SourceLinked, db.SourceLinked},
+		{testAccount, false, db.SourceAccount, db.SourceAccount},
+		{testAccount, true, db.SourceAccount, db.SourceAccount},
+	}
+	for _, tt := range tests {
+		if got := TransactionSource(tt.p, tt.linked); got != tt.source {
+			t.Errorf("TransactionSource(%s, %v) = %s, want %s", tt.p.ID, tt.linked, got, tt.source)
+		}
+		if got := HistorySource(tt.p); got != tt.history {
+			t.Errorf("HistorySource(%s) = %s, want %s", tt.p.ID, got, tt.history)
+		}
+	}
+	if got := strings.Join(Sources(testCard), ","); got != "card,linked" {
+		t.Errorf("Sources(card) = %s", got)
+	}
+
+	if id, err := AccountID(testCard); err != nil || id != "acc-linked" {
+		t.Errorf("AccountID(card) = %q, %v", id, err)
+	}
+	if _, err := AccountID(client.ProductInfo{ID: "card2", ProductType: TypeCard}); err == nil {
+		t.Error("expected an error for a card without a linked account")
+	}
+}
+
+func TestGoals(t *testing.T) {
+	goal := FromGoal(client.GoalInfo{ID: "g1", Name: "Vacation", AccountID: "acc1", Currency: "AMD", Balance: 150000, Status: "ACTIVE"})
+	if !IsGoal(goal) || IsCard(goal) || goal.AccountID != "acc1" || goal.AvailableBalance != 150000 {
+		t.Errorf("unexpected goal product: %+v", goal)
+	}
+
+	// Goals are never fetched from the API
+	svc := Remote(&client.Client{}, "")
+	if balance, err := svc.AvailableBalance(goal); err != nil || balance != 150000 {
+		t.Errorf("AvailableBalance(goal) = %v, %v", balance, err)
+	}
+	if _, err := svc.Transactions(goal, Query{}); err == nil {
+		t.Error("expected an error reading transactions of a goal")
+	}
+}
+
+func TestFind(t *testing.T) {
+	products := []client.ProductInfo{testCard, testAccount, {ID: "acc2", Name: "savings"}}
+	if p, err := Find(products, "visa"); err != nil || p.ID != "card1" {
+		t.Errorf("Find(visa) = %v, %v", p, err)
+	}
+	if p, err := Find(products, "acc2"); err != nil || p.ID != "acc2" {
+		t.Errorf("Find(acc2) = %v, %v", p, err)
+	}
+	if _, err := Find(products, "Savings"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("expected an ambiguous name error, got %v", err)
+	}
+	var notFound *NotFoundError
+	if _, err := Find(products, "missing"); !errors.As(err, &notFound) {
+		t.Errorf("expected NotFoundError, got %v", err)
+	}
+
+	products[0].CardNumber = "4454********6615"
+	products[1].AccountNumber = "1570000000006615"
+	products[2].AccountNumber = "1570000000001234"
+	if p, err := Find(products, "1234"); err != nil || p.ID != "acc2" {
+		t.Errorf("Find(1234) = %v, %v", p, err)
+	}
+	if _, err := Find(products, "6615"); err == nil || !strings.Contains(err.Error(), "ambiguous number") {
+		t.Errorf("expected an ambiguous number error, got %v", err)
+	}
+
+	Aliases = map[string]string{"salary": "card1"}
+	defer func() { Aliases = nil }()
+	if p, err := Find(products, "Salary"); err != nil || p.ID != "card1" {
+		t.Errorf("Find(Salary) = %v, %v", p, err)
+	}
+}
+
+func TestServiceLocal(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.UpsertProducts([]client.ProductInfo{testCard, testAccount}); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	var cardTxns []client.Transaction
+	var accountTxns []client.AccountTransaction
+	for i := range 5 {
+		at := start.Add(time.Duration(i) * time.Hour)
+		cardTxns = append(cardTxns, client.Transaction{
+			ID:             fmt.Sprintf("c%d", i),
+			AccountingType: "DEBIT",
+			Amount:         client.Amount{Currency: "AMD", Amount: 100},
+			OperationDate:  at.Format(time.RFC3339),
+		})
+		accountTxns = append(accountTxns, client.AccountTransaction{
+			ID:                fmt.Sprintf("a%d", i),
+			FlowDirection:     "EXPENSE",
+			TransactionDate:   at.UnixMilli(),
+			TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 100},
+		})
+	}
+	if _, err := database.InsertCardTransactions("card1", cardTxns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+	if _, err := database.InsertAccountTransactions("acc1", accountTxns); err != nil {
+		t.Fatalf("failed to insert account transactions: %v", err)
+	}
+
+	svc := Local(database)
+	card, err := svc.Resolve("visa")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	var notFound *NotFoundError
+	if _, err := svc.Resolve("missing"); !errors.As(err, &notFound) {
+		t.Errorf("expected NotFoundError, got %v", err)
+	}
+
+	txns, err := svc.Transactions(*card, Query{Size: 2, Page: 1})
+	if err != nil {
+		t.Fatalf("Transactions(card) failed: %v", err)
+	}
+	if txns.Card == nil || txns.Card.Data.TotalCount != 5 || len(txns.Card.Data.Entries) != 2 || txns.Card.Data.Entries[0].ID != "c2" {
+		t.Errorf("unexpected card page: %+v", txns.Card)
+	}
+
+	// Accounts are paged like cards
+	txns, err = svc.Transactions(testAccount, Query{Size: 2, Page: 2, Ascending: true})
+	if err != nil {
+		t.Fatalf("Transactions(account) failed: %v", err)
+	}
+	if h := txns.Account; h == nil || len(h.Data.Transactions) != 1 || h.Data.Transactions[0].ID != "a4" || h.Data.HasNext {
+		t.Errorf("unexpected account page: %+v", h)
+	}
+	txns, err = svc.Transactions(testAccount, Query{Size: 2})
+	if err != nil || !txns.Account.Data.HasNext {
+		t.Errorf("expected more account transactions after the first page, got %v", err)
+	}
+
+	if _, err := svc.Transactions(testAccount, Query{Source: db.SourceCard}); err == nil {
+		t.Error("expected an error reading card transactions of an account")
+	}
+	if _, err := svc.Transactions(testAccount, Query{Combined: true}); err == nil {
+		t.Error("expected an error combining account transactions")
+	}
+}
+
+// memProducts is an in-memory ProductStore
+type memProducts []client.ProductInfo
+
+func (m *memProducts) UpsertProducts(products []client.ProductInfo) error {
+	*m = append(*m, products...)
+	return nil
+}
+
+func (m *memProducts) GetProducts() ([]client.ProductInfo, error) {
+	return *m, nil
+}
+
+func (m *memProducts) GetProductByID(id string) (*client.ProductInfo, error) {
+	for _, p := range *m {
+		if p.ID == id {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memProducts) GetProductByNameOrID(identifier string) (*client.ProductInfo, error) {
+	p, err := Find(*m, identifier)
+	var notFound *NotFoundError
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	return p, err
+}
+
+func TestResolve_ProductStore(t *testing.T) {
+	var store memProducts
+	store.UpsertProducts([]client.ProductInfo{testCard, testAccount})
+
+	if p, err := Resolve(&store, "acc1"); err != nil || p.ID != "acc1" {
+		t.Errorf("Resolve(acc1) = %v, %v", p, err)
+	}
+	var notFound *NotFoundError
+	if _, err := Resolve(&store, "missing"); !errors.As(err, &notFound) || notFound.Where != "database" {
+		t.Errorf("expected NotFoundError, got %v", err)
+	}
+}
@@ -0,0 +1,335 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"golang.org/x/sync/errgroup"
+)
+
+// apiPageSize is the page size requested from the API when a Query asks for
+// all transactions
+const apiPageSize = 1000
+
+// Service fetches products and their transactions from local storage, or
+// from the bank's API when Client is set
+type Service struct {
+	Store       Store
+	Client      *client.Client
+	AccessToken string
+}
+
+// Local returns a Service reading from local storage, usually the database
+func Local(store Store) *Service {
+	return &Service{Store: store}
+}
+
+// Remote returns a Service reading from the bank's API
+func Remote(c *client.Client, accessToken string) *Service {
+	return &Service{Client: c, AccessToken: accessToken}
+}
+
+// Resolve returns the product with the given ID or name
+func (s *Service) Resolve(idOrName string) (*client.ProductInfo, error) {
+	if s.Client == nil {
+		return Resolve(s.Store, idOrName)
+	}
+	resp, err := s.Client.GetAccountsAndCards(s.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("fetching accounts and cards: %w", err)
+	}
+	return Find(resp.Data.AccountsAndCards, idOrName)
+}
+
+// Query selects a page of a product's transactions
+type Query struct {
+	Source    string // ledger source (default: TransactionSource(p, false))
+	Combined  bool   // merge a card's events with its linked account history (database only)
+	Size      int    // transactions per page, 0 = all
+	Page      int    // page number, starting at 0
+	Ascending bool   // oldest first; newest first otherwise
+	// Extended fetches the extended info of linked account transactions
+	// from the API. Stored extended info is always read from the database.
+	Extended bool
+	Match    db.MatchOptions // how card events take counterparties from linked account transactions
+	// ShowStages lists the pre-purchase authorization of a settled card
+	// purchase as well (database only); otherwise only the settlement is listed
+	ShowStages bool
+}
+
+// Transactions is a page of a product's transactions: card events and linked
+// account transactions in Card, account history in Account
+type Transactions struct {
+	Card    *client.TransactionsResponse
+	Account *client.HistoryResponse
+}
+
+// Transactions returns a page of a product's transactions from the source
+// selected by q
+func (s *Service) Transactions(p client.ProductInfo, q Query) (*Transactions, error) {
+	if IsGoal(p) {
+		return nil, fmt.Errorf("goal %s has no transactions", p.ID)
+	}
+	if q.Source == "" {
+		q.Source = TransactionSource(p, false)
+	}
+	if (q.Source == db.SourceAccount) == IsCard(p) {
+		return nil, fmt.Errorf("%s %s has no %s transactions", p.ProductType, p.ID, q.Source)
+	}
+	if q.Combined && (s.Client != nil || !IsCard(p)) {
+		return nil, fmt.Errorf("combined transactions are only available for cards in the local database")
+	}
+	if s.Client != nil {
+		return s.remoteTransactions(p, q)
+	}
+	return s.localTransactions(p, q)
+}
+
+func (s *Service) localTransactions(p client.ProductInfo, q Query) (*Transactions, error) {
+	var txns []client.Transaction
+	var total int
+	var err error
+	switch {
+	case q.Combined:
+		opts := db.CombinedTransactionsOptions{
+			Size:            q.Size,
+			Page:            q.Page,
+			IncludeExtended: true,
+			Ascending:       q.Ascending,
+			Match:           q.Match,
+		}
+		txns, total, err = s.Store.GetCombinedTransactions(p.ID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("fetching combined transactions: %w", err)
+		}
+	case q.Source == db.SourceLinked:
+		txns, err = s.Store.GetLinkedAccountTransactions(p.ID, q.Size, q.Page, true, q.Ascending)
+		if err != nil {
+			return nil, fmt.Errorf("fetching linked account transactions: %w", err)
+		}
+		total, err = s.Store.CountLinkedAccountTransactions(p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("counting linked account transactions: %w", err)
+		}
+	case q.Source == db.SourceCard:
+		opts := db.ReadOptions{Size: q.Size, Page: q.Page, Ascending: q.Ascending, CollapseStages: !q.ShowStages}
+		err = s.Store.ForEachCardTransaction(p.ID, opts, func(t client.Transaction) error {
+			txns = append(txns, t)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fetching card transactions: %w", err)
+		}
+		total, err = s.Store.CountCardTransactions(p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("counting card transactions: %w", err)
+		}
+		if !q.ShowStages {
+			stages, err := s.Store.CountCardTxnStages(p.ID)
+			if err != nil {
+				return nil, fmt.Errorf("counting card transactions: %w", err)
+			}
+			total -= stages
+		}
+		// Card transactions take their counterparties from the linked
+		// account transactions they match
+		if err := s.Store.AttachCounterparties(p.ID, txns, q.Match); err != nil {
+			return nil, fmt.Errorf("fetching counterparties: %w", err)
+		}
+	default:
+		return s.localAccountHistory(p, q)
+	}
+
+	resp := &client.TransactionsResponse{Status: "success"}
+	resp.Data.TotalCount = total
+	resp.Data.Entries = txns
+	return &Transactions{Card: resp}, nil
+}
+
+func (s *Service) localAccountHistory(p client.ProductInfo, q Query) (*Transactions, error) {
+	var txns []client.AccountTransaction
+	opts := db.ReadOptions{Size: q.Size, Page: q.Page, Ascending: q.Ascending}
+	err := s.Store.ForEachAccountTransaction(p.ID, opts, func(t client.AccountTransaction) error {
+		txns = append(txns, t)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching account transactions: %w", err)
+	}
+	total, err := s.Store.CountAccountTransactions(p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("counting account transactions: %w", err)
+	}
+
+	resp := &client.HistoryResponse{Status: "success"}
+	resp.Data.Transactions = txns
+	resp.Data.HasNext = q.Size > 0 && (q.Page+1)*q.Size < total
+	resp.Data.IsUpToDate = true
+	return &Transactions{Account: resp}, nil
+}
+
+func (s *Service) remoteTransactions(p client.ProductInfo, q Query) (*Transactions, error) {
+	size := q.Size
+	if size == 0 {
+		size = apiPageSize
+	}
+	switch q.Source {
+	case db.SourceCard:
+		// Card events come in a single response
+		fmt.Fprintln(os.Stderr, "Fetching card transactions...")
+		resp, err := s.Client.GetTransactions(s.AccessToken, p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("fetching card transactions: %w", err)
+		}
+		if q.Ascending {
+			reverse(resp.Data.Entries)
+		}
+		return &Transactions{Card: resp}, nil
+	case db.SourceLinked:
+		if p.AccountID == "" {
+			return nil, fmt.Errorf("card %s has no linked account ID", p.ID)
+		}
+		fmt.Fprintf(os.Stderr, "Fetching card account history (events/past) for account %s...\n", p.AccountID)
+		resp, err := s.Client.GetEventsPast(s.AccessToken, p.AccountID, size, q.Page, time.Time{})
+		if err != nil {
+			return nil, fmt.Errorf("fetching card account history: %w", err)
+		}
+		if q.Extended && len(resp.Data.Entries) > 0 {
+			fmt.Fprintf(os.Stderr, "Fetching extended info for %d transactions...\n", len(resp.Data.Entries))
+			if err := FetchExtendedInfo(s.Client, s.AccessToken, resp.Data.Entries); err != nil {
+				return nil, fmt.Errorf("fetching extended info: %w", err)
+			}
+		}
+		if q.Ascending {
+			reverse(resp.Data.Entries)
+		}
+		return &Transactions{Card: resp}, nil
+	}
+
+	fmt.Fprintln(os.Stderr, "Fetching account history...")
+	resp, err := s.Client.GetAccountHistory(s.AccessToken, p.ID, size, q.Page, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("fetching account history: %w", err)
+	}
+	if q.Ascending {
+		reverse(resp.Data.Transactions)
+	}
+	return &Transactions{Account: resp}, nil
+}
+
+// Holds returns a card's pending authorizations: the current ones from the
+// API, or the ones stored by the last sync
+func (s *Service) Holds(p client.ProductInfo) ([]client.Transaction, error) {
+	if !IsCard(p) {
+		return nil, nil
+	}
+	if s.Client == nil {
+		holds, err := s.Store.GetCardHolds(p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("fetching card holds: %w", err)
+		}
+		return holds, nil
+	}
+	resp, err := s.Client.GetEventsUpcoming(s.AccessToken, p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching pending holds: %w", err)
+	}
+	return resp.Data.Entries, nil
+}
+
+// AvailableBalance returns a product's available balance: the current one
+// from the API, or the one stored by the last sync. A goal's is its balance.
+func (s *Service) AvailableBalance(p client.ProductInfo) (float64, error) {
+	if IsGoal(p) {
+		return p.Balance, nil
+	}
+	if s.Client == nil {
+		return p.AvailableBalance, nil
+	}
+	resp, err := s.Client.GetAvailableBalance(s.AccessToken, p.ProductType, p.ID)
+	if err != nil {
+		return 0, fmt.Errorf("fetching available balance for %s: %w", p.ID, err)
+	}
+	return resp.Data.AvailableBalance, nil
+}
+
+// AvailableBalances sets the available balances of products like
+// AvailableBalance, fetching up to 5 of them in parallel. A product whose
+// balance can't be fetched keeps its own; the errors are returned in the
+// order of products (nil for the fetched ones).
+func (s *Service) AvailableBalances(products []client.ProductInfo) []error {
+	errs := make([]error, len(products))
+	var g errgroup.Group
+	g.SetLimit(5)
+	for i := range products {
+		g.Go(func() error {
+			// Each goroutine writes its own elements
+			balance, err := s.AvailableBalance(products[i])
+			if err != nil {
+				errs[i] = err
+				return nil
+			}
+			products[i].AvailableBalance = balance
+			return nil
+		})
+	}
+	g.Wait()
+	return errs
+}
+
+// DetailsClient fetches the details of linked account transactions
+type DetailsClient interface {
+	GetTransactionDetails(accessToken, transactionID string) (*client.TransactionDetailsResponse, error)
+}
+
+// FetchExtendedInfo sets the extended info of linked account transactions
+// from their details, fetching up to 5 of them in parallel
+func FetchExtendedInfo(c DetailsClient, accessToken string, txns []client.Transaction) error {
+	g, _ := errgroup.WithContext(context.Background())
+	g.SetLimit(5)
+	for i := range txns {
+		g.Go(func() error {
+			details, err := c.GetTransactionDetails(accessToken, txns[i].ID)
+			if err != nil {
+				return fmt.Errorf("fetching extended info for %s: %w", txns[i].ID, err)
+			}
+			// Each goroutine writes its own element
+			txns[i].Extended = extendedInfo(details)
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// extendedInfo extracts the extended info of a transaction from its details
+func extendedInfo(details *client.TransactionDetailsResponse) *client.TransactionExtendedInfo {
+	t := details.Data.Transaction
+	ext := &client.TransactionExtendedInfo{
+		BeneficiaryName:     t.BeneficiaryName,
+		BeneficiaryAddress:  t.BeneficiaryAddress,
+		CreditAccountNumber: t.CreditAccountNumber,
+	}
+	if t.AdditionalInfo != nil {
+		ext.CardMaskedNumber = t.AdditionalInfo.CardMaskedNumber
+		ext.OperationID = t.AdditionalInfo.ProcessedOperationID
+	}
+	if t.TransactionSwiftDetails != nil {
+		if swiftJSON, err := json.Marshal(t.TransactionSwiftDetails); err == nil {
+			ext.SwiftDetails = string(swiftJSON)
+			ext.Swift = client.ParseSwiftDetails(ext.SwiftDetails)
+		}
+	}
+	return ext
+}
+
+// reverse reverses a slice of transactions in place
+func reverse[T any](txns []T) {
+	for i, j := 0, len(txns)-1; i < j; i, j = i+1, j-1 {
+		txns[i], txns[j] = txns[j], txns[i]
+	}
+}
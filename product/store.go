@@ -0,0 +1,40 @@
+package product
+
+import (
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// ProductStore reads and stores products. *db.DB implements it; other backends
+// (e.g. in-memory ones in tests) may be used wherever only products are needed.
+type ProductStore interface {
+	UpsertProducts(products []client.ProductInfo) error
+	GetProducts() ([]client.ProductInfo, error)
+	GetProductByID(id string) (*client.ProductInfo, error)
+	// GetProductByNameOrID returns nil for no match, like GetProductByID
+	GetProductByNameOrID(identifier string) (*client.ProductInfo, error)
+}
+
+// TransactionStore reads the stored transactions of products, as Service
+// does for local views
+type TransactionStore interface {
+	ForEachCardTransaction(productID string, opts db.ReadOptions, fn func(client.Transaction) error) error
+	CountCardTransactions(productID string) (int, error)
+	CountCardTxnStages(productID string) (int, error)
+	GetCardHolds(productID string) ([]client.Transaction, error)
+	GetLinkedAccountTransactions(productID string, size, page int, includeExtended, ascending bool) ([]client.Transaction, error)
+	CountLinkedAccountTransactions(productID string) (int, error)
+	GetCombinedTransactions(productID string, opts db.CombinedTransactionsOptions) ([]client.Transaction, int, error)
+	AttachCounterparties(productID string, cardTxns []client.Transaction, opts db.MatchOptions) error
+	ForEachAccountTransaction(productID string, opts db.ReadOptions, fn func(client.AccountTransaction) error) error
+	CountAccountTransactions(productID string) (int, error)
+}
+
+// Store holds sessions, products and their transactions
+type Store interface {
+	client.SessionStorage
+	ProductStore
+	TransactionStore
+}
+
+var _ Store = (*db.DB)(nil)
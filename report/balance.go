@@ -0,0 +1,82 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/money"
+	"github.com/ivan4th/ameriagrab/product"
+)
+
+// BalanceKey identifies a transaction whose running balance is known
+type BalanceKey struct {
+	ID   string
+	Date time.Time // in UTC
+}
+
+// Balances maps transactions to the product balance after them
+type Balances map[BalanceKey]float64
+
+// Lookup returns the balance after the transaction with the given ID and date
+func (b Balances) Lookup(id string, date time.Time) (float64, bool) {
+	balance, ok := b[BalanceKey{id, date.UTC()}]
+	return balance, ok
+}
+
+// BalanceStore is what RunningBalances and Period read: a product, its known
+// balances and its transactions. *db.DB implements it.
+type BalanceStore interface {
+	GetProductByID(id string) (*client.ProductInfo, error)
+	GetBalancePoints(productID string) ([]db.BalancePoint, error)
+	GetLedgerEntries(opts db.LedgerOptions) ([]db.LedgerEntry, error)
+}
+
+// RunningBalances reconstructs the balance of a product after each of its
+// transactions from the most recent known balance (snapshot or last sync).
+// Card balances follow linked account transactions, which include all movements
+// on the card account. Transactions in other currencies are left out.
+func RunningBalances(database BalanceStore, productID string) (Balances, db.BalancePoint, error) {
+	p, err := database.GetProductByID(productID)
+	if err != nil {
+		return nil, db.BalancePoint{}, err
+	}
+	if p == nil {
+		return nil, db.BalancePoint{}, &product.NotFoundError{ID: productID, Where: "database"}
+	}
+
+	points, err := database.GetBalancePoints(productID)
+	if err != nil {
+		return nil, db.BalancePoint{}, err
+	}
+	if len(points) == 0 {
+		return nil, db.BalancePoint{}, fmt.Errorf("no known balance for product %s", productID)
+	}
+	anchor := points[len(points)-1]
+
+	entries, err := database.GetLedgerEntries(db.LedgerOptions{ProductID: productID, Sources: []string{product.HistorySource(*p)}})
+	if err != nil {
+		return nil, db.BalancePoint{}, err
+	}
+
+	// The balance after each entry is the sum of all entries up to it plus an
+	// offset that makes it match the anchor
+	var beforeAnchor money.Amount
+	for _, e := range entries {
+		if strings.EqualFold(e.Currency, p.Currency) && e.Date.Before(anchor.At) {
+			beforeAnchor += money.FromFloat(e.SignedAmount())
+		}
+	}
+	balance := money.FromFloat(anchor.Balance) - beforeAnchor
+	balances := make(Balances, len(entries))
+	for _, e := range entries {
+		if !strings.EqualFold(e.Currency, p.Currency) {
+			continue
+		}
+		balance += money.FromFloat(e.SignedAmount())
+		balances[BalanceKey{e.ID, e.Date.UTC()}] = balance.Float()
+	}
+	return balances, anchor, nil
+}
@@ -0,0 +1,65 @@
+package report
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestRunningBalances(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	// Current balance 1000 as of the last sync, which is after all transactions
+	if err := database.UpsertProducts([]client.ProductInfo{{ID: "acc1", ProductType: "ACCOUNT", Name: "Savings", Currency: "AMD", Balance: 1000}}); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+
+	date := func(d int) time.Time {
+		return time.Date(2024, 6, d, 12, 0, 0, 0, time.Local)
+	}
+	txns := []client.AccountTransaction{
+		{ID: "t1", FlowDirection: "INCOME", TransactionDate: date(5).UnixMilli(), TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 500}},
+		{ID: "t2", FlowDirection: "EXPENSE", TransactionDate: date(10).UnixMilli(), TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 200}},
+		{ID: "t3", FlowDirection: "EXPENSE", TransactionDate: date(12).UnixMilli(), TransactionAmount: client.TransactionAmt{Currency: "USD", Value: 10}},
+		{ID: "t4", FlowDirection: "EXPENSE", TransactionDate: date(20).UnixMilli(), TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 100}},
+	}
+	if _, err := database.InsertAccountTransactions("acc1", txns); err != nil {
+		t.Fatalf("failed to insert account transactions: %v", err)
+	}
+
+	balances, anchor, err := RunningBalances(database, "acc1")
+	if err != nil {
+		t.Fatalf("RunningBalances failed: %v", err)
+	}
+	if anchor.SnapshotID != 0 || anchor.Balance != 1000 {
+		t.Errorf("expected the last sync as anchor, got %+v", anchor)
+	}
+	for _, tt := range []struct {
+		id   string
+		day  int
+		want float64
+	}{
+		{"t1", 5, 1300},
+		{"t2", 10, 1100},
+		{"t4", 20, 1000},
+	} {
+		got, ok := balances.Lookup(tt.id, date(tt.day))
+		if !ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("balance after %s: expected %v, got %v (found: %v)", tt.id, tt.want, got, ok)
+		}
+	}
+	if _, ok := balances.Lookup("t3", date(12)); ok {
+		t.Error("transactions in other currencies should have no running balance")
+	}
+
+	if _, _, err := RunningBalances(database, "missing"); err == nil {
+		t.Error("expected an error for an unknown product")
+	}
+}
@@ -0,0 +1,97 @@
+package report
+
+import (
+	"flag"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/category"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+var benchTransactions = flag.Int("bench.transactions", 100000, "card transactions in the benchmark database")
+
+var benchEnd = time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+
+var (
+	benchOnce sync.Once
+	benchDB   *db.DB
+	benchErr  error
+)
+
+// benchDatabase returns the shared benchmark database, generating it on first use
+func benchDatabase(b *testing.B) *db.DB {
+	b.Helper()
+	benchOnce.Do(func() {
+		benchDB, benchErr = db.OpenInMemory()
+		if benchErr == nil {
+			benchErr = benchDB.GenerateFixture(db.FixtureOptions{Transactions: *benchTransactions, Seed: 1, End: benchEnd})
+		}
+	})
+	if benchErr != nil {
+		b.Fatalf("failed to generate the benchmark database: %v", benchErr)
+	}
+	return benchDB
+}
+
+// benchEntries returns all ledger entries of the benchmark database
+func benchEntries(b *testing.B) []db.LedgerEntry {
+	b.Helper()
+	entries, err := benchDatabase(b).GetLedgerEntries(db.LedgerOptions{})
+	if err != nil {
+		b.Fatalf("GetLedgerEntries failed: %v", err)
+	}
+	return entries
+}
+
+func BenchmarkRunningBalances(b *testing.B) {
+	database := benchDatabase(b)
+	for b.Loop() {
+		if _, _, err := RunningBalances(database, "card-001"); err != nil {
+			b.Fatalf("RunningBalances failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkPeriod(b *testing.B) {
+	database := benchDatabase(b)
+	from := benchEnd.AddDate(0, -1, 0)
+	for b.Loop() {
+		if _, err := Period(database, "card-001", from, benchEnd); err != nil {
+			b.Fatalf("Period failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkBuildDigest(b *testing.B) {
+	database := benchDatabase(b)
+	month := MonthStart(benchEnd)
+	categorizer := category.Default()
+	for b.Loop() {
+		if _, err := BuildDigest(database, month, categorizer); err != nil {
+			b.Fatalf("BuildDigest failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkMonthly(b *testing.B) {
+	entries := benchEntries(b)
+	for b.Loop() {
+		Monthly(entries)
+	}
+}
+
+func BenchmarkFindRecurring(b *testing.B) {
+	entries := benchEntries(b)
+	for b.Loop() {
+		FindRecurring(entries, benchEnd)
+	}
+}
+
+func BenchmarkFindDuplicates(b *testing.B) {
+	entries := benchEntries(b)
+	for b.Loop() {
+		FindDuplicates(entries, 10*time.Minute)
+	}
+}
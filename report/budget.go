@@ -0,0 +1,42 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// Budget is a budget rule with its months over a period
+type Budget struct {
+	Rule   db.AlertRule     `json:"rule"`
+	Months []db.BudgetMonth `json:"months"` // oldest first
+}
+
+// BudgetStore is what Budgets reads: alert rules and their budget months.
+// *db.DB implements it.
+type BudgetStore interface {
+	GetAlertRules() ([]db.AlertRule, error)
+	GetBudgetMonths(rule db.AlertRule, from, to time.Time, categorize db.CategorizeFunc) ([]db.BudgetMonth, error)
+}
+
+// Budgets returns the months from the month of from to the month of to of
+// every budget rule, in creation order (see db.GetBudgetMonths)
+func Budgets(database BudgetStore, from, to time.Time, categorize db.CategorizeFunc) ([]Budget, error) {
+	rules, err := database.GetAlertRules()
+	if err != nil {
+		return nil, err
+	}
+	budgets := []Budget{}
+	for _, rule := range rules {
+		if rule.Kind != db.AlertBudget {
+			continue
+		}
+		months, err := database.GetBudgetMonths(rule, from, to, categorize)
+		if err != nil {
+			return nil, fmt.Errorf("budget #%d: %w", rule.ID, err)
+		}
+		budgets = append(budgets, Budget{Rule: rule, Months: months})
+	}
+	return budgets, nil
+}
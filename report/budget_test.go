@@ -0,0 +1,45 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestBudgets(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	for _, r := range []db.AlertRule{
+		{Kind: db.AlertTxnAbove, Threshold: 100},
+		{Kind: db.AlertBudget, Threshold: 1000, Currency: "AMD"},
+	} {
+		if _, err := database.AddAlertRule(r); err != nil {
+			t.Fatalf("AddAlertRule failed: %v", err)
+		}
+	}
+	txns := []client.Transaction{
+		{ID: "t1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 300}, OperationDate: "2024-03-10T12:00:00Z"},
+	}
+	if _, err := database.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+
+	from := time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.Local)
+	budgets, err := Budgets(database, from, to, nil)
+	if err != nil {
+		t.Fatalf("Budgets failed: %v", err)
+	}
+	if len(budgets) != 1 || budgets[0].Rule.ID != 2 || len(budgets[0].Months) != 2 {
+		t.Fatalf("expected budget #2 over 2 months, got %+v", budgets)
+	}
+	if m := budgets[0].Months[1]; m.Spent != 300 || m.Left() != 700 {
+		t.Errorf("expected 300 spent and 700 left in March, got %+v", m)
+	}
+}
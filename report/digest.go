@@ -0,0 +1,132 @@
+package report
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/category"
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// digestTopMerchants is the number of merchants listed in a digest
+const digestTopMerchants = 10
+
+// Digest summarizes one calendar month
+type Digest struct {
+	Month        string          `json:"month"` // YYYY-MM
+	Totals       []MonthTotal    `json:"totals"`
+	TopMerchants []SpendingTotal `json:"top_merchants"` // largest first
+	Categories   []SpendingTotal `json:"categories"`    // largest first
+	Balances     []BalanceChange `json:"balances"`
+}
+
+// SpendingTotal is the sum of debits to one merchant or category in one currency
+type SpendingTotal struct {
+	Name     string  `json:"name"`
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+	Count    int     `json:"count"`
+}
+
+// BalanceChange is a product's balance at the start and end of the month
+type BalanceChange struct {
+	ProductName string  `json:"product_name"`
+	Currency    string  `json:"currency"`
+	Opening     float64 `json:"opening"`
+	Closing     float64 `json:"closing"`
+}
+
+// Change returns the closing minus the opening balance
+func (b BalanceChange) Change() float64 {
+	return money.Sub(b.Closing, b.Opening)
+}
+
+// MonthStart returns the first instant of the calendar month containing t, in local time
+func MonthStart(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+}
+
+// DigestStore is what BuildDigest reads: the products and what Period needs
+// for each of them. *db.DB implements it.
+type DigestStore interface {
+	BalanceStore
+	GetProducts() ([]client.ProductInfo, error)
+}
+
+// BuildDigest summarizes the calendar month starting at month (see MonthStart).
+// Balance changes are reconstructed as in Period; products without any known
+// balance are left out.
+func BuildDigest(database DigestStore, month time.Time, categorizer *category.Categorizer) (*Digest, error) {
+	from := MonthStart(month)
+	to := from.AddDate(0, 1, 0)
+
+	entries, err := database.GetLedgerEntries(db.LedgerOptions{From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Digest{
+		Month:  from.Format("2006-01"),
+		Totals: Monthly(entries),
+	}
+	d.TopMerchants = spendingTotals(entries, func(e db.LedgerEntry) string { return e.Counterparty })
+	if len(d.TopMerchants) > digestTopMerchants {
+		d.TopMerchants = d.TopMerchants[:digestTopMerchants]
+	}
+	d.Categories = spendingTotals(entries, categorizer.Categorize)
+
+	products, err := database.GetProducts()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		st, err := Period(database, p.ID, from, to)
+		if err != nil {
+			continue
+		}
+		d.Balances = append(d.Balances, BalanceChange{
+			ProductName: p.Name,
+			Currency:    st.Currency,
+			Opening:     st.OpeningBalance,
+			Closing:     st.ClosingBalance,
+		})
+	}
+	return d, nil
+}
+
+// spendingTotals sums debits per name (as returned by nameOf) and currency, largest first.
+// Names are grouped case-insensitively; the first spelling seen is kept.
+func spendingTotals(entries []db.LedgerEntry, nameOf func(db.LedgerEntry) string) []SpendingTotal {
+	type key struct{ name, currency string }
+	totals := make(map[key]*SpendingTotal)
+	for _, e := range entries {
+		name := strings.TrimSpace(nameOf(e))
+		if e.Credit || name == "" {
+			continue
+		}
+		k := key{strings.ToLower(name), e.Currency}
+		t, ok := totals[k]
+		if !ok {
+			t = &SpendingTotal{Name: name, Currency: e.Currency}
+			totals[k] = t
+		}
+		t.Amount = money.Add(t.Amount, e.Amount)
+		t.Count++
+	}
+
+	result := make([]SpendingTotal, 0, len(totals))
+	for _, t := range totals {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Amount != result[j].Amount {
+			return result[i].Amount > result[j].Amount
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,85 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/ivan4th/ameriagrab/i18n"
+	"github.com/ivan4th/ameriagrab/textwidth"
+)
+
+// Text renders the digest as plain text
+func (d *Digest) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, i18n.T("Monthly digest for %s")+"\n", d.Month)
+
+	fmt.Fprintf(&b, "\n%s\n", i18n.T("Summary"))
+	if len(d.Totals) == 0 {
+		fmt.Fprintf(&b, "  %s\n", i18n.T("No transactions"))
+	}
+	for _, t := range d.Totals {
+		fmt.Fprintf(&b, "  %s: %s %.2f, %s %.2f, %s %+.2f\n", t.Currency,
+			i18n.T("income"), t.Income, i18n.T("expenses"), t.Expenses, i18n.T("net"), t.Net())
+	}
+
+	writeSpending := func(title string, totals []SpendingTotal) {
+		if len(totals) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s\n", i18n.T(title))
+		for _, t := range totals {
+			fmt.Fprintf(&b, "  %s %12.2f %s  (%d)\n", textwidth.PadRight(t.Name, 30), t.Amount, t.Currency, t.Count)
+		}
+	}
+	writeSpending("Top merchants", d.TopMerchants)
+	writeSpending("Categories", d.Categories)
+
+	if len(d.Balances) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", i18n.T("Balances"))
+		for _, bc := range d.Balances {
+			fmt.Fprintf(&b, "  %s %12.2f -> %12.2f %s  (%+.2f)\n", textwidth.PadRight(bc.ProductName, 30), bc.Opening, bc.Closing, bc.Currency, bc.Change())
+		}
+	}
+	return b.String()
+}
+
+var digestTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"amount": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"signed": func(v float64) string { return fmt.Sprintf("%+.2f", v) },
+	"t":      i18n.T,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{printf (t "Monthly digest for %s") .Month}}</title></head>
+<body style="font-family: sans-serif; color: #222;">
+<h2>{{printf (t "Monthly digest for %s") .Month}}</h2>
+<h3>{{t "Summary"}}</h3>
+{{if .Totals}}<table cellpadding="4">
+<tr><th align="left">{{t "Currency"}}</th><th align="right">{{t "Income"}}</th><th align="right">{{t "Expenses"}}</th><th align="right">{{t "Net"}}</th></tr>
+{{range .Totals}}<tr><td>{{.Currency}}</td><td align="right">{{amount .Income}}</td><td align="right">{{amount .Expenses}}</td><td align="right">{{signed .Net}}</td></tr>
+{{end}}</table>{{else}}<p>{{t "No transactions"}}</p>{{end}}
+{{if .TopMerchants}}<h3>{{t "Top merchants"}}</h3>
+<table cellpadding="4">
+{{range .TopMerchants}}<tr><td>{{.Name}}</td><td align="right">{{amount .Amount}} {{.Currency}}</td><td align="right">{{.Count}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Categories}}<h3>{{t "Categories"}}</h3>
+<table cellpadding="4">
+{{range .Categories}}<tr><td>{{.Name}}</td><td align="right">{{amount .Amount}} {{.Currency}}</td><td align="right">{{.Count}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Balances}}<h3>{{t "Balances"}}</h3>
+<table cellpadding="4">
+<tr><th align="left">{{t "Product"}}</th><th align="right">{{t "Opening"}}</th><th align="right">{{t "Closing"}}</th><th align="right">{{t "Change"}}</th></tr>
+{{range .Balances}}<tr><td>{{.ProductName}}</td><td align="right">{{amount .Opening}}</td><td align="right">{{amount .Closing}} {{.Currency}}</td><td align="right">{{signed .Change}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
+
+// HTML renders the digest as an HTML document suitable for email
+func (d *Digest) HTML() (string, error) {
+	var b strings.Builder
+	if err := digestTemplate.Execute(&b, d); err != nil {
+		return "", fmt.Errorf("rendering digest: %w", err)
+	}
+	return b.String(), nil
+}
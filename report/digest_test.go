@@ -0,0 +1,82 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/category"
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestBuildDigest(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.UpsertProducts([]client.ProductInfo{{ID: "acc1", ProductType: "ACCOUNT", Name: "Current", Currency: "AMD", Balance: 10000}}); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+	ms := func(m time.Month, d int) int64 {
+		return time.Date(2024, m, d, 12, 0, 0, 0, time.Local).UnixMilli()
+	}
+	txn := func(id, direction string, date int64, amount float64, beneficiary string) client.AccountTransaction {
+		return client.AccountTransaction{ID: id, FlowDirection: direction, TransactionDate: date, BeneficiaryName: beneficiary,
+			TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: amount}}
+	}
+	txns := []client.AccountTransaction{
+		txn("t1", "INCOME", ms(6, 1), 5000, "Employer"),
+		txn("t2", "EXPENSE", ms(6, 3), 700, "Yerevan City"),
+		txn("t3", "EXPENSE", ms(6, 9), 300, "YEREVAN CITY"),
+		txn("t4", "EXPENSE", ms(6, 15), 1500, "Tashir Pizza"),
+		txn("t5", "EXPENSE", ms(6, 20), 200, "John Smith"),
+		txn("t6", "EXPENSE", ms(7, 2), 400, "Yerevan City"),
+	}
+	if _, err := database.InsertAccountTransactions("acc1", txns); err != nil {
+		t.Fatalf("failed to insert account transactions: %v", err)
+	}
+
+	d, err := BuildDigest(database, time.Date(2024, 6, 17, 0, 0, 0, 0, time.Local), category.Default())
+	if err != nil {
+		t.Fatalf("BuildDigest failed: %v", err)
+	}
+
+	if d.Month != "2024-06" || len(d.Totals) != 1 || d.Totals[0].Income != 5000 || d.Totals[0].Expenses != 2700 {
+		t.Errorf("unexpected month totals %s %+v", d.Month, d.Totals)
+	}
+	if len(d.TopMerchants) != 3 || d.TopMerchants[0].Name != "Tashir Pizza" ||
+		d.TopMerchants[1] != (SpendingTotal{Name: "Yerevan City", Currency: "AMD", Amount: 1000, Count: 2}) {
+		t.Errorf("unexpected top merchants %+v", d.TopMerchants)
+	}
+	wantCategories := []SpendingTotal{
+		{Name: "restaurants", Currency: "AMD", Amount: 1500, Count: 1},
+		{Name: "groceries", Currency: "AMD", Amount: 1000, Count: 2},
+		{Name: category.Other, Currency: "AMD", Amount: 200, Count: 1},
+	}
+	if len(d.Categories) != len(wantCategories) {
+		t.Fatalf("unexpected categories %+v", d.Categories)
+	}
+	for i := range wantCategories {
+		if d.Categories[i] != wantCategories[i] {
+			t.Errorf("category %d: expected %+v, got %+v", i, wantCategories[i], d.Categories[i])
+		}
+	}
+	// Current balance 10000 includes the July debit of 400
+	if len(d.Balances) != 1 || d.Balances[0].Opening != 8100 || d.Balances[0].Closing != 10400 {
+		t.Errorf("unexpected balances %+v", d.Balances)
+	}
+
+	if text := d.Text(); !strings.Contains(text, "AMD: income 5000.00, expenses 2700.00, net +2300.00") {
+		t.Errorf("unexpected text digest:\n%s", text)
+	}
+	html, err := d.HTML()
+	if err != nil {
+		t.Fatalf("HTML failed: %v", err)
+	}
+	if !strings.Contains(html, "<td>Tashir Pizza</td>") || !strings.Contains(html, "10400.00 AMD") {
+		t.Errorf("unexpected HTML digest:\n%s", html)
+	}
+}
@@ -0,0 +1,144 @@
+// Package report builds reports over the normalized transaction ledger
+package report
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// DuplicateGroup is a set of debits that look like the same charge applied more than once
+type DuplicateGroup struct {
+	ProductID    string           `json:"product_id"`
+	Counterparty string           `json:"counterparty"`
+	Amount       float64          `json:"amount"`
+	Currency     string           `json:"currency"`
+	Entries      []db.LedgerEntry `json:"entries"` // oldest first
+}
+
+// Extra returns the amount charged beyond the first charge
+func (g DuplicateGroup) Extra() float64 {
+	return (money.FromFloat(g.Amount) * money.Amount(len(g.Entries)-1)).Float()
+}
+
+// ConvertDuplicates converts the charges of each group to currency at the
+// exchange rates of their dates, keeping the original amounts. Groups in
+// currencies without a rate are left as they are.
+func ConvertDuplicates(groups []DuplicateGroup, rates *db.RateHistory, currency string) {
+	for i := range groups {
+		g := &groups[i]
+		if rates.ConvertEntries(g.Entries, currency) > 0 || len(g.Entries) == 0 {
+			continue
+		}
+		g.Amount, g.Currency = g.Entries[0].Amount, g.Entries[0].Currency
+	}
+}
+
+// FindDuplicates groups debits with the same product, counterparty, amount and currency
+// occurring within window of each other. Entries sharing a transaction ID are the same
+// transaction stored under several dates (composite-key rows) and are not reported.
+// Card and linked account tables mirror each other, so linked groups that match a card
+// group are dropped.
+func FindDuplicates(entries []db.LedgerEntry, window time.Duration) []DuplicateGroup {
+	type groupKey struct {
+		source, productID, counterparty, currency string
+		amount                                    float64
+	}
+
+	sorted := make([]db.LedgerEntry, 0, len(entries))
+	for _, e := range entries {
+		if !e.Credit && e.Amount > 0 {
+			sorted = append(sorted, e)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	byKey := make(map[groupKey][]db.LedgerEntry)
+	var keys []groupKey
+	for _, e := range sorted {
+		k := groupKey{e.Source, e.ProductID, strings.ToLower(strings.TrimSpace(e.Counterparty)), strings.ToUpper(e.Currency), e.Amount}
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], e)
+	}
+
+	var cardGroups, otherGroups []DuplicateGroup
+	for _, k := range keys {
+		for _, run := range splitRuns(byKey[k], window) {
+			g := DuplicateGroup{
+				ProductID:    k.productID,
+				Counterparty: run[0].Counterparty,
+				Amount:       k.amount,
+				Currency:     k.currency,
+				Entries:      run,
+			}
+			if k.source == db.SourceCard {
+				cardGroups = append(cardGroups, g)
+			} else {
+				otherGroups = append(otherGroups, g)
+			}
+		}
+	}
+
+	groups := cardGroups
+	for _, g := range otherGroups {
+		if g.Entries[0].Source != db.SourceLinked || !mirrorsAny(g, cardGroups, window) {
+			groups = append(groups, g)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Entries[0].Date.Before(groups[j].Entries[0].Date)
+	})
+	return groups
+}
+
+// splitRuns splits date-sorted entries into runs where consecutive entries are within window,
+// keeping only runs with at least two distinct transaction IDs
+func splitRuns(entries []db.LedgerEntry, window time.Duration) [][]db.LedgerEntry {
+	var runs [][]db.LedgerEntry
+	var run []db.LedgerEntry
+	seen := make(map[string]bool)
+
+	flush := func() {
+		if len(run) > 1 {
+			runs = append(runs, run)
+		}
+		run = nil
+		seen = make(map[string]bool)
+	}
+
+	for _, e := range entries {
+		if len(run) > 0 && e.Date.Sub(run[len(run)-1].Date) > window {
+			flush()
+		}
+		if seen[e.ID] {
+			continue
+		}
+		seen[e.ID] = true
+		run = append(run, e)
+	}
+	flush()
+	return runs
+}
+
+// mirrorsAny returns true if a card group describes the same charges as g
+func mirrorsAny(g DuplicateGroup, cardGroups []DuplicateGroup, window time.Duration) bool {
+	for _, c := range cardGroups {
+		if c.ProductID != g.ProductID || c.Currency != g.Currency || math.Abs(c.Amount-g.Amount) > 0.005 {
+			continue
+		}
+		gap := g.Entries[0].Date.Sub(c.Entries[0].Date)
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap <= window {
+			return true
+		}
+	}
+	return false
+}
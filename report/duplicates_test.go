@@ -0,0 +1,65 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func debit(source, id string, at time.Time, amount float64, counterparty string) db.LedgerEntry {
+	return db.LedgerEntry{
+		Source:       source,
+		ProductID:    "card1",
+		ID:           id,
+		Date:         at,
+		Amount:       amount,
+		Currency:     "AMD",
+		Counterparty: counterparty,
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	entries := []db.LedgerEntry{
+		// Double charge on the card, mirrored in the linked account table
+		debit(db.SourceCard, "c1", base, 5000, "SHOP"),
+		debit(db.SourceCard, "c2", base.Add(10*time.Minute), 5000, "Shop"),
+		debit(db.SourceLinked, "l1", base.Add(time.Hour), 5000, "Account transfer"),
+		debit(db.SourceLinked, "l2", base.Add(2*time.Hour), 5000, "Account transfer"),
+		// Same merchant and amount, but days apart
+		debit(db.SourceCard, "c3", base.AddDate(0, 0, 3), 5000, "Shop"),
+		// Same transaction ID under a different date (composite key)
+		debit(db.SourceCard, "c4", base.AddDate(0, 0, 10), 800, "Cafe"),
+		debit(db.SourceCard, "c4", base.AddDate(0, 0, 10).Add(time.Hour), 800, "Cafe"),
+		// Refund of the same amount is not a charge
+		{Source: db.SourceCard, ProductID: "card1", ID: "c5", Date: base.Add(20 * time.Minute), Credit: true, Amount: 5000, Currency: "AMD", Counterparty: "Shop"},
+	}
+
+	groups := FindDuplicates(entries, 24*time.Hour)
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 duplicate group, got %d: %+v", len(groups), groups)
+	}
+	g := groups[0]
+	if len(g.Entries) != 2 || g.Entries[0].ID != "c1" || g.Entries[1].ID != "c2" {
+		t.Errorf("unexpected group entries: %+v", g.Entries)
+	}
+	if g.Extra() != 5000 {
+		t.Errorf("expected extra 5000, got %v", g.Extra())
+	}
+}
+
+func TestFindDuplicates_LinkedOnly(t *testing.T) {
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	entries := []db.LedgerEntry{
+		debit(db.SourceLinked, "l1", base, 20000, "Utility Co"),
+		debit(db.SourceLinked, "l2", base.Add(3*time.Hour), 20000, "Utility Co"),
+	}
+
+	if groups := FindDuplicates(entries, 2*time.Hour); len(groups) != 0 {
+		t.Errorf("expected no groups outside window, got %+v", groups)
+	}
+	if groups := FindDuplicates(entries, 4*time.Hour); len(groups) != 1 {
+		t.Errorf("expected linked-only duplicates to be reported, got %+v", groups)
+	}
+}
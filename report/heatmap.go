@@ -0,0 +1,91 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// HeatmapLevels is the number of spending intensity levels above none
+const HeatmapLevels = 4
+
+// HeatmapDay is the spending in one currency during a calendar day
+type HeatmapDay struct {
+	Date   time.Time `json:"date"` // start of the day in local time
+	Amount float64   `json:"amount"`
+	Count  int       `json:"count"`
+	Level  int       `json:"level"` // 0 for no spending, up to HeatmapLevels
+}
+
+// Heatmap is the daily spending in one currency over a period
+type Heatmap struct {
+	Currency string       `json:"currency"`
+	Total    float64      `json:"total"`
+	Days     []HeatmapDay `json:"days"` // every day of the period, oldest first
+}
+
+// Heatmaps sums debits per day and currency for each day from from
+// (inclusive) to to (exclusive), one heatmap per currency sorted by currency.
+// Currency exchanges aren't spending and are left out. A day's level is its
+// rank among the days with spending, in quarters, so that a few large
+// purchases don't wash out the rest.
+func Heatmaps(entries []db.LedgerEntry, from, to time.Time) []Heatmap {
+	from, to = startOfDay(from), startOfDay(to)
+	type key struct {
+		day      time.Time
+		currency string
+	}
+	days := make(map[key]*HeatmapDay)
+	currencies := make(map[string]bool)
+	for _, e := range entries {
+		if e.Credit || e.IsExchange() || e.Date.Before(from) || !e.Date.Before(to) {
+			continue
+		}
+		k := key{startOfDay(e.Date), e.Currency}
+		d, ok := days[k]
+		if !ok {
+			d = &HeatmapDay{Date: k.day}
+			days[k] = d
+		}
+		d.Amount = money.Add(d.Amount, e.Amount)
+		d.Count++
+		currencies[e.Currency] = true
+	}
+
+	var heatmaps []Heatmap
+	for currency := range currencies {
+		h := Heatmap{Currency: currency}
+		var amounts []float64
+		for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+			d := HeatmapDay{Date: day}
+			if spent, ok := days[key{day, currency}]; ok {
+				d = *spent
+				h.Total = money.Add(h.Total, d.Amount)
+			}
+			if d.Amount > 0 {
+				amounts = append(amounts, d.Amount)
+			}
+			h.Days = append(h.Days, d)
+		}
+		sort.Float64s(amounts)
+		for i := range h.Days {
+			h.Days[i].Level = heatmapLevel(amounts, h.Days[i].Amount)
+		}
+		heatmaps = append(heatmaps, h)
+	}
+	sort.Slice(heatmaps, func(i, j int) bool { return heatmaps[i].Currency < heatmaps[j].Currency })
+	return heatmaps
+}
+
+// heatmapLevel returns the level of a day's spending given the sorted
+// spending of all days with any
+func heatmapLevel(sorted []float64, amount float64) int {
+	if amount <= 0 {
+		return 0
+	}
+	// Days spending the same share the higher rank
+	rank := sort.Search(len(sorted), func(i int) bool { return sorted[i] > amount })
+	return (rank*HeatmapLevels + len(sorted) - 1) / len(sorted)
+}
@@ -0,0 +1,51 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestHeatmaps(t *testing.T) {
+	at := func(d, h int) time.Time { return time.Date(2024, 6, d, h, 0, 0, 0, time.Local) }
+	entries := []db.LedgerEntry{
+		{Date: at(3, 10), Amount: 100, Currency: "AMD"},
+		{Date: at(3, 18), Amount: 50, Currency: "AMD"},
+		{Date: at(4, 12), Amount: 1000, Currency: "AMD"},
+		{Date: at(5, 12), Amount: 10, Currency: "AMD"},
+		{Date: at(6, 12), Amount: 150, Currency: "AMD"},
+		{Date: at(5, 9), Credit: true, Amount: 5000, Currency: "AMD"},
+		{Date: at(5, 9), Amount: 400, Currency: "AMD", Type: "Currency exchange"},
+		{Date: at(6, 9), Amount: 5, Currency: "USD"},
+		{Date: at(9, 9), Amount: 7, Currency: "USD"}, // after the period
+	}
+
+	heatmaps := Heatmaps(entries, at(3, 0), at(8, 0))
+	if len(heatmaps) != 2 || heatmaps[0].Currency != "AMD" || heatmaps[1].Currency != "USD" {
+		t.Fatalf("unexpected heatmaps: %+v", heatmaps)
+	}
+
+	amd := heatmaps[0]
+	if amd.Total != 1310 || len(amd.Days) != 5 {
+		t.Fatalf("unexpected AMD heatmap: %+v", amd)
+	}
+	want := []HeatmapDay{
+		{Date: at(3, 0), Amount: 150, Count: 2, Level: 3},
+		{Date: at(4, 0), Amount: 1000, Count: 1, Level: 4},
+		{Date: at(5, 0), Amount: 10, Count: 1, Level: 1},
+		{Date: at(6, 0), Amount: 150, Count: 1, Level: 3},
+		{Date: at(7, 0)},
+	}
+	for i := range want {
+		if got := amd.Days[i]; !got.Date.Equal(want[i].Date) || got.Amount != want[i].Amount ||
+			got.Count != want[i].Count || got.Level != want[i].Level {
+			t.Errorf("day %d: expected %+v, got %+v", i, want[i], got)
+		}
+	}
+
+	// A single day with spending gets the top level
+	if usd := heatmaps[1]; usd.Total != 5 || usd.Days[3].Level != HeatmapLevels {
+		t.Errorf("unexpected USD heatmap: %+v", usd)
+	}
+}
@@ -0,0 +1,185 @@
+package report
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// OwnTransferWindow is how far apart the two sides of a transfer between own
+// products may be dated
+const OwnTransferWindow = 3 * 24 * time.Hour
+
+// salaryMinShare is the share of the average monthly income in its currency a
+// monthly credit must reach to be considered salary-like
+const salaryMinShare = 0.2
+
+// IncomeSource is a counterparty crediting a similar, large amount about
+// monthly: a salary, pension or rent received
+type IncomeSource struct {
+	Counterparty string    `json:"counterparty"`
+	Currency     string    `json:"currency"`
+	Amount       float64   `json:"amount"` // median of the credits
+	Count        int       `json:"count"`
+	LastDate     time.Time `json:"last_date"`
+}
+
+// MonthSavings is the income and spending in one currency during a calendar
+// month, own transfers and currency exchanges left out
+type MonthSavings struct {
+	Month    string  `json:"month"` // YYYY-MM
+	Currency string  `json:"currency"`
+	Income   float64 `json:"income"`
+	Salary   float64 `json:"salary"` // the part of Income from salary-like sources
+	Expenses float64 `json:"expenses"`
+}
+
+// Savings returns income minus expenses
+func (m MonthSavings) Savings() float64 {
+	return money.Sub(m.Income, m.Expenses)
+}
+
+// Rate returns the savings as a share of income, negative when more was
+// spent than earned, and false for a month without income
+func (m MonthSavings) Rate() (float64, bool) {
+	if m.Income <= 0 {
+		return 0, false
+	}
+	return m.Savings() / m.Income, true
+}
+
+// IncomeReport is the salary-like income sources and the savings of each month
+type IncomeReport struct {
+	Sources []IncomeSource `json:"sources"` // largest first
+	Months  []MonthSavings `json:"months"`  // by month then currency
+}
+
+// Income finds salary-like income among entries and sums income and expenses
+// per month and currency. Own transfers (see OwnTransfers) are neither income
+// nor expenses. Salary-like sources are counterparties crediting amounts
+// recurring monthly (as FindRecurring detects for debits) whose median is at
+// least a fifth of the average monthly income in their currency.
+func Income(entries []db.LedgerEntry) IncomeReport {
+	own := OwnTransfers(entries)
+
+	type monthKey struct{ month, currency string }
+	months := make(map[monthKey]*MonthSavings)
+	type sourceKey struct{ counterparty, currency string }
+	credits := make(map[sourceKey][]db.LedgerEntry)
+	monthsSeen := make(map[string]bool)
+	income := make(map[string]float64)
+	for i, e := range entries {
+		month := e.Date.Local().Format("2006-01")
+		monthsSeen[month] = true
+		if own[i] {
+			continue
+		}
+		k := monthKey{month, e.Currency}
+		m, ok := months[k]
+		if !ok {
+			m = &MonthSavings{Month: k.month, Currency: k.currency}
+			months[k] = m
+		}
+		if !e.Credit {
+			m.Expenses = money.Add(m.Expenses, e.Amount)
+			continue
+		}
+		m.Income = money.Add(m.Income, e.Amount)
+		income[e.Currency] = money.Add(income[e.Currency], e.Amount)
+		if counterparty := strings.ToLower(strings.TrimSpace(e.Counterparty)); counterparty != "" && e.Amount > 0 {
+			sk := sourceKey{counterparty, e.Currency}
+			credits[sk] = append(credits[sk], e)
+		}
+	}
+
+	var report IncomeReport
+	salaried := make(map[sourceKey]bool)
+	for k, group := range credits {
+		sort.SliceStable(group, func(i, j int) bool { return group[i].Date.Before(group[j].Date) })
+		p, ok := detectRecurrence(dedupeDays(group))
+		if !ok || p.Period != PeriodMonthly || p.Amount < salaryMinShare*income[k.currency]/float64(len(monthsSeen)) {
+			continue
+		}
+		salaried[k] = true
+		report.Sources = append(report.Sources, IncomeSource{
+			Counterparty: p.Counterparty,
+			Currency:     k.currency,
+			Amount:       p.Amount,
+			Count:        p.Count,
+			LastDate:     p.LastDate,
+		})
+	}
+	sort.Slice(report.Sources, func(i, j int) bool {
+		a, b := report.Sources[i], report.Sources[j]
+		if a.Amount != b.Amount {
+			return a.Amount > b.Amount
+		}
+		return a.Counterparty < b.Counterparty
+	})
+
+	for k, group := range credits {
+		if !salaried[k] {
+			continue
+		}
+		for _, e := range group {
+			m := months[monthKey{e.Date.Local().Format("2006-01"), e.Currency}]
+			m.Salary = money.Add(m.Salary, e.Amount)
+		}
+	}
+
+	report.Months = make([]MonthSavings, 0, len(months))
+	for _, m := range months {
+		report.Months = append(report.Months, *m)
+	}
+	sort.Slice(report.Months, func(i, j int) bool {
+		if report.Months[i].Month != report.Months[j].Month {
+			return report.Months[i].Month < report.Months[j].Month
+		}
+		return report.Months[i].Currency < report.Months[j].Currency
+	})
+	return report
+}
+
+// OwnTransfers returns the indices of entries moving money between own
+// products: currency exchanges, and debits paired with a credit of the same
+// amount and currency on another product within OwnTransferWindow. Each credit
+// pairs with at most one debit, the nearest in time.
+func OwnTransfers(entries []db.LedgerEntry) map[int]bool {
+	own := make(map[int]bool)
+	type key struct {
+		currency string
+		amount   money.Amount
+	}
+	credits := make(map[key][]int)
+	for i, e := range entries {
+		if e.IsExchange() {
+			own[i] = true
+		} else if e.Credit {
+			k := key{strings.ToUpper(e.Currency), money.FromFloat(e.Amount)}
+			credits[k] = append(credits[k], i)
+		}
+	}
+	for i, e := range entries {
+		if e.Credit || own[i] {
+			continue
+		}
+		best := -1
+		var bestGap time.Duration
+		for _, j := range credits[key{strings.ToUpper(e.Currency), money.FromFloat(e.Amount)}] {
+			gap := absDuration(entries[j].Date.Sub(e.Date))
+			if own[j] || entries[j].ProductID == e.ProductID || gap > OwnTransferWindow {
+				continue
+			}
+			if best < 0 || gap < bestGap {
+				best, bestGap = j, gap
+			}
+		}
+		if best >= 0 {
+			own[i], own[best] = true, true
+		}
+	}
+	return own
+}
@@ -0,0 +1,64 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestIncome(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 12, 0, 0, 0, time.Local) }
+	credit := func(product, counterparty string, amount float64, date time.Time) db.LedgerEntry {
+		return db.LedgerEntry{ProductID: product, Credit: true, Amount: amount, Currency: "AMD", Counterparty: counterparty, Date: date}
+	}
+	debit := func(product, counterparty string, amount float64, date time.Time) db.LedgerEntry {
+		return db.LedgerEntry{ProductID: product, Amount: amount, Currency: "AMD", Counterparty: counterparty, Date: date}
+	}
+	var entries []db.LedgerEntry
+	for m := time.January; m <= time.March; m++ {
+		entries = append(entries,
+			credit("acc1", "Employer LLC", 500000+float64(m)*1000, day(m, 10)),
+			credit("card1", "Cashback", 1000, day(m, 1)),
+			debit("card1", "Market", 300000, day(m, 15)),
+		)
+	}
+	entries = append(entries,
+		// Moving money from the account to the card is not spending
+		debit("acc1", "", 100000, day(time.February, 20)),
+		credit("card1", "", 100000, day(time.February, 21)),
+		// Nor is a currency exchange
+		db.LedgerEntry{ProductID: "acc1", Amount: 40000, Currency: "AMD", Details: "Currency exchange", Date: day(time.March, 2)},
+		// A refund on the same product isn't an own transfer
+		debit("card1", "Shop", 5000, day(time.March, 3)),
+		credit("card1", "Shop", 5000, day(time.March, 4)),
+	)
+
+	r := Income(entries)
+	if len(r.Sources) != 1 {
+		t.Fatalf("expected 1 salary-like source, got %+v", r.Sources)
+	}
+	if s := r.Sources[0]; s.Counterparty != "Employer LLC" || s.Amount != 502000 || s.Count != 3 {
+		t.Errorf("unexpected salary-like source %+v", s)
+	}
+
+	want := []MonthSavings{
+		{Month: "2024-01", Currency: "AMD", Income: 502000, Salary: 501000, Expenses: 300000},
+		{Month: "2024-02", Currency: "AMD", Income: 503000, Salary: 502000, Expenses: 300000},
+		{Month: "2024-03", Currency: "AMD", Income: 509000, Salary: 503000, Expenses: 305000},
+	}
+	if len(r.Months) != len(want) {
+		t.Fatalf("expected %d months, got %+v", len(want), r.Months)
+	}
+	for i, m := range r.Months {
+		if m != want[i] {
+			t.Errorf("month %d: expected %+v, got %+v", i, want[i], m)
+		}
+	}
+	if rate, ok := r.Months[2].Rate(); !ok || rate != 204000.0/509000 {
+		t.Errorf("unexpected savings rate %v, %v", rate, ok)
+	}
+	if _, ok := (MonthSavings{Expenses: 100}).Rate(); ok {
+		t.Error("expected no savings rate for a month without income")
+	}
+}
@@ -0,0 +1,52 @@
+package report
+
+import (
+	"sort"
+
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/money"
+)
+
+// MonthTotal is the income and spending in one currency during a calendar month
+type MonthTotal struct {
+	Month    string  `json:"month"` // YYYY-MM
+	Currency string  `json:"currency"`
+	Income   float64 `json:"income"`
+	Expenses float64 `json:"expenses"`
+}
+
+// Net returns income minus expenses
+func (m MonthTotal) Net() float64 {
+	return money.Sub(m.Income, m.Expenses)
+}
+
+// Monthly sums entries per month and currency, sorted by month then currency
+func Monthly(entries []db.LedgerEntry) []MonthTotal {
+	type key struct{ month, currency string }
+	totals := make(map[key]*MonthTotal)
+	for _, e := range entries {
+		k := key{e.Date.Local().Format("2006-01"), e.Currency}
+		t, ok := totals[k]
+		if !ok {
+			t = &MonthTotal{Month: k.month, Currency: k.currency}
+			totals[k] = t
+		}
+		if e.Credit {
+			t.Income = money.Add(t.Income, e.Amount)
+		} else {
+			t.Expenses = money.Add(t.Expenses, e.Amount)
+		}
+	}
+
+	result := make([]MonthTotal, 0, len(totals))
+	for _, t := range totals {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Month != result[j].Month {
+			return result[i].Month < result[j].Month
+		}
+		return result[i].Currency < result[j].Currency
+	})
+	return result
+}
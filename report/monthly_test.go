@@ -0,0 +1,74 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestMonthly(t *testing.T) {
+	at := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 12, 0, 0, 0, time.Local) }
+	entries := []db.LedgerEntry{
+		{Date: at(6, 1), Credit: true, Amount: 1000, Currency: "AMD"},
+		{Date: at(6, 2), Amount: 300, Currency: "AMD"},
+		{Date: at(6, 3), Amount: 10, Currency: "USD"},
+		{Date: at(5, 20), Amount: 50, Currency: "AMD"},
+	}
+
+	got := Monthly(entries)
+	want := []MonthTotal{
+		{Month: "2024-05", Currency: "AMD", Expenses: 50},
+		{Month: "2024-06", Currency: "AMD", Income: 1000, Expenses: 300},
+		{Month: "2024-06", Currency: "USD", Expenses: 10},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d totals, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("total %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+	if got[1].Net() != 700 {
+		t.Errorf("expected net 700, got %v", got[1].Net())
+	}
+}
+
+func TestMonthly_CountsSettledPurchaseOnce(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	txn := func(id, txnType, date string) client.Transaction {
+		return client.Transaction{
+			ID: id, TransactionType: txnType, AccountingType: "DEBIT", State: "SETTLED",
+			Amount: client.Amount{Currency: "AMD", Amount: 1000}, Details: "SHOP", OperationDate: date,
+		}
+	}
+	txns := []client.Transaction{
+		txn("a1", "pre-purchase:pos", "2024-06-10T10:00:00Z"),
+		txn("p1", "purchase:pos", "2024-06-12T10:00:00Z"),
+		txn("a2", "pre-purchase:pos", "2024-06-20T10:00:00Z"), // not settled yet
+	}
+	if _, err := database.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+	if linked, err := database.LinkCardTxnStages("card1"); err != nil || linked != 1 {
+		t.Fatalf("expected the purchase to be linked to its authorization, got %d, %v", linked, err)
+	}
+
+	entries, err := database.GetLedgerEntries(db.LedgerOptions{})
+	if err != nil {
+		t.Fatalf("GetLedgerEntries failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != "p1" || entries[1].ID != "a2" {
+		t.Fatalf("expected the settling purchase and the open authorization, got %+v", entries)
+	}
+	if got := Monthly(entries); len(got) != 1 || got[0].Expenses != 2000 {
+		t.Errorf("expected 2000 spent in June, got %+v", got)
+	}
+}
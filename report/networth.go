@@ -0,0 +1,84 @@
+package report
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/money"
+	"github.com/ivan4th/ameriagrab/product"
+)
+
+// ConvertFunc converts an amount from a currency at the exchange rate of the
+// given time, returning false when no rate is known (see db.RateHistory)
+type ConvertFunc func(amount float64, from string, at time.Time) (float64, bool)
+
+// NetWorthPoint is the total balance of all products at one point in time,
+// converted to one currency
+type NetWorthPoint struct {
+	Date  time.Time `json:"date"`
+	Total float64   `json:"total"`
+	// Missing lists the currencies left out for lack of an exchange rate
+	Missing []string `json:"missing_rates,omitempty"`
+}
+
+// NetWorth sums the available balances of products at the given time,
+// converted with convert. Cards in sharedCards are skipped, as their balance
+// is their linked account's (see SharedBalanceCards).
+func NetWorth(products []client.ProductInfo, sharedCards map[string]bool, convert ConvertFunc, at time.Time) NetWorthPoint {
+	point := NetWorthPoint{Date: at}
+	missing := make(map[string]bool)
+	for _, p := range products {
+		if sharedCards[p.ID] {
+			continue
+		}
+		value, ok := convert(p.AvailableBalance, p.Currency, at)
+		if !ok {
+			missing[strings.ToUpper(p.Currency)] = true
+			continue
+		}
+		point.Total = money.Add(point.Total, value)
+	}
+	for currency := range missing {
+		point.Missing = append(point.Missing, currency)
+	}
+	sort.Strings(point.Missing)
+	return point
+}
+
+// SharedBalanceCards returns the IDs of cards whose linked account is one of
+// products, so that their shared balance is counted once, like
+// db.GetCurrencyPositions does
+func SharedBalanceCards(products []client.ProductInfo) map[string]bool {
+	accounts := make(map[string]bool)
+	for _, p := range products {
+		if !product.IsCard(p) {
+			accounts[p.ID] = true
+		}
+	}
+	shared := make(map[string]bool)
+	for _, p := range products {
+		if product.IsCard(p) && accounts[p.AccountID] {
+			shared[p.ID] = true
+		}
+	}
+	return shared
+}
+
+// NetWorthHistory returns the net worth at each snapshot, oldest first,
+// followed by the current one from products (as of the last sync) at now.
+// Snapshots don't record linked accounts, so the cards sharing a balance are
+// taken from the current products.
+func NetWorthHistory(snapshots []db.Snapshot, products []client.ProductInfo, convert ConvertFunc, now time.Time) []NetWorthPoint {
+	shared := SharedBalanceCards(products)
+	var points []NetWorthPoint
+	for _, s := range snapshots {
+		points = append(points, NetWorth(s.Products, shared, convert, s.CreatedAt))
+	}
+	if len(products) > 0 {
+		points = append(points, NetWorth(products, shared, convert, now))
+	}
+	return points
+}
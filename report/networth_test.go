@@ -0,0 +1,54 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestNetWorthHistory(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 6, d, 12, 0, 0, 0, time.Local) }
+	// USD is worth 400 AMD until June 2, 390 after; EUR has no rate
+	convert := func(amount float64, from string, at time.Time) (float64, bool) {
+		switch from {
+		case "AMD":
+			return amount, true
+		case "USD":
+			if at.Before(day(2)) {
+				return amount * 400, true
+			}
+			return amount * 390, true
+		}
+		return 0, false
+	}
+	account := client.ProductInfo{ID: "acc1", ProductType: "ACCOUNT", Currency: "AMD", AvailableBalance: 100000}
+	card := client.ProductInfo{ID: "card1", ProductType: "CARD", AccountID: "acc1", Currency: "AMD", AvailableBalance: 100000}
+	usd := client.ProductInfo{ID: "acc2", ProductType: "ACCOUNT", Currency: "USD", AvailableBalance: 100}
+	eur := client.ProductInfo{ID: "acc3", ProductType: "ACCOUNT", Currency: "EUR", AvailableBalance: 50}
+
+	// Snapshots don't know the card's linked account
+	snapshotCard := card
+	snapshotCard.AccountID = ""
+	snapshots := []db.Snapshot{
+		{CreatedAt: day(1), Products: []client.ProductInfo{account, snapshotCard, usd}},
+		{CreatedAt: day(3), Products: []client.ProductInfo{account, snapshotCard}},
+	}
+	account.AvailableBalance = 150000
+	points := NetWorthHistory(snapshots, []client.ProductInfo{account, card, usd, eur}, convert, day(5))
+
+	want := []NetWorthPoint{
+		{Date: day(1), Total: 140000},
+		{Date: day(3), Total: 100000},
+		{Date: day(5), Total: 189000, Missing: []string{"EUR"}},
+	}
+	if !reflect.DeepEqual(points, want) {
+		t.Errorf("unexpected net worth history:\n got %+v\nwant %+v", points, want)
+	}
+
+	if points := NetWorthHistory(nil, nil, convert, day(5)); len(points) != 0 {
+		t.Errorf("expected no points without products, got %+v", points)
+	}
+}
@@ -0,0 +1,116 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestPeriod(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	// Current balance 1000 as of the last sync
+	if err := database.UpsertProducts([]client.ProductInfo{{ID: "acc1", ProductType: "ACCOUNT", Name: "Savings", Currency: "AMD", Balance: 1000}}); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+
+	ms := func(y int, m time.Month, d int) int64 {
+		return time.Date(y, m, d, 12, 0, 0, 0, time.Local).UnixMilli()
+	}
+	txns := []client.AccountTransaction{
+		{ID: "t0", FlowDirection: "EXPENSE", TransactionDate: ms(2024, 5, 20), TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 50}},
+		{ID: "t1", FlowDirection: "INCOME", TransactionDate: ms(2024, 6, 5), TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 500}},
+		{ID: "t2", FlowDirection: "EXPENSE", TransactionDate: ms(2024, 6, 10), TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 200}},
+		{ID: "t3", FlowDirection: "EXPENSE", TransactionDate: ms(2024, 6, 12), TransactionAmount: client.TransactionAmt{Currency: "USD", Value: 10}},
+		{ID: "t4", FlowDirection: "EXPENSE", TransactionDate: ms(2024, 7, 3), TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: 100}},
+	}
+	if _, err := database.InsertAccountTransactions("acc1", txns); err != nil {
+		t.Fatalf("failed to insert account transactions: %v", err)
+	}
+
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local)
+	st, err := Period(database, "acc1", from, to)
+	if err != nil {
+		t.Fatalf("Period failed: %v", err)
+	}
+
+	check := func(name string, got, want float64) {
+		t.Helper()
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+	check("closing balance", st.ClosingBalance, 1100)
+	check("opening balance", st.OpeningBalance, 800)
+	check("credits", st.Credits, 500)
+	check("debits", st.Debits, 200)
+	if len(st.Transactions) != 2 || st.Excluded != 1 {
+		t.Errorf("expected 2 transactions and 1 excluded, got %d and %d", len(st.Transactions), st.Excluded)
+	}
+	if st.Anchor.SnapshotID != 0 {
+		t.Errorf("expected anchor from last sync, got snapshot %d", st.Anchor.SnapshotID)
+	}
+}
+
+func TestPeriod_Reconciles(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.UpsertProducts([]client.ProductInfo{{ID: "acc1", ProductType: "ACCOUNT", Name: "Savings", Currency: "AMD", Balance: 1000}}); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+
+	// Thousands of fractional amounts, which drift when summed as float64
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	var txns []client.AccountTransaction
+	for i := range 5000 {
+		direction, value := "EXPENSE", 0.1
+		if i%2 == 0 {
+			direction, value = "INCOME", 0.3
+		}
+		txns = append(txns, client.AccountTransaction{
+			ID:                fmt.Sprintf("t%d", i),
+			FlowDirection:     direction,
+			TransactionDate:   start.Add(time.Duration(i) * time.Minute).UnixMilli(),
+			TransactionAmount: client.TransactionAmt{Currency: "AMD", Value: value},
+		})
+	}
+	if _, err := database.InsertAccountTransactions("acc1", txns); err != nil {
+		t.Fatalf("failed to insert account transactions: %v", err)
+	}
+
+	st, err := Period(database, "acc1", start, start.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("Period failed: %v", err)
+	}
+	if st.Credits != 750 || st.Debits != 250 {
+		t.Errorf("expected credits 750 and debits 250, got %v and %v", st.Credits, st.Debits)
+	}
+	if st.OpeningBalance != 500 || st.ClosingBalance != 1000 {
+		t.Errorf("expected opening 500 and closing 1000, got %v and %v", st.OpeningBalance, st.ClosingBalance)
+	}
+}
+
+func TestPeriod_UnknownProduct(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if _, err := Period(database, "missing", time.Now(), time.Now()); err == nil {
+		t.Error("expected error for unknown product")
+	}
+}
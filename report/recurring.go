@@ -0,0 +1,199 @@
+package report
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// Recurrence periods
+const (
+	PeriodWeekly  = "weekly"
+	PeriodMonthly = "monthly"
+	PeriodYearly  = "yearly"
+)
+
+// minOccurrences is the number of charges needed before a payment is considered recurring
+const minOccurrences = 3
+
+// periodSpec describes a recurrence period: its nominal length in days and the accepted deviation
+type periodSpec struct {
+	name      string
+	days      int
+	tolerance int
+}
+
+var periodSpecs = []periodSpec{
+	{PeriodWeekly, 7, 1},
+	{PeriodMonthly, 30, 4},
+	{PeriodYearly, 365, 10},
+}
+
+// RecurringPayment is a debit repeating at a regular period with a similar amount
+type RecurringPayment struct {
+	Counterparty string    `json:"counterparty"`
+	Currency     string    `json:"currency"`
+	Amount       float64   `json:"amount"` // median of the charges
+	Period       string    `json:"period"` // PeriodWeekly, PeriodMonthly or PeriodYearly
+	Count        int       `json:"count"`
+	LastDate     time.Time `json:"last_date"`
+	NextDate     time.Time `json:"next_date"` // predicted, on or after the reference time's day
+}
+
+// Advance returns the date one period after t
+func (p RecurringPayment) Advance(t time.Time) time.Time {
+	switch p.Period {
+	case PeriodWeekly:
+		return t.AddDate(0, 0, 7)
+	case PeriodYearly:
+		return t.AddDate(1, 0, 0)
+	default:
+		return t.AddDate(0, 1, 0)
+	}
+}
+
+// FindRecurring detects debits to the same counterparty in the same currency repeating
+// weekly, monthly or yearly with amounts within 25% of their median. At least two thirds
+// of the gaps between charges must match the period. Payments whose last charge is more
+// than two periods before now are considered cancelled and skipped.
+// Results are sorted by predicted next date.
+func FindRecurring(entries []db.LedgerEntry, now time.Time) []RecurringPayment {
+	type groupKey struct{ counterparty, currency string }
+	groups := make(map[groupKey][]db.LedgerEntry)
+	for _, e := range entries {
+		counterparty := strings.ToLower(strings.TrimSpace(e.Counterparty))
+		if e.Credit || e.Amount <= 0 || counterparty == "" {
+			continue
+		}
+		k := groupKey{counterparty, strings.ToUpper(e.Currency)}
+		groups[k] = append(groups[k], e)
+	}
+
+	today := startOfDay(now)
+	var result []RecurringPayment
+	for k, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool { return group[i].Date.Before(group[j].Date) })
+		p, ok := detectRecurrence(dedupeDays(group))
+		if !ok {
+			continue
+		}
+		p.Currency = k.currency
+		if p.Advance(p.Advance(p.LastDate)).Before(today) {
+			continue
+		}
+		p.NextDate = p.Advance(p.LastDate)
+		for p.NextDate.Before(today) {
+			p.NextDate = p.Advance(p.NextDate)
+		}
+		result = append(result, p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].NextDate.Equal(result[j].NextDate) {
+			return result[i].NextDate.Before(result[j].NextDate)
+		}
+		return result[i].Counterparty < result[j].Counterparty
+	})
+	return result
+}
+
+// dedupeDays keeps the first of several date-sorted charges on the same day
+func dedupeDays(entries []db.LedgerEntry) []db.LedgerEntry {
+	var result []db.LedgerEntry
+	for _, e := range entries {
+		if len(result) > 0 && startOfDay(result[len(result)-1].Date).Equal(startOfDay(e.Date)) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+// detectRecurrence checks whether date-sorted charges repeat at a regular period
+func detectRecurrence(entries []db.LedgerEntry) (RecurringPayment, bool) {
+	if len(entries) < minOccurrences {
+		return RecurringPayment{}, false
+	}
+
+	gaps := make([]float64, 0, len(entries)-1)
+	for i := 1; i < len(entries); i++ {
+		gaps = append(gaps, float64(daysBetween(entries[i-1].Date, entries[i].Date)))
+	}
+	spec, ok := matchPeriod(median(gaps))
+	if !ok {
+		return RecurringPayment{}, false
+	}
+	regular := 0
+	for _, gap := range gaps {
+		if abs(int(gap)-spec.days) <= spec.tolerance {
+			regular++
+		}
+	}
+	if regular*3 < len(gaps)*2 {
+		return RecurringPayment{}, false
+	}
+
+	amounts := make([]float64, len(entries))
+	for i, e := range entries {
+		amounts[i] = e.Amount
+	}
+	amount := median(amounts)
+	similar := 0
+	for _, a := range amounts {
+		if a >= amount*0.75 && a <= amount*1.25 {
+			similar++
+		}
+	}
+	if similar*3 < len(amounts)*2 {
+		return RecurringPayment{}, false
+	}
+
+	last := entries[len(entries)-1]
+	return RecurringPayment{
+		Counterparty: last.Counterparty,
+		Amount:       amount,
+		Period:       spec.name,
+		Count:        len(entries),
+		LastDate:     startOfDay(last.Date),
+	}, true
+}
+
+func matchPeriod(days float64) (periodSpec, bool) {
+	for _, spec := range periodSpecs {
+		if abs(int(days+0.5)-spec.days) <= spec.tolerance {
+			return spec, true
+		}
+	}
+	return periodSpec{}, false
+}
+
+// daysBetween returns the number of calendar days between two times in local time
+func daysBetween(a, b time.Time) int {
+	a, b = startOfDay(a), startOfDay(b)
+	// Round to absorb DST shifts
+	return int((b.Sub(a) + 12*time.Hour) / (24 * time.Hour))
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,62 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func TestFindRecurring(t *testing.T) {
+	at := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 12, 0, 0, 0, time.Local) }
+	debit := func(date time.Time, amount float64, counterparty string) db.LedgerEntry {
+		return db.LedgerEntry{Date: date, Amount: amount, Currency: "AMD", Counterparty: counterparty}
+	}
+	entries := []db.LedgerEntry{
+		// Monthly subscription with one late charge and a price change
+		debit(at(2024, 1, 5), 5000, "Netflix"),
+		debit(at(2024, 2, 5), 5000, "NETFLIX"),
+		debit(at(2024, 3, 8), 5000, "Netflix"),
+		debit(at(2024, 4, 5), 5500, "Netflix"),
+		// Weekly charge
+		debit(at(2024, 3, 18), 2000, "Gym"),
+		debit(at(2024, 3, 25), 2000, "Gym"),
+		debit(at(2024, 4, 1), 2100, "Gym"),
+		// Irregular
+		debit(at(2024, 1, 3), 800, "Cafe"),
+		debit(at(2024, 1, 20), 1200, "Cafe"),
+		debit(at(2024, 3, 1), 900, "Cafe"),
+		// Monthly but stopped long ago
+		debit(at(2023, 6, 1), 3000, "Old Service"),
+		debit(at(2023, 7, 1), 3000, "Old Service"),
+		debit(at(2023, 8, 1), 3000, "Old Service"),
+		// Too few charges, and incoming payments
+		debit(at(2024, 3, 10), 10000, "Insurance"),
+		debit(at(2024, 4, 10), 10000, "Insurance"),
+		{Date: at(2024, 1, 25), Credit: true, Amount: 500000, Currency: "AMD", Counterparty: "Employer"},
+		{Date: at(2024, 2, 25), Credit: true, Amount: 500000, Currency: "AMD", Counterparty: "Employer"},
+		{Date: at(2024, 3, 25), Credit: true, Amount: 500000, Currency: "AMD", Counterparty: "Employer"},
+	}
+
+	got := FindRecurring(entries, at(2024, 4, 10))
+	if len(got) != 2 {
+		t.Fatalf("expected 2 recurring payments, got %+v", got)
+	}
+
+	gym := got[0]
+	if gym.Counterparty != "Gym" || gym.Period != PeriodWeekly || gym.Amount != 2000 || gym.Count != 3 {
+		t.Errorf("unexpected weekly payment %+v", gym)
+	}
+	// The 2024-04-08 charge is overdue, so the prediction rolls forward
+	if !gym.NextDate.Equal(time.Date(2024, 4, 15, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("unexpected weekly next date %v", gym.NextDate)
+	}
+
+	netflix := got[1]
+	if netflix.Period != PeriodMonthly || netflix.Amount != 5000 || netflix.Count != 4 {
+		t.Errorf("unexpected monthly payment %+v", netflix)
+	}
+	if !netflix.NextDate.Equal(time.Date(2024, 5, 5, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("unexpected monthly next date %v", netflix.NextDate)
+	}
+}
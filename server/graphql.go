@@ -0,0 +1,426 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/graphql"
+	"github.com/ivan4th/ameriagrab/money"
+	"github.com/ivan4th/ameriagrab/product"
+)
+
+// GraphQLSchema describes the types served by /api/v1/graphql.
+// Dates are YYYY-MM-DD strings; timestamps are RFC 3339.
+const GraphQLSchema = `type Query {
+  products: [Product]
+  product(id: String!): Product            # ID or name
+  transactions(<filters>, order: String, limit: Int, offset: Int): [Transaction]
+  summary(groupBy: String!, <filters>): [Summary]
+  snapshots(limit: Int): [Snapshot]
+}
+
+# <filters>: product: String, from: String, to: String, type: String (credit/debit),
+#            currency: String, minAmount: Float, maxAmount: Float, search: String
+
+type Product {
+  id: String  name: String  type: String  currency: String  status: String
+  balance: Float  availableBalance: Float  cardNumber: String  accountNumber: String
+  transactions(<filters except product>, order: String, limit: Int, offset: Int): [Transaction]
+  summary(groupBy: String!, <filters except product>): [Summary]
+}
+
+type Transaction {
+  source: String  id: String  productId: String  product: Product  date: String
+  type: String  credit: Boolean  amount: Float  signedAmount: Float  currency: String
+  counterparty: String  details: String
+}
+
+# groupBy: month, currency, product, counterparty or type
+type Summary {
+  key: String  currency: String  count: Int  income: Float  expenses: Float  net: Float
+}
+
+type Snapshot { id: Int  createdAt: String  products: [Product] }`
+
+// filterArgs are the transaction filter arguments shared by several fields
+var filterArgs = []string{"from", "to", "type", "currency", "minAmount", "maxAmount", "search"}
+
+// aggregate sums transactions sharing a grouping key and currency
+type aggregate struct {
+	Key      string
+	Currency string
+	Count    int
+	Income   float64
+	Expenses float64
+}
+
+// EnableGraphQL serves the GraphQL endpoint at /api/v1/graphql (GET and POST)
+// and its schema as text at /api/v1/graphql/schema
+func (s *Server) EnableGraphQL() {
+	schema := s.graphQLSchema()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var req graphql.Request
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+				return
+			}
+		} else {
+			req.Query = r.URL.Query().Get("query")
+			req.OperationName = r.URL.Query().Get("operationName")
+			if vars := r.URL.Query().Get("variables"); vars != "" {
+				if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+					writeError(w, http.StatusBadRequest, fmt.Errorf("invalid variables parameter: %w", err))
+					return
+				}
+			}
+		}
+
+		resp := graphql.Execute(r.Context(), schema, req)
+		if resp.Data == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+		writeJSON(w, resp)
+	}
+	s.mux.HandleFunc("GET /api/v1/graphql", handler)
+	s.mux.HandleFunc("POST /api/v1/graphql", handler)
+	s.mux.HandleFunc("GET /api/v1/graphql/schema", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, GraphQLSchema)
+	})
+}
+
+func (s *Server) graphQLSchema() *graphql.Object {
+	product := &graphql.Object{Name: "Product"}
+	transaction := &graphql.Object{Name: "Transaction"}
+	summary := &graphql.Object{Name: "Summary"}
+	snapshot := &graphql.Object{Name: "Snapshot"}
+
+	product.Fields = map[string]*graphql.Field{
+		"id":               productField(func(p *client.ProductInfo) interface{} { return p.ID }),
+		"name":             productField(func(p *client.ProductInfo) interface{} { return p.Name }),
+		"type":             productField(func(p *client.ProductInfo) interface{} { return p.ProductType }),
+		"currency":         productField(func(p *client.ProductInfo) interface{} { return p.Currency }),
+		"status":           productField(func(p *client.ProductInfo) interface{} { return p.Status }),
+		"balance":          productField(func(p *client.ProductInfo) interface{} { return p.Balance }),
+		"availableBalance": productField(func(p *client.ProductInfo) interface{} { return p.AvailableBalance }),
+		"cardNumber":       productField(func(p *client.ProductInfo) interface{} { return p.CardNumber }),
+		"accountNumber":    productField(func(p *client.ProductInfo) interface{} { return p.AccountNumber }),
+		"transactions": {
+			Type: transaction,
+			Args: append([]string{"order", "limit", "offset"}, filterArgs...),
+			Resolve: func(p graphql.Params) (interface{}, error) {
+				return s.resolveTransactions(p, p.Source.(*client.ProductInfo).ID)
+			},
+		},
+		"summary": {
+			Type: summary,
+			Args: append([]string{"groupBy"}, filterArgs...),
+			Resolve: func(p graphql.Params) (interface{}, error) {
+				return s.resolveSummary(p, p.Source.(*client.ProductInfo).ID)
+			},
+		},
+	}
+
+	transaction.Fields = map[string]*graphql.Field{
+		"source":       entryField(func(e db.LedgerEntry) interface{} { return e.Source }),
+		"id":           entryField(func(e db.LedgerEntry) interface{} { return e.ID }),
+		"productId":    entryField(func(e db.LedgerEntry) interface{} { return e.ProductID }),
+		"date":         entryField(func(e db.LedgerEntry) interface{} { return e.Date.Format(time.RFC3339) }),
+		"type":         entryField(func(e db.LedgerEntry) interface{} { return e.Type }),
+		"credit":       entryField(func(e db.LedgerEntry) interface{} { return e.Credit }),
+		"amount":       entryField(func(e db.LedgerEntry) interface{} { return e.Amount }),
+		"signedAmount": entryField(func(e db.LedgerEntry) interface{} { return e.SignedAmount() }),
+		"currency":     entryField(func(e db.LedgerEntry) interface{} { return e.Currency }),
+		"counterparty": entryField(func(e db.LedgerEntry) interface{} { return e.Counterparty }),
+		"details":      entryField(func(e db.LedgerEntry) interface{} { return e.Details }),
+		"product": {
+			Type: product,
+			Resolve: func(p graphql.Params) (interface{}, error) {
+				return s.db.GetProductByID(p.Source.(db.LedgerEntry).ProductID)
+			},
+		},
+	}
+
+	summary.Fields = map[string]*graphql.Field{
+		"key":      summaryField(func(m aggregate) interface{} { return m.Key }),
+		"currency": summaryField(func(m aggregate) interface{} { return m.Currency }),
+		"count":    summaryField(func(m aggregate) interface{} { return m.Count }),
+		"income":   summaryField(func(m aggregate) interface{} { return m.Income }),
+		"expenses": summaryField(func(m aggregate) interface{} { return m.Expenses }),
+		"net":      summaryField(func(m aggregate) interface{} { return m.Income - m.Expenses }),
+	}
+
+	snapshot.Fields = map[string]*graphql.Field{
+		"id": {Resolve: func(p graphql.Params) (interface{}, error) { return p.Source.(db.Snapshot).ID, nil }},
+		"createdAt": {Resolve: func(p graphql.Params) (interface{}, error) {
+			return p.Source.(db.Snapshot).CreatedAt.Format(time.RFC3339), nil
+		}},
+		"products": {
+			Type: product,
+			Resolve: func(p graphql.Params) (interface{}, error) {
+				return productPointers(p.Source.(db.Snapshot).Products), nil
+			},
+		},
+	}
+
+	return &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"products": {
+			Type: product,
+			Resolve: func(p graphql.Params) (interface{}, error) {
+				products, err := s.db.GetProducts()
+				return productPointers(products), err
+			},
+		},
+		"product": {
+			Type: product,
+			Args: []string{"id"},
+			Resolve: func(p graphql.Params) (interface{}, error) {
+				id, err := p.String("id")
+				if err != nil {
+					return nil, err
+				}
+				return s.graphqlProduct(id)
+			},
+		},
+		"transactions": {
+			Type: transaction,
+			Args: append([]string{"product", "order", "limit", "offset"}, filterArgs...),
+			Resolve: func(p graphql.Params) (interface{}, error) {
+				return s.resolveTransactions(p, "")
+			},
+		},
+		"summary": {
+			Type: summary,
+			Args: append([]string{"product", "groupBy"}, filterArgs...),
+			Resolve: func(p graphql.Params) (interface{}, error) {
+				return s.resolveSummary(p, "")
+			},
+		},
+		"snapshots": {
+			Type: snapshot,
+			Args: []string{"limit"},
+			Resolve: func(p graphql.Params) (interface{}, error) {
+				limit, err := p.Int("limit", 0)
+				if err != nil {
+					return nil, err
+				}
+				snapshots, err := s.db.GetSnapshots()
+				if err != nil {
+					return nil, err
+				}
+				if limit > 0 && limit < len(snapshots) {
+					snapshots = snapshots[:limit]
+				}
+				return snapshots, nil
+			},
+		},
+	}}
+}
+
+func productField(get func(p *client.ProductInfo) interface{}) *graphql.Field {
+	return &graphql.Field{Resolve: func(p graphql.Params) (interface{}, error) {
+		return get(p.Source.(*client.ProductInfo)), nil
+	}}
+}
+
+func entryField(get func(e db.LedgerEntry) interface{}) *graphql.Field {
+	return &graphql.Field{Resolve: func(p graphql.Params) (interface{}, error) {
+		return get(p.Source.(db.LedgerEntry)), nil
+	}}
+}
+
+func summaryField(get func(m aggregate) interface{}) *graphql.Field {
+	return &graphql.Field{Resolve: func(p graphql.Params) (interface{}, error) {
+		return get(p.Source.(aggregate)), nil
+	}}
+}
+
+// productPointers converts products to pointers, the representation of Product values
+func productPointers(products []client.ProductInfo) []*client.ProductInfo {
+	result := make([]*client.ProductInfo, len(products))
+	for i := range products {
+		result[i] = &products[i]
+	}
+	return result
+}
+
+// graphqlProduct resolves the argument of the product field like product
+// arguments of commands; unknown products are null
+func (s *Server) graphqlProduct(idOrName string) (interface{}, error) {
+	p, err := product.Resolve(s.db, idOrName)
+	var notFound *product.NotFoundError
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// filteredEntries loads ledger entries matching the filter arguments.
+// productID restricts entries to a product; if empty, the "product" argument is used.
+func (s *Server) filteredEntries(p graphql.Params, productID string) ([]db.LedgerEntry, error) {
+	if productID == "" {
+		id, err := p.String("product")
+		if err != nil {
+			return nil, err
+		}
+		if id != "" {
+			p, err := product.Resolve(s.db, id)
+			if err != nil {
+				return nil, err
+			}
+			productID = p.ID
+		}
+	}
+
+	opts := db.LedgerOptions{ProductID: productID}
+	var err error
+	if opts.From, err = dateArg(p, "from", false); err != nil {
+		return nil, err
+	}
+	if opts.To, err = dateArg(p, "to", true); err != nil {
+		return nil, err
+	}
+
+	var filter db.EntryFilter
+	if filter.Type, err = p.String("type"); err != nil {
+		return nil, err
+	}
+	if filter.Currency, err = p.String("currency"); err != nil {
+		return nil, err
+	}
+	if filter.MinAmount, err = p.Float("minAmount", 0); err != nil {
+		return nil, err
+	}
+	if filter.MaxAmount, err = p.Float("maxAmount", 0); err != nil {
+		return nil, err
+	}
+	if filter.Search, err = p.String("search"); err != nil {
+		return nil, err
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.db.GetLedgerEntries(opts)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(entries), nil
+}
+
+// dateArg parses a YYYY-MM-DD argument like query.date
+func dateArg(p graphql.Params, name string, endOfDay bool) (time.Time, error) {
+	value, err := p.String(name)
+	if err != nil || value == "" {
+		return time.Time{}, err
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s argument %q (expected YYYY-MM-DD)", name, value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// resolveTransactions applies filters, order (desc by default) and paging like /api/v1/transactions
+func (s *Server) resolveTransactions(p graphql.Params, productID string) (interface{}, error) {
+	order, err := p.String("order")
+	if err != nil {
+		return nil, err
+	}
+	order = strings.ToLower(order)
+	if order != "" && order != "asc" && order != "desc" {
+		return nil, fmt.Errorf("invalid order %q (expected asc or desc)", order)
+	}
+	limit, err := p.Int("limit", 100)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := p.Int("offset", 0)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("limit and offset must not be negative")
+	}
+
+	entries, err := s.filteredEntries(p, productID)
+	if err != nil {
+		return nil, err
+	}
+	if order != "asc" {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.After(entries[j].Date) })
+	}
+	entries = entries[min(offset, len(entries)):]
+	if limit > 0 && limit < len(entries) {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// summaryKeys extract the grouping key of an entry for each groupBy value
+var summaryKeys = map[string]func(e db.LedgerEntry) string{
+	"month":        func(e db.LedgerEntry) string { return e.Date.Format("2006-01") },
+	"currency":     func(e db.LedgerEntry) string { return e.Currency },
+	"product":      func(e db.LedgerEntry) string { return e.ProductID },
+	"counterparty": func(e db.LedgerEntry) string { return e.Counterparty },
+	"type":         func(e db.LedgerEntry) string { return e.Type },
+}
+
+// resolveSummary aggregates filtered transactions per groupBy key and currency, sorted by key
+func (s *Server) resolveSummary(p graphql.Params, productID string) (interface{}, error) {
+	groupBy, err := p.String("groupBy")
+	if err != nil {
+		return nil, err
+	}
+	keyOf, ok := summaryKeys[strings.ToLower(groupBy)]
+	if !ok {
+		return nil, fmt.Errorf("invalid groupBy %q (expected month, currency, product, counterparty or type)", groupBy)
+	}
+
+	entries, err := s.filteredEntries(p, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []aggregate
+	index := make(map[[2]string]int)
+	for _, e := range entries {
+		k := [2]string{keyOf(e), e.Currency}
+		i, ok := index[k]
+		if !ok {
+			i = len(result)
+			index[k] = i
+			result = append(result, aggregate{Key: k[0], Currency: k[1]})
+		}
+		result[i].Count++
+		if e.Credit {
+			result[i].Income = money.Add(result[i].Income, e.Amount)
+		} else {
+			result[i].Expenses = money.Add(result[i].Expenses, e.Amount)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Key != result[j].Key {
+			return result[i].Key < result[j].Key
+		}
+		return result[i].Currency < result[j].Currency
+	})
+	return result, nil
+}
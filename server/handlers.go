@@ -0,0 +1,289 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/calendar"
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/product"
+	"github.com/ivan4th/ameriagrab/report"
+)
+
+// TransactionsResponse is the response of /api/v1/transactions
+type TransactionsResponse struct {
+	Total        int              `json:"total"` // matching transactions before limit/offset
+	Transactions []db.LedgerEntry `json:"transactions"`
+}
+
+// SnapshotResponse is a snapshot in /api/v1/snapshots
+type SnapshotResponse struct {
+	ID        int64                `json:"id"`
+	CreatedAt time.Time            `json:"created_at"`
+	Products  []client.ProductInfo `json:"products"`
+}
+
+// FXPosition is a currency position in /api/v1/reports/fx
+type FXPosition struct {
+	Currency  string   `json:"currency"`
+	Accounts  float64  `json:"accounts"`
+	Deposits  float64  `json:"deposits"`
+	Loans     float64  `json:"loans"`
+	Net       float64  `json:"net"`
+	BaseValue *float64 `json:"base_value"` // null if no exchange rate is known
+}
+
+func (s *Server) handleProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := s.db.GetProducts()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if products == nil {
+		products = []client.ProductInfo{}
+	}
+	writeJSON(w, products)
+}
+
+func (s *Server) handleProduct(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.resolveProduct(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	writeJSON(w, p)
+}
+
+// resolveProduct returns the product with the given ID or name, or writes a
+// 404 (or 500) response and returns false
+func (s *Server) resolveProduct(w http.ResponseWriter, idOrName string) (*client.ProductInfo, bool) {
+	p, err := product.Resolve(s.db, idOrName)
+	var notFound *product.NotFoundError
+	switch {
+	case errors.As(err, &notFound):
+		writeError(w, http.StatusNotFound, err)
+		return nil, false
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	return p, true
+}
+
+// handleTransactions returns ledger entries matching the filters.
+// Query parameters:
+//
+//	product              product ID
+//	from, to             YYYY-MM-DD, inclusive
+//	source               comma-separated ledger sources (default: card,account)
+//	type                 credit or debit
+//	currency             currency code
+//	min_amount, max_amount
+//	q                    case-insensitive substring of counterparty or details
+//	order                desc (default, newest first) or asc
+//	limit, offset        paging (default limit 100, 0 = no limit)
+func (s *Server) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	q := &query{r: r}
+	opts := db.LedgerOptions{
+		ProductID: q.str("product"),
+		From:      q.date("from", false),
+		To:        q.date("to", true),
+	}
+	if sources := q.str("source"); sources != "" {
+		opts.Sources = strings.Split(sources, ",")
+	}
+	filter := db.EntryFilter{
+		Type:      q.str("type"),
+		Currency:  q.str("currency"),
+		MinAmount: q.float("min_amount"),
+		MaxAmount: q.float("max_amount"),
+		Search:    q.str("q"),
+	}
+	order := q.str("order")
+	limit := q.int("limit", 100)
+	offset := q.int("offset", 0)
+	if q.err == nil {
+		q.err = filter.Validate()
+	}
+	if q.err == nil && order != "" && order != "asc" && order != "desc" {
+		q.err = fmt.Errorf("invalid order parameter %q (expected asc or desc)", order)
+	}
+	if q.err != nil {
+		writeError(w, http.StatusBadRequest, q.err)
+		return
+	}
+
+	entries, err := s.db.GetLedgerEntries(opts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	matched := filter.Apply(entries)
+	if order != "asc" {
+		sort.SliceStable(matched, func(i, j int) bool { return matched[i].Date.After(matched[j].Date) })
+	}
+
+	resp := TransactionsResponse{Total: len(matched)}
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	matched = matched[offset:]
+	if limit > 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+	resp.Transactions = matched
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := s.db.GetSnapshots()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp := make([]SnapshotResponse, len(snapshots))
+	for i, snap := range snapshots {
+		resp[i] = SnapshotResponse{ID: snap.ID, CreatedAt: snap.CreatedAt, Products: snap.Products}
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleLoans(w http.ResponseWriter, r *http.Request) {
+	loans, err := s.db.GetLoans()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if loans == nil {
+		loans = []client.LoanInfo{}
+	}
+	writeJSON(w, loans)
+}
+
+func (s *Server) handleDeposits(w http.ResponseWriter, r *http.Request) {
+	deposits, err := s.db.GetDeposits()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deposits == nil {
+		deposits = []client.DepositInfo{}
+	}
+	writeJSON(w, deposits)
+}
+
+// handleMonthlyReport returns income and expenses per month and currency.
+// Query parameters: product, from, to.
+func (s *Server) handleMonthlyReport(w http.ResponseWriter, r *http.Request) {
+	q := &query{r: r}
+	opts := db.LedgerOptions{ProductID: q.str("product"), From: q.date("from", false), To: q.date("to", true)}
+	if q.err != nil {
+		writeError(w, http.StatusBadRequest, q.err)
+		return
+	}
+	entries, err := s.db.GetLedgerEntries(opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, report.Monthly(entries))
+}
+
+// handleDuplicatesReport returns likely double charges.
+// Query parameters: product, from, to, window (Go duration, default 24h).
+func (s *Server) handleDuplicatesReport(w http.ResponseWriter, r *http.Request) {
+	q := &query{r: r}
+	opts := db.LedgerOptions{
+		ProductID: q.str("product"),
+		From:      q.date("from", false),
+		To:        q.date("to", true),
+		Sources:   []string{db.SourceCard, db.SourceLinked, db.SourceAccount},
+	}
+	window := q.duration("window", 24*time.Hour)
+	if q.err != nil {
+		writeError(w, http.StatusBadRequest, q.err)
+		return
+	}
+	entries, err := s.db.GetLedgerEntries(opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	groups := report.FindDuplicates(entries, window)
+	if groups == nil {
+		groups = []report.DuplicateGroup{}
+	}
+	writeJSON(w, groups)
+}
+
+// handlePeriodReport returns a period statement.
+// Query parameters (all required): product, from, to.
+func (s *Server) handlePeriodReport(w http.ResponseWriter, r *http.Request) {
+	q := &query{r: r}
+	productID := q.str("product")
+	from := q.date("from", false)
+	to := q.date("to", true)
+	if q.err == nil && (productID == "" || from.IsZero() || to.IsZero()) {
+		q.err = fmt.Errorf("product, from and to parameters are required")
+	}
+	if q.err != nil {
+		writeError(w, http.StatusBadRequest, q.err)
+		return
+	}
+
+	p, ok := s.resolveProduct(w, productID)
+	if !ok {
+		return
+	}
+
+	st, err := report.Period(s.db, p.ID, from, to)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, st)
+}
+
+// handleFXReport returns holdings per currency converted to a base currency.
+// Query parameters: base (default AMD).
+func (s *Server) handleFXReport(w http.ResponseWriter, r *http.Request) {
+	base := strings.ToUpper(r.URL.Query().Get("base"))
+	if base == "" {
+		base = db.LocalCurrency
+	}
+
+	positions, err := s.db.GetCurrencyPositions()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	rates, err := s.db.GetExchangeRates()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := make([]FXPosition, len(positions))
+	for i, p := range positions {
+		resp[i] = FXPosition{Currency: p.Currency, Accounts: p.Accounts, Deposits: p.Deposits, Loans: p.Loans, Net: p.Net()}
+		if v, ok := rates.Convert(p.Net(), p.Currency, base); ok {
+			resp[i].BaseValue = &v
+		}
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	cal, err := calendar.Feed(s.db, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", calendar.ContentType)
+	cal.Write(w, time.Now())
+}
@@ -0,0 +1,160 @@
+// Package server exposes the local database as a read-only JSON REST API
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/calendar"
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/product"
+	"github.com/ivan4th/ameriagrab/report"
+)
+
+// Store is the data the API serves. *db.DB implements it; the API only reads.
+type Store interface {
+	product.ProductStore
+	report.BalanceStore
+	calendar.FeedStore
+	GetSnapshots() ([]db.Snapshot, error)
+	GetDeposits() ([]client.DepositInfo, error)
+	GetCurrencyPositions() ([]db.CurrencyPosition, error)
+	GetExchangeRates() (db.ExchangeRates, error)
+}
+
+var _ Store = (*db.DB)(nil)
+
+// Server serves the REST API
+type Server struct {
+	db    Store
+	token string
+	mux   *http.ServeMux
+}
+
+// New creates an API server over the store, usually the database. Requests
+// must carry "Authorization: Bearer <token>"; token must not be empty.
+func New(database Store, token string) *Server {
+	s := &Server{db: database, token: token, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /api/v1/products", s.handleProducts)
+	s.mux.HandleFunc("GET /api/v1/products/{id}", s.handleProduct)
+	s.mux.HandleFunc("GET /api/v1/transactions", s.handleTransactions)
+	s.mux.HandleFunc("GET /api/v1/snapshots", s.handleSnapshots)
+	s.mux.HandleFunc("GET /api/v1/loans", s.handleLoans)
+	s.mux.HandleFunc("GET /api/v1/deposits", s.handleDeposits)
+	s.mux.HandleFunc("GET /api/v1/reports/monthly", s.handleMonthlyReport)
+	s.mux.HandleFunc("GET /api/v1/reports/duplicates", s.handleDuplicatesReport)
+	s.mux.HandleFunc("GET /api/v1/reports/period", s.handlePeriodReport)
+	s.mux.HandleFunc("GET /api/v1/reports/fx", s.handleFXReport)
+	s.mux.HandleFunc("GET "+CalendarPath, s.handleCalendar)
+	return s
+}
+
+// ServeHTTP authenticates the request and dispatches it
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="ameriagrab"`)
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid token"))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// CalendarPath is the iCalendar feed endpoint. Calendar apps can't send headers,
+// so the token may also be passed as the token query parameter.
+const CalendarPath = "/api/v1/calendar.ics"
+
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok && r.URL.Path == CalendarPath {
+		token = r.URL.Query().Get("token")
+		ok = token != ""
+	}
+	if !ok || s.token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// query wraps URL query parameters with typed accessors that record the first parse error
+type query struct {
+	r   *http.Request
+	err error
+}
+
+func (q *query) str(name string) string {
+	return q.r.URL.Query().Get(name)
+}
+
+func (q *query) int(name string, def int) int {
+	value := q.str(name)
+	if value == "" || q.err != nil {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		q.err = fmt.Errorf("invalid %s parameter %q", name, value)
+		return def
+	}
+	return n
+}
+
+func (q *query) float(name string) float64 {
+	value := q.str(name)
+	if value == "" || q.err != nil {
+		return 0
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		q.err = fmt.Errorf("invalid %s parameter %q", name, value)
+	}
+	return f
+}
+
+// date parses a YYYY-MM-DD parameter in local time; if endOfDay is set,
+// the returned time is the start of the following day (for exclusive upper bounds)
+func (q *query) date(name string, endOfDay bool) time.Time {
+	value := q.str(name)
+	if value == "" || q.err != nil {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		q.err = fmt.Errorf("invalid %s parameter %q (expected YYYY-MM-DD)", name, value)
+		return time.Time{}
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+func (q *query) duration(name string, def time.Duration) time.Duration {
+	value := q.str(name)
+	if value == "" || q.err != nil {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		q.err = fmt.Errorf("invalid %s parameter %q", name, value)
+		return def
+	}
+	return d
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
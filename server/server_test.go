@@ -0,0 +1,222 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+const testToken = "secret"
+
+func setupTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(New(setupTestDB(t), testToken))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func setupTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	products := []client.ProductInfo{
+		{ID: "card1", ProductType: "CARD", Name: "Main Card", Currency: "AMD", Balance: 5000},
+		{ID: "acc1", ProductType: "ACCOUNT", Name: "Savings", Currency: "USD", Balance: 100},
+	}
+	if err := database.UpsertProducts(products); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+	txns := []client.Transaction{
+		{ID: "t1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 100}, Details: "Grocery Store", OperationDate: "2024-06-01T10:00:00Z"},
+		{ID: "t2", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 2500}, Details: "Electronics", OperationDate: "2024-06-15T10:00:00Z"},
+		{ID: "t3", AccountingType: "CREDIT", Amount: client.Amount{Currency: "AMD", Amount: 700}, Details: "Refund", OperationDate: "2024-07-02T10:00:00Z"},
+	}
+	if _, err := database.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+	return database
+}
+
+func get(t *testing.T, url, token string, v interface{}) int {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("failed to decode %s: %v", url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAuth(t *testing.T) {
+	server := setupTestServer(t)
+
+	if status := get(t, server.URL+"/api/v1/products", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", status)
+	}
+	if status := get(t, server.URL+"/api/v1/products", "wrong", nil); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong token, got %d", status)
+	}
+	var products []client.ProductInfo
+	if status := get(t, server.URL+"/api/v1/products", testToken, &products); status != http.StatusOK || len(products) != 2 {
+		t.Errorf("expected 2 products, got %d (status %d)", len(products), status)
+	}
+	if status := get(t, server.URL+"/api/v1/products?token="+testToken, "", nil); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with query token outside the calendar feed, got %d", status)
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	server := setupTestServer(t)
+
+	if status := get(t, server.URL+"/api/v1/calendar.ics?token=wrong", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong query token, got %d", status)
+	}
+	resp, err := http.Get(server.URL + "/api/v1/calendar.ics?token=" + testToken)
+	if err != nil {
+		t.Fatalf("GET calendar failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/calendar") {
+		t.Fatalf("unexpected response: status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.HasPrefix(string(body), "BEGIN:VCALENDAR\r\n") {
+		t.Errorf("unexpected calendar body %q", body)
+	}
+}
+
+func TestTransactionFilters(t *testing.T) {
+	server := setupTestServer(t)
+
+	tests := []struct {
+		query string
+		ids   []string
+		total int
+	}{
+		{"", []string{"t3", "t2", "t1"}, 3},
+		{"?order=asc&limit=2", []string{"t1", "t2"}, 3},
+		{"?limit=1&offset=1", []string{"t2"}, 3},
+		{"?type=debit&min_amount=1000", []string{"t2"}, 1},
+		{"?from=2024-06-01&to=2024-06-30", []string{"t2", "t1"}, 2},
+		{"?q=grocery", []string{"t1"}, 1},
+		{"?product=acc1", nil, 0},
+	}
+	for _, tt := range tests {
+		var resp TransactionsResponse
+		if status := get(t, server.URL+"/api/v1/transactions"+tt.query, testToken, &resp); status != http.StatusOK {
+			t.Errorf("%s: unexpected status %d", tt.query, status)
+			continue
+		}
+		var ids []string
+		for _, e := range resp.Transactions {
+			ids = append(ids, e.ID)
+		}
+		if resp.Total != tt.total || len(ids) != len(tt.ids) {
+			t.Errorf("%s: expected %v (total %d), got %v (total %d)", tt.query, tt.ids, tt.total, ids, resp.Total)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.ids[i] {
+				t.Errorf("%s: expected %v, got %v", tt.query, tt.ids, ids)
+				break
+			}
+		}
+	}
+
+	for _, bad := range []string{"?limit=x", "?from=June", "?type=other", "?source=nope"} {
+		if status := get(t, server.URL+"/api/v1/transactions"+bad, testToken, nil); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", bad, status)
+		}
+	}
+}
+
+func TestProductAndReports(t *testing.T) {
+	server := setupTestServer(t)
+
+	var product client.ProductInfo
+	if status := get(t, server.URL+"/api/v1/products/Savings", testToken, &product); status != http.StatusOK || product.ID != "acc1" {
+		t.Errorf("expected product acc1, got %+v (status %d)", product, status)
+	}
+	if status := get(t, server.URL+"/api/v1/products/missing", testToken, nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown product, got %d", status)
+	}
+
+	var monthly []map[string]interface{}
+	if status := get(t, server.URL+"/api/v1/reports/monthly", testToken, &monthly); status != http.StatusOK || len(monthly) != 2 {
+		t.Errorf("expected 2 monthly totals, got %v (status %d)", monthly, status)
+	}
+	if status := get(t, server.URL+"/api/v1/reports/period?product=card1", testToken, nil); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for period without dates, got %d", status)
+	}
+	if status := get(t, server.URL+"/api/v1/reports/period?product=card1&from=2024-06-01&to=2024-06-30", testToken, nil); status != http.StatusOK {
+		t.Errorf("expected period report, got status %d", status)
+	}
+}
+
+func TestGraphQL(t *testing.T) {
+	server := setupTestServer(t)
+	if status := get(t, server.URL+"/api/v1/graphql?query={products{id}}", testToken, nil); status != http.StatusNotFound {
+		t.Errorf("GraphQL should be disabled by default, got status %d", status)
+	}
+
+	s := New(setupTestDB(t), testToken)
+	s.EnableGraphQL()
+	server = httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	query := `query($card: String!) {
+		product(id: $card) {
+			name
+			recent: transactions(limit: 1) { id signedAmount product { id } }
+			summary(groupBy: "month") { key count income expenses net }
+		}
+	}`
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": map[string]string{"card": "Main Card"}})
+	req, _ := http.NewRequest("POST", server.URL+"/api/v1/graphql", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer resp.Body.Close()
+	got, _ := io.ReadAll(resp.Body)
+
+	expected := `{"data":{"product":{"name":"Main Card",` +
+		`"recent":[{"id":"t3","signedAmount":700,"product":{"id":"card1"}}],` +
+		`"summary":[{"key":"2024-06","count":2,"income":0,"expenses":2600,"net":-2600},` +
+		`{"key":"2024-07","count":1,"income":700,"expenses":0,"net":700}]}}}`
+	if strings.TrimSpace(string(got)) != expected {
+		t.Errorf("unexpected response:\n%s\nexpected:\n%s", got, expected)
+	}
+
+	var errResp struct {
+		Errors []struct{ Message string }
+	}
+	if status := get(t, server.URL+`/api/v1/graphql?query={summary(groupBy:"week"){key}}`, testToken, &errResp); status != http.StatusOK ||
+		len(errResp.Errors) != 1 || !strings.Contains(errResp.Errors[0].Message, "invalid groupBy") {
+		t.Errorf("expected groupBy error, got %+v (status %d)", errResp, status)
+	}
+	if status := get(t, server.URL+"/api/v1/graphql?query={nope}", testToken, nil); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid query, got %d", status)
+	}
+}
@@ -0,0 +1,195 @@
+// Package telegram implements an interactive Telegram bot over the local database
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/notify"
+)
+
+// maxMessageLength is the Telegram limit for message text, in characters
+const maxMessageLength = 4096
+
+// Store is what the bot reads to answer commands: products and the ledger.
+// *db.DB implements it.
+type Store interface {
+	GetProducts() ([]client.ProductInfo, error)
+	GetLedgerEntries(opts db.LedgerOptions) ([]db.LedgerEntry, error)
+}
+
+var _ Store = (*db.DB)(nil)
+
+// Bot answers commands from whitelisted chats using long polling
+type Bot struct {
+	Token        string
+	BaseURL      string // defaults to notify.TelegramAPIBaseURL
+	AllowedChats map[int64]bool
+	DB           Store
+	// Sync runs a sync for the /sync command. Nil disables the command.
+	Sync        func(ctx context.Context) error
+	PollTimeout time.Duration
+	HTTPClient  *http.Client
+	// Logf reports ignored messages and API errors. Nil discards them.
+	Logf func(format string, args ...interface{})
+}
+
+// New creates a bot answering only the given chats
+func New(token string, allowedChats []int64, database Store) *Bot {
+	allowed := make(map[int64]bool, len(allowedChats))
+	for _, id := range allowedChats {
+		allowed[id] = true
+	}
+	pollTimeout := 30 * time.Second
+	return &Bot{
+		Token:        token,
+		BaseURL:      notify.TelegramAPIBaseURL,
+		AllowedChats: allowed,
+		DB:           database,
+		PollTimeout:  pollTimeout,
+		HTTPClient:   &http.Client{Timeout: pollTimeout + 30*time.Second},
+	}
+}
+
+// Update is an incoming update from getUpdates
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+// Message is an incoming message
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text"`
+}
+
+// Chat identifies the conversation a message belongs to
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+// apiResponse is the envelope of all Bot API responses
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+}
+
+// Run polls for updates and answers commands until the context is cancelled.
+// API errors are logged and retried after a delay.
+func (b *Bot) Run(ctx context.Context) error {
+	var offset int64
+	for {
+		updates, err := b.getUpdates(ctx, offset)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			b.logf("Warning: Telegram getUpdates failed: %v", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			b.handleUpdate(ctx, u)
+		}
+	}
+}
+
+func (b *Bot) handleUpdate(ctx context.Context, u Update) {
+	if u.Message == nil || u.Message.Text == "" {
+		return
+	}
+	chatID := u.Message.Chat.ID
+	if !b.AllowedChats[chatID] {
+		b.logf("Ignoring message from chat %d (not in the allowed list)", chatID)
+		return
+	}
+	reply := b.Handle(ctx, u.Message.Text)
+	if reply == "" {
+		return
+	}
+	if err := b.SendMessage(ctx, chatID, reply); err != nil {
+		b.logf("Warning: failed to reply to chat %d: %v", chatID, err)
+	}
+}
+
+func (b *Bot) getUpdates(ctx context.Context, offset int64) ([]Update, error) {
+	params := url.Values{
+		"offset":          {strconv.FormatInt(offset, 10)},
+		"timeout":         {strconv.Itoa(int(b.PollTimeout / time.Second))},
+		"allowed_updates": {`["message"]`},
+	}
+	var updates []Update
+	if err := b.call(ctx, "getUpdates", params, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// SendMessage sends a plain text message, truncated to the Telegram limit
+func (b *Bot) SendMessage(ctx context.Context, chatID int64, text string) error {
+	if utf8.RuneCountInString(text) > maxMessageLength {
+		runes := []rune(text)
+		text = string(runes[:maxMessageLength-1]) + "…"
+	}
+	params := url.Values{
+		"chat_id": {strconv.FormatInt(chatID, 10)},
+		"text":    {text},
+	}
+	return b.call(ctx, "sendMessage", params, nil)
+}
+
+// call invokes a Bot API method and decodes its result into out (if not nil)
+func (b *Bot) call(ctx context.Context, method string, params url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/bot%s/%s", b.BaseURL, b.Token, method), strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var result apiResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("%s: unexpected response (status %d)", method, resp.StatusCode)
+	}
+	if !result.OK {
+		return fmt.Errorf("%s: %s", method, result.Description)
+	}
+	if out != nil {
+		if err := json.Unmarshal(result.Result, out); err != nil {
+			return fmt.Errorf("%s: failed to decode result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+func (b *Bot) logf(format string, args ...interface{}) {
+	if b.Logf != nil {
+		b.Logf(format, args...)
+	}
+}
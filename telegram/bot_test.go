@@ -0,0 +1,138 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func setupTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	if err := database.UpsertProducts([]client.ProductInfo{{ID: "card1", ProductType: "CARD", Name: "Main Card", Currency: "AMD", Balance: 150000}}); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+	var txns []client.Transaction
+	for i := 1; i <= 15; i++ {
+		txns = append(txns, client.Transaction{
+			ID:             fmt.Sprintf("t%d", i),
+			AccountingType: "DEBIT",
+			Amount:         client.Amount{Currency: "AMD", Amount: float64(i * 100)},
+			Details:        fmt.Sprintf("Shop %d", i),
+			OperationDate:  fmt.Sprintf("2024-01-%02dT10:00:00Z", i),
+		})
+	}
+	if _, err := database.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+	return database
+}
+
+func TestHandle(t *testing.T) {
+	bot := New("token", []int64{42}, setupTestDB(t))
+	ctx := context.Background()
+
+	if reply := bot.Handle(ctx, "/balance"); reply != "Main Card: 150000.00 AMD" {
+		t.Errorf("unexpected /balance reply %q", reply)
+	}
+
+	reply := bot.Handle(ctx, "/last")
+	lines := strings.Split(reply, "\n")
+	if len(lines) != defaultLast || !strings.Contains(lines[0], "Shop 15") || !strings.Contains(lines[0], "-1500.00 AMD") {
+		t.Errorf("unexpected /last reply %q", reply)
+	}
+	if reply := bot.Handle(ctx, "/last@ameriabot 3"); len(strings.Split(reply, "\n")) != 3 {
+		t.Errorf("unexpected /last 3 reply %q", reply)
+	}
+	if reply := bot.Handle(ctx, "/last x"); !strings.HasPrefix(reply, "Error:") {
+		t.Errorf("expected error for invalid count, got %q", reply)
+	}
+
+	reply = bot.Handle(ctx, "/search shop 1")
+	if !strings.HasPrefix(reply, "7 matching transactions:") {
+		t.Errorf("unexpected /search reply %q", reply)
+	}
+
+	if reply := bot.Handle(ctx, "/sync"); reply != "Error: sync is not available" {
+		t.Errorf("unexpected /sync reply without sync function %q", reply)
+	}
+	bot.Sync = func(ctx context.Context) error { return nil }
+	if reply := bot.Handle(ctx, "/sync"); reply != "Sync completed, no new transactions." {
+		t.Errorf("unexpected /sync reply %q", reply)
+	}
+
+	if reply := bot.Handle(ctx, "hello"); reply != helpText {
+		t.Errorf("expected help text, got %q", reply)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/bottoken/getUpdates":
+			polls++
+			if polls == 1 {
+				w.Write([]byte(`{"ok":true,"result":[
+					{"update_id":10,"message":{"message_id":1,"chat":{"id":42},"text":"/balance"}},
+					{"update_id":11,"message":{"message_id":2,"chat":{"id":7},"text":"/balance"}}
+				]}`))
+				return
+			}
+			if r.FormValue("offset") != "12" {
+				t.Errorf("expected offset 12, got %q", r.FormValue("offset"))
+			}
+			w.Write([]byte(`{"ok":true,"result":[]}`))
+		case "/bottoken/sendMessage":
+			sent = append(sent, r.FormValue("chat_id")+": "+r.FormValue("text"))
+			w.Write([]byte(`{"ok":true,"result":{}}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	bot := New("token", []int64{42}, setupTestDB(t))
+	bot.BaseURL = server.URL
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bot.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := polls
+		mu.Unlock()
+		if n >= 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || sent[0] != "42: Main Card: 150000.00 AMD" {
+		t.Errorf("expected a single reply to the allowed chat, got %v", sent)
+	}
+}
@@ -0,0 +1,132 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+const (
+	defaultLast = 10
+	maxLast     = 50
+	maxSearch   = 20
+)
+
+const helpText = `Commands:
+/balance - balances of all accounts and cards
+/last [N] - last N transactions (default 10)
+/search TEXT - transactions with TEXT in counterparty or details
+/sync - download new transactions now`
+
+// Handle executes a command and returns the reply text
+func (b *Bot) Handle(ctx context.Context, text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return helpText
+	}
+	// Commands in groups may be addressed as /command@botname
+	command, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+	args := strings.TrimSpace(strings.TrimPrefix(text, fields[0]))
+
+	var reply string
+	var err error
+	switch command {
+	case "/balance":
+		reply, err = b.balance()
+	case "/last":
+		reply, err = b.last(args)
+	case "/search":
+		reply, err = b.search(args)
+	case "/sync":
+		reply, err = b.sync(ctx)
+	default:
+		return helpText
+	}
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return reply
+}
+
+func (b *Bot) balance() (string, error) {
+	products, err := b.DB.GetProducts()
+	if err != nil {
+		return "", err
+	}
+	if len(products) == 0 {
+		return "No accounts or cards in the database, run sync first.", nil
+	}
+	var sb strings.Builder
+	for _, p := range products {
+		fmt.Fprintf(&sb, "%s: %.2f %s\n", p.Name, p.Balance, p.Currency)
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+func (b *Bot) last(args string) (string, error) {
+	n := defaultLast
+	if args != "" {
+		var err error
+		if n, err = strconv.Atoi(args); err != nil || n < 1 {
+			return "", fmt.Errorf("invalid count %q", args)
+		}
+		if n > maxLast {
+			n = maxLast
+		}
+	}
+	entries, err := b.DB.GetLedgerEntries(db.LedgerOptions{})
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "No transactions in the database.", nil
+	}
+	return formatEntries(entries, n), nil
+}
+
+func (b *Bot) search(query string) (string, error) {
+	if query == "" {
+		return "", fmt.Errorf("usage: /search TEXT")
+	}
+	entries, err := b.DB.GetLedgerEntries(db.LedgerOptions{})
+	if err != nil {
+		return "", err
+	}
+	matched := db.EntryFilter{Search: query}.Apply(entries)
+	if len(matched) == 0 {
+		return fmt.Sprintf("No transactions matching %q.", query), nil
+	}
+	return fmt.Sprintf("%d matching transactions:\n%s", len(matched), formatEntries(matched, maxSearch)), nil
+}
+
+func (b *Bot) sync(ctx context.Context) (string, error) {
+	if b.Sync == nil {
+		return "", fmt.Errorf("sync is not available")
+	}
+	start := time.Now()
+	if err := b.Sync(ctx); err != nil {
+		return "", fmt.Errorf("sync failed: %w", err)
+	}
+	entries, err := b.DB.GetLedgerEntries(db.LedgerOptions{SyncedSince: start})
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "Sync completed, no new transactions.", nil
+	}
+	return fmt.Sprintf("Sync completed, %d new transactions:\n%s", len(entries), formatEntries(entries, maxSearch)), nil
+}
+
+// formatEntries renders the newest n entries, one line each, newest first
+func formatEntries(entries []db.LedgerEntry, n int) string {
+	var sb strings.Builder
+	for i := len(entries) - 1; i >= 0 && i >= len(entries)-n; i-- {
+		e := entries[i]
+		fmt.Fprintf(&sb, "%s  %+.2f %s  %s\n", e.Date.Format("2006-01-02"), e.SignedAmount(), e.Currency, e.Counterparty)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,64 @@
+package textwidth
+
+import "testing"
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"hello", 5},
+		{"Երևան", 5},    // Armenian
+		{"Москва", 6},   // Cyrillic
+		{"東京", 4},       // CJK, two cells each
+		{"ｶﾌｪ", 3},      // halfwidth katakana
+		{"e\u0301", 1},  // combining acute accent
+		{"a\u200db", 2}, // zero width joiner
+		{"☕ coffee", 9}, // wide emoji
+		{"\xff\xfe", 2}, // invalid bytes
+	}
+	for _, tt := range tests {
+		if got := Width(tt.s); got != tt.want {
+			t.Errorf("Width(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"hello world", 8, "hello..."},
+		{"Երևան Սիթի սուպերմարկետ", 10, "Երևան Ս..."},
+		{"Москва", 3, "Мос"},
+		{"東京タワー", 7, "東京..."},
+		{"東京タワー", 8, "東京..."},
+		{"東京タワー", 10, "東京タワー"},
+		{"abc", 0, ""},
+	}
+	for _, tt := range tests {
+		got := Truncate(tt.s, tt.width)
+		if got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+		if Width(got) > tt.width {
+			t.Errorf("Truncate(%q, %d) = %q is %d cells wide", tt.s, tt.width, got, Width(got))
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	if got := PadRight("東京", 6); got != "東京  " {
+		t.Errorf("PadRight = %q", got)
+	}
+	if got := PadLeft("Երևան", 7); got != "  Երևան" {
+		t.Errorf("PadLeft = %q", got)
+	}
+	if got := PadRight("too long", 3); got != "too long" {
+		t.Errorf("PadRight shouldn't cut text, got %q", got)
+	}
+}
@@ -0,0 +1,139 @@
+// Package web serves a read-only dashboard over the local database
+package web
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/report"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// Balance is a product with its balances as shown on the dashboard
+type Balance struct {
+	ID               string  `json:"id"`
+	Name             string  `json:"name"`
+	Type             string  `json:"type"`
+	Currency         string  `json:"currency"`
+	Balance          float64 `json:"balance"`
+	AvailableBalance float64 `json:"available_balance"`
+}
+
+// Store is what the dashboard reads: products and the ledger. *db.DB implements it.
+type Store interface {
+	GetProducts() ([]client.ProductInfo, error)
+	GetLedgerEntries(opts db.LedgerOptions) ([]db.LedgerEntry, error)
+}
+
+var _ Store = (*db.DB)(nil)
+
+// dashboard serves the dashboard page and its JSON endpoints
+type dashboard struct {
+	db Store
+}
+
+// NewHandler returns an HTTP handler serving the dashboard from the database
+func NewHandler(database Store) http.Handler {
+	d := &dashboard{db: database}
+	static, _ := fs.Sub(staticFiles, "static")
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(static))
+	mux.HandleFunc("GET /api/balances", d.handleBalances)
+	mux.HandleFunc("GET /api/transactions", d.handleTransactions)
+	mux.HandleFunc("GET /api/monthly", d.handleMonthly)
+	return mux
+}
+
+func (d *dashboard) handleBalances(w http.ResponseWriter, r *http.Request) {
+	products, err := d.db.GetProducts()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	balances := make([]Balance, len(products))
+	for i, p := range products {
+		balances[i] = Balance{
+			ID:               p.ID,
+			Name:             p.Name,
+			Type:             p.ProductType,
+			Currency:         p.Currency,
+			Balance:          p.Balance,
+			AvailableBalance: p.AvailableBalance,
+		}
+	}
+	writeJSON(w, balances)
+}
+
+// handleTransactions returns the most recent transactions, newest first.
+// Query parameters: limit (default 50), product (ID).
+func (d *dashboard) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	limit, err := intParam(r, "limit", 50)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	entries, err := d.db.GetLedgerEntries(db.LedgerOptions{ProductID: r.URL.Query().Get("product")})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	recent := make([]db.LedgerEntry, 0, limit)
+	for i := len(entries) - 1; i >= 0 && len(recent) < limit; i-- {
+		recent = append(recent, entries[i])
+	}
+	writeJSON(w, recent)
+}
+
+// handleMonthly returns income and expenses per month and currency.
+// Query parameters: months (default 12).
+func (d *dashboard) handleMonthly(w http.ResponseWriter, r *http.Request) {
+	months, err := intParam(r, "months", 12)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -(months - 1), 0)
+
+	entries, err := d.db.GetLedgerEntries(db.LedgerOptions{From: from})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, report.Monthly(entries))
+}
+
+// intParam parses a positive integer query parameter
+func intParam(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s parameter %q", name, value)
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+	"github.com/ivan4th/ameriagrab/report"
+)
+
+func setupTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	products := []client.ProductInfo{{ID: "card1", ProductType: "CARD", Name: "Main Card", Currency: "AMD", Balance: 5000, AvailableBalance: 4500}}
+	if err := database.UpsertProducts(products); err != nil {
+		t.Fatalf("failed to upsert products: %v", err)
+	}
+	now := time.Now().UTC()
+	txns := []client.Transaction{
+		{ID: "t1", AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 100}, Details: "Old", OperationDate: now.Add(-2 * time.Hour).Format(time.RFC3339)},
+		{ID: "t2", AccountingType: "CREDIT", Amount: client.Amount{Currency: "AMD", Amount: 300}, Details: "New", OperationDate: now.Add(-time.Hour).Format(time.RFC3339)},
+	}
+	if _, err := database.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+
+	server := httptest.NewServer(NewHandler(database))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func getJSON(t *testing.T, url string, v interface{}) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("failed to decode %s: %v", url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestDashboardAPI(t *testing.T) {
+	server := setupTestServer(t)
+
+	var balances []Balance
+	getJSON(t, server.URL+"/api/balances", &balances)
+	if len(balances) != 1 || balances[0].AvailableBalance != 4500 {
+		t.Errorf("unexpected balances: %+v", balances)
+	}
+
+	var txns []db.LedgerEntry
+	getJSON(t, server.URL+"/api/transactions?limit=1", &txns)
+	if len(txns) != 1 || txns[0].ID != "t2" {
+		t.Errorf("expected newest transaction t2, got %+v", txns)
+	}
+
+	var monthly []report.MonthTotal
+	getJSON(t, server.URL+"/api/monthly", &monthly)
+	var income, expenses float64
+	for _, m := range monthly {
+		income += m.Income
+		expenses += m.Expenses
+	}
+	if income != 300 || expenses != 100 {
+		t.Errorf("unexpected monthly totals: %+v", monthly)
+	}
+
+	if status := getJSON(t, server.URL+"/api/transactions?limit=abc", nil); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid limit, got %d", status)
+	}
+}
+
+func TestDashboardPage(t *testing.T) {
+	server := setupTestServer(t)
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
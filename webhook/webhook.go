@@ -0,0 +1,190 @@
+// Package webhook delivers new transactions to an HTTP endpoint as signed JSON batches
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+// EventNewTransactions is the event name of transaction batches
+const EventNewTransactions = "transactions.new"
+
+// Headers set on every delivery
+const (
+	EventHeader     = "X-Ameriagrab-Event"
+	SignatureHeader = "X-Ameriagrab-Signature" // "sha256=" + hex HMAC-SHA256 of the body
+)
+
+// CursorName is the cursor tracking which transactions were delivered
+const CursorName = "webhook"
+
+// DefaultBatchSize is the maximum number of transactions per request
+const DefaultBatchSize = 100
+
+// Batch is the JSON body of a delivery
+type Batch struct {
+	Event        string           `json:"event"`
+	SentAt       time.Time        `json:"sent_at"`
+	Transactions []db.LedgerEntry `json:"transactions"`
+}
+
+// Sender POSTs batches to a URL, retrying failed requests
+type Sender struct {
+	URL         string
+	Secret      string // HMAC key; requests are unsigned if empty
+	BatchSize   int
+	MaxAttempts int
+	Backoff     time.Duration // delay before the first retry, doubled after each attempt
+	HTTPClient  *http.Client
+}
+
+// NewSender creates a sender with default batch size and retry settings
+func NewSender(url, secret string) *Sender {
+	return &Sender{
+		URL:         url,
+		Secret:      secret,
+		BatchSize:   DefaultBatchSize,
+		MaxAttempts: 4,
+		Backoff:     2 * time.Second,
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Sign returns the signature header value for a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header value against a body
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// CursorStore is what Deliver and Skip use: the ledger and the delivery
+// cursor. *db.DB implements it.
+type CursorStore interface {
+	GetCursor(name string) (map[string]int64, error)
+	SaveCursor(name string, positions map[string]int64) error
+	GetLedgerEntries(opts db.LedgerOptions) ([]db.LedgerEntry, error)
+	GetNewLedgerEntries(cursor string, opts db.LedgerOptions) ([]db.LedgerEntry, map[string]int64, error)
+}
+
+var _ CursorStore = (*db.DB)(nil)
+
+// Deliver sends transactions not yet delivered according to the cursor.
+// The cursor is advanced after each successful batch, so transactions from
+// a failed delivery stay queued and are sent again on the next call.
+// Returns the number of transactions delivered.
+func (s *Sender) Deliver(ctx context.Context, database CursorStore) (int, error) {
+	positions, err := database.GetCursor(CursorName)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := database.GetLedgerEntries(db.LedgerOptions{AfterRowIDs: positions})
+	if err != nil {
+		return 0, err
+	}
+
+	// Deliver in insertion order per source, so that the cursor can be
+	// advanced after each batch without skipping undelivered entries
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Source != entries[j].Source {
+			return entries[i].Source < entries[j].Source
+		}
+		return entries[i].RowID < entries[j].RowID
+	})
+
+	batchSize := s.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	delivered := 0
+	for start := 0; start < len(entries); start += batchSize {
+		batch := entries[start:min(start+batchSize, len(entries))]
+		if err := s.send(ctx, Batch{Event: EventNewTransactions, SentAt: time.Now().UTC(), Transactions: batch}); err != nil {
+			return delivered, err
+		}
+		for _, e := range batch {
+			positions[e.Source] = max(positions[e.Source], e.RowID)
+		}
+		if err := database.SaveCursor(CursorName, positions); err != nil {
+			return delivered, err
+		}
+		delivered += len(batch)
+	}
+	return delivered, nil
+}
+
+// Skip marks all current transactions as delivered without sending them
+func Skip(database CursorStore) error {
+	_, positions, err := database.GetNewLedgerEntries(CursorName, db.LedgerOptions{})
+	if err != nil {
+		return err
+	}
+	return database.SaveCursor(CursorName, positions)
+}
+
+// send POSTs a batch, retrying network errors, 429 and 5xx responses with exponential backoff
+func (s *Sender) send(ctx context.Context, batch Batch) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	attempts := max(s.MaxAttempts, 1)
+	delay := s.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := s.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == attempts {
+			return fmt.Errorf("webhook delivery failed after %d attempt(s): %w", attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post sends a single request. The returned bool tells whether a failure is worth retrying.
+func (s *Sender) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, EventNewTransactions)
+	if s.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(s.Secret, body))
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 500))
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return false, nil
+}
@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ivan4th/ameriagrab/client"
+	"github.com/ivan4th/ameriagrab/db"
+)
+
+func insertCardTxns(t *testing.T, database *db.DB, ids ...string) {
+	t.Helper()
+	var txns []client.Transaction
+	for _, id := range ids {
+		txns = append(txns, client.Transaction{ID: id, AccountingType: "DEBIT", Amount: client.Amount{Currency: "AMD", Amount: 100}, OperationDate: "2024-01-10T10:00:00Z"})
+	}
+	if _, err := database.InsertCardTransactions("card1", txns); err != nil {
+		t.Fatalf("failed to insert card transactions: %v", err)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	var received []string
+	failFrom := -1 // requests with this index or later fail with 503
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() { requests++ }()
+		body, _ := io.ReadAll(r.Body)
+		if !Verify("s3cret", body, r.Header.Get(SignatureHeader)) {
+			t.Errorf("invalid signature %q", r.Header.Get(SignatureHeader))
+		}
+		if r.Header.Get(EventHeader) != EventNewTransactions {
+			t.Errorf("unexpected event header %q", r.Header.Get(EventHeader))
+		}
+		if failFrom >= 0 && requests >= failFrom {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var batch Batch
+		if err := json.Unmarshal(body, &batch); err != nil {
+			t.Errorf("invalid body: %v", err)
+		}
+		for _, e := range batch.Transactions {
+			received = append(received, e.ID)
+		}
+	}))
+	defer server.Close()
+
+	sender := NewSender(server.URL, "s3cret")
+	sender.BatchSize = 2
+	sender.Backoff = 0
+
+	insertCardTxns(t, database, "t1", "t2")
+	if err := Skip(database); err != nil {
+		t.Fatalf("Skip failed: %v", err)
+	}
+
+	// Second batch fails on every attempt: the first batch is delivered, the rest stays queued
+	insertCardTxns(t, database, "t3", "t4", "t5")
+	failFrom = 1
+	n, err := sender.Deliver(context.Background(), database)
+	if err == nil {
+		t.Fatal("expected delivery error")
+	}
+	if n != 2 || len(received) != 2 || received[0] != "t3" || received[1] != "t4" {
+		t.Errorf("expected t3, t4 delivered, got %d %v", n, received)
+	}
+	if requests != 1+sender.MaxAttempts {
+		t.Errorf("expected %d requests, got %d", 1+sender.MaxAttempts, requests)
+	}
+
+	failFrom = -1
+	n, err = sender.Deliver(context.Background(), database)
+	if err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if n != 1 || received[len(received)-1] != "t5" {
+		t.Errorf("expected queued t5 to be delivered, got %d %v", n, received)
+	}
+
+	if n, err := sender.Deliver(context.Background(), database); err != nil || n != 0 {
+		t.Errorf("expected nothing left to deliver, got %d (err %v)", n, err)
+	}
+}
+
+func TestDeliver_ClientErrorNotRetried(t *testing.T) {
+	database, err := db.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	insertCardTxns(t, database, "t1")
+	sender := NewSender(server.URL, "")
+	sender.Backoff = 0
+	if _, err := sender.Deliver(context.Background(), database); err == nil {
+		t.Fatal("expected delivery error")
+	}
+	if requests != 1 {
+		t.Errorf("4xx responses should not be retried, got %d requests", requests)
+	}
+}